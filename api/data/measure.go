@@ -18,7 +18,10 @@
 package data
 
 import (
+	"google.golang.org/protobuf/proto"
+
 	"github.com/apache/skywalking-banyandb/api/common"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
 	"github.com/apache/skywalking-banyandb/pkg/bus"
 )
 
@@ -29,6 +32,9 @@ var MeasureWriteKindVersion = common.KindVersion{
 
 var TopicMeasureWrite = bus.UniTopic(MeasureWriteKindVersion.String())
 
+// MeasureWriteCodec serializes the measure writes sent to the remote data nodes, which are coalesced into batches by the liaison.
+var MeasureWriteCodec = bus.NewProtoCodec(func() proto.Message { return &measurev1.InternalWriteBatch{} })
+
 var MeasureQueryKindVersion = common.KindVersion{
 	Version: "v1",
 	Kind:    "measure-query",
@@ -40,3 +46,57 @@ var TopNQueryKindVersion = common.KindVersion{
 	Kind:    "topN-query",
 }
 var TopicTopNQuery = bus.BiTopic(TopNQueryKindVersion.String())
+
+var MeasureExplainKindVersion = common.KindVersion{
+	Version: "v1",
+	Kind:    "measure-explain",
+}
+var TopicMeasureExplain = bus.BiTopic(MeasureExplainKindVersion.String())
+
+var MeasureRolloverKindVersion = common.KindVersion{
+	Version: "v1",
+	Kind:    "measure-rollover",
+}
+var TopicMeasureRollover = bus.BiTopic(MeasureRolloverKindVersion.String())
+
+var MeasureInspectKindVersion = common.KindVersion{
+	Version: "v1",
+	Kind:    "measure-inspect",
+}
+var TopicMeasureInspect = bus.BiTopic(MeasureInspectKindVersion.String())
+
+var MeasureIndexStatsKindVersion = common.KindVersion{
+	Version: "v1",
+	Kind:    "measure-index-stats",
+}
+var TopicMeasureIndexStats = bus.BiTopic(MeasureIndexStatsKindVersion.String())
+
+var MeasureIndexRebuildKindVersion = common.KindVersion{
+	Version: "v1",
+	Kind:    "measure-index-rebuild",
+}
+var TopicMeasureIndexRebuild = bus.BiTopic(MeasureIndexRebuildKindVersion.String())
+
+var MeasureTimeRangeKindVersion = common.KindVersion{
+	Version: "v1",
+	Kind:    "measure-time-range",
+}
+var TopicMeasureTimeRange = bus.BiTopic(MeasureTimeRangeKindVersion.String())
+
+var MeasureUsageKindVersion = common.KindVersion{
+	Version: "v1",
+	Kind:    "measure-usage",
+}
+var TopicMeasureUsage = bus.BiTopic(MeasureUsageKindVersion.String())
+
+var MeasureManifestKindVersion = common.KindVersion{
+	Version: "v1",
+	Kind:    "measure-manifest",
+}
+var TopicMeasureManifest = bus.BiTopic(MeasureManifestKindVersion.String())
+
+var MeasureBlockExportKindVersion = common.KindVersion{
+	Version: "v1",
+	Kind:    "measure-block-export",
+}
+var TopicMeasureBlockExport = bus.BiTopic(MeasureBlockExportKindVersion.String())
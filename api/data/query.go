@@ -0,0 +1,68 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package data
+
+import (
+	"google.golang.org/protobuf/proto"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+)
+
+var SlowQueryListKindVersion = common.KindVersion{
+	Version: "v1",
+	Kind:    "slow-query-list",
+}
+var TopicSlowQueryList = bus.BiTopic(SlowQueryListKindVersion.String())
+
+var TopQueryListKindVersion = common.KindVersion{
+	Version: "v1",
+	Kind:    "top-query-list",
+}
+var TopicTopQueryList = bus.BiTopic(TopQueryListKindVersion.String())
+
+// ShardQuery restricts the stream or the measure query in Request to the shards, which are the ones served by a data node
+// for a replicated group. The messages of the query topics carry either a ShardQuery or a bare query.
+type ShardQuery struct {
+	Request  interface{}
+	ShardIDs []common.ShardID
+}
+
+// UnwrapShardQuery returns the query carried by the payload and the shards it's restricted to, which are nil if it's not restricted.
+func UnwrapShardQuery(payload interface{}) (interface{}, []common.ShardID) {
+	if q, ok := payload.(*ShardQuery); ok {
+		return q.Request, q.ShardIDs
+	}
+	return payload, nil
+}
+
+// QuerySink sends the elements or the data points of the stream or the measure query in Request to Send
+// as they are produced by the executor, instead of returning them in the response message,
+// which carries the truncation or the error only. It's published to the local pipeline only since Send is a function.
+type QuerySink struct {
+	Request interface{}
+	Send    func(item proto.Message) error
+}
+
+// UnwrapQuerySink returns the query carried by the payload and the function its results are sent to, which is nil if they're returned.
+func UnwrapQuerySink(payload interface{}) (interface{}, func(item proto.Message) error) {
+	if q, ok := payload.(*QuerySink); ok {
+		return q.Request, q.Send
+	}
+	return payload, nil
+}
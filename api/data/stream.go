@@ -18,7 +18,10 @@
 package data
 
 import (
+	"google.golang.org/protobuf/proto"
+
 	"github.com/apache/skywalking-banyandb/api/common"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
 	"github.com/apache/skywalking-banyandb/pkg/bus"
 )
 
@@ -29,8 +32,65 @@ var StreamWriteKindVersion = common.KindVersion{
 
 var TopicStreamWrite = bus.UniTopic(StreamWriteKindVersion.String())
 
+// StreamWriteCodec serializes the stream writes sent to the remote data nodes, which are coalesced into batches by the liaison.
+var StreamWriteCodec = bus.NewProtoCodec(func() proto.Message { return &streamv1.InternalWriteBatch{} })
+
 var StreamQueryKindVersion = common.KindVersion{
 	Version: "v1",
 	Kind:    "stream-query",
 }
 var TopicStreamQuery = bus.BiTopic(StreamQueryKindVersion.String())
+
+var StreamExplainKindVersion = common.KindVersion{
+	Version: "v1",
+	Kind:    "stream-explain",
+}
+var TopicStreamExplain = bus.BiTopic(StreamExplainKindVersion.String())
+
+var StreamRolloverKindVersion = common.KindVersion{
+	Version: "v1",
+	Kind:    "stream-rollover",
+}
+var TopicStreamRollover = bus.BiTopic(StreamRolloverKindVersion.String())
+
+var StreamInspectKindVersion = common.KindVersion{
+	Version: "v1",
+	Kind:    "stream-inspect",
+}
+var TopicStreamInspect = bus.BiTopic(StreamInspectKindVersion.String())
+
+var StreamIndexStatsKindVersion = common.KindVersion{
+	Version: "v1",
+	Kind:    "stream-index-stats",
+}
+var TopicStreamIndexStats = bus.BiTopic(StreamIndexStatsKindVersion.String())
+
+var StreamIndexRebuildKindVersion = common.KindVersion{
+	Version: "v1",
+	Kind:    "stream-index-rebuild",
+}
+var TopicStreamIndexRebuild = bus.BiTopic(StreamIndexRebuildKindVersion.String())
+
+var StreamTimeRangeKindVersion = common.KindVersion{
+	Version: "v1",
+	Kind:    "stream-time-range",
+}
+var TopicStreamTimeRange = bus.BiTopic(StreamTimeRangeKindVersion.String())
+
+var StreamUsageKindVersion = common.KindVersion{
+	Version: "v1",
+	Kind:    "stream-usage",
+}
+var TopicStreamUsage = bus.BiTopic(StreamUsageKindVersion.String())
+
+var StreamManifestKindVersion = common.KindVersion{
+	Version: "v1",
+	Kind:    "stream-manifest",
+}
+var TopicStreamManifest = bus.BiTopic(StreamManifestKindVersion.String())
+
+var StreamBlockExportKindVersion = common.KindVersion{
+	Version: "v1",
+	Kind:    "stream-block-export",
+}
+var TopicStreamBlockExport = bus.BiTopic(StreamBlockExportKindVersion.String())
@@ -0,0 +1,379 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package check probes a node by the round trip of a synthetic element, and checks the settings of its groups,
+// so that a node can be validated before taking the traffic.
+package check
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+const (
+	// GroupPrefix prefixes the names of the temporary groups holding the synthetic elements.
+	GroupPrefix = "banyand-check-"
+
+	streamName     = "probe"
+	familyName     = "searchable"
+	indexedTag     = "trace_id"
+	pollInterval   = 100 * time.Millisecond
+	queryInterval  = time.Minute
+	cleanupTimeout = 10 * time.Second
+)
+
+var errElementNotFound = errors.New("the synthetic element is not found")
+
+// Result is the outcome of a check, which fails if Err isn't nil.
+type Result struct {
+	Err     error
+	Name    string
+	Detail  string
+	Elapsed time.Duration
+}
+
+// Report holds the results of the checks in their running order.
+type Report struct {
+	Results []Result
+}
+
+// Failed returns the number of the failed checks.
+func (r *Report) Failed() int {
+	failed := 0
+	for _, res := range r.Results {
+		if res.Err != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+func (r *Report) run(name string, fn func() (string, error)) error {
+	start := time.Now()
+	detail, err := fn()
+	r.Results = append(r.Results, Result{Name: name, Detail: detail, Err: err, Elapsed: time.Since(start)})
+	return err
+}
+
+// Run checks the node connected by conn. A synthetic element is written to a temporary group and queried
+// by its time range and by an indexed tag, which are polled until the ctx is done. The temporary group is deleted
+// at the end. Then the retention settings and the index rule bindings of all the groups are verified.
+func Run(ctx context.Context, conn grpc.ClientConnInterface) *Report {
+	r := &Report{}
+	p := newProbe(ctx, conn)
+	if r.run("schema", p.createSchema) == nil {
+		if r.run("write", p.write) == nil {
+			if r.run("query", p.query) == nil {
+				_ = r.run("index", p.queryByIndex)
+			}
+		}
+		_ = r.run("cleanup", p.cleanup)
+	}
+	_ = r.run("retention", func() (string, error) { return checkRetention(ctx, conn) })
+	_ = r.run("index-rules", func() (string, error) { return checkIndexRules(ctx, conn) })
+	return r
+}
+
+type probe struct {
+	ctx   context.Context
+	conn  grpc.ClientConnInterface
+	md    *commonv1.Metadata
+	ts    time.Time
+	id    string
+	trace string
+}
+
+func newProbe(ctx context.Context, conn grpc.ClientConnInterface) *probe {
+	ts := timestamp.NowMilli()
+	suffix := fmt.Sprintf("%d", ts.UnixNano())
+	return &probe{
+		ctx:   ctx,
+		conn:  conn,
+		md:    &commonv1.Metadata{Group: GroupPrefix + suffix, Name: streamName},
+		ts:    ts,
+		id:    "element-" + suffix,
+		trace: "trace-" + suffix,
+	}
+}
+
+func (p *probe) createSchema() (string, error) {
+	day := &commonv1.IntervalRule{Unit: commonv1.IntervalRule_UNIT_DAY, Num: 1}
+	bundle := &databasev1.SchemaBundle{
+		Group: &commonv1.Group{
+			Metadata: &commonv1.Metadata{Name: p.md.GetGroup()},
+			Catalog:  commonv1.Catalog_CATALOG_STREAM,
+			ResourceOpts: &commonv1.ResourceOpts{
+				ShardNum:        1,
+				BlockInterval:   &commonv1.IntervalRule{Unit: commonv1.IntervalRule_UNIT_HOUR, Num: 1},
+				SegmentInterval: day,
+				Ttl:             day,
+			},
+		},
+		IndexRules: []*databasev1.IndexRule{{
+			Metadata: &commonv1.Metadata{Group: p.md.GetGroup(), Name: indexedTag},
+			Tags:     []string{indexedTag},
+			Type:     databasev1.IndexRule_TYPE_INVERTED,
+			Location: databasev1.IndexRule_LOCATION_SERIES,
+		}},
+		IndexRuleBindings: []*databasev1.IndexRuleBinding{{
+			Metadata: &commonv1.Metadata{Group: p.md.GetGroup(), Name: streamName},
+			Rules:    []string{indexedTag},
+			Subject:  &databasev1.Subject{Catalog: commonv1.Catalog_CATALOG_STREAM, Name: streamName},
+			BeginAt:  timestamppb.New(p.ts.Add(-time.Hour)),
+			ExpireAt: timestamppb.New(p.ts.Add(24 * time.Hour)),
+		}},
+		Streams: []*databasev1.Stream{{
+			Metadata: p.md,
+			TagFamilies: []*databasev1.TagFamilySpec{{
+				Name: familyName,
+				Tags: []*databasev1.TagSpec{
+					{Name: "service_id", Type: databasev1.TagType_TAG_TYPE_STRING},
+					{Name: indexedTag, Type: databasev1.TagType_TAG_TYPE_STRING},
+				},
+			}},
+			Entity: &databasev1.Entity{TagNames: []string{"service_id"}},
+		}},
+	}
+	if _, err := databasev1.NewGroupRegistryServiceClient(p.conn).Import(p.ctx,
+		&databasev1.GroupRegistryServiceImportRequest{Bundle: bundle}); err != nil {
+		return "", errors.WithMessagef(err, "failed to create the group %s", p.md.GetGroup())
+	}
+	return "created the group " + p.md.GetGroup(), nil
+}
+
+func (p *probe) write() (string, error) {
+	wc, err := streamv1.NewStreamServiceClient(p.conn).Write(p.ctx)
+	if err != nil {
+		return "", err
+	}
+	if err = wc.Send(&streamv1.WriteRequest{
+		Metadata: p.md,
+		Element: &streamv1.ElementValue{
+			ElementId: p.id,
+			Timestamp: timestamppb.New(p.ts),
+			TagFamilies: []*modelv1.TagFamilyForWrite{{Tags: []*modelv1.TagValue{
+				{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "banyand-check"}}},
+				{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: p.trace}}},
+			}}},
+		},
+	}); err != nil {
+		return "", err
+	}
+	if err = wc.CloseSend(); err != nil {
+		return "", err
+	}
+	for {
+		resp, errRecv := wc.Recv()
+		if errors.Is(errRecv, io.EOF) {
+			return "wrote the element " + p.id, nil
+		}
+		if errRecv != nil {
+			return "", errRecv
+		}
+		if len(resp.GetErrors()) > 0 {
+			return "", errors.Errorf("the element is rejected: %s", resp.GetErrors()[0].GetMessage())
+		}
+	}
+}
+
+func (p *probe) query() (string, error) {
+	// the element written before the data modules open the group is dropped, so it's written again until it's found
+	return p.poll(nil, p.write)
+}
+
+func (p *probe) queryByIndex() (string, error) {
+	return p.poll(&modelv1.Criteria{Exp: &modelv1.Criteria_Condition{Condition: &modelv1.Condition{
+		Name:  indexedTag,
+		Op:    modelv1.Condition_BINARY_OP_EQ,
+		Value: &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: p.trace}}},
+	}}}, nil)
+}
+
+// poll queries the synthetic element until it's found, since the group is opened by the data modules asynchronously.
+// The retry is called before querying again if it's not nil.
+func (p *probe) poll(criteria *modelv1.Criteria, retry func() (string, error)) (string, error) {
+	client := streamv1.NewStreamServiceClient(p.conn)
+	req := &streamv1.QueryRequest{
+		Metadata: p.md,
+		TimeRange: &modelv1.TimeRange{
+			Begin: timestamppb.New(p.ts.Add(-queryInterval)),
+			End:   timestamppb.New(p.ts.Add(queryInterval)),
+		},
+		Criteria: criteria,
+		Projection: &modelv1.TagProjection{TagFamilies: []*modelv1.TagProjection_TagFamily{
+			{Name: familyName, Tags: []string{indexedTag}},
+		}},
+		IncludeUnflushed: true,
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	attempts := 0
+	for {
+		attempts++
+		resp, err := client.Query(p.ctx, req)
+		if err == nil {
+			for _, e := range resp.GetElements() {
+				if e.GetElementId() == p.id {
+					return fmt.Sprintf("found the element after %d attempts", attempts), nil
+				}
+			}
+			err = errElementNotFound
+		}
+		select {
+		case <-p.ctx.Done():
+			return "", err
+		case <-ticker.C:
+		}
+		if retry != nil {
+			if _, err = retry(); err != nil {
+				return "", err
+			}
+		}
+	}
+}
+
+func (p *probe) cleanup() (string, error) {
+	// the group is deleted even if the checks run out of time
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+	if _, err := databasev1.NewGroupRegistryServiceClient(p.conn).Delete(ctx,
+		&databasev1.GroupRegistryServiceDeleteRequest{Group: p.md.GetGroup()}); err != nil {
+		return "", errors.WithMessagef(err, "failed to delete the group %s", p.md.GetGroup())
+	}
+	return "deleted the group " + p.md.GetGroup(), nil
+}
+
+// checkRetention verifies the intervals of the groups holding data: the ttl should cover a segment,
+// which should cover a block.
+func checkRetention(ctx context.Context, conn grpc.ClientConnInterface) (string, error) {
+	resp, err := databasev1.NewGroupRegistryServiceClient(conn).List(ctx, &databasev1.GroupRegistryServiceListRequest{})
+	if err != nil {
+		return "", err
+	}
+	var problems []string
+	checked := 0
+	for _, g := range resp.GetGroup() {
+		if g.GetCatalog() != commonv1.Catalog_CATALOG_STREAM && g.GetCatalog() != commonv1.Catalog_CATALOG_MEASURE {
+			continue
+		}
+		checked++
+		if problem := retentionProblem(g.GetResourceOpts()); problem != "" {
+			problems = append(problems, g.GetMetadata().GetName()+": "+problem)
+		}
+	}
+	if len(problems) > 0 {
+		return "", errors.New(strings.Join(problems, "; "))
+	}
+	return fmt.Sprintf("%d groups", checked), nil
+}
+
+func retentionProblem(opts *commonv1.ResourceOpts) string {
+	var intervals [3]time.Duration
+	for i, rule := range []*commonv1.IntervalRule{opts.GetBlockInterval(), opts.GetSegmentInterval(), opts.GetTtl()} {
+		ir, err := pbv1.ToIntervalRule(rule)
+		if err != nil || ir.Num <= 0 {
+			return fmt.Sprintf("the interval %v is invalid", rule)
+		}
+		intervals[i] = ir.EstimatedDuration()
+	}
+	if intervals[0] > intervals[1] {
+		return "the block interval is longer than the segment interval"
+	}
+	if intervals[1] > intervals[2] {
+		return "the segment interval is longer than the ttl"
+	}
+	return ""
+}
+
+// checkIndexRules verifies that the index rule bindings of the groups refer to the existing index rules and subjects,
+// and that the tags of the rules are defined by the subjects.
+func checkIndexRules(ctx context.Context, conn grpc.ClientConnInterface) (string, error) {
+	groups, err := databasev1.NewGroupRegistryServiceClient(conn).List(ctx, &databasev1.GroupRegistryServiceListRequest{})
+	if err != nil {
+		return "", err
+	}
+	var problems []string
+	checked := 0
+	for _, g := range groups.GetGroup() {
+		group := g.GetMetadata().GetName()
+		bindings, err := databasev1.NewIndexRuleBindingRegistryServiceClient(conn).List(ctx,
+			&databasev1.IndexRuleBindingRegistryServiceListRequest{Group: group})
+		if err != nil {
+			return "", err
+		}
+		if len(bindings.GetIndexRuleBinding()) == 0 {
+			continue
+		}
+		rules, err := databasev1.NewIndexRuleRegistryServiceClient(conn).List(ctx, &databasev1.IndexRuleRegistryServiceListRequest{Group: group})
+		if err != nil {
+			return "", err
+		}
+		ruleTags := make(map[string][]string, len(rules.GetIndexRule()))
+		for _, rule := range rules.GetIndexRule() {
+			ruleTags[rule.GetMetadata().GetName()] = rule.GetTags()
+		}
+		for _, b := range bindings.GetIndexRuleBinding() {
+			checked++
+			name := group + "/" + b.GetMetadata().GetName()
+			families, err := subjectFamilies(ctx, conn, group, b.GetSubject())
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: the subject %s is not found", name, b.GetSubject().GetName()))
+				continue
+			}
+			for _, rule := range b.GetRules() {
+				tags, ok := ruleTags[rule]
+				if !ok {
+					problems = append(problems, fmt.Sprintf("%s: the index rule %s is not found", name, rule))
+					continue
+				}
+				for _, tag := range tags {
+					if _, _, spec := pbv1.FindTagByName(families, tag); spec == nil {
+						problems = append(problems, fmt.Sprintf("%s: the tag %s of the index rule %s is not defined", name, tag, rule))
+					}
+				}
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return "", errors.New(strings.Join(problems, "; "))
+	}
+	return fmt.Sprintf("%d index rule bindings", checked), nil
+}
+
+func subjectFamilies(ctx context.Context, conn grpc.ClientConnInterface, group string, subject *databasev1.Subject) ([]*databasev1.TagFamilySpec, error) {
+	md := &commonv1.Metadata{Group: group, Name: subject.GetName()}
+	if subject.GetCatalog() == commonv1.Catalog_CATALOG_MEASURE {
+		resp, err := databasev1.NewMeasureRegistryServiceClient(conn).Get(ctx, &databasev1.MeasureRegistryServiceGetRequest{Metadata: md})
+		return resp.GetMeasure().GetTagFamilies(), err
+	}
+	resp, err := databasev1.NewStreamRegistryServiceClient(conn).Get(ctx, &databasev1.StreamRegistryServiceGetRequest{Metadata: md})
+	return resp.GetStream().GetTagFamilies(), err
+}
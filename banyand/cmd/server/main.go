@@ -20,6 +20,8 @@ package main
 import (
 	"fmt"
 	"os"
+	// the time zones of the groups are resolved without the tzdata of the image
+	_ "time/tzdata"
 
 	"github.com/apache/skywalking-banyandb/banyand/internal/cmd"
 )
@@ -19,6 +19,9 @@ package discovery
 
 import (
 	"context"
+	"os"
+
+	"github.com/pkg/errors"
 
 	"github.com/apache/skywalking-banyandb/pkg/bus"
 	"github.com/apache/skywalking-banyandb/pkg/run"
@@ -33,11 +36,30 @@ type ServiceRepo interface {
 }
 
 type repo struct {
-	local *bus.Bus
+	local  *bus.Bus
+	nodeID string
 }
 
 func (r *repo) NodeID() string {
-	return "local"
+	return r.nodeID
+}
+
+func (r *repo) FlagSet() *run.FlagSet {
+	fs := run.NewFlagSet("discovery")
+	fs.StringVarP(&r.nodeID, "node-id", "", "", "the id identifying the node among the ones sharing the metadata, which is the hostname by default")
+	return fs
+}
+
+func (r *repo) Validate() error {
+	if r.nodeID != "" {
+		return nil
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return errors.WithMessage(err, "resolve the node id by the hostname")
+	}
+	r.nodeID = hostname
+	return nil
 }
 
 func (r *repo) Name() string {
@@ -54,6 +76,7 @@ func (r *repo) Publish(topic bus.Topic, message ...bus.Message) (bus.Future, err
 
 func NewServiceRepo(_ context.Context) (ServiceRepo, error) {
 	return &repo{
-		local: bus.NewBus(),
+		local:  bus.NewBus(),
+		nodeID: "local",
 	}, nil
 }
@@ -0,0 +1,139 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/apache/skywalking-banyandb/banyand/check"
+	"github.com/apache/skywalking-banyandb/pkg/config"
+	"github.com/apache/skywalking-banyandb/pkg/grpchelper"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/run"
+	"github.com/apache/skywalking-banyandb/pkg/signal"
+)
+
+func newCheckCmd() *cobra.Command {
+	checkGroup := run.NewGroup("check")
+	closer := run.NewTester("check-closer")
+	checkGroup.Register(append([]run.Unit{new(signal.Handler), closer}, newStandaloneUnits(&checkGroup)...)...)
+	logging := logger.Logging{}
+	var timeout time.Duration
+	checkCmd := &cobra.Command{
+		Use:   "check",
+		Short: "Check a standalone server before sending the traffic",
+		Long: `Check boots the modules of a standalone server by the same flags, writes a synthetic element to a temporary group
+and queries it by its time range and by an indexed tag, verifies the retention settings and the index rule bindings
+of all the groups, and reports the results. It exits with a non-zero status if any check fails.
+The server must be stopped while it's checked, since they share the addresses and the data files.`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) (err error) {
+			if err = config.Load("logging", cmd.Flags()); err != nil {
+				return err
+			}
+			return logger.Init(logging)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			return runCheck(ctx, cmd, &checkGroup, closer)
+		},
+	}
+	checkCmd.Flags().StringVarP(&logging.Env, "logging.env", "", "dev", "the logging")
+	checkCmd.Flags().StringVarP(&logging.Level, loggingLevelFlag, "", "warn", "the level of logging")
+	checkCmd.Flags().DurationVar(&timeout, "check-timeout", time.Minute, "the max time of booting the modules and running the checks")
+	checkCmd.Flags().AddFlagSet(checkGroup.RegisterFlags().FlagSet)
+	return checkCmd
+}
+
+func runCheck(ctx context.Context, cmd *cobra.Command, g *run.Group, closer *run.Tester) error {
+	start := time.Now()
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- g.Run()
+	}()
+	ready := make(chan struct{})
+	go func() {
+		g.WaitTillReady()
+		close(ready)
+	}()
+	select {
+	case err := <-stopped:
+		if err == nil {
+			err = errors.New("the modules are stopped")
+		}
+		return errors.WithMessage(err, "failed to boot the modules")
+	case <-ctx.Done():
+		return errors.New("the modules aren't booted in time")
+	case <-ready:
+	}
+	defer func() {
+		closer.GracefulStop()
+		<-stopped
+	}()
+	out := cmd.OutOrStdout()
+	printResult(out, check.Result{Name: "boot", Detail: "booted the modules", Elapsed: time.Since(start)})
+	conn, err := dialCheck(cmd)
+	if err != nil {
+		return errors.WithMessage(err, "failed to connect to the server")
+	}
+	defer conn.Close()
+	report := check.Run(ctx, conn)
+	for _, r := range report.Results {
+		printResult(out, r)
+	}
+	if failed := report.Failed(); failed > 0 {
+		return errors.Errorf("%d of %d checks failed", failed, len(report.Results))
+	}
+	return nil
+}
+
+// dialCheck connects to the gRPC server booted by the check, whose address and TLS settings are read from the flags.
+func dialCheck(cmd *cobra.Command) (*grpc.ClientConn, error) {
+	flags := cmd.Flags()
+	addr, err := flags.GetString("addr")
+	if err != nil {
+		return nil, err
+	}
+	creds := insecure.NewCredentials()
+	if enabled, _ := flags.GetBool("tls"); enabled {
+		certFile, _ := flags.GetString("cert-file")
+		if creds, err = credentials.NewClientTLSFromFile(certFile, ""); err != nil {
+			return nil, err
+		}
+	}
+	return grpchelper.Conn(addr, 10*time.Second, grpc.WithTransportCredentials(creds))
+}
+
+func printResult(out io.Writer, r check.Result) {
+	if r.Err != nil {
+		fmt.Fprintf(out, "[failed] %s: %v (%s)\n", r.Name, r.Err, r.Elapsed.Round(time.Millisecond))
+		return
+	}
+	fmt.Fprintf(out, "[ok] %s: %s (%s)\n", r.Name, r.Detail, r.Elapsed.Round(time.Millisecond))
+}
@@ -42,6 +42,6 @@ func NewRoot() *cobra.Command {
 BanyanDB, as an observability database, aims to ingest, analyze and store Metrics, Tracing and Logging data
 `,
 	}
-	cmd.AddCommand(newStandaloneCmd())
+	cmd.AddCommand(newStandaloneCmd(), newCheckCmd(), newVerifyCmd())
 	return cmd
 }
@@ -42,7 +42,26 @@ import (
 
 var g = run.NewGroup("standalone")
 
-func newStandaloneCmd() *cobra.Command {
+const loggingLevelFlag = "logging.level"
+
+// newLoggingReloader returns the Reloader changing the level of logging at runtime.
+func newLoggingReloader() run.Reloader {
+	return run.NewReloader("logging", func() map[string]string {
+		return map[string]string{loggingLevelFlag: logger.Level()}
+	}, func(values map[string]string) (func(), error) {
+		level := values[loggingLevelFlag]
+		if err := logger.CheckLevel(level); err != nil {
+			return nil, err
+		}
+		return func() {
+			// the level is checked above
+			_ = logger.SetLevel(level)
+		}, nil
+	})
+}
+
+// newStandaloneUnits initiates the modules of a standalone server, which are run by g.
+func newStandaloneUnits(g *run.Group) []run.Unit {
 	l := logger.GetLogger("bootstrap")
 	ctx := context.Background()
 	repo, err := discovery.NewServiceRepo(ctx)
@@ -77,9 +96,7 @@ func newStandaloneCmd() *cobra.Command {
 	metricSvc := observability.NewMetricService()
 	httpServer := http.NewService()
 
-	// Meta the run Group units.
-	g.Register(
-		new(signal.Handler),
+	units := []run.Unit{
 		repo,
 		pipeline,
 		metaSvc,
@@ -90,7 +107,16 @@ func newStandaloneCmd() *cobra.Command {
 		metricSvc,
 		profSvc,
 		httpServer,
-	)
+		newLoggingReloader(),
+		run.NewConfigWatcher(g.Reload),
+	}
+	tcp.RegisterModules(units...)
+	return units
+}
+
+func newStandaloneCmd() *cobra.Command {
+	// Meta the run Group units.
+	g.Register(append([]run.Unit{new(signal.Handler)}, newStandaloneUnits(&g)...)...)
 	logging := logger.Logging{}
 	standaloneCmd := &cobra.Command{
 		Use:     "standalone",
@@ -115,7 +141,7 @@ func newStandaloneCmd() *cobra.Command {
 	}
 
 	standaloneCmd.Flags().StringVarP(&logging.Env, "logging.env", "", "dev", "the logging")
-	standaloneCmd.Flags().StringVarP(&logging.Level, "logging.level", "", "info", "the level of logging")
+	standaloneCmd.Flags().StringVarP(&logging.Level, loggingLevelFlag, "", "info", "the level of logging")
 	standaloneCmd.Flags().AddFlagSet(g.RegisterFlags().FlagSet)
 	return standaloneCmd
 }
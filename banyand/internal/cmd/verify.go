@@ -0,0 +1,77 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/apache/skywalking-banyandb/banyand/tsdb"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+func newVerifyCmd() *cobra.Command {
+	logging := logger.Logging{}
+	var repair bool
+	verifyCmd := &cobra.Command{
+		Use:   "verify [path...]",
+		Short: "Verify the integrity of the data files",
+		Long: `Verify scans the shards of the databases under the paths, e.g. the stream-root-path and the measure-root-path,
+checks the manifests of the segments, the checksums of the sealed blocks and the stores and the indices of all the blocks,
+and reports the damaged files. The repair moves the damaged blocks to the quarantine directories of their shards.
+The server must be stopped while its data files are verified.`,
+		Args:          cobra.MinimumNArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) (err error) {
+			return logger.Init(logging)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			l := logger.GetLogger("verify")
+			unrepaired := 0
+			for _, p := range args {
+				report, err := tsdb.Verify(p, repair, l)
+				if err != nil {
+					return errors.WithMessagef(err, "failed to verify %s", p)
+				}
+				out := cmd.OutOrStdout()
+				fmt.Fprintf(out, "%s: %d shards, %d segments, %d blocks, %d damaged\n",
+					p, report.Shards, report.Segments, report.Blocks, len(report.Damages))
+				for _, d := range report.Damages {
+					state := "damaged"
+					if d.Repaired {
+						state = "repaired"
+					} else {
+						unrepaired++
+					}
+					fmt.Fprintf(out, "  [%s] %s: %s\n", state, d.Path, d.Reason)
+				}
+			}
+			if unrepaired > 0 {
+				return errors.Errorf("%d damages are not repaired", unrepaired)
+			}
+			return nil
+		},
+	}
+	verifyCmd.Flags().BoolVar(&repair, "repair", false, "quarantine the damaged blocks and fix the manifests")
+	verifyCmd.Flags().StringVarP(&logging.Env, "logging.env", "", "dev", "the logging")
+	verifyCmd.Flags().StringVarP(&logging.Level, "logging.level", "", "warn", "the level of logging")
+	return verifyCmd
+}
@@ -26,10 +26,12 @@ import (
 	"github.com/dgraph-io/badger/v3"
 	"github.com/dgraph-io/badger/v3/bydb"
 	"github.com/dgraph-io/badger/v3/y"
+	"github.com/pkg/errors"
 
 	"github.com/apache/skywalking-banyandb/banyand/observability"
 	"github.com/apache/skywalking-banyandb/pkg/encoding"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/throttle"
 )
 
 var (
@@ -42,14 +44,75 @@ var (
 )
 
 type badgerTSS struct {
-	shardID int
-	dbOpts  badger.Options
-	db      *badger.DB
+	shardID     int
+	dbOpts      badger.Options
+	db          *badger.DB
+	throttle    *throttle.Throttle
+	decoderPool encoding.SeriesDecoderPool
+	*syncer
 	badger.TSet
 }
 
 func (b *badgerTSS) Stats() (s observability.Statistics) {
-	return badgerStats(b.db)
+	s = badgerStats(b.db)
+	b.syncer.stats(&s)
+	return s
+}
+
+func (b *badgerTSS) Put(key, val []byte, ts uint64) error {
+	if err := b.TSet.Put(key, val, ts); err != nil {
+		return err
+	}
+	b.written(len(key) + len(val))
+	return nil
+}
+
+func (b *badgerTSS) PutAsync(key, val []byte, ts uint64, f func(error)) error {
+	return b.TSet.PutAsync(key, val, ts, func(err error) {
+		if err == nil {
+			b.written(len(key) + len(val))
+		}
+		f(err)
+	})
+}
+
+// Visit decodes the series blocks flushed to the levels, and calls visitFn with their values.
+// The blocks are read at the rate limited by the background throttle.
+// The raw values of the memory tables can't be told apart from the blocks, the store should be reopened before visiting
+// if it's written since opened. The key and the value are only valid in visitFn.
+func (b *badgerTSS) Visit(visitFn TimeSeriesVisitFunc) error {
+	if b.decoderPool == nil {
+		return errors.New("the time series store is opened without the encoding")
+	}
+	it := b.db.NewIterator(badger.DefaultIteratorOptions)
+	defer func() {
+		_ = it.Close()
+	}()
+	for it.Rewind(); it.Valid(); it.Next() {
+		key := y.ParseKey(it.Key())
+		// the flushes don't visit the store, so only the reads of the background jobs are throttled
+		b.throttle.Wait(len(it.Key()) + len(it.Value().Value))
+		err := func() error {
+			decoder := b.decoderPool.Get(key)
+			defer b.decoderPool.Put(decoder)
+			if err := decoder.Decode(key, it.Value().Value); err != nil {
+				return errors.WithMessagef(err, "failed to decode the block of %v at %d", key, y.ParseTs(it.Key()))
+			}
+			for iter := decoder.Iterator(); iter.Next(); {
+				if err := visitFn(key, iter.Val(), iter.Time()); err != nil {
+					return err
+				}
+			}
+			return nil
+		}()
+		if errors.Is(err, ErrStopScan) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func badgerStats(db *badger.DB) (s observability.Statistics) {
@@ -62,6 +125,7 @@ func badgerStats(db *badger.DB) (s observability.Statistics) {
 
 func (b *badgerTSS) Close() error {
 	if b.db != nil && !b.db.IsClosed() {
+		b.syncer.stop()
 		return b.db.Close()
 	}
 	return nil
@@ -121,10 +185,13 @@ type badgerDB struct {
 	shardID int
 	dbOpts  badger.Options
 	db      *badger.DB
+	*syncer
 }
 
 func (b *badgerDB) Stats() observability.Statistics {
-	return badgerStats(b.db)
+	s := badgerStats(b.db)
+	b.syncer.stats(&s)
+	return s
 }
 
 func (b *badgerDB) Handover(iterator Iterator) error {
@@ -217,17 +284,22 @@ func (b *badgerDB) NewIterator(opt ScanOpts) Iterator {
 
 func (b *badgerDB) Close() error {
 	if b.db != nil && !b.db.IsClosed() {
+		b.syncer.stop()
 		return b.db.Close()
 	}
 	return nil
 }
 
 func (b *badgerDB) Put(key, val []byte) error {
-	return b.db.Put(y.KeyWithTs(key, math.MaxInt64), val)
+	return b.PutWithVersion(key, val, math.MaxInt64)
 }
 
 func (b *badgerDB) PutWithVersion(key, val []byte, version uint64) error {
-	return b.db.Put(y.KeyWithTs(key, version), val)
+	if err := b.db.Put(y.KeyWithTs(key, version), val); err != nil {
+		return err
+	}
+	b.written(len(key) + len(val))
+	return nil
 }
 
 func (b *badgerDB) Get(key []byte) ([]byte, error) {
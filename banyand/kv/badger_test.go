@@ -0,0 +1,69 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kv_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/banyand/kv"
+	"github.com/apache/skywalking-banyandb/pkg/convert"
+	"github.com/apache/skywalking-banyandb/pkg/encoding"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+)
+
+func TestTimeSeriesVisit(t *testing.T) {
+	tester := require.New(t)
+	path, deferFn := test.Space(tester)
+	defer deferFn()
+	open := func() kv.TimeSeriesStore {
+		store, err := kv.OpenTimeSeriesStore(0, path,
+			kv.TSSWithEncoding(encoding.NewPlainEncoderPool("test", 1<<20), encoding.NewPlainDecoderPool("test", 1<<20)),
+			kv.TSSWithMemTableSize(1<<20))
+		tester.NoError(err)
+		return store
+	}
+	store := open()
+	want := make(map[string]int64)
+	for i := 1; i <= 5; i++ {
+		for j, k := range []string{"k1", "k2"} {
+			v := int64(i*10 + j)
+			tester.NoError(store.Put([]byte(k), convert.Int64ToBytes(v), uint64(i*1000)))
+			want[k+string(convert.Uint64ToBytes(uint64(i*1000)))] = v
+		}
+	}
+	// the values are flushed to the disk once the store is closed
+	tester.NoError(store.Close())
+	store = open()
+	defer store.Close()
+
+	got := make(map[string]int64)
+	tester.NoError(store.Visit(func(key, val []byte, ts uint64) error {
+		got[string(key)+string(convert.Uint64ToBytes(ts))] = convert.BytesToInt64(val)
+		return nil
+	}))
+	tester.Equal(want, got)
+
+	var visited int
+	tester.NoError(store.Visit(func(key, val []byte, ts uint64) error {
+		visited++
+		return kv.ErrStopScan
+	}))
+	tester.Equal(1, visited)
+}
@@ -0,0 +1,169 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kv
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/pkg/errors"
+
+	"github.com/apache/skywalking-banyandb/banyand/observability"
+)
+
+// SyncPolicy decides when the written data are forced onto the disk.
+type SyncPolicy string
+
+const (
+	// SyncPolicyOS leaves the write-ahead logs to the OS page cache, which writes them back at its own pace.
+	// A crash of the node loses the data the OS hasn't written back yet.
+	SyncPolicyOS SyncPolicy = "os"
+	// SyncPolicyPerWrite syncs the write-ahead log before acknowledging each write.
+	SyncPolicyPerWrite SyncPolicy = "per-write"
+	// SyncPolicyInterval syncs the write-ahead logs periodically.
+	// A crash of the node loses at most the data acknowledged within an interval.
+	SyncPolicyInterval SyncPolicy = "interval"
+
+	memFileExt = ".mem"
+)
+
+var ErrUnknownSyncPolicy = errors.New("unknown fsync policy")
+
+// Durability is the fsync policy of a store.
+type Durability struct {
+	Policy SyncPolicy
+	// Interval is the period of syncing under SyncPolicyInterval
+	Interval time.Duration
+}
+
+// Validate checks whether the policy is known and its interval is valid.
+func (d Durability) Validate() error {
+	switch d.Policy {
+	case "", SyncPolicyOS, SyncPolicyPerWrite:
+		return nil
+	case SyncPolicyInterval:
+		if d.Interval <= 0 {
+			return errors.Errorf("the fsync interval should be positive, got %s", d.Interval)
+		}
+		return nil
+	}
+	return errors.WithMessagef(ErrUnknownSyncPolicy, "%s", d.Policy)
+}
+
+// syncer tracks the acknowledged bytes which aren't synced yet, and syncs them periodically under SyncPolicyInterval.
+type syncer struct {
+	durability Durability
+	unsynced   atomic.Int64
+	stopCh     chan struct{}
+	wg         sync.WaitGroup
+}
+
+func newSyncer(durability Durability) *syncer {
+	return &syncer{
+		durability: durability,
+		stopCh:     make(chan struct{}),
+	}
+}
+
+func (s *syncer) start(db *badger.DB) {
+	if s.durability.Policy != SyncPolicyInterval {
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.durability.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stopCh:
+				return
+			case <-ticker.C:
+				n := s.unsynced.Swap(0)
+				if n == 0 {
+					continue
+				}
+				if err := syncDB(db); err != nil {
+					s.unsynced.Add(n)
+					db.Opts().Logger.Errorf("failed to sync %s: %v", db.Opts().Dir, err)
+				}
+			}
+		}
+	}()
+}
+
+func (s *syncer) written(size int) {
+	if s.durability.Policy == SyncPolicyInterval {
+		s.unsynced.Add(int64(size))
+	}
+}
+
+// stats fills the unsynced bytes in. Nothing is synced before flushing memtables under SyncPolicyOS,
+// so all the bytes of the memtables are reported as the upper bound.
+func (s *syncer) stats(stat *observability.Statistics) {
+	switch s.durability.Policy {
+	case SyncPolicyInterval:
+		stat.UnsyncedBytes = s.unsynced.Load()
+	case SyncPolicyPerWrite:
+		stat.UnsyncedBytes = 0
+	default:
+		stat.UnsyncedBytes = stat.MemBytes
+	}
+}
+
+func (s *syncer) stop() {
+	close(s.stopCh)
+	s.wg.Wait()
+}
+
+// syncDB syncs the value log and the write-ahead logs of the memtables.
+// badger only syncs the latter by the SyncWrites option, so the files are synced directly.
+func syncDB(db *badger.DB) error {
+	if err := db.Sync(); err != nil {
+		return err
+	}
+	dir := db.Opts().Dir
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), memFileExt) {
+			continue
+		}
+		// the memtable might be flushed and deleted in the meantime
+		f, err := os.Open(filepath.Join(dir, e.Name()))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		err = f.Sync()
+		_ = f.Close()
+		if err != nil {
+			return errors.WithMessagef(err, "sync %s", e.Name())
+		}
+	}
+	return nil
+}
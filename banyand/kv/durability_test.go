@@ -0,0 +1,67 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kv_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/banyand/kv"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+)
+
+func TestDurabilityValidate(t *testing.T) {
+	assert.NoError(t, kv.Durability{}.Validate())
+	assert.NoError(t, kv.Durability{Policy: kv.SyncPolicyPerWrite}.Validate())
+	assert.NoError(t, kv.Durability{Policy: kv.SyncPolicyInterval, Interval: time.Second}.Validate())
+	assert.Error(t, kv.Durability{Policy: kv.SyncPolicyInterval}.Validate())
+	assert.ErrorIs(t, kv.Durability{Policy: "always"}.Validate(), kv.ErrUnknownSyncPolicy)
+}
+
+func TestUnsyncedBytes(t *testing.T) {
+	tester := require.New(t)
+	open := func(d kv.Durability) kv.Store {
+		path, deferFn := test.Space(tester)
+		t.Cleanup(deferFn)
+		store, err := kv.OpenStore(0, path, kv.StoreWithMemTableSize(1<<20), kv.StoreWithDurability(d))
+		tester.NoError(err)
+		t.Cleanup(func() {
+			_ = store.Close()
+		})
+		return store
+	}
+
+	perWrite := open(kv.Durability{Policy: kv.SyncPolicyPerWrite})
+	tester.NoError(perWrite.Put([]byte("key"), []byte("val")))
+	tester.Zero(perWrite.Stats().UnsyncedBytes)
+
+	interval := open(kv.Durability{Policy: kv.SyncPolicyInterval, Interval: 10 * time.Millisecond})
+	tester.NoError(interval.Put([]byte("key"), []byte("val")))
+	tester.Eventually(func() bool {
+		return interval.Stats().UnsyncedBytes == 0
+	}, time.Second, 10*time.Millisecond)
+
+	osPolicy := open(kv.Durability{Policy: kv.SyncPolicyOS})
+	tester.NoError(osPolicy.Put([]byte("key"), []byte("val")))
+	stat := osPolicy.Stats()
+	tester.Equal(stat.MemBytes, stat.UnsyncedBytes)
+	tester.Positive(stat.UnsyncedBytes)
+}
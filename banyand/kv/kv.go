@@ -28,6 +28,7 @@ import (
 	"github.com/apache/skywalking-banyandb/banyand/observability"
 	"github.com/apache/skywalking-banyandb/pkg/encoding"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/throttle"
 )
 
 var (
@@ -84,12 +85,22 @@ type TimeSeriesReader interface {
 	GetAll(key []byte) ([][]byte, error)
 }
 
+// TimeSeriesVisitFunc is called with a value by its key and timestamp/version
+type TimeSeriesVisitFunc func(key, val []byte, ts uint64) error
+
+type TimeSeriesVisitor interface {
+	// Visit all the values flushed to the disk, which skips the ones still in the memory tables.
+	// Returning ErrStopScan from visitFn stops the visiting.
+	Visit(visitFn TimeSeriesVisitFunc) error
+}
+
 // TimeSeriesStore is time series storage
 type TimeSeriesStore interface {
 	observability.Observable
 	io.Closer
 	TimeSeriesWriter
 	TimeSeriesReader
+	TimeSeriesVisitor
 }
 
 type TimeSeriesOptions func(TimeSeriesStore)
@@ -108,9 +119,10 @@ func TSSWithLogger(l *logger.Logger) TimeSeriesOptions {
 func TSSWithEncoding(encoderPool encoding.SeriesEncoderPool, decoderPool encoding.SeriesDecoderPool) TimeSeriesOptions {
 	return func(store TimeSeriesStore) {
 		if btss, ok := store.(*badgerTSS); ok {
+			btss.decoderPool = decoderPool
 			btss.dbOpts = btss.dbOpts.WithExternalCompactor(
 				&encoderPoolDelegate{
-					encoderPool,
+					SeriesEncoderPool: encoderPool,
 				}, &decoderPoolDelegate{
 					decoderPool,
 				})
@@ -118,6 +130,15 @@ func TSSWithEncoding(encoderPool encoding.SeriesEncoderPool, decoderPool encodin
 	}
 }
 
+// TSSWithBackgroundThrottle limits the IO rate of visiting the store by the background jobs, e.g. merging the blocks
+func TSSWithBackgroundThrottle(t *throttle.Throttle) TimeSeriesOptions {
+	return func(store TimeSeriesStore) {
+		if btss, ok := store.(*badgerTSS); ok {
+			btss.throttle = t
+		}
+	}
+}
+
 func TSSWithFlushCallback(callback func()) TimeSeriesOptions {
 	return func(store TimeSeriesStore) {
 		if btss, ok := store.(*badgerTSS); ok {
@@ -137,6 +158,16 @@ func TSSWithMemTableSize(size int64) TimeSeriesOptions {
 	}
 }
 
+// TSSWithDurability sets the fsync policy of the underlying TimeSeriesStore
+func TSSWithDurability(d Durability) TimeSeriesOptions {
+	return func(store TimeSeriesStore) {
+		if btss, ok := store.(*badgerTSS); ok {
+			btss.syncer = newSyncer(d)
+			btss.dbOpts = btss.dbOpts.WithSyncWrites(d.Policy == SyncPolicyPerWrite)
+		}
+	}
+}
+
 type Iterator interface {
 	Next()
 	Rewind()
@@ -166,6 +197,7 @@ func OpenTimeSeriesStore(shardID int, path string, options ...TimeSeriesOptions)
 	btss := new(badgerTSS)
 	btss.shardID = shardID
 	btss.dbOpts = badger.DefaultOptions(path)
+	btss.syncer = newSyncer(Durability{})
 	for _, opt := range options {
 		opt(btss)
 	}
@@ -180,6 +212,7 @@ func OpenTimeSeriesStore(shardID int, path string, options ...TimeSeriesOptions)
 		return nil, fmt.Errorf("failed to open time series store: %v", err)
 	}
 	btss.TSet = *badger.NewTSet(btss.db)
+	btss.syncer.start(btss.db)
 	return btss, nil
 }
 
@@ -213,11 +246,22 @@ func StoreWithMemTableSize(size int64) StoreOptions {
 	}
 }
 
+// StoreWithDurability sets the fsync policy of the underlying Store
+func StoreWithDurability(d Durability) StoreOptions {
+	return func(store Store) {
+		if bdb, ok := store.(*badgerDB); ok {
+			bdb.syncer = newSyncer(d)
+			bdb.dbOpts = bdb.dbOpts.WithSyncWrites(d.Policy == SyncPolicyPerWrite)
+		}
+	}
+}
+
 // OpenStore creates a new Store
 func OpenStore(shardID int, path string, options ...StoreOptions) (Store, error) {
 	bdb := new(badgerDB)
 	bdb.shardID = shardID
 	bdb.dbOpts = badger.DefaultOptions(path)
+	bdb.syncer = newSyncer(Durability{})
 	for _, opt := range options {
 		opt(bdb)
 	}
@@ -231,6 +275,7 @@ func OpenStore(shardID int, path string, options ...StoreOptions) (Store, error)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open normal store: %v", err)
 	}
+	bdb.syncer.start(bdb.db)
 	return bdb, nil
 }
 
@@ -252,6 +297,7 @@ func OpenIndexStore(shardID int, path string, options ...IndexOptions) (IndexSto
 	bdb := new(badgerDB)
 	bdb.shardID = shardID
 	bdb.dbOpts = badger.DefaultOptions(path)
+	bdb.syncer = newSyncer(Durability{})
 	for _, opt := range options {
 		opt(bdb)
 	}
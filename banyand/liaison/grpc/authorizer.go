@@ -0,0 +1,439 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+// maxAuthorizerCacheSize bounds the number of the decisions cached by the authorizer.
+const maxAuthorizerCacheSize = 10000
+
+var (
+	errInvalidAuthorizerURL    = errors.New("the authorizer url should be an absolute http or https url")
+	errNegativeAuthorizerValue = errors.New("the authorizer timeout should be positive and the cache ttl should not be negative")
+
+	metadataFullName = (&commonv1.Metadata{}).ProtoReflect().Descriptor().FullName()
+	groupFullName    = (&commonv1.Group{}).ProtoReflect().Descriptor().FullName()
+)
+
+// authzInput is the context of a call sent to the authorizer webhook.
+type authzInput struct {
+	// Identity is the subject of the client certificate, which is absent without mutual TLS
+	Identity string `json:"identity,omitempty"`
+	// Token is the bearer token of the authorization header, which is verified by the webhook
+	Token  string `json:"token,omitempty"`
+	Method string `json:"method"`
+	Group  string `json:"group,omitempty"`
+	// TagFamilies are the tag families projected or filtered by a query
+	TagFamilies []string `json:"tag_families,omitempty"`
+}
+
+// authzResult is the decision of the webhook, which is either a boolean or an object with allow and reason.
+// The object of an allowed query might deny some of its tag families too.
+type authzResult struct {
+	Allow             bool     `json:"allow"`
+	Reason            string   `json:"reason,omitempty"`
+	DeniedTagFamilies []string `json:"denied_tag_families,omitempty"`
+}
+
+func (r *authzResult) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &r.Allow); err == nil {
+		return nil
+	}
+	type result authzResult
+	return json.Unmarshal(data, (*result)(r))
+}
+
+type authzDecision struct {
+	authzResult
+	expireAt time.Time
+}
+
+// authorizer delegates the authorization of the calls to an external webhook, e.g. the data API of an OPA server.
+// It posts {"input": {...}} and expects {"result": true} or {"result": {"allow": true, "reason": "..."}}.
+// The decisions are cached by the input for the ttl.
+type authorizer struct {
+	log *logger.Logger
+	// tags returns the tags of a stream or a measure to find the tag families filtered by a query
+	tags     func(ctx context.Context, kind schema.Kind, md *commonv1.Metadata) (*resourceTags, bool)
+	client   *http.Client
+	cache    map[string]authzDecision
+	url      string
+	timeout  time.Duration
+	cacheTTL time.Duration
+	failOpen bool
+	mu       sync.Mutex
+}
+
+func (a *authorizer) enabled() bool {
+	return a.url != ""
+}
+
+func (a *authorizer) validate() error {
+	if !a.enabled() {
+		return nil
+	}
+	u, err := url.Parse(a.url)
+	if err != nil || !u.IsAbs() || (u.Scheme != "http" && u.Scheme != "https") {
+		return errInvalidAuthorizerURL
+	}
+	if a.timeout <= 0 || a.cacheTTL < 0 {
+		return errNegativeAuthorizerValue
+	}
+	a.client = &http.Client{Timeout: a.timeout}
+	a.cache = make(map[string]authzDecision)
+	return nil
+}
+
+func (a *authorizer) unaryInterceptor() grpclib.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpclib.UnaryServerInfo, handler grpclib.UnaryHandler) (interface{}, error) {
+		if err := a.authorize(ctx, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (a *authorizer) streamInterceptor() grpclib.StreamServerInterceptor {
+	return func(srv interface{}, ss grpclib.ServerStream, info *grpclib.StreamServerInfo, handler grpclib.StreamHandler) error {
+		return handler(srv, &authorizedStream{ServerStream: ss, authorizer: a, method: info.FullMethod})
+	}
+}
+
+// authorizedStream authorizes each message received, since the messages of a stream may target different groups.
+type authorizedStream struct {
+	grpclib.ServerStream
+	authorizer *authorizer
+	method     string
+}
+
+func (s *authorizedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.authorizer.authorize(s.Context(), s.method, m)
+}
+
+func (a *authorizer) authorize(ctx context.Context, method string, req interface{}) error {
+	input := authzInput{
+		Method: method,
+		Group:  requestGroup(req),
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.PeerCertificates) > 0 {
+			input.Identity = tlsInfo.State.PeerCertificates[0].Subject.String()
+		}
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			input.Token = strings.TrimPrefix(values[0], "Bearer ")
+		}
+	}
+	q := newTagFamilyQuery(req)
+	if q != nil {
+		input.TagFamilies = q.tagFamilies(ctx, a.tags)
+	}
+	decision, err := a.decide(ctx, input)
+	if err != nil {
+		a.log.Warn().Err(err).Str("method", method).Str("group", input.Group).Bool("fail_open", a.failOpen).
+			Msg("the authorizer is unavailable")
+		if a.failOpen {
+			return nil
+		}
+		return status.Error(codes.PermissionDenied, "the authorizer is unavailable")
+	}
+	if !decision.Allow {
+		msg := "the call is denied by the authorizer"
+		if decision.Reason != "" {
+			msg = fmt.Sprintf("%s: %s", msg, decision.Reason)
+		}
+		return status.Error(codes.PermissionDenied, msg)
+	}
+	if q != nil && len(decision.DeniedTagFamilies) > 0 {
+		return q.strip(decision.DeniedTagFamilies)
+	}
+	return nil
+}
+
+func (a *authorizer) decide(ctx context.Context, input authzInput) (authzResult, error) {
+	body, err := json.Marshal(map[string]authzInput{"input": input})
+	if err != nil {
+		return authzResult{}, err
+	}
+	key := string(body)
+	now := time.Now()
+	a.mu.Lock()
+	if d, ok := a.cache[key]; ok && now.Before(d.expireAt) {
+		a.mu.Unlock()
+		return d.authzResult, nil
+	}
+	a.mu.Unlock()
+	result, err := a.query(ctx, body)
+	if err != nil || a.cacheTTL == 0 {
+		return result, err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.cache) >= maxAuthorizerCacheSize {
+		for k, d := range a.cache {
+			if !now.Before(d.expireAt) {
+				delete(a.cache, k)
+			}
+		}
+		if len(a.cache) >= maxAuthorizerCacheSize {
+			a.cache = make(map[string]authzDecision)
+		}
+	}
+	a.cache[key] = authzDecision{authzResult: result, expireAt: now.Add(a.cacheTTL)}
+	return result, nil
+}
+
+func (a *authorizer) query(ctx context.Context, body []byte) (authzResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return authzResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return authzResult{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return authzResult{}, errors.Errorf("the authorizer responds %s", resp.Status)
+	}
+	var decision struct {
+		Result *authzResult `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decision); err != nil {
+		return authzResult{}, errors.Wrap(err, "invalid authorizer response")
+	}
+	if decision.Result == nil {
+		// OPA omits the result if the policy is undefined
+		return authzResult{Reason: "the policy is undefined"}, nil
+	}
+	return *decision.Result, nil
+}
+
+// requestGroup finds the group targeted by req, which is the one of the metadata,
+// the group field or the group schema in req or its direct fields.
+func requestGroup(req interface{}) string {
+	m, ok := req.(proto.Message)
+	if !ok {
+		return ""
+	}
+	msg := m.ProtoReflect()
+	if g := messageGroup(msg); g != "" {
+		return g
+	}
+	var result string
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() {
+			return true
+		}
+		result = messageGroup(v.Message())
+		return result == ""
+	})
+	return result
+}
+
+// messageGroup finds the group of msg, which is absent if msg has neither the metadata nor the group.
+func messageGroup(msg protoreflect.Message) string {
+	switch msg.Descriptor().FullName() {
+	case metadataFullName:
+		return msg.Interface().(*commonv1.Metadata).GetGroup()
+	case groupFullName:
+		return msg.Interface().(*commonv1.Group).GetMetadata().GetName()
+	}
+	var result string
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.Name() == "group" && fd.Kind() == protoreflect.StringKind && !fd.IsList():
+			result = v.String()
+		case fd.Name() == "metadata" && fd.Kind() == protoreflect.MessageKind && fd.Message().FullName() == metadataFullName:
+			result = v.Message().Interface().(*commonv1.Metadata).GetGroup()
+		}
+		return result == ""
+	})
+	return result
+}
+
+// tagFamilyQuery is a query whose denied tag families are stripped from its projections.
+// The families filtering or grouping the data can't be stripped, so the query is denied if any of them is.
+// A subscription streams all the tag families of the written data and filters them by any tag,
+// so it's denied if any family is.
+type tagFamilyQuery struct {
+	md          *commonv1.Metadata
+	criteria    *modelv1.Criteria
+	groupBy     *modelv1.TagProjection
+	projections []*modelv1.TagProjection
+	// filtering are the tag families of the tags referred by the criteria
+	filtering    map[string]struct{}
+	kind         schema.Kind
+	subscription bool
+}
+
+func newTagFamilyQuery(req interface{}) *tagFamilyQuery {
+	switch r := req.(type) {
+	case *streamv1.QueryRequest:
+		return &tagFamilyQuery{
+			kind:        schema.KindStream,
+			md:          r.GetMetadata(),
+			criteria:    r.GetCriteria(),
+			projections: []*modelv1.TagProjection{r.GetProjection()},
+		}
+	case *measurev1.QueryRequest:
+		return &tagFamilyQuery{
+			kind:        schema.KindMeasure,
+			md:          r.GetMetadata(),
+			criteria:    r.GetCriteria(),
+			groupBy:     r.GetGroupBy().GetTagProjection(),
+			projections: []*modelv1.TagProjection{r.GetTagProjection()},
+		}
+	case *streamv1.SubscribeRequest:
+		return &tagFamilyQuery{kind: schema.KindStream, md: r.GetMetadata(), subscription: true}
+	case *measurev1.SubscribeRequest:
+		return &tagFamilyQuery{kind: schema.KindMeasure, md: r.GetMetadata(), subscription: true}
+	}
+	return nil
+}
+
+// tagFamilies returns the sorted tag families of the query. The tags of the criteria are found by the schema,
+// and the ones absent from the schema are left to the query to reject.
+// The ones of a subscription are all the tag families of the schema.
+func (q *tagFamilyQuery) tagFamilies(ctx context.Context,
+	tags func(ctx context.Context, kind schema.Kind, md *commonv1.Metadata) (*resourceTags, bool),
+) []string {
+	families := make(map[string]struct{})
+	if q.subscription {
+		if rt, ok := tags(ctx, q.kind, q.md); ok {
+			for _, f := range rt.families {
+				families[f] = struct{}{}
+			}
+		}
+	}
+	for _, p := range append(q.projections, q.groupBy) {
+		for _, f := range p.GetTagFamilies() {
+			families[f.GetName()] = struct{}{}
+		}
+	}
+	q.filtering = q.filtered(ctx, tags)
+	for f := range q.filtering {
+		families[f] = struct{}{}
+	}
+	result := make([]string, 0, len(families))
+	for f := range families {
+		result = append(result, f)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// filtered returns the tag families of the tags referred by the criteria.
+func (q *tagFamilyQuery) filtered(ctx context.Context,
+	tags func(ctx context.Context, kind schema.Kind, md *commonv1.Metadata) (*resourceTags, bool),
+) map[string]struct{} {
+	families := make(map[string]struct{})
+	if q.criteria == nil {
+		return families
+	}
+	rt, ok := tags(ctx, q.kind, q.md)
+	if !ok {
+		return families
+	}
+	var walk func(c *modelv1.Criteria)
+	walk = func(c *modelv1.Criteria) {
+		if c == nil || c.GetExp() == nil {
+			return
+		}
+		if cond := c.GetCondition(); cond != nil {
+			if f, ok := rt.families[cond.GetName()]; ok {
+				families[f] = struct{}{}
+			}
+			return
+		}
+		walk(c.GetLe().GetLeft())
+		walk(c.GetLe().GetRight())
+	}
+	walk(q.criteria)
+	return families
+}
+
+// strip removes the denied tag families from the projections of the query.
+func (q *tagFamilyQuery) strip(denied []string) error {
+	if q.subscription {
+		return status.Errorf(codes.PermissionDenied, "the subscription streams the tag family %s denied by the authorizer", denied[0])
+	}
+	isDenied := make(map[string]bool, len(denied))
+	for _, f := range denied {
+		isDenied[f] = true
+	}
+	for _, f := range q.groupBy.GetTagFamilies() {
+		if isDenied[f.GetName()] {
+			return status.Errorf(codes.PermissionDenied, "the tag family %s grouping the data is denied by the authorizer", f.GetName())
+		}
+	}
+	for _, f := range denied {
+		if _, ok := q.filtering[f]; ok {
+			return status.Errorf(codes.PermissionDenied, "the tag family %s filtering the data is denied by the authorizer", f)
+		}
+	}
+	for _, p := range q.projections {
+		if p == nil || len(p.GetTagFamilies()) == 0 {
+			continue
+		}
+		kept := p.TagFamilies[:0]
+		for _, f := range p.GetTagFamilies() {
+			if !isDenied[f.GetName()] {
+				kept = append(kept, f)
+			}
+		}
+		if len(kept) == 0 {
+			return status.Error(codes.PermissionDenied, "all the projected tag families are denied by the authorizer")
+		}
+		p.TagFamilies = kept
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+var _ = Describe("Authorizer", func() {
+	const method = "/banyandb.stream.v1.StreamService/Query"
+	var webhook *httptest.Server
+	var requests atomic.Int32
+	var families atomic.Value
+	var a *authorizer
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer alice"))
+	query := func(group string) *streamv1.QueryRequest {
+		return &streamv1.QueryRequest{Metadata: &commonv1.Metadata{Group: group, Name: "sw"}}
+	}
+	BeforeEach(func() {
+		requests.Store(0)
+		webhook = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			var body struct {
+				Input authzInput `json:"input"`
+			}
+			Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+			Expect(body.Input.Token).To(Equal("alice"))
+			Expect(body.Input.Method).To(Equal(method))
+			families.Store(body.Input.TagFamilies)
+			switch body.Input.Group {
+			case "allowed":
+				_, _ = w.Write([]byte(`{"result": true}`))
+			case "denied":
+				_, _ = w.Write([]byte(`{"result": {"allow": false, "reason": "read only"}}`))
+			case "masked":
+				_, _ = w.Write([]byte(`{"result": {"allow": true, "denied_tag_families": ["pii"]}}`))
+			case "undefined":
+				_, _ = w.Write([]byte(`{}`))
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+		a = &authorizer{
+			log:      logger.GetLogger("test"),
+			url:      webhook.URL,
+			timeout:  time.Second,
+			cacheTTL: time.Minute,
+		}
+		Expect(a.validate()).To(Succeed())
+	})
+	AfterEach(func() {
+		webhook.Close()
+	})
+	It("allows the calls by the decisions", func() {
+		Expect(a.authorize(ctx, method, query("allowed"))).To(Succeed())
+		err := a.authorize(ctx, method, query("denied"))
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+		Expect(err.Error()).To(ContainSubstring("read only"))
+		Expect(status.Code(a.authorize(ctx, method, query("undefined")))).To(Equal(codes.PermissionDenied))
+	})
+	It("caches the decisions", func() {
+		for i := 0; i < 3; i++ {
+			Expect(a.authorize(ctx, method, query("allowed"))).To(Succeed())
+		}
+		Expect(requests.Load()).To(Equal(int32(1)))
+		a.cacheTTL = 0
+		Expect(a.authorize(ctx, method, query("denied"))).NotTo(Succeed())
+		Expect(a.authorize(ctx, method, query("denied"))).NotTo(Succeed())
+		Expect(requests.Load()).To(Equal(int32(3)))
+	})
+	It("fails closed by default", func() {
+		Expect(status.Code(a.authorize(ctx, method, query("unavailable")))).To(Equal(codes.PermissionDenied))
+		a.failOpen = true
+		Expect(a.authorize(ctx, method, query("unavailable"))).To(Succeed())
+	})
+	It("finds the groups of the requests", func() {
+		Expect(requestGroup(query("sw_stream"))).To(Equal("sw_stream"))
+		Expect(requestGroup(&databasev1.GroupRegistryServiceGetRequest{Group: "sw_metric"})).To(Equal("sw_metric"))
+		Expect(requestGroup(&databasev1.GroupRegistryServiceCreateRequest{
+			Group: &commonv1.Group{Metadata: &commonv1.Metadata{Name: "sw_record"}},
+		})).To(Equal("sw_record"))
+		Expect(requestGroup(&databasev1.StreamRegistryServiceCreateRequest{
+			Stream: &databasev1.Stream{Metadata: &commonv1.Metadata{Group: "default", Name: "sw"}},
+		})).To(Equal("default"))
+		Expect(requestGroup(&databasev1.ServerInfoServiceGetRequest{})).To(BeEmpty())
+	})
+	It("strips the denied tag families of the queries", func() {
+		a.tags = func(context.Context, schema.Kind, *commonv1.Metadata) (*resourceTags, bool) {
+			return &resourceTags{families: map[string]string{"endpoint": "searchable", "params": "pii"}}, true
+		}
+		projection := func(families ...string) *modelv1.TagProjection {
+			p := &modelv1.TagProjection{}
+			for _, f := range families {
+				p.TagFamilies = append(p.TagFamilies, &modelv1.TagProjection_TagFamily{Name: f, Tags: []string{"tag"}})
+			}
+			return p
+		}
+		condition := func(tag string) *modelv1.Criteria {
+			return &modelv1.Criteria{Exp: &modelv1.Criteria_Condition{Condition: &modelv1.Condition{Name: tag}}}
+		}
+		req := query("masked")
+		req.Projection = projection("searchable", "pii")
+		req.Criteria = condition("endpoint")
+		Expect(a.authorize(ctx, method, req)).To(Succeed())
+		Expect(families.Load()).To(Equal([]string{"pii", "searchable"}))
+		Expect(req.GetProjection()).To(Equal(projection("searchable")))
+
+		req = query("masked")
+		req.Projection = projection("pii")
+		Expect(status.Code(a.authorize(ctx, method, req))).To(Equal(codes.PermissionDenied))
+		req = query("masked")
+		req.Projection = projection("searchable")
+		req.Criteria = &modelv1.Criteria{Exp: &modelv1.Criteria_Le{Le: &modelv1.LogicalExpression{
+			Op:    modelv1.LogicalExpression_LOGICAL_OP_AND,
+			Left:  condition("endpoint"),
+			Right: condition("params"),
+		}}}
+		err := a.authorize(ctx, method, req)
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+		Expect(err.Error()).To(ContainSubstring("the tag family pii filtering the data"))
+		err = a.authorize(ctx, method, &measurev1.QueryRequest{
+			Metadata:      &commonv1.Metadata{Group: "masked", Name: "service_cpm_minute"},
+			TagProjection: projection("searchable"),
+			GroupBy:       &measurev1.QueryRequest_GroupBy{TagProjection: projection("pii")},
+		})
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+		Expect(err.Error()).To(ContainSubstring("the tag family pii grouping the data"))
+
+		req = query("masked")
+		req.Projection = projection("searchable", "pii")
+		req.Criteria = &modelv1.Criteria{}
+		Expect(a.authorize(ctx, method, req)).To(Succeed())
+		Expect(req.GetProjection()).To(Equal(projection("searchable")))
+		req = query("masked")
+		req.Projection = projection("searchable")
+		req.Criteria = &modelv1.Criteria{Exp: &modelv1.Criteria_Le{Le: &modelv1.LogicalExpression{
+			Op:   modelv1.LogicalExpression_LOGICAL_OP_AND,
+			Left: condition("endpoint"),
+		}}}
+		Expect(a.authorize(ctx, method, req)).To(Succeed())
+		Expect(families.Load()).To(Equal([]string{"searchable"}))
+	})
+	It("denies the subscriptions streaming the denied tag families", func() {
+		a.tags = func(context.Context, schema.Kind, *commonv1.Metadata) (*resourceTags, bool) {
+			return &resourceTags{families: map[string]string{"endpoint": "searchable", "params": "pii"}}, true
+		}
+		err := a.authorize(ctx, method, &streamv1.SubscribeRequest{Metadata: &commonv1.Metadata{Group: "masked", Name: "sw"}})
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+		Expect(err.Error()).To(ContainSubstring("the subscription streams the tag family pii"))
+		Expect(families.Load()).To(Equal([]string{"pii", "searchable"}))
+		err = a.authorize(ctx, method, &measurev1.SubscribeRequest{Metadata: &commonv1.Metadata{Group: "masked", Name: "service_cpm_minute"}})
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+		Expect(a.authorize(ctx, method, &streamv1.SubscribeRequest{Metadata: &commonv1.Metadata{Group: "allowed", Name: "sw"}})).To(Succeed())
+	})
+	It("rejects the invalid urls", func() {
+		Expect((&authorizer{url: "localhost:8181", timeout: time.Second}).validate()).To(MatchError(errInvalidAuthorizerURL))
+	})
+})
@@ -0,0 +1,220 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+)
+
+// batchPolicy bounds the size of the batches sent by the query RPCs, which adapts to the back-pressure of the client.
+// A batch exceeds the max size by its last message only.
+type batchPolicy struct {
+	minBytes int
+	maxBytes int
+	latency  time.Duration
+}
+
+func (p *batchPolicy) validate() error {
+	if p.minBytes <= 0 || p.maxBytes < p.minBytes || p.latency <= 0 {
+		return errInvalidBatchPolicy
+	}
+	return nil
+}
+
+// batchedQuery is a stream or a measure query whose results are sent in batches.
+type batchedQuery interface {
+	GetContinuationToken() string
+	GetSnapshot() bool
+	GetSnapshotToken() string
+	GetReadTimestamp() *timestamppb.Timestamp
+	GetLimits() *modelv1.QueryLimits
+}
+
+// streamable tells whether the results of the query are sent as they're produced by the executor of the local node.
+// The others are sent once they're complete, since the pages and the snapshots are cut from the captured results,
+// and the results of the data nodes are merged.
+func streamable(req batchedQuery, placement map[common.ShardID][]*databasev1.Node) bool {
+	return allLocal(placement) && req.GetContinuationToken() == "" && req.GetSnapshotToken() == "" &&
+		!snapshotRequested(req.GetSnapshot(), req.GetReadTimestamp()) && !req.GetLimits().GetTruncate()
+}
+
+// batcher buffers the messages produced by a query and sends them in batches by a goroutine,
+// so that the query keeps producing the messages while a batch is being sent.
+// A batch is sent once it reaches the batch size, or its first message has waited for the latency target.
+//
+// The batch size adapts to the flow control of the stream, which blocks the sends once the client can't keep up:
+// it doubles if a whole batch is buffered during a send, and halves otherwise.
+// The query is blocked once the buffered messages reach the max size until the pending send completes.
+type batcher[T proto.Message] struct {
+	policy *batchPolicy
+	send   func([]T) error
+	// wake signals the sender about the first message of a batch, the full batch or the end of the results
+	wake chan struct{}
+	// stopped is closed once the sender exits
+	stopped chan struct{}
+
+	mu sync.Mutex
+	// taken signals the query blocked by the full buffer once the sender takes the batch
+	taken *sync.Cond
+	buf   []T
+	bytes int
+	first time.Time
+	size  int
+	// closed stops the sender once the buffered messages are sent, or at once if aborted
+	closed  bool
+	aborted bool
+	sent    bool
+	err     error
+}
+
+func newBatcher[T proto.Message](policy *batchPolicy, send func([]T) error) *batcher[T] {
+	b := &batcher[T]{
+		policy:  policy,
+		send:    send,
+		wake:    make(chan struct{}, 1),
+		stopped: make(chan struct{}),
+		size:    policy.minBytes,
+	}
+	b.taken = sync.NewCond(&b.mu)
+	go b.run()
+	return b
+}
+
+// add buffers the message, which blocks while the buffer is full. It returns the error failing the sender.
+func (b *batcher[T]) add(message T) error {
+	b.mu.Lock()
+	for b.err == nil && !b.aborted && b.bytes >= b.policy.maxBytes {
+		b.taken.Wait()
+	}
+	if b.err != nil || b.aborted {
+		err := b.err
+		b.mu.Unlock()
+		if err == nil {
+			err = errBatcherClosed
+		}
+		return err
+	}
+	b.buf = append(b.buf, message)
+	b.bytes += proto.Size(message)
+	notify := len(b.buf) == 1 || b.bytes >= b.size
+	if len(b.buf) == 1 {
+		b.first = time.Now()
+	}
+	b.mu.Unlock()
+	if notify {
+		b.notify()
+	}
+	return nil
+}
+
+// close stops the sender. The buffered messages are sent before if flush is true,
+// and an empty batch is sent if nothing has been sent, so that the client always receives a response.
+func (b *batcher[T]) close(flush bool) error {
+	b.mu.Lock()
+	b.closed = true
+	b.aborted = b.aborted || !flush
+	b.taken.Broadcast()
+	b.mu.Unlock()
+	b.notify()
+	<-b.stopped
+	return b.err
+}
+
+func (b *batcher[T]) notify() {
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (b *batcher[T]) run() {
+	defer close(b.stopped)
+	for {
+		batch, ok := b.next()
+		if !ok {
+			return
+		}
+		err := b.send(batch)
+		b.mu.Lock()
+		b.sent = true
+		if err != nil {
+			b.err = err
+			b.taken.Broadcast()
+			b.mu.Unlock()
+			return
+		}
+		b.adapt(b.bytes >= b.size)
+		b.mu.Unlock()
+	}
+}
+
+// next waits for the next batch to send. It returns false once the batcher is closed and nothing is left to send.
+func (b *batcher[T]) next() ([]T, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for {
+		if b.aborted || (b.closed && len(b.buf) < 1 && b.sent) {
+			return nil, false
+		}
+		if b.closed || b.bytes >= b.size {
+			break
+		}
+		wait := time.Hour
+		if len(b.buf) > 0 {
+			if wait = time.Until(b.first.Add(b.policy.latency)); wait <= 0 {
+				break
+			}
+		}
+		timer := time.NewTimer(wait)
+		b.mu.Unlock()
+		select {
+		case <-b.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+		b.mu.Lock()
+	}
+	batch := b.buf
+	b.buf, b.bytes = nil, 0
+	b.taken.Broadcast()
+	return batch, true
+}
+
+// adapt grows the batch size if the client is slower than the query, which makes a whole batch buffered during a send.
+func (b *batcher[T]) adapt(backPressured bool) {
+	switch {
+	case backPressured && b.size < b.policy.maxBytes:
+		b.size *= 2
+		if b.size > b.policy.maxBytes {
+			b.size = b.policy.maxBytes
+		}
+	case !backPressured && b.size > b.policy.minBytes:
+		b.size /= 2
+		if b.size < b.policy.minBytes {
+			b.size = b.policy.minBytes
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/protobuf/proto"
+
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+)
+
+var _ = Describe("Batcher", func() {
+	element := &streamv1.Element{ElementId: strings.Repeat("e", 90)}
+	size := proto.Size(element)
+	policy := &batchPolicy{minBytes: 2 * size, maxBytes: 8 * size, latency: 100 * time.Millisecond}
+	var started chan int
+	var release chan struct{}
+	var released bool
+	var sendErr error
+	var b *batcher[*streamv1.Element]
+	BeforeEach(func() {
+		started, release, released, sendErr = make(chan int, 16), make(chan struct{}), false, nil
+		b = newBatcher(policy, func(elements []*streamv1.Element) error {
+			started <- len(elements)
+			<-release
+			return sendErr
+		})
+	})
+	AfterEach(func() {
+		if !released {
+			close(release)
+		}
+		_ = b.close(false)
+	})
+	releaseAll := func() {
+		close(release)
+		released = true
+	}
+	add := func(n int) {
+		for i := 0; i < n; i++ {
+			Expect(b.add(element)).To(Succeed())
+		}
+	}
+	batchSize := func() int {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		return b.size
+	}
+	It("grows the batches buffered while the client blocks the sends", func() {
+		add(2)
+		Eventually(started).Should(Receive(Equal(2)))
+		add(8)
+		release <- struct{}{}
+		Eventually(started).Should(Receive(Equal(8)))
+		Expect(batchSize()).To(Equal(2 * policy.minBytes))
+		release <- struct{}{}
+		Expect(b.close(true)).To(Succeed())
+		Expect(batchSize()).To(Equal(policy.minBytes))
+	})
+	It("blocks the query once the buffer is full", func() {
+		add(2)
+		Eventually(started).Should(Receive(Equal(2)))
+		add(8)
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(b.add(element)).To(Succeed())
+		}()
+		Consistently(done, 200*time.Millisecond).ShouldNot(BeClosed())
+		releaseAll()
+		Eventually(done).Should(BeClosed())
+		Expect(b.close(true)).To(Succeed())
+		Expect(started).To(Receive(Equal(8)))
+		Expect(started).To(Receive(Equal(1)))
+	})
+	It("sends the messages waiting for the latency target", func() {
+		releaseAll()
+		add(1)
+		Consistently(started, policy.latency/2).ShouldNot(Receive())
+		Eventually(started).Should(Receive(Equal(1)))
+	})
+	It("sends an empty batch if nothing is sent", func() {
+		releaseAll()
+		Expect(b.close(true)).To(Succeed())
+		Expect(started).To(Receive(Equal(0)))
+		Expect(started).NotTo(Receive())
+	})
+	It("fails the query once a send fails", func() {
+		sendErr = errors.New("broken stream")
+		releaseAll()
+		add(2)
+		Eventually(func() error { return b.add(element) }).Should(MatchError(sendErr))
+		Expect(b.close(true)).To(MatchError(sendErr))
+	})
+})
@@ -0,0 +1,322 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/api/data"
+	clusterv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/cluster/v1"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	"github.com/apache/skywalking-banyandb/banyand/metadata"
+	"github.com/apache/skywalking-banyandb/banyand/queue"
+	"github.com/apache/skywalking-banyandb/banyand/tsdb"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/throttle"
+)
+
+const (
+	// defaultBlockChunkSize is the size of the chunks if the request doesn't set it
+	defaultBlockChunkSize = 1 << 20
+	// maxBlockChunkSize keeps the chunks under the default max size of the received messages
+	maxBlockChunkSize = 2 << 20
+)
+
+var (
+	errBlockChunkCorrupted = errors.New("the chunk of the block is corrupted")
+	errBlockFileMismatched = errors.New("the file of the block doesn't match the header")
+
+	castagnoli = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// blockExportServer streams the files of the local sealed blocks to the other nodes and the tools.
+type blockExportServer struct {
+	clusterv1.UnimplementedInternalBlockServiceServer
+	schemaRegistry metadata.Service
+	pipeline       queue.Queue
+}
+
+func (s *blockExportServer) ExportBlock(req *clusterv1.ExportBlockRequest, stream clusterv1.InternalBlockService_ExportBlockServer) error {
+	if req.GetGroup() == "" || req.GetSegment() == "" || req.GetBlock() == "" {
+		return status.Error(codes.InvalidArgument, "the group, the segment and the block should be present")
+	}
+	chunkSize := int(req.GetChunkSize())
+	if chunkSize == 0 {
+		chunkSize = defaultBlockChunkSize
+	}
+	if chunkSize > maxBlockChunkSize {
+		chunkSize = maxBlockChunkSize
+	}
+	export, err := s.export(stream.Context(), req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = export.Release()
+	}()
+	return sendBlock(stream, req.GetGroup(), export, chunkSize)
+}
+
+// export takes a snapshot of the block by the storage of the catalog of the group.
+func (s *blockExportServer) export(ctx context.Context, req *clusterv1.ExportBlockRequest) (*tsdb.BlockExport, error) {
+	g, err := s.schemaRegistry.GroupRegistry().GetGroup(ctx, req.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	var topic bus.Topic
+	switch g.GetCatalog() {
+	case commonv1.Catalog_CATALOG_STREAM:
+		topic = data.TopicStreamBlockExport
+	case commonv1.Catalog_CATALOG_MEASURE:
+		topic = data.TopicMeasureBlockExport
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "the group %s of the catalog %s has no data", req.GetGroup(), g.GetCatalog())
+	}
+	feat, err := s.pipeline.Publish(topic, bus.NewMessage(bus.MessageID(time.Now().UnixNano()), req))
+	if err != nil {
+		return nil, err
+	}
+	msg, err := feat.Get()
+	if err != nil {
+		return nil, err
+	}
+	switch d := msg.Data().(type) {
+	case *tsdb.BlockExport:
+		return d, nil
+	case common.Error:
+		return nil, errors.WithMessage(ErrBlockExportMsg, d.Msg())
+	}
+	return nil, ErrBlockExportMsg
+}
+
+// sendBlock sends the header of the exported block, and then the chunks of its files one by one.
+func sendBlock(stream clusterv1.InternalBlockService_ExportBlockServer, group string, export *tsdb.BlockExport, chunkSize int) error {
+	header := &clusterv1.BlockHeader{
+		Group:    group,
+		ShardId:  export.Shard,
+		Segment:  export.Segment,
+		Block:    export.Manifest.Name,
+		Begin:    timestamppb.New(export.Manifest.Begin),
+		End:      timestamppb.New(export.Manifest.End),
+		Checksum: export.Manifest.Checksum,
+	}
+	for _, f := range export.Files {
+		header.Files = append(header.Files, &clusterv1.BlockFile{Name: f.Name, Size: f.Size, Checksum: f.Checksum})
+	}
+	if err := stream.Send(&clusterv1.ExportBlockResponse{Item: &clusterv1.ExportBlockResponse_Header{Header: header}}); err != nil {
+		return err
+	}
+	buf := make([]byte, chunkSize)
+	for _, f := range export.Files {
+		if err := sendBlockFile(stream, filepath.Join(export.Path, filepath.FromSlash(f.Name)), f.Name, buf, export.Throttle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sendBlockFile(stream clusterv1.InternalBlockService_ExportBlockServer, path, name string, buf []byte, t *throttle.Throttle) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var offset int64
+	for {
+		n, err := io.ReadFull(throttle.NewReader(f, t), buf)
+		if n > 0 {
+			chunk := &clusterv1.BlockChunk{
+				Name:   name,
+				Offset: offset,
+				Data:   buf[:n],
+				Crc32:  crc32.Checksum(buf[:n], castagnoli),
+			}
+			if errSend := stream.Send(&clusterv1.ExportBlockResponse{Item: &clusterv1.ExportBlockResponse_Chunk{Chunk: chunk}}); errSend != nil {
+				return errSend
+			}
+			offset += int64(n)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// FetchBlock copies the files of the sealed block exported by the node of the client to the directory dir,
+// which is the copy path shared by the replication, the repair and the backup tools.
+// The chunks, the files and the set of the files are verified against the checksums sent by the node,
+// and dir is removed if the copy fails. It returns the header of the block, whose checksum matches the manifest of its segment.
+func FetchBlock(ctx context.Context, client clusterv1.InternalBlockServiceClient, req *clusterv1.ExportBlockRequest, dir string) (*clusterv1.BlockHeader, error) {
+	stream, err := client.ExportBlock(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	header, err := receiveBlock(stream, dir)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+	return header, nil
+}
+
+type receivingFile struct {
+	out    *os.File
+	digest hash.Hash
+	meta   *clusterv1.BlockFile
+	offset int64
+}
+
+func receiveBlock(stream clusterv1.InternalBlockService_ExportBlockClient, dir string) (*clusterv1.BlockHeader, error) {
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	header := resp.GetHeader()
+	if header == nil {
+		return nil, errors.WithMessage(errBlockFileMismatched, "the header is absent")
+	}
+	if err = os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	files := make(map[string]*clusterv1.BlockFile, len(header.GetFiles()))
+	for _, f := range header.GetFiles() {
+		if !localName(f.GetName()) {
+			return nil, errors.WithMessagef(errBlockFileMismatched, "the name %s is out of the block", f.GetName())
+		}
+		files[f.GetName()] = f
+	}
+	var current *receivingFile
+	done := make(map[string]bool, len(files))
+	for {
+		resp, err = stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			closeReceiving(current)
+			return nil, err
+		}
+		chunk := resp.GetChunk()
+		if chunk == nil {
+			closeReceiving(current)
+			return nil, errors.WithMessage(errBlockFileMismatched, "the header is sent twice")
+		}
+		if crc32.Checksum(chunk.GetData(), castagnoli) != chunk.GetCrc32() {
+			closeReceiving(current)
+			return nil, errors.WithMessagef(errBlockChunkCorrupted, "%s at %d", chunk.GetName(), chunk.GetOffset())
+		}
+		if current == nil || current.meta.GetName() != chunk.GetName() {
+			if err = finishReceiving(current, done); err != nil {
+				return nil, err
+			}
+			if current, err = startReceiving(dir, files, done, chunk.GetName()); err != nil {
+				return nil, err
+			}
+		}
+		if chunk.GetOffset() != current.offset {
+			closeReceiving(current)
+			return nil, errors.WithMessagef(errBlockChunkCorrupted, "%s is expected at %d instead of %d", chunk.GetName(), current.offset, chunk.GetOffset())
+		}
+		if _, err = io.MultiWriter(current.out, current.digest).Write(chunk.GetData()); err != nil {
+			closeReceiving(current)
+			return nil, err
+		}
+		current.offset += int64(len(chunk.GetData()))
+	}
+	if err = finishReceiving(current, done); err != nil {
+		return nil, err
+	}
+	for name := range files {
+		if done[name] {
+			continue
+		}
+		// the empty files have no chunks
+		if current, err = startReceiving(dir, files, done, name); err != nil {
+			return nil, err
+		}
+		if err = finishReceiving(current, done); err != nil {
+			return nil, err
+		}
+	}
+	return header, nil
+}
+
+func localName(name string) bool {
+	cleaned := filepath.Clean(filepath.FromSlash(name))
+	return name != "" && !filepath.IsAbs(cleaned) && cleaned != ".." && !strings.HasPrefix(cleaned, ".."+string(filepath.Separator))
+}
+
+func startReceiving(dir string, files map[string]*clusterv1.BlockFile, done map[string]bool, name string) (*receivingFile, error) {
+	meta, ok := files[name]
+	if !ok || done[name] {
+		return nil, errors.WithMessagef(errBlockFileMismatched, "%s is unexpected", name)
+	}
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &receivingFile{out: out, digest: sha256.New(), meta: meta}, nil
+}
+
+// finishReceiving syncs the file, and checks its size and checksum.
+func finishReceiving(f *receivingFile, done map[string]bool) error {
+	if f == nil {
+		return nil
+	}
+	err := f.out.Sync()
+	if errClose := f.out.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return err
+	}
+	checksum := hex.EncodeToString(f.digest.Sum(nil))
+	if f.offset != f.meta.GetSize() || checksum != f.meta.GetChecksum() {
+		return errors.WithMessagef(errBlockFileMismatched, "%s has %d bytes of the checksum %s", f.meta.GetName(), f.offset, checksum)
+	}
+	done[f.meta.GetName()] = true
+	return nil
+}
+
+func closeReceiving(f *receivingFile) {
+	if f != nil {
+		_ = f.out.Close()
+	}
+}
@@ -0,0 +1,141 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	clusterv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/cluster/v1"
+	"github.com/apache/skywalking-banyandb/banyand/tsdb"
+)
+
+// fakeBlockNode exports the files of a directory as a block.
+type fakeBlockNode struct {
+	clusterv1.UnimplementedInternalBlockServiceServer
+	export *tsdb.BlockExport
+	// corrupt flips the first byte of the chunks after computing their checksums
+	corrupt bool
+}
+
+type corruptingStream struct {
+	clusterv1.InternalBlockService_ExportBlockServer
+}
+
+func (s *corruptingStream) Send(resp *clusterv1.ExportBlockResponse) error {
+	if chunk := resp.GetChunk(); chunk != nil {
+		chunk.Data[0] ^= 0xff
+	}
+	return s.InternalBlockService_ExportBlockServer.Send(resp)
+}
+
+func (n *fakeBlockNode) ExportBlock(req *clusterv1.ExportBlockRequest, stream clusterv1.InternalBlockService_ExportBlockServer) error {
+	if n.corrupt {
+		stream = &corruptingStream{stream}
+	}
+	return sendBlock(stream, req.GetGroup(), n.export, int(req.GetChunkSize()))
+}
+
+var _ = Describe("Block export", func() {
+	var source, target string
+	var node *fakeBlockNode
+	var client clusterv1.InternalBlockServiceClient
+	var stop func()
+	file := func(name, content string) tsdb.ExportedFile {
+		path := filepath.Join(source, filepath.FromSlash(name))
+		Expect(os.MkdirAll(filepath.Dir(path), 0o700)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+		digest := sha256.Sum256([]byte(content))
+		return tsdb.ExportedFile{Name: name, Size: int64(len(content)), Checksum: hex.EncodeToString(digest[:])}
+	}
+	BeforeEach(func() {
+		source = GinkgoT().TempDir()
+		target = filepath.Join(GinkgoT().TempDir(), "block-2006010215")
+		node = &fakeBlockNode{export: &tsdb.BlockExport{
+			Path:     source,
+			Segment:  "20060102",
+			Shard:    1,
+			Manifest: tsdb.BlockManifest{Name: "block-2006010215", Checksum: "checksum", Sealed: true},
+		}}
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		ser := grpclib.NewServer()
+		clusterv1.RegisterInternalBlockServiceServer(ser, node)
+		go func() {
+			_ = ser.Serve(lis)
+		}()
+		conn, err := grpclib.Dial(lis.Addr().String(), grpclib.WithTransportCredentials(insecure.NewCredentials()))
+		Expect(err).NotTo(HaveOccurred())
+		client = clusterv1.NewInternalBlockServiceClient(conn)
+		stop = func() {
+			_ = conn.Close()
+			ser.Stop()
+		}
+	})
+	AfterEach(func() {
+		stop()
+	})
+	req := &clusterv1.ExportBlockRequest{Group: "sw", ShardId: 1, Segment: "20060102", Block: "block-2006010215", ChunkSize: 4}
+
+	It("copies the files of the block", func() {
+		node.export.Files = []tsdb.ExportedFile{file("000001.sst", "the sorted table"), file("empty", ""), file("idx/seg.zap", "index")}
+		header, err := FetchBlock(context.Background(), client, req, target)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(header.GetGroup()).To(Equal("sw"))
+		Expect(header.GetShardId()).To(Equal(uint32(1)))
+		Expect(header.GetChecksum()).To(Equal("checksum"))
+		Expect(header.GetFiles()).To(HaveLen(3))
+		for name, content := range map[string]string{"000001.sst": "the sorted table", "empty": "", "idx/seg.zap": "index"} {
+			data, errRead := os.ReadFile(filepath.Join(target, filepath.FromSlash(name)))
+			Expect(errRead).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal(content))
+		}
+	})
+
+	It("rejects the corrupted chunks", func() {
+		node.export.Files = []tsdb.ExportedFile{file("000001.sst", "the sorted table")}
+		node.corrupt = true
+		_, err := FetchBlock(context.Background(), client, req, target)
+		Expect(err).To(MatchError(ContainSubstring(errBlockChunkCorrupted.Error())))
+		Expect(target).NotTo(BeADirectory())
+	})
+
+	It("rejects the files not matching the header", func() {
+		f := file("000001.sst", "the sorted table")
+		f.Checksum = "mismatched"
+		node.export.Files = []tsdb.ExportedFile{f}
+		_, err := FetchBlock(context.Background(), client, req, target)
+		Expect(err).To(MatchError(ContainSubstring(errBlockFileMismatched.Error())))
+		Expect(target).NotTo(BeADirectory())
+	})
+
+	It("rejects the files out of the block", func() {
+		node.export.Files = []tsdb.ExportedFile{{Name: "../escaped", Size: 0}}
+		_, err := FetchBlock(context.Background(), client, req, target)
+		Expect(err).To(MatchError(ContainSubstring(errBlockFileMismatched.Error())))
+	})
+})
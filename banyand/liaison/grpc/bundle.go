@@ -0,0 +1,309 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/banyand/metadata"
+	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
+)
+
+func (rs *groupRegistryServer) Export(ctx context.Context,
+	req *databasev1.GroupRegistryServiceExportRequest,
+) (*databasev1.GroupRegistryServiceExportResponse, error) {
+	g, err := rs.schemaRegistry.GroupRegistry().GetGroup(ctx, req.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	bundle := &databasev1.SchemaBundle{Group: g}
+	opt := schema.ListOpt{Group: req.GetGroup()}
+	if bundle.IndexRules, err = rs.schemaRegistry.IndexRuleRegistry().ListIndexRule(ctx, opt); err != nil {
+		return nil, err
+	}
+	if bundle.IndexRuleBindings, err = rs.schemaRegistry.IndexRuleBindingRegistry().ListIndexRuleBinding(ctx, opt); err != nil {
+		return nil, err
+	}
+	if bundle.Streams, err = rs.schemaRegistry.StreamRegistry().ListStream(ctx, opt); err != nil {
+		return nil, err
+	}
+	if bundle.Measures, err = rs.schemaRegistry.MeasureRegistry().ListMeasure(ctx, opt); err != nil {
+		return nil, err
+	}
+	if bundle.TopNAggregations, err = rs.schemaRegistry.TopNAggregationRegistry().ListTopNAggregation(ctx, opt); err != nil {
+		return nil, err
+	}
+	clearReadonly(g)
+	for _, ir := range bundle.IndexRules {
+		clearReadonly(ir)
+	}
+	for _, irb := range bundle.IndexRuleBindings {
+		clearReadonly(irb)
+	}
+	for _, s := range bundle.Streams {
+		clearReadonly(s)
+	}
+	for _, m := range bundle.Measures {
+		clearReadonly(m)
+	}
+	for _, t := range bundle.TopNAggregations {
+		clearReadonly(t)
+	}
+	return &databasev1.GroupRegistryServiceExportResponse{Bundle: bundle}, nil
+}
+
+func (rs *groupRegistryServer) Import(ctx context.Context,
+	req *databasev1.GroupRegistryServiceImportRequest,
+) (*databasev1.GroupRegistryServiceImportResponse, error) {
+	p, err := planImport(ctx, rs.schemaRegistry, req.GetBundle())
+	if err != nil {
+		return nil, err
+	}
+	if req.GetDryRun() {
+		return p.resp, nil
+	}
+	for _, step := range p.steps {
+		if err := step(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return p.resp, nil
+}
+
+// clearReadonly clears the fields assigned by the registry, which differ among the clusters.
+func clearReadonly(m schema.HasMetadata) {
+	if md := m.GetMetadata(); md != nil {
+		md.Id = 0
+		md.CreateRevision = 0
+		md.ModRevision = 0
+	}
+	switch s := m.(type) {
+	case *databasev1.Stream:
+		s.SchemaVersion = 0
+	case *databasev1.Measure:
+		s.SchemaVersion = 0
+	}
+}
+
+// importPlan holds the steps applying a bundle, which are planned after all the schemas are validated.
+type importPlan struct {
+	registry metadata.Service
+	resp     *databasev1.GroupRegistryServiceImportResponse
+	group    string
+	names    map[schema.Kind]map[string]struct{}
+	steps    []func(ctx context.Context) error
+}
+
+func planImport(ctx context.Context, registry metadata.Service, bundle *databasev1.SchemaBundle) (*importPlan, error) {
+	p := &importPlan{
+		registry: registry,
+		resp:     &databasev1.GroupRegistryServiceImportResponse{},
+		group:    bundle.GetGroup().GetMetadata().GetName(),
+		names:    make(map[schema.Kind]map[string]struct{}),
+	}
+	if p.group == "" {
+		return nil, schema.BadRequest("bundle.group.metadata.name", "the group is absent")
+	}
+	g := bundle.GetGroup()
+	existingGroup, err := registry.GroupRegistry().GetGroup(ctx, p.group)
+	if err = p.add(schema.KindGroup, g, existingGroup, err,
+		func(ctx context.Context) error { return registry.GroupRegistry().CreateGroup(ctx, g) },
+		func(ctx context.Context) error { return registry.GroupRegistry().UpdateGroup(ctx, g) }); err != nil {
+		return nil, err
+	}
+	for i := range bundle.GetIndexRules() {
+		ir := bundle.GetIndexRules()[i]
+		if err = p.own(schema.KindIndexRule, "index_rules", ir.GetMetadata()); err != nil {
+			return nil, err
+		}
+		existing, getErr := registry.IndexRuleRegistry().GetIndexRule(ctx, ir.GetMetadata())
+		if err = p.add(schema.KindIndexRule, ir, existing, getErr,
+			func(ctx context.Context) error { return registry.IndexRuleRegistry().CreateIndexRule(ctx, ir) },
+			func(ctx context.Context) error { return registry.IndexRuleRegistry().UpdateIndexRule(ctx, ir) }); err != nil {
+			return nil, err
+		}
+	}
+	for i := range bundle.GetStreams() {
+		s := bundle.GetStreams()[i]
+		if err = p.own(schema.KindStream, "streams", s.GetMetadata()); err != nil {
+			return nil, err
+		}
+		existing, getErr := registry.StreamRegistry().GetStream(ctx, s.GetMetadata())
+		if err = p.add(schema.KindStream, s, existing, getErr,
+			func(ctx context.Context) error { return registry.StreamRegistry().CreateStream(ctx, s) },
+			func(ctx context.Context) error { return registry.StreamRegistry().UpdateStream(ctx, s) }); err != nil {
+			return nil, err
+		}
+	}
+	for i := range bundle.GetMeasures() {
+		m := bundle.GetMeasures()[i]
+		if err = p.own(schema.KindMeasure, "measures", m.GetMetadata()); err != nil {
+			return nil, err
+		}
+		existing, getErr := registry.MeasureRegistry().GetMeasure(ctx, m.GetMetadata())
+		if err = p.add(schema.KindMeasure, m, existing, getErr,
+			func(ctx context.Context) error { return registry.MeasureRegistry().CreateMeasure(ctx, m) },
+			func(ctx context.Context) error { return registry.MeasureRegistry().UpdateMeasure(ctx, m) }); err != nil {
+			return nil, err
+		}
+	}
+	for i := range bundle.GetIndexRuleBindings() {
+		irb := bundle.GetIndexRuleBindings()[i]
+		if err = p.own(schema.KindIndexRuleBinding, "index_rule_bindings", irb.GetMetadata()); err != nil {
+			return nil, err
+		}
+		if err = p.checkBinding(ctx, irb); err != nil {
+			return nil, err
+		}
+		existing, getErr := registry.IndexRuleBindingRegistry().GetIndexRuleBinding(ctx, irb.GetMetadata())
+		if err = p.add(schema.KindIndexRuleBinding, irb, existing, getErr,
+			func(ctx context.Context) error {
+				err := registry.IndexRuleBindingRegistry().CreateIndexRuleBinding(ctx, irb)
+				// the bindings of the ID tags are created along with their measures
+				if errors.Is(err, schema.ErrGRPCAlreadyExists) {
+					return registry.IndexRuleBindingRegistry().UpdateIndexRuleBinding(ctx, irb)
+				}
+				return err
+			},
+			func(ctx context.Context) error {
+				return registry.IndexRuleBindingRegistry().UpdateIndexRuleBinding(ctx, irb)
+			}); err != nil {
+			return nil, err
+		}
+	}
+	for i := range bundle.GetTopNAggregations() {
+		t := bundle.GetTopNAggregations()[i]
+		if err = p.own(schema.KindTopNAggregation, "top_n_aggregations", t.GetMetadata()); err != nil {
+			return nil, err
+		}
+		if err = p.checkExistence(ctx, schema.KindMeasure, t.GetSourceMeasure()); err != nil {
+			return nil, err
+		}
+		existing, getErr := registry.TopNAggregationRegistry().GetTopNAggregation(ctx, t.GetMetadata())
+		if err = p.add(schema.KindTopNAggregation, t, existing, getErr,
+			func(ctx context.Context) error {
+				return registry.TopNAggregationRegistry().CreateTopNAggregation(ctx, t)
+			},
+			func(ctx context.Context) error {
+				return registry.TopNAggregationRegistry().UpdateTopNAggregation(ctx, t)
+			}); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// own fills the group of a schema in, and rejects the schemas of the other groups and the duplicated ones.
+func (p *importPlan) own(kind schema.Kind, field string, md *commonv1.Metadata) error {
+	if md.GetName() == "" {
+		return schema.BadRequest("bundle."+field+".metadata.name", "the name is absent")
+	}
+	if md.GetGroup() == "" {
+		md.Group = p.group
+	}
+	if md.GetGroup() != p.group {
+		return schema.BadRequest("bundle."+field+".metadata.group",
+			fmt.Sprintf("%s belongs to the group %s rather than %s", md.GetName(), md.GetGroup(), p.group))
+	}
+	names, ok := p.names[kind]
+	if !ok {
+		names = make(map[string]struct{})
+		p.names[kind] = names
+	}
+	if _, ok := names[md.GetName()]; ok {
+		return schema.BadRequest("bundle."+field+".metadata.name", fmt.Sprintf("%s is duplicated", md.GetName()))
+	}
+	names[md.GetName()] = struct{}{}
+	return nil
+}
+
+// add plans to create the schema if it doesn't exist, or to update it once the change is validated.
+func (p *importPlan) add(kind schema.Kind, spec, existing proto.Message, getErr error, create, update func(ctx context.Context) error) error {
+	switch {
+	case errors.Is(getErr, schema.ErrGRPCResourceNotFound):
+		p.resp.Created++
+		p.steps = append(p.steps, create)
+	case getErr != nil:
+		return getErr
+	case equalSchema(kind, existing, spec):
+		p.resp.Unchanged++
+	default:
+		if err := schema.CheckEvolution(kind, existing, spec); err != nil {
+			return err
+		}
+		p.resp.Updated++
+		p.steps = append(p.steps, update)
+	}
+	return nil
+}
+
+func equalSchema(kind schema.Kind, existing, spec proto.Message) bool {
+	if kind != schema.KindTopNAggregation {
+		return schema.Metadata{TypeMeta: schema.TypeMeta{Kind: kind}, Spec: existing}.Equal(spec)
+	}
+	a, b := proto.Clone(existing).(*databasev1.TopNAggregation), proto.Clone(spec).(*databasev1.TopNAggregation)
+	clearReadonly(a)
+	clearReadonly(b)
+	a.UpdatedAt, b.UpdatedAt = nil, nil
+	return proto.Equal(a, b)
+}
+
+func (p *importPlan) checkBinding(ctx context.Context, irb *databasev1.IndexRuleBinding) error {
+	for _, rule := range irb.GetRules() {
+		if err := p.checkExistence(ctx, schema.KindIndexRule, &commonv1.Metadata{Group: p.group, Name: rule}); err != nil {
+			return err
+		}
+	}
+	subject := &commonv1.Metadata{Group: p.group, Name: irb.GetSubject().GetName()}
+	switch irb.GetSubject().GetCatalog() {
+	case commonv1.Catalog_CATALOG_STREAM:
+		return p.checkExistence(ctx, schema.KindStream, subject)
+	case commonv1.Catalog_CATALOG_MEASURE:
+		return p.checkExistence(ctx, schema.KindMeasure, subject)
+	}
+	return schema.BadRequest("bundle.index_rule_bindings.subject.catalog",
+		fmt.Sprintf("the catalog of %s is unsupported", irb.GetMetadata().GetName()))
+}
+
+// checkExistence checks whether a referred schema is in the bundle or the registry.
+func (p *importPlan) checkExistence(ctx context.Context, kind schema.Kind, md *commonv1.Metadata) error {
+	if md.GetGroup() == p.group {
+		if _, ok := p.names[kind][md.GetName()]; ok {
+			return nil
+		}
+	}
+	var err error
+	switch kind {
+	case schema.KindIndexRule:
+		_, err = p.registry.IndexRuleRegistry().GetIndexRule(ctx, md)
+	case schema.KindStream:
+		_, err = p.registry.StreamRegistry().GetStream(ctx, md)
+	case schema.KindMeasure:
+		_, err = p.registry.MeasureRegistry().GetMeasure(ctx, md)
+	}
+	if errors.Is(err, schema.ErrGRPCResourceNotFound) {
+		return schema.BadRequest("bundle", fmt.Sprintf("%s/%s referred by the bundle doesn't exist", md.GetGroup(), md.GetName()))
+	}
+	return err
+}
@@ -0,0 +1,303 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+const (
+	// the number of the elements or the data points read by a query of the copy
+	clonePageSize = 1000
+	// the cloned group is expected to be opened by the data modules in such a period
+	cloneReadyTimeout       = 30 * time.Second
+	cloneReadyCheckInterval = 100 * time.Millisecond
+)
+
+func (rs *groupRegistryServer) Clone(ctx context.Context,
+	req *databasev1.GroupRegistryServiceCloneRequest,
+) (*databasev1.GroupRegistryServiceCloneResponse, error) {
+	_, err := rs.schemaRegistry.GroupRegistry().GetGroup(ctx, req.GetTarget())
+	if err == nil {
+		return nil, status.Errorf(codes.AlreadyExists, "the group %s already exists", req.GetTarget())
+	}
+	if !errors.Is(err, schema.ErrGRPCResourceNotFound) {
+		return nil, err
+	}
+	if req.GetTimeRange() != nil {
+		if err = timestamp.CheckTimeRange(req.GetTimeRange()); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "%v is invalid :%s", req.GetTimeRange(), err)
+		}
+	}
+	exported, err := rs.Export(ctx, &databasev1.GroupRegistryServiceExportRequest{Group: req.GetGroup()})
+	if err != nil {
+		return nil, err
+	}
+	bundle := retarget(exported.GetBundle(), req.GetTarget())
+	if req.GetResourceOpts() != nil {
+		if bundle.Group.ResourceOpts == nil {
+			bundle.Group.ResourceOpts = &commonv1.ResourceOpts{}
+		}
+		proto.Merge(bundle.Group.ResourceOpts, req.GetResourceOpts())
+	}
+	imported, err := rs.Import(ctx, &databasev1.GroupRegistryServiceImportRequest{Bundle: bundle})
+	if err != nil {
+		return nil, err
+	}
+	resp := &databasev1.GroupRegistryServiceCloneResponse{Created: imported.GetCreated()}
+	if req.GetTimeRange() == nil {
+		return resp, nil
+	}
+	if err = rs.waitCloned(ctx, bundle); err != nil {
+		return nil, err
+	}
+	for _, s := range bundle.GetStreams() {
+		n, errCopy := rs.copyStream(ctx, req.GetGroup(), s, req.GetTimeRange())
+		resp.Elements += n
+		if errCopy != nil {
+			return nil, errCopy
+		}
+	}
+	for _, m := range bundle.GetMeasures() {
+		n, errCopy := rs.copyMeasure(ctx, req.GetGroup(), m, req.GetTimeRange())
+		resp.DataPoints += n
+		if errCopy != nil {
+			return nil, errCopy
+		}
+	}
+	return resp, nil
+}
+
+// retarget moves all the schemas of the bundle into the target group.
+func retarget(bundle *databasev1.SchemaBundle, target string) *databasev1.SchemaBundle {
+	source := bundle.GetGroup().GetMetadata().GetName()
+	bundle.Group.Metadata.Name = target
+	for _, ir := range bundle.GetIndexRules() {
+		ir.Metadata.Group = target
+	}
+	for _, irb := range bundle.GetIndexRuleBindings() {
+		irb.Metadata.Group = target
+	}
+	for _, s := range bundle.GetStreams() {
+		s.Metadata.Group = target
+	}
+	for _, m := range bundle.GetMeasures() {
+		m.Metadata.Group = target
+	}
+	for _, t := range bundle.GetTopNAggregations() {
+		t.Metadata.Group = target
+		if t.GetSourceMeasure().GetGroup() == source {
+			t.SourceMeasure.Group = target
+		}
+	}
+	return bundle
+}
+
+// waitCloned waits until the liaison knows the shards and the entities of the cloned streams and measures,
+// which are reported by the data modules once they open the group.
+func (rs *groupRegistryServer) waitCloned(ctx context.Context, bundle *databasev1.SchemaBundle) error {
+	ctx, cancel := context.WithTimeout(ctx, cloneReadyTimeout)
+	defer cancel()
+	ready := func() bool {
+		for _, s := range bundle.GetStreams() {
+			if !rs.streamSVC.located(s.GetMetadata()) {
+				return false
+			}
+		}
+		for _, m := range bundle.GetMeasures() {
+			if !rs.measureSVC.located(m.GetMetadata()) {
+				return false
+			}
+		}
+		return true
+	}
+	ticker := time.NewTicker(cloneReadyCheckInterval)
+	defer ticker.Stop()
+	for !ready() {
+		select {
+		case <-ctx.Done():
+			return status.Errorf(codes.DeadlineExceeded, "the group %s isn't opened by the data modules in time",
+				bundle.GetGroup().GetMetadata().GetName())
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// located tells whether the writes to the resource can be navigated to its shards.
+func (ds *discoveryService) located(md *commonv1.Metadata) bool {
+	if _, ok := ds.shardRepo.sharding(getID(&commonv1.Metadata{Name: md.GetGroup()})); !ok {
+		return false
+	}
+	_, ok := ds.entityRepo.getLocator(getID(md))
+	return ok
+}
+
+// copyStream copies the elements of the stream in the time range from the source group, and returns the number of the copied ones.
+func (rs *groupRegistryServer) copyStream(ctx context.Context, source string, s *databasev1.Stream,
+	timeRange *modelv1.TimeRange,
+) (uint64, error) {
+	projection := storedTags(s.GetTagFamilies())
+	if len(projection.GetTagFamilies()) < 1 {
+		return 0, nil
+	}
+	fw := rs.streamSVC.newForwarder(ctx)
+	defer fw.close()
+	var copied uint64
+	for offset := uint32(0); ; offset += clonePageSize {
+		resp, err := rs.streamSVC.Query(ctx, &streamv1.QueryRequest{
+			Metadata:   &commonv1.Metadata{Group: source, Name: s.GetMetadata().GetName()},
+			TimeRange:  proto.Clone(timeRange).(*modelv1.TimeRange),
+			Offset:     offset,
+			Limit:      clonePageSize,
+			OrderBy:    &modelv1.QueryOrder{Sort: modelv1.Sort_SORT_ASC},
+			Projection: projection,
+		})
+		if err != nil {
+			return copied, err
+		}
+		elements := resp.GetElements()
+		if len(elements) < 1 {
+			return copied, nil
+		}
+		writeRequest := &streamv1.WriteRequest{Metadata: s.GetMetadata(), Elements: make([]*streamv1.ElementValue, 0, len(elements))}
+		for _, e := range elements {
+			writeRequest.Elements = append(writeRequest.Elements, &streamv1.ElementValue{
+				ElementId:   e.GetElementId(),
+				Timestamp:   e.GetTimestamp(),
+				TagFamilies: tagFamiliesForWrite(s.GetTagFamilies(), e.GetTagFamilies()),
+			})
+		}
+		copied += uint64(len(elements) - len(rs.streamSVC.write(ctx, fw, writeRequest, false)))
+		if len(elements) < clonePageSize {
+			return copied, nil
+		}
+	}
+}
+
+// copyMeasure copies the data points of the measure in the time range from the source group, and returns the number of the copied ones.
+func (rs *groupRegistryServer) copyMeasure(ctx context.Context, source string, m *databasev1.Measure,
+	timeRange *modelv1.TimeRange,
+) (uint64, error) {
+	projection := storedTags(m.GetTagFamilies())
+	if len(projection.GetTagFamilies()) < 1 {
+		return 0, nil
+	}
+	fieldProjection := &measurev1.QueryRequest_FieldProjection{}
+	for _, f := range m.GetFields() {
+		fieldProjection.Names = append(fieldProjection.Names, f.GetName())
+	}
+	fw := rs.measureSVC.newForwarder(ctx)
+	defer fw.close()
+	var copied uint64
+	for offset := uint32(0); ; offset += clonePageSize {
+		resp, err := rs.measureSVC.Query(ctx, &measurev1.QueryRequest{
+			Metadata:        &commonv1.Metadata{Group: source, Name: m.GetMetadata().GetName()},
+			TimeRange:       proto.Clone(timeRange).(*modelv1.TimeRange),
+			Offset:          offset,
+			Limit:           clonePageSize,
+			OrderBy:         &modelv1.QueryOrder{Sort: modelv1.Sort_SORT_ASC},
+			TagProjection:   projection,
+			FieldProjection: fieldProjection,
+		})
+		if err != nil {
+			return copied, err
+		}
+		dataPoints := resp.GetDataPoints()
+		for _, dp := range dataPoints {
+			fields := make([]*modelv1.FieldValue, 0, len(m.GetFields()))
+			for _, spec := range m.GetFields() {
+				fields = append(fields, findField(dp.GetFields(), spec.GetName()))
+			}
+			if len(rs.measureSVC.write(ctx, fw, &measurev1.WriteRequest{
+				Metadata: m.GetMetadata(),
+				DataPoint: &measurev1.DataPointValue{
+					Timestamp:   dp.GetTimestamp(),
+					TagFamilies: tagFamiliesForWrite(m.GetTagFamilies(), dp.GetTagFamilies()),
+					Fields:      fields,
+				},
+			}, false)) < 1 {
+				copied++
+			}
+		}
+		if len(dataPoints) < clonePageSize {
+			return copied, nil
+		}
+	}
+}
+
+// storedTags projects all the tags except the ones only indexed, which can't be read.
+func storedTags(families []*databasev1.TagFamilySpec) *modelv1.TagProjection {
+	projection := &modelv1.TagProjection{}
+	for _, family := range families {
+		var tags []string
+		for _, tag := range family.GetTags() {
+			if !tag.GetIndexedOnly() {
+				tags = append(tags, tag.GetName())
+			}
+		}
+		if len(tags) > 0 {
+			projection.TagFamilies = append(projection.TagFamilies, &modelv1.TagProjection_TagFamily{
+				Name: family.GetName(),
+				Tags: tags,
+			})
+		}
+	}
+	return projection
+}
+
+// tagFamiliesForWrite lays the queried tags out in the order of the schema, the absent ones are null.
+func tagFamiliesForWrite(specs []*databasev1.TagFamilySpec, families []*modelv1.TagFamily) []*modelv1.TagFamilyForWrite {
+	result := make([]*modelv1.TagFamilyForWrite, 0, len(specs))
+	for _, spec := range specs {
+		family := &modelv1.TagFamilyForWrite{Tags: make([]*modelv1.TagValue, 0, len(spec.GetTags()))}
+		for _, tag := range spec.GetTags() {
+			v := findTag(families, spec.GetName(), tag.GetName())
+			if v == nil {
+				v = pbv1.NullTag
+			}
+			family.Tags = append(family.Tags, v)
+		}
+		result = append(result, family)
+	}
+	return result
+}
+
+func findField(fields []*measurev1.DataPoint_Field, name string) *modelv1.FieldValue {
+	for _, f := range fields {
+		if f.GetName() == name {
+			return f.GetValue()
+		}
+	}
+	return pbv1.NullFieldValue
+}
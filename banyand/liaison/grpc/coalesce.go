@@ -0,0 +1,188 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+var (
+	coalescedWrites = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "banyand_liaison_coalesced_writes_total",
+			Help: "The number of the writes coalesced into the batches published to the pipeline",
+		},
+		[]string{"kind"},
+	)
+	coalescedBatches = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "banyand_liaison_coalesced_batches_total",
+			Help: "The number of the batches of the coalesced writes published to the pipeline",
+		},
+		[]string{"kind"},
+	)
+)
+
+// coalescePolicy bounds the batches of the writes coalesced per shard by their sizes and the time they linger.
+type coalescePolicy struct {
+	maxSize int
+	linger  time.Duration
+}
+
+func (p *coalescePolicy) validate() error {
+	if p.maxSize < 0 || p.linger < 0 {
+		return errInvalidCoalescePolicy
+	}
+	return nil
+}
+
+type coalesceKey struct {
+	group string
+	shard uint32
+}
+
+type coalesceBuffer[T any] struct {
+	timer *time.Timer
+	items []T
+}
+
+// writeCoalescer coalesces the writes of all the write streams into the batches of their shards, which are published to the pipeline
+// once they're full or they linger for the time of the policy. It saves the messages of the bus and the locks of the shards taken by them.
+type writeCoalescer[T any] struct {
+	policy    *coalescePolicy
+	publisher bus.Publisher
+	log       *logger.Logger
+	buffers   map[coalesceKey]*coalesceBuffer[T]
+	key       func(item T) coalesceKey
+	newBatch  func(items []T) bus.Payload
+	writes    prometheus.Counter
+	batches   prometheus.Counter
+	topic     bus.Topic
+	pending   int
+	mu        sync.Mutex
+}
+
+func newWriteCoalescer[T any](kind string, policy *coalescePolicy, publisher bus.Publisher, topic bus.Topic,
+	key func(item T) coalesceKey, newBatch func(items []T) bus.Payload,
+) *writeCoalescer[T] {
+	return &writeCoalescer[T]{
+		policy:    policy,
+		publisher: publisher,
+		topic:     topic,
+		key:       key,
+		newBatch:  newBatch,
+		buffers:   make(map[coalesceKey]*coalesceBuffer[T]),
+		writes:    coalescedWrites.WithLabelValues(kind),
+		batches:   coalescedBatches.WithLabelValues(kind),
+	}
+}
+
+// add buffers the items into the batches of their shards. The full batches are published at once,
+// and so are all the ones touched if the policy doesn't let them linger.
+func (c *writeCoalescer[T]) add(items ...T) {
+	if len(items) < 1 {
+		return
+	}
+	var ready [][]T
+	touched := make(map[coalesceKey]*coalesceBuffer[T])
+	c.mu.Lock()
+	for _, item := range items {
+		k := c.key(item)
+		b, ok := c.buffers[k]
+		if !ok {
+			b = &coalesceBuffer[T]{}
+			c.buffers[k] = b
+			if c.policy.linger > 0 {
+				b.timer = time.AfterFunc(c.policy.linger, func() {
+					c.expire(k, b)
+				})
+			}
+		}
+		b.items = append(b.items, item)
+		c.pending++
+		touched[k] = b
+		if c.policy.maxSize > 0 && len(b.items) >= c.policy.maxSize {
+			ready = append(ready, c.take(k, b))
+			delete(touched, k)
+		}
+	}
+	if c.policy.linger <= 0 {
+		for k, b := range touched {
+			ready = append(ready, c.take(k, b))
+		}
+	}
+	c.mu.Unlock()
+	c.publish(ready)
+}
+
+// expire publishes the batch lingering for the time of the policy, unless it has been published because it's full.
+func (c *writeCoalescer[T]) expire(k coalesceKey, b *coalesceBuffer[T]) {
+	c.mu.Lock()
+	if c.buffers[k] != b {
+		c.mu.Unlock()
+		return
+	}
+	items := c.take(k, b)
+	c.mu.Unlock()
+	c.publish([][]T{items})
+}
+
+// take removes the buffer of the shard and returns its items. The caller should hold the lock.
+func (c *writeCoalescer[T]) take(k coalesceKey, b *coalesceBuffer[T]) []T {
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	delete(c.buffers, k)
+	c.pending -= len(b.items)
+	return b.items
+}
+
+// flush publishes all the buffered batches, which lets the pipeline be flushed with all the writes received before.
+func (c *writeCoalescer[T]) flush() {
+	c.mu.Lock()
+	ready := make([][]T, 0, len(c.buffers))
+	for k, b := range c.buffers {
+		ready = append(ready, c.take(k, b))
+	}
+	c.mu.Unlock()
+	c.publish(ready)
+}
+
+// size returns the number of the items buffered but not published yet.
+func (c *writeCoalescer[T]) size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pending
+}
+
+func (c *writeCoalescer[T]) publish(batches [][]T) {
+	for _, items := range batches {
+		c.writes.Add(float64(len(items)))
+		c.batches.Inc()
+		if _, err := c.publisher.Publish(c.topic, bus.NewMessage(bus.MessageID(time.Now().UnixNano()), c.newBatch(items))); err != nil {
+			c.log.Error().Err(err).Int("writes", len(items)).Msg("failed to publish the coalesced writes")
+		}
+	}
+}
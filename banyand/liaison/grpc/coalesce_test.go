@@ -0,0 +1,101 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/apache/skywalking-banyandb/api/data"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+type batchPublisher struct {
+	batches chan []string
+}
+
+func (p *batchPublisher) Publish(_ bus.Topic, messages ...bus.Message) (bus.Future, error) {
+	for _, m := range messages {
+		var ids []string
+		for _, r := range m.Data().(*streamv1.InternalWriteBatch).GetRequests() {
+			ids = append(ids, r.GetRequest().GetElement().GetElementId())
+		}
+		p.batches <- ids
+	}
+	return nil, nil
+}
+
+var _ = Describe("WriteCoalescer", func() {
+	write := func(id string, shard uint32) *streamv1.InternalWriteRequest {
+		return &streamv1.InternalWriteRequest{
+			ShardId: shard,
+			Request: &streamv1.WriteRequest{
+				Metadata: &commonv1.Metadata{Group: "default", Name: "sw"},
+				Element:  &streamv1.ElementValue{ElementId: id},
+			},
+		}
+	}
+	var publisher *batchPublisher
+	newCoalescer := func(maxSize int, linger time.Duration) *writeCoalescer[*streamv1.InternalWriteRequest] {
+		c := newWriteCoalescer("stream", &coalescePolicy{maxSize: maxSize, linger: linger}, publisher, data.TopicStreamWrite,
+			func(r *streamv1.InternalWriteRequest) coalesceKey {
+				return coalesceKey{group: r.GetRequest().GetMetadata().GetGroup(), shard: r.GetShardId()}
+			},
+			func(requests []*streamv1.InternalWriteRequest) bus.Payload {
+				return &streamv1.InternalWriteBatch{Requests: requests}
+			})
+		c.log = logger.GetLogger("test")
+		return c
+	}
+	BeforeEach(func() {
+		publisher = &batchPublisher{batches: make(chan []string, 16)}
+	})
+
+	It("publishes the full batches of a shard", func() {
+		c := newCoalescer(2, time.Hour)
+		c.add(write("1", 0), write("2", 1))
+		c.add(write("3", 0))
+		Eventually(publisher.batches).Should(Receive(Equal([]string{"1", "3"})))
+		Consistently(publisher.batches).ShouldNot(Receive())
+		Expect(c.size()).To(Equal(1))
+		c.flush()
+		Eventually(publisher.batches).Should(Receive(Equal([]string{"2"})))
+		Expect(c.size()).To(BeZero())
+	})
+
+	It("publishes the batches lingering for the time of the policy", func() {
+		c := newCoalescer(100, 50*time.Millisecond)
+		c.add(write("1", 0))
+		c.add(write("2", 0))
+		Eventually(publisher.batches).Should(Receive(Equal([]string{"1", "2"})))
+		c.add(write("3", 0))
+		Eventually(publisher.batches).Should(Receive(Equal([]string{"3"})))
+	})
+
+	It("publishes the writes of a request at once without lingering", func() {
+		c := newCoalescer(0, 0)
+		c.add(write("1", 0), write("2", 0))
+		Eventually(publisher.batches).Should(Receive(Equal([]string{"1", "2"})))
+		Expect(c.size()).To(BeZero())
+	})
+})
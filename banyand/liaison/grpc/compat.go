@@ -0,0 +1,259 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+)
+
+const (
+	schemaCompatWindowFlag = "write-schema-compat-window"
+	// the window is split into the buckets, the oldest one is discarded as the window rolls
+	compatBuckets = 10
+	// the resources exceeding it in a bucket are not recorded to bound the memory
+	maxCompatResources = 1000
+)
+
+var rejectedAnomalies = map[modelv1.WriteError_Code]databasev1.SchemaAnomaly_Kind{
+	modelv1.WriteError_CODE_SCHEMA_NOT_FOUND:  databasev1.SchemaAnomaly_KIND_SCHEMA_NOT_FOUND,
+	modelv1.WriteError_CODE_TAG_FAMILY_COUNT:  databasev1.SchemaAnomaly_KIND_UNKNOWN_TAG_FAMILY,
+	modelv1.WriteError_CODE_TAG_COUNT:         databasev1.SchemaAnomaly_KIND_UNKNOWN_TAG,
+	modelv1.WriteError_CODE_TAG_TYPE:          databasev1.SchemaAnomaly_KIND_TAG_TYPE_MISMATCH,
+	modelv1.WriteError_CODE_FIELD_COUNT:       databasev1.SchemaAnomaly_KIND_UNKNOWN_FIELD,
+	modelv1.WriteError_CODE_FIELD_TYPE:        databasev1.SchemaAnomaly_KIND_FIELD_TYPE_MISMATCH,
+	modelv1.WriteError_CODE_ENTITY_INCOMPLETE: databasev1.SchemaAnomaly_KIND_MISSING_ENTITY_TAG,
+}
+
+type compatKey struct {
+	typ string
+	identity
+}
+
+type compatBucket struct {
+	start     time.Time
+	resources map[compatKey]*databasev1.SchemaCompatibility
+}
+
+// schemaCompat tracks how the writes of the resources match their registered schemas in a rolling window,
+// so that the payloads changed by upgrading the agents or the OAP can be spotted quickly. A window of 0 disables it.
+type schemaCompat struct {
+	now     func() time.Time
+	buckets []compatBucket
+	window  time.Duration
+	width   time.Duration
+	mu      sync.Mutex
+}
+
+func newSchemaCompat() *schemaCompat {
+	return &schemaCompat{now: time.Now}
+}
+
+func (c *schemaCompat) validate() error {
+	if c.window < 0 || (c.window > 0 && c.window < time.Second) {
+		return errInvalidSchemaCompatWindow
+	}
+	return nil
+}
+
+// observe records a write validated against the schema s. The write is rejected for the reason wErr if it's not nil,
+// otherwise the tag families and the fields absent from the write are recorded as the missing ones.
+func (c *schemaCompat) observe(typ string, md *commonv1.Metadata, s *writeSchema,
+	families []*modelv1.TagFamilyForWrite, fields []*modelv1.FieldValue, wErr *modelv1.WriteError,
+) {
+	if c == nil || c.window <= 0 {
+		return
+	}
+	var anomalies []*databasev1.SchemaAnomaly
+	if wErr != nil {
+		kind, ok := rejectedAnomalies[wErr.GetCode()]
+		if !ok {
+			// the invalid timestamps and the oversized tags aren't caused by the schema
+			return
+		}
+		anomalies = append(anomalies, &databasev1.SchemaAnomaly{Kind: kind, Rejected: true, Example: wErr.GetMessage()})
+	} else {
+		if s == nil {
+			return
+		}
+		anomalies = missingAnomalies(s, families, fields)
+	}
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b := c.bucket(now)
+	key := compatKey{typ: typ, identity: getID(md)}
+	r, ok := b.resources[key]
+	if !ok {
+		if len(b.resources) >= maxCompatResources {
+			return
+		}
+		r = &databasev1.SchemaCompatibility{Type: typ, Metadata: md}
+		b.resources[key] = r
+	}
+	r.Writes++
+	if len(anomalies) == 0 {
+		return
+	}
+	r.AnomalousWrites++
+	seenAt := timestamppb.New(now)
+	for _, a := range anomalies {
+		a.Count, a.LastSeenAt = 1, seenAt
+		mergeAnomaly(r, a)
+	}
+}
+
+// missingAnomalies returns the tag families, the tags and the fields defined by the schema but absent from an accepted write.
+func missingAnomalies(s *writeSchema, families []*modelv1.TagFamilyForWrite, fields []*modelv1.FieldValue) []*databasev1.SchemaAnomaly {
+	var anomalies []*databasev1.SchemaAnomaly
+	if len(families) < len(s.families) {
+		anomalies = append(anomalies, &databasev1.SchemaAnomaly{
+			Kind:    databasev1.SchemaAnomaly_KIND_MISSING_TAG_FAMILY,
+			Example: s.families[len(families)].GetName(),
+		})
+	}
+	for fi, family := range families {
+		spec := s.families[fi]
+		if n := len(family.GetTags()); n < len(spec.GetTags()) {
+			anomalies = append(anomalies, &databasev1.SchemaAnomaly{
+				Kind:    databasev1.SchemaAnomaly_KIND_MISSING_TAG,
+				Example: spec.GetName() + "." + spec.GetTags()[n].GetName(),
+			})
+			break
+		}
+	}
+	if s.fields != nil && len(fields) < len(s.fields) {
+		anomalies = append(anomalies, &databasev1.SchemaAnomaly{
+			Kind:    databasev1.SchemaAnomaly_KIND_MISSING_FIELD,
+			Example: s.fields[len(fields)].GetName(),
+		})
+	}
+	return anomalies
+}
+
+// bucket returns the bucket covering t, which is reset if it holds the writes out of the window.
+func (c *schemaCompat) bucket(t time.Time) *compatBucket {
+	if c.buckets == nil {
+		// the window is configured by the flags after the tracker is created
+		c.buckets = make([]compatBucket, compatBuckets)
+		c.width = c.window / compatBuckets
+	}
+	start := t.Truncate(c.width)
+	b := &c.buckets[(start.UnixNano()/int64(c.width))%compatBuckets]
+	if !b.start.Equal(start) {
+		b.start = start
+		b.resources = make(map[compatKey]*databasev1.SchemaCompatibility)
+	}
+	return b
+}
+
+func mergeAnomaly(r *databasev1.SchemaCompatibility, src *databasev1.SchemaAnomaly) {
+	for _, dst := range r.GetAnomalies() {
+		if dst.GetKind() != src.GetKind() {
+			continue
+		}
+		dst.Count += src.GetCount()
+		if dst.GetLastSeenAt().AsTime().Before(src.GetLastSeenAt().AsTime()) {
+			dst.Example, dst.LastSeenAt = src.GetExample(), src.GetLastSeenAt()
+		}
+		return
+	}
+	r.Anomalies = append(r.Anomalies, &databasev1.SchemaAnomaly{
+		Kind:       src.GetKind(),
+		Rejected:   src.GetRejected(),
+		Count:      src.GetCount(),
+		Example:    src.GetExample(),
+		LastSeenAt: src.GetLastSeenAt(),
+	})
+}
+
+// report merges the buckets in the window and returns the resources of the group, the most anomalous first.
+func (c *schemaCompat) report(group string) []*databasev1.SchemaCompatibility {
+	now := c.now()
+	merged := make(map[compatKey]*databasev1.SchemaCompatibility)
+	c.mu.Lock()
+	for i := range c.buckets {
+		b := c.buckets[i]
+		if b.resources == nil || !b.start.Add(c.width).After(now.Add(-c.window)) {
+			continue
+		}
+		for key, r := range b.resources {
+			if key.group != group {
+				continue
+			}
+			m, ok := merged[key]
+			if !ok {
+				m = &databasev1.SchemaCompatibility{Type: r.GetType(), Metadata: r.GetMetadata()}
+				merged[key] = m
+			}
+			m.Writes += r.GetWrites()
+			m.AnomalousWrites += r.GetAnomalousWrites()
+			for _, a := range r.GetAnomalies() {
+				mergeAnomaly(m, a)
+			}
+		}
+	}
+	c.mu.Unlock()
+	result := make([]*databasev1.SchemaCompatibility, 0, len(merged))
+	for _, r := range merged {
+		sort.Slice(r.Anomalies, func(i, j int) bool {
+			return r.Anomalies[i].GetKind() < r.Anomalies[j].GetKind()
+		})
+		result = append(result, r)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].GetAnomalousWrites() != result[j].GetAnomalousWrites() {
+			return result[i].GetAnomalousWrites() > result[j].GetAnomalousWrites()
+		}
+		if result[i].GetType() != result[j].GetType() {
+			return result[i].GetType() < result[j].GetType()
+		}
+		return result[i].GetMetadata().GetName() < result[j].GetMetadata().GetName()
+	})
+	return result
+}
+
+type schemaCompatServer struct {
+	databasev1.UnimplementedSchemaCompatibilityServiceServer
+	compat *schemaCompat
+}
+
+func (s *schemaCompatServer) Get(_ context.Context, req *databasev1.SchemaCompatibilityServiceGetRequest,
+) (*databasev1.SchemaCompatibilityServiceGetResponse, error) {
+	if req.GetGroup() == "" {
+		return nil, status.Error(codes.InvalidArgument, "the group is absent")
+	}
+	if s.compat.window <= 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "the write schema compatibility is disabled by the %s", schemaCompatWindowFlag)
+	}
+	return &databasev1.SchemaCompatibilityServiceGetResponse{
+		Resources: s.compat.report(req.GetGroup()),
+		Window:    durationpb.New(s.compat.window),
+	}, nil
+}
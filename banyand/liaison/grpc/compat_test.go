@@ -0,0 +1,126 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+)
+
+var _ = Describe("Schema compatibility", func() {
+	md := &commonv1.Metadata{Group: "default", Name: "sw"}
+	element := func(families ...[]*modelv1.TagValue) *streamv1.ElementValue {
+		e := &streamv1.ElementValue{ElementId: "1", Timestamp: timestamppb.New(time.Now().Truncate(time.Millisecond))}
+		for _, tags := range families {
+			e.TagFamilies = append(e.TagFamilies, &modelv1.TagFamilyForWrite{Tags: tags})
+		}
+		return e
+	}
+	str := &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "/home"}}}
+	num := &modelv1.TagValue{Value: &modelv1.TagValue_Int{Int: &modelv1.Int{Value: 200}}}
+	var now time.Time
+	var compat *schemaCompat
+	var validator *writeValidator
+	BeforeEach(func() {
+		now = time.Unix(1700000000, 0)
+		compat = &schemaCompat{now: func() time.Time { return now }, window: 10 * time.Minute}
+		validator = newWriteValidator(&fakeRepo{}, &tagSizeGuard{}, compat)
+	})
+	kinds := func(r *databasev1.SchemaCompatibility) []databasev1.SchemaAnomaly_Kind {
+		var result []databasev1.SchemaAnomaly_Kind
+		for _, a := range r.GetAnomalies() {
+			result = append(result, a.GetKind())
+		}
+		return result
+	}
+
+	It("reports the rejected and the missing tags", func() {
+		ctx := context.Background()
+		Expect(validator.validateElement(ctx, md, element([]*modelv1.TagValue{str, num}))).To(BeNil())
+		Expect(validator.validateElement(ctx, md, element([]*modelv1.TagValue{str}))).To(BeNil())
+		Expect(validator.validateElement(ctx, md, element([]*modelv1.TagValue{str, str}))).NotTo(BeNil())
+		Expect(validator.validateElement(ctx, md, element([]*modelv1.TagValue{str, num}, []*modelv1.TagValue{str}))).NotTo(BeNil())
+		report := compat.report("default")
+		Expect(report).To(HaveLen(1))
+		r := report[0]
+		Expect(r.GetType()).To(Equal(rejectedTypeStream))
+		Expect(r.GetWrites()).To(Equal(int64(4)))
+		Expect(r.GetAnomalousWrites()).To(Equal(int64(3)))
+		Expect(kinds(r)).To(Equal([]databasev1.SchemaAnomaly_Kind{
+			databasev1.SchemaAnomaly_KIND_UNKNOWN_TAG_FAMILY,
+			databasev1.SchemaAnomaly_KIND_TAG_TYPE_MISMATCH,
+			databasev1.SchemaAnomaly_KIND_MISSING_TAG,
+		}))
+		Expect(r.GetAnomalies()[0].GetRejected()).To(BeTrue())
+		missing := r.GetAnomalies()[2]
+		Expect(missing.GetRejected()).To(BeFalse())
+		Expect(missing.GetExample()).To(Equal("searchable.status"))
+		Expect(missing.GetCount()).To(Equal(int64(1)))
+		Expect(compat.report("other")).To(BeEmpty())
+	})
+
+	It("skips the rejections not caused by the schema", func() {
+		e := element([]*modelv1.TagValue{str, num})
+		e.Timestamp = &timestamppb.Timestamp{Seconds: -1 << 40}
+		Expect(validator.validateElement(context.Background(), md, e)).NotTo(BeNil())
+		Expect(compat.report("default")).To(BeEmpty())
+	})
+
+	It("rolls the window", func() {
+		ctx := context.Background()
+		Expect(validator.validateElement(ctx, md, element([]*modelv1.TagValue{str}))).To(BeNil())
+		now = now.Add(5 * time.Minute)
+		Expect(validator.validateElement(ctx, md, element([]*modelv1.TagValue{str, num}))).To(BeNil())
+		Expect(compat.report("default")[0].GetAnomalousWrites()).To(Equal(int64(1)))
+		now = now.Add(6 * time.Minute)
+		r := compat.report("default")[0]
+		Expect(r.GetWrites()).To(Equal(int64(1)))
+		Expect(r.GetAnomalousWrites()).To(BeZero())
+		Expect(r.GetAnomalies()).To(BeEmpty())
+	})
+
+	It("serves the report of a group", func() {
+		s := &schemaCompatServer{compat: compat}
+		_, err := s.Get(context.Background(), &databasev1.SchemaCompatibilityServiceGetRequest{})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		resp, err := s.Get(context.Background(), &databasev1.SchemaCompatibilityServiceGetRequest{Group: "default"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.GetWindow().AsDuration()).To(Equal(10 * time.Minute))
+		_, err = (&schemaCompatServer{compat: newSchemaCompat()}).Get(context.Background(),
+			&databasev1.SchemaCompatibilityServiceGetRequest{Group: "default"})
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+	})
+
+	It("rejects the invalid windows", func() {
+		Expect((&schemaCompat{window: -time.Second}).validate()).To(MatchError(errInvalidSchemaCompatWindow))
+		Expect((&schemaCompat{window: time.Millisecond}).validate()).To(MatchError(errInvalidSchemaCompatWindow))
+		Expect((&schemaCompat{}).validate()).To(Succeed())
+		Expect((&schemaCompat{window: time.Minute}).validate()).To(Succeed())
+	})
+})
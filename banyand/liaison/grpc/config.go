@@ -0,0 +1,59 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/pkg/run"
+)
+
+// configServer gets and reloads the reloadable flags of the modules of the running server.
+type configServer struct {
+	databasev1.UnimplementedConfigServiceServer
+	modules []run.Unit
+	mu      sync.RWMutex
+}
+
+func (s *configServer) register(modules ...run.Unit) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.modules = append(s.modules, modules...)
+}
+
+func (s *configServer) Get(_ context.Context, _ *databasev1.ConfigServiceGetRequest) (*databasev1.ConfigServiceGetResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return &databasev1.ConfigServiceGetResponse{Flags: run.ReloadableFlags(s.modules...)}, nil
+}
+
+func (s *configServer) Reload(_ context.Context, req *databasev1.ConfigServiceReloadRequest) (*databasev1.ConfigServiceReloadResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	applied, err := run.Reload(req.GetFlags(), s.modules...)
+	// the flags which aren't reloadable and the invalid values are rejected as a whole
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &databasev1.ConfigServiceReloadResponse{Applied: applied}, nil
+}
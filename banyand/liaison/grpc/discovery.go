@@ -18,7 +18,9 @@
 package grpc
 
 import (
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -29,6 +31,7 @@ import (
 	"github.com/apache/skywalking-banyandb/banyand/queue"
 	"github.com/apache/skywalking-banyandb/banyand/tsdb"
 	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/intern"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 	"github.com/apache/skywalking-banyandb/pkg/partition"
 )
@@ -40,11 +43,16 @@ type discoveryService struct {
 	entityRepo *entityRepo
 	pipeline   queue.Queue
 	log        *logger.Logger
+	// interning shares the entries of the entities located for the writes
+	interning *intern.Table
 }
 
 func newDiscoveryService(pipeline queue.Queue) *discoveryService {
 	return &discoveryService{
-		shardRepo:  &shardRepo{shardEventsMap: make(map[identity]uint32)},
+		shardRepo: &shardRepo{
+			shardEventsMap: make(map[identity]partition.Sharding),
+			replications:   make(map[identity]replication),
+		},
 		entityRepo: &entityRepo{entitiesMap: make(map[identity]partition.EntityLocator)},
 		pipeline:   pipeline,
 	}
@@ -56,8 +64,10 @@ func (ds *discoveryService) SetLogger(log *logger.Logger) {
 	ds.entityRepo.log = log
 }
 
-func (ds *discoveryService) navigate(metadata *commonv1.Metadata, tagFamilies []*modelv1.TagFamilyForWrite) (tsdb.Entity, common.ShardID, error) {
-	shardNum, existed := ds.shardRepo.shardNum(getID(&commonv1.Metadata{
+func (ds *discoveryService) navigate(metadata *commonv1.Metadata, tagFamilies []*modelv1.TagFamilyForWrite,
+	t time.Time,
+) (tsdb.Entity, common.ShardID, error) {
+	sharding, existed := ds.shardRepo.sharding(getID(&commonv1.Metadata{
 		Name: metadata.Group,
 	}))
 	if !existed {
@@ -67,7 +77,19 @@ func (ds *discoveryService) navigate(metadata *commonv1.Metadata, tagFamilies []
 	if !existed {
 		return nil, common.ShardID(0), errors.Wrapf(ErrNotExist, "finding the locator by: %v", metadata)
 	}
-	return locator.Locate(metadata.Name, tagFamilies, shardNum)
+	return locator.Locate(ds.interning, metadata.Name, tagFamilies, sharding, t)
+}
+
+// navigationError reports the write which can't be located, e.g. the one routed to a group lacking its resource.
+func navigationError(metadata *commonv1.Metadata, err error) *modelv1.WriteError {
+	code := modelv1.WriteError_CODE_UNSPECIFIED
+	if errors.Is(err, ErrNotExist) {
+		code = modelv1.WriteError_CODE_SCHEMA_NOT_FOUND
+	}
+	return &modelv1.WriteError{
+		Code:    code,
+		Message: fmt.Sprintf("failed to locate the write target %s/%s: %v", metadata.GetGroup(), metadata.GetName(), err),
+	}
 }
 
 type identity struct {
@@ -77,10 +99,22 @@ type identity struct {
 
 type shardRepo struct {
 	log            *logger.Logger
-	shardEventsMap map[identity]uint32
+	shardEventsMap map[identity]partition.Sharding
+	replications   map[identity]replication
 	sync.RWMutex
 }
 
+// replication tells how the shards of a group are replicated.
+type replication struct {
+	replicas int
+	mode     commonv1.ReplicationMode
+}
+
+// async tells whether the replicas other than the primary one receive the writes in the background.
+func (r replication) async() bool {
+	return r.mode == commonv1.ReplicationMode_REPLICATION_MODE_ASYNC
+}
+
 func (s *shardRepo) Rev(message bus.Message) (resp bus.Message) {
 	e, ok := message.Data().(*databasev1.ShardEvent)
 	if !ok {
@@ -100,20 +134,37 @@ func (s *shardRepo) setShardNum(eventVal *databasev1.ShardEvent) {
 	defer s.RWMutex.Unlock()
 	idx := getID(eventVal.GetShard().GetMetadata())
 	if eventVal.Action == databasev1.Action_ACTION_PUT {
-		s.shardEventsMap[idx] = eventVal.Shard.Total
+		sharding, err := partition.NewSharding(eventVal.Shard.Total, eventVal.Shard.TimeBand)
+		if err != nil {
+			s.log.Warn().Err(err).Str("group", idx.name).Msg("disable the time band of the group")
+		}
+		s.shardEventsMap[idx] = sharding
+		s.replications[idx] = replication{
+			replicas: int(eventVal.GetShard().GetReplicas()),
+			mode:     eventVal.GetShard().GetReplicationMode(),
+		}
 	} else if eventVal.Action == databasev1.Action_ACTION_DELETE {
 		delete(s.shardEventsMap, idx)
+		delete(s.replications, idx)
 	}
 }
 
-func (s *shardRepo) shardNum(idx identity) (uint32, bool) {
+// replication returns the replication of the group, which has one replica at least.
+func (s *shardRepo) replication(idx identity) replication {
 	s.RWMutex.RLock()
 	defer s.RWMutex.RUnlock()
-	sn, ok := s.shardEventsMap[idx]
-	if !ok {
-		return 0, false
+	r := s.replications[idx]
+	if r.replicas < 1 {
+		r.replicas = 1
 	}
-	return sn, true
+	return r
+}
+
+func (s *shardRepo) sharding(idx identity) (partition.Sharding, bool) {
+	s.RWMutex.RLock()
+	defer s.RWMutex.RUnlock()
+	sharding, ok := s.shardEventsMap[idx]
+	return sharding, ok
 }
 
 func getID(metadata *commonv1.Metadata) identity {
@@ -0,0 +1,201 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/apache/skywalking-banyandb/api/data"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/banyand/metadata"
+	"github.com/apache/skywalking-banyandb/banyand/queue"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+const defaultDrainTimeout = 10 * time.Second
+
+var errDraining = status.Error(codes.Unavailable, "the server is draining, write to another one")
+
+// drainer tracks the write streams, which are rejected once the server starts to drain, and the active queries.
+type drainer struct {
+	streams      sync.WaitGroup
+	writeStreams atomic.Int64
+	queries      atomic.Int64
+	mu           sync.Mutex
+	draining     bool
+	startedAt    time.Time
+	// finished indicates the pipeline is flushed and the open blocks are rolled over
+	finished bool
+}
+
+// acquire registers a new write stream, which fails if the server is draining.
+func (d *drainer) acquire() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return false
+	}
+	d.streams.Add(1)
+	d.writeStreams.Add(1)
+	return true
+}
+
+func (d *drainer) release() {
+	d.writeStreams.Add(-1)
+	d.streams.Done()
+}
+
+// trackQuery registers an active query, which is unregistered by the returned function once it ends.
+func (d *drainer) trackQuery() func() {
+	d.queries.Add(1)
+	return func() {
+		d.queries.Add(-1)
+	}
+}
+
+func (d *drainer) start() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.draining {
+		d.startedAt = time.Now()
+	}
+	d.draining = true
+}
+
+func (d *drainer) finish() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.finished = true
+}
+
+// wait blocks until the registered write streams end, and tells whether they end before the context is done.
+func (d *drainer) wait(ctx context.Context) bool {
+	ended := make(chan struct{})
+	go func() {
+		d.streams.Wait()
+		close(ended)
+	}()
+	select {
+	case <-ended:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+type drainServer struct {
+	databasev1.UnimplementedDrainServiceServer
+	log            *logger.Logger
+	drainer        *drainer
+	health         *health.Server
+	pipeline       queue.Queue
+	schemaRegistry metadata.Service
+	shardSVC       *shardServer
+	streamSVC      *streamService
+	measureSVC     *measureService
+}
+
+func (s *drainServer) Drain(ctx context.Context, req *databasev1.DrainServiceDrainRequest) (*databasev1.DrainServiceDrainResponse, error) {
+	timeout := defaultDrainTimeout
+	if req.GetTimeout() != nil {
+		timeout = req.GetTimeout().AsDuration()
+	}
+	return s.drain(ctx, timeout)
+}
+
+// drain stops accepting new write streams and reports NOT_SERVING, so that the writes are sent to the other servers.
+// Then it waits for the in-flight write streams, flushes the write pipeline, and rolls over the open blocks of all the groups.
+func (s *drainServer) drain(ctx context.Context, timeout time.Duration) (*databasev1.DrainServiceDrainResponse, error) {
+	s.drainer.start()
+	s.health.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	s.log.Info().Dur("timeout", timeout).Msg("draining")
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	resp := &databasev1.DrainServiceDrainResponse{}
+	if !s.drainer.wait(ctx) {
+		resp.TimedOut = true
+		s.log.Warn().Msg("the write streams are still open after the drain timeout")
+	}
+	// the pipeline is flushed regardless of the open streams, which bounds the writes received before the rollovers.
+	// The coalesced writes are published first.
+	s.streamSVC.coalescer.flush()
+	s.measureSVC.coalescer.flush()
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), timeout)
+	defer flushCancel()
+	if err := s.pipeline.Flush(flushCtx, data.TopicStreamWrite, data.TopicMeasureWrite); err != nil {
+		return nil, errors.WithMessage(err, "flush the write pipeline")
+	}
+	groups, err := s.schemaRegistry.GroupRegistry().ListGroup(flushCtx)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range groups {
+		if g.GetCatalog() != commonv1.Catalog_CATALOG_STREAM && g.GetCatalog() != commonv1.Catalog_CATALOG_MEASURE {
+			continue
+		}
+		name := g.GetMetadata().GetName()
+		rollover, err := s.shardSVC.Rollover(flushCtx, &databasev1.ShardServiceRolloverRequest{Group: name})
+		// a liaison without the data modules holds no shard
+		if errors.Is(err, bus.ErrTopicNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.WithMessagef(err, "roll over the group %s", name)
+		}
+		resp.Groups = append(resp.Groups, &databasev1.DrainServiceDrainResponse_Group{
+			Group:  name,
+			Shards: rollover.GetShards(),
+		})
+	}
+	s.drainer.finish()
+	s.log.Info().Int("groups", len(resp.Groups)).Bool("timed_out", resp.TimedOut).Msg("drained")
+	return resp, nil
+}
+
+// Status reports the in-flight write streams, the active queries and the pending writes of the pipeline.
+// The server is drained once the drain finishes and none of them is left, which is safe to be killed.
+func (s *drainServer) Status(_ context.Context, _ *databasev1.DrainServiceStatusRequest) (*databasev1.DrainServiceStatusResponse, error) {
+	s.drainer.mu.Lock()
+	draining, startedAt, finished := s.drainer.draining, s.drainer.startedAt, s.drainer.finished
+	s.drainer.mu.Unlock()
+	// the coalesced writes not published yet are pending as well
+	coalesced := int64(s.streamSVC.coalescer.size() + s.measureSVC.coalescer.size())
+	resp := &databasev1.DrainServiceStatusResponse{
+		Draining:        draining,
+		WriteStreams:    s.drainer.writeStreams.Load(),
+		ActiveQueries:   s.drainer.queries.Load(),
+		PendingMessages: s.pipeline.Pending(data.TopicStreamWrite, data.TopicMeasureWrite) + coalesced,
+	}
+	if draining {
+		resp.StartedAt = timestamppb.New(startedAt)
+	}
+	resp.Drained = finished && resp.WriteStreams == 0 && resp.ActiveQueries == 0 && resp.PendingMessages == 0
+	return resp, nil
+}
@@ -0,0 +1,101 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/grpchelper"
+)
+
+var _ = Describe("Drain", func() {
+	var gracefulStop func()
+	var conn *grpclib.ClientConn
+	BeforeEach(func() {
+		gracefulStop = setupForRegistry()
+		var err error
+		conn, err = grpchelper.Conn("localhost:17912", 10*time.Second, grpclib.WithTransportCredentials(insecure.NewCredentials()))
+		Expect(err).NotTo(HaveOccurred())
+	})
+	AfterEach(func() {
+		_ = conn.Close()
+		gracefulStop()
+	})
+	It("rejects the new write streams once draining", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		client := streamv1.NewStreamServiceClient(conn)
+		inflight, err := client.Write(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		// the stream is registered once the server receives a message
+		Expect(inflight.Send(&streamv1.WriteRequest{})).To(Succeed())
+		_, err = inflight.Recv()
+		Expect(err).ShouldNot(HaveOccurred())
+
+		drainClient := databasev1.NewDrainServiceClient(conn)
+		drainStatus, err := drainClient.Status(context.TODO(), &databasev1.DrainServiceStatusRequest{})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(drainStatus.GetDraining()).To(BeFalse())
+		Expect(drainStatus.GetStartedAt()).To(BeNil())
+		Expect(drainStatus.GetWriteStreams()).To(Equal(int64(1)))
+
+		resp, err := drainClient.Drain(context.TODO(), &databasev1.DrainServiceDrainRequest{
+			Timeout: durationpb.New(200 * time.Millisecond),
+		})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(resp.GetTimedOut()).To(BeTrue())
+		drainStatus, err = drainClient.Status(context.TODO(), &databasev1.DrainServiceStatusRequest{})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(drainStatus.GetDraining()).To(BeTrue())
+		Expect(drainStatus.GetStartedAt()).NotTo(BeNil())
+		Expect(drainStatus.GetWriteStreams()).To(Equal(int64(1)))
+		Expect(drainStatus.GetDrained()).To(BeFalse())
+
+		health, err := grpc_health_v1.NewHealthClient(conn).Check(context.TODO(), &grpc_health_v1.HealthCheckRequest{})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(health.GetStatus()).To(Equal(grpc_health_v1.HealthCheckResponse_NOT_SERVING))
+
+		// the in-flight stream keeps working
+		Expect(inflight.Send(&streamv1.WriteRequest{})).To(Succeed())
+		_, err = inflight.Recv()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(inflight.CloseSend()).To(Succeed())
+		Eventually(func() bool {
+			drainStatus, err = drainClient.Status(context.TODO(), &databasev1.DrainServiceStatusRequest{})
+			Expect(err).ShouldNot(HaveOccurred())
+			return drainStatus.GetDrained()
+		}, 10*time.Second).Should(BeTrue())
+
+		rejected, err := client.Write(context.TODO())
+		Expect(err).ShouldNot(HaveOccurred())
+		_, err = rejected.Recv()
+		Expect(status.Code(err)).To(Equal(codes.Unavailable))
+	})
+})
@@ -0,0 +1,608 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	clusterv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/cluster/v1"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/banyand/metadata"
+	"github.com/apache/skywalking-banyandb/pkg/convert"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/query/aggregation"
+	"github.com/apache/skywalking-banyandb/pkg/query/logical"
+)
+
+var errOrderTagNotDefined = errors.New("the tag of the index rule ordering the query is not defined")
+
+// internalQueryServer executes the sub-queries sent by the liaison coordinating a distributed query.
+// They are run on the local shards only, and on the shards assigned by the coordinator if there are any.
+type internalQueryServer struct {
+	clusterv1.UnimplementedInternalQueryServiceServer
+	streamSVC  *streamService
+	measureSVC *measureService
+	usageSVC   *usageServer
+}
+
+func (s *internalQueryServer) QueryStream(ctx context.Context, req *streamv1.QueryRequest) (*streamv1.QueryResponse, error) {
+	defer s.streamSVC.drainer.trackQuery()()
+	shardIDs, err := assignedShards(ctx)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return s.streamSVC.queryLocal(req, shardIDs...)
+}
+
+func (s *internalQueryServer) QueryMeasure(ctx context.Context, req *measurev1.QueryRequest) (*measurev1.QueryResponse, error) {
+	defer s.measureSVC.drainer.trackQuery()()
+	shardIDs, err := assignedShards(ctx)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return s.measureSVC.queryLocal(req, shardIDs...)
+}
+
+func (s *internalQueryServer) Usage(ctx context.Context, req *databasev1.UsageServiceGetRequest) (*databasev1.UsageServiceGetResponse, error) {
+	return s.usageSVC.localUsage(ctx, req)
+}
+
+// shardPlacement returns the replicas of the shards of the group, and whether the sub-queries are restricted to their shards.
+// A node holding several replicas returns the elements of all of them without the restriction, which are duplicated.
+func (ds *discoveryService) shardPlacement(router *nodeRouter, group string) (map[common.ShardID][]*databasev1.Node, bool) {
+	id := getID(&commonv1.Metadata{Name: group})
+	sharding, existed := ds.shardRepo.sharding(id)
+	if !existed {
+		return nil, false
+	}
+	r := ds.shardRepo.replication(id)
+	return router.placement(group, sharding.ShardNum, r.replicas), r.replicas > 1
+}
+
+// allLocal tells whether the primary replicas of all the shards are local.
+func allLocal(placement map[common.ShardID][]*databasev1.Node) bool {
+	for _, replicas := range placement {
+		if replicas[0] != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// fanOut runs the sub-queries on the nodes holding the primary replicas of the shards concurrently.
+// The shards of an unreachable node fall back to their next replicas,
+// and any other failure fails the whole query since the merged result would be incomplete.
+// The sub-queries carry the shards assigned to the nodes if restricted is true.
+func fanOut[R any](ctx context.Context, router *nodeRouter, placement map[common.ShardID][]*databasev1.Node, restricted bool,
+	queryLocal func(shardIDs []common.ShardID) (R, error), queryRemote func(ctx context.Context, conn *grpclib.ClientConn) (R, error),
+) ([]R, error) {
+	pending := make([]common.ShardID, 0, len(placement))
+	for id := range placement {
+		pending = append(pending, id)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i] < pending[j] })
+	chosen := make(map[common.ShardID]int, len(placement))
+	var results []R
+	for len(pending) > 0 {
+		assignments := assign(placement, chosen, pending)
+		partials := make([]R, len(assignments))
+		errs := make([]error, len(assignments))
+		var wg sync.WaitGroup
+		for i, a := range assignments {
+			var shardIDs []common.ShardID
+			if restricted {
+				shardIDs = a.shardIDs
+			}
+			if a.node == nil {
+				partials[i], errs[i] = queryLocal(shardIDs)
+				continue
+			}
+			wg.Add(1)
+			go func(i int, node *databasev1.Node) {
+				defer wg.Done()
+				if shardIDs != nil && !supports(node, featureShardRestriction) {
+					// the node would return the elements of all its shards
+					errs[i] = status.Errorf(codes.Unavailable, "the node doesn't support the %s", featureShardRestriction)
+					return
+				}
+				conn, err := router.conn(node)
+				if err != nil {
+					errs[i] = status.Error(codes.Unavailable, err.Error())
+					return
+				}
+				partials[i], errs[i] = queryRemote(withShards(withAPIVersion(ctx), shardIDs), conn)
+			}(i, a.node)
+		}
+		wg.Wait()
+		pending = pending[:0]
+		for i, a := range assignments {
+			if errs[i] == nil {
+				results = append(results, partials[i])
+				continue
+			}
+			if status.Code(errs[i]) != codes.Unavailable {
+				return nil, errors.WithMessagef(errs[i], "query the node %s", a.node.GetId())
+			}
+			for _, id := range a.shardIDs {
+				if chosen[id]++; chosen[id] >= len(placement[id]) {
+					return nil, errors.WithMessagef(errs[i], "no replica of the shard %d is available", id)
+				}
+			}
+			router.log.Warn().Err(errs[i]).Str("node", a.node.GetId()).Msg("fall back to the replicas of the shards")
+			pending = append(pending, a.shardIDs...)
+		}
+	}
+	return results, nil
+}
+
+// shardAssignment is the shards queried on a node, which is nil if it's local.
+type shardAssignment struct {
+	node     *databasev1.Node
+	shardIDs []common.ShardID
+}
+
+// assign groups the pending shards by the replicas chosen for them.
+func assign(placement map[common.ShardID][]*databasev1.Node, chosen map[common.ShardID]int, pending []common.ShardID) []*shardAssignment {
+	var assignments []*shardAssignment
+	index := make(map[string]*shardAssignment)
+	for _, id := range pending {
+		node := placement[id][chosen[id]]
+		a, ok := index[node.GetId()]
+		if !ok {
+			a = &shardAssignment{node: node}
+			index[node.GetId()] = a
+			assignments = append(assignments, a)
+		}
+		a.shardIDs = append(a.shardIDs, id)
+	}
+	return assignments
+}
+
+// orderTag is the tag sorting the partial results, which is the first tag of the index rule ordering the query.
+// It's fetched as well if it's not projected, and it's removed from the merged result.
+type orderTag struct {
+	family    string
+	name      string
+	projected bool
+}
+
+func resolveOrderTag(ctx context.Context, registry metadata.Repo, subject *commonv1.Metadata,
+	order *modelv1.QueryOrder, projection *modelv1.TagProjection, specs func() ([]*databasev1.TagFamilySpec, error),
+) (*orderTag, error) {
+	if order.GetIndexRuleName() == "" {
+		return nil, nil
+	}
+	rules, err := registry.IndexRules(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	var name string
+	for _, r := range rules {
+		if r.GetMetadata().GetName() == order.GetIndexRuleName() && len(r.GetTags()) > 0 {
+			name = r.GetTags()[0]
+			break
+		}
+	}
+	if name == "" {
+		return nil, errors.Wrap(logical.ErrIndexNotDefined, order.GetIndexRuleName())
+	}
+	for _, f := range projection.GetTagFamilies() {
+		for _, t := range f.GetTags() {
+			if t == name {
+				return &orderTag{family: f.GetName(), name: name, projected: true}, nil
+			}
+		}
+	}
+	families, err := specs()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range families {
+		for _, t := range f.GetTags() {
+			if t.GetName() == name {
+				return &orderTag{family: f.GetName(), name: name}, nil
+			}
+		}
+	}
+	return nil, errors.Wrap(errOrderTagNotDefined, name)
+}
+
+// project adds the tag to the projection of a sub-query if it's not projected.
+func (o *orderTag) project(projection *modelv1.TagProjection) *modelv1.TagProjection {
+	if o == nil || o.projected {
+		return projection
+	}
+	if projection == nil {
+		projection = &modelv1.TagProjection{}
+	}
+	for _, f := range projection.GetTagFamilies() {
+		if f.GetName() == o.family {
+			f.Tags = append(f.Tags, o.name)
+			return projection
+		}
+	}
+	projection.TagFamilies = append(projection.TagFamilies, &modelv1.TagProjection_TagFamily{Name: o.family, Tags: []string{o.name}})
+	return projection
+}
+
+func (o *orderTag) value(families []*modelv1.TagFamily) []byte {
+	if v := findTag(families, o.family, o.name); v != nil {
+		if raw, err := pbv1.MarshalIndexFieldValue(v); err == nil {
+			return raw
+		}
+	}
+	return nil
+}
+
+// strip removes the tag which is fetched only for sorting.
+func (o *orderTag) strip(families []*modelv1.TagFamily) []*modelv1.TagFamily {
+	if o == nil || o.projected {
+		return families
+	}
+	result := families[:0]
+	for _, f := range families {
+		if f.GetName() == o.family {
+			tags := f.Tags[:0]
+			for _, t := range f.GetTags() {
+				if t.GetKey() != o.name {
+					tags = append(tags, t)
+				}
+			}
+			if f.Tags = tags; len(tags) < 1 {
+				continue
+			}
+		}
+		result = append(result, f)
+	}
+	return result
+}
+
+func findTag(families []*modelv1.TagFamily, family, name string) *modelv1.TagValue {
+	for _, f := range families {
+		if f.GetName() != family {
+			continue
+		}
+		for _, t := range f.GetTags() {
+			if t.GetKey() == name {
+				return t.GetValue()
+			}
+		}
+	}
+	return nil
+}
+
+// less compares two items in the order of the query, which is the timestamp or the tag of the index rule.
+// The nodes sort their items in the same way, so that the merged items are sorted as a single node does.
+func less(order *modelv1.QueryOrder, tag *orderTag, ta, tb int64, fa, fb []*modelv1.TagFamily) bool {
+	var c int
+	if tag != nil {
+		c = bytes.Compare(tag.value(fa), tag.value(fb))
+	} else if ta < tb {
+		c = -1
+	} else if ta > tb {
+		c = 1
+	}
+	if order.GetSort() == modelv1.Sort_SORT_DESC {
+		return c > 0
+	}
+	return c < 0
+}
+
+func limitOf(limit uint32) uint32 {
+	if limit == 0 {
+		return logical.DefaultLimit
+	}
+	return limit
+}
+
+// page cuts the page of the merged items.
+func page(total int, offset, limit uint32) (int, int) {
+	begin := int(offset)
+	if begin > total {
+		begin = total
+	}
+	end := begin + int(limitOf(limit))
+	if end > total {
+		end = total
+	}
+	return begin, end
+}
+
+// subStreamQuery asks every node for the first offset+limit elements, then the page is cut from the merged ones.
+func subStreamQuery(req *streamv1.QueryRequest, tag *orderTag) *streamv1.QueryRequest {
+	sub := proto.Clone(req).(*streamv1.QueryRequest)
+	sub.Offset = 0
+	sub.Limit = addLimit(req.GetOffset(), limitOf(req.GetLimit()))
+	sub.Limits = subLimits(req.GetLimits())
+	sub.Projection = tag.project(sub.Projection)
+	return sub
+}
+
+func addLimit(offset, limit uint32) uint32 {
+	if uint64(offset)+uint64(limit) > math.MaxUint32 {
+		return math.MaxUint32
+	}
+	return offset + limit
+}
+
+func mergeElements(req *streamv1.QueryRequest, tag *orderTag, partials [][]*streamv1.Element) []*streamv1.Element {
+	var elements []*streamv1.Element
+	for _, p := range partials {
+		elements = append(elements, p...)
+	}
+	sort.SliceStable(elements, func(i, j int) bool {
+		return less(req.GetOrderBy(), tag, elements[i].GetTimestamp().AsTime().UnixNano(), elements[j].GetTimestamp().AsTime().UnixNano(),
+			elements[i].GetTagFamilies(), elements[j].GetTagFamilies())
+	})
+	begin, end := page(len(elements), req.GetOffset(), req.GetLimit())
+	elements = elements[begin:end]
+	for _, e := range elements {
+		e.TagFamilies = tag.strip(e.TagFamilies)
+	}
+	return elements
+}
+
+// subMeasureQueries returns the sub-queries sent to every node.
+// The groups are merged across the nodes, so the grouped sub-queries return all the groups,
+// and the mean is rebuilt from a sum and a count. The custom aggregate functions return their partial states instead.
+func subMeasureQueries(req *measurev1.QueryRequest, tag *orderTag) []*measurev1.QueryRequest {
+	sub := proto.Clone(req).(*measurev1.QueryRequest)
+	sub.Offset = 0
+	sub.Limits = subLimits(req.GetLimits())
+	sub.TagProjection = tag.project(sub.TagProjection)
+	if req.GetGroupBy() == nil && req.GetAgg() == nil {
+		if req.GetTop() == nil {
+			sub.Limit = addLimit(req.GetOffset(), limitOf(req.GetLimit()))
+		} else {
+			sub.Limit = math.MaxUint32
+		}
+		return []*measurev1.QueryRequest{sub}
+	}
+	sub.Top = nil
+	sub.Limit = math.MaxUint32
+	if req.GetAgg().GetCustomFunction() != "" {
+		sub.Agg.PartialState = true
+		return []*measurev1.QueryRequest{sub}
+	}
+	if req.GetAgg().GetFunction() != modelv1.AggregationFunction_AGGREGATION_FUNCTION_MEAN {
+		return []*measurev1.QueryRequest{sub}
+	}
+	count := proto.Clone(sub).(*measurev1.QueryRequest)
+	sub.Agg.Function = modelv1.AggregationFunction_AGGREGATION_FUNCTION_SUM
+	count.Agg.Function = modelv1.AggregationFunction_AGGREGATION_FUNCTION_COUNT
+	return []*measurev1.QueryRequest{sub, count}
+}
+
+// mergeDataPoints merges the partial results of the sub-queries, which are in the order of subMeasureQueries for each node.
+func mergeDataPoints(req *measurev1.QueryRequest, tag *orderTag, partials [][][]*measurev1.DataPoint) ([]*measurev1.DataPoint, error) {
+	var dataPoints []*measurev1.DataPoint
+	switch {
+	case req.GetAgg().GetCustomFunction() != "":
+		var err error
+		if dataPoints, err = aggregateCustom(req, partials); err != nil {
+			return nil, err
+		}
+	case req.GetAgg() != nil:
+		dataPoints = aggregate(req, partials)
+	case req.GetGroupBy() != nil:
+		dataPoints = group(req, partials)
+	default:
+		for _, p := range partials {
+			dataPoints = append(dataPoints, p[0]...)
+		}
+		if req.GetTop() == nil {
+			sort.SliceStable(dataPoints, func(i, j int) bool {
+				return less(req.GetOrderBy(), tag, dataPoints[i].GetTimestamp().AsTime().UnixNano(), dataPoints[j].GetTimestamp().AsTime().UnixNano(),
+					dataPoints[i].GetTagFamilies(), dataPoints[j].GetTagFamilies())
+			})
+		}
+	}
+	if top := req.GetTop(); top != nil {
+		dataPoints = topN(top, dataPoints)
+	}
+	begin, end := page(len(dataPoints), req.GetOffset(), req.GetLimit())
+	dataPoints = dataPoints[begin:end]
+	for _, dp := range dataPoints {
+		dp.TagFamilies = tag.strip(dp.TagFamilies)
+	}
+	return dataPoints, nil
+}
+
+// groupKey returns the values of the group-by tags of the data point, which is empty if the query isn't grouped.
+// The data points grouped by the time buckets are stamped with the beginnings of their buckets, which are a part of the key.
+func groupKey(groupBy *measurev1.QueryRequest_GroupBy, dp *measurev1.DataPoint) string {
+	var key []byte
+	if groupBy.GetTimeBucket() != nil {
+		key = append(key, convert.Int64ToBytes(dp.GetTimestamp().AsTime().UnixNano())...)
+	}
+	for _, f := range groupBy.GetTagProjection().GetTagFamilies() {
+		for _, t := range f.GetTags() {
+			v, _ := proto.MarshalOptions{Deterministic: true}.Marshal(findTag(dp.GetTagFamilies(), f.GetName(), t))
+			key = append(key, v...)
+			key = append(key, 0)
+		}
+	}
+	return string(key)
+}
+
+func group(req *measurev1.QueryRequest, partials [][][]*measurev1.DataPoint) []*measurev1.DataPoint {
+	var keys []string
+	groups := make(map[string][]*measurev1.DataPoint)
+	for _, p := range partials {
+		for _, dp := range p[0] {
+			k := groupKey(req.GetGroupBy(), dp)
+			if _, ok := groups[k]; !ok {
+				keys = append(keys, k)
+			}
+			groups[k] = append(groups[k], dp)
+		}
+	}
+	dataPoints := make([]*measurev1.DataPoint, 0, len(keys))
+	for _, k := range keys {
+		dataPoints = append(dataPoints, groups[k]...)
+	}
+	return dataPoints
+}
+
+func aggregate(req *measurev1.QueryRequest, partials [][][]*measurev1.DataPoint) []*measurev1.DataPoint {
+	type aggGroup struct {
+		dp    *measurev1.DataPoint
+		value int64
+		count int64
+	}
+	fn := req.GetAgg().GetFunction()
+	var keys []string
+	groups := make(map[string]*aggGroup)
+	for _, p := range partials {
+		// the counts of the mean are returned by the second sub-query
+		counts := make(map[string]int64)
+		if len(p) > 1 {
+			for _, dp := range p[1] {
+				counts[groupKey(req.GetGroupBy(), dp)] = fieldValue(dp, req.GetAgg().GetFieldName())
+			}
+		}
+		for _, dp := range p[0] {
+			k := groupKey(req.GetGroupBy(), dp)
+			v := fieldValue(dp, req.GetAgg().GetFieldName())
+			c := counts[k]
+			g, ok := groups[k]
+			if !ok {
+				keys = append(keys, k)
+				groups[k] = &aggGroup{dp: dp, value: v, count: c}
+				continue
+			}
+			switch fn {
+			case modelv1.AggregationFunction_AGGREGATION_FUNCTION_MAX:
+				if v > g.value {
+					g.value = v
+				}
+			case modelv1.AggregationFunction_AGGREGATION_FUNCTION_MIN:
+				if v < g.value {
+					g.value = v
+				}
+			default:
+				g.value += v
+				g.count += c
+			}
+		}
+	}
+	dataPoints := make([]*measurev1.DataPoint, 0, len(keys))
+	for _, k := range keys {
+		g := groups[k]
+		v := g.value
+		if fn == modelv1.AggregationFunction_AGGREGATION_FUNCTION_MEAN {
+			if g.count == 0 {
+				v = 0
+			} else {
+				v = g.value / g.count
+			}
+		}
+		g.dp.Fields = []*measurev1.DataPoint_Field{{
+			Name:  req.GetAgg().GetFieldName(),
+			Value: &modelv1.FieldValue{Value: &modelv1.FieldValue_Int{Int: &modelv1.Int{Value: v}}},
+		}}
+		dataPoints = append(dataPoints, g.dp)
+	}
+	return dataPoints
+}
+
+// aggregateCustom merges the partial states of the custom aggregate function returned by the nodes.
+func aggregateCustom(req *measurev1.QueryRequest, partials [][][]*measurev1.DataPoint) ([]*measurev1.DataPoint, error) {
+	type aggGroup struct {
+		dp         *measurev1.DataPoint
+		aggregator aggregation.Aggregator
+	}
+	var keys []string
+	groups := make(map[string]*aggGroup)
+	for _, p := range partials {
+		for _, dp := range p[0] {
+			k := groupKey(req.GetGroupBy(), dp)
+			g, ok := groups[k]
+			if !ok {
+				aggregator, err := aggregation.NewAggregator(req.GetAgg().GetCustomFunction())
+				if err != nil {
+					return nil, err
+				}
+				keys = append(keys, k)
+				g = &aggGroup{dp: dp, aggregator: aggregator}
+				groups[k] = g
+			}
+			if err := g.aggregator.Merge(fieldBinary(dp, req.GetAgg().GetFieldName())); err != nil {
+				return nil, err
+			}
+		}
+	}
+	dataPoints := make([]*measurev1.DataPoint, 0, len(keys))
+	for _, k := range keys {
+		g := groups[k]
+		g.dp.Fields = []*measurev1.DataPoint_Field{{
+			Name:  req.GetAgg().GetFieldName(),
+			Value: &modelv1.FieldValue{Value: &modelv1.FieldValue_Int{Int: &modelv1.Int{Value: g.aggregator.Val()}}},
+		}}
+		dataPoints = append(dataPoints, g.dp)
+	}
+	return dataPoints, nil
+}
+
+func fieldBinary(dp *measurev1.DataPoint, name string) []byte {
+	for _, f := range dp.GetFields() {
+		if f.GetName() == name {
+			return f.GetValue().GetBinaryData()
+		}
+	}
+	return nil
+}
+
+func fieldValue(dp *measurev1.DataPoint, name string) int64 {
+	for _, f := range dp.GetFields() {
+		if f.GetName() == name {
+			return f.GetValue().GetInt().GetValue()
+		}
+	}
+	return 0
+}
+
+// topN picks the data points with the largest values, or the smallest ones if the sort is ascending.
+func topN(top *measurev1.QueryRequest_Top, dataPoints []*measurev1.DataPoint) []*measurev1.DataPoint {
+	sort.SliceStable(dataPoints, func(i, j int) bool {
+		vi, vj := fieldValue(dataPoints[i], top.GetFieldName()), fieldValue(dataPoints[j], top.GetFieldName())
+		if top.GetFieldValueSort() == modelv1.Sort_SORT_ASC {
+			return vi < vj
+		}
+		return vi > vj
+	})
+	if n := int(top.GetNumber()); n > 0 && n < len(dataPoints) {
+		return dataPoints[:n]
+	}
+	return dataPoints
+}
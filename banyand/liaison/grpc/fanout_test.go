@@ -0,0 +1,346 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"math"
+	"net"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	clusterv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/cluster/v1"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/query/aggregation"
+)
+
+type fakeQueryNode struct {
+	clusterv1.UnimplementedInternalQueryServiceServer
+	elements []*streamv1.Element
+	shardIDs []common.ShardID
+}
+
+func (n *fakeQueryNode) QueryStream(ctx context.Context, req *streamv1.QueryRequest) (*streamv1.QueryResponse, error) {
+	var err error
+	if n.shardIDs, err = assignedShards(ctx); err != nil {
+		return nil, err
+	}
+	elements := n.elements
+	if int(req.GetLimit()) < len(elements) {
+		elements = elements[:req.GetLimit()]
+	}
+	return &streamv1.QueryResponse{Elements: elements}, nil
+}
+
+var _ = Describe("FanOut", func() {
+	base := time.Now().Truncate(time.Millisecond)
+	element := func(id string, offset time.Duration) *streamv1.Element {
+		return &streamv1.Element{ElementId: id, Timestamp: timestamppb.New(base.Add(offset))}
+	}
+	ids := func(elements []*streamv1.Element) []string {
+		result := make([]string, 0, len(elements))
+		for _, e := range elements {
+			result = append(result, e.GetElementId())
+		}
+		return result
+	}
+	strTag := func(family, key, value string) []*modelv1.TagFamily {
+		return []*modelv1.TagFamily{{Name: family, Tags: []*modelv1.Tag{{
+			Key:   key,
+			Value: &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: value}}},
+		}}}}
+	}
+	dataPoint := func(service string, field string, value int64) *measurev1.DataPoint {
+		return &measurev1.DataPoint{
+			TagFamilies: strTag("default", "service", service),
+			Fields: []*measurev1.DataPoint_Field{{
+				Name:  field,
+				Value: &modelv1.FieldValue{Value: &modelv1.FieldValue_Int{Int: &modelv1.Int{Value: value}}},
+			}},
+		}
+	}
+	values := func(dataPoints []*measurev1.DataPoint) map[string]int64 {
+		result := make(map[string]int64)
+		for _, dp := range dataPoints {
+			result[findTag(dp.GetTagFamilies(), "default", "service").GetStr().GetValue()] = dp.GetFields()[0].GetValue().GetInt().GetValue()
+		}
+		return result
+	}
+	merge := func(req *measurev1.QueryRequest, partials [][][]*measurev1.DataPoint) []*measurev1.DataPoint {
+		dataPoints, err := mergeDataPoints(req, nil, partials)
+		Expect(err).NotTo(HaveOccurred())
+		return dataPoints
+	}
+
+	It("merges the elements in the order of the timestamp", func() {
+		req := &streamv1.QueryRequest{
+			Offset:  1,
+			Limit:   3,
+			OrderBy: &modelv1.QueryOrder{Sort: modelv1.Sort_SORT_DESC},
+		}
+		sub := subStreamQuery(req, nil)
+		Expect(sub.GetOffset()).To(BeZero())
+		Expect(sub.GetLimit()).To(Equal(uint32(4)))
+		merged := mergeElements(req, nil, [][]*streamv1.Element{
+			{element("a4", 4*time.Second), element("a2", 2*time.Second), element("a0", 0)},
+			{element("b5", 5*time.Second), element("b3", 3*time.Second), element("b1", time.Second)},
+		})
+		Expect(ids(merged)).To(Equal([]string{"a4", "b3", "a2"}))
+	})
+	It("sorts the elements by the tag of the index rule and strips it", func() {
+		req := &streamv1.QueryRequest{OrderBy: &modelv1.QueryOrder{IndexRuleName: "endpoint", Sort: modelv1.Sort_SORT_ASC}}
+		tag := &orderTag{family: "searchable", name: "endpoint"}
+		sub := subStreamQuery(req, tag)
+		Expect(sub.GetProjection().GetTagFamilies()).To(HaveLen(1))
+		Expect(sub.GetProjection().GetTagFamilies()[0].GetTags()).To(Equal([]string{"endpoint"}))
+		c := element("c", 0)
+		c.TagFamilies = strTag("searchable", "endpoint", "/c")
+		a := element("a", time.Second)
+		a.TagFamilies = strTag("searchable", "endpoint", "/a")
+		b := element("b", 2*time.Second)
+		b.TagFamilies = strTag("searchable", "endpoint", "/b")
+		merged := mergeElements(req, tag, [][]*streamv1.Element{{c}, {a, b}})
+		Expect(ids(merged)).To(Equal([]string{"a", "b", "c"}))
+		for _, e := range merged {
+			Expect(e.GetTagFamilies()).To(BeEmpty())
+		}
+	})
+	It("combines the groups aggregated by the nodes", func() {
+		req := &measurev1.QueryRequest{
+			GroupBy: &measurev1.QueryRequest_GroupBy{
+				TagProjection: &modelv1.TagProjection{TagFamilies: []*modelv1.TagProjection_TagFamily{
+					{Name: "default", Tags: []string{"service"}},
+				}},
+				FieldName: "latency",
+			},
+			Agg:    &measurev1.QueryRequest_Aggregation{Function: modelv1.AggregationFunction_AGGREGATION_FUNCTION_MEAN, FieldName: "latency"},
+			Top:    &measurev1.QueryRequest_Top{Number: 1, FieldName: "latency"},
+			Offset: 1,
+		}
+		subQueries := subMeasureQueries(req, nil)
+		Expect(subQueries).To(HaveLen(2))
+		Expect(subQueries[0].GetAgg().GetFunction()).To(Equal(modelv1.AggregationFunction_AGGREGATION_FUNCTION_SUM))
+		Expect(subQueries[1].GetAgg().GetFunction()).To(Equal(modelv1.AggregationFunction_AGGREGATION_FUNCTION_COUNT))
+		Expect(subQueries[0].GetTop()).To(BeNil())
+		Expect(subQueries[0].GetOffset()).To(BeZero())
+
+		partials := [][][]*measurev1.DataPoint{
+			{
+				{dataPoint("svc-1", "latency", 300), dataPoint("svc-2", "latency", 100)},
+				{dataPoint("svc-2", "latency", 1), dataPoint("svc-1", "latency", 3)},
+			},
+			{
+				{dataPoint("svc-1", "latency", 100)},
+				{dataPoint("svc-1", "latency", 1)},
+			},
+		}
+		req.Top = nil
+		req.Offset = 0
+		Expect(values(merge(req, partials))).To(Equal(map[string]int64{"svc-1": 100, "svc-2": 100}))
+
+		req.Agg.Function = modelv1.AggregationFunction_AGGREGATION_FUNCTION_MAX
+		Expect(values(merge(req, [][][]*measurev1.DataPoint{
+			{{dataPoint("svc-1", "latency", 300), dataPoint("svc-2", "latency", 100)}},
+			{{dataPoint("svc-1", "latency", 500)}},
+		}))).To(Equal(map[string]int64{"svc-1": 500, "svc-2": 100}))
+	})
+	It("merges the partial states of the custom functions", func() {
+		// spread tracks the min and the max values, whose difference is the result
+		type spread struct {
+			Min, Max int64
+		}
+		combine := func(a, b spread) spread {
+			if b.Min < a.Min {
+				a.Min = b.Min
+			}
+			if b.Max > a.Max {
+				a.Max = b.Max
+			}
+			return a
+		}
+		aggregation.MustRegister(aggregation.Function[spread]{
+			Name: "fanout-max-spread",
+			Init: func() spread { return spread{Min: math.MaxInt64, Max: math.MinInt64} },
+			In: func(s spread, v int64) spread {
+				return combine(s, spread{Min: v, Max: v})
+			},
+			Merge:    combine,
+			Finalize: func(s spread) int64 { return s.Max - s.Min },
+		})
+		req := &measurev1.QueryRequest{
+			GroupBy: &measurev1.QueryRequest_GroupBy{
+				TagProjection: &modelv1.TagProjection{TagFamilies: []*modelv1.TagProjection_TagFamily{
+					{Name: "default", Tags: []string{"service"}},
+				}},
+				FieldName: "latency",
+			},
+			Agg: &measurev1.QueryRequest_Aggregation{CustomFunction: "fanout-max-spread", FieldName: "latency"},
+		}
+		subQueries := subMeasureQueries(req, nil)
+		Expect(subQueries).To(HaveLen(1))
+		Expect(subQueries[0].GetAgg().GetPartialState()).To(BeTrue())
+		partial := func(service string, values ...int64) *measurev1.DataPoint {
+			aggregator, err := aggregation.NewAggregator("fanout-max-spread")
+			Expect(err).NotTo(HaveOccurred())
+			for _, v := range values {
+				aggregator.In(v)
+			}
+			dp := dataPoint(service, "latency", 0)
+			dp.Fields[0].Value = &modelv1.FieldValue{Value: &modelv1.FieldValue_BinaryData{BinaryData: aggregator.State()}}
+			return dp
+		}
+		Expect(values(merge(req, [][][]*measurev1.DataPoint{
+			{{partial("svc-1", 10, 30), partial("svc-2", 5)}},
+			{{partial("svc-1", 50)}},
+		}))).To(Equal(map[string]int64{"svc-1": 40, "svc-2": 0}))
+
+		req.Agg.CustomFunction = "absent"
+		_, err := mergeDataPoints(req, nil, [][][]*measurev1.DataPoint{{{partial("svc-1", 1)}}})
+		Expect(err).To(MatchError(aggregation.ErrUnknownFunc))
+	})
+	It("keeps the time buckets of the groups apart", func() {
+		req := &measurev1.QueryRequest{
+			GroupBy: &measurev1.QueryRequest_GroupBy{
+				TagProjection: &modelv1.TagProjection{TagFamilies: []*modelv1.TagProjection_TagFamily{
+					{Name: "default", Tags: []string{"service"}},
+				}},
+				FieldName:  "total",
+				TimeBucket: &measurev1.QueryRequest_GroupBy_TimeBucket{Interval: durationpb.New(time.Hour)},
+			},
+			Agg: &measurev1.QueryRequest_Aggregation{Function: modelv1.AggregationFunction_AGGREGATION_FUNCTION_SUM, FieldName: "total"},
+		}
+		bucket := func(value int64, offset time.Duration) *measurev1.DataPoint {
+			dp := dataPoint("svc-1", "total", value)
+			dp.Timestamp = timestamppb.New(base.Add(offset))
+			return dp
+		}
+		merged := merge(req, [][][]*measurev1.DataPoint{
+			{{bucket(1, 0), bucket(2, time.Hour)}},
+			{{bucket(3, 0)}},
+		})
+		Expect(merged).To(HaveLen(2))
+		Expect(merged[0].GetFields()[0].GetValue().GetInt().GetValue()).To(Equal(int64(4)))
+		Expect(merged[1].GetFields()[0].GetValue().GetInt().GetValue()).To(Equal(int64(2)))
+	})
+	It("picks the top data points of all the nodes", func() {
+		req := &measurev1.QueryRequest{Top: &measurev1.QueryRequest_Top{Number: 2, FieldName: "total"}}
+		subQueries := subMeasureQueries(req, nil)
+		Expect(subQueries).To(HaveLen(1))
+		Expect(subQueries[0].GetTop()).NotTo(BeNil())
+		merged := merge(req, [][][]*measurev1.DataPoint{
+			{{dataPoint("svc-1", "total", 1), dataPoint("svc-2", "total", 5)}},
+			{{dataPoint("svc-3", "total", 3), dataPoint("svc-4", "total", 4)}},
+		})
+		Expect(values(merged)).To(Equal(map[string]int64{"svc-2": 5, "svc-4": 4}))
+	})
+	It("sends the sub-queries to the owners", func() {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		ser := grpclib.NewServer()
+		clusterv1.RegisterInternalQueryServiceServer(ser, &fakeQueryNode{
+			elements: []*streamv1.Element{element("r3", 3*time.Second), element("r1", time.Second)},
+		})
+		go func() {
+			_ = ser.Serve(lis)
+		}()
+		defer ser.Stop()
+		router := newNodeRouter("local")
+		router.log = logger.GetLogger("test")
+		defer router.close()
+		router.Rev(bus.NewMessage(bus.MessageID(1), &databasev1.NodeEvent{
+			Node:   &databasev1.Node{Id: "local", Addr: "localhost:17912"},
+			Action: databasev1.Action_ACTION_PUT,
+		}))
+		router.Rev(bus.NewMessage(bus.MessageID(2), &databasev1.NodeEvent{
+			Node:   &databasev1.Node{Id: "remote", Addr: lis.Addr().String()},
+			Action: databasev1.Action_ACTION_PUT,
+		}))
+		placement := router.placement("default", 32, 1)
+		Expect(allLocal(placement)).To(BeFalse())
+
+		req := &streamv1.QueryRequest{Metadata: &commonv1.Metadata{Group: "default", Name: "sw"}, Limit: 3}
+		sub := subStreamQuery(req, nil)
+		partials, err := fanOut(context.Background(), router, placement, false, func([]common.ShardID) ([]*streamv1.Element, error) {
+			return []*streamv1.Element{element("l0", 0), element("l2", 2*time.Second)}, nil
+		}, func(ctx context.Context, conn *grpclib.ClientConn) ([]*streamv1.Element, error) {
+			resp, errQuery := clusterv1.NewInternalQueryServiceClient(conn).QueryStream(ctx, sub)
+			return resp.GetElements(), errQuery
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ids(mergeElements(req, nil, partials))).To(Equal([]string{"l0", "r1", "l2"}))
+	})
+	It("falls back to the replicas of an unreachable node", func() {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		replica := &fakeQueryNode{elements: []*streamv1.Element{element("r1", time.Second)}}
+		ser := grpclib.NewServer()
+		clusterv1.RegisterInternalQueryServiceServer(ser, replica)
+		go func() {
+			_ = ser.Serve(lis)
+		}()
+		defer ser.Stop()
+		down, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(down.Close()).To(Succeed())
+		router := newNodeRouter("local")
+		router.log = logger.GetLogger("test")
+		defer router.close()
+
+		primary := &databasev1.Node{Id: "down", Addr: down.Addr().String()}
+		secondary := &databasev1.Node{Id: "replica", Addr: lis.Addr().String()}
+		placement := map[common.ShardID][]*databasev1.Node{
+			0: {primary, secondary},
+			1: {nil, primary},
+			2: {primary, nil},
+		}
+		req := &streamv1.QueryRequest{Metadata: &commonv1.Metadata{Group: "default", Name: "sw"}, Limit: 3}
+		sub := subStreamQuery(req, nil)
+		var localShards [][]common.ShardID
+		queryLocal := func(shardIDs []common.ShardID) ([]*streamv1.Element, error) {
+			localShards = append(localShards, shardIDs)
+			return []*streamv1.Element{element("l"+strconv.Itoa(int(shardIDs[0])), 0)}, nil
+		}
+		queryRemote := func(ctx context.Context, conn *grpclib.ClientConn) ([]*streamv1.Element, error) {
+			resp, errQuery := clusterv1.NewInternalQueryServiceClient(conn).QueryStream(ctx, sub)
+			return resp.GetElements(), errQuery
+		}
+		partials, err := fanOut(context.Background(), router, placement, true, queryLocal, queryRemote)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ids(mergeElements(req, nil, partials))).To(ConsistOf("l1", "l2", "r1"))
+		Expect(localShards).To(Equal([][]common.ShardID{{1}, {2}}))
+		Expect(replica.shardIDs).To(Equal([]common.ShardID{0}))
+
+		delete(placement, 0)
+		placement[2] = []*databasev1.Node{primary}
+		_, err = fanOut(context.Background(), router, placement, true, queryLocal, queryRemote)
+		Expect(err).To(HaveOccurred())
+	})
+})
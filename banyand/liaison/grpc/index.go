@@ -0,0 +1,95 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/api/data"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/banyand/metadata"
+	"github.com/apache/skywalking-banyandb/banyand/queue"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+)
+
+type indexServer struct {
+	databasev1.UnimplementedIndexServiceServer
+	schemaRegistry metadata.Service
+	pipeline       queue.Queue
+}
+
+func (s *indexServer) Stats(ctx context.Context, req *databasev1.IndexServiceStatsRequest) (*databasev1.IndexServiceStatsResponse, error) {
+	msg, err := s.publish(ctx, req.GetMetadata(), data.TopicStreamIndexStats, data.TopicMeasureIndexStats, req)
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := msg.(*databasev1.IndexServiceStatsResponse); ok {
+		return d, nil
+	}
+	return nil, ErrIndexMsg
+}
+
+func (s *indexServer) Rebuild(ctx context.Context, req *databasev1.IndexServiceRebuildRequest) (*databasev1.IndexServiceRebuildResponse, error) {
+	msg, err := s.publish(ctx, req.GetMetadata(), data.TopicStreamIndexRebuild, data.TopicMeasureIndexRebuild, req)
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := msg.(*databasev1.IndexServiceRebuildResponse); ok {
+		return d, nil
+	}
+	return nil, ErrIndexMsg
+}
+
+// publish sends req to the stream or the measure module by the catalog of the group, and returns the response data.
+func (s *indexServer) publish(ctx context.Context, md *commonv1.Metadata, streamTopic, measureTopic bus.Topic, req interface{}) (interface{}, error) {
+	if md.GetGroup() == "" || md.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "the group or the name is absent")
+	}
+	g, err := s.schemaRegistry.GroupRegistry().GetGroup(ctx, md.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	var topic bus.Topic
+	switch g.GetCatalog() {
+	case commonv1.Catalog_CATALOG_STREAM:
+		topic = streamTopic
+	case commonv1.Catalog_CATALOG_MEASURE:
+		topic = measureTopic
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "the group %s of the catalog %s has no indices", md.GetGroup(), g.GetCatalog())
+	}
+	feat, err := s.pipeline.Publish(topic, bus.NewMessage(bus.MessageID(time.Now().UnixNano()), req))
+	if err != nil {
+		return nil, err
+	}
+	msg, err := feat.Get()
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := msg.Data().(common.Error); ok {
+		return nil, errors.WithMessage(ErrIndexMsg, e.Msg())
+	}
+	return msg.Data(), nil
+}
@@ -0,0 +1,81 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/grpchelper"
+)
+
+var _ = Describe("Interceptors", func() {
+	var gracefulStop func()
+	var conn *grpclib.ClientConn
+	var calls []string
+	var mu sync.Mutex
+	record := func(name string) grpclib.UnaryServerInterceptor {
+		return func(ctx context.Context, req interface{}, info *grpclib.UnaryServerInfo, handler grpclib.UnaryHandler) (interface{}, error) {
+			mu.Lock()
+			calls = append(calls, name+" "+info.FullMethod)
+			mu.Unlock()
+			return handler(ctx, req)
+		}
+	}
+	deny := func(ctx context.Context, req interface{}, info *grpclib.UnaryServerInfo, handler grpclib.UnaryHandler) (interface{}, error) {
+		if info.FullMethod == "/banyandb.stream.v1.StreamService/Query" {
+			return nil, status.Error(codes.PermissionDenied, "denied")
+		}
+		return handler(ctx, req)
+	}
+	BeforeEach(func() {
+		calls = nil
+		gracefulStop = setupForRegistry(record("first"), deny, record("second"))
+		var err error
+		conn, err = grpchelper.Conn("localhost:17912", 10*time.Second, grpclib.WithTransportCredentials(insecure.NewCredentials()))
+		Expect(err).NotTo(HaveOccurred())
+	})
+	AfterEach(func() {
+		_ = conn.Close()
+		gracefulStop()
+	})
+	It("runs the registered interceptors in order", func() {
+		_, err := databasev1.NewServerInfoServiceClient(conn).Get(context.TODO(), &databasev1.ServerInfoServiceGetRequest{})
+		Expect(err).ShouldNot(HaveOccurred())
+		_, err = streamv1.NewStreamServiceClient(conn).Query(context.TODO(), &streamv1.QueryRequest{})
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(calls[len(calls)-3:]).To(Equal([]string{
+			"first /banyandb.database.v1.ServerInfoService/Get",
+			"second /banyandb.database.v1.ServerInfoService/Get",
+			// the denied call never reaches the interceptors after the denying one
+			"first /banyandb.stream.v1.StreamService/Query",
+		}))
+	})
+})
@@ -0,0 +1,149 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+)
+
+const (
+	queryTimeoutFlag          = "query-timeout"
+	queryMaxScannedSeriesFlag = "query-max-scanned-series"
+	queryMaxScannedBlocksFlag = "query-max-scanned-blocks"
+	queryMaxResponseBytesFlag = "query-max-response-bytes"
+	queryMaxResponseItemsFlag = "query-max-response-items"
+)
+
+// queryLimits are the server's limits of a query, 0 means unlimited.
+type queryLimits struct {
+	timeout          time.Duration
+	maxScannedSeries int64
+	maxScannedBlocks int64
+	maxResponseBytes int64
+	maxResponseItems int64
+	// mu guards the limits, which are reloaded at runtime
+	mu sync.RWMutex
+}
+
+func (l *queryLimits) validate() error {
+	if l.timeout < 0 || l.maxScannedSeries < 0 || l.maxScannedBlocks < 0 || l.maxResponseBytes < 0 || l.maxResponseItems < 0 {
+		return errNegativeQueryLimit
+	}
+	return nil
+}
+
+// apply caps the requested limits with the server's ones.
+func (l *queryLimits) apply(requested *modelv1.QueryLimits) *modelv1.QueryLimits {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	timeout := capLimit(int64(requested.GetTimeout().AsDuration()), int64(l.timeout))
+	applied := &modelv1.QueryLimits{
+		MaxScannedSeries: capLimit(requested.GetMaxScannedSeries(), l.maxScannedSeries),
+		MaxScannedBlocks: capLimit(requested.GetMaxScannedBlocks(), l.maxScannedBlocks),
+		MaxResponseBytes: capLimit(requested.GetMaxResponseBytes(), l.maxResponseBytes),
+		MaxResponseItems: capLimit(requested.GetMaxResponseItems(), l.maxResponseItems),
+		Truncate:         requested.GetTruncate(),
+	}
+	if timeout > 0 {
+		applied.Timeout = durationpb.New(time.Duration(timeout))
+	}
+	return applied
+}
+
+// flags returns the current values of the limits keyed by their flags.
+func (l *queryLimits) flags() map[string]string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return map[string]string{
+		queryTimeoutFlag:          l.timeout.String(),
+		queryMaxScannedSeriesFlag: strconv.FormatInt(l.maxScannedSeries, 10),
+		queryMaxScannedBlocksFlag: strconv.FormatInt(l.maxScannedBlocks, 10),
+		queryMaxResponseBytesFlag: strconv.FormatInt(l.maxResponseBytes, 10),
+		queryMaxResponseItemsFlag: strconv.FormatInt(l.maxResponseItems, 10),
+	}
+}
+
+// prepareReload validates the new values of the limits, and returns the function applying them.
+func (l *queryLimits) prepareReload(values map[string]string) (func(), error) {
+	l.mu.RLock()
+	next := queryLimits{
+		timeout:          l.timeout,
+		maxScannedSeries: l.maxScannedSeries,
+		maxScannedBlocks: l.maxScannedBlocks,
+		maxResponseBytes: l.maxResponseBytes,
+		maxResponseItems: l.maxResponseItems,
+	}
+	l.mu.RUnlock()
+	for name, value := range values {
+		var err error
+		switch name {
+		case queryTimeoutFlag:
+			next.timeout, err = time.ParseDuration(value)
+		case queryMaxScannedSeriesFlag:
+			next.maxScannedSeries, err = strconv.ParseInt(value, 10, 64)
+		case queryMaxScannedBlocksFlag:
+			next.maxScannedBlocks, err = strconv.ParseInt(value, 10, 64)
+		case queryMaxResponseBytesFlag:
+			next.maxResponseBytes, err = strconv.ParseInt(value, 10, 64)
+		case queryMaxResponseItemsFlag:
+			next.maxResponseItems, err = strconv.ParseInt(value, 10, 64)
+		}
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid %s", name)
+		}
+	}
+	if err := next.validate(); err != nil {
+		return nil, err
+	}
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.timeout = next.timeout
+		l.maxScannedSeries = next.maxScannedSeries
+		l.maxScannedBlocks = next.maxScannedBlocks
+		l.maxResponseBytes = next.maxResponseBytes
+		l.maxResponseItems = next.maxResponseItems
+	}, nil
+}
+
+func capLimit(requested, limit int64) int64 {
+	if limit <= 0 || (requested > 0 && requested < limit) {
+		return requested
+	}
+	return limit
+}
+
+// resourceExhausted converts the detail to a RESOURCE_EXHAUSTED error carrying it.
+func resourceExhausted(detail *modelv1.ResourceExhausted) error {
+	st := status.Newf(codes.ResourceExhausted, "the query exceeds the limit %s %d", detail.GetResource(), detail.GetLimit())
+	stWithDetail, err := st.WithDetails(detail)
+	if err != nil {
+		return st.Err()
+	}
+	return stWithDetail.Err()
+}
@@ -23,12 +23,21 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	grpclib "google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/apache/skywalking-banyandb/api/common"
 	"github.com/apache/skywalking-banyandb/api/data"
+	clusterv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/cluster/v1"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
 	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/banyand/metadata"
+	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
 	"github.com/apache/skywalking-banyandb/banyand/tsdb"
 	"github.com/apache/skywalking-banyandb/pkg/bus"
 	"github.com/apache/skywalking-banyandb/pkg/timestamp"
@@ -36,16 +45,39 @@ import (
 
 type measureService struct {
 	*discoveryService
+	schemaRegistry metadata.Repo
+	limits         *queryLimits
+	batch          *batchPolicy
+	filter         *writeFilter
+	validator      *writeValidator
+	router         *nodeRouter
+	replicator     *replicator
+	drainer        *drainer
+	subscriptions  *subscriptionHub
+	rejections     *rejectionLog
+	coalescer      *writeCoalescer[*measurev1.InternalWriteRequest]
+	snapshots      *snapshots[*measurev1.DataPoint]
 	measurev1.UnimplementedMeasureServiceServer
 }
 
 func (ms *measureService) Write(measure measurev1.MeasureService_WriteServer) error {
-	reply := func() error {
-		if err := measure.Send(&measurev1.WriteResponse{}); err != nil {
+	return ms.serveWrite(measure, false)
+}
+
+// serveWrite writes the requests received by the stream, which are forwarded by another liaison if forwarded is true.
+func (ms *measureService) serveWrite(measure measurev1.MeasureService_WriteServer, forwarded bool) error {
+	if !ms.drainer.acquire() {
+		return errDraining
+	}
+	defer ms.drainer.release()
+	reply := func(writeErrors []*modelv1.WriteError) error {
+		if err := measure.Send(&measurev1.WriteResponse{Errors: writeErrors}); err != nil {
 			return err
 		}
 		return nil
 	}
+	fw := ms.newForwarder(measure.Context())
+	defer fw.close()
 	for {
 		writeRequest, err := measure.Recv()
 		if err == io.EOF {
@@ -54,45 +86,275 @@ func (ms *measureService) Write(measure measurev1.MeasureService_WriteServer) er
 		if err != nil {
 			return err
 		}
-		if errTime := timestamp.CheckPb(writeRequest.DataPoint.Timestamp); errTime != nil {
-			ms.log.Error().Err(errTime).Msg("the data point time is invalid")
-			if errResp := reply(); errResp != nil {
-				return errResp
-			}
-			continue
+		if errSend := reply(ms.write(measure.Context(), fw, writeRequest, forwarded)); errSend != nil {
+			return errSend
 		}
-		entity, shardID, err := ms.navigate(writeRequest.GetMetadata(), writeRequest.GetDataPoint().GetTagFamilies())
-		if err != nil {
-			ms.log.Error().Err(err).Msg("failed to navigate to the write target")
-			if errResp := reply(); errResp != nil {
-				return errResp
+	}
+}
+
+func (ms *measureService) newForwarder(ctx context.Context) *forwarder[*measurev1.WriteRequest, *measurev1.WriteResponse] {
+	return newForwarder(ctx, ms.router, openMeasureWrite, true)
+}
+
+// write writes the data point to the replicas of its shard, the forwarded ones are written locally only.
+// It returns the reason if the data point is rejected by the schema or not received by a replica.
+func (ms *measureService) write(ctx context.Context, fw *forwarder[*measurev1.WriteRequest, *measurev1.WriteResponse],
+	writeRequest *measurev1.WriteRequest, forwarded bool,
+) []*modelv1.WriteError {
+	if wErr := ms.validator.validateDataPoint(ctx, writeRequest.GetMetadata(), writeRequest.GetDataPoint()); wErr != nil {
+		ms.rejections.record(rejectedTypeMeasure, writeRequest.GetMetadata(), wErr, writeRequest.GetDataPoint())
+		return []*modelv1.WriteError{wErr}
+	}
+	if !forwarded {
+		target, accepted := ms.filter.apply(ctx, schema.KindMeasure, writeRequest.GetMetadata(), writeRequest.GetDataPoint().GetTagFamilies())
+		if !accepted {
+			return nil
+		}
+		writeRequest.Metadata = target
+	}
+	entity, shardID, err := ms.navigate(writeRequest.GetMetadata(), writeRequest.GetDataPoint().GetTagFamilies(),
+		writeRequest.GetDataPoint().GetTimestamp().AsTime())
+	if err != nil {
+		wErr := navigationError(writeRequest.GetMetadata(), err)
+		ms.rejections.record(rejectedTypeMeasure, writeRequest.GetMetadata(), wErr, writeRequest.GetDataPoint())
+		return []*modelv1.WriteError{wErr}
+	}
+	replicas, r := []*databasev1.Node{nil}, replication{replicas: 1}
+	if !forwarded {
+		group := writeRequest.GetMetadata().GetGroup()
+		r = ms.shardRepo.replication(getID(&commonv1.Metadata{Name: group}))
+		replicas = ms.router.replicas(group, shardID, r.replicas)
+	}
+	acks := newReplicaAcks()
+	for i, node := range replicas {
+		switch {
+		case node == nil:
+			ms.subscriptions.publish(ctx, schema.KindMeasure, writeRequest.GetMetadata(),
+				writeRequest.GetDataPoint().GetTagFamilies(), writeRequest.GetDataPoint())
+			ms.coalescer.add(&measurev1.InternalWriteRequest{
+				Request:    writeRequest,
+				ShardId:    uint32(shardID),
+				SeriesHash: tsdb.HashEntity(entity),
+			})
+		case i > 0 && r.async():
+			ms.replicator.enqueue(node, writeRequest)
+		default:
+			if errFwd := fw.send(node, writeRequest); errFwd != nil {
+				ms.log.Error().Err(errFwd).Str("node", node.GetId()).Msg("failed to forward the data point")
+				acks.fail(node, []uint32{0}, errFwd)
+				continue
 			}
-			continue
+			acks.add(node, []uint32{0})
+		}
+	}
+	return awaitReplicas(fw, acks)
+}
+
+func (ms *measureService) Subscribe(req *measurev1.SubscribeRequest, stream measurev1.MeasureService_SubscribeServer) error {
+	return ms.subscriptions.subscribe(stream, schema.KindMeasure, subscription{
+		metadata:   req.GetMetadata(),
+		filter:     req.GetFilter(),
+		bufferSize: req.GetBufferSize(),
+		policy:     req.GetDropPolicy(),
+	}, func(item proto.Message, dropped uint64) error {
+		return stream.Send(&measurev1.SubscribeResponse{DataPoint: item.(*measurev1.DataPointValue), Dropped: dropped})
+	})
+}
+
+func (ms *measureService) Query(ctx context.Context, entityCriteria *measurev1.QueryRequest) (*measurev1.QueryResponse, error) {
+	defer ms.drainer.trackQuery()()
+	page, err := resumeQuery(entityCriteria, entityCriteria.GetLimits())
+	if err != nil {
+		return nil, err
+	}
+	if page.exhausted {
+		return &measurev1.QueryResponse{ReadTimestamp: entityCriteria.GetReadTimestamp(), SnapshotToken: entityCriteria.GetSnapshotToken()}, nil
+	}
+	entityCriteria.Limits = ms.limits.apply(entityCriteria.GetLimits())
+	if token := entityCriteria.GetSnapshotToken(); token != "" {
+		session, errGet := ms.snapshots.get(token, page.fingerprint)
+		if errGet != nil {
+			return nil, errGet
 		}
-		message := bus.NewMessage(bus.MessageID(time.Now().UnixNano()), &measurev1.InternalWriteRequest{
-			Request:    writeRequest,
-			ShardId:    uint32(shardID),
-			SeriesHash: tsdb.HashEntity(entity),
+		return ms.snapshotPage(entityCriteria, page, token, session)
+	}
+	if err = timestamp.CheckTimeRange(entityCriteria.GetTimeRange()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v is invalid :%s", entityCriteria.GetTimeRange(), err)
+	}
+	readTimestamp := entityCriteria.GetReadTimestamp()
+	snapshot := snapshotRequested(entityCriteria.GetSnapshot(), readTimestamp)
+	if snapshot && readTimestamp == nil {
+		readTimestamp = timestamppb.Now()
+	}
+	timeRange, visible, err := pinSnapshot(readTimestamp, entityCriteria.GetTimeRange())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v is invalid :%s", entityCriteria.GetReadTimestamp(), err)
+	}
+	if !visible {
+		return &measurev1.QueryResponse{ReadTimestamp: readTimestamp}, nil
+	}
+	entityCriteria.TimeRange = timeRange
+	if snapshot {
+		// the first page captures all the results within the max items, which the pages are cut from
+		first := proto.Clone(entityCriteria).(*measurev1.QueryRequest)
+		first.Offset, first.Limit = 0, uint32(ms.snapshots.policy.maxItems)
+		first.Limits = subLimits(entityCriteria.GetLimits())
+		resp, errQuery := ms.query(ctx, first)
+		if errQuery != nil {
+			return nil, errQuery
+		}
+		token, session, errOpen := ms.snapshots.open(page.fingerprint, readTimestamp, resp.GetDataPoints())
+		if errOpen != nil {
+			return nil, errOpen
+		}
+		return ms.snapshotPage(entityCriteria, page, token, session)
+	}
+	resp, err := ms.query(ctx, entityCriteria)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Truncation == nil {
+		resp.DataPoints, resp.Truncation = truncateItems(entityCriteria.GetLimits(), resp.GetDataPoints())
+	}
+	page.continueAfter(resp.GetTruncation(), len(resp.GetDataPoints()), readTimestamp)
+	resp.ReadTimestamp = readTimestamp
+	return resp, nil
+}
+
+// snapshotPage cuts the page of the query from the results captured by its snapshot.
+func (ms *measureService) snapshotPage(req *measurev1.QueryRequest, page *queryPage, token string,
+	session *snapshotSession[*measurev1.DataPoint],
+) (*measurev1.QueryResponse, error) {
+	dataPoints, err := session.page(req.GetOffset(), req.GetLimit())
+	if err != nil {
+		return nil, err
+	}
+	resp := &measurev1.QueryResponse{ReadTimestamp: session.readTimestamp, SnapshotToken: token}
+	resp.DataPoints, resp.Truncation = truncateItems(req.GetLimits(), dataPoints)
+	page.snapshotToken = token
+	page.continueAfter(resp.GetTruncation(), len(resp.GetDataPoints()), session.readTimestamp)
+	return resp, nil
+}
+
+func (ms *measureService) QueryBatches(req *measurev1.QueryRequest, stream measurev1.MeasureService_QueryBatchesServer) error {
+	if placement, _ := ms.shardPlacement(ms.router, req.GetMetadata().GetGroup()); streamable(req, placement) {
+		return ms.streamBatches(req, stream)
+	}
+	resp, err := ms.Query(stream.Context(), req)
+	if err != nil {
+		return err
+	}
+	b := newBatcher(ms.batch, func(dataPoints []*measurev1.DataPoint) error {
+		return stream.Send(&measurev1.QueryResponse{DataPoints: dataPoints, ReadTimestamp: resp.GetReadTimestamp()})
+	})
+	for _, dp := range resp.GetDataPoints() {
+		if err = b.add(dp); err != nil {
+			break
+		}
+	}
+	if errClose := b.close(err == nil); err == nil {
+		err = errClose
+	}
+	if err != nil || resp.GetTruncation() == nil {
+		return err
+	}
+	// the truncation and the snapshot token of the next page follow the last batch
+	return stream.Send(&measurev1.QueryResponse{
+		ReadTimestamp: resp.GetReadTimestamp(),
+		Truncation:    resp.GetTruncation(),
+		SnapshotToken: resp.GetSnapshotToken(),
+	})
+}
+
+// streamBatches sends the data points of the query in batches as they're produced by the executor.
+func (ms *measureService) streamBatches(req *measurev1.QueryRequest, stream measurev1.MeasureService_QueryBatchesServer) error {
+	defer ms.drainer.trackQuery()()
+	if err := timestamp.CheckTimeRange(req.GetTimeRange()); err != nil {
+		return status.Errorf(codes.InvalidArgument, "%v is invalid :%s", req.GetTimeRange(), err)
+	}
+	req.Limits = ms.limits.apply(req.GetLimits())
+	b := newBatcher(ms.batch, func(dataPoints []*measurev1.DataPoint) error {
+		return stream.Send(&measurev1.QueryResponse{DataPoints: dataPoints})
+	})
+	resp, err := ms.publishQuery(req, &data.QuerySink{Request: req, Send: func(item proto.Message) error {
+		return b.add(item.(*measurev1.DataPoint))
+	}})
+	if errClose := b.close(err == nil); err == nil {
+		err = errClose
+	}
+	if err != nil || resp.GetTruncation() == nil {
+		return err
+	}
+	return stream.Send(&measurev1.QueryResponse{Truncation: resp.GetTruncation()})
+}
+
+// query fans the query out to the data nodes holding the replicas of the shards of the group,
+// then merges and aggregates their data points.
+func (ms *measureService) query(ctx context.Context, req *measurev1.QueryRequest) (*measurev1.QueryResponse, error) {
+	placement, restricted := ms.shardPlacement(ms.router, req.GetMetadata().GetGroup())
+	if allLocal(placement) {
+		return ms.queryLocal(req)
+	}
+	tag, err := resolveOrderTag(ctx, ms.schemaRegistry, req.GetMetadata(), req.GetOrderBy(), req.GetTagProjection(),
+		func() ([]*databasev1.TagFamilySpec, error) {
+			measure, errGet := ms.schemaRegistry.MeasureRegistry().GetMeasure(ctx, req.GetMetadata())
+			if errGet != nil {
+				return nil, errGet
+			}
+			return measure.GetTagFamilies(), nil
 		})
-		_, errWritePub := ms.pipeline.Publish(data.TopicMeasureWrite, message)
-		if errWritePub != nil {
-			ms.log.Error().Err(errWritePub).Msg("failed to send a message")
-			if errResp := reply(); errResp != nil {
-				return errResp
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v is invalid :%s", req.GetOrderBy(), err)
+	}
+	subQueries := subMeasureQueries(req, tag)
+	partials, err := fanOut(ctx, ms.router, placement, restricted, func(shardIDs []common.ShardID) ([][]*measurev1.DataPoint, error) {
+		result := make([][]*measurev1.DataPoint, 0, len(subQueries))
+		for _, q := range subQueries {
+			resp, errLocal := ms.queryLocal(proto.Clone(q).(*measurev1.QueryRequest), shardIDs...)
+			if errLocal != nil {
+				return nil, errLocal
 			}
-			continue
+			result = append(result, resp.GetDataPoints())
 		}
-		if errSend := reply(); errSend != nil {
-			return errSend
+		return result, nil
+	}, func(ctx context.Context, conn *grpclib.ClientConn) ([][]*measurev1.DataPoint, error) {
+		client := clusterv1.NewInternalQueryServiceClient(conn)
+		result := make([][]*measurev1.DataPoint, 0, len(subQueries))
+		for _, q := range subQueries {
+			resp, errRemote := client.QueryMeasure(ctx, q)
+			if errRemote != nil {
+				return nil, errRemote
+			}
+			result = append(result, resp.GetDataPoints())
 		}
+		return result, nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	dataPoints, err := mergeDataPoints(req, tag, partials)
+	if err != nil {
+		return nil, err
+	}
+	return &measurev1.QueryResponse{DataPoints: dataPoints}, nil
 }
 
-func (ms *measureService) Query(_ context.Context, entityCriteria *measurev1.QueryRequest) (*measurev1.QueryResponse, error) {
-	if err := timestamp.CheckTimeRange(entityCriteria.GetTimeRange()); err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "%v is invalid :%s", entityCriteria.GetTimeRange(), err)
+// queryLocal executes the query on the local shards, which are restricted to the shardIDs if there are any.
+func (ms *measureService) queryLocal(req *measurev1.QueryRequest, shardIDs ...common.ShardID) (*measurev1.QueryResponse, error) {
+	var payload interface{} = req
+	if len(shardIDs) > 0 {
+		payload = &data.ShardQuery{Request: req, ShardIDs: shardIDs}
 	}
-	message := bus.NewMessage(bus.MessageID(time.Now().UnixNano()), entityCriteria)
+	return ms.publishQuery(req, payload)
+}
+
+// publishQuery runs the query carried by the payload on the local node.
+func (ms *measureService) publishQuery(req *measurev1.QueryRequest, payload interface{}) (*measurev1.QueryResponse, error) {
+	if req.GetIncludeUnflushed() {
+		// the coalesced writes acknowledged before the query are published to the pipeline flushed by the query
+		ms.coalescer.flush()
+	}
+	message := bus.NewMessage(bus.MessageID(time.Now().UnixNano()), payload)
 	feat, errQuery := ms.pipeline.Publish(data.TopicMeasureQuery, message)
 	if errQuery != nil {
 		return nil, errQuery
@@ -105,6 +367,56 @@ func (ms *measureService) Query(_ context.Context, entityCriteria *measurev1.Que
 	switch d := data.(type) {
 	case []*measurev1.DataPoint:
 		return &measurev1.QueryResponse{DataPoints: d}, nil
+	case *measurev1.QueryResponse:
+		return d, nil
+	case *modelv1.ResourceExhausted:
+		return nil, resourceExhausted(d)
+	case common.Error:
+		return nil, errors.WithMessage(ErrQueryMsg, d.Msg())
+	}
+	return nil, ErrQueryMsg
+}
+
+func (ms *measureService) Explain(_ context.Context, entityCriteria *measurev1.QueryRequest) (*measurev1.ExplainResponse, error) {
+	if err := timestamp.CheckTimeRange(entityCriteria.GetTimeRange()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v is invalid :%s", entityCriteria.GetTimeRange(), err)
+	}
+	message := bus.NewMessage(bus.MessageID(time.Now().UnixNano()), entityCriteria)
+	feat, errQuery := ms.pipeline.Publish(data.TopicMeasureExplain, message)
+	if errQuery != nil {
+		return nil, errQuery
+	}
+	msg, errFeat := feat.Get()
+	if errFeat != nil {
+		return nil, errFeat
+	}
+	switch d := msg.Data().(type) {
+	case *modelv1.PlanNode:
+		return &measurev1.ExplainResponse{Plan: d}, nil
+	case common.Error:
+		return nil, errors.WithMessage(ErrQueryMsg, d.Msg())
+	}
+	return nil, ErrQueryMsg
+}
+
+func (ms *measureService) Inspect(_ context.Context, req *measurev1.InspectRequest) (*measurev1.InspectResponse, error) {
+	if req.GetTimeRange() != nil {
+		if err := timestamp.CheckTimeRange(req.GetTimeRange()); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "%v is invalid :%s", req.GetTimeRange(), err)
+		}
+	}
+	message := bus.NewMessage(bus.MessageID(time.Now().UnixNano()), req)
+	feat, errQuery := ms.pipeline.Publish(data.TopicMeasureInspect, message)
+	if errQuery != nil {
+		return nil, errQuery
+	}
+	msg, errFeat := feat.Get()
+	if errFeat != nil {
+		return nil, errFeat
+	}
+	switch d := msg.Data().(type) {
+	case *measurev1.InspectResponse:
+		return d, nil
 	case common.Error:
 		return nil, errors.WithMessage(ErrQueryMsg, d.Msg())
 	}
@@ -112,6 +424,7 @@ func (ms *measureService) Query(_ context.Context, entityCriteria *measurev1.Que
 }
 
 func (ms *measureService) TopN(_ context.Context, topNRequest *measurev1.TopNRequest) (*measurev1.TopNResponse, error) {
+	defer ms.drainer.trackQuery()()
 	if err := timestamp.CheckTimeRange(topNRequest.GetTimeRange()); err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "%v is invalid :%s", topNRequest.GetTimeRange(), err)
 	}
@@ -0,0 +1,120 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"net"
+	"os"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/apache/skywalking-banyandb/api/event"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+var _ schema.EventHandler = (*nodeWatcher)(nil)
+
+// nodeWatcher turns the nodes registered in the schema registry into the node events rebalancing the shards.
+type nodeWatcher struct {
+	log       *logger.Logger
+	publisher bus.Publisher
+}
+
+func (w *nodeWatcher) OnAddOrUpdate(m schema.Metadata) {
+	if node, ok := m.Spec.(*databasev1.Node); ok {
+		w.publish(databasev1.Action_ACTION_PUT, node)
+	}
+}
+
+func (w *nodeWatcher) OnDelete(m schema.Metadata) {
+	if node, ok := m.Spec.(*databasev1.Node); ok {
+		w.publish(databasev1.Action_ACTION_DELETE, node)
+	}
+}
+
+func (w *nodeWatcher) publish(action databasev1.Action, node *databasev1.Node) {
+	now := timestamppb.Now()
+	_, err := w.publisher.Publish(event.TopicNodeEvent, bus.NewMessage(bus.MessageID(now.AsTime().UnixNano()), &databasev1.NodeEvent{
+		Node:   node,
+		Action: action,
+		Time:   now,
+	}))
+	if err != nil {
+		w.log.Error().Err(err).Str("node", node.GetId()).Msg("failed to publish the node event")
+	}
+}
+
+// registerNode registers the local node reachable at the address and the internal one, and loads the nodes registered before it.
+func (s *Server) registerNode(ctx context.Context, addr, internalAddr string) error {
+	w := &nodeWatcher{log: s.log, publisher: s.repo}
+	s.schemaRegistry.StreamRegistry().RegisterHandler(schema.KindNode, w)
+	if err := s.schemaRegistry.RegisterNode(ctx, &databasev1.Node{
+		Id:           s.repo.NodeID(),
+		Addr:         addr,
+		InternalAddr: internalAddr,
+		ApiVersion:   apiVersion,
+		Features:     features,
+	}); err != nil {
+		return errors.WithMessage(err, "register the node")
+	}
+	nodes, err := s.schemaRegistry.SchemaRegistry().ListNode(ctx)
+	if err != nil {
+		return errors.WithMessage(err, "list the nodes")
+	}
+	for _, node := range nodes {
+		w.publish(databasev1.Action_ACTION_PUT, node)
+	}
+	return nil
+}
+
+// advertiseAddr returns the address the other nodes reach the server at.
+// The host of the listening address is replaced by the hostname if it's absent.
+func advertiseAddr(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", errors.WithMessagef(err, "invalid address %s", addr)
+	}
+	if host != "" {
+		return addr, nil
+	}
+	if host, err = os.Hostname(); err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// internalAdvertiseAddr returns the address the other nodes forward the writes to.
+// The host of the internal listening address is replaced by the one of the advertised address if it's absent.
+func internalAdvertiseAddr(internalAddr, advertised string) (string, error) {
+	host, port, err := net.SplitHostPort(internalAddr)
+	if err != nil {
+		return "", errors.WithMessagef(err, "invalid internal address %s", internalAddr)
+	}
+	if host != "" {
+		return internalAddr, nil
+	}
+	if host, _, err = net.SplitHostPort(advertised); err != nil {
+		return "", errors.WithMessagef(err, "invalid address %s", advertised)
+	}
+	return net.JoinHostPort(host, port), nil
+}
@@ -30,15 +30,15 @@ type propertyServer struct {
 }
 
 func (ps *propertyServer) Apply(ctx context.Context, req *propertyv1.ApplyRequest) (*propertyv1.ApplyResponse, error) {
-	created, tagsNum, err := ps.schemaRegistry.PropertyRegistry().ApplyProperty(ctx, req.Property, req.Strategy)
+	created, tagsNum, modRevision, err := ps.schemaRegistry.PropertyRegistry().ApplyProperty(ctx, req.Property, req.Strategy, req.ModRevision)
 	if err != nil {
 		return nil, err
 	}
-	return &propertyv1.ApplyResponse{Created: created, TagsNum: tagsNum}, nil
+	return &propertyv1.ApplyResponse{Created: created, TagsNum: tagsNum, ModRevision: modRevision}, nil
 }
 
 func (ps *propertyServer) Delete(ctx context.Context, req *propertyv1.DeleteRequest) (*propertyv1.DeleteResponse, error) {
-	ok, tagsNum, err := ps.schemaRegistry.PropertyRegistry().DeleteProperty(ctx, req.GetMetadata(), req.Tags)
+	ok, tagsNum, err := ps.schemaRegistry.PropertyRegistry().DeleteProperty(ctx, req.GetMetadata(), req.Tags, req.ModRevision)
 	if err != nil {
 		return nil, err
 	}
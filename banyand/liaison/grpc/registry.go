@@ -32,6 +32,7 @@ import (
 
 type streamRegistryServer struct {
 	schemaRegistry metadata.Service
+	hub            *watchHub
 	databasev1.UnimplementedStreamRegistryServiceServer
 }
 
@@ -89,6 +90,15 @@ func (rs *streamRegistryServer) List(ctx context.Context,
 	}, nil
 }
 
+func (rs *streamRegistryServer) Watch(req *databasev1.StreamRegistryServiceWatchRequest, srv databasev1.StreamRegistryService_WatchServer) error {
+	return rs.hub.watch(srv, schema.KindStream, req.GetGroup(), func(e watchEvent) error {
+		return srv.Send(&databasev1.StreamRegistryServiceWatchResponse{
+			Type:   e.eventType,
+			Stream: e.spec.(*databasev1.Stream),
+		})
+	})
+}
+
 func (rs *streamRegistryServer) Exist(ctx context.Context, req *databasev1.StreamRegistryServiceExistRequest) (*databasev1.StreamRegistryServiceExistResponse, error) {
 	_, err := rs.Get(ctx, &databasev1.StreamRegistryServiceGetRequest{Metadata: req.Metadata})
 	if err == nil {
@@ -205,6 +215,7 @@ func (rs *indexRuleBindingRegistryServer) Exist(ctx context.Context, req *databa
 
 type indexRuleRegistryServer struct {
 	schemaRegistry metadata.Service
+	hub            *watchHub
 	databasev1.UnimplementedIndexRuleRegistryServiceServer
 }
 
@@ -262,6 +273,15 @@ func (rs *indexRuleRegistryServer) List(ctx context.Context, req *databasev1.Ind
 	}, nil
 }
 
+func (rs *indexRuleRegistryServer) Watch(req *databasev1.IndexRuleRegistryServiceWatchRequest, srv databasev1.IndexRuleRegistryService_WatchServer) error {
+	return rs.hub.watch(srv, schema.KindIndexRule, req.GetGroup(), func(e watchEvent) error {
+		return srv.Send(&databasev1.IndexRuleRegistryServiceWatchResponse{
+			Type:      e.eventType,
+			IndexRule: e.spec.(*databasev1.IndexRule),
+		})
+	})
+}
+
 func (rs *indexRuleRegistryServer) Exist(ctx context.Context, req *databasev1.IndexRuleRegistryServiceExistRequest) (
 	*databasev1.IndexRuleRegistryServiceExistResponse, error,
 ) {
@@ -281,6 +301,7 @@ func (rs *indexRuleRegistryServer) Exist(ctx context.Context, req *databasev1.In
 
 type measureRegistryServer struct {
 	schemaRegistry metadata.Service
+	hub            *watchHub
 	databasev1.UnimplementedMeasureRegistryServiceServer
 }
 
@@ -338,6 +359,15 @@ func (rs *measureRegistryServer) List(ctx context.Context, req *databasev1.Measu
 	}, nil
 }
 
+func (rs *measureRegistryServer) Watch(req *databasev1.MeasureRegistryServiceWatchRequest, srv databasev1.MeasureRegistryService_WatchServer) error {
+	return rs.hub.watch(srv, schema.KindMeasure, req.GetGroup(), func(e watchEvent) error {
+		return srv.Send(&databasev1.MeasureRegistryServiceWatchResponse{
+			Type:    e.eventType,
+			Measure: e.spec.(*databasev1.Measure),
+		})
+	})
+}
+
 func (rs *measureRegistryServer) Exist(ctx context.Context, req *databasev1.MeasureRegistryServiceExistRequest) (*databasev1.MeasureRegistryServiceExistResponse, error) {
 	_, err := rs.Get(ctx, &databasev1.MeasureRegistryServiceGetRequest{Metadata: req.Metadata})
 	if err == nil {
@@ -355,6 +385,10 @@ func (rs *measureRegistryServer) Exist(ctx context.Context, req *databasev1.Meas
 
 type groupRegistryServer struct {
 	schemaRegistry metadata.Service
+	hub            *watchHub
+	// streamSVC and measureSVC copy the data of the cloned groups
+	streamSVC  *streamService
+	measureSVC *measureService
 	databasev1.UnimplementedGroupRegistryServiceServer
 }
 
@@ -412,6 +446,15 @@ func (rs *groupRegistryServer) List(ctx context.Context, req *databasev1.GroupRe
 	}, nil
 }
 
+func (rs *groupRegistryServer) Watch(req *databasev1.GroupRegistryServiceWatchRequest, srv databasev1.GroupRegistryService_WatchServer) error {
+	return rs.hub.watch(srv, schema.KindGroup, "", func(e watchEvent) error {
+		return srv.Send(&databasev1.GroupRegistryServiceWatchResponse{
+			Type:  e.eventType,
+			Group: e.spec.(*commonv1.Group),
+		})
+	})
+}
+
 func (rs *groupRegistryServer) Exist(ctx context.Context, req *databasev1.GroupRegistryServiceExistRequest) (*databasev1.GroupRegistryServiceExistResponse, error) {
 	_, err := rs.Get(ctx, &databasev1.GroupRegistryServiceGetRequest{Group: req.Group})
 	if err == nil {
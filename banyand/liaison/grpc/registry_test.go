@@ -99,6 +99,181 @@ var _ = Describe("Registry", func() {
 		Expect(existResp.HasGroup).To(BeTrue())
 		Expect(existResp.HasStream).To(BeTrue())
 	})
+	It("evolves the stream", func() {
+		client := databasev1.NewStreamRegistryServiceClient(conn)
+		meta.Name = "sw"
+		getResp, err := client.Get(context.TODO(), &databasev1.StreamRegistryServiceGetRequest{Metadata: meta})
+		Expect(err).ShouldNot(HaveOccurred())
+		s := getResp.GetStream()
+		version := s.GetSchemaVersion()
+		Expect(version).To(BeNumerically(">=", 1))
+		By("Appending a tag")
+		s.TagFamilies[0].Tags = append(s.TagFamilies[0].Tags, &databasev1.TagSpec{Name: "appended", Type: databasev1.TagType_TAG_TYPE_STRING})
+		_, err = client.Update(context.TODO(), &databasev1.StreamRegistryServiceUpdateRequest{Stream: s})
+		Expect(err).ShouldNot(HaveOccurred())
+		getResp, err = client.Get(context.TODO(), &databasev1.StreamRegistryServiceGetRequest{Metadata: meta})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(getResp.GetStream().GetSchemaVersion()).To(Equal(version + 1))
+		By("Changing the type of a tag")
+		s = getResp.GetStream()
+		s.TagFamilies[0].Tags[0].Type = databasev1.TagType_TAG_TYPE_INT_ARRAY
+		_, err = client.Update(context.TODO(), &databasev1.StreamRegistryServiceUpdateRequest{Stream: s})
+		errStatus, _ := status.FromError(err)
+		Expect(errStatus.Code()).To(Equal(codes.InvalidArgument))
+		By("Changing the entity")
+		getResp, err = client.Get(context.TODO(), &databasev1.StreamRegistryServiceGetRequest{Metadata: meta})
+		Expect(err).ShouldNot(HaveOccurred())
+		s = getResp.GetStream()
+		s.Entity.TagNames = s.Entity.TagNames[1:]
+		_, err = client.Update(context.TODO(), &databasev1.StreamRegistryServiceUpdateRequest{Stream: s})
+		errStatus, _ = status.FromError(err)
+		Expect(errStatus.Code()).To(Equal(codes.InvalidArgument))
+		By("Removing a tag family")
+		getResp, err = client.Get(context.TODO(), &databasev1.StreamRegistryServiceGetRequest{Metadata: meta})
+		Expect(err).ShouldNot(HaveOccurred())
+		s = getResp.GetStream()
+		s.TagFamilies = s.TagFamilies[:len(s.TagFamilies)-1]
+		_, err = client.Update(context.TODO(), &databasev1.StreamRegistryServiceUpdateRequest{Stream: s})
+		errStatus, _ = status.FromError(err)
+		Expect(errStatus.Code()).To(Equal(codes.InvalidArgument))
+		getResp, err = client.Get(context.TODO(), &databasev1.StreamRegistryServiceGetRequest{Metadata: meta})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(getResp.GetStream().GetSchemaVersion()).To(Equal(version + 1))
+	})
+	It("watches the streams", func() {
+		client := databasev1.NewStreamRegistryServiceClient(conn)
+		meta.Name = "sw"
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		opened := make(chan struct{})
+		events := make(chan *databasev1.StreamRegistryServiceWatchResponse, 10)
+		go func() {
+			defer GinkgoRecover()
+			_ = grpchelper.Watch(ctx, time.Second,
+				func(ctx context.Context) (grpchelper.Receiver[*databasev1.StreamRegistryServiceWatchResponse], error) {
+					return client.Watch(ctx, &databasev1.StreamRegistryServiceWatchRequest{Group: meta.Group})
+				}, func(context.Context) error {
+					close(opened)
+					return nil
+				}, func(resp *databasev1.StreamRegistryServiceWatchResponse) {
+					events <- resp
+				})
+		}()
+		Eventually(opened, flags.EventuallyTimeout).Should(BeClosed())
+		getResp, err := client.Get(context.TODO(), &databasev1.StreamRegistryServiceGetRequest{Metadata: meta})
+		Expect(err).ShouldNot(HaveOccurred())
+		s := getResp.GetStream()
+		By("Deleting the stream")
+		_, err = client.Delete(context.TODO(), &databasev1.StreamRegistryServiceDeleteRequest{Metadata: meta})
+		Expect(err).ShouldNot(HaveOccurred())
+		var e *databasev1.StreamRegistryServiceWatchResponse
+		Eventually(events, flags.EventuallyTimeout).Should(Receive(&e))
+		Expect(e.GetType()).To(Equal(databasev1.EventType_EVENT_TYPE_DELETED))
+		Expect(e.GetStream().GetMetadata().GetName()).To(Equal(meta.Name))
+		By("Creating the stream")
+		_, err = client.Create(context.TODO(), &databasev1.StreamRegistryServiceCreateRequest{Stream: s})
+		Expect(err).ShouldNot(HaveOccurred())
+		Eventually(events, flags.EventuallyTimeout).Should(Receive(&e))
+		Expect(e.GetType()).To(Equal(databasev1.EventType_EVENT_TYPE_CREATED))
+		By("Updating the stream")
+		s = e.GetStream()
+		s.TagFamilies[0].Tags = append(s.TagFamilies[0].Tags, &databasev1.TagSpec{Name: "watched", Type: databasev1.TagType_TAG_TYPE_STRING})
+		_, err = client.Update(context.TODO(), &databasev1.StreamRegistryServiceUpdateRequest{Stream: s})
+		Expect(err).ShouldNot(HaveOccurred())
+		Eventually(events, flags.EventuallyTimeout).Should(Receive(&e))
+		Expect(e.GetType()).To(Equal(databasev1.EventType_EVENT_TYPE_UPDATED))
+		Expect(e.GetStream().GetTagFamilies()[0].GetTags()).To(HaveLen(len(s.TagFamilies[0].Tags)))
+	})
+	It("exports and imports the group", func() {
+		client := databasev1.NewGroupRegistryServiceClient(conn)
+		streamClient := databasev1.NewStreamRegistryServiceClient(conn)
+		meta.Name = "sw"
+		exportResp, err := client.Export(context.TODO(), &databasev1.GroupRegistryServiceExportRequest{Group: meta.Group})
+		Expect(err).ShouldNot(HaveOccurred())
+		bundle := exportResp.GetBundle()
+		Expect(bundle.GetStreams()).NotTo(BeEmpty())
+		Expect(bundle.GetStreams()[0].GetMetadata().GetModRevision()).To(BeZero())
+		By("Deleting a stream")
+		_, err = streamClient.Delete(context.TODO(), &databasev1.StreamRegistryServiceDeleteRequest{Metadata: meta})
+		Expect(err).ShouldNot(HaveOccurred())
+		By("Importing the bundle in the dry run mode")
+		importResp, err := client.Import(context.TODO(), &databasev1.GroupRegistryServiceImportRequest{Bundle: bundle, DryRun: true})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(importResp.GetCreated()).To(Equal(uint32(1)))
+		_, err = streamClient.Get(context.TODO(), &databasev1.StreamRegistryServiceGetRequest{Metadata: meta})
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+		By("Importing the bundle")
+		importResp, err = client.Import(context.TODO(), &databasev1.GroupRegistryServiceImportRequest{Bundle: bundle})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(importResp.GetCreated()).To(Equal(uint32(1)))
+		_, err = streamClient.Get(context.TODO(), &databasev1.StreamRegistryServiceGetRequest{Metadata: meta})
+		Expect(err).ShouldNot(HaveOccurred())
+		importResp, err = client.Import(context.TODO(), &databasev1.GroupRegistryServiceImportRequest{Bundle: bundle})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(importResp.GetCreated()).To(BeZero())
+		Expect(importResp.GetUpdated()).To(BeZero())
+		By("Rejecting the bundle holding the schemas of another group")
+		_, err = streamClient.Delete(context.TODO(), &databasev1.StreamRegistryServiceDeleteRequest{Metadata: meta})
+		Expect(err).ShouldNot(HaveOccurred())
+		bundle.Measures = append(bundle.Measures, &databasev1.Measure{Metadata: &commonv1.Metadata{Group: "another", Name: "m"}})
+		_, err = client.Import(context.TODO(), &databasev1.GroupRegistryServiceImportRequest{Bundle: bundle})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		_, err = streamClient.Get(context.TODO(), &databasev1.StreamRegistryServiceGetRequest{Metadata: meta})
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+	})
+	It("clones the group", func() {
+		client := databasev1.NewGroupRegistryServiceClient(conn)
+		exportResp, err := client.Export(context.TODO(), &databasev1.GroupRegistryServiceExportRequest{Group: meta.Group})
+		Expect(err).ShouldNot(HaveOccurred())
+		cloneResp, err := client.Clone(context.TODO(), &databasev1.GroupRegistryServiceCloneRequest{
+			Group:  meta.Group,
+			Target: "staging",
+			ResourceOpts: &commonv1.ResourceOpts{
+				ShardNum: 1,
+				Ttl:      &commonv1.IntervalRule{Unit: commonv1.IntervalRule_UNIT_DAY, Num: 1},
+			},
+		})
+		Expect(err).ShouldNot(HaveOccurred())
+		bundle := exportResp.GetBundle()
+		Expect(cloneResp.GetCreated()).To(BeNumerically("==", 1+len(bundle.GetIndexRules())+len(bundle.GetIndexRuleBindings())+
+			len(bundle.GetStreams())+len(bundle.GetMeasures())+len(bundle.GetTopNAggregations())))
+		Expect(cloneResp.GetElements()).To(BeZero())
+		getResp, err := client.Get(context.TODO(), &databasev1.GroupRegistryServiceGetRequest{Group: "staging"})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(getResp.GetGroup().GetResourceOpts().GetTtl().GetNum()).To(Equal(uint32(1)))
+		_, err = databasev1.NewStreamRegistryServiceClient(conn).Get(context.TODO(), &databasev1.StreamRegistryServiceGetRequest{
+			Metadata: &commonv1.Metadata{Group: "staging", Name: "sw"},
+		})
+		Expect(err).ShouldNot(HaveOccurred())
+		By("Rejecting the existing target")
+		_, err = client.Clone(context.TODO(), &databasev1.GroupRegistryServiceCloneRequest{Group: meta.Group, Target: "staging"})
+		Expect(status.Code(err)).To(Equal(codes.AlreadyExists))
+	})
+	It("validates the write filters of the group", func() {
+		client := databasev1.NewGroupRegistryServiceClient(conn)
+		getResp, err := client.Get(context.TODO(), &databasev1.GroupRegistryServiceGetRequest{Group: meta.Group})
+		Expect(err).ShouldNot(HaveOccurred())
+		g := getResp.GetGroup()
+		g.WriteFilters = []*commonv1.WriteFilterRule{
+			{Name: "drop-health", Expression: `endpoint_id.startsWith("/health"`, Action: commonv1.WriteFilterRule_ACTION_DROP},
+		}
+		_, err = client.Update(context.TODO(), &databasev1.GroupRegistryServiceUpdateRequest{Group: g})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		g.WriteFilters[0] = &commonv1.WriteFilterRule{Name: "route-debug", Expression: `endpoint_id == "/debug"`, Action: commonv1.WriteFilterRule_ACTION_ROUTE}
+		_, err = client.Update(context.TODO(), &databasev1.GroupRegistryServiceUpdateRequest{Group: g})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		g.WriteFilters[0].RouteGroup = "debug"
+		_, err = client.Update(context.TODO(), &databasev1.GroupRegistryServiceUpdateRequest{Group: g})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		_, err = client.Create(context.TODO(), &databasev1.GroupRegistryServiceCreateRequest{Group: &commonv1.Group{
+			Metadata:     &commonv1.Metadata{Name: "debug"},
+			Catalog:      g.GetCatalog(),
+			ResourceOpts: g.GetResourceOpts(),
+		}})
+		Expect(err).ShouldNot(HaveOccurred())
+		_, err = client.Update(context.TODO(), &databasev1.GroupRegistryServiceUpdateRequest{Group: g})
+		Expect(err).ShouldNot(HaveOccurred())
+	})
 	It("manages the index-rule-binding", func() {
 		client := databasev1.NewIndexRuleBindingRegistryServiceClient(conn)
 		Expect(client).NotTo(BeNil())
@@ -165,7 +340,7 @@ var _ = Describe("Registry", func() {
 	})
 })
 
-func setupForRegistry() func() {
+func setupForRegistry(interceptors ...grpclib.UnaryServerInterceptor) func() {
 	// Init `Discovery` module
 	repo, err := discovery.NewServiceRepo(context.Background())
 	Expect(err).NotTo(HaveOccurred())
@@ -177,8 +352,10 @@ func setupForRegistry() func() {
 	Expect(err).NotTo(HaveOccurred())
 
 	tcp := grpc.NewServer(context.TODO(), pipeline, repo, metaSvc)
+	tcp.RegisterModules(repo, pipeline, metaSvc, tcp)
+	tcp.RegisterUnaryInterceptors(interceptors...)
 	preloadStreamSvc := &preloadStreamService{metaSvc: metaSvc}
-	var flags []string
+	flags := []string{"--enable-reflection"}
 	metaPath, metaDeferFunc, err := test.NewSpace()
 	Expect(err).NotTo(HaveOccurred())
 	listenClientURL, listenPeerURL, err := test.NewEtcdListenUrls()
@@ -0,0 +1,205 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+)
+
+const (
+	rejectedTypeStream  = "stream"
+	rejectedTypeMeasure = "measure"
+)
+
+var rejectedWrites = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "banyand_liaison_rejected_writes_total",
+		Help: "The number of the elements and the data points rejected by the liaison, by their types and the codes of the rejections",
+	},
+	[]string{"type", "code"},
+)
+
+// rejectionLog keeps the sampled writes rejected by the schema in a ring buffer, so that the dropped data
+// can be debugged without turning on the verbose logs. A sample rate of 0 disables it.
+type rejectionLog struct {
+	writes         []*databasev1.RejectedWrite
+	sampleRate     float64
+	capacity       int
+	maxPayloadSize int
+	redact         bool
+	next           int
+	// rejected counts all the rejections including the unsampled ones
+	rejected atomic.Uint64
+	mu       sync.RWMutex
+}
+
+func (l *rejectionLog) validate() error {
+	if l.sampleRate < 0 || l.sampleRate > 1 || l.capacity < 0 || l.maxPayloadSize < 0 {
+		return errInvalidRejectionLog
+	}
+	return nil
+}
+
+func (l *rejectionLog) sample() bool {
+	if l.sampleRate <= 0 || l.capacity == 0 {
+		return false
+	}
+	return l.sampleRate >= 1 || rand.Float64() < l.sampleRate
+}
+
+// record counts the write rejected for the reason, and keeps it if it's sampled.
+// The item is either an element or a data point, which is serialized only if it's kept.
+func (l *rejectionLog) record(typ string, md *commonv1.Metadata, wErr *modelv1.WriteError, item proto.Message) {
+	if l == nil {
+		return
+	}
+	rejectedWrites.WithLabelValues(typ, wErr.GetCode().String()).Inc()
+	l.rejected.Add(1)
+	if !l.sample() {
+		return
+	}
+	if l.redact {
+		item = redactWrite(item)
+	}
+	payload, truncated := "", false
+	if bb, err := protojson.Marshal(item); err == nil {
+		payload, truncated = truncatePayload(string(bb), l.maxPayloadSize)
+	} else {
+		payload = fmt.Sprintf("failed to marshal the payload: %v", err)
+	}
+	l.add(&databasev1.RejectedWrite{
+		Type:       typ,
+		Metadata:   md,
+		Code:       wErr.GetCode(),
+		Reason:     wErr.GetMessage(),
+		Payload:    payload,
+		Truncated:  truncated,
+		RejectedAt: timestamppb.Now(),
+	})
+}
+
+func (l *rejectionLog) add(w *databasev1.RejectedWrite) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.writes) < l.capacity {
+		l.writes = append(l.writes, w)
+		return
+	}
+	l.writes[l.next] = w
+	l.next = (l.next + 1) % l.capacity
+}
+
+// list returns the kept writes of the group from the latest to the oldest, an empty group lists all the groups.
+func (l *rejectionLog) list(group string) []*databasev1.RejectedWrite {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	size := len(l.writes)
+	result := make([]*databasev1.RejectedWrite, 0, size)
+	// the oldest one sits at next once the buffer is full, otherwise at 0
+	for i := 0; i < size; i++ {
+		w := l.writes[(l.next+size-1-i)%size]
+		if group == "" || w.GetMetadata().GetGroup() == group {
+			result = append(result, w)
+		}
+	}
+	return result
+}
+
+// truncatePayload cuts the payload at the boundary of a rune within the max size, 0 means unlimited.
+func truncatePayload(payload string, maxSize int) (string, bool) {
+	if maxSize == 0 || len(payload) <= maxSize {
+		return payload, false
+	}
+	n := maxSize
+	for n > 0 && !utf8.RuneStart(payload[n]) {
+		n--
+	}
+	return payload[:n], true
+}
+
+// redactWrite returns a copy of the element or the data point whose string and binary values are hidden,
+// which keeps the types of the values and the sizes of the strings for debugging the schema.
+func redactWrite(item proto.Message) proto.Message {
+	item = proto.Clone(item)
+	var families []*modelv1.TagFamilyForWrite
+	switch v := item.(type) {
+	case *streamv1.ElementValue:
+		families = v.GetTagFamilies()
+	case *measurev1.DataPointValue:
+		families = v.GetTagFamilies()
+		for _, f := range v.GetFields() {
+			switch fv := f.GetValue().(type) {
+			case *modelv1.FieldValue_Str:
+				fv.Str = &modelv1.Str{Value: redactedString(fv.Str.GetValue())}
+			case *modelv1.FieldValue_BinaryData:
+				fv.BinaryData = nil
+			}
+		}
+	}
+	for _, family := range families {
+		for _, tag := range family.GetTags() {
+			switch tv := tag.GetValue().(type) {
+			case *modelv1.TagValue_Str:
+				tv.Str = &modelv1.Str{Value: redactedString(tv.Str.GetValue())}
+			case *modelv1.TagValue_StrArray:
+				items := make([]string, 0, len(tv.StrArray.GetValue()))
+				for _, s := range tv.StrArray.GetValue() {
+					items = append(items, redactedString(s))
+				}
+				tv.StrArray = &modelv1.StrArray{Value: items}
+			case *modelv1.TagValue_BinaryData:
+				tv.BinaryData = nil
+			}
+		}
+	}
+	return item
+}
+
+func redactedString(s string) string {
+	return fmt.Sprintf("<redacted %d bytes>", len(s))
+}
+
+type rejectionServer struct {
+	databasev1.UnimplementedWriteRejectionServiceServer
+	rejections *rejectionLog
+}
+
+func (s *rejectionServer) List(_ context.Context, req *databasev1.WriteRejectionServiceListRequest,
+) (*databasev1.WriteRejectionServiceListResponse, error) {
+	return &databasev1.WriteRejectionServiceListResponse{
+		RejectedWrites: s.rejections.list(req.GetGroup()),
+		Rejected:       s.rejections.rejected.Load(),
+	}, nil
+}
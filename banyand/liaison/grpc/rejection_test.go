@@ -0,0 +1,132 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+)
+
+var _ = Describe("Write rejection log", func() {
+	wErr := &modelv1.WriteError{Code: modelv1.WriteError_CODE_TAG_TYPE, Message: "the type of the tag status is invalid"}
+	element := func(id string) *streamv1.ElementValue {
+		return &streamv1.ElementValue{ElementId: id, TagFamilies: []*modelv1.TagFamilyForWrite{{Tags: []*modelv1.TagValue{
+			{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "secret"}}},
+			{Value: &modelv1.TagValue_StrArray{StrArray: &modelv1.StrArray{Value: []string{"a", "bc"}}}},
+			{Value: &modelv1.TagValue_BinaryData{BinaryData: []byte("secret")}},
+			{Value: &modelv1.TagValue_Int{Int: &modelv1.Int{Value: 500}}},
+		}}}}
+	}
+	md := func(group string) *commonv1.Metadata {
+		return &commonv1.Metadata{Group: group, Name: "sw"}
+	}
+	list := func(l *rejectionLog, group string) []string {
+		var ids []string
+		for _, w := range l.list(group) {
+			Expect(w.GetType()).To(Equal(rejectedTypeStream))
+			Expect(w.GetCode()).To(Equal(modelv1.WriteError_CODE_TAG_TYPE))
+			Expect(w.GetReason()).To(Equal(wErr.GetMessage()))
+			e := &streamv1.ElementValue{}
+			Expect(protojson.Unmarshal([]byte(w.GetPayload()), e)).To(Succeed())
+			ids = append(ids, e.GetElementId())
+		}
+		return ids
+	}
+
+	It("keeps the latest sampled writes", func() {
+		l := &rejectionLog{sampleRate: 1, capacity: 2}
+		for _, id := range []string{"1", "2", "3"} {
+			l.record(rejectedTypeStream, md("default"), wErr, element(id))
+		}
+		Expect(list(l, "")).To(Equal([]string{"3", "2"}))
+		resp, err := (&rejectionServer{rejections: l}).List(context.Background(), &databasev1.WriteRejectionServiceListRequest{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.GetRejected()).To(Equal(uint64(3)))
+	})
+
+	It("filters the writes by the group", func() {
+		l := &rejectionLog{sampleRate: 1, capacity: 10}
+		l.record(rejectedTypeStream, md("default"), wErr, element("1"))
+		l.record(rejectedTypeStream, md("other"), wErr, element("2"))
+		Expect(list(l, "other")).To(Equal([]string{"2"}))
+		Expect(list(l, "absent")).To(BeEmpty())
+	})
+
+	It("counts the writes without keeping them if it's disabled", func() {
+		l := &rejectionLog{capacity: 10}
+		l.record(rejectedTypeStream, md("default"), wErr, element("1"))
+		Expect(l.list("")).To(BeEmpty())
+		Expect(l.rejected.Load()).To(Equal(uint64(1)))
+		var disabled *rejectionLog
+		disabled.record(rejectedTypeStream, md("default"), wErr, element("1"))
+	})
+
+	It("redacts the string and binary values", func() {
+		l := &rejectionLog{sampleRate: 1, capacity: 10, redact: true}
+		e := element("1")
+		l.record(rejectedTypeStream, md("default"), wErr, e)
+		dp := &measurev1.DataPointValue{Fields: []*modelv1.FieldValue{
+			{Value: &modelv1.FieldValue_Str{Str: &modelv1.Str{Value: "secret"}}},
+			{Value: &modelv1.FieldValue_BinaryData{BinaryData: []byte("secret")}},
+			{Value: &modelv1.FieldValue_Int{Int: &modelv1.Int{Value: 1}}},
+		}}
+		l.record(rejectedTypeMeasure, md("default"), wErr, dp)
+		writes := l.list("")
+		Expect(writes).To(HaveLen(2))
+		redactedDP := &measurev1.DataPointValue{}
+		Expect(protojson.Unmarshal([]byte(writes[0].GetPayload()), redactedDP)).To(Succeed())
+		Expect(redactedDP.GetFields()[0].GetStr().GetValue()).To(Equal("<redacted 6 bytes>"))
+		Expect(redactedDP.GetFields()[1].GetBinaryData()).To(BeEmpty())
+		Expect(redactedDP.GetFields()[2].GetInt().GetValue()).To(Equal(int64(1)))
+		redacted := &streamv1.ElementValue{}
+		Expect(protojson.Unmarshal([]byte(writes[1].GetPayload()), redacted)).To(Succeed())
+		tags := redacted.GetTagFamilies()[0].GetTags()
+		Expect(tags[0].GetStr().GetValue()).To(Equal("<redacted 6 bytes>"))
+		Expect(tags[1].GetStrArray().GetValue()).To(Equal([]string{"<redacted 1 bytes>", "<redacted 2 bytes>"}))
+		Expect(tags[2].GetBinaryData()).To(BeEmpty())
+		Expect(tags[3].GetInt().GetValue()).To(Equal(int64(500)))
+		// the written element is untouched
+		Expect(e.GetTagFamilies()[0].GetTags()[0].GetStr().GetValue()).To(Equal("secret"))
+	})
+
+	It("truncates the payloads at the boundaries of the runes", func() {
+		l := &rejectionLog{sampleRate: 1, capacity: 10, maxPayloadSize: 32}
+		e := element(strings.Repeat("é", 32))
+		l.record(rejectedTypeStream, md("default"), wErr, e)
+		w := l.list("")[0]
+		Expect(w.GetTruncated()).To(BeTrue())
+		Expect(len(w.GetPayload())).To(BeNumerically("<=", 32))
+		Expect(strings.ToValidUTF8(w.GetPayload(), "?")).To(Equal(w.GetPayload()))
+	})
+
+	It("rejects the invalid flags", func() {
+		Expect((&rejectionLog{sampleRate: 2}).validate()).To(MatchError(errInvalidRejectionLog))
+		Expect((&rejectionLog{capacity: -1}).validate()).To(MatchError(errInvalidRejectionLog))
+		Expect((&rejectionLog{sampleRate: 0.5, capacity: 10, maxPayloadSize: 4096}).validate()).To(Succeed())
+	})
+})
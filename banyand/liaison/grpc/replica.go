@@ -0,0 +1,214 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/apache/skywalking-banyandb/api/event"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+const (
+	// the writes waiting for a replica, beyond which the new ones are dropped
+	replicaQueueSize = 10000
+	// how often the lag of the replicas is reported on the event bus
+	replicaReportInterval = 10 * time.Second
+)
+
+var (
+	replicaLag = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "banyand_replica_lag_writes",
+			Help: "The number of the writes which are not sent to the replica yet",
+		},
+		[]string{"node"},
+	)
+	replicaDropped = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "banyand_replica_dropped_writes_total",
+			Help: "The number of the writes dropped since the replica lags too much",
+		},
+		[]string{"node"},
+	)
+)
+
+// replicator sends the writes to the secondary replicas of the groups replicated asynchronously in the background.
+// Every data node has a bounded queue, whose length is the lag of its replicas.
+type replicator struct {
+	log       *logger.Logger
+	router    *nodeRouter
+	publisher bus.Publisher
+	queues    map[string]*replicaQueue
+	closer    chan struct{}
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+	closed    bool
+}
+
+type replicaQueue struct {
+	// droppedAt is when the drops were reported last time
+	droppedAt time.Time
+	node      *databasev1.Node
+	writes    chan interface{}
+	dropped   uint64
+}
+
+func newReplicator(router *nodeRouter, publisher bus.Publisher) *replicator {
+	return &replicator{
+		router:    router,
+		publisher: publisher,
+		queues:    make(map[string]*replicaQueue),
+		closer:    make(chan struct{}),
+	}
+}
+
+// start reports the lag of the replicas periodically.
+func (r *replicator) start() {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(replicaReportInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.report()
+			case <-r.closer:
+				return
+			}
+		}
+	}()
+}
+
+// enqueue queues a write of a stream or a measure for the replicas on the node.
+// It's dropped if the queue is full, which doesn't block the writes of the primary replicas.
+// The drops are reported by a replica event and a log at most once per report interval,
+// besides the periodical reports.
+func (r *replicator) enqueue(node *databasev1.Node, write interface{}) {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	q, ok := r.queues[node.GetId()]
+	if !ok {
+		q = &replicaQueue{node: node, writes: make(chan interface{}, replicaQueueSize)}
+		r.queues[node.GetId()] = q
+		r.wg.Add(1)
+		go r.replicate(q)
+	}
+	select {
+	case q.writes <- write:
+		replicaLag.WithLabelValues(node.GetId()).Inc()
+		r.mu.Unlock()
+		return
+	default:
+	}
+	q.dropped++
+	replicaDropped.WithLabelValues(node.GetId()).Inc()
+	now := time.Now()
+	if now.Sub(q.droppedAt) < replicaReportInterval {
+		r.mu.Unlock()
+		return
+	}
+	q.droppedAt = now
+	dropped, e := q.dropped, q.event(timestamppb.New(now))
+	r.mu.Unlock()
+	r.log.Warn().Str("node", node.GetId()).Uint64("dropped", dropped).Msg("the replica lags too much, drop the writes")
+	r.publish(e)
+}
+
+func (r *replicator) replicate(q *replicaQueue) {
+	defer r.wg.Done()
+	ctx := context.Background()
+	streamFw := newForwarder(ctx, r.router, openStreamWrite, false)
+	defer streamFw.close()
+	measureFw := newForwarder(ctx, r.router, openMeasureWrite, false)
+	defer measureFw.close()
+	for write := range q.writes {
+		replicaLag.WithLabelValues(q.node.GetId()).Dec()
+		var err error
+		switch w := write.(type) {
+		case *streamv1.WriteRequest:
+			err = streamFw.send(q.node, w)
+		case *measurev1.WriteRequest:
+			err = measureFw.send(q.node, w)
+		}
+		if err != nil {
+			r.log.Error().Err(err).Str("node", q.node.GetId()).Msg("failed to replicate the write")
+		}
+	}
+}
+
+// report publishes the lag of the replicas on every node.
+func (r *replicator) report() {
+	r.mu.Lock()
+	events := make([]bus.Message, 0, len(r.queues))
+	now := timestamppb.Now()
+	for _, q := range r.queues {
+		events = append(events, q.event(now))
+	}
+	r.mu.Unlock()
+	r.publish(events...)
+}
+
+func (q *replicaQueue) event(now *timestamppb.Timestamp) bus.Message {
+	return bus.NewMessage(bus.MessageID(now.AsTime().UnixNano()), &databasev1.ReplicaEvent{
+		Node:    q.node,
+		Lag:     uint64(len(q.writes)),
+		Dropped: q.dropped,
+		Time:    now,
+	})
+}
+
+func (r *replicator) publish(events ...bus.Message) {
+	if len(events) < 1 {
+		return
+	}
+	if _, err := r.publisher.Publish(event.TopicReplicaEvent, events...); err != nil && !errors.Is(err, bus.ErrTopicNotExist) {
+		r.log.Error().Err(err).Msg("failed to report the lag of the replicas")
+	}
+}
+
+// close stops the reports and waits until the queued writes are sent.
+func (r *replicator) close() {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.closed = true
+	close(r.closer)
+	for _, q := range r.queues {
+		close(q.writes)
+	}
+	r.mu.Unlock()
+	r.wg.Wait()
+}
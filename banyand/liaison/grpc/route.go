@@ -0,0 +1,541 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	clusterv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/cluster/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/convert"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/partition"
+)
+
+const (
+	// the virtual nodes of a data node on the ring
+	ringReplicas = 128
+	// how long the forwarded writes wait for the responses of a replica written synchronously
+	forwardAckTimeout = 10 * time.Second
+	// legacyForwardedKey marks the writes forwarded to the nodes predating the InternalWriteService, which trust it
+	legacyForwardedKey = "banyandb-forwarded"
+	// shardsKey carries the shards a sub-query is restricted to
+	shardsKey = "banyandb-shards"
+)
+
+var (
+	errForwardStreamBroken = errors.New("the stream to the replica is broken")
+	errForwardAckTimeout   = errors.New("the replica doesn't respond in time")
+)
+
+var _ bus.MessageListener = (*nodeRouter)(nil)
+
+// nodeRouter places the shards on the data nodes by a consistent hashing ring built from the node events,
+// so that only the shards of a joining or leaving node are moved.
+// All the shards are local until a node joins.
+type nodeRouter struct {
+	log        *logger.Logger
+	ring       *partition.Ring
+	nodes      map[string]*databasev1.Node
+	conns      map[string]*grpclib.ClientConn
+	writeConns map[string]*grpclib.ClientConn
+	// creds dials the nodes, which are the ones of the server if it's on TLS
+	creds   credentials.TransportCredentials
+	localID string
+	mu      sync.RWMutex
+}
+
+func newNodeRouter(localID string) *nodeRouter {
+	return &nodeRouter{
+		ring:       partition.NewRing(ringReplicas),
+		nodes:      make(map[string]*databasev1.Node),
+		conns:      make(map[string]*grpclib.ClientConn),
+		writeConns: make(map[string]*grpclib.ClientConn),
+		creds:      insecure.NewCredentials(),
+		localID:    localID,
+	}
+}
+
+func (r *nodeRouter) Rev(message bus.Message) (resp bus.Message) {
+	e, ok := message.Data().(*databasev1.NodeEvent)
+	if !ok {
+		r.log.Warn().Msg("invalid event data type")
+		return
+	}
+	node := e.GetNode()
+	id := node.GetId()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch e.GetAction() {
+	case databasev1.Action_ACTION_PUT:
+		if err := checkAPIVersion(nodeAPIVersion(node)); err != nil {
+			// the node is left out of the placement until it's upgraded
+			r.log.Warn().Err(err).Str("node", id).Str("addr", node.GetAddr()).Msg("ignore the incompatible node")
+			delete(r.nodes, id)
+			r.ring.Remove(id)
+			r.closeConn(id)
+			return
+		}
+		if old, existed := r.nodes[id]; existed &&
+			(old.GetAddr() != node.GetAddr() || old.GetInternalAddr() != node.GetInternalAddr()) {
+			r.closeConn(id)
+		}
+		r.nodes[id] = node
+		r.ring.Add(id)
+	case databasev1.Action_ACTION_DELETE:
+		delete(r.nodes, id)
+		r.ring.Remove(id)
+		r.closeConn(id)
+	default:
+		return
+	}
+	r.log.Info().
+		Str("action", databasev1.Action_name[int32(e.GetAction())]).
+		Str("node", id).
+		Str("addr", node.GetAddr()).
+		Int("nodes", r.ring.Len()).
+		Msg("rebalanced the shards")
+	return
+}
+
+// locate returns the data node owning the shard of the group, which is nil if the shard is local.
+func (r *nodeRouter) locate(group string, shardID common.ShardID) *databasev1.Node {
+	return r.replicas(group, shardID, 1)[0]
+}
+
+// replicas returns n data nodes holding the replicas of the shard of the group, the primary one first.
+// A local replica is nil. There are fewer replicas if the ring has fewer nodes, and only a local one if it's empty.
+func (r *nodeRouter) replicas(group string, shardID common.ShardID, n int) []*databasev1.Node {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.locateN(group, shardID, n)
+}
+
+func (r *nodeRouter) locateN(group string, shardID common.ShardID, n int) []*databasev1.Node {
+	ids := r.ring.LocateN(append([]byte(group), convert.Uint32ToBytes(uint32(shardID))...), n)
+	if len(ids) < 1 {
+		return []*databasev1.Node{nil}
+	}
+	nodes := make([]*databasev1.Node, 0, len(ids))
+	for _, id := range ids {
+		if id == r.localID {
+			nodes = append(nodes, nil)
+			continue
+		}
+		nodes = append(nodes, r.nodes[id])
+	}
+	return nodes
+}
+
+// placement returns the replicas of all the shards of the group, which are the candidates to serve the queries.
+func (r *nodeRouter) placement(group string, shardNum uint32, n int) map[common.ShardID][]*databasev1.Node {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p := make(map[common.ShardID][]*databasev1.Node, shardNum)
+	for i := uint32(0); i < shardNum; i++ {
+		p[common.ShardID(i)] = r.locateN(group, common.ShardID(i), n)
+	}
+	return p
+}
+
+// remotes returns the data nodes on the ring other than the local one, which are sorted by their ids.
+func (r *nodeRouter) remotes() []*databasev1.Node {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	nodes := make([]*databasev1.Node, 0, len(r.nodes))
+	for id, node := range r.nodes {
+		if id != r.localID {
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].GetId() < nodes[j].GetId() })
+	return nodes
+}
+
+// conn returns the connection to the public listener of the node, which is shared by the sub-queries sent to it.
+func (r *nodeRouter) conn(node *databasev1.Node) (*grpclib.ClientConn, error) {
+	return r.dial(r.conns, node.GetId(), node.GetAddr())
+}
+
+// writeConn returns the connection forwarding the writes to the node, which is to its internal listener.
+// The nodes predating the internal listener receive them by the public one.
+func (r *nodeRouter) writeConn(node *databasev1.Node) (*grpclib.ClientConn, error) {
+	addr := node.GetInternalAddr()
+	if addr == "" {
+		addr = node.GetAddr()
+	}
+	return r.dial(r.writeConns, node.GetId(), addr)
+}
+
+func (r *nodeRouter) dial(conns map[string]*grpclib.ClientConn, id, addr string) (*grpclib.ClientConn, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := conns[id]; ok {
+		return c, nil
+	}
+	c, err := grpclib.Dial(addr, grpclib.WithTransportCredentials(r.creds))
+	if err != nil {
+		return nil, err
+	}
+	conns[id] = c
+	return c, nil
+}
+
+func (r *nodeRouter) closeConn(id string) {
+	for _, conns := range []map[string]*grpclib.ClientConn{r.conns, r.writeConns} {
+		if c, ok := conns[id]; ok {
+			_ = c.Close()
+			delete(conns, id)
+		}
+	}
+}
+
+func (r *nodeRouter) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id := range r.conns {
+		r.closeConn(id)
+	}
+	for id := range r.writeConns {
+		r.closeConn(id)
+	}
+}
+
+// withShards restricts the sub-query sent in the context to the shards.
+func withShards(ctx context.Context, shardIDs []common.ShardID) context.Context {
+	for _, id := range shardIDs {
+		ctx = metadata.AppendToOutgoingContext(ctx, shardsKey, strconv.FormatUint(uint64(id), 10))
+	}
+	return ctx
+}
+
+// assignedShards returns the shards a sub-query is restricted to, which are none if it isn't restricted.
+func assignedShards(ctx context.Context) ([]common.ShardID, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+	values := md.Get(shardsKey)
+	shardIDs := make([]common.ShardID, 0, len(values))
+	for _, v := range values {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid shard id %s", v)
+		}
+		shardIDs = append(shardIDs, common.ShardID(id))
+	}
+	return shardIDs, nil
+}
+
+type forwardStream[Q, R any] interface {
+	Send(Q) error
+	Recv() (R, error)
+	CloseSend() error
+}
+
+// forwarder holds a stream to each data node, which forwards the writes received by a stream of the liaison.
+// The responses of the data nodes are taken by ack in the order of the requests if they're awaited, otherwise they're drained.
+type forwarder[Q, R any] struct {
+	ctx     context.Context
+	router  *nodeRouter
+	open    func(ctx context.Context, node *databasev1.Node, conn *grpclib.ClientConn) (forwardStream[Q, R], error)
+	streams map[string]*forwardConn[Q, R]
+	wg      sync.WaitGroup
+	awaited bool
+}
+
+// forwardConn is a stream to a data node and the responses received but not acknowledged yet.
+type forwardConn[Q, R any] struct {
+	stream    forwardStream[Q, R]
+	err       error
+	notify    chan struct{}
+	done      chan struct{}
+	responses []R
+	// pending is the number of the requests sent since the last ack
+	pending int
+	mu      sync.Mutex
+}
+
+func newForwarder[Q, R any](ctx context.Context, router *nodeRouter,
+	open func(ctx context.Context, node *databasev1.Node, conn *grpclib.ClientConn) (forwardStream[Q, R], error),
+	awaited bool,
+) *forwarder[Q, R] {
+	return &forwarder[Q, R]{
+		ctx:     withAPIVersion(ctx),
+		router:  router,
+		open:    open,
+		streams: make(map[string]*forwardConn[Q, R]),
+		awaited: awaited,
+	}
+}
+
+// openStreamWrite opens the stream forwarding the elements to the node.
+// The nodes predating the InternalWriteService receive them by the public service marked by the legacy header.
+func openStreamWrite(ctx context.Context, node *databasev1.Node, conn *grpclib.ClientConn,
+) (forwardStream[*streamv1.WriteRequest, *streamv1.WriteResponse], error) {
+	if supports(node, featureInternalWrites) {
+		return clusterv1.NewInternalWriteServiceClient(conn).WriteStream(ctx)
+	}
+	return streamv1.NewStreamServiceClient(conn).Write(metadata.AppendToOutgoingContext(ctx, legacyForwardedKey, "true"))
+}
+
+// openMeasureWrite opens the stream forwarding the data points to the node.
+func openMeasureWrite(ctx context.Context, node *databasev1.Node, conn *grpclib.ClientConn,
+) (forwardStream[*measurev1.WriteRequest, *measurev1.WriteResponse], error) {
+	if supports(node, featureInternalWrites) {
+		return clusterv1.NewInternalWriteServiceClient(conn).WriteMeasure(ctx)
+	}
+	return measurev1.NewMeasureServiceClient(conn).Write(metadata.AppendToOutgoingContext(ctx, legacyForwardedKey, "true"))
+}
+
+func (f *forwarder[Q, R]) send(node *databasev1.Node, req Q) error {
+	c, ok := f.streams[node.GetId()]
+	if !ok {
+		conn, err := f.router.writeConn(node)
+		if err != nil {
+			return err
+		}
+		s, err := f.open(f.ctx, node, conn)
+		if err != nil {
+			return err
+		}
+		c = &forwardConn[Q, R]{stream: s, notify: make(chan struct{}, 1), done: make(chan struct{})}
+		f.streams[node.GetId()] = c
+		f.wg.Add(1)
+		go f.receive(c)
+	}
+	if err := c.stream.Send(req); err != nil {
+		f.drop(node)
+		return err
+	}
+	if f.awaited {
+		c.pending++
+	}
+	return nil
+}
+
+// receive keeps the responses of the stream for the acks, or drains them to keep the stream flowing.
+func (f *forwarder[Q, R]) receive(c *forwardConn[Q, R]) {
+	defer f.wg.Done()
+	defer close(c.done)
+	for {
+		resp, err := c.stream.Recv()
+		if err != nil {
+			c.err = err
+			return
+		}
+		if !f.awaited {
+			continue
+		}
+		c.mu.Lock()
+		c.responses = append(c.responses, resp)
+		c.mu.Unlock()
+		select {
+		case c.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// ack waits for the responses of the requests sent to the node since the last ack.
+// It returns an error if the stream breaks or the node doesn't respond in time, and the stream is dropped.
+func (f *forwarder[Q, R]) ack(node *databasev1.Node) ([]R, error) {
+	c, ok := f.streams[node.GetId()]
+	if !ok {
+		return nil, errForwardStreamBroken
+	}
+	timer := time.NewTimer(forwardAckTimeout)
+	defer timer.Stop()
+	for {
+		if resps, ok := c.take(); ok {
+			return resps, nil
+		}
+		select {
+		case <-c.notify:
+		case <-c.done:
+			if resps, ok := c.take(); ok {
+				return resps, nil
+			}
+			f.drop(node)
+			if c.err == nil || errors.Is(c.err, io.EOF) {
+				return nil, errForwardStreamBroken
+			}
+			return nil, c.err
+		case <-timer.C:
+			f.drop(node)
+			return nil, errForwardAckTimeout
+		}
+	}
+}
+
+// take takes the responses of the pending requests if all of them are received.
+func (c *forwardConn[Q, R]) take() ([]R, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.responses) < c.pending {
+		return nil, false
+	}
+	resps := c.responses[:c.pending:c.pending]
+	c.responses = c.responses[c.pending:]
+	c.pending = 0
+	return resps, true
+}
+
+// drop ends the stream to the node, a new one is opened by the next request sent to the node.
+func (f *forwarder[Q, R]) drop(node *databasev1.Node) {
+	if c, ok := f.streams[node.GetId()]; ok {
+		_ = c.stream.CloseSend()
+		delete(f.streams, node.GetId())
+	}
+}
+
+// close ends the streams and waits until the data nodes receive all the forwarded writes.
+func (f *forwarder[Q, R]) close() {
+	for _, c := range f.streams {
+		_ = c.stream.CloseSend()
+	}
+	f.wg.Wait()
+}
+
+// replicaAcks collects the items of a write sent to the replicas written synchronously,
+// which are acknowledged by the responses of the replicas.
+type replicaAcks struct {
+	// sent are the indexes of the items of the requests sent to each node, in the order they're sent
+	sent        map[string][][]uint32
+	failed      map[string]bool
+	unavailable map[uint32]bool
+	nodes       []*databasev1.Node
+	errors      []*modelv1.WriteError
+}
+
+func newReplicaAcks() *replicaAcks {
+	return &replicaAcks{
+		sent:        make(map[string][][]uint32),
+		failed:      make(map[string]bool),
+		unavailable: make(map[uint32]bool),
+	}
+}
+
+// add records the items of a request sent to the node.
+func (a *replicaAcks) add(node *databasev1.Node, indexes []uint32) {
+	if _, ok := a.sent[node.GetId()]; !ok {
+		a.nodes = append(a.nodes, node)
+	}
+	a.sent[node.GetId()] = append(a.sent[node.GetId()], indexes)
+}
+
+// skip tells whether the node failed in the write, whose items left are reported as unavailable instead of being sent.
+func (a *replicaAcks) skip(node *databasev1.Node) bool {
+	return a.failed[node.GetId()]
+}
+
+// fail reports the items sent or to be sent to the node are unavailable.
+func (a *replicaAcks) fail(node *databasev1.Node, indexes []uint32, err error) {
+	a.failed[node.GetId()] = true
+	for _, i := range indexes {
+		if !a.unavailable[i] {
+			a.unavailable[i] = true
+			a.errors = append(a.errors, replicaUnavailable(i, node, err))
+		}
+	}
+}
+
+// awaitReplicas waits for the acks of all the replicas the items are sent to, and returns the items not stored by any of them.
+// The items rejected by a replica are reported with its reason.
+func awaitReplicas[Q any, R interface{ GetErrors() []*modelv1.WriteError }](fw *forwarder[Q, R], a *replicaAcks,
+) []*modelv1.WriteError {
+	for _, node := range a.nodes {
+		sent := a.sent[node.GetId()]
+		if a.skip(node) {
+			for _, indexes := range sent {
+				a.fail(node, indexes, errForwardStreamBroken)
+			}
+			continue
+		}
+		resps, err := fw.ack(node)
+		if err != nil {
+			for _, indexes := range sent {
+				a.fail(node, indexes, err)
+			}
+			continue
+		}
+		for k, resp := range resps {
+			for _, wErr := range resp.GetErrors() {
+				if k >= len(sent) || int(wErr.GetIndex()) >= len(sent[k]) {
+					continue
+				}
+				i := sent[k][wErr.GetIndex()]
+				if a.unavailable[i] {
+					continue
+				}
+				a.unavailable[i] = true
+				a.errors = append(a.errors, &modelv1.WriteError{
+					Index:   i,
+					Code:    wErr.GetCode(),
+					Message: fmt.Sprintf("rejected by the replica on the node %s: %s", node.GetId(), wErr.GetMessage()),
+				})
+			}
+		}
+	}
+	return a.errors
+}
+
+// replicaUnavailable reports the item at the index of the request isn't received by the replica on the node.
+func replicaUnavailable(index uint32, node *databasev1.Node, err error) *modelv1.WriteError {
+	return &modelv1.WriteError{
+		Index:   index,
+		Code:    modelv1.WriteError_CODE_REPLICA_UNAVAILABLE,
+		Message: fmt.Sprintf("the replica on the node %s is unavailable: %v", node.GetId(), err),
+	}
+}
+
+var _ clusterv1.InternalWriteServiceServer = (*internalWriteServer)(nil)
+
+// internalWriteServer receives the writes forwarded by the liaisons, which are written to the local shards only.
+// It's served by the internal listener only, which authenticates the peers by their certificates if the server is on TLS,
+// so the clients of the public listener can't skip the write filters and the routing.
+type internalWriteServer struct {
+	clusterv1.UnimplementedInternalWriteServiceServer
+	streamSVC  *streamService
+	measureSVC *measureService
+}
+
+func (s *internalWriteServer) WriteStream(stream clusterv1.InternalWriteService_WriteStreamServer) error {
+	return s.streamSVC.serveWrite(stream, true)
+}
+
+func (s *internalWriteServer) WriteMeasure(stream clusterv1.InternalWriteService_WriteMeasureServer) error {
+	return s.measureSVC.serveWrite(stream, true)
+}
@@ -0,0 +1,461 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/api/event"
+	clusterv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/cluster/v1"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+type fakeDataNode struct {
+	streamv1.UnimplementedStreamServiceServer
+	clusterv1.UnimplementedInternalWriteServiceServer
+	received []string
+	// internal tells whether the writes are received by the InternalWriteService
+	internal bool
+	// legacy tells whether the writes are marked by the legacy header
+	legacy bool
+	// broken breaks the streams once a write is received
+	broken bool
+	mu     sync.Mutex
+}
+
+func (n *fakeDataNode) Write(stream streamv1.StreamService_WriteServer) error {
+	return n.receive(stream, false)
+}
+
+func (n *fakeDataNode) WriteStream(stream clusterv1.InternalWriteService_WriteStreamServer) error {
+	return n.receive(stream, true)
+}
+
+func (n *fakeDataNode) receive(stream streamv1.StreamService_WriteServer, internal bool) error {
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		n.mu.Lock()
+		n.internal = internal
+		n.legacy = len(md.Get(legacyForwardedKey)) > 0
+		n.received = append(n.received, req.GetElement().GetElementId())
+		broken := n.broken
+		n.mu.Unlock()
+		if broken {
+			return status.Error(codes.Unavailable, "the disk is full")
+		}
+		resp := &streamv1.WriteResponse{}
+		if strings.HasPrefix(req.GetElement().GetElementId(), "bad") {
+			resp.Errors = []*modelv1.WriteError{{Code: modelv1.WriteError_CODE_TAG_TYPE, Message: "invalid tag"}}
+		}
+		if err = stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// serve starts a data node serving both the public and the internal services.
+func (n *fakeDataNode) serve() (addr string, stop func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+	ser := grpclib.NewServer()
+	streamv1.RegisterStreamServiceServer(ser, n)
+	clusterv1.RegisterInternalWriteServiceServer(ser, n)
+	go func() {
+		_ = ser.Serve(lis)
+	}()
+	return lis.Addr().String(), ser.Stop
+}
+
+// serveInternal starts a data node serving the public services and the internal one by the separate listeners.
+// The internal listener is on the credentials if they're present.
+func (n *fakeDataNode) serveInternal(creds credentials.TransportCredentials) (addr, internalAddr string, stop func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+	internalLis, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+	ser := grpclib.NewServer()
+	streamv1.RegisterStreamServiceServer(ser, n)
+	var opts []grpclib.ServerOption
+	if creds != nil {
+		opts = append(opts, grpclib.Creds(creds))
+	}
+	internalSer := grpclib.NewServer(opts...)
+	clusterv1.RegisterInternalWriteServiceServer(internalSer, n)
+	go func() {
+		_ = ser.Serve(lis)
+	}()
+	go func() {
+		_ = internalSer.Serve(internalLis)
+	}()
+	return lis.Addr().String(), internalLis.Addr().String(), func() {
+		ser.Stop()
+		internalSer.Stop()
+	}
+}
+
+type fakePublisher struct {
+	topic    bus.Topic
+	messages []bus.Message
+}
+
+func (p *fakePublisher) Publish(topic bus.Topic, message ...bus.Message) (bus.Future, error) {
+	p.topic = topic
+	p.messages = append(p.messages, message...)
+	return nil, nil
+}
+
+var _ = Describe("NodeRouter", func() {
+	var router *nodeRouter
+	nodeEvent := func(id, addr string, action databasev1.Action) {
+		router.Rev(bus.NewMessage(bus.MessageID(1), &databasev1.NodeEvent{
+			Node:   &databasev1.Node{Id: id, Addr: addr, ApiVersion: apiVersion, Features: features},
+			Action: action,
+		}))
+	}
+	// owners returns the remote node of each shard, "" means the shard is local
+	owners := func() []string {
+		result := make([]string, 0, 32)
+		for i := 0; i < 32; i++ {
+			var owner string
+			if n := router.locate("default", common.ShardID(i)); n != nil {
+				owner = n.GetId()
+			}
+			result = append(result, owner)
+		}
+		return result
+	}
+	BeforeEach(func() {
+		router = newNodeRouter("local")
+		router.log = logger.GetLogger("test")
+	})
+	AfterEach(func() {
+		router.close()
+	})
+	It("keeps all the shards local without any node", func() {
+		Expect(owners()).To(HaveEach(""))
+	})
+	It("rebalances the shards as the nodes join and leave", func() {
+		nodeEvent("local", "localhost:17912", databasev1.Action_ACTION_PUT)
+		nodeEvent("remote-1", "remote-1:17912", databasev1.Action_ACTION_PUT)
+		before := owners()
+		Expect(before).To(ContainElement(""))
+		Expect(before).To(ContainElement("remote-1"))
+		nodeEvent("remote-2", "remote-2:17912", databasev1.Action_ACTION_PUT)
+		after := owners()
+		Expect(after).To(ContainElement("remote-2"))
+		for i := range after {
+			// only the shards taken by the joining node move
+			if after[i] != "remote-2" {
+				Expect(after[i]).To(Equal(before[i]))
+			}
+		}
+		nodeEvent("remote-2", "", databasev1.Action_ACTION_DELETE)
+		Expect(owners()).To(Equal(before))
+	})
+	It("places the replicas of a shard on the distinct nodes", func() {
+		Expect(router.replicas("default", 0, 3)).To(Equal([]*databasev1.Node{nil}))
+		nodeEvent("local", "localhost:17912", databasev1.Action_ACTION_PUT)
+		nodeEvent("remote-1", "remote-1:17912", databasev1.Action_ACTION_PUT)
+		nodeEvent("remote-2", "remote-2:17912", databasev1.Action_ACTION_PUT)
+		for i := 0; i < 32; i++ {
+			replicas := router.replicas("default", common.ShardID(i), 3)
+			Expect(replicas).To(HaveLen(3))
+			// the primary replica is the owner of the shard
+			Expect(replicas[0]).To(Equal(router.locate("default", common.ShardID(i))))
+			Expect(replicas).To(ContainElement(BeNil()))
+		}
+		Expect(router.replicas("default", 0, 5)).To(HaveLen(3))
+	})
+	It("replicates the writes in the background", func() {
+		dataNode := &fakeDataNode{}
+		addr, stop := dataNode.serve()
+		defer stop()
+		nodeEvent("remote", addr, databasev1.Action_ACTION_PUT)
+		node := router.locate("default", 0)
+		Expect(node).NotTo(BeNil())
+
+		events := &fakePublisher{}
+		r := newReplicator(router, events)
+		r.log = logger.GetLogger("test")
+		for i := 0; i < 3; i++ {
+			r.enqueue(node, &streamv1.WriteRequest{
+				Metadata: &commonv1.Metadata{Group: "default", Name: "sw"},
+				Element:  &streamv1.ElementValue{ElementId: strconv.Itoa(i)},
+			})
+		}
+		r.report()
+		Expect(events.topic).To(Equal(event.TopicReplicaEvent))
+		Expect(events.messages).To(HaveLen(1))
+		e := events.messages[0].Data().(*databasev1.ReplicaEvent)
+		Expect(e.GetNode().GetId()).To(Equal("remote"))
+		Expect(e.GetLag()).To(BeNumerically("<=", 3))
+		Expect(e.GetDropped()).To(BeZero())
+		r.close()
+		dataNode.mu.Lock()
+		defer dataNode.mu.Unlock()
+		Expect(dataNode.received).To(Equal([]string{"0", "1", "2"}))
+		Expect(dataNode.internal).To(BeTrue())
+	})
+	It("reports the writes dropped by a lagging replica", func() {
+		node := &databasev1.Node{Id: "remote", Addr: "remote:17912"}
+		events := &fakePublisher{}
+		r := newReplicator(router, events)
+		r.log = logger.GetLogger("test")
+		// the queue isn't consumed, so the writes beyond its capacity are dropped
+		r.queues[node.GetId()] = &replicaQueue{node: node, writes: make(chan interface{}, 1)}
+		for i := 0; i < 3; i++ {
+			r.enqueue(node, writeOf(strconv.Itoa(i)))
+		}
+		// the drops are reported once in an interval
+		Expect(events.messages).To(HaveLen(1))
+		e := events.messages[0].Data().(*databasev1.ReplicaEvent)
+		Expect(e.GetDropped()).To(BeNumerically("==", 1))
+		r.report()
+		Expect(events.messages).To(HaveLen(2))
+		e = events.messages[1].Data().(*databasev1.ReplicaEvent)
+		Expect(e.GetDropped()).To(BeNumerically("==", 2))
+		Expect(e.GetLag()).To(BeNumerically("==", 1))
+		r.close()
+	})
+	It("forwards the writes to the owner by the internal service", func() {
+		dataNode := &fakeDataNode{}
+		addr, stop := dataNode.serve()
+		defer stop()
+		nodeEvent("remote", addr, databasev1.Action_ACTION_PUT)
+		node := router.locate("default", 0)
+		Expect(node).NotTo(BeNil())
+
+		fw := newForwarder(context.Background(), router, openStreamWrite, true)
+		for i := 0; i < 3; i++ {
+			Expect(fw.send(node, &streamv1.WriteRequest{
+				Metadata: &commonv1.Metadata{Group: "default", Name: "sw"},
+				Element:  &streamv1.ElementValue{ElementId: strconv.Itoa(i)},
+			})).To(Succeed())
+		}
+		resps, err := fw.ack(node)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resps).To(HaveLen(3))
+		fw.close()
+		dataNode.mu.Lock()
+		defer dataNode.mu.Unlock()
+		Expect(dataNode.received).To(Equal([]string{"0", "1", "2"}))
+		Expect(dataNode.internal).To(BeTrue())
+		Expect(dataNode.legacy).To(BeFalse())
+	})
+	It("forwards the writes to the internal listener of the owner", func() {
+		dataNode := &fakeDataNode{}
+		addr, internalAddr, stop := dataNode.serveInternal(nil)
+		defer stop()
+		router.Rev(bus.NewMessage(bus.MessageID(1), &databasev1.NodeEvent{
+			Node:   &databasev1.Node{Id: "remote", Addr: addr, InternalAddr: internalAddr, ApiVersion: apiVersion, Features: features},
+			Action: databasev1.Action_ACTION_PUT,
+		}))
+		node := router.locate("default", 0)
+		Expect(node).NotTo(BeNil())
+
+		fw := newForwarder(context.Background(), router, openStreamWrite, true)
+		Expect(fw.send(node, writeOf("0"))).To(Succeed())
+		_, err := fw.ack(node)
+		Expect(err).NotTo(HaveOccurred())
+		fw.close()
+		dataNode.mu.Lock()
+		defer dataNode.mu.Unlock()
+		Expect(dataNode.received).To(Equal([]string{"0"}))
+		Expect(dataNode.internal).To(BeTrue())
+	})
+	It("authenticates the peers on the internal listener by their certificates", func() {
+		certFile, keyFile := selfSignedCert(GinkgoT().TempDir())
+		serverCreds, peerCreds, err := peerTLS(certFile, keyFile)
+		Expect(err).NotTo(HaveOccurred())
+		dataNode := &fakeDataNode{}
+		_, internalAddr, stop := dataNode.serveInternal(serverCreds)
+		defer stop()
+		_, port, err := net.SplitHostPort(internalAddr)
+		Expect(err).NotTo(HaveOccurred())
+		// the cert is issued to localhost
+		node := &databasev1.Node{Id: "remote", InternalAddr: net.JoinHostPort("localhost", port), ApiVersion: apiVersion, Features: features}
+
+		router.creds = peerCreds
+		fw := newForwarder(context.Background(), router, openStreamWrite, true)
+		Expect(fw.send(node, writeOf("0"))).To(Succeed())
+		_, err = fw.ack(node)
+		Expect(err).NotTo(HaveOccurred())
+		fw.close()
+		router.closeConn("remote")
+
+		// the caller trusting the cert without presenting it isn't a peer
+		router.creds, err = credentials.NewClientTLSFromFile(certFile, "")
+		Expect(err).NotTo(HaveOccurred())
+		fw = newForwarder(context.Background(), router, openStreamWrite, true)
+		defer fw.close()
+		if err = fw.send(node, writeOf("1")); err == nil {
+			_, err = fw.ack(node)
+		}
+		Expect(err).To(HaveOccurred())
+		dataNode.mu.Lock()
+		defer dataNode.mu.Unlock()
+		Expect(dataNode.received).To(Equal([]string{"0"}))
+	})
+	It("forwards the writes to a legacy node by the public service", func() {
+		dataNode := &fakeDataNode{}
+		addr, stop := dataNode.serve()
+		defer stop()
+		// the node registered without a version predates the internal service
+		router.Rev(bus.NewMessage(bus.MessageID(1), &databasev1.NodeEvent{
+			Node:   &databasev1.Node{Id: "remote", Addr: addr},
+			Action: databasev1.Action_ACTION_PUT,
+		}))
+		node := router.locate("default", 0)
+		Expect(node).NotTo(BeNil())
+
+		fw := newForwarder(context.Background(), router, openStreamWrite, true)
+		Expect(fw.send(node, &streamv1.WriteRequest{
+			Metadata: &commonv1.Metadata{Group: "default", Name: "sw"},
+			Element:  &streamv1.ElementValue{ElementId: "0"},
+		})).To(Succeed())
+		fw.close()
+		dataNode.mu.Lock()
+		defer dataNode.mu.Unlock()
+		Expect(dataNode.received).To(Equal([]string{"0"}))
+		Expect(dataNode.internal).To(BeFalse())
+		Expect(dataNode.legacy).To(BeTrue())
+	})
+	It("reports the elements not received by an unreachable replica", func() {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		addr := lis.Addr().String()
+		Expect(lis.Close()).To(Succeed())
+		nodeEvent("remote", addr, databasev1.Action_ACTION_PUT)
+		node := router.locate("default", 0)
+		Expect(node).NotTo(BeNil())
+
+		fw := newForwarder(context.Background(), router, openStreamWrite, true)
+		defer fw.close()
+		acks := newReplicaAcks()
+		if errFwd := fw.send(node, writeOf("0")); errFwd != nil {
+			acks.fail(node, []uint32{2}, errFwd)
+		} else {
+			acks.add(node, []uint32{2})
+		}
+		writeErrors := awaitReplicas(fw, acks)
+		Expect(writeErrors).To(HaveLen(1))
+		Expect(writeErrors[0].GetIndex()).To(BeNumerically("==", 2))
+		Expect(writeErrors[0].GetCode()).To(Equal(modelv1.WriteError_CODE_REPLICA_UNAVAILABLE))
+		Expect(writeErrors[0].GetMessage()).To(ContainSubstring("remote"))
+	})
+	It("waits for the acks of the replicas written synchronously", func() {
+		dataNode := &fakeDataNode{}
+		addr, stop := dataNode.serve()
+		defer stop()
+		nodeEvent("remote", addr, databasev1.Action_ACTION_PUT)
+		node := router.locate("default", 0)
+		Expect(node).NotTo(BeNil())
+
+		fw := newForwarder(context.Background(), router, openStreamWrite, true)
+		defer fw.close()
+		acks := newReplicaAcks()
+		for i, id := range []string{"0", "bad", "2"} {
+			Expect(fw.send(node, writeOf(id))).To(Succeed())
+			acks.add(node, []uint32{uint32(i) + 4})
+		}
+		writeErrors := awaitReplicas(fw, acks)
+		// the element rejected by the replica is reported by its offset in the request received
+		Expect(writeErrors).To(HaveLen(1))
+		Expect(writeErrors[0].GetIndex()).To(BeNumerically("==", 5))
+		Expect(writeErrors[0].GetCode()).To(Equal(modelv1.WriteError_CODE_TAG_TYPE))
+
+		dataNode.mu.Lock()
+		dataNode.broken = true
+		dataNode.mu.Unlock()
+		acks = newReplicaAcks()
+		// the broken stream might fail the sending or the ack
+		if errFwd := fw.send(node, writeOf("3")); errFwd != nil {
+			acks.fail(node, []uint32{0}, errFwd)
+		} else {
+			acks.add(node, []uint32{0})
+		}
+		writeErrors = awaitReplicas(fw, acks)
+		Expect(writeErrors).To(HaveLen(1))
+		Expect(writeErrors[0].GetCode()).To(Equal(modelv1.WriteError_CODE_REPLICA_UNAVAILABLE))
+	})
+})
+
+// selfSignedCert writes the cert issued to localhost and its key to the directory.
+func selfSignedCert(dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Expect(err).NotTo(HaveOccurred())
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	Expect(os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)).To(Succeed())
+	Expect(os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)).To(Succeed())
+	return certFile, keyFile
+}
+
+func writeOf(elementID string) *streamv1.WriteRequest {
+	return &streamv1.WriteRequest{
+		Metadata: &commonv1.Metadata{Group: "default", Name: "sw"},
+		Element:  &streamv1.ElementValue{ElementId: elementID},
+	}
+}
@@ -19,7 +19,11 @@ package grpc
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"net"
+	"os"
+	"sync"
 	"time"
 
 	grpc_validator "github.com/grpc-ecosystem/go-grpc-middleware/validator"
@@ -28,8 +32,11 @@ import (
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 
+	"github.com/apache/skywalking-banyandb/api/data"
 	"github.com/apache/skywalking-banyandb/api/event"
+	clusterv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/cluster/v1"
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
 	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
 	propertyv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/property/v1"
@@ -38,35 +45,100 @@ import (
 	"github.com/apache/skywalking-banyandb/banyand/metadata"
 	"github.com/apache/skywalking-banyandb/banyand/queue"
 	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/grpchelper"
+	"github.com/apache/skywalking-banyandb/pkg/intern"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 	"github.com/apache/skywalking-banyandb/pkg/run"
 )
 
-const defaultRecvSize = 1024 * 1024 * 10
+const (
+	defaultRecvSize = 1024 * 1024 * 10
+	// defaultInternSize is the number of the strings interned by default, which takes several MBs at most
+	defaultInternSize = 1 << 16
+)
 
 var (
-	ErrServerCert = errors.New("invalid server cert file")
-	ErrServerKey  = errors.New("invalid server key file")
-	ErrNoAddr     = errors.New("no address")
-	ErrQueryMsg   = errors.New("invalid query message")
+	ErrServerCert     = errors.New("invalid server cert file")
+	ErrServerKey      = errors.New("invalid server key file")
+	ErrNoAddr         = errors.New("no address")
+	ErrNoInternalAddr = errors.New("no internal address")
+	ErrQueryMsg       = errors.New("invalid query message")
+	ErrRolloverMsg    = errors.New("invalid rollover message")
+	ErrIndexMsg       = errors.New("invalid index message")
+	ErrTimeRangeMsg   = errors.New("invalid time range message")
+	ErrUsageMsg       = errors.New("invalid usage message")
+	ErrManifestMsg    = errors.New("invalid manifest message")
+	ErrBlockExportMsg = errors.New("invalid block export message")
+
+	errNegativeQueryLimit        = errors.New("the query limits should not be negative")
+	errInvalidBatchPolicy        = errors.New("the query batch sizes and latency should be positive, and the max size should not be less than the min one")
+	errBatcherClosed             = errors.New("the batches of the query are no longer sent")
+	errInvalidCoalescePolicy     = errors.New("the size and linger of the coalesced writes should not be negative")
+	errInvalidTagSizePolicy      = errors.New("the tag oversize policy should be one of truncate, reject and external")
+	errNegativeTagSize           = errors.New("the max sizes of the tag values should not be negative")
+	errNoExternalTagPath         = errors.New("the external policy of the oversized tag values needs the tag-external-path")
+	errInvalidRejectionLog       = errors.New("the write rejection sample rate should be within [0, 1], and its capacity and max payload size should not be negative")
+	errInvalidSchemaCompatWindow = errors.New("the write schema compatibility window should be 0 or at least 1s")
 )
 
 type Server struct {
-	addr           string
-	maxRecvMsgSize int
-	tls            bool
-	certFile       string
-	keyFile        string
-	log            *logger.Logger
-	ser            *grpclib.Server
-	pipeline       queue.Queue
-	repo           discovery.ServiceRepo
-	creds          credentials.TransportCredentials
+	addr             string
+	advertiseAddr    string
+	internalAddr     string
+	internalAdvAddr  string
+	maxRecvMsgSize   int
+	internSize       int
+	tls              bool
+	enableReflection bool
+	certFile         string
+	keyFile          string
+	log              *logger.Logger
+	ser              *grpclib.Server
+	internalSer      *grpclib.Server
+	pipeline         queue.Queue
+	repo             discovery.ServiceRepo
+	creds            credentials.TransportCredentials
+	internalCreds    credentials.TransportCredentials
+	queryLimits      *queryLimits
+	batchPolicy      *batchPolicy
+	snapshotPolicy   *snapshotPolicy
+	coalescePolicy   *coalescePolicy
+	tagSizes         *tagSizeGuard
+	rejections       *rejectionLog
+	schemaCompat     *schemaCompat
+	schemaRegistry   metadata.Service
+	watchHub         *watchHub
+	subscriptions    *subscriptionHub
+	writeFilter      *writeFilter
+	writeValidator   *writeValidator
+	router           *nodeRouter
+	replicator       *replicator
+	drainer          *drainer
+	health           *health.Server
+	drainTimeout     time.Duration
+	sendCompression  string
+	authorizer       *authorizer
+
+	unaryInterceptors  []grpclib.UnaryServerInterceptor
+	streamInterceptors []grpclib.StreamServerInterceptor
 
 	stopCh chan struct{}
 
-	streamSVC  *streamService
-	measureSVC *measureService
+	streamSVC     *streamService
+	measureSVC    *measureService
+	serverInfoSVC *serverInfoServer
+	slowQuerySVC  *slowQueryServer
+	topQuerySVC   *topQueryServer
+	shardSVC      *shardServer
+	indexSVC      *indexServer
+	drainSVC      *drainServer
+	timeRangeSVC  *timeRangeServer
+	usageSVC      *usageServer
+	manifestSVC   *manifestServer
+	blockSVC      *blockExportServer
+	configSVC     *configServer
+	rejectionSVC  *rejectionServer
+	compatSVC     *schemaCompatServer
 	*streamRegistryServer
 	*indexRuleBindingRegistryServer
 	*indexRuleRegistryServer
@@ -77,29 +149,154 @@ type Server struct {
 }
 
 func NewServer(_ context.Context, pipeline queue.Queue, repo discovery.ServiceRepo, schemaRegistry metadata.Service) *Server {
+	limits := &queryLimits{}
+	batch := &batchPolicy{}
+	snapshot := &snapshotPolicy{}
+	coalesce := &coalescePolicy{}
+	tagSizes := &tagSizeGuard{}
+	rejections := &rejectionLog{}
+	compat := newSchemaCompat()
+	hub := newWatchHub()
+	filter := newWriteFilter(schemaRegistry)
+	subscriptions := newSubscriptionHub(filter)
+	validator := newWriteValidator(schemaRegistry, tagSizes, compat)
+	router := newNodeRouter(repo.NodeID())
+	replicator := newReplicator(router, repo)
+	d := &drainer{}
+	healthSVC := health.NewServer()
+	shardSVC := &shardServer{
+		schemaRegistry: schemaRegistry,
+		pipeline:       pipeline,
+	}
+	streamSVC := &streamService{
+		discoveryService: newDiscoveryService(pipeline),
+		schemaRegistry:   schemaRegistry,
+		limits:           limits,
+		batch:            batch,
+		filter:           filter,
+		validator:        validator,
+		router:           router,
+		replicator:       replicator,
+		drainer:          d,
+		subscriptions:    subscriptions,
+		rejections:       rejections,
+		snapshots:        newSnapshots[*streamv1.Element](snapshot),
+		coalescer: newWriteCoalescer("stream", coalesce, pipeline, data.TopicStreamWrite,
+			func(r *streamv1.InternalWriteRequest) coalesceKey {
+				return coalesceKey{group: r.GetRequest().GetMetadata().GetGroup(), shard: r.GetShardId()}
+			},
+			func(requests []*streamv1.InternalWriteRequest) bus.Payload {
+				return &streamv1.InternalWriteBatch{Requests: requests}
+			}),
+	}
+	measureSVC := &measureService{
+		discoveryService: newDiscoveryService(pipeline),
+		schemaRegistry:   schemaRegistry,
+		limits:           limits,
+		batch:            batch,
+		filter:           filter,
+		validator:        validator,
+		router:           router,
+		replicator:       replicator,
+		drainer:          d,
+		subscriptions:    subscriptions,
+		rejections:       rejections,
+		snapshots:        newSnapshots[*measurev1.DataPoint](snapshot),
+		coalescer: newWriteCoalescer("measure", coalesce, pipeline, data.TopicMeasureWrite,
+			func(r *measurev1.InternalWriteRequest) coalesceKey {
+				return coalesceKey{group: r.GetRequest().GetMetadata().GetGroup(), shard: r.GetShardId()}
+			},
+			func(requests []*measurev1.InternalWriteRequest) bus.Payload {
+				return &measurev1.InternalWriteBatch{Requests: requests}
+			}),
+	}
 	return &Server{
-		pipeline: pipeline,
-		repo:     repo,
-		streamSVC: &streamService{
-			discoveryService: newDiscoveryService(pipeline),
+		pipeline:       pipeline,
+		repo:           repo,
+		queryLimits:    limits,
+		batchPolicy:    batch,
+		snapshotPolicy: snapshot,
+		coalescePolicy: coalesce,
+		tagSizes:       tagSizes,
+		rejections:     rejections,
+		schemaCompat:   compat,
+		schemaRegistry: schemaRegistry,
+		watchHub:       hub,
+		subscriptions:  subscriptions,
+		writeFilter:    filter,
+		writeValidator: validator,
+		router:         router,
+		replicator:     replicator,
+		drainer:        d,
+		health:         healthSVC,
+		authorizer:     &authorizer{tags: filter.resource},
+		streamSVC:      streamSVC,
+		measureSVC:     measureSVC,
+		serverInfoSVC:  &serverInfoServer{},
+		slowQuerySVC: &slowQueryServer{
+			pipeline: pipeline,
+		},
+		topQuerySVC: &topQueryServer{
+			pipeline: pipeline,
+		},
+		shardSVC: shardSVC,
+		indexSVC: &indexServer{
+			schemaRegistry: schemaRegistry,
+			pipeline:       pipeline,
+		},
+		timeRangeSVC: &timeRangeServer{
+			schemaRegistry: schemaRegistry,
+			pipeline:       pipeline,
+		},
+		configSVC: &configServer{},
+		rejectionSVC: &rejectionServer{
+			rejections: rejections,
+		},
+		compatSVC: &schemaCompatServer{
+			compat: compat,
+		},
+		manifestSVC: &manifestServer{
+			schemaRegistry: schemaRegistry,
+			pipeline:       pipeline,
 		},
-		measureSVC: &measureService{
-			discoveryService: newDiscoveryService(pipeline),
+		blockSVC: &blockExportServer{
+			schemaRegistry: schemaRegistry,
+			pipeline:       pipeline,
+		},
+		usageSVC: &usageServer{
+			schemaRegistry: schemaRegistry,
+			pipeline:       pipeline,
+			router:         router,
+		},
+		drainSVC: &drainServer{
+			drainer:        d,
+			health:         healthSVC,
+			pipeline:       pipeline,
+			schemaRegistry: schemaRegistry,
+			shardSVC:       shardSVC,
+			streamSVC:      streamSVC,
+			measureSVC:     measureSVC,
 		},
 		streamRegistryServer: &streamRegistryServer{
 			schemaRegistry: schemaRegistry,
+			hub:            hub,
 		},
 		indexRuleBindingRegistryServer: &indexRuleBindingRegistryServer{
 			schemaRegistry: schemaRegistry,
 		},
 		indexRuleRegistryServer: &indexRuleRegistryServer{
 			schemaRegistry: schemaRegistry,
+			hub:            hub,
 		},
 		measureRegistryServer: &measureRegistryServer{
 			schemaRegistry: schemaRegistry,
+			hub:            hub,
 		},
 		groupRegistryServer: &groupRegistryServer{
 			schemaRegistry: schemaRegistry,
+			hub:            hub,
+			streamSVC:      streamSVC,
+			measureSVC:     measureSVC,
 		},
 		topNAggregationRegistryServer: &topNAggregationRegistryServer{
 			schemaRegistry: schemaRegistry,
@@ -112,6 +309,23 @@ func NewServer(_ context.Context, pipeline queue.Queue, repo discovery.ServiceRe
 
 func (s *Server) PreRun() error {
 	s.log = logger.GetLogger("liaison-grpc")
+	s.writeFilter.log = s.log
+	s.writeValidator.log = s.log
+	s.router.log = s.log
+	s.replicator.log = s.log
+	s.drainSVC.log = s.log
+	s.usageSVC.log = s.log
+	s.authorizer.log = s.log
+	s.streamSVC.coalescer.log = s.log
+	s.measureSVC.coalescer.log = s.log
+	// the node id is configured by the flags after the server is created
+	s.router.localID = s.repo.NodeID()
+	interning := intern.New("liaison-entity", s.internSize)
+	s.streamSVC.interning = interning
+	s.measureSVC.interning = interning
+	if err := s.repo.Subscribe(event.TopicNodeEvent, s.router); err != nil {
+		return err
+	}
 	components := []struct {
 		shardEvent   bus.Topic
 		entityEvent  bus.Topic
@@ -146,6 +360,34 @@ func (s *Server) Name() string {
 	return "grpc"
 }
 
+// RegisterModules exposes the modules of the running server through the ServerInfoService,
+// and their reloadable flags through the ConfigService.
+func (s *Server) RegisterModules(modules ...run.Unit) {
+	s.serverInfoSVC.register(modules...)
+	s.configSVC.register(modules...)
+}
+
+// ReloadableFlags returns the server's limits of a query, which are changed at runtime.
+func (s *Server) ReloadableFlags() map[string]string {
+	return s.queryLimits.flags()
+}
+
+func (s *Server) PrepareReload(values map[string]string) (func(), error) {
+	return s.queryLimits.prepareReload(values)
+}
+
+// RegisterUnaryInterceptors appends the interceptors to the chain of the unary calls.
+// They run in the order of the registration before the built-in ones, and should be registered before the server serves.
+func (s *Server) RegisterUnaryInterceptors(interceptors ...grpclib.UnaryServerInterceptor) {
+	s.unaryInterceptors = append(s.unaryInterceptors, interceptors...)
+}
+
+// RegisterStreamInterceptors appends the interceptors to the chain of the streaming calls.
+// They run in the order of the registration before the built-in ones, and should be registered before the server serves.
+func (s *Server) RegisterStreamInterceptors(interceptors ...grpclib.StreamServerInterceptor) {
+	s.streamInterceptors = append(s.streamInterceptors, interceptors...)
+}
+
 func (s *Server) FlagSet() *run.FlagSet {
 	fs := run.NewFlagSet("grpc")
 	fs.IntVarP(&s.maxRecvMsgSize, "max-recv-msg-size", "", defaultRecvSize, "the size of max receiving message")
@@ -153,6 +395,63 @@ func (s *Server) FlagSet() *run.FlagSet {
 	fs.StringVarP(&s.certFile, "cert-file", "", "", "the TLS cert file")
 	fs.StringVarP(&s.keyFile, "key-file", "", "", "the TLS key file")
 	fs.StringVarP(&s.addr, "addr", "", ":17912", "the address of banyand listens")
+	fs.StringVarP(&s.advertiseAddr, "advertise-addr", "", "",
+		"the address the other nodes reach banyand at, which is the listening address whose absent host is replaced by the hostname by default")
+	fs.StringVarP(&s.internalAddr, "internal-addr", "", ":17914",
+		"the address of banyand listens for the writes forwarded by the other nodes, which should be reachable by them only. "+
+			"The peers are authenticated by their certificates if the connection uses TLS")
+	fs.DurationVarP(&s.drainTimeout, "drain-timeout", "", 0,
+		"drain the server before stopping, which bounds the time waiting for the in-flight write streams, 0 disables draining on the stop")
+	fs.StringVarP(&s.sendCompression, "send-compression", "", "",
+		"compress all the messages sent to the clients by gzip or zstd, e.g. the large query responses across the zones, "+
+			"which should be decompressible by the clients. The messages are sent as compressed as the received ones if it's absent")
+	fs.StringVarP(&s.authorizer.url, "authorizer-url", "", "",
+		"delegate the authorization of the calls to the webhook at the url, e.g. the data API of an OPA policy, which is disabled if it's absent")
+	fs.DurationVarP(&s.authorizer.timeout, "authorizer-timeout", "", time.Second, "the timeout of a request to the authorizer webhook")
+	fs.DurationVarP(&s.authorizer.cacheTTL, "authorizer-cache-ttl", "", 10*time.Second,
+		"the time to cache a decision of the authorizer webhook, 0 disables the cache")
+	fs.BoolVarP(&s.authorizer.failOpen, "authorizer-fail-open", "", false,
+		"allow the calls if the authorizer webhook is unavailable, which are denied by default")
+	fs.IntVarP(&s.internSize, "write-intern-size", "", defaultInternSize,
+		"the number of the strings interned for the entities of the writes, e.g. the names of services, 0 disables the interning")
+	fs.IntVarP(&s.coalescePolicy.maxSize, "write-coalesce-size", "", 256,
+		"the max number of the writes of a shard coalesced into a message published to the write pipeline, 0 means unbounded")
+	fs.DurationVarP(&s.coalescePolicy.linger, "write-coalesce-linger", "", 5*time.Millisecond,
+		"the max time the writes of a shard linger before they're published to the write pipeline, 0 publishes the writes of a request at once")
+	fs.IntVarP(&s.tagSizes.maxString, "tag-max-string-size", "", 0, "the max size of a string tag value in bytes, 0 means unlimited")
+	fs.IntVarP(&s.tagSizes.maxBinary, "tag-max-binary-size", "", 0, "the max size of a binary tag value in bytes, 0 means unlimited")
+	fs.IntVarP(&s.tagSizes.maxArray, "tag-max-array-size", "", 0,
+		"the max size of an array tag value in bytes, which counts 8 bytes per integer, 0 means unlimited")
+	fs.StringVarP(&s.tagSizes.policy, "tag-oversize-policy", "", tagSizePolicyReject,
+		"the policy of the oversized tag values: truncate, reject or external, the tags of the entity are always rejected")
+	fs.StringVarP(&s.tagSizes.marker, "tag-truncated-marker", "", "truncated_tags",
+		"the string array tag set to the names of the truncated tags if a stream or a measure defines it")
+	fs.StringVarP(&s.tagSizes.externalPath, "tag-external-path", "", "", "the directory of the oversized tag values stored by the external policy")
+	fs.Float64VarP(&s.rejections.sampleRate, "write-rejection-sample-rate", "", 0,
+		"the rate of the rejected writes kept in the write rejection log for debugging the dropped data, 0 disables the log")
+	fs.IntVarP(&s.rejections.capacity, "write-rejection-log-capacity", "", 100, "the max number of the rejected writes kept in the write rejection log")
+	fs.IntVarP(&s.rejections.maxPayloadSize, "write-rejection-max-payload-size", "", 4096,
+		"the max size of the payload of a rejected write kept in the write rejection log in bytes, 0 means unlimited")
+	fs.BoolVarP(&s.rejections.redact, "write-rejection-redact", "", true,
+		"hide the string and binary values of the rejected writes kept in the write rejection log, which keeps the sizes of the strings")
+	fs.DurationVarP(&s.schemaCompat.window, schemaCompatWindowFlag, "", 10*time.Minute,
+		"the rolling window of the write schema compatibility report, which tracks how the writes match their schemas, 0 disables the report")
+	fs.BoolVarP(&s.enableReflection, "enable-reflection", "", false, "register the gRPC reflection service if true")
+	fs.DurationVarP(&s.queryLimits.timeout, queryTimeoutFlag, "", 0, "the max execution time of a query, 0 means unlimited")
+	fs.Int64VarP(&s.queryLimits.maxScannedSeries, queryMaxScannedSeriesFlag, "", 0, "the max number of the series scanned by a query, 0 means unlimited")
+	fs.Int64VarP(&s.queryLimits.maxScannedBlocks, queryMaxScannedBlocksFlag, "", 0, "the max number of the blocks scanned by a query, 0 means unlimited")
+	fs.Int64VarP(&s.queryLimits.maxResponseBytes, queryMaxResponseBytesFlag, "", 0, "the max size of a query's response in bytes, 0 means unlimited")
+	fs.Int64VarP(&s.queryLimits.maxResponseItems, queryMaxResponseItemsFlag, "", 0,
+		"the max number of the elements or the data points of a query's response, 0 means unlimited")
+	fs.IntVarP(&s.batchPolicy.minBytes, "query-batch-min-bytes", "", 64<<10, "the min size of a batch sent by the batched query RPCs in bytes")
+	fs.IntVarP(&s.batchPolicy.maxBytes, "query-batch-max-bytes", "", 1<<20,
+		"the max size of a batch sent by the batched query RPCs in bytes, beyond which the query waits for the client")
+	fs.DurationVarP(&s.batchPolicy.latency, "query-batch-latency", "", 10*time.Millisecond,
+		"the max time a message waits for its batch to be sent by the batched query RPCs")
+	fs.DurationVarP(&s.snapshotPolicy.ttl, querySnapshotTTLFlag, "", time.Minute,
+		"how long the results captured by a snapshot query are kept for its next page since its last page is read")
+	fs.IntVarP(&s.snapshotPolicy.maxItems, querySnapshotMaxItemsFlag, "", 10000,
+		"the max number of the elements or the data points captured by a snapshot query for its pages")
 	return fs
 }
 
@@ -160,6 +459,48 @@ func (s *Server) Validate() error {
 	if s.addr == "" {
 		return ErrNoAddr
 	}
+	if s.internalAddr == "" {
+		return ErrNoInternalAddr
+	}
+	if err := s.queryLimits.validate(); err != nil {
+		return err
+	}
+	if err := s.batchPolicy.validate(); err != nil {
+		return err
+	}
+	if err := s.snapshotPolicy.validate(); err != nil {
+		return err
+	}
+	if err := s.coalescePolicy.validate(); err != nil {
+		return err
+	}
+	if err := s.tagSizes.validate(); err != nil {
+		return err
+	}
+	if err := s.rejections.validate(); err != nil {
+		return err
+	}
+	if err := s.schemaCompat.validate(); err != nil {
+		return err
+	}
+	if err := grpchelper.CheckCompressor(s.sendCompression); err != nil {
+		return err
+	}
+	if err := s.authorizer.validate(); err != nil {
+		return err
+	}
+	if s.advertiseAddr == "" {
+		addr, err := advertiseAddr(s.addr)
+		if err != nil {
+			return err
+		}
+		s.advertiseAddr = addr
+	}
+	internalAdvAddr, err := internalAdvertiseAddr(s.internalAddr, s.advertiseAddr)
+	if err != nil {
+		return err
+	}
+	s.internalAdvAddr = internalAdvAddr
 	if !s.tls {
 		return nil
 	}
@@ -174,22 +515,72 @@ func (s *Server) Validate() error {
 		return errors.Wrap(errTLS, "failed to load cert and key")
 	}
 	s.creds = creds
+	internalCreds, peerCreds, errTLS := peerTLS(s.certFile, s.keyFile)
+	if errTLS != nil {
+		return errTLS
+	}
+	s.internalCreds = internalCreds
+	s.router.creds = peerCreds
 	return nil
 }
 
-func (s *Server) Serve() run.StopNotify {
+// peerTLS returns the credentials of the internal listener and the ones dialing the peers.
+// The nodes share the cert, which authenticates both the internal listener and its callers.
+func peerTLS(certFile, keyFile string) (credentials.TransportCredentials, credentials.TransportCredentials, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to load cert and key")
+	}
+	pem, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to read cert")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, nil, ErrServerCert
+	}
+	return credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{cert},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    pool,
+			MinVersion:   tls.VersionTLS12,
+		}), credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{cert},
+			RootCAs:      pool,
+			MinVersion:   tls.VersionTLS12,
+		}), nil
+}
+
+// serverOptions returns the options of a listener on the credentials, which are used if the server is on TLS.
+func (s *Server) serverOptions(creds credentials.TransportCredentials) []grpclib.ServerOption {
 	var opts []grpclib.ServerOption
 	if s.tls {
-		opts = []grpclib.ServerOption{grpclib.Creds(s.creds)}
+		opts = []grpclib.ServerOption{grpclib.Creds(creds)}
+	}
+	if s.sendCompression != "" {
+		// the compressor is checked by Validate
+		cp, _ := grpchelper.SendCompressor(s.sendCompression)
+		//nolint:staticcheck // the registered compressors can't be forced on the responses
+		opts = append(opts, grpclib.RPCCompressor(cp))
 	}
+	unary, stream := s.interceptors()
 	opts = append(opts, grpclib.MaxRecvMsgSize(s.maxRecvMsgSize),
-		grpclib.UnaryInterceptor(grpc_validator.UnaryServerInterceptor()),
-		grpclib.StreamInterceptor(grpc_validator.StreamServerInterceptor()),
+		grpclib.ChainUnaryInterceptor(unary...),
+		grpclib.ChainStreamInterceptor(stream...),
 	)
-	s.ser = grpclib.NewServer(opts...)
+	return opts
+}
+
+func (s *Server) Serve() run.StopNotify {
+	s.ser = grpclib.NewServer(s.serverOptions(s.creds)...)
+	// the forwarded writes are received by the internal listener only
+	s.internalSer = grpclib.NewServer(s.serverOptions(s.internalCreds)...)
+	clusterv1.RegisterInternalWriteServiceServer(s.internalSer, &internalWriteServer{streamSVC: s.streamSVC, measureSVC: s.measureSVC})
 
 	streamv1.RegisterStreamServiceServer(s.ser, s.streamSVC)
 	measurev1.RegisterMeasureServiceServer(s.ser, s.measureSVC)
+	clusterv1.RegisterInternalQueryServiceServer(s.ser, &internalQueryServer{streamSVC: s.streamSVC, measureSVC: s.measureSVC, usageSVC: s.usageSVC})
+	clusterv1.RegisterInternalBlockServiceServer(s.ser, s.blockSVC)
 	// register *Registry
 	databasev1.RegisterGroupRegistryServiceServer(s.ser, s.groupRegistryServer)
 	databasev1.RegisterIndexRuleBindingRegistryServiceServer(s.ser, s.indexRuleBindingRegistryServer)
@@ -197,31 +588,99 @@ func (s *Server) Serve() run.StopNotify {
 	databasev1.RegisterStreamRegistryServiceServer(s.ser, s.streamRegistryServer)
 	databasev1.RegisterMeasureRegistryServiceServer(s.ser, s.measureRegistryServer)
 	propertyv1.RegisterPropertyServiceServer(s.ser, s.propertyServer)
-	grpc_health_v1.RegisterHealthServer(s.ser, health.NewServer())
+	databasev1.RegisterServerInfoServiceServer(s.ser, s.serverInfoSVC)
+	databasev1.RegisterSlowQueryServiceServer(s.ser, s.slowQuerySVC)
+	databasev1.RegisterTopQueryServiceServer(s.ser, s.topQuerySVC)
+	databasev1.RegisterShardServiceServer(s.ser, s.shardSVC)
+	databasev1.RegisterIndexServiceServer(s.ser, s.indexSVC)
+	databasev1.RegisterDrainServiceServer(s.ser, s.drainSVC)
+	databasev1.RegisterTimeRangeServiceServer(s.ser, s.timeRangeSVC)
+	databasev1.RegisterUsageServiceServer(s.ser, s.usageSVC)
+	databasev1.RegisterManifestServiceServer(s.ser, s.manifestSVC)
+	databasev1.RegisterConfigServiceServer(s.ser, s.configSVC)
+	databasev1.RegisterWriteRejectionServiceServer(s.ser, s.rejectionSVC)
+	databasev1.RegisterSchemaCompatibilityServiceServer(s.ser, s.compatSVC)
+	grpc_health_v1.RegisterHealthServer(s.ser, s.health)
+	if s.enableReflection {
+		reflection.Register(s.ser)
+	}
+	s.serverInfoSVC.startedAt = time.Now()
+	s.schemaRegistry.StreamRegistry().RegisterHandler(watchKinds, s.watchHub)
+	s.schemaRegistry.StreamRegistry().RegisterHandler(writeFilterKinds, s.writeFilter)
+	s.schemaRegistry.StreamRegistry().RegisterHandler(writeValidatorKinds, s.writeValidator)
+
+	s.replicator.start()
 
 	s.stopCh = make(chan struct{})
-	go func() {
-		lis, err := net.Listen("tcp", s.addr)
-		if err != nil {
-			s.log.Error().Err(err).Msg("Failed to listen")
-			close(s.stopCh)
-			return
-		}
-		s.log.Info().Str("addr", s.addr).Msg("Listening to")
-		err = s.ser.Serve(lis)
-		if err != nil {
-			s.log.Error().Err(err).Msg("server is interrupted")
-		}
+	if err := s.registerNode(context.Background(), s.advertiseAddr, s.internalAdvAddr); err != nil {
+		s.log.Error().Err(err).Msg("failed to register the node")
 		close(s.stopCh)
-	}()
+		return s.stopCh
+	}
+	var once sync.Once
+	stop := func() {
+		once.Do(func() { close(s.stopCh) })
+	}
+	go s.listen(s.ser, s.addr, stop)
+	go s.listen(s.internalSer, s.internalAddr, stop)
 	return s.stopCh
 }
 
+// listen serves the listener at the address, and calls stop once it's interrupted.
+func (s *Server) listen(ser *grpclib.Server, addr string, stop func()) {
+	defer stop()
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		s.log.Error().Err(err).Str("addr", addr).Msg("Failed to listen")
+		return
+	}
+	s.log.Info().Str("addr", addr).Msg("Listening to")
+	if err = ser.Serve(lis); err != nil {
+		s.log.Error().Err(err).Str("addr", addr).Msg("server is interrupted")
+	}
+}
+
+// interceptors builds the chains of the registered interceptors followed by the built-in ones.
+// The built-in ones reject the incompatible callers, then authorize the calls before validating them, so that the denied calls aren't validated.
+func (s *Server) interceptors() ([]grpclib.UnaryServerInterceptor, []grpclib.StreamServerInterceptor) {
+	unary := make([]grpclib.UnaryServerInterceptor, 0, len(s.unaryInterceptors)+3)
+	unary = append(unary, s.unaryInterceptors...)
+	unary = append(unary, versionUnaryInterceptor())
+	stream := make([]grpclib.StreamServerInterceptor, 0, len(s.streamInterceptors)+3)
+	stream = append(stream, s.streamInterceptors...)
+	stream = append(stream, versionStreamInterceptor())
+	if s.authorizer.enabled() {
+		unary = append(unary, s.authorizer.unaryInterceptor())
+		stream = append(stream, s.authorizer.streamInterceptor())
+	}
+	unary = append(unary, grpc_validator.UnaryServerInterceptor())
+	stream = append(stream, grpc_validator.StreamServerInterceptor())
+	return unary, stream
+}
+
+// Drain drains the server before the other modules stop if the drain timeout is set.
+func (s *Server) Drain() {
+	if s.drainTimeout <= 0 {
+		return
+	}
+	if _, err := s.drainSVC.drain(context.Background(), s.drainTimeout); err != nil {
+		s.log.Error().Err(err).Msg("failed to drain")
+	}
+}
+
 func (s *Server) GracefulStop() {
 	s.log.Info().Msg("stopping")
+	s.watchHub.close()
+	s.subscriptions.close()
+	// the writes received before stopping are published to the pipeline, which stops after the server
+	defer s.measureSVC.coalescer.flush()
+	defer s.streamSVC.coalescer.flush()
+	defer s.router.close()
+	defer s.replicator.close()
 	stopped := make(chan struct{})
 	go func() {
 		s.ser.GracefulStop()
+		s.internalSer.GracefulStop()
 		close(stopped)
 	}()
 
@@ -229,6 +688,7 @@ func (s *Server) GracefulStop() {
 	select {
 	case <-t.C:
 		s.ser.Stop()
+		s.internalSer.Stop()
 		s.log.Info().Msg("force stopped")
 	case <-stopped:
 		t.Stop()
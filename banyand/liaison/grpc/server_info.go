@@ -0,0 +1,90 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/banyand/tsdb"
+	"github.com/apache/skywalking-banyandb/pkg/run"
+	"github.com/apache/skywalking-banyandb/pkg/version"
+)
+
+// recoveryReporter is a module opening the databases at startup
+type recoveryReporter interface {
+	RecoveryProgress() tsdb.RecoveryProgress
+}
+
+type serverInfoServer struct {
+	databasev1.UnimplementedServerInfoServiceServer
+	startedAt time.Time
+	modules   []run.Unit
+	mu        sync.RWMutex
+}
+
+func (s *serverInfoServer) register(modules ...run.Unit) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.modules = append(s.modules, modules...)
+}
+
+func (s *serverInfoServer) Get(_ context.Context, _ *databasev1.ServerInfoServiceGetRequest) (*databasev1.ServerInfoServiceGetResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	info := &databasev1.ServerInfo{
+		Version:     version.Parse(),
+		BuildCommit: version.Commit(),
+		StartedAt:   timestamppb.New(s.startedAt),
+		Modules:     make([]*databasev1.Module, 0, len(s.modules)),
+		ApiVersion:  apiVersion,
+		Features:    features,
+	}
+	for _, m := range s.modules {
+		module := &databasev1.Module{
+			Name:    m.Name(),
+			Healthy: true,
+		}
+		if checker, ok := m.(run.HealthChecker); ok {
+			if err := checker.CheckHealth(); err != nil {
+				module.Healthy = false
+				module.Message = err.Error()
+			}
+		}
+		if reporter, ok := m.(recoveryReporter); ok {
+			p := reporter.RecoveryProgress()
+			module.Recovery = &databasev1.Recovery{
+				Shards:       uint32(p.Shards),
+				OpenedShards: uint32(p.OpenedShards),
+				Segments:     uint32(p.Segments),
+				Eta:          durationpb.New(p.ETA),
+				Done:         p.Done(),
+			}
+			if !p.StartedAt.IsZero() {
+				module.Recovery.StartedAt = timestamppb.New(p.StartedAt)
+			}
+		}
+		info.Modules = append(info.Modules, module)
+	}
+	return &databasev1.ServerInfoServiceGetResponse{ServerInfo: info}, nil
+}
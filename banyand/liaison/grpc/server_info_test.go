@@ -0,0 +1,75 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/pkg/grpchelper"
+)
+
+var _ = Describe("ServerInfo", func() {
+	var gracefulStop func()
+	var conn *grpclib.ClientConn
+	BeforeEach(func() {
+		gracefulStop = setupForRegistry()
+		var err error
+		conn, err = grpchelper.Conn("localhost:17912", 10*time.Second, grpclib.WithTransportCredentials(insecure.NewCredentials()))
+		Expect(err).NotTo(HaveOccurred())
+	})
+	AfterEach(func() {
+		_ = conn.Close()
+		gracefulStop()
+	})
+	It("shows the server info", func() {
+		resp, err := databasev1.NewServerInfoServiceClient(conn).Get(context.TODO(), &databasev1.ServerInfoServiceGetRequest{})
+		Expect(err).ShouldNot(HaveOccurred())
+		info := resp.GetServerInfo()
+		Expect(info.GetVersion()).NotTo(BeEmpty())
+		Expect(info.GetStartedAt().AsTime()).To(BeTemporally("<=", time.Now()))
+		names := make([]string, 0, len(info.GetModules()))
+		for _, m := range info.GetModules() {
+			Expect(m.GetHealthy()).To(BeTrue(), m.GetMessage())
+			names = append(names, m.GetName())
+		}
+		Expect(names).To(ContainElements("metadata", "grpc"))
+	})
+	It("lists services through the reflection", func() {
+		stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.TODO())
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(stream.Send(&reflectionpb.ServerReflectionRequest{
+			MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+		})).To(Succeed())
+		resp, err := stream.Recv()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(stream.CloseSend()).To(Succeed())
+		var services []string
+		for _, s := range resp.GetListServicesResponse().GetService() {
+			services = append(services, s.GetName())
+		}
+		Expect(services).To(ContainElement("banyandb.database.v1.ServerInfoService"))
+	})
+})
@@ -0,0 +1,76 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/api/data"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/banyand/metadata"
+	"github.com/apache/skywalking-banyandb/banyand/queue"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+)
+
+type shardServer struct {
+	databasev1.UnimplementedShardServiceServer
+	schemaRegistry metadata.Service
+	pipeline       queue.Queue
+}
+
+func (s *shardServer) Rollover(ctx context.Context, req *databasev1.ShardServiceRolloverRequest) (*databasev1.ShardServiceRolloverResponse, error) {
+	if req.GetGroup() == "" {
+		return nil, status.Error(codes.InvalidArgument, "the group is absent")
+	}
+	g, err := s.schemaRegistry.GroupRegistry().GetGroup(ctx, req.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	var topic bus.Topic
+	switch g.GetCatalog() {
+	case commonv1.Catalog_CATALOG_STREAM:
+		topic = data.TopicStreamRollover
+	case commonv1.Catalog_CATALOG_MEASURE:
+		topic = data.TopicMeasureRollover
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "the group %s of the catalog %s has no shards", req.GetGroup(), g.GetCatalog())
+	}
+	message := bus.NewMessage(bus.MessageID(time.Now().UnixNano()), req)
+	feat, err := s.pipeline.Publish(topic, message)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := feat.Get()
+	if err != nil {
+		return nil, err
+	}
+	switch d := msg.Data().(type) {
+	case *databasev1.ShardServiceRolloverResponse:
+		return d, nil
+	case common.Error:
+		return nil, errors.WithMessage(ErrRolloverMsg, d.Msg())
+	}
+	return nil, ErrRolloverMsg
+}
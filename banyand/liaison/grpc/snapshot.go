@@ -0,0 +1,172 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+const (
+	querySnapshotTTLFlag      = "query-snapshot-ttl"
+	querySnapshotMaxItemsFlag = "query-snapshot-max-items"
+	// maxSnapshots bounds the snapshots held at the same time, the expired ones are released before opening a new one
+	maxSnapshots = 1024
+)
+
+var errInvalidSnapshotPolicy = errors.New("the query snapshot TTL and max items should be positive")
+
+// snapshotPolicy is shared by the snapshots of streams and measures.
+type snapshotPolicy struct {
+	// ttl is how long a snapshot is kept since it's read last time
+	ttl time.Duration
+	// maxItems is the max number of the results captured by a snapshot
+	maxItems int
+}
+
+func (p *snapshotPolicy) validate() error {
+	if p.ttl <= 0 || p.maxItems <= 0 {
+		return errInvalidSnapshotPolicy
+	}
+	return nil
+}
+
+// snapshotSession holds the results of a query read by its first page, which all its pages are cut from,
+// so that they don't see the data written in between, including the late data earlier than the read timestamp.
+type snapshotSession[T proto.Message] struct {
+	expireAt      time.Time
+	readTimestamp *timestamppb.Timestamp
+	items         []T
+	fingerprint   uint32
+	// full is true if the results are cut at the max items of the policy
+	full bool
+}
+
+// snapshots keeps the sessions of the snapshot queries by their tokens chosen by the server.
+type snapshots[T proto.Message] struct {
+	now      func() time.Time
+	policy   *snapshotPolicy
+	sessions map[string]*snapshotSession[T]
+	mu       sync.Mutex
+}
+
+func newSnapshots[T proto.Message](policy *snapshotPolicy) *snapshots[T] {
+	return &snapshots[T]{
+		now:      time.Now,
+		policy:   policy,
+		sessions: make(map[string]*snapshotSession[T]),
+	}
+}
+
+// open captures the results of the first page and returns the session with its token.
+func (s *snapshots[T]) open(fingerprint uint32, readTimestamp *timestamppb.Timestamp, items []T) (string, *snapshotSession[T], error) {
+	var bb [16]byte
+	if _, err := rand.Read(bb[:]); err != nil {
+		return "", nil, err
+	}
+	token := hex.EncodeToString(bb[:])
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.releaseExpired(now)
+	if len(s.sessions) >= maxSnapshots {
+		return "", nil, status.Errorf(codes.ResourceExhausted, "there are %d snapshots open, retry once some of them expire", len(s.sessions))
+	}
+	session := &snapshotSession[T]{
+		expireAt:      now.Add(s.policy.ttl),
+		readTimestamp: readTimestamp,
+		items:         items,
+		fingerprint:   fingerprint,
+		full:          len(items) >= s.policy.maxItems,
+	}
+	s.sessions[token] = session
+	return token, session, nil
+}
+
+// get returns the session of the token, whose TTL is renewed.
+// The token is rejected if it's unknown, expired or opened by another query.
+func (s *snapshots[T]) get(token string, fingerprint uint32) (*snapshotSession[T], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.releaseExpired(now)
+	session, ok := s.sessions[token]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "the snapshot token is unknown or expired, restart the query from the first page")
+	}
+	if session.fingerprint != fingerprint {
+		return nil, status.Error(codes.InvalidArgument, "the snapshot token belongs to another query")
+	}
+	session.expireAt = now.Add(s.policy.ttl)
+	return session, nil
+}
+
+func (s *snapshots[T]) releaseExpired(now time.Time) {
+	for token, session := range s.sessions {
+		if now.After(session.expireAt) {
+			delete(s.sessions, token)
+		}
+	}
+}
+
+// page cuts the page of the captured results.
+func (s *snapshotSession[T]) page(offset, limit uint32) ([]T, error) {
+	if s.full && uint64(offset)+uint64(limitOf(limit)) > uint64(len(s.items)) {
+		return nil, status.Errorf(codes.OutOfRange, "the snapshot holds the first %d results only", len(s.items))
+	}
+	begin, end := page(len(s.items), offset, limit)
+	return s.items[begin:end], nil
+}
+
+// snapshotRequested tells whether the first page of a query opens a snapshot.
+// The read timestamp and the truncation of the limits, which sets the read timestamp, imply it.
+func snapshotRequested(snapshot bool, readTimestamp *timestamppb.Timestamp) bool {
+	return snapshot || readTimestamp != nil
+}
+
+// pinSnapshot hides the data later than the read timestamp, which is validated whenever it's present.
+// It returns the time range narrowed by the read timestamp, which is empty if nothing is visible in the snapshot.
+// The late data earlier than the read timestamp are hidden by capturing the results of the first page instead.
+func pinSnapshot(readTimestamp *timestamppb.Timestamp, timeRange *modelv1.TimeRange) (*modelv1.TimeRange, bool, error) {
+	if readTimestamp == nil {
+		return timeRange, true, nil
+	}
+	if err := timestamp.CheckPb(readTimestamp); err != nil {
+		return nil, false, err
+	}
+	if !timeRange.GetEnd().AsTime().After(readTimestamp.AsTime()) {
+		return timeRange, true, nil
+	}
+	// don't modify the original one which might be shared
+	pinned := &modelv1.TimeRange{
+		Begin: timeRange.GetBegin(),
+		End:   readTimestamp,
+	}
+	return pinned, pinned.GetBegin().AsTime().Before(pinned.GetEnd().AsTime()), nil
+}
@@ -0,0 +1,117 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+)
+
+var _ = Describe("Snapshot", func() {
+	elements := func(ids ...string) []*streamv1.Element {
+		result := make([]*streamv1.Element, 0, len(ids))
+		for _, id := range ids {
+			result = append(result, &streamv1.Element{ElementId: id})
+		}
+		return result
+	}
+	ids := func(elements []*streamv1.Element) []string {
+		result := make([]string, 0, len(elements))
+		for _, e := range elements {
+			result = append(result, e.GetElementId())
+		}
+		return result
+	}
+	var now time.Time
+	var s *snapshots[*streamv1.Element]
+	readTimestamp := timestamppb.New(time.Unix(100, 0))
+	BeforeEach(func() {
+		now = time.Unix(1000, 0)
+		s = newSnapshots[*streamv1.Element](&snapshotPolicy{ttl: time.Minute, maxItems: 4})
+		s.now = func() time.Time { return now }
+	})
+
+	It("cuts the pages from the captured results", func() {
+		token, _, err := s.open(1, readTimestamp, elements("1", "2", "3"))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(token).NotTo(BeEmpty())
+		session, err := s.get(token, 1)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(session.readTimestamp).To(Equal(readTimestamp))
+		items, err := session.page(0, 2)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(ids(items)).To(Equal([]string{"1", "2"}))
+		items, err = session.page(2, 2)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(ids(items)).To(Equal([]string{"3"}))
+	})
+
+	It("validates the tokens", func() {
+		token, _, err := s.open(1, readTimestamp, elements("1"))
+		Expect(err).ShouldNot(HaveOccurred())
+		_, err = s.get("unknown", 1)
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+		_, err = s.get(token, 2)
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("releases the snapshots unused within the TTL", func() {
+		token, _, err := s.open(1, readTimestamp, elements("1"))
+		Expect(err).ShouldNot(HaveOccurred())
+		now = now.Add(50 * time.Second)
+		_, err = s.get(token, 1)
+		Expect(err).ShouldNot(HaveOccurred())
+		// the TTL is renewed by reading a page
+		now = now.Add(50 * time.Second)
+		_, err = s.get(token, 1)
+		Expect(err).ShouldNot(HaveOccurred())
+		now = now.Add(2 * time.Minute)
+		_, err = s.get(token, 1)
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+		Expect(s.sessions).To(BeEmpty())
+	})
+
+	It("refuses the pages beyond the max items", func() {
+		token, _, err := s.open(1, readTimestamp, elements("1", "2", "3", "4"))
+		Expect(err).ShouldNot(HaveOccurred())
+		session, err := s.get(token, 1)
+		Expect(err).ShouldNot(HaveOccurred())
+		_, err = session.page(2, 2)
+		Expect(err).ShouldNot(HaveOccurred())
+		_, err = session.page(3, 2)
+		Expect(status.Code(err)).To(Equal(codes.OutOfRange))
+	})
+
+	It("always validates the read timestamp", func() {
+		timeRange := &modelv1.TimeRange{Begin: timestamppb.New(time.Unix(0, 0)), End: timestamppb.New(time.Unix(10, 0))}
+		_, _, err := pinSnapshot(timestamppb.New(time.Unix(20, 1)), timeRange)
+		Expect(err).Should(HaveOccurred())
+		pinned, visible, err := pinSnapshot(timestamppb.New(time.Unix(5, 0)), timeRange)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(visible).To(BeTrue())
+		Expect(pinned.GetEnd().AsTime()).To(Equal(time.Unix(5, 0).UTC()))
+	})
+})
@@ -20,15 +20,25 @@ package grpc
 import (
 	"context"
 	"io"
+	"sort"
 	"time"
 
 	"github.com/pkg/errors"
+	grpclib "google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/apache/skywalking-banyandb/api/common"
 	"github.com/apache/skywalking-banyandb/api/data"
+	clusterv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/cluster/v1"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
 	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/banyand/metadata"
+	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
 	"github.com/apache/skywalking-banyandb/banyand/tsdb"
 	"github.com/apache/skywalking-banyandb/pkg/bus"
 	"github.com/apache/skywalking-banyandb/pkg/timestamp"
@@ -36,16 +46,39 @@ import (
 
 type streamService struct {
 	*discoveryService
+	schemaRegistry metadata.Repo
+	limits         *queryLimits
+	batch          *batchPolicy
+	filter         *writeFilter
+	validator      *writeValidator
+	router         *nodeRouter
+	replicator     *replicator
+	drainer        *drainer
+	subscriptions  *subscriptionHub
+	rejections     *rejectionLog
+	coalescer      *writeCoalescer[*streamv1.InternalWriteRequest]
+	snapshots      *snapshots[*streamv1.Element]
 	streamv1.UnimplementedStreamServiceServer
 }
 
 func (s *streamService) Write(stream streamv1.StreamService_WriteServer) error {
-	reply := func() error {
-		if err := stream.Send(&streamv1.WriteResponse{}); err != nil {
+	return s.serveWrite(stream, false)
+}
+
+// serveWrite writes the requests received by the stream, which are forwarded by another liaison if forwarded is true.
+func (s *streamService) serveWrite(stream streamv1.StreamService_WriteServer, forwarded bool) error {
+	if !s.drainer.acquire() {
+		return errDraining
+	}
+	defer s.drainer.release()
+	reply := func(writeErrors []*modelv1.WriteError) error {
+		if err := stream.Send(&streamv1.WriteResponse{Errors: writeErrors}); err != nil {
 			return err
 		}
 		return nil
 	}
+	fw := s.newForwarder(stream.Context())
+	defer fw.close()
 	for {
 		writeEntity, err := stream.Recv()
 		if err == io.EOF {
@@ -54,41 +87,175 @@ func (s *streamService) Write(stream streamv1.StreamService_WriteServer) error {
 		if err != nil {
 			return err
 		}
-		if errTime := timestamp.CheckPb(writeEntity.GetElement().Timestamp); errTime != nil {
-			s.log.Error().Err(errTime).Msg("the element time is invalid")
-			if errResp := reply(); errResp != nil {
-				return errResp
+		if errSend := reply(s.write(stream.Context(), fw, writeEntity, forwarded)); errSend != nil {
+			return errSend
+		}
+	}
+}
+
+func (s *streamService) newForwarder(ctx context.Context) *forwarder[*streamv1.WriteRequest, *streamv1.WriteResponse] {
+	return newForwarder(ctx, s.router, openStreamWrite, true)
+}
+
+// write writes the elements of the request to the replicas of their shards,
+// and returns the ones rejected by the schema or not received by a replica.
+func (s *streamService) write(ctx context.Context, fw *forwarder[*streamv1.WriteRequest, *streamv1.WriteResponse],
+	writeEntity *streamv1.WriteRequest, forwarded bool,
+) []*modelv1.WriteError {
+	requests, batches, writeErrors := s.split(ctx, writeEntity, forwarded)
+	acks := newReplicaAcks()
+	for _, b := range batches {
+		downgraded := downgradeStreamWrite(b.node, b.request)
+		for k, request := range downgraded {
+			if b.async {
+				s.replicator.enqueue(b.node, request)
+				continue
+			}
+			indexes := b.indexes
+			if len(downgraded) > 1 {
+				indexes = b.indexes[k : k+1]
 			}
+			if acks.skip(b.node) {
+				acks.fail(b.node, indexes, errForwardStreamBroken)
+				continue
+			}
+			if errFwd := fw.send(b.node, request); errFwd != nil {
+				s.log.Error().Err(errFwd).Str("node", b.node.GetId()).Msg("failed to forward the elements")
+				acks.fail(b.node, indexes, errFwd)
+				continue
+			}
+			acks.add(b.node, indexes)
+		}
+	}
+	writeErrors = append(writeErrors, awaitReplicas(fw, acks)...)
+	sort.SliceStable(writeErrors, func(i, j int) bool { return writeErrors[i].GetIndex() < writeErrors[j].GetIndex() })
+	for _, r := range requests {
+		s.subscriptions.publish(ctx, schema.KindStream, r.GetRequest().GetMetadata(), r.GetRequest().GetElement().GetTagFamilies(), r.GetRequest().GetElement())
+	}
+	s.coalescer.add(requests...)
+	return writeErrors
+}
+
+// streamBatch is the elements of a stream forwarded to a data node in a message.
+// The batches of the secondary replicas replicated asynchronously are sent in the background.
+type streamBatch struct {
+	node    *databasev1.Node
+	request *streamv1.WriteRequest
+	// indexes are the offsets of the elements in the request received
+	indexes []uint32
+	async   bool
+}
+
+type batchKey struct {
+	node string
+	identity
+	async bool
+}
+
+// split splits the elements of a request into the ones written locally and the batches forwarded to the replicas of their shards.
+// The forwarded elements are written locally only. The elements rejected by the schema are skipped and reported by the errors.
+func (s *streamService) split(ctx context.Context, writeEntity *streamv1.WriteRequest, forwarded bool,
+) ([]*streamv1.InternalWriteRequest, []*streamBatch, []*modelv1.WriteError) {
+	elements := writeEntity.GetElements()
+	if writeEntity.GetElement() != nil {
+		elements = append([]*streamv1.ElementValue{writeEntity.GetElement()}, elements...)
+	}
+	if len(elements) == 0 {
+		s.log.Error().Stringer("metadata", writeEntity.GetMetadata()).Msg("the write request carries no element")
+		return nil, nil, nil
+	}
+	requests := make([]*streamv1.InternalWriteRequest, 0, len(elements))
+	var batches []*streamBatch
+	var writeErrors []*modelv1.WriteError
+	batchIndex := make(map[batchKey]*streamBatch)
+	for i, element := range elements {
+		md := writeEntity.GetMetadata()
+		if wErr := s.validator.validateElement(ctx, md, element); wErr != nil {
+			s.rejections.record(rejectedTypeStream, md, wErr, element)
+			wErr.Index = uint32(i)
+			writeErrors = append(writeErrors, wErr)
 			continue
 		}
-		entity, shardID, err := s.navigate(writeEntity.GetMetadata(), writeEntity.GetElement().GetTagFamilies())
-		if err != nil {
-			s.log.Error().Err(err).Msg("failed to navigate to the write target")
-			if errResp := reply(); errResp != nil {
-				return errResp
+		if !forwarded {
+			target, accepted := s.filter.apply(ctx, schema.KindStream, md, element.GetTagFamilies())
+			if !accepted {
+				continue
 			}
+			md = target
+		}
+		entity, shardID, err := s.navigate(md, element.GetTagFamilies(), element.GetTimestamp().AsTime())
+		if err != nil {
+			wErr := navigationError(md, err)
+			s.rejections.record(rejectedTypeStream, md, wErr, element)
+			wErr.Index = uint32(i)
+			writeErrors = append(writeErrors, wErr)
 			continue
 		}
-		message := bus.NewMessage(bus.MessageID(time.Now().UnixNano()), &streamv1.InternalWriteRequest{
-			Request:    writeEntity,
-			ShardId:    uint32(shardID),
-			SeriesHash: tsdb.HashEntity(entity),
-		})
-		_, errWritePub := s.pipeline.Publish(data.TopicStreamWrite, message)
-		if errWritePub != nil {
-			s.log.Error().Err(errWritePub).Msg("failed to send a message")
-			if errResp := reply(); errResp != nil {
-				return errResp
+		replicas, r := []*databasev1.Node{nil}, replication{replicas: 1}
+		if !forwarded {
+			if wErr := s.validator.assignElementID(ctx, md, element); wErr != nil {
+				s.rejections.record(rejectedTypeStream, md, wErr, element)
+				wErr.Index = uint32(i)
+				writeErrors = append(writeErrors, wErr)
+				continue
 			}
-			continue
+			r = s.shardRepo.replication(getID(&commonv1.Metadata{Name: md.GetGroup()}))
+			replicas = s.router.replicas(md.GetGroup(), shardID, r.replicas)
 		}
-		if errSend := reply(); errSend != nil {
-			return errSend
+		for ri, node := range replicas {
+			if node != nil {
+				key := batchKey{node: node.GetId(), identity: getID(md), async: ri > 0 && r.async()}
+				b, ok := batchIndex[key]
+				if !ok {
+					b = &streamBatch{node: node, request: &streamv1.WriteRequest{Metadata: md}, async: key.async}
+					batchIndex[key] = b
+					batches = append(batches, b)
+				}
+				b.request.Elements = append(b.request.Elements, element)
+				b.indexes = append(b.indexes, uint32(i))
+				continue
+			}
+			requests = append(requests, &streamv1.InternalWriteRequest{
+				Request: &streamv1.WriteRequest{
+					Metadata: md,
+					Element:  element,
+				},
+				ShardId:    uint32(shardID),
+				SeriesHash: tsdb.HashEntity(entity),
+			})
 		}
 	}
+	return requests, batches, writeErrors
 }
 
-func (s *streamService) Query(_ context.Context, entityCriteria *streamv1.QueryRequest) (*streamv1.QueryResponse, error) {
+func (s *streamService) Subscribe(req *streamv1.SubscribeRequest, stream streamv1.StreamService_SubscribeServer) error {
+	return s.subscriptions.subscribe(stream, schema.KindStream, subscription{
+		metadata:   req.GetMetadata(),
+		filter:     req.GetFilter(),
+		bufferSize: req.GetBufferSize(),
+		policy:     req.GetDropPolicy(),
+	}, func(item proto.Message, dropped uint64) error {
+		return stream.Send(&streamv1.SubscribeResponse{Element: item.(*streamv1.ElementValue), Dropped: dropped})
+	})
+}
+
+func (s *streamService) Query(ctx context.Context, entityCriteria *streamv1.QueryRequest) (*streamv1.QueryResponse, error) {
+	defer s.drainer.trackQuery()()
+	page, err := resumeQuery(entityCriteria, entityCriteria.GetLimits())
+	if err != nil {
+		return nil, err
+	}
+	if page.exhausted {
+		return &streamv1.QueryResponse{ReadTimestamp: entityCriteria.GetReadTimestamp(), SnapshotToken: entityCriteria.GetSnapshotToken()}, nil
+	}
+	entityCriteria.Limits = s.limits.apply(entityCriteria.GetLimits())
+	if token := entityCriteria.GetSnapshotToken(); token != "" {
+		session, errGet := s.snapshots.get(token, page.fingerprint)
+		if errGet != nil {
+			return nil, errGet
+		}
+		return s.snapshotPage(entityCriteria, page, token, session)
+	}
 	timeRange := entityCriteria.GetTimeRange()
 	if timeRange == nil {
 		entityCriteria.TimeRange = timestamp.DefaultTimeRange
@@ -96,7 +263,163 @@ func (s *streamService) Query(_ context.Context, entityCriteria *streamv1.QueryR
 	if err := timestamp.CheckTimeRange(entityCriteria.GetTimeRange()); err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "%v is invalid :%s", entityCriteria.GetTimeRange(), err)
 	}
-	message := bus.NewMessage(bus.MessageID(time.Now().UnixNano()), entityCriteria)
+	readTimestamp := entityCriteria.GetReadTimestamp()
+	snapshot := snapshotRequested(entityCriteria.GetSnapshot(), readTimestamp)
+	if snapshot && readTimestamp == nil {
+		readTimestamp = timestamppb.Now()
+	}
+	timeRange, visible, err := pinSnapshot(readTimestamp, entityCriteria.GetTimeRange())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v is invalid :%s", entityCriteria.GetReadTimestamp(), err)
+	}
+	if !visible {
+		return &streamv1.QueryResponse{ReadTimestamp: readTimestamp}, nil
+	}
+	entityCriteria.TimeRange = timeRange
+	if snapshot {
+		// the first page captures all the results within the max items, which the pages are cut from
+		first := proto.Clone(entityCriteria).(*streamv1.QueryRequest)
+		first.Offset, first.Limit = 0, uint32(s.snapshots.policy.maxItems)
+		first.Limits = subLimits(entityCriteria.GetLimits())
+		resp, errQuery := s.query(ctx, first)
+		if errQuery != nil {
+			return nil, errQuery
+		}
+		token, session, errOpen := s.snapshots.open(page.fingerprint, readTimestamp, resp.GetElements())
+		if errOpen != nil {
+			return nil, errOpen
+		}
+		return s.snapshotPage(entityCriteria, page, token, session)
+	}
+	resp, err := s.query(ctx, entityCriteria)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Truncation == nil {
+		resp.Elements, resp.Truncation = truncateItems(entityCriteria.GetLimits(), resp.GetElements())
+	}
+	page.continueAfter(resp.GetTruncation(), len(resp.GetElements()), readTimestamp)
+	resp.ReadTimestamp = readTimestamp
+	return resp, nil
+}
+
+// snapshotPage cuts the page of the query from the results captured by its snapshot.
+func (s *streamService) snapshotPage(req *streamv1.QueryRequest, page *queryPage, token string,
+	session *snapshotSession[*streamv1.Element],
+) (*streamv1.QueryResponse, error) {
+	elements, err := session.page(req.GetOffset(), req.GetLimit())
+	if err != nil {
+		return nil, err
+	}
+	resp := &streamv1.QueryResponse{ReadTimestamp: session.readTimestamp, SnapshotToken: token}
+	resp.Elements, resp.Truncation = truncateItems(req.GetLimits(), elements)
+	page.snapshotToken = token
+	page.continueAfter(resp.GetTruncation(), len(resp.GetElements()), session.readTimestamp)
+	return resp, nil
+}
+
+func (s *streamService) QueryBatches(req *streamv1.QueryRequest, stream streamv1.StreamService_QueryBatchesServer) error {
+	if placement, _ := s.shardPlacement(s.router, req.GetMetadata().GetGroup()); streamable(req, placement) {
+		return s.streamBatches(req, stream)
+	}
+	resp, err := s.Query(stream.Context(), req)
+	if err != nil {
+		return err
+	}
+	b := newBatcher(s.batch, func(elements []*streamv1.Element) error {
+		return stream.Send(&streamv1.QueryResponse{Elements: elements, ReadTimestamp: resp.GetReadTimestamp()})
+	})
+	for _, e := range resp.GetElements() {
+		if err = b.add(e); err != nil {
+			break
+		}
+	}
+	if errClose := b.close(err == nil); err == nil {
+		err = errClose
+	}
+	if err != nil || resp.GetTruncation() == nil {
+		return err
+	}
+	// the truncation and the snapshot token of the next page follow the last batch
+	return stream.Send(&streamv1.QueryResponse{
+		ReadTimestamp: resp.GetReadTimestamp(),
+		Truncation:    resp.GetTruncation(),
+		SnapshotToken: resp.GetSnapshotToken(),
+	})
+}
+
+// streamBatches sends the elements of the query in batches as they're produced by the executor.
+func (s *streamService) streamBatches(req *streamv1.QueryRequest, stream streamv1.StreamService_QueryBatchesServer) error {
+	defer s.drainer.trackQuery()()
+	if req.GetTimeRange() == nil {
+		req.TimeRange = timestamp.DefaultTimeRange
+	}
+	if err := timestamp.CheckTimeRange(req.GetTimeRange()); err != nil {
+		return status.Errorf(codes.InvalidArgument, "%v is invalid :%s", req.GetTimeRange(), err)
+	}
+	req.Limits = s.limits.apply(req.GetLimits())
+	b := newBatcher(s.batch, func(elements []*streamv1.Element) error {
+		return stream.Send(&streamv1.QueryResponse{Elements: elements})
+	})
+	resp, err := s.publishQuery(req, &data.QuerySink{Request: req, Send: func(item proto.Message) error {
+		return b.add(item.(*streamv1.Element))
+	}})
+	if errClose := b.close(err == nil); err == nil {
+		err = errClose
+	}
+	if err != nil || resp.GetTruncation() == nil {
+		return err
+	}
+	return stream.Send(&streamv1.QueryResponse{Truncation: resp.GetTruncation()})
+}
+
+// query fans the query out to the data nodes holding the replicas of the shards of the group, then merges their elements.
+func (s *streamService) query(ctx context.Context, req *streamv1.QueryRequest) (*streamv1.QueryResponse, error) {
+	placement, restricted := s.shardPlacement(s.router, req.GetMetadata().GetGroup())
+	if allLocal(placement) {
+		return s.queryLocal(req)
+	}
+	tag, err := resolveOrderTag(ctx, s.schemaRegistry, req.GetMetadata(), req.GetOrderBy(), req.GetProjection(),
+		func() ([]*databasev1.TagFamilySpec, error) {
+			stream, errGet := s.schemaRegistry.StreamRegistry().GetStream(ctx, req.GetMetadata())
+			if errGet != nil {
+				return nil, errGet
+			}
+			return stream.GetTagFamilies(), nil
+		})
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v is invalid :%s", req.GetOrderBy(), err)
+	}
+	subQuery := subStreamQuery(req, tag)
+	partials, err := fanOut(ctx, s.router, placement, restricted, func(shardIDs []common.ShardID) ([]*streamv1.Element, error) {
+		resp, errLocal := s.queryLocal(proto.Clone(subQuery).(*streamv1.QueryRequest), shardIDs...)
+		return resp.GetElements(), errLocal
+	}, func(ctx context.Context, conn *grpclib.ClientConn) ([]*streamv1.Element, error) {
+		resp, errRemote := clusterv1.NewInternalQueryServiceClient(conn).QueryStream(ctx, subQuery)
+		return resp.GetElements(), errRemote
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &streamv1.QueryResponse{Elements: mergeElements(req, tag, partials)}, nil
+}
+
+// queryLocal executes the query on the local shards, which are restricted to the shardIDs if there are any.
+func (s *streamService) queryLocal(req *streamv1.QueryRequest, shardIDs ...common.ShardID) (*streamv1.QueryResponse, error) {
+	var payload interface{} = req
+	if len(shardIDs) > 0 {
+		payload = &data.ShardQuery{Request: req, ShardIDs: shardIDs}
+	}
+	return s.publishQuery(req, payload)
+}
+
+// publishQuery runs the query carried by the payload on the local node.
+func (s *streamService) publishQuery(req *streamv1.QueryRequest, payload interface{}) (*streamv1.QueryResponse, error) {
+	if req.GetIncludeUnflushed() {
+		// the coalesced writes acknowledged before the query are published to the pipeline flushed by the query
+		s.coalescer.flush()
+	}
+	message := bus.NewMessage(bus.MessageID(time.Now().UnixNano()), payload)
 	feat, errQuery := s.pipeline.Publish(data.TopicStreamQuery, message)
 	if errQuery != nil {
 		return nil, errQuery
@@ -109,6 +432,60 @@ func (s *streamService) Query(_ context.Context, entityCriteria *streamv1.QueryR
 	switch d := data.(type) {
 	case []*streamv1.Element:
 		return &streamv1.QueryResponse{Elements: d}, nil
+	case *streamv1.QueryResponse:
+		return d, nil
+	case *modelv1.ResourceExhausted:
+		return nil, resourceExhausted(d)
+	case common.Error:
+		return nil, errors.WithMessage(ErrQueryMsg, d.Msg())
+	}
+	return nil, ErrQueryMsg
+}
+
+func (s *streamService) Explain(_ context.Context, entityCriteria *streamv1.QueryRequest) (*streamv1.ExplainResponse, error) {
+	timeRange := entityCriteria.GetTimeRange()
+	if timeRange == nil {
+		entityCriteria.TimeRange = timestamp.DefaultTimeRange
+	}
+	if err := timestamp.CheckTimeRange(entityCriteria.GetTimeRange()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v is invalid :%s", entityCriteria.GetTimeRange(), err)
+	}
+	message := bus.NewMessage(bus.MessageID(time.Now().UnixNano()), entityCriteria)
+	feat, errQuery := s.pipeline.Publish(data.TopicStreamExplain, message)
+	if errQuery != nil {
+		return nil, errQuery
+	}
+	msg, errFeat := feat.Get()
+	if errFeat != nil {
+		return nil, errFeat
+	}
+	switch d := msg.Data().(type) {
+	case *modelv1.PlanNode:
+		return &streamv1.ExplainResponse{Plan: d}, nil
+	case common.Error:
+		return nil, errors.WithMessage(ErrQueryMsg, d.Msg())
+	}
+	return nil, ErrQueryMsg
+}
+
+func (s *streamService) Inspect(_ context.Context, req *streamv1.InspectRequest) (*streamv1.InspectResponse, error) {
+	if req.GetTimeRange() != nil {
+		if err := timestamp.CheckTimeRange(req.GetTimeRange()); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "%v is invalid :%s", req.GetTimeRange(), err)
+		}
+	}
+	message := bus.NewMessage(bus.MessageID(time.Now().UnixNano()), req)
+	feat, errQuery := s.pipeline.Publish(data.TopicStreamInspect, message)
+	if errQuery != nil {
+		return nil, errQuery
+	}
+	msg, errFeat := feat.Get()
+	if errFeat != nil {
+		return nil, errFeat
+	}
+	switch d := msg.Data().(type) {
+	case *streamv1.InspectResponse:
+		return d, nil
 	case common.Error:
 		return nil, errors.WithMessage(ErrQueryMsg, d.Msg())
 	}
@@ -0,0 +1,186 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+var _ = Describe("StreamService", func() {
+	md := &commonv1.Metadata{Group: "default", Name: "sw"}
+	element := func(id, endpoint string, ts *timestamppb.Timestamp) *streamv1.ElementValue {
+		return &streamv1.ElementValue{
+			ElementId: id,
+			Timestamp: ts,
+			TagFamilies: []*modelv1.TagFamilyForWrite{{Tags: []*modelv1.TagValue{
+				{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: endpoint}}},
+				{Value: &modelv1.TagValue_Int{Int: &modelv1.Int{Value: 200}}},
+			}}},
+		}
+	}
+	var s *streamService
+	var repo *fakeRepo
+	var request *streamv1.WriteRequest
+	BeforeEach(func() {
+		log := logger.GetLogger("test")
+		repo = &fakeRepo{groups: map[string]*commonv1.Group{
+			"default": {
+				Metadata: &commonv1.Metadata{Name: "default"},
+				WriteFilters: []*commonv1.WriteFilterRule{
+					{Name: "drop-health", Expression: `endpoint == "/health"`, Action: commonv1.WriteFilterRule_ACTION_DROP},
+				},
+			},
+		}}
+		s = &streamService{
+			discoveryService: newDiscoveryService(nil),
+			filter:           newWriteFilter(repo),
+			validator:        newWriteValidator(repo, &tagSizeGuard{}, nil),
+			router:           newNodeRouter("local"),
+		}
+		s.SetLogger(log)
+		s.filter.log = log
+		s.validator.log = log
+		s.router.log = log
+		s.shardRepo.Rev(bus.NewMessage(bus.MessageID(1), &databasev1.ShardEvent{
+			Shard:  &databasev1.Shard{Total: 4, Metadata: &commonv1.Metadata{Name: "default"}},
+			Action: databasev1.Action_ACTION_PUT,
+		}))
+		s.entityRepo.Rev(bus.NewMessage(bus.MessageID(1), &databasev1.EntityEvent{
+			Subject:       md,
+			EntityLocator: []*databasev1.EntityEvent_TagLocator{{FamilyOffset: 0, TagOffset: 0}},
+			Action:        databasev1.Action_ACTION_PUT,
+		}))
+		now := time.Now().Truncate(time.Millisecond)
+		request = &streamv1.WriteRequest{
+			Metadata: md,
+			Element:  element("0", "/home", timestamppb.New(now)),
+			Elements: []*streamv1.ElementValue{
+				element("1", "/checkout", timestamppb.New(now.Add(time.Millisecond))),
+				element("2", "/health", timestamppb.New(now.Add(2*time.Millisecond))),
+				element("3", "/home", nil),
+			},
+		}
+	})
+	AfterEach(func() {
+		s.router.close()
+	})
+	It("splits a batch into the elements", func() {
+		requests, batches, _ := s.split(context.TODO(), request, false)
+		Expect(batches).To(BeEmpty())
+		var ids []string
+		for _, r := range requests {
+			Expect(r.GetRequest().GetMetadata()).To(Equal(md))
+			Expect(r.GetRequest().GetElements()).To(BeEmpty())
+			Expect(r.GetShardId()).To(BeNumerically("<", 4))
+			ids = append(ids, r.GetRequest().GetElement().GetElementId())
+		}
+		// the dropped element and the one without the timestamp are skipped
+		Expect(ids).To(Equal([]string{"0", "1"}))
+	})
+	It("reports the elements rejected by the schema", func() {
+		request.Elements[0].TagFamilies[0].Tags[1] = &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "200"}}}
+		requests, _, writeErrors := s.split(context.TODO(), request, false)
+		Expect(requests).To(HaveLen(1))
+		Expect(writeErrors).To(HaveLen(2))
+		Expect(writeErrors[0].GetIndex()).To(BeNumerically("==", 1))
+		Expect(writeErrors[0].GetCode()).To(Equal(modelv1.WriteError_CODE_TAG_TYPE))
+		Expect(writeErrors[1].GetIndex()).To(BeNumerically("==", 3))
+		Expect(writeErrors[1].GetCode()).To(Equal(modelv1.WriteError_CODE_INVALID_TIMESTAMP))
+	})
+	It("reports the elements routed to a group lacking the stream", func() {
+		repo.groups["default"].WriteFilters = append(repo.groups["default"].WriteFilters, &commonv1.WriteFilterRule{
+			Name: "route-checkout", Expression: `endpoint == "/checkout"`, Action: commonv1.WriteFilterRule_ACTION_ROUTE, RouteGroup: "debug",
+		})
+		requests, _, writeErrors := s.split(context.TODO(), request, false)
+		Expect(requests).To(HaveLen(1))
+		Expect(writeErrors).To(HaveLen(2))
+		Expect(writeErrors[0].GetIndex()).To(BeNumerically("==", 1))
+		Expect(writeErrors[0].GetCode()).To(Equal(modelv1.WriteError_CODE_SCHEMA_NOT_FOUND))
+		Expect(writeErrors[0].GetMessage()).To(ContainSubstring("debug/sw"))
+	})
+	It("batches the elements forwarded to a node", func() {
+		s.router.Rev(bus.NewMessage(bus.MessageID(1), &databasev1.NodeEvent{
+			Node:   &databasev1.Node{Id: "remote", Addr: "remote:17912"},
+			Action: databasev1.Action_ACTION_PUT,
+		}))
+		requests, batches, _ := s.split(context.TODO(), request, false)
+		Expect(requests).To(BeEmpty())
+		Expect(batches).To(HaveLen(1))
+		Expect(batches[0].node.GetId()).To(Equal("remote"))
+		Expect(batches[0].request.GetMetadata()).To(Equal(md))
+		Expect(batches[0].request.GetElements()).To(HaveLen(2))
+	})
+	It("writes the forwarded elements locally", func() {
+		s.router.Rev(bus.NewMessage(bus.MessageID(1), &databasev1.NodeEvent{
+			Node:   &databasev1.Node{Id: "remote", Addr: "remote:17912"},
+			Action: databasev1.Action_ACTION_PUT,
+		}))
+		requests, batches, _ := s.split(context.TODO(), request, true)
+		Expect(batches).To(BeEmpty())
+		// the forwarded elements have been filtered by the liaison forwarding them
+		Expect(requests).To(HaveLen(3))
+	})
+	It("generates the uuids once for all the replicas", func() {
+		repo.idPolicy = &databasev1.ElementIDPolicy{Source: databasev1.ElementIDPolicy_SOURCE_UUID}
+		s.shardRepo.Rev(bus.NewMessage(bus.MessageID(1), &databasev1.ShardEvent{
+			Shard:  &databasev1.Shard{Total: 4, Replicas: 2, Metadata: &commonv1.Metadata{Name: "default"}},
+			Action: databasev1.Action_ACTION_PUT,
+		}))
+		for _, n := range []*databasev1.Node{{Id: "local", Addr: "local:17912"}, {Id: "remote", Addr: "remote:17912"}} {
+			s.router.Rev(bus.NewMessage(bus.MessageID(1), &databasev1.NodeEvent{Node: n, Action: databasev1.Action_ACTION_PUT}))
+		}
+		requests, batches, _ := s.split(context.TODO(), request, false)
+		Expect(requests).To(HaveLen(2))
+		Expect(batches).To(HaveLen(1))
+		Expect(batches[0].request.GetElements()).To(HaveLen(2))
+		for i, r := range requests {
+			id := r.GetRequest().GetElement().GetElementId()
+			// the ids provided by the client are overwritten by default
+			Expect(id).To(HaveLen(36))
+			Expect(batches[0].request.GetElements()[i].GetElementId()).To(Equal(id))
+		}
+
+		repo.idPolicy.Conflict = databasev1.ElementIDPolicy_CONFLICT_REJECT
+		s.validator.invalidate(schema.Metadata{TypeMeta: schema.TypeMeta{Kind: schema.KindStream, Group: md.GetGroup(), Name: md.GetName()}})
+		request.Element.ElementId = "0"
+		request.Elements[0].ElementId = "1"
+		requests, batches, writeErrors := s.split(context.TODO(), request, false)
+		Expect(requests).To(BeEmpty())
+		Expect(batches).To(BeEmpty())
+		Expect(writeErrors).To(HaveLen(3))
+		Expect(writeErrors[0].GetMessage()).To(ContainSubstring("the element id conflicts"))
+	})
+	It("skips the request without any element", func() {
+		requests, batches, _ := s.split(context.TODO(), &streamv1.WriteRequest{Metadata: md}, false)
+		Expect(requests).To(BeEmpty())
+		Expect(batches).To(BeEmpty())
+	})
+})
@@ -0,0 +1,210 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
+	"github.com/apache/skywalking-banyandb/pkg/expr"
+)
+
+// subscribeBufferSize is the number of the items buffered for a subscriber if the request doesn't set it.
+const subscribeBufferSize = 1024
+
+// subscriptionHub taps the writes published to the local pipeline, and sends the ones matching the filters
+// to the Subscribe streams of the stream and measure services.
+type subscriptionHub struct {
+	filter      *writeFilter
+	subscribers map[*subscriber]struct{}
+	closed      chan struct{}
+	// count is the number of the subscribers, which saves the writes from locking the hub if there's none
+	count atomic.Int32
+	mu    sync.RWMutex
+	once  sync.Once
+}
+
+type subscriber struct {
+	program *expr.Program
+	items   chan proto.Message
+	// overflowed is closed once the buffer of a subscriber dropping nothing is full
+	overflowed chan struct{}
+	id         identity
+	kind       schema.Kind
+	policy     modelv1.DropPolicy
+	dropped    atomic.Uint64
+	// evicting serializes the evictions of the oldest items
+	evicting sync.Mutex
+	once     sync.Once
+}
+
+// subscription is the request of a subscriber.
+type subscription struct {
+	metadata   *commonv1.Metadata
+	filter     string
+	bufferSize uint32
+	policy     modelv1.DropPolicy
+}
+
+func newSubscriptionHub(filter *writeFilter) *subscriptionHub {
+	return &subscriptionHub{
+		filter:      filter,
+		subscribers: make(map[*subscriber]struct{}),
+		closed:      make(chan struct{}),
+	}
+}
+
+// publish offers the item written to the resource to the subscribers whose filters match its tags.
+// The item is cloned since it's modified by the storage once it's published to the pipeline.
+func (h *subscriptionHub) publish(ctx context.Context, kind schema.Kind, md *commonv1.Metadata,
+	families []*modelv1.TagFamilyForWrite, item proto.Message,
+) {
+	if h.count.Load() < 1 {
+		return
+	}
+	id := getID(md)
+	var resolve expr.Resolver
+	var cloned proto.Message
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for s := range h.subscribers {
+		if s.kind != kind || s.id != id {
+			continue
+		}
+		if s.program != nil {
+			if resolve == nil {
+				tags, ok := h.filter.resource(ctx, kind, md)
+				if !ok {
+					return
+				}
+				resolve = tagResolver(tags.locators, families)
+			}
+			if matched, err := s.program.Eval(resolve); err != nil || !matched {
+				continue
+			}
+		}
+		if cloned == nil {
+			cloned = proto.Clone(item)
+		}
+		s.offer(cloned)
+	}
+}
+
+func (s *subscriber) offer(item proto.Message) {
+	select {
+	case s.items <- item:
+		return
+	default:
+	}
+	switch s.policy {
+	case modelv1.DropPolicy_DROP_POLICY_DISCONNECT:
+		s.once.Do(func() {
+			close(s.overflowed)
+		})
+	case modelv1.DropPolicy_DROP_POLICY_OLDEST:
+		s.evicting.Lock()
+		defer s.evicting.Unlock()
+		select {
+		case <-s.items:
+			s.dropped.Add(1)
+		default:
+		}
+		select {
+		case s.items <- item:
+		default:
+			s.dropped.Add(1)
+		}
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// subscribe sends the items written to the resource and matching the filter to the stream until it's closed.
+// send takes the number of the items dropped since the last item sent.
+func (h *subscriptionHub) subscribe(stream grpclib.ServerStream, kind schema.Kind, req subscription,
+	send func(item proto.Message, dropped uint64) error,
+) error {
+	ctx := stream.Context()
+	s := &subscriber{
+		kind:       kind,
+		id:         getID(req.metadata),
+		policy:     req.policy,
+		overflowed: make(chan struct{}),
+	}
+	tags, ok := h.filter.resource(ctx, kind, req.metadata)
+	if !ok {
+		return status.Errorf(codes.NotFound, "%s is not found", req.metadata)
+	}
+	if req.filter != "" {
+		program, err := h.filter.program(req.filter, tags)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "the filter %q is invalid: %v", req.filter, err)
+		}
+		s.program = program
+	}
+	bufferSize := req.bufferSize
+	if bufferSize == 0 {
+		bufferSize = subscribeBufferSize
+	}
+	s.items = make(chan proto.Message, bufferSize)
+	h.mu.Lock()
+	h.subscribers[s] = struct{}{}
+	h.count.Add(1)
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.subscribers, s)
+		h.count.Add(-1)
+		h.mu.Unlock()
+	}()
+	// the header tells the client that the items written after now are subscribed
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-h.closed:
+			return status.Error(codes.Unavailable, "the server is stopping")
+		case <-s.overflowed:
+			return status.Error(codes.ResourceExhausted, "the subscriber falls behind the writes")
+		case item := <-s.items:
+			if err := send(item, s.dropped.Swap(0)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// close ends all the Subscribe streams, which block the graceful stop of the server otherwise.
+func (h *subscriptionHub) close() {
+	h.once.Do(func() {
+		close(h.closed)
+	})
+}
@@ -0,0 +1,160 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+type fakeServerStream struct {
+	grpclib.ServerStream
+	ctx     context.Context
+	started chan struct{}
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeServerStream) SendHeader(metadata.MD) error {
+	close(s.started)
+	return nil
+}
+
+var _ = Describe("Subscription", func() {
+	md := &commonv1.Metadata{Group: "default", Name: "sw"}
+	element := func(id, endpoint string, status int64) *streamv1.ElementValue {
+		return &streamv1.ElementValue{ElementId: id, TagFamilies: []*modelv1.TagFamilyForWrite{{Tags: []*modelv1.TagValue{
+			{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: endpoint}}},
+			{Value: &modelv1.TagValue_Int{Int: &modelv1.Int{Value: status}}},
+		}}}}
+	}
+	var hub *subscriptionHub
+	var ctx context.Context
+	var cancel context.CancelFunc
+	BeforeEach(func() {
+		filter := newWriteFilter(&fakeRepo{})
+		filter.log = logger.GetLogger("test")
+		hub = newSubscriptionHub(filter)
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+	AfterEach(func() {
+		cancel()
+		hub.close()
+	})
+
+	type received struct {
+		id      string
+		dropped uint64
+	}
+	// start subscribes in the background, and returns the channel of the received items and the one of the result.
+	// The sender blocks after receiving an item until blocked is closed.
+	start := func(req subscription, blocked chan struct{}) (chan received, chan error) {
+		items := make(chan received, 16)
+		result := make(chan error, 1)
+		stream := &fakeServerStream{ctx: ctx, started: make(chan struct{})}
+		go func() {
+			result <- hub.subscribe(stream, schema.KindStream, req, func(item proto.Message, dropped uint64) error {
+				items <- received{id: item.(*streamv1.ElementValue).GetElementId(), dropped: dropped}
+				if blocked != nil {
+					<-blocked
+				}
+				return nil
+			})
+		}()
+		Eventually(stream.started).Should(BeClosed())
+		return items, result
+	}
+	publish := func(e *streamv1.ElementValue) {
+		hub.publish(ctx, schema.KindStream, md, e.GetTagFamilies(), e)
+	}
+
+	It("sends the elements matching the filter", func() {
+		items, _ := start(subscription{metadata: md, filter: `status >= 500`}, nil)
+		all, _ := start(subscription{metadata: md}, nil)
+		e := element("1", "/home", 200)
+		publish(e)
+		publish(element("2", "/home", 503))
+		hub.publish(ctx, schema.KindStream, &commonv1.Metadata{Group: "default", Name: "other"}, e.GetTagFamilies(), e)
+		Eventually(items).Should(Receive(Equal(received{id: "2"})))
+		Consistently(items).ShouldNot(Receive())
+		Eventually(all).Should(Receive(Equal(received{id: "1"})))
+		Eventually(all).Should(Receive(Equal(received{id: "2"})))
+		Consistently(all).ShouldNot(Receive())
+	})
+
+	It("rejects the invalid filters", func() {
+		err := hub.subscribe(&fakeServerStream{ctx: ctx, started: make(chan struct{})}, schema.KindStream,
+			subscription{metadata: md, filter: `status >=`}, nil)
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("drops the newest elements once the buffer is full", func() {
+		blocked := make(chan struct{})
+		items, _ := start(subscription{metadata: md, bufferSize: 1, policy: modelv1.DropPolicy_DROP_POLICY_NEWEST}, blocked)
+		publish(element("1", "/home", 200))
+		// the first element is taken by the blocked sender
+		Eventually(items).Should(Receive(Equal(received{id: "1"})))
+		publish(element("2", "/home", 200))
+		publish(element("3", "/home", 200))
+		close(blocked)
+		Eventually(items).Should(Receive(Equal(received{id: "2", dropped: 1})))
+	})
+
+	It("evicts the oldest elements once the buffer is full", func() {
+		blocked := make(chan struct{})
+		items, _ := start(subscription{metadata: md, bufferSize: 1, policy: modelv1.DropPolicy_DROP_POLICY_OLDEST}, blocked)
+		publish(element("1", "/home", 200))
+		Eventually(items).Should(Receive(Equal(received{id: "1"})))
+		publish(element("2", "/home", 200))
+		publish(element("3", "/home", 200))
+		close(blocked)
+		Eventually(items).Should(Receive(Equal(received{id: "3", dropped: 1})))
+	})
+
+	It("disconnects the subscriber falling behind", func() {
+		blocked := make(chan struct{})
+		items, result := start(subscription{metadata: md, bufferSize: 1, policy: modelv1.DropPolicy_DROP_POLICY_DISCONNECT}, blocked)
+		publish(element("1", "/home", 200))
+		Eventually(items).Should(Receive())
+		publish(element("2", "/home", 200))
+		publish(element("3", "/home", 200))
+		close(blocked)
+		Eventually(result).Should(Receive(WithTransform(status.Code, Equal(codes.ResourceExhausted))))
+	})
+
+	It("ends the subscriptions once the hub is closed", func() {
+		_, result := start(subscription{metadata: md}, nil)
+		hub.close()
+		Eventually(result).Should(Receive(WithTransform(status.Code, Equal(codes.Unavailable))))
+	})
+})
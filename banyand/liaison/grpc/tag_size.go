@@ -0,0 +1,274 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/protobuf/proto"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+)
+
+const (
+	// tagSizePolicyTruncate truncates the oversized values, and records the names of their tags in the marker tag.
+	tagSizePolicyTruncate = "truncate"
+	// tagSizePolicyReject rejects the elements and the data points with the oversized values.
+	tagSizePolicyReject = "reject"
+	// tagSizePolicyExternal stores the oversized values in the external directory, and replaces them with their references.
+	tagSizePolicyExternal = "external"
+
+	// externalRefPrefix prefixes the reference to an oversized value stored externally, which is followed by its SHA-256 in hex
+	externalRefPrefix = "external:sha256:"
+)
+
+var oversizedTags = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "banyand_liaison_oversized_tags_total",
+		Help: "The number of the oversized tag values written, by the policy applied to them",
+	},
+	[]string{"policy"},
+)
+
+// tagSizeGuard bounds the sizes of the tag values by their types in the write path, so that a misbehaving agent
+// can't blow up the blocks and the indices by huge values. The integers aren't bounded, and a max size of 0 means unlimited.
+type tagSizeGuard struct {
+	policy       string
+	marker       string
+	externalPath string
+	// maxString bounds a string in bytes
+	maxString int
+	// maxBinary bounds a binary value in bytes
+	maxBinary int
+	// maxArray bounds the total bytes of the strings of a string array, or 8 bytes per item of an integer array
+	maxArray int
+}
+
+func (g *tagSizeGuard) validate() error {
+	if g.maxString < 0 || g.maxBinary < 0 || g.maxArray < 0 {
+		return errNegativeTagSize
+	}
+	switch g.policy {
+	case tagSizePolicyTruncate, tagSizePolicyReject:
+	case tagSizePolicyExternal:
+		if g.externalPath == "" {
+			return errNoExternalTagPath
+		}
+		return os.MkdirAll(g.externalPath, 0o755)
+	default:
+		return errInvalidTagSizePolicy
+	}
+	return nil
+}
+
+func (g *tagSizeGuard) enabled() bool {
+	return g.maxString > 0 || g.maxBinary > 0 || g.maxArray > 0
+}
+
+// enforce applies the policy to the oversized values of the tag families in place, and returns the families
+// which are extended to hold the marker tag. The tags of the entity are never altered, whose oversized values are rejected
+// since they identify the series. The specs are nil if the schema fails to be loaded, then the marker tag is skipped.
+func (g *tagSizeGuard) enforce(specs []*databasev1.TagFamilySpec, entity *databasev1.Entity,
+	families []*modelv1.TagFamilyForWrite,
+) ([]*modelv1.TagFamilyForWrite, *modelv1.WriteError) {
+	if !g.enabled() {
+		return families, nil
+	}
+	entityTags := make(map[string]struct{}, len(entity.GetTagNames()))
+	for _, name := range entity.GetTagNames() {
+		entityTags[name] = struct{}{}
+	}
+	var truncated []string
+	for fi, family := range families {
+		for ti, tag := range family.GetTags() {
+			size, limit := g.measure(tag)
+			if limit < 1 || size <= limit {
+				continue
+			}
+			name := fmt.Sprintf("%d.%d", fi, ti)
+			if fi < len(specs) && ti < len(specs[fi].GetTags()) {
+				name = specs[fi].GetTags()[ti].GetName()
+			}
+			_, isEntity := entityTags[name]
+			policy := g.policy
+			if isEntity || (policy == tagSizePolicyExternal && tag.GetIntArray() != nil) {
+				// the integer arrays can't hold the references
+				policy = tagSizePolicyReject
+			}
+			oversizedTags.WithLabelValues(policy).Inc()
+			switch policy {
+			case tagSizePolicyTruncate:
+				family.Tags[ti] = truncateTag(tag, limit)
+				truncated = append(truncated, name)
+			case tagSizePolicyExternal:
+				ref, err := g.store(tag)
+				if err != nil {
+					return families, &modelv1.WriteError{
+						Code:    modelv1.WriteError_CODE_TAG_TOO_LARGE,
+						Message: fmt.Sprintf("failed to store the value of the tag %s of %d bytes: %v", name, size, err),
+					}
+				}
+				family.Tags[ti] = refTag(tag, ref)
+			default:
+				return families, &modelv1.WriteError{
+					Code:    modelv1.WriteError_CODE_TAG_TOO_LARGE,
+					Message: fmt.Sprintf("the value of the tag %s is %d bytes, larger than %d", name, size, limit),
+				}
+			}
+		}
+	}
+	if len(truncated) > 0 {
+		families = g.mark(specs, families, truncated)
+	}
+	return families, nil
+}
+
+// measure returns the size of the tag value and the limit of its type.
+func (g *tagSizeGuard) measure(tag *modelv1.TagValue) (size int, limit int) {
+	switch v := tag.GetValue().(type) {
+	case *modelv1.TagValue_Str:
+		return len(v.Str.GetValue()), g.maxString
+	case *modelv1.TagValue_BinaryData:
+		return len(v.BinaryData), g.maxBinary
+	case *modelv1.TagValue_StrArray:
+		for _, s := range v.StrArray.GetValue() {
+			size += len(s)
+		}
+		return size, g.maxArray
+	case *modelv1.TagValue_IntArray:
+		return 8 * len(v.IntArray.GetValue()), g.maxArray
+	}
+	return 0, 0
+}
+
+// truncateTag returns the prefix of the value fitting the limit. The strings are cut at the boundaries of the runes,
+// and the arrays keep their leading items.
+func truncateTag(tag *modelv1.TagValue, limit int) *modelv1.TagValue {
+	cut := func(s string, n int) string {
+		for n > 0 && n < len(s) && !utf8.RuneStart(s[n]) {
+			n--
+		}
+		return s[:n]
+	}
+	switch v := tag.GetValue().(type) {
+	case *modelv1.TagValue_Str:
+		return &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: cut(v.Str.GetValue(), limit)}}}
+	case *modelv1.TagValue_BinaryData:
+		return &modelv1.TagValue{Value: &modelv1.TagValue_BinaryData{BinaryData: v.BinaryData[:limit]}}
+	case *modelv1.TagValue_StrArray:
+		var items []string
+		for _, s := range v.StrArray.GetValue() {
+			if len(s) > limit {
+				if s = cut(s, limit); s != "" {
+					items = append(items, s)
+				}
+				break
+			}
+			items = append(items, s)
+			limit -= len(s)
+		}
+		return &modelv1.TagValue{Value: &modelv1.TagValue_StrArray{StrArray: &modelv1.StrArray{Value: items}}}
+	case *modelv1.TagValue_IntArray:
+		return &modelv1.TagValue{Value: &modelv1.TagValue_IntArray{IntArray: &modelv1.IntArray{Value: v.IntArray.GetValue()[:limit/8]}}}
+	}
+	return tag
+}
+
+// refTag returns the tag value replaced by the reference to its stored value.
+func refTag(tag *modelv1.TagValue, ref string) *modelv1.TagValue {
+	switch tag.GetValue().(type) {
+	case *modelv1.TagValue_BinaryData:
+		return &modelv1.TagValue{Value: &modelv1.TagValue_BinaryData{BinaryData: []byte(ref)}}
+	case *modelv1.TagValue_StrArray:
+		return &modelv1.TagValue{Value: &modelv1.TagValue_StrArray{StrArray: &modelv1.StrArray{Value: []string{ref}}}}
+	}
+	return &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: ref}}}
+}
+
+// store writes the TagValue encoded by protobuf to the external directory, which is addressed by its SHA-256,
+// and returns the reference to it.
+func (g *tagSizeGuard) store(tag *modelv1.TagValue) (string, error) {
+	bb, err := proto.Marshal(tag)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(bb)
+	digest := hex.EncodeToString(sum[:])
+	dir := filepath.Join(g.externalPath, digest[:2])
+	p := filepath.Join(dir, digest)
+	if _, err = os.Stat(p); err == nil {
+		return externalRefPrefix + digest, nil
+	}
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	// the value is renamed into place, so that the readers never see a partial one
+	tmp, err := os.CreateTemp(dir, digest+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(bb)
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return "", err
+	}
+	if err = os.Rename(tmp.Name(), p); err != nil {
+		return "", err
+	}
+	return externalRefPrefix + digest, nil
+}
+
+// mark sets the names of the truncated tags to the marker tag if the schema defines it as a string array.
+func (g *tagSizeGuard) mark(specs []*databasev1.TagFamilySpec, families []*modelv1.TagFamilyForWrite, truncated []string) []*modelv1.TagFamilyForWrite {
+	if g.marker == "" {
+		return families
+	}
+	var fi, ti int
+	var spec *databasev1.TagSpec
+	for i, family := range specs {
+		for j, tag := range family.GetTags() {
+			if tag.GetName() == g.marker {
+				fi, ti, spec = i, j, tag
+			}
+		}
+	}
+	if spec == nil || spec.GetType() != databasev1.TagType_TAG_TYPE_STRING_ARRAY {
+		return families
+	}
+	for len(families) <= fi {
+		families = append(families, &modelv1.TagFamilyForWrite{})
+	}
+	family := families[fi]
+	for len(family.Tags) <= ti {
+		family.Tags = append(family.Tags, pbv1.NullTag)
+	}
+	family.Tags[ti] = &modelv1.TagValue{Value: &modelv1.TagValue_StrArray{StrArray: &modelv1.StrArray{Value: truncated}}}
+	return families
+}
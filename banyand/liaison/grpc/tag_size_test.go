@@ -0,0 +1,124 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/protobuf/proto"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+)
+
+var _ = Describe("TagSizeGuard", func() {
+	specs := []*databasev1.TagFamilySpec{
+		{Name: "default", Tags: []*databasev1.TagSpec{
+			{Name: "service", Type: databasev1.TagType_TAG_TYPE_STRING},
+			{Name: "stack", Type: databasev1.TagType_TAG_TYPE_STRING},
+			{Name: "labels", Type: databasev1.TagType_TAG_TYPE_STRING_ARRAY},
+			{Name: "ids", Type: databasev1.TagType_TAG_TYPE_INT_ARRAY},
+		}},
+		{Name: "meta", Tags: []*databasev1.TagSpec{
+			{Name: "data", Type: databasev1.TagType_TAG_TYPE_DATA_BINARY},
+			{Name: "truncated_tags", Type: databasev1.TagType_TAG_TYPE_STRING_ARRAY},
+		}},
+	}
+	entity := &databasev1.Entity{TagNames: []string{"service"}}
+	str := func(s string) *modelv1.TagValue {
+		return &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: s}}}
+	}
+	strArray := func(ss ...string) *modelv1.TagValue {
+		return &modelv1.TagValue{Value: &modelv1.TagValue_StrArray{StrArray: &modelv1.StrArray{Value: ss}}}
+	}
+	write := func(tags ...*modelv1.TagValue) []*modelv1.TagFamilyForWrite {
+		return []*modelv1.TagFamilyForWrite{{Tags: tags}}
+	}
+
+	It("leaves the values alone without the limits", func() {
+		g := &tagSizeGuard{policy: tagSizePolicyReject}
+		families := write(str("svc"), str(strings.Repeat("x", 1<<20)))
+		result, wErr := g.enforce(specs, entity, families)
+		Expect(wErr).To(BeNil())
+		Expect(result).To(Equal(families))
+	})
+
+	It("rejects the oversized values", func() {
+		g := &tagSizeGuard{policy: tagSizePolicyReject, maxString: 4}
+		_, wErr := g.enforce(specs, entity, write(str("svc"), str("stack trace")))
+		Expect(wErr.GetCode()).To(Equal(modelv1.WriteError_CODE_TAG_TOO_LARGE))
+		Expect(wErr.GetMessage()).To(ContainSubstring("stack"))
+	})
+
+	It("truncates the oversized values and marks them", func() {
+		g := &tagSizeGuard{policy: tagSizePolicyTruncate, marker: "truncated_tags", maxString: 4, maxArray: 16}
+		families, wErr := g.enforce(specs, entity, write(
+			str("svc"),
+			str("日本語"),
+			strArray("a", "bcd", "efgh"),
+			&modelv1.TagValue{Value: &modelv1.TagValue_IntArray{IntArray: &modelv1.IntArray{Value: []int64{1, 2, 3}}}},
+		))
+		Expect(wErr).To(BeNil())
+		tags := families[0].GetTags()
+		// a rune isn't split
+		Expect(tags[1].GetStr().GetValue()).To(Equal("日"))
+		Expect(tags[2].GetStrArray().GetValue()).To(Equal([]string{"a", "bcd", "efgh"}))
+		Expect(tags[3].GetIntArray().GetValue()).To(Equal([]int64{1, 2}))
+		Expect(families).To(HaveLen(2))
+		Expect(families[1].GetTags()).To(HaveLen(2))
+		Expect(families[1].GetTags()[0]).To(Equal(pbv1.NullTag))
+		Expect(families[1].GetTags()[1].GetStrArray().GetValue()).To(Equal([]string{"stack", "ids"}))
+	})
+
+	It("rejects the oversized tags of the entity", func() {
+		g := &tagSizeGuard{policy: tagSizePolicyTruncate, maxString: 4}
+		_, wErr := g.enforce(specs, entity, write(str("a long service name")))
+		Expect(wErr.GetCode()).To(Equal(modelv1.WriteError_CODE_TAG_TOO_LARGE))
+	})
+
+	It("stores the oversized values externally", func() {
+		dir, deferFn, err := test.NewSpace()
+		Expect(err).NotTo(HaveOccurred())
+		defer deferFn()
+		g := &tagSizeGuard{policy: tagSizePolicyExternal, externalPath: dir, maxBinary: 4}
+		Expect(g.validate()).To(Succeed())
+		data := &modelv1.TagValue{Value: &modelv1.TagValue_BinaryData{BinaryData: []byte("a large payload")}}
+		families, wErr := g.enforce(specs, entity, []*modelv1.TagFamilyForWrite{{}, {Tags: []*modelv1.TagValue{data}}})
+		Expect(wErr).To(BeNil())
+		ref := string(families[1].GetTags()[0].GetBinaryData())
+		Expect(ref).To(HavePrefix(externalRefPrefix))
+		digest := strings.TrimPrefix(ref, externalRefPrefix)
+		bb, err := os.ReadFile(filepath.Join(dir, digest[:2], digest))
+		Expect(err).NotTo(HaveOccurred())
+		stored := &modelv1.TagValue{}
+		Expect(proto.Unmarshal(bb, stored)).To(Succeed())
+		Expect(stored.GetBinaryData()).To(Equal([]byte("a large payload")))
+	})
+
+	It("validates the policy", func() {
+		Expect((&tagSizeGuard{policy: "drop"}).validate()).To(MatchError(errInvalidTagSizePolicy))
+		Expect((&tagSizeGuard{policy: tagSizePolicyReject, maxString: -1}).validate()).To(MatchError(errNegativeTagSize))
+		Expect((&tagSizeGuard{policy: tagSizePolicyExternal}).validate()).To(MatchError(errNoExternalTagPath))
+	})
+})
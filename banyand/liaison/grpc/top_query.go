@@ -0,0 +1,49 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/apache/skywalking-banyandb/api/data"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/banyand/queue"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+)
+
+type topQueryServer struct {
+	databasev1.UnimplementedTopQueryServiceServer
+	pipeline queue.Queue
+}
+
+func (s *topQueryServer) List(_ context.Context, req *databasev1.TopQueryServiceListRequest) (*databasev1.TopQueryServiceListResponse, error) {
+	message := bus.NewMessage(bus.MessageID(time.Now().UnixNano()), req)
+	feat, err := s.pipeline.Publish(data.TopicTopQueryList, message)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := feat.Get()
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := msg.Data().(*databasev1.TopQueryServiceListResponse); ok {
+		return d, nil
+	}
+	return nil, ErrQueryMsg
+}
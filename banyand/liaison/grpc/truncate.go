@@ -0,0 +1,163 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"hash/crc32"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/query/executor"
+)
+
+// The fields shared by the query requests of streams and measures, which locate a page of the results.
+// They are left out of the fingerprint of a query, so that all its pages share the continuation tokens.
+var pageFields = []protoreflect.Name{"offset", "limit", "read_timestamp", "limits", "continuation_token", "snapshot", "snapshot_token"}
+
+// continuation is the position of the next results of a truncated query, which is encoded as its continuation token.
+type continuation struct {
+	// ReadTimestamp is the snapshot of the first page in nanoseconds
+	ReadTimestamp int64 `json:"read_timestamp"`
+	// Fingerprint tells the query the token belongs to
+	Fingerprint uint32 `json:"fingerprint"`
+	// Skip is the number of the results returned by the previous pages
+	Skip uint32 `json:"skip"`
+	// Snapshot is the token of the snapshot the pages are cut from
+	Snapshot string `json:"snapshot,omitempty"`
+}
+
+// queryPage is a page of the results of a query, which is continued by the token of the previous page if there is one.
+type queryPage struct {
+	readTimestamp *timestamppb.Timestamp
+	fingerprint   uint32
+	skip          uint32
+	// snapshotToken is the token of the snapshot the page is cut from if there is one
+	snapshotToken string
+	// exhausted is true if the previous pages returned all the results within the limit of the query
+	exhausted bool
+}
+
+// resumeQuery moves the query to the page continued by its continuation token.
+// The offset skips the results returned by the previous pages, the limit is reduced by them,
+// and the read timestamp and the snapshot token pin the snapshot of the first page.
+// A query truncating its results without a read timestamp is pinned to now.
+func resumeQuery(req proto.Message, limits *modelv1.QueryLimits) (*queryPage, error) {
+	m := req.ProtoReflect()
+	fields := m.Descriptor().Fields()
+	page := &queryPage{fingerprint: queryFingerprint(req)}
+	readTimestamp, offset, limit := fields.ByName("read_timestamp"), fields.ByName("offset"), fields.ByName("limit")
+	token := m.Get(fields.ByName("continuation_token")).String()
+	if token == "" {
+		if limits.GetTruncate() && !m.Has(readTimestamp) {
+			m.Set(readTimestamp, protoreflect.ValueOfMessage(timestamppb.Now().ProtoReflect()))
+		}
+		return page, nil
+	}
+	bb, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "the continuation token is invalid: %v", err)
+	}
+	var c continuation
+	if err = json.Unmarshal(bb, &c); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "the continuation token is invalid: %v", err)
+	}
+	if c.Fingerprint != page.fingerprint {
+		return nil, status.Error(codes.InvalidArgument, "the continuation token belongs to another query")
+	}
+	page.skip = c.Skip
+	if c.Snapshot != "" {
+		m.Set(fields.ByName("snapshot_token"), protoreflect.ValueOfString(c.Snapshot))
+	}
+	m.Set(readTimestamp, protoreflect.ValueOfMessage(timestamppb.New(time.Unix(0, c.ReadTimestamp)).ProtoReflect()))
+	m.Set(offset, protoreflect.ValueOfUint32(addLimit(uint32(m.Get(offset).Uint()), c.Skip)))
+	l := limitOf(uint32(m.Get(limit).Uint()))
+	if c.Skip >= l {
+		page.exhausted = true
+		return page, nil
+	}
+	m.Set(limit, protoreflect.ValueOfUint32(l-c.Skip))
+	return page, nil
+}
+
+// continueAfter sets the continuation token of the truncation, which continues after the n results of the page.
+func (p *queryPage) continueAfter(truncation *modelv1.Truncation, n int, readTimestamp *timestamppb.Timestamp) {
+	if truncation == nil {
+		return
+	}
+	bb, err := json.Marshal(continuation{
+		ReadTimestamp: readTimestamp.AsTime().UnixNano(),
+		Fingerprint:   p.fingerprint,
+		Skip:          addLimit(p.skip, uint32(n)),
+		Snapshot:      p.snapshotToken,
+	})
+	if err != nil {
+		return
+	}
+	truncation.ContinuationToken = base64.RawURLEncoding.EncodeToString(bb)
+}
+
+// queryFingerprint returns the checksum of the query without the fields locating a page.
+func queryFingerprint(req proto.Message) uint32 {
+	m := proto.Clone(req).ProtoReflect()
+	for _, name := range pageFields {
+		if fd := m.Descriptor().Fields().ByName(name); fd != nil {
+			m.Clear(fd)
+		}
+	}
+	bb, _ := proto.MarshalOptions{Deterministic: true}.Marshal(m.Interface())
+	return crc32.ChecksumIEEE(bb)
+}
+
+// truncateItems returns the items within the response limits if the query truncates its results, and the truncation if any is cut off.
+// The first item is always returned, so that the next page makes progress.
+func truncateItems[T proto.Message](limits *modelv1.QueryLimits, items []T) ([]T, *modelv1.Truncation) {
+	if !limits.GetTruncate() {
+		return items, nil
+	}
+	maxItems, maxBytes := limits.GetMaxResponseItems(), limits.GetMaxResponseBytes()
+	var size int64
+	for i, item := range items {
+		if maxItems > 0 && int64(i) >= maxItems {
+			return items[:i], &modelv1.Truncation{Resource: executor.ResourceMaxResponseItems, Limit: maxItems}
+		}
+		size += int64(proto.Size(item))
+		if maxBytes > 0 && size > maxBytes && i > 0 {
+			return items[:i], &modelv1.Truncation{Resource: executor.ResourceMaxResponseBytes, Limit: maxBytes}
+		}
+	}
+	return items, nil
+}
+
+// subLimits returns the limits of the sub-queries sent to the data nodes.
+// The results of a query truncating them are capped once the partial results are merged.
+func subLimits(limits *modelv1.QueryLimits) *modelv1.QueryLimits {
+	if !limits.GetTruncate() {
+		return limits
+	}
+	sub := proto.Clone(limits).(*modelv1.QueryLimits)
+	sub.MaxResponseBytes, sub.MaxResponseItems, sub.Truncate = 0, 0, false
+	return sub
+}
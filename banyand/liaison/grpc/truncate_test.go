@@ -0,0 +1,133 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/query/executor"
+)
+
+var _ = Describe("Truncation", func() {
+	elements := func(ids ...string) []*streamv1.Element {
+		result := make([]*streamv1.Element, 0, len(ids))
+		for _, id := range ids {
+			result = append(result, &streamv1.Element{ElementId: id})
+		}
+		return result
+	}
+	ids := func(elements []*streamv1.Element) []string {
+		result := make([]string, 0, len(elements))
+		for _, e := range elements {
+			result = append(result, e.GetElementId())
+		}
+		return result
+	}
+	query := func() *streamv1.QueryRequest {
+		return &streamv1.QueryRequest{
+			Metadata: &commonv1.Metadata{Group: "default", Name: "sw"},
+			Offset:   1,
+			Limit:    5,
+			Limits:   &modelv1.QueryLimits{MaxResponseItems: 2, Truncate: true},
+		}
+	}
+
+	It("truncates the items by the response limits", func() {
+		limits := &modelv1.QueryLimits{MaxResponseItems: 2, Truncate: true}
+		items, truncation := truncateItems(limits, elements("1", "2", "3"))
+		Expect(ids(items)).To(Equal([]string{"1", "2"}))
+		Expect(truncation).To(Equal(&modelv1.Truncation{Resource: executor.ResourceMaxResponseItems, Limit: 2}))
+
+		size := int64(proto.Size(elements("1")[0]))
+		limits = &modelv1.QueryLimits{MaxResponseBytes: size, Truncate: true}
+		items, truncation = truncateItems(limits, elements("1", "2"))
+		Expect(ids(items)).To(Equal([]string{"1"}))
+		Expect(truncation.GetResource()).To(Equal(executor.ResourceMaxResponseBytes))
+		// the first item is returned even if it's too large
+		limits.MaxResponseBytes = 1
+		items, _ = truncateItems(limits, elements("1", "2"))
+		Expect(ids(items)).To(Equal([]string{"1"}))
+
+		items, truncation = truncateItems(&modelv1.QueryLimits{MaxResponseItems: 1}, elements("1", "2"))
+		Expect(items).To(HaveLen(2))
+		Expect(truncation).To(BeNil())
+	})
+
+	It("continues a truncated query", func() {
+		req := query()
+		page, err := resumeQuery(req, req.GetLimits())
+		Expect(err).ShouldNot(HaveOccurred())
+		// the first page is pinned to a snapshot
+		Expect(req.GetReadTimestamp()).NotTo(BeNil())
+		truncation := &modelv1.Truncation{Resource: executor.ResourceMaxResponseItems, Limit: 2}
+		page.continueAfter(truncation, 2, req.GetReadTimestamp())
+		Expect(truncation.GetContinuationToken()).NotTo(BeEmpty())
+
+		next := query()
+		next.ContinuationToken = truncation.GetContinuationToken()
+		page, err = resumeQuery(next, next.GetLimits())
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(page.exhausted).To(BeFalse())
+		Expect(next.GetOffset()).To(Equal(uint32(3)))
+		Expect(next.GetLimit()).To(Equal(uint32(3)))
+		Expect(next.GetReadTimestamp().AsTime()).To(Equal(req.GetReadTimestamp().AsTime()))
+
+		page.continueAfter(truncation, 3, next.GetReadTimestamp())
+		last := query()
+		last.ContinuationToken = truncation.GetContinuationToken()
+		page, err = resumeQuery(last, last.GetLimits())
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(page.exhausted).To(BeTrue())
+	})
+
+	It("rejects the tokens of the other queries", func() {
+		req := query()
+		req.ReadTimestamp = timestamppb.New(time.Unix(1, 0))
+		page, err := resumeQuery(req, req.GetLimits())
+		Expect(err).ShouldNot(HaveOccurred())
+		truncation := &modelv1.Truncation{}
+		page.continueAfter(truncation, 2, req.GetReadTimestamp())
+
+		other := query()
+		other.Metadata.Name = "other"
+		other.ContinuationToken = truncation.GetContinuationToken()
+		_, err = resumeQuery(other, other.GetLimits())
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		other = query()
+		other.ContinuationToken = "invalid"
+		_, err = resumeQuery(other, other.GetLimits())
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("lets the merged results of the sub-queries be truncated", func() {
+		limits := &modelv1.QueryLimits{MaxResponseBytes: 10, MaxResponseItems: 2, MaxScannedBlocks: 3, Truncate: true}
+		Expect(proto.Equal(subLimits(limits), &modelv1.QueryLimits{MaxScannedBlocks: 3})).To(BeTrue())
+		limits.Truncate = false
+		Expect(subLimits(limits)).To(Equal(limits))
+	})
+})
@@ -0,0 +1,214 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/api/data"
+	clusterv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/cluster/v1"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/banyand/metadata"
+	"github.com/apache/skywalking-banyandb/banyand/queue"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+// usageServer collects the storage usage of the local shards and the ones of the other data nodes.
+type usageServer struct {
+	databasev1.UnimplementedUsageServiceServer
+	schemaRegistry metadata.Service
+	pipeline       queue.Queue
+	router         *nodeRouter
+	log            *logger.Logger
+}
+
+func (s *usageServer) Get(ctx context.Context, req *databasev1.UsageServiceGetRequest) (*databasev1.UsageServiceGetResponse, error) {
+	localID := s.router.localID
+	groups, err := s.local(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	remotes := s.router.remotes()
+	partials := make([]*databasev1.UsageServiceGetResponse, len(remotes))
+	errs := make([]error, len(remotes))
+	var wg sync.WaitGroup
+	for i, node := range remotes {
+		wg.Add(1)
+		go func(i int, node *databasev1.Node) {
+			defer wg.Done()
+			if !supports(node, featureUsage) {
+				errs[i] = errors.Errorf("the node doesn't support the %s", featureUsage)
+				return
+			}
+			conn, err := s.router.conn(node)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			partials[i], errs[i] = clusterv1.NewInternalQueryServiceClient(conn).Usage(withAPIVersion(ctx), req)
+		}(i, node)
+	}
+	wg.Wait()
+	nodes := map[string]*databasev1.UsageServiceGetResponse_Node{localID: {Id: localID}}
+	for i, node := range remotes {
+		if errs[i] != nil {
+			// the usage of the other nodes is still reported
+			s.log.Warn().Err(errs[i]).Str("node", node.GetId()).Msg("fail to get the usage of the node")
+			nodes[node.GetId()] = &databasev1.UsageServiceGetResponse_Node{Id: node.GetId(), Error: errs[i].Error()}
+			continue
+		}
+		nodes[node.GetId()] = &databasev1.UsageServiceGetResponse_Node{Id: node.GetId()}
+		groups = append(groups, partials[i].GetGroups()...)
+	}
+	return summarize(groups, nodes), nil
+}
+
+// localUsage returns the usage of the local shards, which answers the liaison collecting the usage of the data nodes.
+func (s *usageServer) localUsage(ctx context.Context, req *databasev1.UsageServiceGetRequest) (*databasev1.UsageServiceGetResponse, error) {
+	groups, err := s.local(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	localID := s.router.localID
+	return summarize(groups, map[string]*databasev1.UsageServiceGetResponse_Node{localID: {Id: localID}}), nil
+}
+
+// local returns the usage of the local shards of the requested group, or the ones of all the groups of streams and measures.
+// The shards are marked by the id of the local node.
+func (s *usageServer) local(ctx context.Context, req *databasev1.UsageServiceGetRequest) ([]*databasev1.UsageServiceGetResponse_Group, error) {
+	var groups []*commonv1.Group
+	if req.GetGroup() != "" {
+		g, err := s.schemaRegistry.GroupRegistry().GetGroup(ctx, req.GetGroup())
+		if err != nil {
+			return nil, err
+		}
+		if g.GetCatalog() != commonv1.Catalog_CATALOG_STREAM && g.GetCatalog() != commonv1.Catalog_CATALOG_MEASURE {
+			return nil, status.Errorf(codes.InvalidArgument, "the group %s of the catalog %s has no data", req.GetGroup(), g.GetCatalog())
+		}
+		groups = append(groups, g)
+	} else {
+		all, err := s.schemaRegistry.GroupRegistry().ListGroup(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range all {
+			if g.GetCatalog() == commonv1.Catalog_CATALOG_STREAM || g.GetCatalog() == commonv1.Catalog_CATALOG_MEASURE {
+				groups = append(groups, g)
+			}
+		}
+	}
+	result := make([]*databasev1.UsageServiceGetResponse_Group, 0, len(groups))
+	for _, g := range groups {
+		u, err := s.group(g)
+		if err != nil {
+			return nil, err
+		}
+		for _, shard := range u.GetShards() {
+			shard.Node = s.router.localID
+		}
+		result = append(result, u)
+	}
+	return result, nil
+}
+
+func (s *usageServer) group(g *commonv1.Group) (*databasev1.UsageServiceGetResponse_Group, error) {
+	topic := data.TopicStreamUsage
+	if g.GetCatalog() == commonv1.Catalog_CATALOG_MEASURE {
+		topic = data.TopicMeasureUsage
+	}
+	feat, err := s.pipeline.Publish(topic, bus.NewMessage(bus.MessageID(time.Now().UnixNano()),
+		&databasev1.UsageServiceGetRequest{Group: g.GetMetadata().GetName()}))
+	if err != nil {
+		return nil, err
+	}
+	msg, err := feat.Get()
+	if err != nil {
+		return nil, err
+	}
+	switch d := msg.Data().(type) {
+	case *databasev1.UsageServiceGetResponse_Group:
+		return d, nil
+	case common.Error:
+		return nil, errors.WithMessage(ErrUsageMsg, d.Msg())
+	}
+	return nil, ErrUsageMsg
+}
+
+// summarize merges the groups reported by the nodes, and sums up the usage of the groups, the nodes and the total from their shards.
+// The groups are sorted by their names, the shards by their ids and nodes, and the nodes by their ids.
+func summarize(groups []*databasev1.UsageServiceGetResponse_Group,
+	nodes map[string]*databasev1.UsageServiceGetResponse_Node,
+) *databasev1.UsageServiceGetResponse {
+	resp := &databasev1.UsageServiceGetResponse{Total: &databasev1.Usage{}}
+	merged := make(map[string]*databasev1.UsageServiceGetResponse_Group, len(groups))
+	for _, g := range groups {
+		m, ok := merged[g.GetName()]
+		if !ok {
+			m = &databasev1.UsageServiceGetResponse_Group{Name: g.GetName(), Catalog: g.GetCatalog(), Usage: &databasev1.Usage{}}
+			merged[g.GetName()] = m
+			resp.Groups = append(resp.Groups, m)
+		}
+		m.Shards = append(m.Shards, g.GetShards()...)
+	}
+	for _, n := range nodes {
+		if n.GetError() == "" {
+			n.Usage = &databasev1.Usage{}
+		}
+	}
+	for _, g := range resp.Groups {
+		sort.Slice(g.Shards, func(i, j int) bool {
+			if g.Shards[i].GetId() != g.Shards[j].GetId() {
+				return g.Shards[i].GetId() < g.Shards[j].GetId()
+			}
+			return g.Shards[i].GetNode() < g.Shards[j].GetNode()
+		})
+		for _, shard := range g.GetShards() {
+			addUsage(g.Usage, shard.GetUsage())
+			n, ok := nodes[shard.GetNode()]
+			if !ok {
+				n = &databasev1.UsageServiceGetResponse_Node{Id: shard.GetNode(), Usage: &databasev1.Usage{}}
+				nodes[shard.GetNode()] = n
+			}
+			addUsage(n.Usage, shard.GetUsage())
+		}
+		addUsage(resp.Total, g.Usage)
+	}
+	sort.Slice(resp.Groups, func(i, j int) bool { return resp.Groups[i].GetName() < resp.Groups[j].GetName() })
+	for _, n := range nodes {
+		resp.Nodes = append(resp.Nodes, n)
+	}
+	sort.Slice(resp.Nodes, func(i, j int) bool { return resp.Nodes[i].GetId() < resp.Nodes[j].GetId() })
+	return resp
+}
+
+func addUsage(u, delta *databasev1.Usage) {
+	u.DiskBytes += delta.GetDiskBytes()
+	u.Series += delta.GetSeries()
+	u.Blocks += delta.GetBlocks()
+	u.IngestRate += delta.GetIngestRate()
+}
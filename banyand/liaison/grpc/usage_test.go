@@ -0,0 +1,73 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+)
+
+var _ = Describe("Usage", func() {
+	shard := func(id uint32, node string, diskBytes int64) *databasev1.UsageServiceGetResponse_Shard {
+		return &databasev1.UsageServiceGetResponse_Shard{
+			Id:    id,
+			Node:  node,
+			Usage: &databasev1.Usage{DiskBytes: diskBytes, Series: 1, Blocks: 2, IngestRate: 0.5},
+		}
+	}
+	group := func(name string, shards ...*databasev1.UsageServiceGetResponse_Shard) *databasev1.UsageServiceGetResponse_Group {
+		return &databasev1.UsageServiceGetResponse_Group{Name: name, Catalog: commonv1.Catalog_CATALOG_STREAM, Shards: shards}
+	}
+
+	It("merges the groups reported by the nodes", func() {
+		resp := summarize([]*databasev1.UsageServiceGetResponse_Group{
+			group("sw", shard(1, "local", 10)),
+			group("default", shard(0, "local", 20)),
+			group("sw", shard(0, "data-1", 30), shard(1, "data-1", 40)),
+		}, map[string]*databasev1.UsageServiceGetResponse_Node{
+			"local":  {Id: "local"},
+			"data-1": {Id: "data-1"},
+			"data-2": {Id: "data-2", Error: "unavailable"},
+		})
+		Expect(resp.GetGroups()).To(HaveLen(2))
+		Expect(resp.GetGroups()[0].GetName()).To(Equal("default"))
+		sw := resp.GetGroups()[1]
+		Expect(sw.GetName()).To(Equal("sw"))
+		Expect(sw.GetUsage().GetDiskBytes()).To(Equal(int64(80)))
+		Expect(sw.GetUsage().GetSeries()).To(Equal(int64(3)))
+		Expect(sw.GetUsage().GetIngestRate()).To(Equal(1.5))
+		Expect(sw.GetShards()).To(HaveLen(3))
+		Expect(sw.GetShards()[0].GetNode()).To(Equal("data-1"))
+		Expect(sw.GetShards()[1].GetNode()).To(Equal("data-1"))
+		Expect(sw.GetShards()[2].GetNode()).To(Equal("local"))
+
+		Expect(resp.GetNodes()).To(HaveLen(3))
+		Expect(resp.GetNodes()[0].GetId()).To(Equal("data-1"))
+		Expect(resp.GetNodes()[0].GetUsage().GetDiskBytes()).To(Equal(int64(70)))
+		Expect(resp.GetNodes()[1].GetId()).To(Equal("data-2"))
+		Expect(resp.GetNodes()[1].GetUsage()).To(BeNil())
+		Expect(resp.GetNodes()[1].GetError()).To(Equal("unavailable"))
+		Expect(resp.GetNodes()[2].GetUsage().GetDiskBytes()).To(Equal(int64(30)))
+
+		Expect(resp.GetTotal().GetDiskBytes()).To(Equal(int64(100)))
+		Expect(resp.GetTotal().GetBlocks()).To(Equal(int64(8)))
+	})
+})
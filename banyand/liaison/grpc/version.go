@@ -0,0 +1,176 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+)
+
+const (
+	// apiVersion is the major.minor version of the wire protocol spoken by the server.
+	// The peers are compatible if they share the major version and their minor versions differ by one at most.
+	apiVersion = "0.3"
+	// legacyAPIVersion is assumed for the nodes registered without a version, which predate the negotiation
+	legacyAPIVersion = "0.3"
+	// apiVersionKey carries the api version of the caller, and the one of the server in the response header
+	apiVersionKey = "banyandb-api-version"
+	// featuresKey carries the features of the server in the response header
+	featuresKey = "banyandb-features"
+
+	// featureBatchedWrites marks the nodes accepting the elements of a stream write in batches
+	featureBatchedWrites = "batched-writes"
+	// featureShardRestriction marks the nodes restricting the sub-queries to the shards assigned to them
+	featureShardRestriction = "shard-restriction"
+	// featureUsage marks the nodes reporting the storage usage of their shards
+	featureUsage = "usage"
+	// featureBlockExport marks the nodes streaming the files of their sealed blocks
+	featureBlockExport = "block-export"
+	// featureInternalWrites marks the nodes receiving the forwarded writes by the InternalWriteService
+	featureInternalWrites = "internal-writes"
+)
+
+var (
+	// features are the optional capabilities of the server announced to its peers
+	features = []string{featureBatchedWrites, featureShardRestriction, featureUsage, featureBlockExport, featureInternalWrites}
+	// legacyFeatures are assumed for the nodes registered without a version
+	legacyFeatures = []string{featureBatchedWrites, featureShardRestriction}
+
+	errInvalidAPIVersion = errors.New("the api version should be major.minor")
+)
+
+// checkAPIVersion returns an error if a peer speaking the version v is incompatible with the server.
+func checkAPIVersion(v string) error {
+	major, minor, err := parseAPIVersion(v)
+	if err != nil {
+		return err
+	}
+	localMajor, localMinor, _ := parseAPIVersion(apiVersion)
+	if major != localMajor || minor < localMinor-1 || minor > localMinor+1 {
+		return errors.Errorf("the api version %s is incompatible with %s", v, apiVersion)
+	}
+	return nil
+}
+
+func parseAPIVersion(v string) (major, minor int, err error) {
+	majorStr, minorStr, found := strings.Cut(v, ".")
+	if !found {
+		return 0, 0, errors.WithMessage(errInvalidAPIVersion, v)
+	}
+	if major, err = strconv.Atoi(majorStr); err != nil {
+		return 0, 0, errors.WithMessage(errInvalidAPIVersion, v)
+	}
+	if minor, err = strconv.Atoi(minorStr); err != nil {
+		return 0, 0, errors.WithMessage(errInvalidAPIVersion, v)
+	}
+	return major, minor, nil
+}
+
+// nodeAPIVersion returns the api version announced by the node.
+func nodeAPIVersion(node *databasev1.Node) string {
+	if node.GetApiVersion() == "" {
+		return legacyAPIVersion
+	}
+	return node.GetApiVersion()
+}
+
+// supports tells whether the node announces the feature.
+func supports(node *databasev1.Node, feature string) bool {
+	announced := node.GetFeatures()
+	if node.GetApiVersion() == "" {
+		announced = legacyFeatures
+	}
+	for _, f := range announced {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// withAPIVersion announces the api version of the server to the node receiving the outgoing calls.
+func withAPIVersion(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, apiVersionKey, apiVersion)
+}
+
+// negotiate rejects the calls of the incompatible clients and nodes, which announce their api versions in the metadata.
+// The callers without a version are accepted.
+func negotiate(ctx context.Context) error {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+	values := md.Get(apiVersionKey)
+	if len(values) < 1 {
+		return nil
+	}
+	if err := checkAPIVersion(values[0]); err != nil {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return nil
+}
+
+func versionHeader() metadata.MD {
+	return metadata.Pairs(apiVersionKey, apiVersion, featuresKey, strings.Join(features, ","))
+}
+
+func versionUnaryInterceptor() grpclib.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpclib.UnaryServerInfo, handler grpclib.UnaryHandler) (interface{}, error) {
+		// the header is absent if the context doesn't come from a transport, e.g. an in-process call
+		_ = grpclib.SetHeader(ctx, versionHeader())
+		if err := negotiate(ctx); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func versionStreamInterceptor() grpclib.StreamServerInterceptor {
+	return func(srv interface{}, ss grpclib.ServerStream, _ *grpclib.StreamServerInfo, handler grpclib.StreamHandler) error {
+		_ = ss.SetHeader(versionHeader())
+		if err := negotiate(ss.Context()); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// downgradeStreamWrite splits the batch into the requests of single elements for the node not accepting the batched writes.
+func downgradeStreamWrite(node *databasev1.Node, request *streamv1.WriteRequest) []*streamv1.WriteRequest {
+	if supports(node, featureBatchedWrites) || len(request.GetElements()) < 1 {
+		return []*streamv1.WriteRequest{request}
+	}
+	requests := make([]*streamv1.WriteRequest, 0, len(request.GetElements())+1)
+	if request.GetElement() != nil {
+		requests = append(requests, &streamv1.WriteRequest{Metadata: request.GetMetadata(), Element: request.GetElement()})
+	}
+	for _, e := range request.GetElements() {
+		requests = append(requests, &streamv1.WriteRequest{Metadata: request.GetMetadata(), Element: e})
+	}
+	return requests
+}
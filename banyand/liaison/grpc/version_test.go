@@ -0,0 +1,126 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+var _ = Describe("Version", func() {
+	It("accepts the peers within one minor version", func() {
+		Expect(checkAPIVersion(apiVersion)).To(Succeed())
+		major, minor, err := parseAPIVersion(apiVersion)
+		Expect(err).NotTo(HaveOccurred())
+		version := func(major, minor int) string {
+			return fmt.Sprintf("%d.%d", major, minor)
+		}
+		Expect(checkAPIVersion(version(major, minor-1))).To(Succeed())
+		Expect(checkAPIVersion(version(major, minor+1))).To(Succeed())
+		Expect(checkAPIVersion(version(major, minor+2))).NotTo(Succeed())
+		Expect(checkAPIVersion(version(major+1, minor))).NotTo(Succeed())
+		Expect(checkAPIVersion("latest")).To(MatchError(ContainSubstring(errInvalidAPIVersion.Error())))
+	})
+	It("assumes the features of the legacy nodes", func() {
+		legacy := &databasev1.Node{Id: "legacy"}
+		Expect(nodeAPIVersion(legacy)).To(Equal(legacyAPIVersion))
+		Expect(supports(legacy, featureBatchedWrites)).To(BeTrue())
+		Expect(supports(&databasev1.Node{ApiVersion: apiVersion}, featureBatchedWrites)).To(BeFalse())
+		Expect(supports(&databasev1.Node{ApiVersion: apiVersion, Features: features}, featureBatchedWrites)).To(BeTrue())
+	})
+	It("splits the batches for the nodes not accepting them", func() {
+		md := &commonv1.Metadata{Group: "default", Name: "sw"}
+		request := &streamv1.WriteRequest{Metadata: md, Elements: []*streamv1.ElementValue{{ElementId: "1"}, {ElementId: "2"}}}
+		Expect(downgradeStreamWrite(&databasev1.Node{ApiVersion: apiVersion, Features: features}, request)).To(HaveLen(1))
+		requests := downgradeStreamWrite(&databasev1.Node{ApiVersion: apiVersion}, request)
+		Expect(requests).To(HaveLen(2))
+		for i, r := range requests {
+			Expect(r.GetMetadata()).To(Equal(md))
+			Expect(r.GetElements()).To(BeEmpty())
+			Expect(r.GetElement()).To(Equal(request.GetElements()[i]))
+		}
+	})
+	It("leaves the incompatible nodes out of the placement", func() {
+		router := newNodeRouter("local")
+		router.log = logger.GetLogger("test")
+		defer router.close()
+		nodeEvent := func(version string) {
+			router.Rev(bus.NewMessage(bus.MessageID(1), &databasev1.NodeEvent{
+				Node:   &databasev1.Node{Id: "remote", Addr: "remote:17912", ApiVersion: version},
+				Action: databasev1.Action_ACTION_PUT,
+			}))
+		}
+		owned := func() bool {
+			for i := 0; i < 32; i++ {
+				if router.locate("default", common.ShardID(i)) != nil {
+					return true
+				}
+			}
+			return false
+		}
+		nodeEvent(apiVersion)
+		Expect(owned()).To(BeTrue())
+		nodeEvent("99.0")
+		Expect(owned()).To(BeFalse())
+	})
+	It("negotiates the api version with the callers", func() {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		ser := grpclib.NewServer(grpclib.UnaryInterceptor(versionUnaryInterceptor()))
+		databasev1.RegisterServerInfoServiceServer(ser, &serverInfoServer{startedAt: time.Now()})
+		go func() {
+			_ = ser.Serve(lis)
+		}()
+		defer ser.Stop()
+		conn, err := grpclib.Dial(lis.Addr().String(), grpclib.WithTransportCredentials(insecure.NewCredentials()))
+		Expect(err).NotTo(HaveOccurred())
+		defer conn.Close()
+		client := databasev1.NewServerInfoServiceClient(conn)
+
+		var header metadata.MD
+		resp, err := client.Get(context.Background(), &databasev1.ServerInfoServiceGetRequest{}, grpclib.Header(&header))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(header.Get(apiVersionKey)).To(Equal([]string{apiVersion}))
+		Expect(header.Get(featuresKey)).To(HaveLen(1))
+		Expect(resp.GetServerInfo().GetApiVersion()).To(Equal(apiVersion))
+		Expect(resp.GetServerInfo().GetFeatures()).To(Equal(features))
+
+		_, err = client.Get(withAPIVersion(context.Background()), &databasev1.ServerInfoServiceGetRequest{})
+		Expect(err).NotTo(HaveOccurred())
+		ctx := metadata.AppendToOutgoingContext(context.Background(), apiVersionKey, "99.0")
+		_, err = client.Get(ctx, &databasev1.ServerInfoServiceGetRequest{})
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+	})
+})
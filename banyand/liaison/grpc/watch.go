@@ -0,0 +1,152 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"sync"
+
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
+)
+
+const (
+	watchKinds = schema.KindGroup | schema.KindStream | schema.KindMeasure | schema.KindIndexRule
+	// watchBufferSize is the number of the events buffered for a watcher,
+	// which is disconnected once the buffer is full.
+	watchBufferSize = 1024
+)
+
+var _ schema.EventHandler = (*watchHub)(nil)
+
+// watchHub fans the events of the schema registry out to the Watch streams of the registry services.
+type watchHub struct {
+	watchers map[*watcher]struct{}
+	closed   chan struct{}
+	mu       sync.RWMutex
+	once     sync.Once
+}
+
+type watchEvent struct {
+	spec      proto.Message
+	eventType databasev1.EventType
+}
+
+type watcher struct {
+	events chan watchEvent
+	// overflowed is closed once the buffer is full
+	overflowed chan struct{}
+	group      string
+	kind       schema.Kind
+	once       sync.Once
+}
+
+func newWatchHub() *watchHub {
+	return &watchHub{
+		watchers: make(map[*watcher]struct{}),
+		closed:   make(chan struct{}),
+	}
+}
+
+func (h *watchHub) OnAddOrUpdate(m schema.Metadata) {
+	eventType := databasev1.EventType_EVENT_TYPE_UPDATED
+	// the registry assigns the same revision to the both on the creation
+	if spec, ok := m.Spec.(schema.HasMetadata); ok && spec.GetMetadata().GetCreateRevision() == spec.GetMetadata().GetModRevision() {
+		eventType = databasev1.EventType_EVENT_TYPE_CREATED
+	}
+	h.publish(m, eventType)
+}
+
+func (h *watchHub) OnDelete(m schema.Metadata) {
+	h.publish(m, databasev1.EventType_EVENT_TYPE_DELETED)
+}
+
+func (h *watchHub) publish(m schema.Metadata, eventType databasev1.EventType) {
+	spec, ok := m.Spec.(proto.Message)
+	if !ok {
+		return
+	}
+	var event *watchEvent
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for w := range h.watchers {
+		if w.kind != m.Kind || (w.group != "" && w.group != m.Group) {
+			continue
+		}
+		if event == nil {
+			// the spec is shared with the other handlers
+			event = &watchEvent{spec: proto.Clone(spec), eventType: eventType}
+		}
+		select {
+		case w.events <- *event:
+		default:
+			w.once.Do(func() {
+				close(w.overflowed)
+			})
+		}
+	}
+}
+
+// watch sends the events of the kind in the group to the stream until it's closed.
+func (h *watchHub) watch(stream grpclib.ServerStream, kind schema.Kind, group string, send func(watchEvent) error) error {
+	w := &watcher{
+		kind:       kind,
+		group:      group,
+		events:     make(chan watchEvent, watchBufferSize),
+		overflowed: make(chan struct{}),
+	}
+	h.mu.Lock()
+	h.watchers[w] = struct{}{}
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.watchers, w)
+		h.mu.Unlock()
+	}()
+	// the header tells the client that the events after now are watched
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-h.closed:
+			return status.Error(codes.Unavailable, "the server is stopping")
+		case <-w.overflowed:
+			return status.Error(codes.ResourceExhausted, "the watcher falls behind the events")
+		case e := <-w.events:
+			if err := send(e); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// close ends all the Watch streams, which block the graceful stop of the server otherwise.
+func (h *watchHub) close() {
+	h.once.Do(func() {
+		close(h.closed)
+	})
+}
@@ -0,0 +1,279 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/banyand/metadata"
+	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
+	"github.com/apache/skywalking-banyandb/pkg/expr"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/partition"
+)
+
+const (
+	writeFilterKinds = schema.KindGroup | schema.KindStream | schema.KindMeasure
+	// writeFilterPrograms bounds the compiled expressions, which are keyed by their sources and the tags of the resources
+	writeFilterPrograms = 1024
+)
+
+var _ schema.EventHandler = (*writeFilter)(nil)
+
+// writeFilter evaluates the write filter rules of the groups in the write path.
+// The rules of a group and the tags of a resource are loaded on the first write, and cached until their schemas change.
+// A rule is compiled against the tags of each resource it's evaluated on.
+type writeFilter struct {
+	registry metadata.Repo
+	log      *logger.Logger
+	groups   map[string][]*commonv1.WriteFilterRule
+	tags     map[identity]*resourceTags
+	// programs caches the compiled expressions, which are shared by the resources declaring the same tags
+	programs *lru.Cache
+	// version is bumped by every event to prevent a stale schema loaded before the event from being cached
+	version uint64
+	mu      sync.RWMutex
+}
+
+// resourceTags locates and declares the tags of a stream or a measure.
+type resourceTags struct {
+	locators map[string]partition.TagLocator
+	// families are the names of the tag families of the tags
+	families map[string]string
+	decls    expr.Tags
+	// signature identifies the declarations in the keys of the programs
+	signature string
+}
+
+// compiledProgram is the program of an expression, or the error failing to compile it against the tags.
+type compiledProgram struct {
+	program *expr.Program
+	err     error
+}
+
+func newWriteFilter(registry metadata.Repo) *writeFilter {
+	programs, _ := lru.New(writeFilterPrograms)
+	return &writeFilter{
+		registry: registry,
+		groups:   make(map[string][]*commonv1.WriteFilterRule),
+		tags:     make(map[identity]*resourceTags),
+		programs: programs,
+	}
+}
+
+func (f *writeFilter) OnAddOrUpdate(m schema.Metadata) {
+	f.invalidate(m)
+}
+
+func (f *writeFilter) OnDelete(m schema.Metadata) {
+	f.invalidate(m)
+}
+
+func (f *writeFilter) invalidate(m schema.Metadata) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.version++
+	switch m.Kind {
+	case schema.KindGroup:
+		delete(f.groups, m.Name)
+		// deleting a group deletes its resources without their own events
+		for id := range f.tags {
+			if id.group == m.Name {
+				delete(f.tags, id)
+			}
+		}
+	case schema.KindStream, schema.KindMeasure:
+		delete(f.tags, identity{name: m.Name, group: m.Group})
+	}
+}
+
+// apply returns the metadata of the resource the element is written to, or false if it's dropped.
+// The element routed to another group is filtered by the rules of that group too, which don't route it again.
+// The element is accepted if the rules or the schema fail to be loaded, or a rule fails to be compiled or evaluated.
+func (f *writeFilter) apply(ctx context.Context, kind schema.Kind, md *commonv1.Metadata,
+	families []*modelv1.TagFamilyForWrite,
+) (*commonv1.Metadata, bool) {
+	target, action := f.match(ctx, kind, md, families)
+	if action != commonv1.WriteFilterRule_ACTION_ROUTE {
+		return md, action != commonv1.WriteFilterRule_ACTION_DROP
+	}
+	if _, action = f.match(ctx, kind, target, families); action == commonv1.WriteFilterRule_ACTION_ROUTE {
+		f.log.Debug().Str("group", md.GetGroup()).Str("route_group", target.GetGroup()).Msg("ignore the route of the routed element")
+	}
+	return target, action != commonv1.WriteFilterRule_ACTION_DROP
+}
+
+// match returns the action of the first rule of the group matching the element, and the metadata it's routed to.
+func (f *writeFilter) match(ctx context.Context, kind schema.Kind, md *commonv1.Metadata,
+	families []*modelv1.TagFamilyForWrite,
+) (*commonv1.Metadata, commonv1.WriteFilterRule_Action) {
+	rules := f.rules(ctx, md.GetGroup())
+	if len(rules) < 1 {
+		return md, commonv1.WriteFilterRule_ACTION_ACCEPT
+	}
+	tags, ok := f.resource(ctx, kind, md)
+	if !ok {
+		return md, commonv1.WriteFilterRule_ACTION_ACCEPT
+	}
+	resolve := tagResolver(tags.locators, families)
+	for _, r := range rules {
+		program, err := f.program(r.GetExpression(), tags)
+		if err != nil {
+			f.log.Debug().Err(err).Str("group", md.GetGroup()).Str("name", md.GetName()).Str("rule", r.GetName()).
+				Msg("failed to compile the write filter against the tags")
+			continue
+		}
+		matched, err := program.Eval(resolve)
+		if err != nil {
+			f.log.Debug().Err(err).Str("group", md.GetGroup()).Str("rule", r.GetName()).Msg("failed to evaluate the write filter")
+			continue
+		}
+		if !matched {
+			continue
+		}
+		switch r.GetAction() {
+		case commonv1.WriteFilterRule_ACTION_DROP:
+			return md, commonv1.WriteFilterRule_ACTION_DROP
+		case commonv1.WriteFilterRule_ACTION_ROUTE:
+			return &commonv1.Metadata{Group: r.GetRouteGroup(), Name: md.GetName()}, commonv1.WriteFilterRule_ACTION_ROUTE
+		default:
+			return md, commonv1.WriteFilterRule_ACTION_ACCEPT
+		}
+	}
+	return md, commonv1.WriteFilterRule_ACTION_ACCEPT
+}
+
+func (f *writeFilter) rules(ctx context.Context, group string) []*commonv1.WriteFilterRule {
+	f.mu.RLock()
+	rules, ok := f.groups[group]
+	version := f.version
+	f.mu.RUnlock()
+	if ok {
+		return rules
+	}
+	g, err := f.registry.GroupRegistry().GetGroup(ctx, group)
+	if err != nil {
+		f.log.Error().Err(err).Str("group", group).Msg("failed to load the write filters")
+		return nil
+	}
+	rules = g.GetWriteFilters()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.version == version {
+		f.groups[group] = rules
+	}
+	return rules
+}
+
+// program returns the expression compiled against the tags, which is cached with the failure to compile it.
+func (f *writeFilter) program(expression string, tags *resourceTags) (*expr.Program, error) {
+	key := expression + "\x00" + tags.signature
+	if cached, ok := f.programs.Get(key); ok {
+		c := cached.(compiledProgram)
+		return c.program, c.err
+	}
+	program, err := expr.Compile(expression, tags.decls)
+	f.programs.Add(key, compiledProgram{program: program, err: err})
+	return program, err
+}
+
+func (f *writeFilter) resource(ctx context.Context, kind schema.Kind, md *commonv1.Metadata) (*resourceTags, bool) {
+	id := getID(md)
+	f.mu.RLock()
+	tags, ok := f.tags[id]
+	version := f.version
+	f.mu.RUnlock()
+	if ok {
+		return tags, true
+	}
+	var families []*databasev1.TagFamilySpec
+	var err error
+	if kind == schema.KindStream {
+		var s *databasev1.Stream
+		if s, err = f.registry.StreamRegistry().GetStream(ctx, md); err == nil {
+			families = s.GetTagFamilies()
+		}
+	} else {
+		var m *databasev1.Measure
+		if m, err = f.registry.MeasureRegistry().GetMeasure(ctx, md); err == nil {
+			families = m.GetTagFamilies()
+		}
+	}
+	if err != nil {
+		f.log.Error().Err(err).Str("group", md.GetGroup()).Str("name", md.GetName()).Msg("failed to load the tags to filter")
+		return nil, false
+	}
+	tags = &resourceTags{
+		locators: make(map[string]partition.TagLocator),
+		families: make(map[string]string),
+		decls:    make(expr.Tags),
+	}
+	var signature strings.Builder
+	for fi, family := range families {
+		for ti, tag := range family.GetTags() {
+			tags.locators[tag.GetName()] = partition.TagLocator{FamilyOffset: fi, TagOffset: ti}
+			tags.families[tag.GetName()] = family.GetName()
+			tags.decls[tag.GetName()] = tag.GetType()
+			fmt.Fprintf(&signature, "%s:%d,", tag.GetName(), tag.GetType())
+		}
+	}
+	tags.signature = signature.String()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.version == version {
+		f.tags[id] = tags
+	}
+	return tags, true
+}
+
+// tagResolver resolves the identifiers of an expression to the values of the tags located by the locators.
+func tagResolver(locators map[string]partition.TagLocator, families []*modelv1.TagFamilyForWrite) expr.Resolver {
+	return func(name string) (interface{}, bool) {
+		l, ok := locators[name]
+		if !ok {
+			return nil, false
+		}
+		if l.FamilyOffset >= len(families) || l.TagOffset >= len(families[l.FamilyOffset].GetTags()) {
+			return nil, true
+		}
+		return tagValue(families[l.FamilyOffset].GetTags()[l.TagOffset]), true
+	}
+}
+
+// tagValue converts a tag to the value of an expression, the binary data is taken as null.
+func tagValue(tag *modelv1.TagValue) interface{} {
+	switch v := tag.GetValue().(type) {
+	case *modelv1.TagValue_Str:
+		return v.Str.GetValue()
+	case *modelv1.TagValue_Int:
+		return v.Int.GetValue()
+	case *modelv1.TagValue_StrArray:
+		return v.StrArray.GetValue()
+	case *modelv1.TagValue_IntArray:
+		return v.IntArray.GetValue()
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/banyand/metadata"
+	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+type fakeRepo struct {
+	metadata.Repo
+	schema.Group
+	schema.Stream
+	groups   map[string]*commonv1.Group
+	idPolicy *databasev1.ElementIDPolicy
+	loaded   int
+}
+
+func (r *fakeRepo) GroupRegistry() schema.Group {
+	return r
+}
+
+func (r *fakeRepo) StreamRegistry() schema.Stream {
+	return r
+}
+
+func (r *fakeRepo) GetGroup(_ context.Context, name string) (*commonv1.Group, error) {
+	r.loaded++
+	if g, ok := r.groups[name]; ok {
+		return g, nil
+	}
+	return &commonv1.Group{Metadata: &commonv1.Metadata{Name: name}}, nil
+}
+
+func (r *fakeRepo) GetStream(context.Context, *commonv1.Metadata) (*databasev1.Stream, error) {
+	return &databasev1.Stream{
+		ElementIdPolicy: r.idPolicy,
+		TagFamilies: []*databasev1.TagFamilySpec{{
+			Name: "searchable",
+			Tags: []*databasev1.TagSpec{
+				{Name: "endpoint", Type: databasev1.TagType_TAG_TYPE_STRING},
+				{Name: "status", Type: databasev1.TagType_TAG_TYPE_INT},
+			},
+		}},
+	}, nil
+}
+
+var _ = Describe("WriteFilter", func() {
+	md := &commonv1.Metadata{Group: "default", Name: "sw"}
+	tags := func(endpoint string, status int64) []*modelv1.TagFamilyForWrite {
+		return []*modelv1.TagFamilyForWrite{{Tags: []*modelv1.TagValue{
+			{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: endpoint}}},
+			{Value: &modelv1.TagValue_Int{Int: &modelv1.Int{Value: status}}},
+		}}}
+	}
+	var repo *fakeRepo
+	var filter *writeFilter
+	BeforeEach(func() {
+		repo = &fakeRepo{groups: map[string]*commonv1.Group{
+			"default": {
+				Metadata: &commonv1.Metadata{Name: "default"},
+				WriteFilters: []*commonv1.WriteFilterRule{
+					{Name: "keep-errors", Expression: `status >= 500`, Action: commonv1.WriteFilterRule_ACTION_ACCEPT},
+					{Name: "drop-health", Expression: `endpoint.startsWith("/health")`, Action: commonv1.WriteFilterRule_ACTION_DROP},
+					{Name: "route-debug", Expression: `endpoint in ["/debug", "/pprof"]`, Action: commonv1.WriteFilterRule_ACTION_ROUTE, RouteGroup: "debug"},
+					{Name: "undeclared", Expression: `undefined == 1`, Action: commonv1.WriteFilterRule_ACTION_DROP},
+					{Name: "mistyped", Expression: `endpoint == 1`, Action: commonv1.WriteFilterRule_ACTION_DROP},
+				},
+			},
+		}}
+		filter = newWriteFilter(repo)
+		filter.log = logger.GetLogger("test")
+	})
+
+	It("decides by the first matched rule", func() {
+		target, accepted := filter.apply(context.TODO(), schema.KindStream, md, tags("/home", 200))
+		Expect(accepted).To(BeTrue())
+		Expect(target).To(Equal(md))
+		_, accepted = filter.apply(context.TODO(), schema.KindStream, md, tags("/health", 200))
+		Expect(accepted).To(BeFalse())
+		_, accepted = filter.apply(context.TODO(), schema.KindStream, md, tags("/health", 503))
+		Expect(accepted).To(BeTrue())
+		target, accepted = filter.apply(context.TODO(), schema.KindStream, md, tags("/debug", 200))
+		Expect(accepted).To(BeTrue())
+		Expect(target.GetGroup()).To(Equal("debug"))
+		Expect(target.GetName()).To(Equal("sw"))
+	})
+
+	It("caches the rules until the group changes", func() {
+		filter.apply(context.TODO(), schema.KindStream, md, tags("/home", 200))
+		filter.apply(context.TODO(), schema.KindStream, md, tags("/home", 200))
+		Expect(repo.loaded).To(Equal(1))
+		repo.groups["default"].WriteFilters = nil
+		filter.OnAddOrUpdate(schema.Metadata{TypeMeta: schema.TypeMeta{Kind: schema.KindGroup, Name: "default"}, Spec: repo.groups["default"]})
+		_, accepted := filter.apply(context.TODO(), schema.KindStream, md, tags("/health", 200))
+		Expect(accepted).To(BeTrue())
+		Expect(repo.loaded).To(Equal(2))
+	})
+
+	It("filters the routed elements by the rules of the target group", func() {
+		repo.groups["debug"] = &commonv1.Group{
+			Metadata: &commonv1.Metadata{Name: "debug"},
+			WriteFilters: []*commonv1.WriteFilterRule{
+				{Name: "drop-missing", Expression: `status == 404`, Action: commonv1.WriteFilterRule_ACTION_DROP},
+				{Name: "route-back", Expression: `true`, Action: commonv1.WriteFilterRule_ACTION_ROUTE, RouteGroup: "default"},
+			},
+		}
+		_, accepted := filter.apply(context.TODO(), schema.KindStream, md, tags("/debug", 404))
+		Expect(accepted).To(BeFalse())
+		target, accepted := filter.apply(context.TODO(), schema.KindStream, md, tags("/pprof", 200))
+		Expect(accepted).To(BeTrue())
+		Expect(target.GetGroup()).To(Equal("debug"))
+	})
+
+	It("shares the compiled programs by the resources declaring the same tags", func() {
+		filter.apply(context.TODO(), schema.KindStream, md, tags("/home", 200))
+		// the failures to compile are cached too
+		Expect(filter.programs.Len()).To(Equal(5))
+		filter.apply(context.TODO(), schema.KindStream, &commonv1.Metadata{Group: "default", Name: "other"}, tags("/home", 200))
+		filter.OnAddOrUpdate(schema.Metadata{TypeMeta: schema.TypeMeta{Kind: schema.KindGroup, Name: "default"}, Spec: repo.groups["default"]})
+		filter.apply(context.TODO(), schema.KindStream, md, tags("/home", 200))
+		Expect(filter.programs.Len()).To(Equal(5))
+	})
+})
@@ -0,0 +1,209 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpc
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/banyand/metadata"
+	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+const writeValidatorKinds = schema.KindGroup | schema.KindStream | schema.KindMeasure
+
+var _ schema.EventHandler = (*writeValidator)(nil)
+
+// writeValidator checks the elements and the data points against their schemas in the write path,
+// so that the malformed ones are rejected with the reasons instead of being dropped by the data nodes.
+// The schema of a resource is loaded on the first write, and cached until it changes.
+type writeValidator struct {
+	registry metadata.Repo
+	log      *logger.Logger
+	tagSizes *tagSizeGuard
+	compat   *schemaCompat
+	schemas  map[identity]*writeSchema
+	// version is bumped by every event to prevent a stale schema loaded before the event from being cached
+	version uint64
+	mu      sync.RWMutex
+}
+
+type writeSchema struct {
+	entity   *databasev1.Entity
+	idPolicy *databasev1.ElementIDPolicy
+	families []*databasev1.TagFamilySpec
+	fields   []*databasev1.FieldSpec
+}
+
+func (s *writeSchema) getFamilies() []*databasev1.TagFamilySpec {
+	if s == nil {
+		return nil
+	}
+	return s.families
+}
+
+func (s *writeSchema) getEntity() *databasev1.Entity {
+	if s == nil {
+		return nil
+	}
+	return s.entity
+}
+
+func (s *writeSchema) getIDPolicy() *databasev1.ElementIDPolicy {
+	if s == nil {
+		return nil
+	}
+	return s.idPolicy
+}
+
+func newWriteValidator(registry metadata.Repo, tagSizes *tagSizeGuard, compat *schemaCompat) *writeValidator {
+	return &writeValidator{
+		registry: registry,
+		tagSizes: tagSizes,
+		compat:   compat,
+		schemas:  make(map[identity]*writeSchema),
+	}
+}
+
+func (v *writeValidator) OnAddOrUpdate(m schema.Metadata) {
+	v.invalidate(m)
+}
+
+func (v *writeValidator) OnDelete(m schema.Metadata) {
+	v.invalidate(m)
+}
+
+func (v *writeValidator) invalidate(m schema.Metadata) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.version++
+	switch m.Kind {
+	case schema.KindGroup:
+		// deleting a group deletes its resources without their own events
+		for id := range v.schemas {
+			if id.group == m.Name {
+				delete(v.schemas, id)
+			}
+		}
+	case schema.KindStream, schema.KindMeasure:
+		delete(v.schemas, identity{name: m.Name, group: m.Group})
+	}
+}
+
+// validateElement returns the reason why the element is rejected, or nil if it's valid.
+func (v *writeValidator) validateElement(ctx context.Context, md *commonv1.Metadata, element *streamv1.ElementValue) *modelv1.WriteError {
+	if err := timestamp.CheckPb(element.GetTimestamp()); err != nil {
+		return &modelv1.WriteError{Code: modelv1.WriteError_CODE_INVALID_TIMESTAMP, Message: err.Error()}
+	}
+	s, wErr := v.schema(ctx, schema.KindStream, md)
+	if wErr == nil && s != nil {
+		wErr = pbv1.ValidateTagFamilies(s.families, s.entity, element.GetTagFamilies())
+	}
+	v.compat.observe(rejectedTypeStream, md, s, element.GetTagFamilies(), nil, wErr)
+	if wErr != nil {
+		return wErr
+	}
+	element.TagFamilies, wErr = v.tagSizes.enforce(s.getFamilies(), s.getEntity(), element.GetTagFamilies())
+	return wErr
+}
+
+// assignElementID generates the UUID of an element once before it's fanned out to the replicas,
+// so that all of them store the same id. The ids hashed from the tags are left to the data nodes,
+// since the tags might be filled by the enrichment there.
+func (v *writeValidator) assignElementID(ctx context.Context, md *commonv1.Metadata, element *streamv1.ElementValue) *modelv1.WriteError {
+	s, wErr := v.schema(ctx, schema.KindStream, md)
+	if wErr != nil || s.getIDPolicy().GetSource() != databasev1.ElementIDPolicy_SOURCE_UUID {
+		return wErr
+	}
+	id, err := pbv1.ElementID(s.getIDPolicy(), nil, nil, element.GetElementId())
+	if err != nil {
+		return &modelv1.WriteError{Code: modelv1.WriteError_CODE_UNSPECIFIED, Message: err.Error()}
+	}
+	element.ElementId = id
+	return nil
+}
+
+// validateDataPoint returns the reason why the data point is rejected, or nil if it's valid.
+func (v *writeValidator) validateDataPoint(ctx context.Context, md *commonv1.Metadata, dataPoint *measurev1.DataPointValue) *modelv1.WriteError {
+	if err := timestamp.CheckPb(dataPoint.GetTimestamp()); err != nil {
+		return &modelv1.WriteError{Code: modelv1.WriteError_CODE_INVALID_TIMESTAMP, Message: err.Error()}
+	}
+	s, wErr := v.schema(ctx, schema.KindMeasure, md)
+	if wErr == nil && s != nil {
+		if wErr = pbv1.ValidateTagFamilies(s.families, s.entity, dataPoint.GetTagFamilies()); wErr == nil {
+			wErr = pbv1.ValidateFields(s.fields, dataPoint.GetFields())
+		}
+	}
+	v.compat.observe(rejectedTypeMeasure, md, s, dataPoint.GetTagFamilies(), dataPoint.GetFields(), wErr)
+	if wErr != nil {
+		return wErr
+	}
+	dataPoint.TagFamilies, wErr = v.tagSizes.enforce(s.getFamilies(), s.getEntity(), dataPoint.GetTagFamilies())
+	return wErr
+}
+
+// schema returns the cached schema of the resource. It's nil without an error if the schema fails to be loaded
+// for a reason other than its absence, which lets the write go through.
+func (v *writeValidator) schema(ctx context.Context, kind schema.Kind, md *commonv1.Metadata) (*writeSchema, *modelv1.WriteError) {
+	id := getID(md)
+	v.mu.RLock()
+	s, ok := v.schemas[id]
+	version := v.version
+	v.mu.RUnlock()
+	if ok {
+		return s, nil
+	}
+	var err error
+	if kind == schema.KindStream {
+		var stream *databasev1.Stream
+		if stream, err = v.registry.StreamRegistry().GetStream(ctx, md); err == nil {
+			s = &writeSchema{entity: stream.GetEntity(), idPolicy: stream.GetElementIdPolicy(), families: stream.GetTagFamilies()}
+		}
+	} else {
+		var measure *databasev1.Measure
+		if measure, err = v.registry.MeasureRegistry().GetMeasure(ctx, md); err == nil {
+			s = &writeSchema{entity: measure.GetEntity(), families: measure.GetTagFamilies(), fields: measure.GetFields()}
+		}
+	}
+	if errors.Is(err, schema.ErrGRPCResourceNotFound) {
+		return nil, &modelv1.WriteError{
+			Code:    modelv1.WriteError_CODE_SCHEMA_NOT_FOUND,
+			Message: "the schema " + md.GetGroup() + "/" + md.GetName() + " is not found",
+		}
+	}
+	if err != nil {
+		v.log.Error().Err(err).Str("group", md.GetGroup()).Str("name", md.GetName()).Msg("failed to load the schema to validate the writes")
+		return nil, nil
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.version == version {
+		v.schemas[id] = s
+	}
+	return s, nil
+}
@@ -0,0 +1,139 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
+)
+
+// maxRetryAttempts is the cap of the attempts of a call set by the gRPC client.
+const maxRetryAttempts = 5
+
+var (
+	errInvalidRetryPolicy   = errors.New("the retry attempts should be in [0, 5], and the retry backoffs and multiplier should be positive")
+	errInvalidBackoffPolicy = errors.New("the connection backoffs, multiplier and connect timeout should be positive, and the max delay should not be less than the base one")
+)
+
+// dialPolicy tunes how the gateway dials the gRPC addr, so that the transient restarts of the gRPC server
+// are retried instead of failing the HTTP requests.
+type dialPolicy struct {
+	retryCodes             []string
+	retryMaxAttempts       int
+	retryInitialBackoff    time.Duration
+	retryMaxBackoff        time.Duration
+	retryBackoffMultiplier float64
+	backoffBaseDelay       time.Duration
+	backoffMaxDelay        time.Duration
+	backoffMultiplier      float64
+	minConnectTimeout      time.Duration
+	waitForReady           bool
+}
+
+type methodConfig struct {
+	RetryPolicy  *retryPolicy `json:"retryPolicy,omitempty"`
+	WaitForReady bool         `json:"waitForReady,omitempty"`
+	Name         []struct{}   `json:"name"`
+}
+
+type retryPolicy struct {
+	InitialBackoff       string   `json:"initialBackoff"`
+	MaxBackoff           string   `json:"maxBackoff"`
+	RetryableStatusCodes []string `json:"retryableStatusCodes"`
+	MaxAttempts          int      `json:"maxAttempts"`
+	BackoffMultiplier    float64  `json:"backoffMultiplier"`
+}
+
+func (d *dialPolicy) validate() error {
+	if d.retryMaxAttempts < 0 || d.retryMaxAttempts > maxRetryAttempts {
+		return errInvalidRetryPolicy
+	}
+	if d.retrying() {
+		if d.retryInitialBackoff <= 0 || d.retryMaxBackoff <= 0 || d.retryBackoffMultiplier <= 0 {
+			return errInvalidRetryPolicy
+		}
+		for _, c := range d.retryCodes {
+			var code codes.Code
+			if err := code.UnmarshalJSON([]byte(fmt.Sprintf("%q", strings.ToUpper(c)))); err != nil {
+				return errors.WithMessagef(errInvalidRetryPolicy, "unknown code %s", c)
+			}
+		}
+	}
+	if d.backoffBaseDelay <= 0 || d.backoffMaxDelay < d.backoffBaseDelay || d.backoffMultiplier <= 0 || d.minConnectTimeout <= 0 {
+		return errInvalidBackoffPolicy
+	}
+	return nil
+}
+
+// retrying returns true if a call is attempted more than once, 0 and 1 attempt disable the retries.
+func (d *dialPolicy) retrying() bool {
+	return d.retryMaxAttempts > 1 && len(d.retryCodes) > 0
+}
+
+// serviceConfig returns the default service config applying the retry policy and the wait-for-ready to all the methods.
+func (d *dialPolicy) serviceConfig() string {
+	mc := methodConfig{
+		Name:         []struct{}{{}},
+		WaitForReady: d.waitForReady,
+	}
+	if d.retrying() {
+		codeNames := make([]string, 0, len(d.retryCodes))
+		for _, c := range d.retryCodes {
+			codeNames = append(codeNames, strings.ToUpper(c))
+		}
+		mc.RetryPolicy = &retryPolicy{
+			MaxAttempts:          d.retryMaxAttempts,
+			InitialBackoff:       durationJSON(d.retryInitialBackoff),
+			MaxBackoff:           durationJSON(d.retryMaxBackoff),
+			BackoffMultiplier:    d.retryBackoffMultiplier,
+			RetryableStatusCodes: codeNames,
+		}
+	}
+	// the config consists of the plain values, which are always encoded
+	data, _ := json.Marshal(map[string][]methodConfig{"methodConfig": {mc}})
+	return string(data)
+}
+
+// options returns the dial options applying the policy.
+func (d *dialPolicy) options() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithDefaultServiceConfig(d.serviceConfig()),
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff: backoff.Config{
+				BaseDelay:  d.backoffBaseDelay,
+				Multiplier: d.backoffMultiplier,
+				Jitter:     backoff.DefaultConfig.Jitter,
+				MaxDelay:   d.backoffMaxDelay,
+			},
+			MinConnectTimeout: d.minConnectTimeout,
+		}),
+	}
+}
+
+// durationJSON encodes the duration as the JSON of google.protobuf.Duration, e.g. "0.1s".
+func durationJSON(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+}
@@ -0,0 +1,120 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package http
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// flakyHealthServer fails the first checks with UNAVAILABLE.
+type flakyHealthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+	calls    atomic.Int32
+	failures int32
+}
+
+func (s *flakyHealthServer) Check(context.Context, *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	if s.calls.Add(1) <= s.failures {
+		return nil, status.Error(codes.Unavailable, "restarting")
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+}
+
+var _ = Describe("Dial", func() {
+	var policy dialPolicy
+	BeforeEach(func() {
+		policy = dialPolicy{
+			retryCodes:             []string{"unavailable"},
+			retryMaxAttempts:       3,
+			retryInitialBackoff:    10 * time.Millisecond,
+			retryMaxBackoff:        100 * time.Millisecond,
+			retryBackoffMultiplier: 2,
+			backoffBaseDelay:       time.Second,
+			backoffMaxDelay:        10 * time.Second,
+			backoffMultiplier:      1.6,
+			minConnectTimeout:      20 * time.Second,
+		}
+	})
+
+	It("validates the policy", func() {
+		Expect(policy.validate()).To(Succeed())
+		policy.retryCodes = []string{"RESTARTING"}
+		Expect(policy.validate()).To(MatchError(errInvalidRetryPolicy))
+		policy.retryMaxAttempts = 1
+		Expect(policy.validate()).To(Succeed())
+		policy.retryMaxAttempts = 6
+		Expect(policy.validate()).To(MatchError(errInvalidRetryPolicy))
+		policy.retryMaxAttempts = 3
+		policy.retryCodes = nil
+		policy.backoffMaxDelay = 100 * time.Millisecond
+		Expect(policy.validate()).To(MatchError(errInvalidBackoffPolicy))
+	})
+
+	It("builds the service config", func() {
+		Expect(policy.serviceConfig()).To(MatchJSON(`{"methodConfig":[{"name":[{}],"retryPolicy":{"maxAttempts":3,` +
+			`"initialBackoff":"0.01s","maxBackoff":"0.1s","backoffMultiplier":2,"retryableStatusCodes":["UNAVAILABLE"]}}]}`))
+		policy.retryMaxAttempts = 0
+		policy.waitForReady = true
+		Expect(policy.serviceConfig()).To(MatchJSON(`{"methodConfig":[{"name":[{}],"waitForReady":true}]}`))
+	})
+
+	Context("calls the flaky server", func() {
+		var health *flakyHealthServer
+		var conn *grpc.ClientConn
+		BeforeEach(func() {
+			lis, err := net.Listen("tcp", "localhost:0")
+			Expect(err).ShouldNot(HaveOccurred())
+			health = &flakyHealthServer{failures: 2}
+			ser := grpc.NewServer()
+			grpc_health_v1.RegisterHealthServer(ser, health)
+			go func() {
+				_ = ser.Serve(lis)
+			}()
+			DeferCleanup(ser.Stop)
+			conn, err = grpc.Dial(lis.Addr().String(),
+				append(policy.options(), grpc.WithTransportCredentials(insecure.NewCredentials()))...)
+			Expect(err).ShouldNot(HaveOccurred())
+			DeferCleanup(conn.Close)
+		})
+
+		It("retries the unavailable calls", func() {
+			resp, err := grpc_health_v1.NewHealthClient(conn).Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resp.GetStatus()).To(Equal(grpc_health_v1.HealthCheckResponse_SERVING))
+			Expect(health.calls.Load()).To(BeNumerically("==", 3))
+		})
+
+		It("fails once the attempts run out", func() {
+			health.failures = 3
+			_, err := grpc_health_v1.NewHealthClient(conn).Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+			Expect(status.Code(err)).To(Equal(codes.Unavailable))
+			Expect(health.calls.Load()).To(BeNumerically("==", 3))
+		})
+	})
+})
@@ -0,0 +1,146 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	common_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	database_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/pkg/convert"
+)
+
+type withMetadata interface {
+	GetMetadata() *common_v1.Metadata
+}
+
+// setETag sets the ETag of a registry read to the revisions of the returned schemas.
+func setETag(_ context.Context, w http.ResponseWriter, resp proto.Message) error {
+	if etag, ok := schemaETag(resp); ok {
+		w.Header().Set("ETag", etag)
+	}
+	return nil
+}
+
+// schemaETag returns the mod_revision of the schema of a Get response,
+// or the hash of the names and revisions of the schemas of a List response,
+// which changes if any schema is created, updated or deleted.
+func schemaETag(resp proto.Message) (string, bool) {
+	m := resp.ProtoReflect()
+	md := m.Descriptor()
+	if md.ParentFile().Package() != database_v1.File_banyandb_database_v1_rpc_proto.Package() {
+		return "", false
+	}
+	name := string(md.Name())
+	isGet := strings.HasSuffix(name, "RegistryServiceGetResponse")
+	if (!isGet && !strings.HasSuffix(name, "RegistryServiceListResponse")) || md.Fields().Len() != 1 {
+		return "", false
+	}
+	fd := md.Fields().Get(0)
+	if fd.Message() == nil {
+		return "", false
+	}
+	if isGet {
+		if !m.Has(fd) {
+			return "", false
+		}
+		s, ok := m.Get(fd).Message().Interface().(withMetadata)
+		if !ok {
+			return "", false
+		}
+		return strconv.Quote(strconv.FormatInt(s.GetMetadata().GetModRevision(), 10)), true
+	}
+	var buf []byte
+	list := m.Get(fd).List()
+	for i := 0; i < list.Len(); i++ {
+		s, ok := list.Get(i).Message().Interface().(withMetadata)
+		if !ok {
+			return "", false
+		}
+		buf = append(buf, s.GetMetadata().GetGroup()...)
+		buf = append(buf, '/')
+		buf = append(buf, s.GetMetadata().GetName()...)
+		buf = append(buf, '@')
+		buf = strconv.AppendInt(buf, s.GetMetadata().GetModRevision(), 10)
+		buf = append(buf, ',')
+	}
+	return strconv.Quote(strconv.FormatUint(convert.Hash(buf), 16)), true
+}
+
+// conditionalGet replies 304 Not Modified to a GET request if its If-None-Match matches the ETag of the response.
+func conditionalGet(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ifNoneMatch := r.Header.Get("If-None-Match")
+		if ifNoneMatch == "" || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(&conditionalResponseWriter{ResponseWriter: w, ifNoneMatch: ifNoneMatch}, r)
+	})
+}
+
+type conditionalResponseWriter struct {
+	http.ResponseWriter
+	ifNoneMatch string
+	wroteHeader bool
+	notModified bool
+}
+
+func (crw *conditionalResponseWriter) WriteHeader(status int) {
+	if crw.wroteHeader {
+		return
+	}
+	crw.wroteHeader = true
+	if status == http.StatusOK && etagMatch(crw.ifNoneMatch, crw.Header().Get("ETag")) {
+		crw.notModified = true
+		crw.Header().Del("Content-Type")
+		crw.Header().Del("Content-Length")
+		status = http.StatusNotModified
+	}
+	crw.ResponseWriter.WriteHeader(status)
+}
+
+func (crw *conditionalResponseWriter) Write(p []byte) (int, error) {
+	if !crw.wroteHeader {
+		crw.WriteHeader(http.StatusOK)
+	}
+	if crw.notModified {
+		return len(p), nil
+	}
+	return crw.ResponseWriter.Write(p)
+}
+
+// etagMatch compares the ETags weakly as RFC 7232 requires for If-None-Match.
+func etagMatch(ifNoneMatch, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	common_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	database_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+)
+
+var _ = Describe("ETag", func() {
+	stream := func(name string, revision int64) *database_v1.Stream {
+		return &database_v1.Stream{Metadata: &common_v1.Metadata{Group: "default", Name: name, ModRevision: revision}}
+	}
+
+	It("surfaces the revision of a schema", func() {
+		etag, ok := schemaETag(&database_v1.StreamRegistryServiceGetResponse{Stream: stream("sw", 10)})
+		Expect(ok).To(BeTrue())
+		Expect(etag).To(Equal(`"10"`))
+		_, ok = schemaETag(&database_v1.StreamRegistryServiceExistResponse{})
+		Expect(ok).To(BeFalse())
+	})
+
+	It("changes the ETag of a list once a schema is modified or deleted", func() {
+		list := func(streams ...*database_v1.Stream) string {
+			etag, ok := schemaETag(&database_v1.StreamRegistryServiceListResponse{Stream: streams})
+			Expect(ok).To(BeTrue())
+			return etag
+		}
+		etag := list(stream("sw", 10), stream("sw2", 11))
+		Expect(list(stream("sw", 10), stream("sw2", 11))).To(Equal(etag))
+		Expect(list(stream("sw", 12), stream("sw2", 11))).NotTo(Equal(etag))
+		Expect(list(stream("sw2", 11))).NotTo(Equal(etag))
+	})
+
+	It("replies not modified if the ETag matches", func() {
+		handler := conditionalGet(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("ETag", `"10"`)
+			_, _ = io.WriteString(w, "{}")
+		}))
+		get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+			r := httptest.NewRequest(http.MethodGet, "/v1/stream/schema/default/sw", nil)
+			if ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", ifNoneMatch)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			return w
+		}
+		w := get(`"9", W/"10"`)
+		Expect(w.Code).To(Equal(http.StatusNotModified))
+		Expect(w.Body.Len()).To(BeZero())
+		Expect(w.Header().Get("ETag")).To(Equal(`"10"`))
+
+		w = get(`"9"`)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(Equal("{}"))
+		Expect(get("").Code).To(Equal(http.StatusOK))
+	})
+})
@@ -0,0 +1,353 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	common_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	database_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measure_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	model_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	stream_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/export"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+// formatParam is the query parameter choosing the format of the exported rows, which is csv by default.
+const formatParam = "format"
+
+// exporter runs the queries posted to the export endpoints, and streams their results as CSV or Parquet.
+// The columns are the timestamp, the element id of a stream, the projected tags and the projected fields of a measure,
+// which are typed by the schema of the queried stream or measure.
+type exporter struct {
+	stream          stream_v1.StreamServiceClient
+	measure         measure_v1.MeasureServiceClient
+	streamRegistry  database_v1.StreamRegistryServiceClient
+	measureRegistry database_v1.MeasureRegistryServiceClient
+	l               *logger.Logger
+}
+
+func newExporter(ctx context.Context, l *logger.Logger, addr string, opts []grpc.DialOption) (*exporter, error) {
+	conn, err := grpc.Dial(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		if cerr := conn.Close(); cerr != nil {
+			l.Info().Str("addr", addr).Err(cerr).Msg("Failed to close conn")
+		}
+	}()
+	return &exporter{
+		stream:          stream_v1.NewStreamServiceClient(conn),
+		measure:         measure_v1.NewMeasureServiceClient(conn),
+		streamRegistry:  database_v1.NewStreamRegistryServiceClient(conn),
+		measureRegistry: database_v1.NewMeasureRegistryServiceClient(conn),
+		l:               l,
+	}, nil
+}
+
+func (e *exporter) exportStream(w http.ResponseWriter, r *http.Request) {
+	req := &stream_v1.QueryRequest{}
+	format, err := parseExportRequest(r, req)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+	ctx := r.Context()
+	resp, err := e.streamRegistry.Get(ctx, &database_v1.StreamRegistryServiceGetRequest{Metadata: req.GetMetadata()})
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+	t, err := newExportTable(resp.GetStream().GetTagFamilies(), req.GetProjection(), nil, nil)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+	columns := append([]export.Column{{Name: "element_id", Type: export.TypeString}}, t.columns...)
+	batches, err := e.stream.QueryBatches(ctx, req)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+	e.export(w, format, req.GetMetadata(), columns, func() ([][]interface{}, error) {
+		batch, err := batches.Recv()
+		if err != nil {
+			return nil, err
+		}
+		rows := make([][]interface{}, 0, len(batch.GetElements()))
+		for _, element := range batch.GetElements() {
+			rows = append(rows, append([]interface{}{element.GetElementId()},
+				t.row(element.GetTimestamp().AsTime(), element.GetTagFamilies(), nil)...))
+		}
+		return rows, nil
+	})
+}
+
+func (e *exporter) exportMeasure(w http.ResponseWriter, r *http.Request) {
+	req := &measure_v1.QueryRequest{}
+	format, err := parseExportRequest(r, req)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+	ctx := r.Context()
+	resp, err := e.measureRegistry.Get(ctx, &database_v1.MeasureRegistryServiceGetRequest{Metadata: req.GetMetadata()})
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+	t, err := newExportTable(resp.GetMeasure().GetTagFamilies(), req.GetTagProjection(),
+		resp.GetMeasure().GetFields(), req.GetFieldProjection().GetNames())
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+	batches, err := e.measure.QueryBatches(ctx, req)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+	e.export(w, format, req.GetMetadata(), t.columns, func() ([][]interface{}, error) {
+		batch, err := batches.Recv()
+		if err != nil {
+			return nil, err
+		}
+		rows := make([][]interface{}, 0, len(batch.GetDataPoints()))
+		for _, dp := range batch.GetDataPoints() {
+			rows = append(rows, t.row(dp.GetTimestamp().AsTime(), dp.GetTagFamilies(), dp.GetFields()))
+		}
+		return rows, nil
+	})
+}
+
+// export writes the rows of the batches returned by next until it returns io.EOF.
+// The first batch is received before the response is written, so that the failures of a query are replied by their status.
+func (e *exporter) export(w http.ResponseWriter, format export.Format, md *common_v1.Metadata,
+	columns []export.Column, next func() ([][]interface{}, error),
+) {
+	rows, err := next()
+	if err != nil && err != io.EOF {
+		writeExportError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", md.GetName()+"."+string(format)))
+	writer, err := export.NewWriter(format, w, columns)
+	if err == nil {
+		for err == nil {
+			for _, row := range rows {
+				if err = writer.Write(row); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				rows, err = next()
+			}
+		}
+		if err == io.EOF {
+			err = writer.Close()
+		}
+	}
+	if err != nil {
+		e.l.Error().Err(err).Str("group", md.GetGroup()).Str("name", md.GetName()).Msg("fail to export the query results")
+		// aborts the response to tell the client that it's incomplete, since the status was sent
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func parseExportRequest(r *http.Request, req proto.Message) (export.Format, error) {
+	format := export.Format(r.URL.Query().Get(formatParam))
+	if format == "" {
+		format = export.FormatCSV
+	}
+	if format != export.FormatCSV && format != export.FormatParquet {
+		return "", status.Errorf(codes.InvalidArgument, "the format %q is not one of [%s %s]", format, export.FormatCSV, export.FormatParquet)
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err = (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(body, req); err != nil {
+		return "", status.Errorf(codes.InvalidArgument, "the query is invalid: %v", err)
+	}
+	return format, nil
+}
+
+func writeExportError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	http.Error(w, st.Message(), runtime.HTTPStatusFromCode(st.Code()))
+}
+
+// exportTable maps the tags and the fields of the query results to the columns.
+type exportTable struct {
+	tags    map[string]int
+	fields  map[string]int
+	columns []export.Column
+}
+
+// newExportTable returns the table of the timestamp, the projected tags and the projected fields, whose types are looked up in the schema.
+// A column is named by its tag or field, or by the family and the tag if the name is taken.
+func newExportTable(families []*database_v1.TagFamilySpec, tagProjection *model_v1.TagProjection,
+	fields []*database_v1.FieldSpec, fieldProjection []string,
+) (*exportTable, error) {
+	t := &exportTable{
+		tags:    make(map[string]int),
+		fields:  make(map[string]int),
+		columns: []export.Column{{Name: "timestamp", Type: export.TypeTimestamp}},
+	}
+	names := map[string]bool{"timestamp": true, "element_id": true}
+	for _, pf := range tagProjection.GetTagFamilies() {
+		for _, tag := range pf.GetTags() {
+			spec := findTagSpec(families, pf.GetName(), tag)
+			if spec == nil {
+				return nil, status.Errorf(codes.InvalidArgument, "the tag %s of the family %s is not found", tag, pf.GetName())
+			}
+			name := tag
+			if names[name] {
+				name = pf.GetName() + "." + tag
+			}
+			names[name] = true
+			t.tags[tagKey(pf.GetName(), tag)] = len(t.columns)
+			t.columns = append(t.columns, export.Column{Name: name, Type: tagColumnType(spec.GetType())})
+		}
+	}
+	for _, field := range fieldProjection {
+		var spec *database_v1.FieldSpec
+		for _, f := range fields {
+			if f.GetName() == field {
+				spec = f
+				break
+			}
+		}
+		if spec == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "the field %s is not found", field)
+		}
+		name := field
+		if names[name] {
+			name = "fields." + field
+		}
+		names[name] = true
+		t.fields[field] = len(t.columns)
+		t.columns = append(t.columns, export.Column{Name: name, Type: fieldColumnType(spec.GetFieldType())})
+	}
+	return t, nil
+}
+
+func (t *exportTable) row(ts time.Time, families []*model_v1.TagFamily, fields []*measure_v1.DataPoint_Field) []interface{} {
+	row := make([]interface{}, len(t.columns))
+	row[0] = ts
+	for _, f := range families {
+		for _, tag := range f.GetTags() {
+			if i, ok := t.tags[tagKey(f.GetName(), tag.GetKey())]; ok {
+				row[i] = tagValue(tag.GetValue())
+			}
+		}
+	}
+	for _, f := range fields {
+		if i, ok := t.fields[f.GetName()]; ok {
+			row[i] = fieldValue(f.GetValue())
+		}
+	}
+	return row
+}
+
+func tagKey(family, tag string) string {
+	return family + ":" + tag
+}
+
+func findTagSpec(families []*database_v1.TagFamilySpec, family, tag string) *database_v1.TagSpec {
+	for _, f := range families {
+		if f.GetName() != family {
+			continue
+		}
+		for _, spec := range f.GetTags() {
+			if spec.GetName() == tag {
+				return spec
+			}
+		}
+	}
+	return nil
+}
+
+func tagColumnType(t database_v1.TagType) export.Type {
+	switch t {
+	case database_v1.TagType_TAG_TYPE_INT:
+		return export.TypeInt
+	case database_v1.TagType_TAG_TYPE_STRING_ARRAY:
+		return export.TypeStringArray
+	case database_v1.TagType_TAG_TYPE_INT_ARRAY:
+		return export.TypeIntArray
+	case database_v1.TagType_TAG_TYPE_DATA_BINARY:
+		return export.TypeBinary
+	}
+	return export.TypeString
+}
+
+func fieldColumnType(t database_v1.FieldType) export.Type {
+	switch t {
+	case database_v1.FieldType_FIELD_TYPE_INT:
+		return export.TypeInt
+	case database_v1.FieldType_FIELD_TYPE_DATA_BINARY:
+		return export.TypeBinary
+	}
+	return export.TypeString
+}
+
+func tagValue(v *model_v1.TagValue) interface{} {
+	switch val := v.GetValue().(type) {
+	case *model_v1.TagValue_Str:
+		return val.Str.GetValue()
+	case *model_v1.TagValue_Int:
+		return val.Int.GetValue()
+	case *model_v1.TagValue_StrArray:
+		return val.StrArray.GetValue()
+	case *model_v1.TagValue_IntArray:
+		return val.IntArray.GetValue()
+	case *model_v1.TagValue_BinaryData:
+		return val.BinaryData
+	case *model_v1.TagValue_Id:
+		return val.Id.GetValue()
+	}
+	return nil
+}
+
+func fieldValue(v *model_v1.FieldValue) interface{} {
+	switch val := v.GetValue().(type) {
+	case *model_v1.FieldValue_Str:
+		return val.Str.GetValue()
+	case *model_v1.FieldValue_Int:
+		return val.Int.GetValue()
+	case *model_v1.FieldValue_BinaryData:
+		return val.BinaryData
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	database_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measure_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	model_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	stream_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+type fakeStreamRegistry struct {
+	database_v1.StreamRegistryServiceClient
+	stream *database_v1.Stream
+}
+
+func (f *fakeStreamRegistry) Get(context.Context, *database_v1.StreamRegistryServiceGetRequest,
+	...grpc.CallOption,
+) (*database_v1.StreamRegistryServiceGetResponse, error) {
+	if f.stream == nil {
+		return nil, status.Error(codes.NotFound, "the stream is not found")
+	}
+	return &database_v1.StreamRegistryServiceGetResponse{Stream: f.stream}, nil
+}
+
+type fakeMeasureRegistry struct {
+	database_v1.MeasureRegistryServiceClient
+	measure *database_v1.Measure
+}
+
+func (f *fakeMeasureRegistry) Get(context.Context, *database_v1.MeasureRegistryServiceGetRequest,
+	...grpc.CallOption,
+) (*database_v1.MeasureRegistryServiceGetResponse, error) {
+	if f.measure == nil {
+		return nil, status.Error(codes.NotFound, "the measure is not found")
+	}
+	return &database_v1.MeasureRegistryServiceGetResponse{Measure: f.measure}, nil
+}
+
+type fakeStreamService struct {
+	stream_v1.StreamServiceClient
+	batches []*stream_v1.QueryResponse
+}
+
+func (f *fakeStreamService) QueryBatches(context.Context, *stream_v1.QueryRequest,
+	...grpc.CallOption,
+) (stream_v1.StreamService_QueryBatchesClient, error) {
+	return &fakeStreamBatches{batches: f.batches}, nil
+}
+
+type fakeStreamBatches struct {
+	grpc.ClientStream
+	batches []*stream_v1.QueryResponse
+}
+
+func (f *fakeStreamBatches) Recv() (*stream_v1.QueryResponse, error) {
+	if len(f.batches) == 0 {
+		return nil, io.EOF
+	}
+	batch := f.batches[0]
+	f.batches = f.batches[1:]
+	return batch, nil
+}
+
+type fakeMeasureService struct {
+	measure_v1.MeasureServiceClient
+	req     *measure_v1.QueryRequest
+	batches []*measure_v1.QueryResponse
+}
+
+func (f *fakeMeasureService) QueryBatches(_ context.Context, req *measure_v1.QueryRequest,
+	_ ...grpc.CallOption,
+) (measure_v1.MeasureService_QueryBatchesClient, error) {
+	f.req = req
+	return &fakeMeasureBatches{batches: f.batches}, nil
+}
+
+type fakeMeasureBatches struct {
+	grpc.ClientStream
+	batches []*measure_v1.QueryResponse
+}
+
+func (f *fakeMeasureBatches) Recv() (*measure_v1.QueryResponse, error) {
+	if len(f.batches) == 0 {
+		return nil, io.EOF
+	}
+	batch := f.batches[0]
+	f.batches = f.batches[1:]
+	return batch, nil
+}
+
+var _ = Describe("Export", func() {
+	ts := timestamppb.New(time.UnixMilli(1000))
+	str := func(v string) *model_v1.TagValue {
+		return &model_v1.TagValue{Value: &model_v1.TagValue_Str{Str: &model_v1.Str{Value: v}}}
+	}
+	families := []*database_v1.TagFamilySpec{{
+		Name: "searchable",
+		Tags: []*database_v1.TagSpec{
+			{Name: "endpoint", Type: database_v1.TagType_TAG_TYPE_STRING},
+			{Name: "status", Type: database_v1.TagType_TAG_TYPE_INT},
+			{Name: "labels", Type: database_v1.TagType_TAG_TYPE_STRING_ARRAY},
+		},
+	}}
+	var e *exporter
+	BeforeEach(func() {
+		e = &exporter{
+			streamRegistry: &fakeStreamRegistry{stream: &database_v1.Stream{TagFamilies: families}},
+			measureRegistry: &fakeMeasureRegistry{measure: &database_v1.Measure{
+				TagFamilies: families,
+				Fields:      []*database_v1.FieldSpec{{Name: "total", FieldType: database_v1.FieldType_FIELD_TYPE_INT}},
+			}},
+			stream: &fakeStreamService{batches: []*stream_v1.QueryResponse{
+				{Elements: []*stream_v1.Element{{
+					ElementId: "1",
+					Timestamp: ts,
+					TagFamilies: []*model_v1.TagFamily{{Name: "searchable", Tags: []*model_v1.Tag{
+						{Key: "endpoint", Value: str("/home")},
+						{Key: "labels", Value: &model_v1.TagValue{Value: &model_v1.TagValue_StrArray{
+							StrArray: &model_v1.StrArray{Value: []string{"a", "b"}},
+						}}},
+					}}},
+				}}},
+				{Elements: []*stream_v1.Element{{
+					ElementId: "2",
+					Timestamp: ts,
+					TagFamilies: []*model_v1.TagFamily{{Name: "searchable", Tags: []*model_v1.Tag{
+						{Key: "endpoint", Value: &model_v1.TagValue{Value: &model_v1.TagValue_Null{}}},
+						{Key: "status", Value: &model_v1.TagValue{Value: &model_v1.TagValue_Int{Int: &model_v1.Int{Value: 500}}}},
+					}}},
+				}}},
+			}},
+			measure: &fakeMeasureService{batches: []*measure_v1.QueryResponse{{DataPoints: []*measure_v1.DataPoint{{
+				Timestamp: ts,
+				TagFamilies: []*model_v1.TagFamily{{Name: "searchable", Tags: []*model_v1.Tag{
+					{Key: "endpoint", Value: str("/home")},
+				}}},
+				Fields: []*measure_v1.DataPoint_Field{{
+					Name:  "total",
+					Value: &model_v1.FieldValue{Value: &model_v1.FieldValue_Int{Int: &model_v1.Int{Value: 100}}},
+				}},
+			}}}}},
+			l: logger.GetLogger("test"),
+		}
+	})
+	post := func(handler http.HandlerFunc, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, target, strings.NewReader(body)))
+		return rec
+	}
+
+	It("exports the elements of a stream as CSV", func() {
+		rec := post(e.exportStream, "/api/v1/stream/export", `{"metadata":{"group":"default","name":"sw"},`+
+			`"projection":{"tagFamilies":[{"name":"searchable","tags":["endpoint","status","labels"]}]}}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(HavePrefix("text/csv"))
+		Expect(rec.Header().Get("Content-Disposition")).To(Equal(`attachment; filename="sw.csv"`))
+		Expect(rec.Body.String()).To(Equal("element_id,timestamp,endpoint,status,labels\n" +
+			`1,1970-01-01T00:00:01Z,/home,,"[""a"",""b""]"` + "\n" +
+			"2,1970-01-01T00:00:01Z,,500,\n"))
+	})
+
+	It("exports the data points of a measure as Parquet", func() {
+		rec := post(e.exportMeasure, "/api/v1/measure/export?format=parquet", `{"metadata":{"group":"default","name":"cpm"},`+
+			`"tagProjection":{"tagFamilies":[{"name":"searchable","tags":["endpoint"]}]},"fieldProjection":{"names":["total"]}}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal("application/vnd.apache.parquet"))
+		Expect(rec.Body.Bytes()).To(HavePrefix("PAR1"))
+		Expect(rec.Body.Bytes()).To(HaveSuffix("PAR1"))
+	})
+
+	It("exports the data points of a measure as CSV", func() {
+		rec := post(e.exportMeasure, "/api/v1/measure/export", `{"metadata":{"group":"default","name":"cpm"},`+
+			`"tagProjection":{"tagFamilies":[{"name":"searchable","tags":["endpoint"]}]},"fieldProjection":{"names":["total"]}}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(Equal("timestamp,endpoint,total\n1970-01-01T00:00:01Z,/home,100\n"))
+	})
+
+	It("rejects the invalid requests", func() {
+		rec := post(e.exportStream, "/api/v1/stream/export?format=json", `{}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		rec = post(e.exportStream, "/api/v1/stream/export", `{"metadata":{"group":"default","name":"sw"},`+
+			`"projection":{"tagFamilies":[{"name":"searchable","tags":["unknown"]}]}}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		e.streamRegistry = &fakeStreamRegistry{}
+		rec = post(e.exportStream, "/api/v1/stream/export", `{"metadata":{"group":"default","name":"sw"}}`)
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+	})
+})
@@ -0,0 +1,245 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	measure_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	stream_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+)
+
+// fieldsParam is the query parameter selecting the fields of a query response, e.g. fields=elements.element_id,elements.timestamp
+const fieldsParam = "fields"
+
+type fieldsKey struct{}
+
+// jsonMarshaler is the JSON marshaler of the gateway.
+// It encodes the 64-bit integers as numbers instead of strings if int64AsNumber is true.
+type jsonMarshaler struct {
+	runtime.JSONPb
+	int64AsNumber bool
+}
+
+func newJSONMarshaler(emitDefaults, int64AsNumber bool) runtime.Marshaler {
+	return &runtime.HTTPBodyMarshaler{
+		Marshaler: &jsonMarshaler{
+			JSONPb: runtime.JSONPb{
+				MarshalOptions: protojson.MarshalOptions{
+					EmitUnpopulated: emitDefaults,
+				},
+				UnmarshalOptions: protojson.UnmarshalOptions{
+					DiscardUnknown: true,
+				},
+			},
+			int64AsNumber: int64AsNumber,
+		},
+	}
+}
+
+func (m *jsonMarshaler) Marshal(v interface{}) ([]byte, error) {
+	data, err := m.JSONPb.Marshal(v)
+	if err != nil || !m.int64AsNumber {
+		return data, err
+	}
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return data, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var obj interface{}
+	if err = dec.Decode(&obj); err != nil {
+		return nil, err
+	}
+	return json.Marshal(int64ToNumber(msg.ProtoReflect().Descriptor(), obj))
+}
+
+func (m *jsonMarshaler) NewEncoder(w io.Writer) runtime.Encoder {
+	if !m.int64AsNumber {
+		return m.JSONPb.NewEncoder(w)
+	}
+	return runtime.EncoderFunc(func(v interface{}) error {
+		data, err := m.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if _, err = w.Write(data); err != nil {
+			return err
+		}
+		_, err = w.Write(m.Delimiter())
+		return err
+	})
+}
+
+// int64ToNumber replaces the strings of the 64-bit integer fields in the JSON object of the message md.
+func int64ToNumber(md protoreflect.MessageDescriptor, obj interface{}) interface{} {
+	fields, ok := obj.(map[string]interface{})
+	// the well-known types, e.g. Timestamp, have their own JSON formats
+	if !ok || md.FullName().Parent() == "google.protobuf" {
+		return obj
+	}
+	for name, val := range fields {
+		fd := md.Fields().ByJSONName(name)
+		if fd == nil {
+			fd = md.Fields().ByName(protoreflect.Name(name))
+		}
+		if fd == nil {
+			continue
+		}
+		if fd.IsMap() {
+			if entries, ok := val.(map[string]interface{}); ok {
+				for k, v := range entries {
+					entries[k] = int64ValueToNumber(fd.MapValue(), v)
+				}
+			}
+			continue
+		}
+		if fd.IsList() {
+			if items, ok := val.([]interface{}); ok {
+				for i, v := range items {
+					items[i] = int64ValueToNumber(fd, v)
+				}
+			}
+			continue
+		}
+		fields[name] = int64ValueToNumber(fd, val)
+	}
+	return fields
+}
+
+func int64ValueToNumber(fd protoreflect.FieldDescriptor, val interface{}) interface{} {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return int64ToNumber(fd.Message(), val)
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if s, ok := val.(string); ok {
+			return json.Number(s)
+		}
+	}
+	return val
+}
+
+// withFields moves the fields parameter to the context of the request, which is read by pruneFields.
+func withFields(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		fields := query.Get(fieldsParam)
+		if fields == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		query.Del(fieldsParam)
+		r.URL.RawQuery = query.Encode()
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), fieldsKey{}, strings.Split(fields, ","))))
+	})
+}
+
+// pruneFields clears the fields of a query response which aren't selected by the fields parameter.
+func pruneFields(ctx context.Context, _ http.ResponseWriter, resp proto.Message) error {
+	paths, ok := ctx.Value(fieldsKey{}).([]string)
+	if !ok {
+		return nil
+	}
+	switch resp.(type) {
+	case *stream_v1.QueryResponse, *measure_v1.QueryResponse, *measure_v1.TopNResponse:
+	default:
+		return nil
+	}
+	mask, err := newFieldMask(resp.ProtoReflect().Descriptor(), paths)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	mask.prune(resp.ProtoReflect())
+	return nil
+}
+
+// fieldMask is a tree of the selected fields. A leaf selects the whole field.
+type fieldMask map[protoreflect.Name]fieldMask
+
+func newFieldMask(md protoreflect.MessageDescriptor, paths []string) (fieldMask, error) {
+	mask := fieldMask{}
+	for _, path := range paths {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		node, desc := mask, md
+		for _, name := range strings.Split(path, ".") {
+			if desc == nil {
+				return nil, errors.Errorf("the field %s isn't a message", path)
+			}
+			fd := desc.Fields().ByName(protoreflect.Name(name))
+			if fd == nil {
+				fd = desc.Fields().ByJSONName(name)
+			}
+			if fd == nil {
+				return nil, errors.Errorf("unknown field %s of %s", name, desc.FullName())
+			}
+			child, ok := node[fd.Name()]
+			if !ok {
+				child = fieldMask{}
+				node[fd.Name()] = child
+			}
+			node, desc = child, fd.Message()
+			if fd.IsMap() {
+				desc = fd.MapValue().Message()
+			}
+		}
+	}
+	return mask, nil
+}
+
+func (fm fieldMask) prune(m protoreflect.Message) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		child, ok := fm[fd.Name()]
+		switch {
+		case !ok:
+			m.Clear(fd)
+		case len(child) == 0:
+		case fd.IsMap():
+			if fd.MapValue().Message() != nil {
+				v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+					child.prune(mv.Message())
+					return true
+				})
+			}
+		case fd.IsList():
+			for i := 0; i < v.List().Len(); i++ {
+				child.prune(v.List().Get(i).Message())
+			}
+		case fd.Message() != nil:
+			child.prune(v.Message())
+		}
+		return true
+	})
+}
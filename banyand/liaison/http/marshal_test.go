@@ -0,0 +1,98 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	measure_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	model_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+)
+
+var _ = Describe("Marshal", func() {
+	var resp *measure_v1.QueryResponse
+	BeforeEach(func() {
+		resp = &measure_v1.QueryResponse{
+			DataPoints: []*measure_v1.DataPoint{{
+				Timestamp: timestamppb.New(time.Unix(1, 0).UTC()),
+				TagFamilies: []*model_v1.TagFamily{{
+					Name: "default",
+					Tags: []*model_v1.Tag{{
+						Key:   "id",
+						Value: &model_v1.TagValue{Value: &model_v1.TagValue_IntArray{IntArray: &model_v1.IntArray{Value: []int64{1, 2}}}},
+					}},
+				}},
+				Fields: []*measure_v1.DataPoint_Field{{
+					Name:  "total",
+					Value: &model_v1.FieldValue{Value: &model_v1.FieldValue_Int{Int: &model_v1.Int{Value: 100}}},
+				}},
+			}},
+		}
+	})
+
+	It("encodes int64 as strings by default", func() {
+		data, err := newJSONMarshaler(false, false).Marshal(resp)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(data).To(MatchJSON(`{"dataPoints":[{"timestamp":"1970-01-01T00:00:01Z",` +
+			`"tagFamilies":[{"name":"default","tags":[{"key":"id","value":{"intArray":{"value":["1","2"]}}}]}],` +
+			`"fields":[{"name":"total","value":{"int":{"value":"100"}}}]}]}`))
+	})
+
+	It("encodes int64 as numbers", func() {
+		data, err := newJSONMarshaler(false, true).Marshal(resp)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(data).To(MatchJSON(`{"dataPoints":[{"timestamp":"1970-01-01T00:00:01Z",` +
+			`"tagFamilies":[{"name":"default","tags":[{"key":"id","value":{"intArray":{"value":[1,2]}}}]}],` +
+			`"fields":[{"name":"total","value":{"int":{"value":100}}}]}]}`))
+	})
+
+	It("emits the default values", func() {
+		data, err := newJSONMarshaler(true, false).Marshal(&measure_v1.QueryResponse{})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(data).To(MatchJSON(`{"dataPoints":[],"readTimestamp":null,"truncation":null,"snapshotToken":""}`))
+	})
+
+	It("selects the fields of a query response", func() {
+		var ctx context.Context
+		next := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			ctx = r.Context()
+			Expect(r.URL.Query().Has(fieldsParam)).To(BeFalse())
+		})
+		withFields(next).ServeHTTP(httptest.NewRecorder(),
+			httptest.NewRequest(http.MethodPost, "/v1/measure/data?fields=data_points.timestamp,dataPoints.fields.value", nil))
+		Expect(pruneFields(ctx, nil, resp)).To(Succeed())
+		data, err := newJSONMarshaler(false, false).Marshal(resp)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(data).To(MatchJSON(`{"dataPoints":[{"timestamp":"1970-01-01T00:00:01Z","fields":[{"value":{"int":{"value":"100"}}}]}]}`))
+	})
+
+	It("rejects unknown fields", func() {
+		ctx := context.WithValue(context.Background(), fieldsKey{}, []string{"data_points.unknown"})
+		err := pruneFields(ctx, nil, resp)
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+})
@@ -0,0 +1,508 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	common_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	database_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measure_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	model_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/promql"
+)
+
+const (
+	// prometheusPrefix is the path of the Prometheus API, which is the URL of a Prometheus datasource of Grafana.
+	prometheusPrefix = "/prometheus"
+	// defaultLookback is the lookback delta of the queries, which is the same as Prometheus.
+	defaultLookback = 5 * time.Minute
+	// metricNameSeparator separates the group, the measure and the field of a metric name.
+	metricNameSeparator = ":"
+)
+
+// prometheusAPI serves the read-only subset of the Prometheus HTTP API on the measures. A metric is named by
+// `group:measure:field`, or `group:measure` if the measure has a single field, and labeled by the tags of the measure.
+// The series selected by the queries are read by the measure queries, and evaluated by the PromQL subset of pkg/promql.
+type prometheusAPI struct {
+	measure         measure_v1.MeasureServiceClient
+	measureRegistry database_v1.MeasureRegistryServiceClient
+	groupRegistry   database_v1.GroupRegistryServiceClient
+	l               *logger.Logger
+}
+
+func newPrometheusAPI(ctx context.Context, l *logger.Logger, addr string, opts []grpc.DialOption) (*prometheusAPI, error) {
+	conn, err := grpc.Dial(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		if cerr := conn.Close(); cerr != nil {
+			l.Info().Str("addr", addr).Err(cerr).Msg("Failed to close conn")
+		}
+	}()
+	return &prometheusAPI{
+		measure:         measure_v1.NewMeasureServiceClient(conn),
+		measureRegistry: database_v1.NewMeasureRegistryServiceClient(conn),
+		groupRegistry:   database_v1.NewGroupRegistryServiceClient(conn),
+		l:               l,
+	}, nil
+}
+
+func (p *prometheusAPI) routes() http.Handler {
+	r := chi.NewRouter()
+	r.Get("/api/v1/query", p.query)
+	r.Post("/api/v1/query", p.query)
+	r.Get("/api/v1/query_range", p.queryRange)
+	r.Post("/api/v1/query_range", p.queryRange)
+	r.Get("/api/v1/labels", p.labels)
+	r.Post("/api/v1/labels", p.labels)
+	r.Get("/api/v1/label/{name}/values", p.labelValues)
+	return r
+}
+
+type prometheusResponse struct {
+	Data      interface{} `json:"data,omitempty"`
+	Status    string      `json:"status"`
+	ErrorType string      `json:"errorType,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+type prometheusResult struct {
+	Result     []prometheusSeries `json:"result"`
+	ResultType string             `json:"resultType"`
+}
+
+type prometheusSeries struct {
+	Metric map[string]string `json:"metric"`
+	Value  []interface{}     `json:"value,omitempty"`
+	Values [][]interface{}   `json:"values,omitempty"`
+}
+
+func (p *prometheusAPI) query(w http.ResponseWriter, r *http.Request) {
+	e, err := parsePromQL(r)
+	if err != nil {
+		writePrometheusError(w, err)
+		return
+	}
+	ts := time.Now().UnixMilli()
+	if v := r.Form.Get("time"); v != "" {
+		if ts, err = parsePrometheusTime(v); err != nil {
+			writePrometheusError(w, err)
+			return
+		}
+	}
+	rng, err := parseLookback(r, promql.Range{Start: ts, End: ts, Step: 1})
+	if err != nil {
+		writePrometheusError(w, err)
+		return
+	}
+	result, err := promql.Eval(e, rng, p.fetch(r.Context()))
+	if err != nil {
+		writePrometheusError(w, err)
+		return
+	}
+	vector := make([]prometheusSeries, 0, len(result))
+	for _, s := range result {
+		vector = append(vector, prometheusSeries{Metric: s.Labels, Value: prometheusSample(s.Samples[0])})
+	}
+	writePrometheusData(w, prometheusResult{ResultType: "vector", Result: vector})
+}
+
+func (p *prometheusAPI) queryRange(w http.ResponseWriter, r *http.Request) {
+	e, err := parsePromQL(r)
+	if err != nil {
+		writePrometheusError(w, err)
+		return
+	}
+	var rng promql.Range
+	if rng.Start, err = parsePrometheusTime(r.Form.Get("start")); err != nil {
+		writePrometheusError(w, err)
+		return
+	}
+	if rng.End, err = parsePrometheusTime(r.Form.Get("end")); err != nil {
+		writePrometheusError(w, err)
+		return
+	}
+	step, err := parsePrometheusDuration(r.Form.Get("step"))
+	if err != nil {
+		writePrometheusError(w, err)
+		return
+	}
+	rng.Step = step.Milliseconds()
+	if rng, err = parseLookback(r, rng); err != nil {
+		writePrometheusError(w, err)
+		return
+	}
+	result, err := promql.Eval(e, rng, p.fetch(r.Context()))
+	if err != nil {
+		writePrometheusError(w, err)
+		return
+	}
+	matrix := make([]prometheusSeries, 0, len(result))
+	for _, s := range result {
+		values := make([][]interface{}, 0, len(s.Samples))
+		for _, sample := range s.Samples {
+			values = append(values, prometheusSample(sample))
+		}
+		matrix = append(matrix, prometheusSeries{Metric: s.Labels, Values: values})
+	}
+	writePrometheusData(w, prometheusResult{ResultType: "matrix", Result: matrix})
+}
+
+// labels returns the metric name label and the tags of all the measures.
+func (p *prometheusAPI) labels(w http.ResponseWriter, r *http.Request) {
+	names := map[string]bool{promql.MetricNameLabel: true}
+	err := p.walkMeasures(r.Context(), func(m *database_v1.Measure) {
+		for _, f := range m.GetTagFamilies() {
+			for _, t := range f.GetTags() {
+				if !t.GetIndexedOnly() {
+					names[t.GetName()] = true
+				}
+			}
+		}
+	})
+	if err != nil {
+		writePrometheusError(w, err)
+		return
+	}
+	writePrometheusData(w, sortedKeys(names))
+}
+
+// labelValues returns the metric names of all the numeric fields. The values of the other labels are not listed,
+// since they're the tag values of the data rather than the schemas.
+func (p *prometheusAPI) labelValues(w http.ResponseWriter, r *http.Request) {
+	values := make(map[string]bool)
+	if chi.URLParam(r, "name") == promql.MetricNameLabel {
+		err := p.walkMeasures(r.Context(), func(m *database_v1.Measure) {
+			for _, f := range m.GetFields() {
+				if f.GetFieldType() == database_v1.FieldType_FIELD_TYPE_INT {
+					values[strings.Join([]string{m.GetMetadata().GetGroup(), m.GetMetadata().GetName(), f.GetName()}, metricNameSeparator)] = true
+				}
+			}
+		})
+		if err != nil {
+			writePrometheusError(w, err)
+			return
+		}
+	}
+	writePrometheusData(w, sortedKeys(values))
+}
+
+func (p *prometheusAPI) walkMeasures(ctx context.Context, fn func(m *database_v1.Measure)) error {
+	groups, err := p.groupRegistry.List(ctx, &database_v1.GroupRegistryServiceListRequest{})
+	if err != nil {
+		return err
+	}
+	for _, g := range groups.GetGroup() {
+		if g.GetCatalog() != common_v1.Catalog_CATALOG_MEASURE {
+			continue
+		}
+		measures, err := p.measureRegistry.List(ctx, &database_v1.MeasureRegistryServiceListRequest{Group: g.GetMetadata().GetName()})
+		if err != nil {
+			return err
+		}
+		for _, m := range measures.GetMeasure() {
+			fn(m)
+		}
+	}
+	return nil
+}
+
+// fetch returns the fetcher querying the data points of the measure named by a selector.
+// The equality matchers of the entity tags are pushed down to select the series, and the others are evaluated on the results.
+func (p *prometheusAPI) fetch(ctx context.Context) promql.Fetcher {
+	return func(s *promql.VectorSelector, start, end int64) ([]promql.Series, error) {
+		parts := strings.Split(s.Name, metricNameSeparator)
+		if len(parts) != 2 && len(parts) != 3 {
+			return nil, status.Errorf(codes.InvalidArgument, "the metric %s is not named by group:measure:field", s.Name)
+		}
+		md := &common_v1.Metadata{Group: parts[0], Name: parts[1]}
+		resp, err := p.measureRegistry.Get(ctx, &database_v1.MeasureRegistryServiceGetRequest{Metadata: md})
+		if status.Code(err) == codes.NotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		m := resp.GetMeasure()
+		field, err := metricField(m, parts)
+		if err != nil {
+			return nil, err
+		}
+		req := &measure_v1.QueryRequest{
+			Metadata: md,
+			TimeRange: &model_v1.TimeRange{
+				Begin: timestamppb.New(time.UnixMilli(start)),
+				// the end is exclusive
+				End: timestamppb.New(time.UnixMilli(end + 1)),
+			},
+			Criteria:        entityCriteria(m, s.Matchers),
+			TagProjection:   &model_v1.TagProjection{},
+			FieldProjection: &measure_v1.QueryRequest_FieldProjection{Names: []string{field}},
+			Limit:           math.MaxUint32,
+		}
+		for _, f := range m.GetTagFamilies() {
+			pf := &model_v1.TagProjection_TagFamily{Name: f.GetName()}
+			for _, t := range f.GetTags() {
+				if !t.GetIndexedOnly() {
+					pf.Tags = append(pf.Tags, t.GetName())
+				}
+			}
+			if len(pf.Tags) > 0 {
+				req.TagProjection.TagFamilies = append(req.TagProjection.TagFamilies, pf)
+			}
+		}
+		batches, err := p.measure.QueryBatches(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		var result []promql.Series
+		for {
+			batch, err := batches.Recv()
+			if err == io.EOF {
+				return result, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			for _, dp := range batch.GetDataPoints() {
+				var v *model_v1.FieldValue
+				for _, f := range dp.GetFields() {
+					if f.GetName() == field {
+						v = f.GetValue()
+					}
+				}
+				if _, ok := v.GetValue().(*model_v1.FieldValue_Int); !ok {
+					continue
+				}
+				labels := map[string]string{promql.MetricNameLabel: s.Name}
+				for _, f := range dp.GetTagFamilies() {
+					for _, t := range f.GetTags() {
+						if lv, ok := labelValue(t.GetValue()); ok {
+							if _, taken := labels[t.GetKey()]; !taken {
+								labels[t.GetKey()] = lv
+							}
+						}
+					}
+				}
+				result = append(result, promql.Series{
+					Labels:  labels,
+					Samples: []promql.Sample{{T: dp.GetTimestamp().AsTime().UnixMilli(), V: float64(v.GetInt().GetValue())}},
+				})
+			}
+		}
+	}
+}
+
+// metricField returns the numeric field named by the metric, which may omit the field if the measure has a single one.
+func metricField(m *database_v1.Measure, parts []string) (string, error) {
+	if len(parts) == 2 {
+		if len(m.GetFields()) != 1 {
+			return "", status.Errorf(codes.InvalidArgument, "the measure %s has %d fields, which should be named by group:measure:field",
+				m.GetMetadata().GetName(), len(m.GetFields()))
+		}
+		parts = append(parts, m.GetFields()[0].GetName())
+	}
+	for _, f := range m.GetFields() {
+		if f.GetName() != parts[2] {
+			continue
+		}
+		if f.GetFieldType() != database_v1.FieldType_FIELD_TYPE_INT {
+			return "", status.Errorf(codes.InvalidArgument, "the field %s is not numeric", f.GetName())
+		}
+		return f.GetName(), nil
+	}
+	return "", status.Errorf(codes.InvalidArgument, "the field %s is not found", parts[2])
+}
+
+// entityCriteria returns the conditions of the equality matchers of the entity tags, which are ANDed.
+func entityCriteria(m *database_v1.Measure, matchers []*promql.Matcher) *model_v1.Criteria {
+	var criteria *model_v1.Criteria
+	for _, name := range m.GetEntity().GetTagNames() {
+		spec := findTagSpecByName(m.GetTagFamilies(), name)
+		for _, matcher := range matchers {
+			// the empty value matches the absent tags, which aren't selected by the conditions
+			if matcher.Name != name || matcher.Type != promql.MatchEqual || matcher.Value == "" {
+				continue
+			}
+			value := &model_v1.TagValue{Value: &model_v1.TagValue_Str{Str: &model_v1.Str{Value: matcher.Value}}}
+			switch spec.GetType() {
+			case database_v1.TagType_TAG_TYPE_STRING:
+			case database_v1.TagType_TAG_TYPE_INT:
+				i, err := strconv.ParseInt(matcher.Value, 10, 64)
+				if err != nil {
+					continue
+				}
+				value = &model_v1.TagValue{Value: &model_v1.TagValue_Int{Int: &model_v1.Int{Value: i}}}
+			default:
+				continue
+			}
+			cond := &model_v1.Criteria{Exp: &model_v1.Criteria_Condition{Condition: &model_v1.Condition{
+				Name:  name,
+				Op:    model_v1.Condition_BINARY_OP_EQ,
+				Value: value,
+			}}}
+			if criteria == nil {
+				criteria = cond
+				continue
+			}
+			criteria = &model_v1.Criteria{Exp: &model_v1.Criteria_Le{Le: &model_v1.LogicalExpression{
+				Op:    model_v1.LogicalExpression_LOGICAL_OP_AND,
+				Left:  criteria,
+				Right: cond,
+			}}}
+		}
+	}
+	return criteria
+}
+
+func findTagSpecByName(families []*database_v1.TagFamilySpec, name string) *database_v1.TagSpec {
+	for _, f := range families {
+		if spec := findTagSpec(families, f.GetName(), name); spec != nil {
+			return spec
+		}
+	}
+	return nil
+}
+
+// labelValue returns the label of a tag value. The arrays are joined by commas, and the binary data and nulls aren't labels.
+func labelValue(v *model_v1.TagValue) (string, bool) {
+	switch val := v.GetValue().(type) {
+	case *model_v1.TagValue_Str:
+		return val.Str.GetValue(), true
+	case *model_v1.TagValue_Int:
+		return strconv.FormatInt(val.Int.GetValue(), 10), true
+	case *model_v1.TagValue_Id:
+		return val.Id.GetValue(), true
+	case *model_v1.TagValue_StrArray:
+		return strings.Join(val.StrArray.GetValue(), ","), true
+	case *model_v1.TagValue_IntArray:
+		values := make([]string, 0, len(val.IntArray.GetValue()))
+		for _, i := range val.IntArray.GetValue() {
+			values = append(values, strconv.FormatInt(i, 10))
+		}
+		return strings.Join(values, ","), true
+	}
+	return "", false
+}
+
+func parsePromQL(r *http.Request) (promql.Expr, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	e, err := promql.Parse(r.Form.Get("query"))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return e, nil
+}
+
+func parseLookback(r *http.Request, rng promql.Range) (promql.Range, error) {
+	rng.Lookback = defaultLookback.Milliseconds()
+	if v := r.Form.Get("lookback_delta"); v != "" {
+		d, err := parsePrometheusDuration(v)
+		if err != nil {
+			return rng, err
+		}
+		rng.Lookback = d.Milliseconds()
+	}
+	return rng, nil
+}
+
+// parsePrometheusTime parses the unix timestamp in seconds or the RFC3339 time into the milliseconds.
+func parsePrometheusTime(v string) (int64, error) {
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return int64(math.Round(f * 1000)), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return 0, status.Errorf(codes.InvalidArgument, "the time %q is invalid", v)
+	}
+	return t.UnixMilli(), nil
+}
+
+// parsePrometheusDuration parses the seconds or the duration, e.g. 1m.
+func parsePrometheusDuration(v string) (time.Duration, error) {
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return time.Duration(f * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, status.Errorf(codes.InvalidArgument, "the duration %q is invalid", v)
+	}
+	return d, nil
+}
+
+func prometheusSample(s promql.Sample) []interface{} {
+	return []interface{}{float64(s.T) / 1000, strconv.FormatFloat(s.V, 'f', -1, 64)}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writePrometheusData(w http.ResponseWriter, data interface{}) {
+	writePrometheusResponse(w, http.StatusOK, prometheusResponse{Status: "success", Data: data})
+}
+
+// writePrometheusError replies the error in the format of Prometheus, whose type is bad_data if the query is invalid.
+func writePrometheusError(w http.ResponseWriter, err error) {
+	if errors.Is(err, promql.ErrSyntax) || errors.Is(err, promql.ErrRange) {
+		err = status.Error(codes.InvalidArgument, err.Error())
+	}
+	st := status.Convert(err)
+	errorType := "execution"
+	if st.Code() == codes.InvalidArgument {
+		errorType = "bad_data"
+	}
+	writePrometheusResponse(w, runtime.HTTPStatusFromCode(st.Code()), prometheusResponse{
+		Status:    "error",
+		ErrorType: errorType,
+		Error:     st.Message(),
+	})
+}
+
+func writePrometheusResponse(w http.ResponseWriter, code int, resp prometheusResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,199 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	common_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	database_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measure_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	model_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+func (f *fakeMeasureRegistry) List(context.Context, *database_v1.MeasureRegistryServiceListRequest,
+	...grpc.CallOption,
+) (*database_v1.MeasureRegistryServiceListResponse, error) {
+	return &database_v1.MeasureRegistryServiceListResponse{Measure: []*database_v1.Measure{f.measure}}, nil
+}
+
+type fakeGroupRegistry struct {
+	database_v1.GroupRegistryServiceClient
+	groups []*common_v1.Group
+}
+
+func (f *fakeGroupRegistry) List(context.Context, *database_v1.GroupRegistryServiceListRequest,
+	...grpc.CallOption,
+) (*database_v1.GroupRegistryServiceListResponse, error) {
+	return &database_v1.GroupRegistryServiceListResponse{Group: f.groups}, nil
+}
+
+var _ = Describe("Prometheus", func() {
+	str := func(v string) *model_v1.TagValue {
+		return &model_v1.TagValue{Value: &model_v1.TagValue_Str{Str: &model_v1.Str{Value: v}}}
+	}
+	dataPoint := func(sec int64, service, instance string, value int64) *measure_v1.DataPoint {
+		return &measure_v1.DataPoint{
+			Timestamp: timestamppb.New(time.Unix(sec, 0)),
+			TagFamilies: []*model_v1.TagFamily{{Name: "default", Tags: []*model_v1.Tag{
+				{Key: "service", Value: str(service)},
+				{Key: "instance", Value: str(instance)},
+			}}},
+			Fields: []*measure_v1.DataPoint_Field{{
+				Name:  "value",
+				Value: &model_v1.FieldValue{Value: &model_v1.FieldValue_Int{Int: &model_v1.Int{Value: value}}},
+			}},
+		}
+	}
+	var measure *fakeMeasureService
+	var registry *fakeMeasureRegistry
+	var handler http.Handler
+	BeforeEach(func() {
+		measure = &fakeMeasureService{batches: []*measure_v1.QueryResponse{
+			{DataPoints: []*measure_v1.DataPoint{dataPoint(60, "gateway", "1", 10), dataPoint(60, "gateway", "2", 20)}},
+			{DataPoints: []*measure_v1.DataPoint{dataPoint(120, "gateway", "1", 30), dataPoint(120, "web", "1", 5)}},
+		}}
+		registry = &fakeMeasureRegistry{measure: &database_v1.Measure{
+			Metadata: &common_v1.Metadata{Group: "sw_metric", Name: "service_cpm"},
+			TagFamilies: []*database_v1.TagFamilySpec{{
+				Name: "default",
+				Tags: []*database_v1.TagSpec{
+					{Name: "service", Type: database_v1.TagType_TAG_TYPE_STRING},
+					{Name: "instance", Type: database_v1.TagType_TAG_TYPE_STRING},
+					{Name: "trace", Type: database_v1.TagType_TAG_TYPE_STRING, IndexedOnly: true},
+				},
+			}},
+			Entity: &database_v1.Entity{TagNames: []string{"service", "instance"}},
+			Fields: []*database_v1.FieldSpec{
+				{Name: "value", FieldType: database_v1.FieldType_FIELD_TYPE_INT},
+				{Name: "label", FieldType: database_v1.FieldType_FIELD_TYPE_STRING},
+			},
+		}}
+		p := &prometheusAPI{
+			measure:         measure,
+			measureRegistry: registry,
+			groupRegistry: &fakeGroupRegistry{groups: []*common_v1.Group{
+				{Metadata: &common_v1.Metadata{Name: "sw_metric"}, Catalog: common_v1.Catalog_CATALOG_MEASURE},
+				{Metadata: &common_v1.Metadata{Name: "sw_record"}, Catalog: common_v1.Catalog_CATALOG_STREAM},
+			}},
+			l: logger.GetLogger("test"),
+		}
+		handler = p.routes()
+	})
+	get := func(path string, params url.Values) (int, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path+"?"+params.Encode(), nil))
+		var body map[string]interface{}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &body)).To(Succeed())
+		return rec.Code, body
+	}
+
+	It("evaluates the instant queries", func() {
+		code, body := get("/api/v1/query", url.Values{
+			"query": {`sum by (service) (sw_metric:service_cpm:value{service="gateway"})`},
+			"time":  {"150"},
+		})
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(body).To(Equal(map[string]interface{}{
+			"status": "success",
+			"data": map[string]interface{}{
+				"resultType": "vector",
+				"result": []interface{}{
+					map[string]interface{}{"metric": map[string]interface{}{"service": "gateway"}, "value": []interface{}{150.0, "50"}},
+				},
+			},
+		}))
+		Expect(measure.req.GetCriteria()).To(BeComparableTo(&model_v1.Criteria{Exp: &model_v1.Criteria_Condition{Condition: &model_v1.Condition{
+			Name:  "service",
+			Op:    model_v1.Condition_BINARY_OP_EQ,
+			Value: str("gateway"),
+		}}}, protocmp.Transform()))
+		Expect(measure.req.GetTimeRange().GetBegin().AsTime()).To(Equal(time.Unix(-150, 0).UTC()))
+		Expect(measure.req.GetTagProjection().GetTagFamilies()[0].GetTags()).To(Equal([]string{"service", "instance"}))
+		Expect(measure.req.GetFieldProjection().GetNames()).To(Equal([]string{"value"}))
+	})
+
+	It("evaluates the range queries", func() {
+		code, body := get("/api/v1/query_range", url.Values{
+			"query": {`sw_metric:service_cpm:value{instance=~"1|3", service!="web"}`},
+			"start": {"1970-01-01T00:01:00Z"},
+			"end":   {"180"},
+			"step":  {"1m"},
+		})
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(body["data"]).To(Equal(map[string]interface{}{
+			"resultType": "matrix",
+			"result": []interface{}{
+				map[string]interface{}{
+					"metric": map[string]interface{}{"__name__": "sw_metric:service_cpm:value", "service": "gateway", "instance": "1"},
+					"values": []interface{}{[]interface{}{60.0, "10"}, []interface{}{120.0, "30"}, []interface{}{180.0, "30"}},
+				},
+			},
+		}))
+		Expect(measure.req.GetCriteria()).To(BeNil())
+	})
+
+	It("rejects the invalid queries", func() {
+		for _, params := range []url.Values{
+			{"query": {`rate(sw_metric:service_cpm:value[5m])`}},
+			{"query": {`sw_metric:service_cpm`}},
+			{"query": {`sw_metric:service_cpm:label`}},
+			{"query": {`service_cpm`}},
+			{"query": {`sw_metric:service_cpm:value`}, "time": {"yesterday"}},
+		} {
+			code, body := get("/api/v1/query", params)
+			Expect(code).To(Equal(http.StatusBadRequest), params.Encode())
+			Expect(body["status"]).To(Equal("error"))
+			Expect(body["errorType"]).To(Equal("bad_data"))
+		}
+		code, _ := get("/api/v1/query_range", url.Values{"query": {`sw_metric:service_cpm:value`}, "start": {"0"}, "end": {"60"}})
+		Expect(code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("lists the labels and the metric names", func() {
+		_, body := get("/api/v1/labels", nil)
+		Expect(body["data"]).To(Equal([]interface{}{"__name__", "instance", "service"}))
+		_, body = get("/api/v1/label/__name__/values", nil)
+		Expect(body["data"]).To(Equal([]interface{}{"sw_metric:service_cpm:value"}))
+		_, body = get("/api/v1/label/service/values", nil)
+		Expect(body["data"]).To(BeEmpty())
+	})
+
+	It("selects nothing of the unknown measures", func() {
+		registry.measure = nil
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/query", strings.NewReader(url.Values{"query": {`sw_metric:absent:value`}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		handler.ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(MatchJSON(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+	})
+})
@@ -23,17 +23,20 @@ import (
 	"io/fs"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"go.uber.org/multierr"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/credentials/insecure"
 
 	database_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
 	measure_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
 	property_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/property/v1"
 	stream_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/grpchelper"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 	"github.com/apache/skywalking-banyandb/pkg/run"
 	"github.com/apache/skywalking-banyandb/ui"
@@ -53,12 +56,16 @@ func NewService() ServiceRepo {
 }
 
 type service struct {
-	listenAddr   string
-	grpcAddr     string
-	mux          *chi.Mux
-	stopCh       chan struct{}
-	clientCloser context.CancelFunc
-	l            *logger.Logger
+	listenAddr    string
+	grpcAddr      string
+	compression   string
+	emitDefaults  bool
+	int64AsNumber bool
+	dial          dialPolicy
+	mux           *chi.Mux
+	stopCh        chan struct{}
+	clientCloser  context.CancelFunc
+	l             *logger.Logger
 
 	srv *http.Server
 }
@@ -67,11 +74,32 @@ func (p *service) FlagSet() *run.FlagSet {
 	flagSet := run.NewFlagSet("")
 	flagSet.StringVar(&p.listenAddr, "http-addr", ":17913", "listen addr for http")
 	flagSet.StringVar(&p.grpcAddr, "grpc-addr", "localhost:17912", "the grpc addr")
+	flagSet.StringVar(&p.compression, "grpc-compression", "",
+		"compress the requests sent to the grpc addr by gzip or zstd, whose responses are compressed the same way. Nothing is compressed if it's absent")
+	flagSet.BoolVar(&p.emitDefaults, "http-emit-defaults", true, "emit the fields with default values in the JSON responses")
+	flagSet.BoolVar(&p.int64AsNumber, "http-int64-as-number", false, "encode the 64-bit integers as numbers instead of strings in the JSON responses")
+	flagSet.IntVar(&p.dial.retryMaxAttempts, "grpc-retry-max-attempts", 3,
+		"the max attempts of a call to the grpc addr, including the first one, which is at most 5. 0 or 1 disables the retries")
+	flagSet.DurationVar(&p.dial.retryInitialBackoff, "grpc-retry-initial-backoff", 100*time.Millisecond, "the backoff before the first retry of a call to the grpc addr")
+	flagSet.DurationVar(&p.dial.retryMaxBackoff, "grpc-retry-max-backoff", time.Second, "the max backoff between the retries of a call to the grpc addr")
+	flagSet.Float64Var(&p.dial.retryBackoffMultiplier, "grpc-retry-backoff-multiplier", 2, "the multiplier of the backoff after each retry of a call to the grpc addr")
+	flagSet.StringSliceVar(&p.dial.retryCodes, "grpc-retry-codes", []string{"UNAVAILABLE"},
+		"the status codes of the calls to the grpc addr which are retried, e.g. UNAVAILABLE")
+	flagSet.BoolVar(&p.dial.waitForReady, "grpc-wait-for-ready", false,
+		"block the calls to the grpc addr until the connection is ready or the HTTP request is canceled, instead of failing them fast while it's reconnecting")
+	flagSet.DurationVar(&p.dial.backoffBaseDelay, "grpc-backoff-base-delay", time.Second, "the backoff after the first failure of connecting to the grpc addr")
+	flagSet.DurationVar(&p.dial.backoffMaxDelay, "grpc-backoff-max-delay", 10*time.Second, "the max backoff between the attempts of connecting to the grpc addr")
+	flagSet.Float64Var(&p.dial.backoffMultiplier, "grpc-backoff-multiplier", backoff.DefaultConfig.Multiplier,
+		"the multiplier of the backoff after each failure of connecting to the grpc addr")
+	flagSet.DurationVar(&p.dial.minConnectTimeout, "grpc-min-connect-timeout", 20*time.Second, "the min time given to an attempt of connecting to the grpc addr")
 	return flagSet
 }
 
 func (p *service) Validate() error {
-	return nil
+	if err := p.dial.validate(); err != nil {
+		return err
+	}
+	return grpchelper.CheckCompressor(p.compression)
 }
 
 func (p *service) Name() string {
@@ -104,19 +132,52 @@ func (p *service) Serve() run.StopNotify {
 		// TODO: add TLS
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 	}
+	opts = append(opts, p.dial.options()...)
+	if p.compression != "" {
+		opts = append(opts, grpc.WithDefaultCallOptions(grpc.UseCompressor(p.compression)))
+	}
 	client, err := newHealthCheckClient(ctx, p.l, p.grpcAddr, opts)
 	if err != nil {
 		p.l.Error().Err(err).Msg("Failed to health check client")
 		close(p.stopCh)
 		return p.stopCh
 	}
-	gwMux := runtime.NewServeMux(runtime.WithHealthzEndpoint(client))
+	exp, err := newExporter(ctx, p.l, p.grpcAddr, opts)
+	if err != nil {
+		p.l.Error().Err(err).Msg("Failed to create the exporter")
+		close(p.stopCh)
+		return p.stopCh
+	}
+	prom, err := newPrometheusAPI(ctx, p.l, p.grpcAddr, opts)
+	if err != nil {
+		p.l.Error().Err(err).Msg("Failed to create the Prometheus API")
+		close(p.stopCh)
+		return p.stopCh
+	}
+	gwMux := runtime.NewServeMux(
+		runtime.WithHealthzEndpoint(client),
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, newJSONMarshaler(p.emitDefaults, p.int64AsNumber)),
+		runtime.WithForwardResponseOption(pruneFields),
+		runtime.WithForwardResponseOption(setETag),
+	)
 	err = multierr.Combine(
 		database_v1.RegisterStreamRegistryServiceHandlerFromEndpoint(ctx, gwMux, p.grpcAddr, opts),
 		database_v1.RegisterMeasureRegistryServiceHandlerFromEndpoint(ctx, gwMux, p.grpcAddr, opts),
 		database_v1.RegisterIndexRuleRegistryServiceHandlerFromEndpoint(ctx, gwMux, p.grpcAddr, opts),
 		database_v1.RegisterIndexRuleBindingRegistryServiceHandlerFromEndpoint(ctx, gwMux, p.grpcAddr, opts),
 		database_v1.RegisterGroupRegistryServiceHandlerFromEndpoint(ctx, gwMux, p.grpcAddr, opts),
+		database_v1.RegisterServerInfoServiceHandlerFromEndpoint(ctx, gwMux, p.grpcAddr, opts),
+		database_v1.RegisterSlowQueryServiceHandlerFromEndpoint(ctx, gwMux, p.grpcAddr, opts),
+		database_v1.RegisterTopQueryServiceHandlerFromEndpoint(ctx, gwMux, p.grpcAddr, opts),
+		database_v1.RegisterShardServiceHandlerFromEndpoint(ctx, gwMux, p.grpcAddr, opts),
+		database_v1.RegisterIndexServiceHandlerFromEndpoint(ctx, gwMux, p.grpcAddr, opts),
+		database_v1.RegisterDrainServiceHandlerFromEndpoint(ctx, gwMux, p.grpcAddr, opts),
+		database_v1.RegisterTimeRangeServiceHandlerFromEndpoint(ctx, gwMux, p.grpcAddr, opts),
+		database_v1.RegisterUsageServiceHandlerFromEndpoint(ctx, gwMux, p.grpcAddr, opts),
+		database_v1.RegisterManifestServiceHandlerFromEndpoint(ctx, gwMux, p.grpcAddr, opts),
+		database_v1.RegisterConfigServiceHandlerFromEndpoint(ctx, gwMux, p.grpcAddr, opts),
+		database_v1.RegisterWriteRejectionServiceHandlerFromEndpoint(ctx, gwMux, p.grpcAddr, opts),
+		database_v1.RegisterSchemaCompatibilityServiceHandlerFromEndpoint(ctx, gwMux, p.grpcAddr, opts),
 		stream_v1.RegisterStreamServiceHandlerFromEndpoint(ctx, gwMux, p.grpcAddr, opts),
 		measure_v1.RegisterMeasureServiceHandlerFromEndpoint(ctx, gwMux, p.grpcAddr, opts),
 		property_v1.RegisterPropertyServiceHandlerFromEndpoint(ctx, gwMux, p.grpcAddr, opts),
@@ -126,7 +187,10 @@ func (p *service) Serve() run.StopNotify {
 		close(p.stopCh)
 		return p.stopCh
 	}
-	p.mux.Mount("/api", http.StripPrefix("/api", gwMux))
+	p.mux.Post("/api/v1/stream/export", exp.exportStream)
+	p.mux.Post("/api/v1/measure/export", exp.exportMeasure)
+	p.mux.Mount(prometheusPrefix, prom.routes())
+	p.mux.Mount("/api", http.StripPrefix("/api", conditionalGet(withFields(gwMux))))
 	go func() {
 		p.l.Info().Str("listenAddr", p.listenAddr).Msg("Start liaison http server")
 		if err := p.srv.ListenAndServe(); err != http.ErrServerClosed {
@@ -20,6 +20,8 @@ package liaison
 import (
 	"context"
 
+	grpclib "google.golang.org/grpc"
+
 	"github.com/apache/skywalking-banyandb/banyand/discovery"
 	"github.com/apache/skywalking-banyandb/banyand/liaison/grpc"
 	"github.com/apache/skywalking-banyandb/banyand/metadata"
@@ -31,6 +33,10 @@ type Endpoint interface {
 	run.Config
 	run.PreRunner
 	run.Service
+	run.Drainer
+	RegisterModules(modules ...run.Unit)
+	RegisterUnaryInterceptors(interceptors ...grpclib.UnaryServerInterceptor)
+	RegisterStreamInterceptors(interceptors ...grpclib.StreamServerInterceptor)
 }
 
 func NewEndpoint(ctx context.Context, pipeline queue.Queue, repo discovery.ServiceRepo, schemaRegistry metadata.Service) (Endpoint, error) {
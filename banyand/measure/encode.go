@@ -39,16 +39,20 @@ var (
 )
 
 type encoderPool struct {
-	intPool     encoding.SeriesEncoderPool
-	defaultPool encoding.SeriesEncoderPool
-	l           *logger.Logger
+	intPool        encoding.SeriesEncoderPool
+	deltaPool      encoding.SeriesEncoderPool
+	dictionaryPool encoding.SeriesEncoderPool
+	defaultPool    encoding.SeriesEncoderPool
+	l              *logger.Logger
 }
 
 func newEncoderPool(name string, plainSize, intSize int, l *logger.Logger) encoding.SeriesEncoderPool {
 	return &encoderPool{
-		intPool:     encoding.NewIntEncoderPool(name, intSize, intervalFn),
-		defaultPool: encoding.NewPlainEncoderPool(name, plainSize),
-		l:           l,
+		intPool:        encoding.NewIntEncoderPool(name, intSize, intervalFn),
+		deltaPool:      encoding.NewDeltaEncoderPool(name, intSize),
+		dictionaryPool: encoding.NewDictionaryEncoderPool(name, intSize),
+		defaultPool:    encoding.NewPlainEncoderPool(name, plainSize),
+		l:              l,
 	}
 }
 
@@ -58,28 +62,39 @@ func (p *encoderPool) Get(metadata []byte) encoding.SeriesEncoder {
 		p.l.Err(err).Msg("failed to decode field flag")
 		return p.defaultPool.Get(metadata)
 	}
-	if fieldSpec.EncodingMethod == databasev1.EncodingMethod_ENCODING_METHOD_GORILLA {
+	switch fieldSpec.EncodingMethod {
+	case databasev1.EncodingMethod_ENCODING_METHOD_GORILLA:
 		return p.intPool.Get(metadata)
+	case databasev1.EncodingMethod_ENCODING_METHOD_DELTA:
+		return p.deltaPool.Get(metadata)
+	case databasev1.EncodingMethod_ENCODING_METHOD_DICTIONARY:
+		return p.dictionaryPool.Get(metadata)
 	}
 	return p.defaultPool.Get(metadata)
 }
 
 func (p *encoderPool) Put(encoder encoding.SeriesEncoder) {
 	p.intPool.Put(encoder)
+	p.deltaPool.Put(encoder)
+	p.dictionaryPool.Put(encoder)
 	p.defaultPool.Put(encoder)
 }
 
 type decoderPool struct {
-	intPool     encoding.SeriesDecoderPool
-	defaultPool encoding.SeriesDecoderPool
-	l           *logger.Logger
+	intPool        encoding.SeriesDecoderPool
+	deltaPool      encoding.SeriesDecoderPool
+	dictionaryPool encoding.SeriesDecoderPool
+	defaultPool    encoding.SeriesDecoderPool
+	l              *logger.Logger
 }
 
 func newDecoderPool(name string, plainSize, intSize int, l *logger.Logger) encoding.SeriesDecoderPool {
 	return &decoderPool{
-		intPool:     encoding.NewIntDecoderPool(name, intSize, intervalFn),
-		defaultPool: encoding.NewPlainDecoderPool(name, plainSize),
-		l:           l,
+		intPool:        encoding.NewIntDecoderPool(name, intSize, intervalFn),
+		deltaPool:      encoding.NewDeltaDecoderPool(name, intSize),
+		dictionaryPool: encoding.NewDictionaryDecoderPool(name, intSize),
+		defaultPool:    encoding.NewPlainDecoderPool(name, plainSize),
+		l:              l,
 	}
 }
 
@@ -89,13 +104,20 @@ func (p *decoderPool) Get(metadata []byte) encoding.SeriesDecoder {
 		p.l.Err(err).Msg("failed to decode field flag")
 		return p.defaultPool.Get(metadata)
 	}
-	if fieldSpec.EncodingMethod == databasev1.EncodingMethod_ENCODING_METHOD_GORILLA {
+	switch fieldSpec.EncodingMethod {
+	case databasev1.EncodingMethod_ENCODING_METHOD_GORILLA:
 		return p.intPool.Get(metadata)
+	case databasev1.EncodingMethod_ENCODING_METHOD_DELTA:
+		return p.deltaPool.Get(metadata)
+	case databasev1.EncodingMethod_ENCODING_METHOD_DICTIONARY:
+		return p.dictionaryPool.Get(metadata)
 	}
 	return p.defaultPool.Get(metadata)
 }
 
 func (p *decoderPool) Put(decoder encoding.SeriesDecoder) {
 	p.intPool.Put(decoder)
+	p.deltaPool.Put(decoder)
+	p.dictionaryPool.Put(decoder)
 	p.defaultPool.Put(decoder)
 }
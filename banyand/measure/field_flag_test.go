@@ -37,3 +37,24 @@ func TestEncodeFieldFlag(t *testing.T) {
 	assert.Equal(t, databasev1.CompressionMethod_COMPRESSION_METHOD_ZSTD, fieldSpec.CompressionMethod)
 	assert.Equal(t, time.Minute, interval)
 }
+
+func TestEncodeFieldFlagByFieldType(t *testing.T) {
+	for fieldType, encodingMethod := range map[databasev1.FieldType]databasev1.EncodingMethod{
+		databasev1.FieldType_FIELD_TYPE_INT:         databasev1.EncodingMethod_ENCODING_METHOD_DELTA,
+		databasev1.FieldType_FIELD_TYPE_STRING:      databasev1.EncodingMethod_ENCODING_METHOD_DICTIONARY,
+		databasev1.FieldType_FIELD_TYPE_DATA_BINARY: databasev1.EncodingMethod_ENCODING_METHOD_UNSPECIFIED,
+	} {
+		spec := &databasev1.FieldSpec{FieldType: fieldType}
+		fieldSpec, _, err := pbv1.DecodeFieldFlag(pbv1.EncoderFieldFlag(spec, time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, encodingMethod, fieldSpec.EncodingMethod)
+		legacyFlag := pbv1.LegacyFieldFlag(spec, time.Minute)
+		if encodingMethod == databasev1.EncodingMethod_ENCODING_METHOD_UNSPECIFIED {
+			assert.Nil(t, legacyFlag)
+			continue
+		}
+		fieldSpec, _, err = pbv1.DecodeFieldFlag(legacyFlag)
+		assert.NoError(t, err)
+		assert.Equal(t, databasev1.EncodingMethod_ENCODING_METHOD_UNSPECIFIED, fieldSpec.EncodingMethod)
+	}
+}
@@ -27,12 +27,16 @@ import (
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
 	"github.com/apache/skywalking-banyandb/banyand/tsdb"
 	"github.com/apache/skywalking-banyandb/banyand/tsdb/index"
+	"github.com/apache/skywalking-banyandb/pkg/intern"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 	"github.com/apache/skywalking-banyandb/pkg/partition"
 	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/schema"
 	"github.com/apache/skywalking-banyandb/pkg/timestamp"
 )
 
+var _ schema.IndexRebuilder = (*measure)(nil)
+
 const (
 	plainChunkSize = 1 << 20
 	intChunkSize   = 120
@@ -41,7 +45,7 @@ const (
 type measure struct {
 	name     string
 	group    string
-	shardNum uint32
+	sharding partition.Sharding
 	l        *logger.Logger
 	schema   *databasev1.Measure
 	// maxObservedModRevision is the max observed revision of index rules in the spec
@@ -52,6 +56,8 @@ type measure struct {
 	indexWriter            *index.Writer
 	interval               time.Duration
 	processorManager       *topNProcessorManager
+	propertyTags           pbv1.PropertyTagsGetter
+	interning              *intern.Table
 }
 
 func (s *measure) GetSchema() *databasev1.Measure {
@@ -78,6 +84,26 @@ func (s *measure) Close() error {
 	return multierr.Combine(s.processorManager.Close(), s.indexWriter.Close())
 }
 
+func (s *measure) Flush(ctx context.Context) error {
+	return s.indexWriter.Flush(ctx)
+}
+
+func (s *measure) RebuildIndex(ctx context.Context, rules []*databasev1.IndexRule, opts schema.IndexRebuildOptions) (int, error) {
+	return index.Rebuild(context.WithValue(ctx, logger.ContextKey, s.l), s, index.RebuildOptions{
+		WriterOptions: index.WriterOptions{
+			DB:         s.databaseSupplier,
+			ShardNum:   s.sharding.ShardNum,
+			Families:   s.schema.GetTagFamilies(),
+			IndexRules: rules,
+		},
+		TimeRange:   opts.TimeRange,
+		Throttle:    opts.Throttle,
+		EntityLen:   len(s.schema.GetEntity().GetTagNames()),
+		Progress:    opts.Progress,
+		Incremental: opts.Incremental,
+	})
+}
+
 func (s *measure) parseSpec() (err error) {
 	s.name, s.group = s.schema.GetMetadata().GetName(), s.schema.GetMetadata().GetGroup()
 	s.entityLocator = partition.NewEntityLocator(s.schema.GetTagFamilies(), s.schema.GetEntity())
@@ -92,14 +118,18 @@ type measureSpec struct {
 	schema           *databasev1.Measure
 	indexRules       []*databasev1.IndexRule
 	topNAggregations []*databasev1.TopNAggregation
+	propertyTags     pbv1.PropertyTagsGetter
+	interning        *intern.Table
 }
 
-func openMeasure(shardNum uint32, db tsdb.Supplier, spec measureSpec, l *logger.Logger) (*measure, error) {
+func openMeasure(sharding partition.Sharding, db tsdb.Supplier, spec measureSpec, opts topNOpts, l *logger.Logger) (*measure, error) {
 	m := &measure{
-		shardNum:   shardNum,
-		schema:     spec.schema,
-		indexRules: spec.indexRules,
-		l:          l,
+		sharding:     sharding,
+		schema:       spec.schema,
+		indexRules:   spec.indexRules,
+		propertyTags: spec.propertyTags,
+		interning:    spec.interning,
+		l:            l,
 	}
 	if err := m.parseSpec(); err != nil {
 		return nil, err
@@ -109,7 +139,7 @@ func openMeasure(shardNum uint32, db tsdb.Supplier, spec measureSpec, l *logger.
 	m.databaseSupplier = db
 	m.indexWriter = index.NewWriter(ctx, index.WriterOptions{
 		DB:         db,
-		ShardNum:   shardNum,
+		ShardNum:   sharding.ShardNum,
 		Families:   spec.schema.TagFamilies,
 		IndexRules: spec.indexRules,
 	})
@@ -117,6 +147,7 @@ func openMeasure(shardNum uint32, db tsdb.Supplier, spec measureSpec, l *logger.
 	m.processorManager = &topNProcessorManager{
 		l:            l,
 		m:            m,
+		opts:         opts,
 		topNSchemas:  spec.topNAggregations,
 		processorMap: make(map[*commonv1.Metadata][]*topNStreamingProcessor),
 	}
@@ -18,7 +18,9 @@
 package measure
 
 import (
+	"context"
 	"io"
+	"strconv"
 	"time"
 
 	"github.com/pkg/errors"
@@ -29,8 +31,8 @@ import (
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
 	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
 	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/banyand/kv"
 	"github.com/apache/skywalking-banyandb/banyand/tsdb"
-	"github.com/apache/skywalking-banyandb/pkg/partition"
 	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
 	resourceSchema "github.com/apache/skywalking-banyandb/pkg/schema"
 )
@@ -53,6 +55,8 @@ type Measure interface {
 	GetSchema() *databasev1.Measure
 	GetIndexRules() []*databasev1.IndexRule
 	GetInterval() time.Duration
+	// Flush waits for the data points written before to be indexed.
+	Flush(ctx context.Context) error
 }
 
 var _ Measure = (*measure)(nil)
@@ -78,22 +82,27 @@ func (s *measure) Shards(entity tsdb.Entity) ([]tsdb.Shard, error) {
 			return wrap(db.Shards()), nil
 		}
 	}
-	shardID, err := partition.ShardID(entity.Prepend(tsdb.Entry(s.name)).Marshal(), s.shardNum)
+	shardIDs, err := s.sharding.ShardIDs(entity.Prepend(tsdb.Entry(s.name)).Marshal())
 	if err != nil {
 		return nil, err
 	}
-	shard, err := s.Shard(common.ShardID(shardID))
-	if err != nil {
-		return nil, err
+	shards := make([]tsdb.Shard, 0, len(shardIDs))
+	for _, id := range shardIDs {
+		shard, errShard := s.Shard(common.ShardID(id))
+		if errShard != nil {
+			return nil, errShard
+		}
+		shards = append(shards, shard)
 	}
-	return []tsdb.Shard{shard}, nil
+	return shards, nil
 }
 
 func (s *measure) CompanionShards(metadata *commonv1.Metadata) ([]tsdb.Shard, error) {
+	generation := s.processorManager.opts.ledger.generation(metadata)
 	wrap := func(shards []tsdb.Shard) []tsdb.Shard {
 		result := make([]tsdb.Shard, len(shards))
 		for i := 0; i < len(shards); i++ {
-			result[i] = tsdb.NewScopedShard(tsdb.Entry(formatMeasureCompanionPrefix(s.name, metadata.GetName())), shards[i])
+			result[i] = tsdb.NewScopedShard(tsdb.Entry(formatMeasureCompanionPrefix(s.name, metadata.GetName(), generation)), shards[i])
 		}
 		return result
 	}
@@ -101,8 +110,13 @@ func (s *measure) CompanionShards(metadata *commonv1.Metadata) ([]tsdb.Shard, er
 	return wrap(db.Shards()), nil
 }
 
-func formatMeasureCompanionPrefix(measureName, name string) string {
-	return measureName + "." + name
+// formatMeasureCompanionPrefix returns the prefix of the series derived from a TopN aggregation of the measure.
+// The first generation is unnumbered to read the data written before the generations are introduced.
+func formatMeasureCompanionPrefix(measureName, name string, generation uint64) string {
+	if generation == 0 {
+		return measureName + "." + name
+	}
+	return measureName + "." + name + "#" + strconv.FormatUint(generation, 10)
 }
 
 func (s *measure) Shard(id common.ShardID) (tsdb.Shard, error) {
@@ -114,7 +128,20 @@ func (s *measure) Shard(id common.ShardID) (tsdb.Shard, error) {
 }
 
 func (s *measure) ParseTagFamily(family string, item tsdb.Item) (*modelv1.TagFamily, error) {
+	var tagSpec []*databasev1.TagSpec
+	for _, tf := range s.schema.GetTagFamilies() {
+		if tf.GetName() == family {
+			tagSpec = tf.GetTags()
+		}
+	}
+	if tagSpec == nil {
+		return nil, ErrTagFamilyNotExist
+	}
 	familyRawBytes, err := item.Family(familyIdentity(family, pbv1.TagFlag))
+	if errors.Is(err, kv.ErrKeyNotFound) {
+		// the tag family is appended to the schema after the item is written
+		return &modelv1.TagFamily{Name: family}, nil
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -124,15 +151,6 @@ func (s *measure) ParseTagFamily(family string, item tsdb.Item) (*modelv1.TagFam
 		return nil, err
 	}
 	tags := make([]*modelv1.Tag, len(tagFamily.GetTags()))
-	var tagSpec []*databasev1.TagSpec
-	for _, tf := range s.schema.GetTagFamilies() {
-		if tf.GetName() == family {
-			tagSpec = tf.GetTags()
-		}
-	}
-	if tagSpec == nil {
-		return nil, ErrTagFamilyNotExist
-	}
 	for i, tag := range tagFamily.GetTags() {
 		tags[i] = &modelv1.Tag{
 			Key: tagSpec[i].GetName(),
@@ -156,6 +174,19 @@ func (s *measure) ParseField(name string, item tsdb.Item) (*measurev1.DataPoint_
 		}
 	}
 	bytes, err := item.Family(familyIdentity(name, pbv1.EncoderFieldFlag(fieldSpec, s.interval)))
+	if errors.Is(err, kv.ErrKeyNotFound) {
+		// the field is written before its encoding method is chosen by the field type
+		if legacyFlag := pbv1.LegacyFieldFlag(fieldSpec, s.interval); legacyFlag != nil {
+			bytes, err = item.Family(familyIdentity(name, legacyFlag))
+		}
+	}
+	if errors.Is(err, kv.ErrKeyNotFound) {
+		// the field is appended to the schema after the item is written
+		return &measurev1.DataPoint_Field{
+			Name:  name,
+			Value: pbv1.NullFieldValue,
+		}, nil
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -52,9 +52,10 @@ const (
 )
 
 var (
-	_ io.Closer = (*topNStreamingProcessor)(nil)
-	_ io.Closer = (*topNProcessorManager)(nil)
-	_ flow.Sink = (*topNStreamingProcessor)(nil)
+	_ io.Closer      = (*topNStreamingProcessor)(nil)
+	_ io.Closer      = (*topNProcessorManager)(nil)
+	_ flow.Sink      = (*topNStreamingProcessor)(nil)
+	_ flow.DataCodec = (*topNDataCodec)(nil)
 
 	errUnsupportedConditionValueType = errors.New("unsupported value type in the condition")
 
@@ -66,9 +67,19 @@ var (
 	}
 )
 
+// topNOpts configures the checkpoint of TopN aggregations' flow state.
+type topNOpts struct {
+	checkpointStore    flow.CheckpointStore
+	ledger             *topNLedger
+	checkpointInterval time.Duration
+}
+
 type topNStreamingProcessor struct {
 	flow.ComponentState
 	l                *logger.Logger
+	checkpoint       *flow.CheckpointCoordinator
+	ledger           *topNLedger
+	generation       uint64
 	shardNum         uint32
 	interval         time.Duration
 	topNSchema       *databasev1.TopNAggregation
@@ -122,6 +133,10 @@ func (t *topNStreamingProcessor) Close() error {
 	// and wait for error channel close
 	<-t.stopCh
 	t.stopCh = nil
+	if t.checkpoint != nil {
+		// the flow is drained, persist the windows which are not flushed yet
+		err = multierr.Append(err, t.checkpoint.Close())
+	}
 	return err
 }
 
@@ -138,11 +153,20 @@ func (t *topNStreamingProcessor) writeStreamRecord(record flow.StreamRecord) err
 		Str("TopN", t.topNSchema.GetMetadata().GetName()).
 		Int("rankNums", len(tuples)).
 		Msg("Write a tuple")
+	// the window is recorded until all its ranks are written, which tells the window partially written by a crash
+	if t.ledger != nil {
+		if err = t.ledger.begin(t.topNSchema.GetMetadata(), t.checkpointName(), timeBucket); err != nil {
+			return err
+		}
+	}
 	for rankNum, tuple := range tuples {
 		fieldValue := tuple.V1.(int64)
 		data := tuple.V2.(flow.StreamRecord).Data().(flow.Data)
 		err = multierr.Append(err, t.writeData(eventTime, timeBucket, fieldValue, data, rankNum))
 	}
+	if t.ledger != nil {
+		err = multierr.Append(err, t.ledger.commit(t.topNSchema.GetMetadata(), t.checkpointName()))
+	}
 	return err
 }
 
@@ -253,7 +277,7 @@ func (t *topNStreamingProcessor) locate(tagValues []*modelv1.TagValue, rankNum i
 	entity := make(tsdb.Entity, 1+1+1+len(t.topNSchema.GetGroupByTagNames()))
 	// entity prefix
 	entity[0] = []byte(formatMeasureCompanionPrefix(t.topNSchema.GetSourceMeasure().GetName(),
-		t.topNSchema.GetMetadata().GetName()))
+		t.topNSchema.GetMetadata().GetName(), t.generation))
 	entity[1] = convert.Int64ToBytes(int64(t.sortDirection.Number()))
 	entity[2] = convert.Int64ToBytes(int64(rankNum))
 	// measureID as sharding key
@@ -272,18 +296,87 @@ func (t *topNStreamingProcessor) locate(tagValues []*modelv1.TagValue, rankNum i
 }
 
 func (t *topNStreamingProcessor) start() *topNStreamingProcessor {
-	t.errCh = t.streamingFlow.Window(streaming.NewTumblingTimeWindows(t.interval)).
+	windows := streaming.NewTumblingTimeWindows(t.interval)
+	topNFlow := t.streamingFlow.Window(windows).
 		AllowedMaxWindows(int(t.topNSchema.GetLruSize())).
 		TopN(int(t.topNSchema.GetCountersNumber()),
 			streaming.WithSortKeyExtractor(func(record flow.StreamRecord) int64 {
 				return record.Data().(flow.Data)[1].(int64)
 			}),
 			OrderBy(t.topNSchema.GetFieldValueSort()),
-		).To(t).Open()
+			streaming.WithDataCodec(topNDataCodec{}),
+		)
+	if t.ledger != nil {
+		if timeBucket := t.ledger.pending(t.topNSchema.GetMetadata(), t.checkpointName()); timeBucket != "" {
+			// the window is written again if it's restored from the checkpoint, otherwise it lacks some ranks
+			t.l.Warn().Str("topN", t.topNSchema.GetMetadata().GetName()).Stringer("sort", t.sortDirection).
+				Str("timeBucket", timeBucket).Msg("the window was partially written before the crash")
+		}
+	}
+	if t.checkpoint != nil {
+		t.checkpoint.Register("windows", windows)
+		restored, err := t.checkpoint.Restore()
+		if err != nil {
+			t.l.Err(err).Str("topN", t.topNSchema.GetMetadata().GetName()).Msg("fail to restore the checkpoint, start from scratch")
+		} else if restored {
+			t.l.Info().Str("topN", t.topNSchema.GetMetadata().GetName()).
+				Uint64("checkpoint", t.checkpoint.LastCheckpointID()).Msg("restored from the checkpoint")
+		}
+	}
+	t.errCh = topNFlow.To(t).Open()
+	if t.checkpoint != nil {
+		t.checkpoint.Start()
+	}
 	go t.handleError()
 	return t
 }
 
+// checkpointName is unique to the generation of the aggregation, thus an aggregation created again never restores the former flows.
+func (t *topNStreamingProcessor) checkpointName() string {
+	parts := []string{t.topNSchema.GetMetadata().GetGroup(), t.topNSchema.GetMetadata().GetName()}
+	if t.generation > 0 {
+		parts = append(parts, strconv.FormatUint(t.generation, 10))
+	}
+	return strings.Join(append(parts, t.sortDirection.String()), ".")
+}
+
+// topNDataCodec encodes the flow.Data produced by the mapper of topNProcessorManager.
+// The group key and the field value are the leading two tags, followed by the group-by tag values.
+type topNDataCodec struct{}
+
+func (topNDataCodec) Encode(data interface{}) ([]byte, error) {
+	d, ok := data.(flow.Data)
+	if !ok || len(d) != 3 {
+		return nil, errors.New("invalid data type")
+	}
+	family := &modelv1.TagFamilyForWrite{
+		Tags: []*modelv1.TagValue{
+			{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: d[0].(string)}}},
+			{Value: &modelv1.TagValue_Int{Int: &modelv1.Int{Value: d[1].(int64)}}},
+		},
+	}
+	if tagValues, ok := d[2].([]*modelv1.TagValue); ok {
+		family.Tags = append(family.Tags, tagValues...)
+	}
+	return proto.Marshal(family)
+}
+
+func (topNDataCodec) Decode(raw []byte) (interface{}, error) {
+	family := &modelv1.TagFamilyForWrite{}
+	if err := proto.Unmarshal(raw, family); err != nil {
+		return nil, err
+	}
+	tags := family.GetTags()
+	if len(tags) < 2 {
+		return nil, errors.New("no enough tags in the data")
+	}
+	data := flow.Data{tags[0].GetStr().GetValue(), tags[1].GetInt().GetValue(), nil}
+	if len(tags) > 2 {
+		data[2] = tags[2:]
+	}
+	return data, nil
+}
+
 func OrderBy(sort modelv1.Sort) streaming.TopNOption {
 	if sort == modelv1.Sort_SORT_ASC {
 		return streaming.OrderBy(streaming.ASC)
@@ -307,6 +400,7 @@ type topNProcessorManager struct {
 	sync.RWMutex
 	l            *logger.Logger
 	m            *measure
+	opts         topNOpts
 	topNSchemas  []*databasev1.TopNAggregation
 	processorMap map[*commonv1.Metadata][]*topNStreamingProcessor
 }
@@ -335,8 +429,55 @@ func (manager *topNProcessorManager) onMeasureWrite(request *measurev1.WriteRequ
 	}()
 }
 
+// remove stops the processors of the removed aggregation, and cleans up the flow state and the data derived from it.
+func (manager *topNProcessorManager) remove(md *commonv1.Metadata) error {
+	link, err := manager.opts.ledger.remove(md)
+	if err != nil {
+		return err
+	}
+	manager.Lock()
+	for key, processorList := range manager.processorMap {
+		if processorList[0].topNSchema.GetMetadata().GetName() != md.GetName() {
+			continue
+		}
+		for _, processor := range processorList {
+			err = multierr.Append(err, processor.Close())
+		}
+		delete(manager.processorMap, key)
+	}
+	manager.Unlock()
+	return multierr.Append(err, removeTopN(manager.opts, link))
+}
+
+// removeTopN deletes the checkpoints of the removed aggregation, whose data are left to the retention.
+// The processors of the aggregation have to be stopped.
+func removeTopN(opts topNOpts, link topNLink) (err error) {
+	if !link.Removing {
+		if link, err = opts.ledger.remove(&commonv1.Metadata{Group: link.Group, Name: link.Name}); err != nil {
+			return err
+		}
+	}
+	if opts.checkpointStore != nil {
+		for _, name := range link.Checkpoints {
+			if err = opts.checkpointStore.Delete(name); err != nil {
+				return err
+			}
+		}
+	}
+	return opts.ledger.removed(&commonv1.Metadata{Group: link.Group, Name: link.Name})
+}
+
 func (manager *topNProcessorManager) start() error {
 	interval := manager.m.interval
+	if manager.opts.ledger != nil {
+		// resume the removals interrupted by a crash, and clean up the aggregations removed while the node was down
+		for _, link := range manager.opts.ledger.stale(manager.m.group, manager.m.name, manager.topNSchemas) {
+			if err := removeTopN(manager.opts, link); err != nil {
+				return err
+			}
+			manager.l.Info().Str("topN", link.Name).Uint64("generation", link.Generation).Msg("cleaned up the removed TopN aggregation")
+		}
+	}
 	for _, topNSchema := range manager.topNSchemas {
 		sortDirections := make([]modelv1.Sort, 0, 2)
 		if topNSchema.GetFieldValueSort() == modelv1.Sort_SORT_UNSPECIFIED {
@@ -365,7 +506,9 @@ func (manager *topNProcessorManager) start() error {
 
 			processor := &topNStreamingProcessor{
 				l:                manager.l,
-				shardNum:         manager.m.shardNum,
+				ledger:           manager.opts.ledger,
+				generation:       manager.opts.ledger.generation(topNSchema.GetMetadata()),
+				shardNum:         manager.m.sharding.ShardNum,
 				interval:         interval,
 				topNSchema:       topNSchema,
 				sortDirection:    sortDirection,
@@ -375,7 +518,25 @@ func (manager *topNProcessorManager) start() error {
 				stopCh:           make(chan struct{}),
 				streamingFlow:    streamingFlow,
 			}
-			processorList[i] = processor.start()
+			if manager.opts.checkpointStore != nil {
+				processor.checkpoint = flow.NewCheckpointCoordinator(processor.checkpointName(), manager.opts.checkpointStore,
+					manager.opts.checkpointInterval, func(err error) {
+						manager.l.Err(err).Str("topN", topNSchema.GetMetadata().GetName()).Msg("fail to checkpoint")
+					})
+			}
+			processorList[i] = processor
+		}
+		if manager.opts.ledger != nil {
+			checkpoints := make([]string, 0, len(processorList))
+			for _, processor := range processorList {
+				checkpoints = append(checkpoints, processor.checkpointName())
+			}
+			if err := manager.opts.ledger.track(topNSchema, checkpoints); err != nil {
+				return err
+			}
+		}
+		for _, processor := range processorList {
+			processor.start()
 		}
 
 		manager.processorMap[topNSchema.GetSourceMeasure()] = processorList
@@ -501,22 +662,26 @@ func (manager *topNProcessorManager) buildMapper(fieldName string, groupByNames
 	}
 	return func(_ context.Context, request any) any {
 		dataPoint := request.(*measurev1.DataPointValue)
+		tagValues := transform(groupLocator, func(locator partition.TagLocator) *modelv1.TagValue {
+			return dataPoint.GetTagFamilies()[locator.FamilyOffset].GetTags()[locator.TagOffset]
+		})
 		return flow.Data{
 			// save string representation of group values as the key, i.e. v1
-			strings.Join(transform(groupLocator, func(locator partition.TagLocator) string {
-				return stringify(dataPoint.GetTagFamilies()[locator.FamilyOffset].GetTags()[locator.TagOffset])
-			}), "|"),
+			GroupValues(tagValues),
 			// field value as v2
 			// TODO: we only support int64
 			dataPoint.GetFields()[fieldIdx].GetInt().GetValue(),
 			// groupBy tag values as v3
-			transform(groupLocator, func(locator partition.TagLocator) *modelv1.TagValue {
-				return dataPoint.GetTagFamilies()[locator.FamilyOffset].GetTags()[locator.TagOffset]
-			}),
+			tagValues,
 		}
 	}, nil
 }
 
+// GroupValues returns the key of the group-by tag values, which names the items of the TopN lists.
+func GroupValues(tagValues []*modelv1.TagValue) string {
+	return strings.Join(transform(tagValues, stringify), "|")
+}
+
 var (
 	_ conditionFilter = (*strTagFilter)(nil)
 	_ conditionFilter = (*int64TagFilter)(nil)
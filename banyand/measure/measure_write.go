@@ -33,6 +33,7 @@ import (
 	"github.com/apache/skywalking-banyandb/pkg/convert"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/profiling"
 	"github.com/apache/skywalking-banyandb/pkg/timestamp"
 )
 
@@ -40,7 +41,7 @@ var ErrMalformedElement = errors.New("element is malformed")
 
 // Write is for testing
 func (s *measure) Write(value *measurev1.DataPointValue) error {
-	entity, shardID, err := s.entityLocator.Locate(s.name, value.GetTagFamilies(), s.shardNum)
+	entity, shardID, err := s.entityLocator.Locate(s.interning, s.name, value.GetTagFamilies(), s.sharding, value.GetTimestamp().AsTime())
 	if err != nil {
 		return err
 	}
@@ -69,7 +70,14 @@ func (s *measure) write(shardID common.ShardID, seriesHashKey []byte, value *mea
 	if fLen > len(sm.TagFamilies) {
 		return errors.Wrap(ErrMalformedElement, "tag family number is more than expected")
 	}
-	shard, err := s.databaseSupplier.SupplyTSDB().Shard(shardID)
+	pbv1.InternTagFamilies(s.interning, value.GetTagFamilies())
+	tagFamilies, err := pbv1.EnrichTagFamilies(s.group, sm.GetTagFamilies(), sm.GetEnrichments(), value.GetTagFamilies(), s.propertyTags)
+	if err != nil {
+		return err
+	}
+	value.TagFamilies = tagFamilies
+	db := s.databaseSupplier.SupplyTSDB()
+	shard, err := db.Shard(shardID)
 	if err != nil {
 		return err
 	}
@@ -144,6 +152,7 @@ func (s *measure) write(shardID common.ShardID, seriesHashKey []byte, value *mea
 		Cb:          cb,
 	}
 	s.indexWriter.Write(m)
+	db.ObserveWrite(s.name, t)
 	s.processorManager.onMeasureWrite(&measurev1.WriteRequest{
 		Metadata:  s.GetMetadata(),
 		DataPoint: value,
@@ -163,13 +172,23 @@ func setUpWriteCallback(l *logger.Logger, schemaRepo *schemaRepo) bus.MessageLis
 	}
 }
 
+// Rev writes the batch of the writes coalesced by the liaison, or a single write.
 func (w *writeCallback) Rev(message bus.Message) (resp bus.Message) {
-	writeEvent, ok := message.Data().(*measurev1.InternalWriteRequest)
-	if !ok {
+	defer profiling.Label(profiling.LabelModule, "measure")()
+	switch d := message.Data().(type) {
+	case *measurev1.InternalWriteBatch:
+		for _, writeEvent := range d.GetRequests() {
+			w.write(writeEvent)
+		}
+	case *measurev1.InternalWriteRequest:
+		w.write(d)
+	default:
 		w.l.Warn().Msg("invalid event data type")
-		return
 	}
+	return
+}
 
+func (w *writeCallback) write(writeEvent *measurev1.InternalWriteRequest) {
 	stm, ok := w.schemaRepo.loadMeasure(writeEvent.GetRequest().GetMetadata())
 	if !ok {
 		w.l.Warn().Msg("cannot find measure definition")
@@ -179,7 +198,6 @@ func (w *writeCallback) Rev(message bus.Message) (resp bus.Message) {
 	if err != nil {
 		w.l.Error().Err(err).Msg("fail to write entity")
 	}
-	return
 }
 
 func familyIdentity(name string, flag []byte) []byte {
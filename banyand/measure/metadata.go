@@ -30,7 +30,9 @@ import (
 	"github.com/apache/skywalking-banyandb/banyand/metadata"
 	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
 	"github.com/apache/skywalking-banyandb/banyand/tsdb"
+	"github.com/apache/skywalking-banyandb/pkg/intern"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/partition"
 	pb_v1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
 	resourceSchema "github.com/apache/skywalking-banyandb/pkg/schema"
 )
@@ -39,19 +41,23 @@ type schemaRepo struct {
 	resourceSchema.Repository
 	l        *logger.Logger
 	metadata metadata.Repo
+	topNOpts topNOpts
+	// indexManager backfills the indices of the new bindings, it's absent until the service runs
+	indexManager *resourceSchema.IndexManager
 }
 
 func newSchemaRepo(path string, metadata metadata.Repo, repo discovery.ServiceRepo,
-	dbOpts tsdb.DatabaseOpts, l *logger.Logger,
+	dbOpts tsdb.DatabaseOpts, opts topNOpts, interning *intern.Table, l *logger.Logger,
 ) schemaRepo {
 	return schemaRepo{
 		l:        l,
 		metadata: metadata,
+		topNOpts: opts,
 		Repository: resourceSchema.NewRepository(
 			metadata,
 			repo,
 			l,
-			newSupplier(path, metadata, dbOpts, l),
+			newSupplier(path, metadata, dbOpts, opts, interning, l),
 			event.MeasureTopicShardEvent,
 			event.MeasureTopicEntityEvent,
 		),
@@ -98,6 +104,9 @@ func (sr *schemaRepo) OnAddOrUpdate(metadata schema.Metadata) {
 				Kind:     resourceSchema.EventKindResource,
 				Metadata: stm.GetMetadata(),
 			})
+			if sr.indexManager != nil {
+				sr.indexManager.Backfill(irb)
+			}
 		}
 	case schema.KindIndexRule:
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -163,6 +172,19 @@ func (sr *schemaRepo) OnDelete(metadata schema.Metadata) {
 		}
 	case schema.KindIndexRule:
 	case schema.KindTopNAggregation:
+		topNSchema := metadata.Spec.(*databasev1.TopNAggregation)
+		if sr.topNOpts.ledger != nil {
+			var err error
+			if m, ok := sr.loadMeasure(topNSchema.GetSourceMeasure()); ok {
+				err = m.processorManager.remove(topNSchema.GetMetadata())
+			} else {
+				err = removeTopN(sr.topNOpts, topNLink{Group: topNSchema.GetMetadata().GetGroup(), Name: topNSchema.GetMetadata().GetName()})
+			}
+			if err != nil {
+				// the removal is resumed once the source measure is opened
+				sr.l.Error().Err(err).Str("topN", topNSchema.GetMetadata().GetName()).Msg("fail to clean up the removed TopN aggregation")
+			}
+		}
 		// we should update instead of delete
 		sr.SendMetadataEvent(resourceSchema.MetadataEvent{
 			Typ:      resourceSchema.EventAddOrUpdate,
@@ -185,28 +207,39 @@ func (sr *schemaRepo) loadMeasure(metadata *commonv1.Metadata) (*measure, bool)
 var _ resourceSchema.ResourceSupplier = (*supplier)(nil)
 
 type supplier struct {
-	path     string
-	dbOpts   tsdb.DatabaseOpts
-	metadata metadata.Repo
-	l        *logger.Logger
+	path         string
+	dbOpts       tsdb.DatabaseOpts
+	topNOpts     topNOpts
+	metadata     metadata.Repo
+	propertyTags pb_v1.PropertyTagsGetter
+	interning    *intern.Table
+	l            *logger.Logger
 }
 
-func newSupplier(path string, metadata metadata.Repo, dbOpts tsdb.DatabaseOpts, l *logger.Logger) *supplier {
+func newSupplier(path string, metadata metadata.Repo, dbOpts tsdb.DatabaseOpts, opts topNOpts,
+	interning *intern.Table, l *logger.Logger,
+) *supplier {
 	return &supplier{
-		path:     path,
-		dbOpts:   dbOpts,
-		metadata: metadata,
-		l:        l,
+		path:      path,
+		dbOpts:    dbOpts,
+		topNOpts:  opts,
+		metadata:  metadata,
+		interning: interning,
+		l:         l,
+
+		propertyTags: resourceSchema.NewPropertyTagsGetter(metadata.PropertyRegistry()),
 	}
 }
 
-func (s *supplier) OpenResource(shardNum uint32, db tsdb.Supplier, spec resourceSchema.ResourceSpec) (resourceSchema.Resource, error) {
+func (s *supplier) OpenResource(sharding partition.Sharding, db tsdb.Supplier, spec resourceSchema.ResourceSpec) (resourceSchema.Resource, error) {
 	measureSchema := spec.Schema.(*databasev1.Measure)
-	return openMeasure(shardNum, db, measureSpec{
+	return openMeasure(sharding, db, measureSpec{
 		schema:           measureSchema,
 		indexRules:       spec.IndexRules,
 		topNAggregations: spec.Aggregations,
-	}, s.l)
+		propertyTags:     s.propertyTags,
+		interning:        s.interning,
+	}, s.topNOpts, s.l)
 }
 
 func (s *supplier) ResourceSchema(repo metadata.Repo, md *commonv1.Metadata) (resourceSchema.ResourceSchema, error) {
@@ -234,6 +267,10 @@ func (s *supplier) OpenDB(groupSchema *commonv1.Group) (tsdb.Database, error) {
 	if opts.TTL, err = pb_v1.ToIntervalRule(groupSchema.ResourceOpts.Ttl); err != nil {
 		return nil, err
 	}
+	opts = pb_v1.ApplyFlushOpts(opts, groupSchema.ResourceOpts.GetFlush())
+	if opts, err = pb_v1.ApplyTimeZone(opts, groupSchema.ResourceOpts.GetTimeZone()); err != nil {
+		return nil, err
+	}
 	return tsdb.OpenDatabase(
 		context.WithValue(context.Background(), common.PositionKey, common.Position{
 			Module:   "measure",
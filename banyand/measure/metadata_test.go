@@ -124,9 +124,14 @@ var _ = Describe("Metadata", func() {
 
 			It("should update a new measure", func() {
 				svcs.repo.EXPECT().Publish(event.MeasureTopicEntityEvent, test.NewEntityEventMatcher(databasev1.Action_ACTION_PUT)).Times(1)
-				// Remove the first tag from the entity
-				measureSchema.Entity.TagNames = measureSchema.Entity.TagNames[1:]
-				entitySize := len(measureSchema.Entity.TagNames)
+				// Append a field
+				measureSchema.Fields = append(measureSchema.Fields, &databasev1.FieldSpec{
+					Name:              "appended",
+					FieldType:         databasev1.FieldType_FIELD_TYPE_INT,
+					EncodingMethod:    databasev1.EncodingMethod_ENCODING_METHOD_GORILLA,
+					CompressionMethod: databasev1.CompressionMethod_COMPRESSION_METHOD_ZSTD,
+				})
+				fieldSize := len(measureSchema.Fields)
 
 				Expect(svcs.metadataService.MeasureRegistry().UpdateMeasure(context.TODO(), measureSchema)).Should(Succeed())
 
@@ -139,9 +144,16 @@ var _ = Describe("Metadata", func() {
 						return false
 					}
 
-					return len(val.GetSchema().GetEntity().TagNames) == entitySize
+					return len(val.GetSchema().GetFields()) == fieldSize &&
+						val.GetSchema().GetSchemaVersion() == measureSchema.GetSchemaVersion()
 				}).WithTimeout(10 * time.Second).Should(BeTrue())
 			})
+
+			It("should reject changing the type of a field", func() {
+				measureSchema.Fields[0].FieldType = databasev1.FieldType_FIELD_TYPE_STRING
+
+				Expect(svcs.metadataService.MeasureRegistry().UpdateMeasure(context.TODO(), measureSchema)).ShouldNot(Succeed())
+			})
 		})
 	})
 })
@@ -20,6 +20,7 @@ package measure
 import (
 	"context"
 	"path"
+	"strconv"
 	"time"
 
 	"github.com/pkg/errors"
@@ -27,19 +28,25 @@ import (
 	"github.com/apache/skywalking-banyandb/api/data"
 	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
 	"github.com/apache/skywalking-banyandb/banyand/discovery"
+	"github.com/apache/skywalking-banyandb/banyand/kv"
 	"github.com/apache/skywalking-banyandb/banyand/metadata"
 	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
 	"github.com/apache/skywalking-banyandb/banyand/queue"
 	"github.com/apache/skywalking-banyandb/banyand/tsdb"
 	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/cache"
+	"github.com/apache/skywalking-banyandb/pkg/flow"
+	"github.com/apache/skywalking-banyandb/pkg/intern"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 	"github.com/apache/skywalking-banyandb/pkg/run"
 	resourceSchema "github.com/apache/skywalking-banyandb/pkg/schema"
+	"github.com/apache/skywalking-banyandb/pkg/throttle"
 )
 
 var (
-	ErrEmptyRootPath   = errors.New("root path is empty")
-	ErrMeasureNotExist = errors.New("measure doesn't exist")
+	ErrEmptyRootPath       = errors.New("root path is empty")
+	ErrRecoveryConcurrency = errors.New("the recovery concurrency should be positive")
+	ErrMeasureNotExist     = errors.New("measure doesn't exist")
 )
 
 type Service interface {
@@ -51,12 +58,25 @@ type Service interface {
 
 var _ Service = (*service)(nil)
 
+var _ run.Reloader = (*service)(nil)
+
+const backgroundIORateFlag = "measure-background-io-rate"
+
 type service struct {
-	root   string
-	dbOpts tsdb.DatabaseOpts
+	root     string
+	dbOpts   tsdb.DatabaseOpts
+	topNOpts topNOpts
+	// backgroundIORate is the max bytes per second read by the background jobs
+	backgroundIORate int
+	blockCacheSize   int64
+	blockCachePolicy string
+	internSize       int
+	fsyncPolicy      string
+	timeZone         string
 
 	schemaRepo    schemaRepo
 	writeListener bus.MessageListener
+	indexManager  *resourceSchema.IndexManager
 	l             *logger.Logger
 	metadata      metadata.Repo
 	pipeline      queue.Queue
@@ -80,8 +100,32 @@ func (s *service) LoadGroup(name string) (resourceSchema.Group, bool) {
 func (s *service) FlagSet() *run.FlagSet {
 	flagS := run.NewFlagSet("storage")
 	flagS.StringVar(&s.root, "measure-root-path", "/tmp", "the root path of database")
-	flagS.Int64Var(&s.dbOpts.BlockMemSize, "measure-block-mem-size", 16<<20, "block memory size")
+	flagS.Int64Var(&s.dbOpts.BlockMemSize, "measure-block-mem-size", 16<<20, "block memory size, which is overridden by the memtable size of a group")
 	flagS.Int64Var(&s.dbOpts.SeriesMemSize, "measure-seriesmeta-mem-size", 1<<20, "series metadata memory size")
+	flagS.IntVar(&s.backgroundIORate, backgroundIORateFlag, 0,
+		"the max bytes per second read by the background jobs, e.g. merging the blocks and backfilling the indices, 0 means unlimited")
+	flagS.Int64Var(&s.blockCacheSize, "measure-block-cache-size", 0, "the size in bytes of the cache of the values read from the blocks, 0 disables the cache")
+	flagS.IntVar(&s.internSize, "measure-intern-size", 1<<16,
+		"the number of the strings of the tags interned by the writes, e.g. the names of services and endpoints, 0 disables the interning")
+	flagS.StringVar(&s.blockCachePolicy, "measure-block-cache-policy", string(cache.PolicyLRU), "the eviction policy of the block cache, lru or tinylfu")
+	flagS.StringVar(&s.fsyncPolicy, "measure-fsync-policy", string(kv.SyncPolicyOS),
+		"when the write-ahead logs are synced to the disk: os leaves them to the OS, per-write syncs before acknowledging each write, interval syncs them periodically")
+	flagS.DurationVar(&s.dbOpts.Durability.Interval, "measure-fsync-interval", time.Second,
+		"the period of syncing the write-ahead logs under the interval fsync policy, which bounds the data lost by a crash")
+	flagS.DurationVar(&s.dbOpts.IdleTimeout, "measure-idle-timeout", 0,
+		"close the blocks not written for the period, which flushes the buffered writes of their series and frees the memory, 0 disables it")
+	flagS.Int64Var(&s.dbOpts.MaxBufferedElements, "measure-max-buffered-elements", 0,
+		"flush a block once the elements written since its last flush reach the number, 0 disables it, which is overridden by the flush options of a group")
+	flagS.DurationVar(&s.dbOpts.FlushInterval, "measure-flush-interval", 0,
+		"flush the elements buffered by the blocks at least once per the interval, 0 disables it, which is overridden by the flush options of a group")
+	flagS.Int64Var(&s.dbOpts.BlockMergeSize, "measure-block-merge-size", 0,
+		"the target size in bytes of the blocks merged from the small adjacent sealed blocks of a segment, 0 disables the merging")
+	flagS.StringVar(&s.timeZone, "measure-time-zone", "Local",
+		"the IANA name of the time zone the segments and blocks align to, e.g. Asia/Shanghai, which is overridden by the time zone of a group")
+	flagS.IntVar(&s.dbOpts.RecoveryConcurrency, "measure-recovery-concurrency", tsdb.DefaultRecoveryConcurrency,
+		"the number of the shards opened in parallel at startup, whose series databases replay their write-ahead logs")
+	flagS.DurationVar(&s.topNOpts.checkpointInterval, "measure-topn-checkpoint-interval", 30*time.Second,
+		"the interval of checkpointing the state of TopN aggregations, 0 means only checkpointing on the shutdown")
 	return flagS
 }
 
@@ -89,7 +133,36 @@ func (s *service) Validate() error {
 	if s.root == "" {
 		return ErrEmptyRootPath
 	}
-	return nil
+	if s.dbOpts.RecoveryConcurrency < 1 {
+		return ErrRecoveryConcurrency
+	}
+	if err := (kv.Durability{Policy: kv.SyncPolicy(s.fsyncPolicy), Interval: s.dbOpts.Durability.Interval}).Validate(); err != nil {
+		return err
+	}
+	if _, err := time.LoadLocation(s.timeZone); err != nil {
+		return errors.Wrapf(err, "invalid time zone %q", s.timeZone)
+	}
+	return cache.CheckPolicy(cache.Policy(s.blockCachePolicy))
+}
+
+// RecoveryProgress returns the progress of opening the shards of the groups.
+func (s *service) RecoveryProgress() tsdb.RecoveryProgress {
+	return s.dbOpts.Recovery.Progress()
+}
+
+// ReloadableFlags returns the IO rate of the background jobs, which is changed at runtime.
+func (s *service) ReloadableFlags() map[string]string {
+	return map[string]string{backgroundIORateFlag: strconv.Itoa(s.dbOpts.BackgroundThrottle.Limit())}
+}
+
+func (s *service) PrepareReload(values map[string]string) (func(), error) {
+	rate, err := strconv.Atoi(values[backgroundIORateFlag])
+	if err != nil || rate < 0 {
+		return nil, errors.Errorf("invalid %s %q", backgroundIORateFlag, values[backgroundIORateFlag])
+	}
+	return func() {
+		s.dbOpts.BackgroundThrottle.SetLimit(rate)
+	}, nil
 }
 
 func (s *service) Name() string {
@@ -104,7 +177,21 @@ func (s *service) PreRun() error {
 	if err != nil {
 		return err
 	}
-	s.schemaRepo = newSchemaRepo(path.Join(s.root, s.Name()), s.metadata, s.repo, s.dbOpts, s.l)
+	if s.topNOpts.checkpointStore, err = flow.NewLocalCheckpointStore(path.Join(s.root, s.Name()+"-checkpoint")); err != nil {
+		return err
+	}
+	if s.topNOpts.ledger, err = openTopNLedger(path.Join(s.root, s.Name()+"-topn")); err != nil {
+		return err
+	}
+	s.dbOpts.BackgroundThrottle = throttle.NewAdjustable(s.backgroundIORate)
+	s.dbOpts.Durability.Policy = kv.SyncPolicy(s.fsyncPolicy)
+	// the time zone is checked by Validate
+	s.dbOpts.TimeZone, _ = time.LoadLocation(s.timeZone)
+	if s.dbOpts.BlockCache, err = cache.New(cache.Policy(s.blockCachePolicy), s.blockCacheSize); err != nil {
+		return err
+	}
+	s.dbOpts.Recovery = tsdb.NewRecovery(s.l)
+	s.schemaRepo = newSchemaRepo(path.Join(s.root, s.Name()), s.metadata, s.repo, s.dbOpts, s.topNOpts, intern.New(s.Name(), s.internSize), s.l)
 	for _, g := range groups {
 		if g.Catalog != commonv1.Catalog_CATALOG_MEASURE {
 			continue
@@ -132,7 +219,27 @@ func (s *service) PreRun() error {
 	if err != nil {
 		return err
 	}
-	return nil
+	if err := s.pipeline.Subscribe(data.TopicMeasureRollover, resourceSchema.NewRolloverListener(s.schemaRepo, s.l)); err != nil {
+		return err
+	}
+	if err := s.pipeline.Subscribe(data.TopicMeasureTimeRange, resourceSchema.NewTimeRangeListener(s.schemaRepo, s.l)); err != nil {
+		return err
+	}
+	if err := s.pipeline.Subscribe(data.TopicMeasureManifest, resourceSchema.NewManifestListener(s.schemaRepo, s.l)); err != nil {
+		return err
+	}
+	if err := s.pipeline.Subscribe(data.TopicMeasureUsage, resourceSchema.NewUsageListener(s.schemaRepo, s.l)); err != nil {
+		return err
+	}
+	if err := s.pipeline.Subscribe(data.TopicMeasureBlockExport, resourceSchema.NewBlockExportListener(s.schemaRepo, s.l)); err != nil {
+		return err
+	}
+	s.indexManager = resourceSchema.NewIndexManager(s.schemaRepo, s.l)
+	s.schemaRepo.indexManager = s.indexManager
+	if err := s.pipeline.Subscribe(data.TopicMeasureIndexStats, s.indexManager); err != nil {
+		return err
+	}
+	return s.pipeline.Subscribe(data.TopicMeasureIndexRebuild, s.indexManager)
 }
 
 func (s *service) Serve() run.StopNotify {
@@ -148,7 +255,11 @@ func (s *service) Serve() run.StopNotify {
 }
 
 func (s *service) GracefulStop() {
+	if s.indexManager != nil {
+		s.indexManager.Close()
+	}
 	s.schemaRepo.Close()
+	s.dbOpts.BlockCache.Close()
 	if s.stopCh != nil {
 		close(s.stopCh)
 	}
@@ -0,0 +1,255 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+)
+
+const topNLinkFileExt = ".json"
+
+// topNLedger links the TopN aggregations to the data and the flow state derived from them.
+//
+// The derived data of an aggregation are written to a generation of it. Once the aggregation is removed,
+// it moves to the next generation, so that the data of the former ones, which are left to the retention,
+// are never read or written again even if an aggregation with the same name is created later.
+// The checkpoints of its flows are deleted then. Each step is persisted before the next one,
+// thus a removal interrupted by a crash is resumed once the source measure is opened.
+type topNLedger struct {
+	links map[string]*topNLink
+	root  string
+	mu    sync.Mutex
+}
+
+type topNLink struct {
+	// Writing is the time bucket of the window being written by each flow, which is left by a crash
+	Writing map[string]string `json:"writing,omitempty"`
+	Group   string            `json:"group"`
+	Name    string            `json:"name"`
+	// Source is the name of the source measure
+	Source string `json:"source"`
+	// Checkpoints are the names of the checkpoints of the flows
+	Checkpoints []string `json:"checkpoints,omitempty"`
+	// Revision is the create revision of the aggregation owning the current generation
+	Revision   int64  `json:"revision"`
+	Generation uint64 `json:"generation"`
+	// Removing tells the aggregation is removed, and its checkpoints are being deleted
+	Removing bool `json:"removing,omitempty"`
+}
+
+func openTopNLedger(root string) (*topNLedger, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, errors.Wrap(err, "failed to create the directory of the TopN ledger")
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	l := &topNLedger{root: root, links: make(map[string]*topNLink)}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), topNLinkFileExt) {
+			continue
+		}
+		data, errRead := os.ReadFile(filepath.Join(root, e.Name()))
+		if errRead != nil {
+			return nil, errRead
+		}
+		link := &topNLink{}
+		if err = json.Unmarshal(data, link); err != nil {
+			return nil, errors.Wrapf(err, "the TopN link %s is corrupted", e.Name())
+		}
+		l.links[topNKey(&commonv1.Metadata{Group: link.Group, Name: link.Name})] = link
+	}
+	return l, nil
+}
+
+// generation returns the generation of the data derived from the aggregation.
+func (l *topNLedger) generation(md *commonv1.Metadata) uint64 {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if link, ok := l.links[topNKey(md)]; ok {
+		return link.Generation
+	}
+	return 0
+}
+
+// track links the aggregation to the checkpoints of its flows.
+func (l *topNLedger) track(topNSchema *databasev1.TopNAggregation, checkpoints []string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	md := topNSchema.GetMetadata()
+	link, ok := l.links[topNKey(md)]
+	if !ok {
+		link = &topNLink{Group: md.GetGroup(), Name: md.GetName()}
+	}
+	cloned := *link
+	cloned.Source = topNSchema.GetSourceMeasure().GetName()
+	cloned.Revision = md.GetCreateRevision()
+	cloned.Checkpoints = checkpoints
+	return l.save(&cloned)
+}
+
+// remove moves the aggregation to the next generation, and returns the link to the checkpoints to delete.
+// The generation isn't moved again if the aggregation is being removed.
+func (l *topNLedger) remove(md *commonv1.Metadata) (topNLink, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	link, ok := l.links[topNKey(md)]
+	if !ok {
+		link = &topNLink{Group: md.GetGroup(), Name: md.GetName()}
+	}
+	if link.Removing {
+		return *link, nil
+	}
+	cloned := *link
+	cloned.Generation++
+	cloned.Revision = 0
+	cloned.Removing = true
+	return cloned, l.save(&cloned)
+}
+
+// removed finishes the removal of the aggregation, whose link is left to keep the generation.
+func (l *topNLedger) removed(md *commonv1.Metadata) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	link, ok := l.links[topNKey(md)]
+	if !ok {
+		return nil
+	}
+	cloned := *link
+	cloned.Checkpoints = nil
+	cloned.Writing = nil
+	cloned.Removing = false
+	return l.save(&cloned)
+}
+
+// stale returns the links of the source measure which are being removed, or whose aggregations are absent from the schemas.
+// An aggregation created again with the same name is absent as well since its create revision changes.
+func (l *topNLedger) stale(group, source string, topNSchemas []*databasev1.TopNAggregation) (result []topNLink) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	revisions := make(map[string]int64, len(topNSchemas))
+	for _, s := range topNSchemas {
+		revisions[s.GetMetadata().GetName()] = s.GetMetadata().GetCreateRevision()
+	}
+	for _, link := range l.links {
+		if link.Group != group || link.Source != source || (link.Revision == 0 && !link.Removing) {
+			continue
+		}
+		revision, ok := revisions[link.Name]
+		if link.Removing || !ok || revision != link.Revision {
+			result = append(result, *link)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// begin records the window which the flow starts to write.
+func (l *topNLedger) begin(md *commonv1.Metadata, checkpoint, timeBucket string) error {
+	return l.write(md, checkpoint, timeBucket)
+}
+
+// commit records the window written by the flow completely.
+func (l *topNLedger) commit(md *commonv1.Metadata, checkpoint string) error {
+	return l.write(md, checkpoint, "")
+}
+
+// pending returns the window which the flow was writing when the node crashed.
+func (l *topNLedger) pending(md *commonv1.Metadata, checkpoint string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if link, ok := l.links[topNKey(md)]; ok {
+		return link.Writing[checkpoint]
+	}
+	return ""
+}
+
+func (l *topNLedger) write(md *commonv1.Metadata, checkpoint, timeBucket string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	link, ok := l.links[topNKey(md)]
+	if !ok {
+		return errors.Errorf("the TopN aggregation %s isn't tracked", md)
+	}
+	cloned := *link
+	cloned.Writing = make(map[string]string, len(link.Writing)+1)
+	for k, v := range link.Writing {
+		cloned.Writing[k] = v
+	}
+	if timeBucket == "" {
+		delete(cloned.Writing, checkpoint)
+	} else {
+		cloned.Writing[checkpoint] = timeBucket
+	}
+	return l.save(&cloned)
+}
+
+// save persists the link before it replaces the one in the memory, which keeps the ledger unchanged if it fails.
+func (l *topNLedger) save(link *topNLink) (err error) {
+	data, err := json.Marshal(link)
+	if err != nil {
+		return err
+	}
+	key := topNKey(&commonv1.Metadata{Group: link.Group, Name: link.Name})
+	target := filepath.Join(l.root, key+topNLinkFileExt)
+	tmp := target + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp)
+		}
+	}()
+	if _, err = f.Write(data); err != nil {
+		return multierr.Append(err, f.Close())
+	}
+	if err = f.Sync(); err != nil {
+		return multierr.Append(err, f.Close())
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, target); err != nil {
+		return err
+	}
+	l.links[key] = link
+	return nil
+}
+
+func topNKey(md *commonv1.Metadata) string {
+	return md.GetGroup() + "." + md.GetName()
+}
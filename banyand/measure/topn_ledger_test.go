@@ -0,0 +1,97 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/pkg/flow"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+)
+
+func TestTopNLedgerRemoval(t *testing.T) {
+	req := require.New(t)
+	root, deferFn := test.Space(req)
+	defer deferFn()
+	store, err := flow.NewLocalCheckpointStore(filepath.Join(root, "checkpoint"))
+	req.NoError(err)
+	open := func() topNOpts {
+		ledger, errOpen := openTopNLedger(filepath.Join(root, "topn"))
+		req.NoError(errOpen)
+		return topNOpts{checkpointStore: store, ledger: ledger}
+	}
+	opts := open()
+	md := &commonv1.Metadata{Group: "sw_metric", Name: "endpoint_top", CreateRevision: 10}
+	topN := &databasev1.TopNAggregation{Metadata: md, SourceMeasure: &commonv1.Metadata{Group: "sw_metric", Name: "endpoint"}}
+	req.NoError(opts.ledger.track(topN, []string{"sw_metric.endpoint_top.SORT_DESC"}))
+	req.NoError(store.Save("sw_metric.endpoint_top.SORT_DESC", &flow.Checkpoint{ID: 1}))
+	assert.Empty(t, opts.ledger.stale("sw_metric", "endpoint", []*databasev1.TopNAggregation{topN}))
+
+	link, err := opts.ledger.remove(md)
+	req.NoError(err)
+	assert.EqualValues(t, 1, link.Generation)
+	assert.EqualValues(t, 1, opts.ledger.generation(md), "the derived data move to the next generation at once")
+
+	// the node crashes before the checkpoints are deleted
+	opts = open()
+	stale := opts.ledger.stale("sw_metric", "endpoint", nil)
+	req.Len(stale, 1)
+	assert.True(t, stale[0].Removing)
+	req.NoError(removeTopN(opts, stale[0]))
+	checkpoint, err := store.Load("sw_metric.endpoint_top.SORT_DESC")
+	req.NoError(err)
+	assert.Nil(t, checkpoint)
+	assert.Empty(t, opts.ledger.stale("sw_metric", "endpoint", nil))
+
+	opts = open()
+	assert.EqualValues(t, 1, opts.ledger.generation(md), "the generation survives the removal")
+	md.CreateRevision = 20
+	req.NoError(opts.ledger.track(topN, []string{"sw_metric.endpoint_top.1.SORT_DESC"}))
+	assert.Empty(t, opts.ledger.stale("sw_metric", "endpoint", []*databasev1.TopNAggregation{topN}))
+	recreated := &databasev1.TopNAggregation{
+		Metadata:      &commonv1.Metadata{Group: "sw_metric", Name: "endpoint_top", CreateRevision: 30},
+		SourceMeasure: topN.SourceMeasure,
+	}
+	assert.Len(t, opts.ledger.stale("sw_metric", "endpoint", []*databasev1.TopNAggregation{recreated}), 1,
+		"the aggregation removed and created again while the node is down is stale")
+}
+
+func TestTopNLedgerPendingWindow(t *testing.T) {
+	req := require.New(t)
+	root, deferFn := test.Space(req)
+	defer deferFn()
+	ledger, err := openTopNLedger(root)
+	req.NoError(err)
+	md := &commonv1.Metadata{Group: "sw_metric", Name: "endpoint_top", CreateRevision: 10}
+	req.Error(ledger.begin(md, "asc", "202210101010"), "the aggregation isn't tracked")
+	req.NoError(ledger.track(&databasev1.TopNAggregation{Metadata: md, SourceMeasure: &commonv1.Metadata{Name: "endpoint"}}, []string{"asc", "desc"}))
+	req.NoError(ledger.begin(md, "asc", "202210101010"))
+	req.NoError(ledger.begin(md, "desc", "202210101010"))
+	req.NoError(ledger.commit(md, "desc"))
+
+	ledger, err = openTopNLedger(root)
+	req.NoError(err)
+	assert.Equal(t, "202210101010", ledger.pending(md, "asc"))
+	assert.Empty(t, ledger.pending(md, "desc"))
+}
@@ -56,6 +56,8 @@ type Service interface {
 	run.Service
 	run.Config
 	SchemaRegistry() schema.Registry
+	// RegisterNode registers the node sharing the schema registry, which is removed once it stops or its lease expires.
+	RegisterNode(ctx context.Context, node *databasev1.Node) error
 }
 
 type service struct {
@@ -63,6 +65,13 @@ type service struct {
 	rootDir         string
 	listenClientURL string
 	listenPeerURL   string
+	endpoints       []string
+	tlsCAFile       string
+	tlsCertFile     string
+	tlsKeyFile      string
+	username        string
+	password        string
+	nodeLeaseTTL    time.Duration
 }
 
 func (s *service) FlagSet() *run.FlagSet {
@@ -70,6 +79,15 @@ func (s *service) FlagSet() *run.FlagSet {
 	fs.StringVarP(&s.rootDir, "metadata-root-path", "", "/tmp", "the root path of metadata")
 	fs.StringVarP(&s.listenClientURL, "etcd-listen-client-url", "", "http://localhost:2379", "A URL to listen on for client traffic")
 	fs.StringVarP(&s.listenPeerURL, "etcd-listen-peer-url", "", "http://localhost:2380", "A URL to listen on for peer traffic")
+	fs.StringSliceVarP(&s.endpoints, "etcd-endpoints", "", nil,
+		"the endpoints of an external etcd cluster shared by the nodes, the embedded etcd server is started if it's empty")
+	fs.StringVarP(&s.tlsCAFile, "etcd-tls-ca-file", "", "", "the CA file verifying the external etcd cluster")
+	fs.StringVarP(&s.tlsCertFile, "etcd-tls-cert-file", "", "", "the cert file authenticating to the external etcd cluster")
+	fs.StringVarP(&s.tlsKeyFile, "etcd-tls-key-file", "", "", "the key file of the cert authenticating to the external etcd cluster")
+	fs.StringVarP(&s.username, "etcd-username", "", "", "the username authenticating to the external etcd cluster")
+	fs.StringVarP(&s.password, "etcd-password", "", "", "the password authenticating to the external etcd cluster")
+	fs.DurationVarP(&s.nodeLeaseTTL, "node-lease-ttl", "", 10*time.Second,
+		"the ttl of the lease of a registered node, after which the node is removed if it doesn't keep the lease alive")
 	return fs
 }
 
@@ -77,6 +95,12 @@ func (s *service) Validate() error {
 	if s.rootDir == "" {
 		return errors.New("rootDir is empty")
 	}
+	if (s.tlsCertFile == "") != (s.tlsKeyFile == "") {
+		return errors.New("the etcd TLS cert file and key file should be set together")
+	}
+	if s.nodeLeaseTTL < time.Second {
+		return errors.New("the node lease ttl should be one second at least")
+	}
 	return nil
 }
 
@@ -84,7 +108,10 @@ func (s *service) PreRun() error {
 	var err error
 	s.schemaRegistry, err = schema.NewEtcdSchemaRegistry(
 		schema.ConfigureListener(s.listenClientURL, s.listenPeerURL),
-		schema.RootDir(s.rootDir), schema.LoggerLevel(logger.GetLogger().GetLevel().String()))
+		schema.ConfigureServerEndpoints(s.endpoints),
+		schema.ConfigureTLS(s.tlsCAFile, s.tlsCertFile, s.tlsKeyFile),
+		schema.ConfigureAuth(s.username, s.password),
+		schema.RootDir(s.rootDir), schema.LoggerLevel(logger.Level()))
 	if err != nil {
 		return err
 	}
@@ -96,6 +123,15 @@ func (s *service) Serve() run.StopNotify {
 	return s.schemaRegistry.StoppingNotify()
 }
 
+func (s *service) CheckHealth() error {
+	select {
+	case <-s.schemaRegistry.StoppingNotify():
+		return errors.New("the schema registry is stopping")
+	default:
+		return nil
+	}
+}
+
 func (s *service) GracefulStop() {
 	_ = s.schemaRegistry.Close()
 	<-s.schemaRegistry.StopNotify()
@@ -109,6 +145,10 @@ func (s *service) SchemaRegistry() schema.Registry {
 	return s.schemaRegistry
 }
 
+func (s *service) RegisterNode(ctx context.Context, node *databasev1.Node) error {
+	return s.schemaRegistry.RegisterNode(ctx, node, s.nodeLeaseTTL)
+}
+
 func (s *service) StreamRegistry() schema.Stream {
 	return s.schemaRegistry
 }
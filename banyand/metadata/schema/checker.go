@@ -48,7 +48,7 @@ var checkerMap = map[Kind]equalityChecker{
 	KindMeasure: func(a, b proto.Message) bool {
 		return cmp.Equal(a, b,
 			protocmp.IgnoreUnknown(),
-			protocmp.IgnoreFields(&databasev1.Measure{}, "updated_at"),
+			protocmp.IgnoreFields(&databasev1.Measure{}, "updated_at", "schema_version"),
 			protocmp.IgnoreFields(&commonv1.Metadata{}, "id", "create_revision", "mod_revision"),
 			protocmp.Transform(),
 		)
@@ -56,7 +56,7 @@ var checkerMap = map[Kind]equalityChecker{
 	KindStream: func(a, b proto.Message) bool {
 		return cmp.Equal(a, b,
 			protocmp.IgnoreUnknown(),
-			protocmp.IgnoreFields(&databasev1.Stream{}, "updated_at"),
+			protocmp.IgnoreFields(&databasev1.Stream{}, "updated_at", "schema_version"),
 			protocmp.IgnoreFields(&commonv1.Metadata{}, "id", "create_revision", "mod_revision"),
 			protocmp.Transform())
 	},
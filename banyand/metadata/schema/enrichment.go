@@ -0,0 +1,63 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package schema
+
+import (
+	"fmt"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+)
+
+// validateTagFilling validates the default values and the enrichments filling the absent tags.
+// The type of a default value should match its tag, and tags composing the entity can not take the default value or be enriched.
+func validateTagFilling(families []*databasev1.TagFamilySpec, entity *databasev1.Entity, enrichments []*databasev1.TagEnrichment) error {
+	for i, family := range families {
+		for j, tag := range family.GetTags() {
+			if tag.GetDefaultValue() == nil {
+				continue
+			}
+			field := fmt.Sprintf("tag_families[%d].tags[%d].default_value", i, j)
+			for _, name := range entity.GetTagNames() {
+				if name == tag.GetName() {
+					return BadRequest(field, fmt.Sprintf("the tag %s composes the entity and can not take the default value", tag.GetName()))
+				}
+			}
+			if err := pbv1.CheckTagValue(tag, tag.GetDefaultValue()); err != nil {
+				return BadRequest(field, err.Error())
+			}
+		}
+	}
+	for i, e := range enrichments {
+		if _, _, spec := pbv1.FindTagByName(families, e.GetKeyTagName()); spec == nil {
+			return BadRequest(fmt.Sprintf("enrichments[%d].key_tag_name", i), fmt.Sprintf("the key tag %s is not defined", e.GetKeyTagName()))
+		}
+		for j, name := range e.GetTagNames() {
+			field := fmt.Sprintf("enrichments[%d].tag_names[%d]", i, j)
+			if _, _, spec := pbv1.FindTagByName(families, name); spec == nil {
+				return BadRequest(field, fmt.Sprintf("the tag %s is not defined", name))
+			}
+			for _, entityTag := range entity.GetTagNames() {
+				if entityTag == name {
+					return BadRequest(field, fmt.Sprintf("the tag %s composes the entity and can not be enriched", name))
+				}
+			}
+		}
+	}
+	return nil
+}
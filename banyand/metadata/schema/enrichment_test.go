@@ -0,0 +1,86 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+)
+
+func Test_Validate_Tag_Filling(t *testing.T) {
+	str := &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: "GENERAL"}}}
+	families := func(typ databasev1.TagType, name string) []*databasev1.TagFamilySpec {
+		return []*databasev1.TagFamilySpec{{
+			Name: "default",
+			Tags: []*databasev1.TagSpec{
+				{Name: "service_id", Type: databasev1.TagType_TAG_TYPE_STRING},
+				{Name: name, Type: typ, DefaultValue: str},
+			},
+		}}
+	}
+	entity := &databasev1.Entity{TagNames: []string{"service_id"}}
+	tests := []struct {
+		name        string
+		families    []*databasev1.TagFamilySpec
+		enrichments []*databasev1.TagEnrichment
+		wantErr     bool
+	}{
+		{name: "matched default value", families: families(databasev1.TagType_TAG_TYPE_STRING, "layer")},
+		{name: "mismatched default value", families: families(databasev1.TagType_TAG_TYPE_INT, "layer"), wantErr: true},
+		{name: "default value of the entity", families: families(databasev1.TagType_TAG_TYPE_STRING, "service_id"), wantErr: true},
+		{
+			name:        "undefined key tag of the enrichment",
+			families:    families(databasev1.TagType_TAG_TYPE_STRING, "layer"),
+			enrichments: []*databasev1.TagEnrichment{{Container: "service", KeyTagName: "service_name"}},
+			wantErr:     true,
+		},
+		{
+			name:        "enriched tag",
+			families:    families(databasev1.TagType_TAG_TYPE_STRING, "layer"),
+			enrichments: []*databasev1.TagEnrichment{{Container: "service", KeyTagName: "service_id", TagNames: []string{"layer"}}},
+		},
+		{
+			name:        "undefined enriched tag",
+			families:    families(databasev1.TagType_TAG_TYPE_STRING, "layer"),
+			enrichments: []*databasev1.TagEnrichment{{Container: "service", KeyTagName: "service_id", TagNames: []string{"region"}}},
+			wantErr:     true,
+		},
+		{
+			name:        "enriched tag of the entity",
+			families:    families(databasev1.TagType_TAG_TYPE_STRING, "layer"),
+			enrichments: []*databasev1.TagEnrichment{{Container: "service", KeyTagName: "service_id", TagNames: []string{"layer", "service_id"}}},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTagFilling(tt.families, entity, tt.enrichments)
+			if tt.wantErr {
+				assert.Equal(t, codes.InvalidArgument, status.Code(err), err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
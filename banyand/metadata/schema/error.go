@@ -33,6 +33,8 @@ var (
 	ErrGRPCAlreadyExists       = statusGRPCAlreadyExists.Err()
 	statusDataLoss             = status.New(codes.DataLoss, "banyandb: resource corrupts.")
 	ErrGRPCDataLoss            = statusDataLoss.Err()
+	statusRevisionMismatch     = status.New(codes.Aborted, "banyandb: the revision doesn't match the resource's")
+	ErrGRPCRevisionMismatch    = statusRevisionMismatch.Err()
 )
 
 func IsNotFound(err error) bool {
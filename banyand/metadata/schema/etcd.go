@@ -19,12 +19,17 @@ package schema
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
+	"go.etcd.io/etcd/api/v3/mvccpb"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/server/v3/embed"
 	"google.golang.org/protobuf/proto"
@@ -46,6 +51,11 @@ var (
 	ErrConcurrentModification     = errors.New("concurrent modification of entities")
 )
 
+const (
+	dialTimeout  = 5 * time.Second
+	closeTimeout = 5 * time.Second
+)
+
 type HasMetadata interface {
 	GetMetadata() *commonv1.Metadata
 	proto.Message
@@ -72,6 +82,30 @@ func ConfigureListener(lc, lp string) RegistryOption {
 	}
 }
 
+// ConfigureServerEndpoints connects the registry to an external etcd cluster instead of starting an embedded server.
+func ConfigureServerEndpoints(endpoints []string) RegistryOption {
+	return func(config *etcdSchemaRegistryConfig) {
+		config.serverEndpoints = endpoints
+	}
+}
+
+// ConfigureTLS secures the connections to the external cluster. The files are optional, e.g. no client cert.
+func ConfigureTLS(caFile, certFile, keyFile string) RegistryOption {
+	return func(config *etcdSchemaRegistryConfig) {
+		config.tlsCAFile = caFile
+		config.tlsCertFile = certFile
+		config.tlsKeyFile = keyFile
+	}
+}
+
+// ConfigureAuth authenticates the registry to the external cluster.
+func ConfigureAuth(username, password string) RegistryOption {
+	return func(config *etcdSchemaRegistryConfig) {
+		config.username = username
+		config.password = password
+	}
+}
+
 type eventHandler struct {
 	interestKeys Kind
 	handler      EventHandler
@@ -82,10 +116,23 @@ func (eh *eventHandler) InterestOf(kind Kind) bool {
 }
 
 type etcdSchemaRegistry struct {
-	server   *embed.Etcd
-	kv       clientv3.KV
-	handlers []*eventHandler
-	mux      sync.RWMutex
+	server     *embed.Etcd
+	client     *clientv3.Client
+	kv         clientv3.KV
+	lease      clientv3.Lease
+	handlers   []*eventHandler
+	nodeLeases []clientv3.LeaseID
+	mux        sync.RWMutex
+	// external tells the registry is shared with the other nodes through an external cluster.
+	// The handlers are notified of all the changes by watching the cluster then.
+	external bool
+	stopCtx  context.Context
+	stop     context.CancelFunc
+	wg       sync.WaitGroup
+	// the lifecycle of the registry without an embedded server
+	stopping  chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
 }
 
 type etcdSchemaRegistryConfig struct {
@@ -97,6 +144,13 @@ type etcdSchemaRegistryConfig struct {
 	listenerPeerURL string
 	// loggerLevel defines log level
 	loggerLevel string
+	// serverEndpoints are the endpoints of an external cluster, no server is embedded if there are any
+	serverEndpoints []string
+	tlsCAFile       string
+	tlsCertFile     string
+	tlsKeyFile      string
+	username        string
+	password        string
 }
 
 func (e *etcdSchemaRegistry) RegisterHandler(kind Kind, handler EventHandler) {
@@ -108,7 +162,21 @@ func (e *etcdSchemaRegistry) RegisterHandler(kind Kind, handler EventHandler) {
 	})
 }
 
+// notifyUpdate notifies the handlers of a change made by the registry itself.
+// The changes are notified by the watch if the registry is external, which covers the ones made by the other nodes.
 func (e *etcdSchemaRegistry) notifyUpdate(metadata Metadata) {
+	if !e.external {
+		e.dispatchUpdate(metadata)
+	}
+}
+
+func (e *etcdSchemaRegistry) notifyDelete(metadata Metadata) {
+	if !e.external {
+		e.dispatchDelete(metadata)
+	}
+}
+
+func (e *etcdSchemaRegistry) dispatchUpdate(metadata Metadata) {
 	e.mux.RLock()
 	hh := e.handlers
 	e.mux.RUnlock()
@@ -119,7 +187,7 @@ func (e *etcdSchemaRegistry) notifyUpdate(metadata Metadata) {
 	}
 }
 
-func (e *etcdSchemaRegistry) notifyDelete(metadata Metadata) {
+func (e *etcdSchemaRegistry) dispatchDelete(metadata Metadata) {
 	e.mux.RLock()
 	hh := e.handlers
 	e.mux.RUnlock()
@@ -131,19 +199,52 @@ func (e *etcdSchemaRegistry) notifyDelete(metadata Metadata) {
 }
 
 func (e *etcdSchemaRegistry) ReadyNotify() <-chan struct{} {
+	if e.server == nil {
+		// the registry is ready once it's connected
+		ready := make(chan struct{})
+		close(ready)
+		return ready
+	}
 	return e.server.Server.ReadyNotify()
 }
 
 func (e *etcdSchemaRegistry) StopNotify() <-chan struct{} {
+	if e.server == nil {
+		return e.stopped
+	}
 	return e.server.Server.StopNotify()
 }
 
 func (e *etcdSchemaRegistry) StoppingNotify() <-chan struct{} {
+	if e.server == nil {
+		return e.stopping
+	}
 	return e.server.Server.StoppingNotify()
 }
 
+// Close revokes the leases of the registered nodes, so that the others know they leave.
 func (e *etcdSchemaRegistry) Close() error {
-	e.server.Close()
+	e.closeOnce.Do(func() {
+		if e.stopping != nil {
+			close(e.stopping)
+		}
+		e.mux.RLock()
+		leases := e.nodeLeases
+		e.mux.RUnlock()
+		ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+		for _, id := range leases {
+			e.revoke(ctx, id)
+		}
+		cancel()
+		e.stop()
+		e.wg.Wait()
+		_ = e.client.Close()
+		if e.server != nil {
+			e.server.Close()
+			return
+		}
+		close(e.stopped)
+	})
 	return nil
 }
 
@@ -156,27 +257,175 @@ func NewEtcdSchemaRegistry(options ...RegistryOption) (Registry, error) {
 	for _, opt := range options {
 		opt(registryConfig)
 	}
-	// TODO: allow use cluster setting
-	embedConfig := newStandaloneEtcdConfig(registryConfig)
-	e, err := embed.StartEtcd(embedConfig)
-	if err != nil {
-		return nil, err
-	}
-	if e != nil {
+	reg := &etcdSchemaRegistry{}
+	endpoints := registryConfig.serverEndpoints
+	if len(endpoints) < 1 {
+		embedConfig := newStandaloneEtcdConfig(registryConfig)
+		e, err := embed.StartEtcd(embedConfig)
+		if err != nil {
+			return nil, err
+		}
 		<-e.Server.ReadyNotify() // wait for e.Server to join the cluster
+		reg.server = e
+		endpoints = []string{e.Config().ACUrls[0].String()}
+	} else {
+		reg.external = true
+		reg.stopping = make(chan struct{})
+		reg.stopped = make(chan struct{})
 	}
-	client, err := clientv3.NewFromURL(e.Config().ACUrls[0].String())
+	client, err := newEtcdClient(endpoints, registryConfig)
 	if err != nil {
+		if reg.server != nil {
+			reg.server.Close()
+		}
 		return nil, err
 	}
-	kvClient := clientv3.NewKV(client)
-	reg := &etcdSchemaRegistry{
-		server: e,
-		kv:     kvClient,
+	reg.client = client
+	reg.kv = clientv3.NewKV(client)
+	reg.lease = clientv3.NewLease(client)
+	reg.stopCtx, reg.stop = context.WithCancel(context.Background())
+	prefixes := []string{NodeKeyPrefix}
+	if reg.external {
+		prefixes = append(prefixes, GroupsKeyPrefix)
+	}
+	for _, prefix := range prefixes {
+		if err = reg.watch(prefix); err != nil {
+			_ = reg.Close()
+			return nil, err
+		}
 	}
 	return reg, nil
 }
 
+func newEtcdClient(endpoints []string, config *etcdSchemaRegistryConfig) (*clientv3.Client, error) {
+	cfg := clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: dialTimeout,
+		Username:    config.username,
+		Password:    config.password,
+	}
+	if config.tlsCAFile != "" || config.tlsCertFile != "" {
+		tlsConfig, err := newTLSConfig(config.tlsCAFile, config.tlsCertFile, config.tlsKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.TLS = tlsConfig
+	}
+	client, err := clientv3.New(cfg)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "connect to the etcd cluster %v", endpoints)
+	}
+	// the client connects lazily, so the cluster is checked in advance
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+	if _, err = client.Get(ctx, GroupsKeyPrefix, clientv3.WithCountOnly()); err != nil {
+		_ = client.Close()
+		return nil, errors.WithMessagef(err, "connect to the etcd cluster %v", endpoints)
+	}
+	return client, nil
+}
+
+func newTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, errors.WithMessage(err, "read the CA file")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.Errorf("no valid certificate in the CA file %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, errors.WithMessage(err, "load the client cert and key")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// watch notifies the handlers of the changes of the entities under the prefix since the registry starts.
+func (e *etcdSchemaRegistry) watch(prefix string) error {
+	resp, err := e.kv.Get(e.stopCtx, prefix, clientv3.WithPrefix(), clientv3.WithCountOnly())
+	if err != nil {
+		return err
+	}
+	ch := e.client.Watch(e.stopCtx, prefix, clientv3.WithPrefix(), clientv3.WithPrevKV(), clientv3.WithRev(resp.Header.Revision+1))
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		for wr := range ch {
+			for _, ev := range wr.Events {
+				e.dispatchEvent(ev)
+			}
+		}
+	}()
+	return nil
+}
+
+func (e *etcdSchemaRegistry) dispatchEvent(ev *clientv3.Event) {
+	tm, ok := parseKey(string(ev.Kv.Key))
+	if !ok {
+		return
+	}
+	kv := ev.Kv
+	if ev.Type == mvccpb.DELETE {
+		if ev.PrevKv == nil {
+			return
+		}
+		kv = ev.PrevKv
+	}
+	spec, err := tm.Unmarshal(kv.Value)
+	if err != nil {
+		return
+	}
+	assignReadonly(spec, kv)
+	if ev.Type == mvccpb.DELETE {
+		e.dispatchDelete(Metadata{TypeMeta: tm, Spec: spec})
+		return
+	}
+	e.dispatchUpdate(Metadata{TypeMeta: tm, Spec: spec})
+}
+
+// parseKey tells the entity stored by a key, which is formatted by Metadata.Key.
+func parseKey(key string) (TypeMeta, bool) {
+	if strings.HasPrefix(key, NodeKeyPrefix) {
+		return TypeMeta{Kind: KindNode, Name: strings.TrimPrefix(key, NodeKeyPrefix)}, true
+	}
+	if !strings.HasPrefix(key, GroupsKeyPrefix) {
+		return TypeMeta{}, false
+	}
+	rest := strings.TrimPrefix(key, GroupsKeyPrefix)
+	i := strings.Index(rest, "/")
+	if i < 0 {
+		return TypeMeta{}, false
+	}
+	group, rest := rest[:i], rest[i:]
+	if rest == GroupMetadataKey {
+		return TypeMeta{Kind: KindGroup, Name: group}, true
+	}
+	for _, p := range []struct {
+		prefix string
+		kind   Kind
+	}{
+		{StreamKeyPrefix, KindStream},
+		{MeasureKeyPrefix, KindMeasure},
+		{IndexRuleBindingKeyPrefix, KindIndexRuleBinding},
+		{IndexRuleKeyPrefix, KindIndexRule},
+		{TopNAggregationKeyPrefix, KindTopNAggregation},
+		{PropertyKeyPrefix, KindProperty},
+	} {
+		if strings.HasPrefix(rest, p.prefix) {
+			return TypeMeta{Kind: p.kind, Group: group, Name: strings.TrimPrefix(rest, p.prefix)}, true
+		}
+	}
+	return TypeMeta{}, false
+}
+
 func (e *etcdSchemaRegistry) get(ctx context.Context, key string, message proto.Message) error {
 	resp, err := e.kv.Get(ctx, key)
 	if err != nil {
@@ -191,14 +440,21 @@ func (e *etcdSchemaRegistry) get(ctx context.Context, key string, message proto.
 	if err = proto.Unmarshal(resp.Kvs[0].Value, message); err != nil {
 		return err
 	}
-	if messageWithMetadata, ok := message.(HasMetadata); ok {
-		// Assign readonly fields
-		messageWithMetadata.GetMetadata().CreateRevision = resp.Kvs[0].CreateRevision
-		messageWithMetadata.GetMetadata().ModRevision = resp.Kvs[0].ModRevision
-	}
+	assignReadonly(message, resp.Kvs[0])
 	return nil
 }
 
+// assignReadonly fills the readonly fields of the message stored in the kv.
+func assignReadonly(message proto.Message, kv *mvccpb.KeyValue) {
+	switch m := message.(type) {
+	case HasMetadata:
+		m.GetMetadata().CreateRevision = kv.CreateRevision
+		m.GetMetadata().ModRevision = kv.ModRevision
+	case *propertyv1.Property:
+		m.ModRevision = kv.ModRevision
+	}
+}
+
 // update will first ensure the existence of the entity with the metadata,
 // and overwrite the existing value if so.
 // Otherwise, it will return ErrGRPCResourceNotFound.
@@ -214,10 +470,6 @@ func (e *etcdSchemaRegistry) update(ctx context.Context, metadata Metadata) erro
 	if getResp.Count > 1 {
 		return ErrUnexpectedNumberOfEntities
 	}
-	val, err := proto.Marshal(metadata.Spec.(proto.Message))
-	if err != nil {
-		return err
-	}
 	replace := getResp.Count > 0
 	if replace {
 		existingVal, innerErr := metadata.Unmarshal(getResp.Kvs[0].Value)
@@ -228,6 +480,15 @@ func (e *etcdSchemaRegistry) update(ctx context.Context, metadata Metadata) erro
 		if metadata.Equal(existingVal) {
 			return nil
 		}
+		if evolve, ok := evolverMap[metadata.Kind]; ok {
+			if innerErr = evolve(existingVal, metadata.Spec.(proto.Message)); innerErr != nil {
+				return innerErr
+			}
+		}
+		val, innerErr := proto.Marshal(metadata.Spec.(proto.Message))
+		if innerErr != nil {
+			return innerErr
+		}
 
 		modRevision := getResp.Kvs[0].ModRevision
 		txnResp, txnErr := e.kv.Txn(context.Background()).
@@ -240,6 +501,7 @@ func (e *etcdSchemaRegistry) update(ctx context.Context, metadata Metadata) erro
 		if !txnResp.Succeeded {
 			return ErrConcurrentModification
 		}
+		assignRevisions(metadata.Spec, getResp.Kvs[0].CreateRevision, txnResp.Header.Revision)
 	} else {
 		return ErrGRPCResourceNotFound
 	}
@@ -270,15 +532,24 @@ func (e *etcdSchemaRegistry) create(ctx context.Context, metadata Metadata) erro
 	if replace {
 		return ErrGRPCAlreadyExists
 	}
-	_, err = e.kv.Put(ctx, key, string(val))
+	putResp, err := e.kv.Put(ctx, key, string(val))
 	if err != nil {
 		return err
 	}
+	assignRevisions(metadata.Spec, putResp.Header.Revision, putResp.Header.Revision)
 
 	e.notifyUpdate(metadata)
 	return nil
 }
 
+// assignRevisions fills the readonly revisions in the spec, by which the handlers tell a creation from an update.
+func assignRevisions(spec Spec, createRevision, modRevision int64) {
+	if messageWithMetadata, ok := spec.(HasMetadata); ok && messageWithMetadata.GetMetadata() != nil {
+		messageWithMetadata.GetMetadata().CreateRevision = createRevision
+		messageWithMetadata.GetMetadata().ModRevision = modRevision
+	}
+}
+
 func (e *etcdSchemaRegistry) listWithPrefix(ctx context.Context, prefix string, factory func() proto.Message) ([]proto.Message, error) {
 	resp, err := e.kv.Get(ctx, prefix, clientv3.WithFromKey(), clientv3.WithRange(incrementLastByte(prefix)))
 	if err != nil {
@@ -291,11 +562,7 @@ func (e *etcdSchemaRegistry) listWithPrefix(ctx context.Context, prefix string,
 			return nil, innerErr
 		}
 		entities[i] = message
-		if messageWithMetadata, ok := message.(HasMetadata); ok {
-			// Assign readonly fields
-			messageWithMetadata.GetMetadata().CreateRevision = resp.Kvs[i].CreateRevision
-			messageWithMetadata.GetMetadata().ModRevision = resp.Kvs[i].ModRevision
-		}
+		assignReadonly(message, resp.Kvs[i])
 	}
 	return entities, nil
 }
@@ -304,15 +571,21 @@ func listPrefixesForEntity(group, entityPrefix string) string {
 	return GroupsKeyPrefix + group + entityPrefix
 }
 
-func (e *etcdSchemaRegistry) delete(ctx context.Context, metadata Metadata) (bool, error) {
+// delete removes the entity with the metadata if all the comparisons succeed.
+// Otherwise, it will return ErrGRPCRevisionMismatch.
+func (e *etcdSchemaRegistry) delete(ctx context.Context, metadata Metadata, cmps ...clientv3.Cmp) (bool, error) {
 	key, err := metadata.Key()
 	if err != nil {
 		return false, err
 	}
-	resp, err := e.kv.Delete(ctx, key, clientv3.WithPrevKV())
+	txnResp, err := e.kv.Txn(ctx).If(cmps...).Then(clientv3.OpDelete(key, clientv3.WithPrevKV())).Commit()
 	if err != nil {
 		return false, err
 	}
+	if !txnResp.Succeeded {
+		return false, ErrGRPCRevisionMismatch
+	}
+	resp := txnResp.Responses[0].GetResponseDeleteRange()
 	if resp.Deleted == 1 {
 		var message proto.Message
 		switch metadata.Kind {
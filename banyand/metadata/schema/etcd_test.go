@@ -24,7 +24,9 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -35,6 +37,7 @@ import (
 	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
 	"github.com/apache/skywalking-banyandb/pkg/test"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
 )
 
 const indexRuleDir = "testdata/index_rules"
@@ -521,3 +524,111 @@ func Test_Notify(t *testing.T) {
 		})
 	}
 }
+
+// recordedEventHandler records the events dispatched by the watches, which are concurrent with the assertions.
+type recordedEventHandler struct {
+	added   []TypeMeta
+	deleted []TypeMeta
+	mu      sync.Mutex
+}
+
+func (r *recordedEventHandler) OnAddOrUpdate(metadata Metadata) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.added = append(r.added, metadata.TypeMeta)
+}
+
+func (r *recordedEventHandler) OnDelete(metadata Metadata) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deleted = append(r.deleted, metadata.TypeMeta)
+}
+
+func (r *recordedEventHandler) has(deleted bool, tm TypeMeta) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := r.added
+	if deleted {
+		events = r.deleted
+	}
+	for _, e := range events {
+		if e == tm {
+			return true
+		}
+	}
+	return false
+}
+
+func Test_External_Cluster(t *testing.T) {
+	req := require.New(t)
+	ports, err := test.AllocateFreePorts(2)
+	req.NoError(err)
+	clientURL := fmt.Sprintf("http://127.0.0.1:%d", ports[0])
+	embedded, err := NewEtcdSchemaRegistry(ConfigureListener(clientURL, fmt.Sprintf("http://127.0.0.1:%d", ports[1])),
+		useRandomTempDir(), LoggerLevel("warn"))
+	req.NoError(err)
+	defer embedded.Close()
+
+	writer, err := NewEtcdSchemaRegistry(ConfigureServerEndpoints([]string{clientURL}), LoggerLevel("warn"))
+	req.NoError(err)
+	defer writer.Close()
+	reader, err := NewEtcdSchemaRegistry(ConfigureServerEndpoints([]string{clientURL}), LoggerLevel("warn"))
+	req.NoError(err)
+	defer reader.Close()
+
+	handler := &recordedEventHandler{}
+	reader.RegisterHandler(KindGroup|KindStream|KindIndexRuleBinding|KindIndexRule|KindNode, handler)
+
+	// the changes made by a node are watched by the others
+	req.NoError(preloadSchema(writer))
+	req.Eventually(func() bool {
+		return handler.has(false, TypeMeta{Kind: KindStream, Name: "sw", Group: "default"})
+	}, flags.EventuallyTimeout, 10*time.Millisecond)
+	s, err := reader.GetStream(context.TODO(), &commonv1.Metadata{Name: "sw", Group: "default"})
+	req.NoError(err)
+	req.Equal("sw", s.GetMetadata().GetName())
+
+	deleted, err := writer.DeleteIndexRule(context.TODO(), &commonv1.Metadata{Name: "db.instance", Group: "default"})
+	req.NoError(err)
+	req.True(deleted)
+	req.Eventually(func() bool {
+		return handler.has(true, TypeMeta{Kind: KindIndexRule, Name: "db.instance", Group: "default"})
+	}, flags.EventuallyTimeout, 10*time.Millisecond)
+
+	// the lease of a node is revoked once its registry is closed
+	req.NoError(writer.RegisterNode(context.TODO(), &databasev1.Node{Id: "writer", Addr: "127.0.0.1:17912"}, 5*time.Second))
+	nodes, err := reader.ListNode(context.TODO())
+	req.NoError(err)
+	req.Len(nodes, 1)
+	req.Equal("127.0.0.1:17912", nodes[0].GetAddr())
+	req.NoError(writer.Close())
+	req.Eventually(func() bool {
+		return handler.has(true, TypeMeta{Kind: KindNode, Name: "writer"})
+	}, flags.EventuallyTimeout, 10*time.Millisecond)
+	nodes, err = reader.ListNode(context.TODO())
+	req.NoError(err)
+	req.Empty(nodes)
+}
+
+func Test_Parse_Key(t *testing.T) {
+	tests := []struct {
+		meta Metadata
+	}{
+		{meta: Metadata{TypeMeta: TypeMeta{Kind: KindGroup, Name: "default"}}},
+		{meta: Metadata{TypeMeta: TypeMeta{Kind: KindStream, Name: "sw", Group: "default"}}},
+		{meta: Metadata{TypeMeta: TypeMeta{Kind: KindMeasure, Name: "cpm", Group: "default"}}},
+		{meta: Metadata{TypeMeta: TypeMeta{Kind: KindIndexRule, Name: "db.instance", Group: "default"}}},
+		{meta: Metadata{TypeMeta: TypeMeta{Kind: KindIndexRuleBinding, Name: "sw-index-rule-binding", Group: "default"}}},
+		{meta: Metadata{TypeMeta: TypeMeta{Kind: KindTopNAggregation, Name: "top", Group: "default"}}},
+		{meta: Metadata{TypeMeta: TypeMeta{Kind: KindNode, Name: "node-1"}}},
+	}
+	for _, tt := range tests {
+		key, err := tt.meta.Key()
+		require.NoError(t, err)
+		tm, ok := parseKey(key)
+		assert.True(t, ok, key)
+		assert.Equal(t, tt.meta.TypeMeta, tm, key)
+	}
+	_, ok := parseKey("/properties/default/sw/1")
+	assert.False(t, ok)
+}
@@ -0,0 +1,133 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package schema
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+)
+
+// evolver validates the change from the existing schema to the new one and bumps the new one's version.
+// The data written in the previous versions should be readable in the new one,
+// so only the additive changes are accepted since the tags and fields are encoded by their positions.
+type evolver func(prev, next proto.Message) error
+
+// CheckEvolution validates the change from the existing schema to the new one without touching them,
+// for example, to validate a batch of changes before applying any of them.
+func CheckEvolution(kind Kind, prev, next proto.Message) error {
+	evolve, ok := evolverMap[kind]
+	if !ok {
+		return nil
+	}
+	return evolve(prev, proto.Clone(next))
+}
+
+var evolverMap = map[Kind]evolver{
+	KindStream: func(prev, next proto.Message) error {
+		p, n := prev.(*databasev1.Stream), next.(*databasev1.Stream)
+		if err := evolveEntity(p.GetEntity(), n.GetEntity()); err != nil {
+			return err
+		}
+		if err := evolveTagFamilies(p.GetTagFamilies(), n.GetTagFamilies()); err != nil {
+			return err
+		}
+		n.SchemaVersion = p.GetSchemaVersion() + 1
+		return nil
+	},
+	KindMeasure: func(prev, next proto.Message) error {
+		p, n := prev.(*databasev1.Measure), next.(*databasev1.Measure)
+		if err := evolveEntity(p.GetEntity(), n.GetEntity()); err != nil {
+			return err
+		}
+		if p.GetInterval() != n.GetInterval() {
+			return BadRequest("interval", fmt.Sprintf("the interval can't be changed from %s to %s", p.GetInterval(), n.GetInterval()))
+		}
+		if err := evolveTagFamilies(p.GetTagFamilies(), n.GetTagFamilies()); err != nil {
+			return err
+		}
+		if err := evolveFields(p.GetFields(), n.GetFields()); err != nil {
+			return err
+		}
+		n.SchemaVersion = p.GetSchemaVersion() + 1
+		return nil
+	},
+}
+
+func evolveEntity(prev, next *databasev1.Entity) error {
+	p, n := prev.GetTagNames(), next.GetTagNames()
+	if len(p) != len(n) {
+		return BadRequest("entity", fmt.Sprintf("the entity can't be changed from %v to %v", p, n))
+	}
+	for i := range p {
+		if p[i] != n[i] {
+			return BadRequest("entity", fmt.Sprintf("the entity can't be changed from %v to %v", p, n))
+		}
+	}
+	return nil
+}
+
+func evolveTagFamilies(prev, next []*databasev1.TagFamilySpec) error {
+	if len(next) < len(prev) {
+		return BadRequest("tag_families", fmt.Sprintf("the tag families can't be removed, expected at least %d but got %d", len(prev), len(next)))
+	}
+	for i, pf := range prev {
+		nf := next[i]
+		if pf.GetName() != nf.GetName() {
+			return BadRequest("tag_families",
+				fmt.Sprintf("the tag family %s can't be renamed or moved, got %s at its position", pf.GetName(), nf.GetName()))
+		}
+		if len(nf.GetTags()) < len(pf.GetTags()) {
+			return BadRequest("tag_families", fmt.Sprintf("the tags of the tag family %s can't be removed", pf.GetName()))
+		}
+		for j, pt := range pf.GetTags() {
+			nt := nf.GetTags()[j]
+			if pt.GetName() != nt.GetName() {
+				return BadRequest("tag_families",
+					fmt.Sprintf("the tag %s.%s can't be renamed or moved, got %s at its position", pf.GetName(), pt.GetName(), nt.GetName()))
+			}
+			if pt.GetType() != nt.GetType() {
+				return BadRequest("tag_families",
+					fmt.Sprintf("the type of the tag %s.%s can't be changed from %s to %s", pf.GetName(), pt.GetName(), pt.GetType(), nt.GetType()))
+			}
+		}
+	}
+	return nil
+}
+
+func evolveFields(prev, next []*databasev1.FieldSpec) error {
+	if len(next) < len(prev) {
+		return BadRequest("fields", fmt.Sprintf("the fields can't be removed, expected at least %d but got %d", len(prev), len(next)))
+	}
+	for i, pf := range prev {
+		nf := next[i]
+		if pf.GetName() != nf.GetName() {
+			return BadRequest("fields", fmt.Sprintf("the field %s can't be renamed or moved, got %s at its position", pf.GetName(), nf.GetName()))
+		}
+		if pf.GetFieldType() != nf.GetFieldType() {
+			return BadRequest("fields",
+				fmt.Sprintf("the type of the field %s can't be changed from %s to %s", pf.GetName(), pf.GetFieldType(), nf.GetFieldType()))
+		}
+		if pf.GetEncodingMethod() != nf.GetEncodingMethod() || pf.GetCompressionMethod() != nf.GetCompressionMethod() {
+			return BadRequest("fields", fmt.Sprintf("the encoding and the compression of the field %s can't be changed", pf.GetName()))
+		}
+	}
+	return nil
+}
@@ -19,6 +19,7 @@ package schema
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -26,6 +27,7 @@ import (
 	"google.golang.org/protobuf/proto"
 
 	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	"github.com/apache/skywalking-banyandb/pkg/expr"
 )
 
 var (
@@ -87,6 +89,15 @@ func (e *etcdSchemaRegistry) DeleteGroup(ctx context.Context, group string) (boo
 }
 
 func (e *etcdSchemaRegistry) CreateGroup(ctx context.Context, group *commonv1.Group) error {
+	if err := validateIntervals(group.GetResourceOpts()); err != nil {
+		return err
+	}
+	if err := validateTimeBand(group.GetResourceOpts()); err != nil {
+		return err
+	}
+	if err := e.validateWriteFilters(ctx, group); err != nil {
+		return err
+	}
 	return e.create(ctx, Metadata{
 		TypeMeta: TypeMeta{
 			Kind: KindGroup,
@@ -97,6 +108,15 @@ func (e *etcdSchemaRegistry) CreateGroup(ctx context.Context, group *commonv1.Gr
 }
 
 func (e *etcdSchemaRegistry) UpdateGroup(ctx context.Context, group *commonv1.Group) error {
+	if err := validateIntervals(group.GetResourceOpts()); err != nil {
+		return err
+	}
+	if err := validateTimeBand(group.GetResourceOpts()); err != nil {
+		return err
+	}
+	if err := e.validateWriteFilters(ctx, group); err != nil {
+		return err
+	}
 	return e.update(ctx, Metadata{
 		TypeMeta: TypeMeta{
 			Kind: KindGroup,
@@ -106,6 +126,52 @@ func (e *etcdSchemaRegistry) UpdateGroup(ctx context.Context, group *commonv1.Gr
 	})
 }
 
+// validateIntervals rejects the monthly segments and blocks, since they are aligned to the fixed hours or days.
+func validateIntervals(opts *commonv1.ResourceOpts) error {
+	if opts.GetSegmentInterval().GetUnit() == commonv1.IntervalRule_UNIT_MONTH {
+		return BadRequest("resource_opts.segment_interval.unit", "the segment interval can't be in months, which are only allowed by the ttl")
+	}
+	if opts.GetBlockInterval().GetUnit() == commonv1.IntervalRule_UNIT_MONTH {
+		return BadRequest("resource_opts.block_interval.unit", "the block interval can't be in months, which are only allowed by the ttl")
+	}
+	return nil
+}
+
+func validateTimeBand(opts *commonv1.ResourceOpts) error {
+	band := opts.GetTimeBand()
+	if band == nil {
+		return nil
+	}
+	if band.GetShardNum() >= opts.GetShardNum() {
+		return BadRequest("resource_opts.time_band.shard_num",
+			fmt.Sprintf("the shard num %d of the time band should be less than the shard num %d of the group", band.GetShardNum(), opts.GetShardNum()))
+	}
+	return nil
+}
+
+// validateWriteFilters rejects the invalid expressions and the rules routing the writes to an undefined group.
+func (e *etcdSchemaRegistry) validateWriteFilters(ctx context.Context, group *commonv1.Group) error {
+	for i, rule := range group.GetWriteFilters() {
+		field := fmt.Sprintf("write_filters[%d]", i)
+		if err := expr.Parse(rule.GetExpression()); err != nil {
+			return BadRequest(field+".expression", err.Error())
+		}
+		if rule.GetAction() != commonv1.WriteFilterRule_ACTION_ROUTE {
+			continue
+		}
+		if rule.GetRouteGroup() == "" || rule.GetRouteGroup() == group.GetMetadata().GetName() {
+			return BadRequest(field+".route_group", "the route group of a rule should be another group")
+		}
+		if _, err := e.GetGroup(ctx, rule.GetRouteGroup()); err != nil {
+			if IsNotFound(err) {
+				return BadRequest(field+".route_group", fmt.Sprintf("the route group %s is not defined", rule.GetRouteGroup()))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
 func formatGroupKey(group string) string {
 	return GroupsKeyPrefix + group + GroupMetadataKey
 }
@@ -0,0 +1,58 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+)
+
+func Test_Validate_Intervals(t *testing.T) {
+	opts := func(segment, block commonv1.IntervalRule_Unit) *commonv1.ResourceOpts {
+		return &commonv1.ResourceOpts{
+			ShardNum:        2,
+			SegmentInterval: &commonv1.IntervalRule{Unit: segment, Num: 1},
+			BlockInterval:   &commonv1.IntervalRule{Unit: block, Num: 2},
+			Ttl:             &commonv1.IntervalRule{Unit: commonv1.IntervalRule_UNIT_MONTH, Num: 1},
+		}
+	}
+	tests := []struct {
+		name    string
+		opts    *commonv1.ResourceOpts
+		wantErr bool
+	}{
+		{name: "daily segments and hourly blocks", opts: opts(commonv1.IntervalRule_UNIT_DAY, commonv1.IntervalRule_UNIT_HOUR)},
+		{name: "monthly segments", opts: opts(commonv1.IntervalRule_UNIT_MONTH, commonv1.IntervalRule_UNIT_HOUR), wantErr: true},
+		{name: "monthly blocks", opts: opts(commonv1.IntervalRule_UNIT_DAY, commonv1.IntervalRule_UNIT_MONTH), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateIntervals(tt.opts)
+			if tt.wantErr {
+				assert.Equal(t, codes.InvalidArgument, status.Code(err), err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
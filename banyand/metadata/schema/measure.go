@@ -59,6 +59,10 @@ func (e *etcdSchemaRegistry) ListMeasure(ctx context.Context, opt ListOpt) ([]*d
 }
 
 func (e *etcdSchemaRegistry) CreateMeasure(ctx context.Context, measure *databasev1.Measure) error {
+	if err := validateTagFilling(measure.GetTagFamilies(), measure.GetEntity(), measure.GetEnrichments()); err != nil {
+		return err
+	}
+	measure.SchemaVersion = 1
 	if err := e.create(ctx, Metadata{
 		TypeMeta: TypeMeta{
 			Kind:  KindMeasure,
@@ -126,6 +130,9 @@ func (e *etcdSchemaRegistry) CreateMeasure(ctx context.Context, measure *databas
 }
 
 func (e *etcdSchemaRegistry) UpdateMeasure(ctx context.Context, measure *databasev1.Measure) error {
+	if err := validateTagFilling(measure.GetTagFamilies(), measure.GetEntity(), measure.GetEnrichments()); err != nil {
+		return err
+	}
 	if err := e.update(ctx, Metadata{
 		TypeMeta: TypeMeta{
 			Kind:  KindMeasure,
@@ -0,0 +1,86 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package schema
+
+import (
+	"context"
+	"math"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+)
+
+var NodeKeyPrefix = "/nodes/"
+
+func (e *etcdSchemaRegistry) RegisterNode(ctx context.Context, node *databasev1.Node, ttl time.Duration) error {
+	now := timestamppb.Now()
+	if node.CreatedAt == nil {
+		node.CreatedAt = now
+	}
+	node.UpdatedAt = now
+	val, err := proto.Marshal(node)
+	if err != nil {
+		return err
+	}
+	grantResp, err := e.lease.Grant(ctx, int64(math.Ceil(ttl.Seconds())))
+	if err != nil {
+		return err
+	}
+	if _, err = e.kv.Put(ctx, formatNodeKey(node.GetId()), string(val), clientv3.WithLease(grantResp.ID)); err != nil {
+		e.revoke(ctx, grantResp.ID)
+		return err
+	}
+	keepAlive, err := e.lease.KeepAlive(e.stopCtx, grantResp.ID)
+	if err != nil {
+		e.revoke(ctx, grantResp.ID)
+		return err
+	}
+	e.mux.Lock()
+	e.nodeLeases = append(e.nodeLeases, grantResp.ID)
+	e.mux.Unlock()
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		// the responses are drained until the registry is closed
+		for range keepAlive {
+		}
+	}()
+	return nil
+}
+
+func (e *etcdSchemaRegistry) ListNode(ctx context.Context) ([]*databasev1.Node, error) {
+	messages, err := e.listWithPrefix(ctx, NodeKeyPrefix, func() proto.Message {
+		return &databasev1.Node{}
+	})
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]*databasev1.Node, 0, len(messages))
+	for _, m := range messages {
+		nodes = append(nodes, m.(*databasev1.Node))
+	}
+	return nodes, nil
+}
+
+func formatNodeKey(id string) string {
+	return NodeKeyPrefix + id
+}
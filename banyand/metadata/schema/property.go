@@ -19,9 +19,12 @@ package schema
 
 import (
 	"context"
-	"errors"
+	"math"
 
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
 
 	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
 	propertyv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/property/v1"
@@ -44,8 +47,10 @@ func filterTags(property *propertyv1.Property, tags []string) *propertyv1.Proper
 		return property
 	}
 	filtered := &propertyv1.Property{
-		Metadata:  property.Metadata,
-		UpdatedAt: property.UpdatedAt,
+		Metadata:    property.Metadata,
+		UpdatedAt:   property.UpdatedAt,
+		Ttl:         property.Ttl,
+		ModRevision: property.ModRevision,
 	}
 
 	for _, expectedTag := range tags {
@@ -84,57 +89,128 @@ func (e *etcdSchemaRegistry) ListProperty(ctx context.Context, container *common
 	return entities, nil
 }
 
-func (e *etcdSchemaRegistry) ApplyProperty(ctx context.Context, property *propertyv1.Property, strategy propertyv1.ApplyRequest_Strategy) (bool, uint32, error) {
+// ApplyProperty creates or updates a property in a transaction.
+// The property with a ttl is attached to a lease, which removes it once the ttl elapses since it's applied.
+func (e *etcdSchemaRegistry) ApplyProperty(ctx context.Context, property *propertyv1.Property, strategy propertyv1.ApplyRequest_Strategy,
+	modRevision int64,
+) (bool, uint32, int64, error) {
 	m := transformKey(property.GetMetadata())
-	md := Metadata{
+	key := formatPropertyKey(m)
+	getResp, err := e.kv.Get(ctx, key)
+	if err != nil {
+		return false, 0, 0, err
+	}
+	if getResp.Count > 1 {
+		return false, 0, 0, ErrUnexpectedNumberOfEntities
+	}
+	var existing *mvccpb.KeyValue
+	if getResp.Count > 0 {
+		existing = getResp.Kvs[0]
+	}
+	if modRevision > 0 && (existing == nil || existing.ModRevision != modRevision) {
+		return false, 0, 0, ErrGRPCRevisionMismatch
+	}
+	tagsNum := uint32(len(property.Tags))
+	spec := proto.Clone(property).(*propertyv1.Property)
+	if existing != nil && strategy != propertyv1.ApplyRequest_STRATEGY_REPLACE {
+		existed := &propertyv1.Property{}
+		if err = proto.Unmarshal(existing.Value, existed); err != nil {
+			return false, 0, 0, err
+		}
+		mergeTags(existed, spec)
+		if spec.Ttl != nil {
+			existed.Ttl = spec.Ttl
+		}
+		spec = existed
+	}
+	spec.ModRevision = 0
+	val, err := proto.Marshal(spec)
+	if err != nil {
+		return false, 0, 0, err
+	}
+	var opts []clientv3.OpOption
+	var leaseID clientv3.LeaseID
+	if spec.GetTtl() != nil {
+		grantResp, errGrant := e.lease.Grant(ctx, ttlSeconds(spec.GetTtl()))
+		if errGrant != nil {
+			return false, 0, 0, errGrant
+		}
+		leaseID = grantResp.ID
+		opts = append(opts, clientv3.WithLease(leaseID))
+	}
+	cmp := clientv3.Compare(clientv3.CreateRevision(key), "=", 0)
+	if existing != nil {
+		cmp = clientv3.Compare(clientv3.ModRevision(key), "=", existing.ModRevision)
+	}
+	txnResp, err := e.kv.Txn(ctx).If(cmp).Then(clientv3.OpPut(key, string(val), opts...)).Commit()
+	if err != nil || !txnResp.Succeeded {
+		e.revoke(ctx, leaseID)
+		if err != nil {
+			return false, 0, 0, err
+		}
+		if modRevision > 0 {
+			return false, 0, 0, ErrGRPCRevisionMismatch
+		}
+		return false, 0, 0, ErrConcurrentModification
+	}
+	if existing != nil {
+		// the property has been detached from the lease of the previous version
+		e.revoke(ctx, clientv3.LeaseID(existing.Lease))
+	}
+	spec.ModRevision = txnResp.Header.Revision
+	e.notifyUpdate(Metadata{
 		TypeMeta: TypeMeta{
 			Kind:  KindProperty,
 			Group: m.GetGroup(),
 			Name:  m.GetName(),
 		},
-		Spec: property,
-	}
-	tagsNum := uint32(len(property.Tags))
-	err := e.create(ctx, md)
-	if err == nil {
-		return true, tagsNum, nil
-	}
-	if !errors.Is(err, ErrGRPCAlreadyExists) {
-		return false, 0, err
-	}
-	if strategy != propertyv1.ApplyRequest_STRATEGY_REPLACE {
-		existed, errGet := e.GetProperty(ctx, property.Metadata, nil)
-		if errGet != nil {
-			return false, 0, errGet
-		}
-		for i := 0; i < int(tagsNum); i++ {
-			t := property.Tags[0]
-			property.Tags = property.Tags[1:]
-			for _, et := range existed.Tags {
-				if et.Key == t.Key {
-					et.Value = t.Value
-				}
+		Spec: spec,
+	})
+	return existing == nil, tagsNum, spec.ModRevision, nil
+}
+
+// mergeTags overwrites the values of the existing tags, and appends the absent ones.
+func mergeTags(existed, property *propertyv1.Property) {
+	for _, t := range property.Tags {
+		found := false
+		for _, et := range existed.Tags {
+			if et.Key == t.Key {
+				et.Value = t.Value
+				found = true
 			}
 		}
-		existed.Tags = append(existed.Tags, property.Tags...)
-		md.Spec = existed
+		if !found {
+			existed.Tags = append(existed.Tags, t)
+		}
 	}
-	if err = e.update(ctx, md); err != nil {
-		return false, 0, err
+}
+
+func ttlSeconds(ttl *durationpb.Duration) int64 {
+	return int64(math.Ceil(ttl.AsDuration().Seconds()))
+}
+
+func (e *etcdSchemaRegistry) revoke(ctx context.Context, id clientv3.LeaseID) {
+	if id == clientv3.NoLease {
+		return
 	}
-	return false, tagsNum, nil
+	// the lease expires by itself if it fails to be revoked
+	_, _ = e.lease.Revoke(ctx, id)
 }
 
-func (e *etcdSchemaRegistry) DeleteProperty(ctx context.Context, metadata *propertyv1.Metadata, tags []string) (bool, uint32, error) {
+func (e *etcdSchemaRegistry) DeleteProperty(ctx context.Context, metadata *propertyv1.Metadata, tags []string, modRevision int64) (bool, uint32, error) {
 	if len(tags) == 0 || tags[0] == all {
 		m := transformKey(metadata)
+		var cmps []clientv3.Cmp
+		if modRevision > 0 {
+			cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(formatPropertyKey(m)), "=", modRevision))
+		}
 		deleted, err := e.delete(ctx, Metadata{
 			TypeMeta: TypeMeta{
 				Kind:  KindProperty,
 				Group: m.GetGroup(),
 				Name:  m.GetName(),
 			},
-		})
+		}, cmps...)
 		return deleted, 0, err
 	}
 	property, err := e.GetProperty(ctx, metadata, nil)
@@ -144,6 +220,7 @@ func (e *etcdSchemaRegistry) DeleteProperty(ctx context.Context, metadata *prope
 	filtered := &propertyv1.Property{
 		Metadata:  property.Metadata,
 		UpdatedAt: property.UpdatedAt,
+		Ttl:       property.Ttl,
 	}
 
 	for _, expectedTag := range tags {
@@ -153,7 +230,7 @@ func (e *etcdSchemaRegistry) DeleteProperty(ctx context.Context, metadata *prope
 			}
 		}
 	}
-	_, num, err := e.ApplyProperty(ctx, filtered, propertyv1.ApplyRequest_STRATEGY_REPLACE)
+	_, num, _, err := e.ApplyProperty(ctx, filtered, propertyv1.ApplyRequest_STRATEGY_REPLACE, modRevision)
 	return true, num, err
 }
 
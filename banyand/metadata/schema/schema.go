@@ -20,6 +20,7 @@ package schema
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/pkg/errors"
 	"google.golang.org/protobuf/proto"
@@ -46,9 +47,10 @@ const (
 	KindIndexRule
 	KindTopNAggregation
 	KindProperty
+	KindNode
 )
 
-const KindMask = KindGroup | KindStream | KindMeasure | KindIndexRuleBinding | KindIndexRule | KindTopNAggregation
+const KindMask = KindGroup | KindStream | KindMeasure | KindIndexRuleBinding | KindIndexRule | KindTopNAggregation | KindNode
 
 type ListOpt struct {
 	Group string
@@ -66,6 +68,7 @@ type Registry interface {
 	Group
 	TopNAggregation
 	Property
+	Node
 }
 
 type TypeMeta struct {
@@ -100,6 +103,8 @@ func (tm TypeMeta) Unmarshal(data []byte) (m proto.Message, err error) {
 		m = &propertyv1.Property{}
 	case KindTopNAggregation:
 		m = &databasev1.TopNAggregation{}
+	case KindNode:
+		m = &databasev1.Node{}
 	default:
 		return nil, ErrUnsupportedEntityType
 	}
@@ -141,6 +146,8 @@ func (m Metadata) Key() (string, error) {
 			Group: m.Group,
 			Name:  m.Name,
 		}), nil
+	case KindNode:
+		return formatNodeKey(m.Name), nil
 	default:
 		return "", ErrUnsupportedEntityType
 	}
@@ -213,6 +220,16 @@ type TopNAggregation interface {
 type Property interface {
 	GetProperty(ctx context.Context, metadata *propertyv1.Metadata, tags []string) (*propertyv1.Property, error)
 	ListProperty(ctx context.Context, container *commonv1.Metadata, ids []string, tags []string) ([]*propertyv1.Property, error)
-	ApplyProperty(ctx context.Context, property *propertyv1.Property, strategy propertyv1.ApplyRequest_Strategy) (bool, uint32, error)
-	DeleteProperty(ctx context.Context, metadata *propertyv1.Metadata, tags []string) (bool, uint32, error)
+	// ApplyProperty and DeleteProperty check the revision of the existing property if modRevision is positive.
+	ApplyProperty(ctx context.Context, property *propertyv1.Property, strategy propertyv1.ApplyRequest_Strategy,
+		modRevision int64) (bool, uint32, int64, error)
+	DeleteProperty(ctx context.Context, metadata *propertyv1.Metadata, tags []string, modRevision int64) (bool, uint32, error)
+}
+
+// Node registers the nodes sharing the registry. The handlers of KindNode are notified as they join and leave.
+type Node interface {
+	// RegisterNode puts the node attached to a lease, which is kept alive until the registry is closed.
+	// The node is removed once the lease expires, e.g. it crashes.
+	RegisterNode(ctx context.Context, node *databasev1.Node, ttl time.Duration) error
+	ListNode(ctx context.Context) ([]*databasev1.Node, error)
 }
@@ -54,6 +54,9 @@ func (e *etcdSchemaRegistry) ListStream(ctx context.Context, opt ListOpt) ([]*da
 }
 
 func (e *etcdSchemaRegistry) UpdateStream(ctx context.Context, stream *databasev1.Stream) error {
+	if err := validateTagFilling(stream.GetTagFamilies(), stream.GetEntity(), stream.GetEnrichments()); err != nil {
+		return err
+	}
 	return e.update(ctx, Metadata{
 		TypeMeta: TypeMeta{
 			Kind:  KindStream,
@@ -65,11 +68,15 @@ func (e *etcdSchemaRegistry) UpdateStream(ctx context.Context, stream *databasev
 }
 
 func (e *etcdSchemaRegistry) CreateStream(ctx context.Context, stream *databasev1.Stream) error {
+	if err := validateTagFilling(stream.GetTagFamilies(), stream.GetEntity(), stream.GetEnrichments()); err != nil {
+		return err
+	}
 	group := stream.Metadata.GetGroup()
 	_, err := e.GetGroup(ctx, group)
 	if err != nil {
 		return err
 	}
+	stream.SchemaVersion = 1
 	return e.create(ctx, Metadata{
 		TypeMeta: TypeMeta{
 			Kind:  KindStream,
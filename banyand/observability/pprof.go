@@ -18,14 +18,26 @@
 package observability
 
 import (
+	"bytes"
 	"net/http"
 	// Register pprof package
-	_ "net/http/pprof"
+	httppprof "net/http/pprof"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
 
 	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/profiling"
 	"github.com/apache/skywalking-banyandb/pkg/run"
 )
 
+const (
+	// scopedProfilePath serves the profiles scoped to the pprof labels set by the modules
+	scopedProfilePath      = "/debug/pprof/scoped"
+	defaultProfileDuration = 10 * time.Second
+)
+
 var (
 	_ run.Service = (*metricService)(nil)
 	_ run.Config  = (*metricService)(nil)
@@ -61,7 +73,10 @@ func (p *pprofService) Serve() run.StopNotify {
 	p.l = logger.GetLogger(p.Name())
 	go func() {
 		p.l.Info().Str("listenAddr", p.listenAddr).Msg("Start pprof server")
-		_ = http.ListenAndServe(p.listenAddr, nil)
+		mux := http.NewServeMux()
+		mux.Handle("/", http.DefaultServeMux)
+		mux.HandleFunc(scopedProfilePath, serveScopedProfile)
+		_ = http.ListenAndServe(p.listenAddr, mux)
 		p.stopCh <- struct{}{}
 	}()
 
@@ -71,3 +86,52 @@ func (p *pprofService) Serve() run.StopNotify {
 func (p *pprofService) GracefulStop() {
 	close(p.stopCh)
 }
+
+// serveScopedProfile captures a profile for the seconds of the query, e.g. ?seconds=10&module=query&fingerprint=<fingerprint>.
+// The cpu profile keeps only the samples carrying all the labels, which are the parameters other than profile and seconds.
+// The allocs profile is the allocations within the seconds, which can't be scoped since the runtime doesn't label them.
+func serveScopedProfile(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	duration := defaultProfileDuration
+	if s := query.Get("seconds"); s != "" {
+		seconds, err := strconv.Atoi(s)
+		if err != nil || seconds <= 0 {
+			http.Error(w, "the seconds should be a positive integer", http.StatusBadRequest)
+			return
+		}
+		duration = time.Duration(seconds) * time.Second
+	}
+	labels := make(map[string]string)
+	for k, v := range query {
+		if k != "profile" && k != "seconds" && len(v) > 0 {
+			labels[k] = v[0]
+		}
+	}
+	switch query.Get("profile") {
+	case "", "cpu":
+		var buf bytes.Buffer
+		err := profiling.CaptureCPU(r.Context(), &buf, duration, labels)
+		if errors.Is(err, profiling.ErrCapturing) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", `attachment; filename="profile"`)
+		_, _ = w.Write(buf.Bytes())
+	case "allocs":
+		if len(labels) > 0 {
+			http.Error(w, "the allocation profiles can't be scoped to the labels", http.StatusBadRequest)
+			return
+		}
+		// the handler of pprof returns the delta of the allocations within the seconds
+		query.Set("seconds", strconv.Itoa(int(duration/time.Second)))
+		r.URL.RawQuery = query.Encode()
+		httppprof.Handler("allocs").ServeHTTP(w, r)
+	default:
+		http.Error(w, "the profile should be either cpu or allocs", http.StatusBadRequest)
+	}
+}
@@ -19,6 +19,8 @@ package observability
 type Statistics struct {
 	MemBytes    int64
 	MaxMemBytes int64
+	// UnsyncedBytes is the size of the acknowledged writes which might be lost by a crash of the node
+	UnsyncedBytes int64
 }
 
 type Observable interface {
@@ -0,0 +1,79 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package query
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/query/executor"
+)
+
+// newStats returns the Stats of a query. It's nil if the query is neither limited, sampled by the audit nor logged as a slow one.
+func (q *queryService) newStats(queryType string, limits *modelv1.QueryLimits, sampled bool) *executor.Stats {
+	l := executor.NewLimits(limits)
+	stats := q.slowQuery.newStats(queryType)
+	if stats == nil && (sampled || !l.IsZero()) {
+		stats = executor.NewStats()
+	}
+	return stats.WithLimits(l)
+}
+
+// observe hands the finished query over to the slow query log and the audit if it's sampled.
+func (q *queryService) observe(queryType string, request proto.Message, metadata *commonv1.Metadata, plan fmt.Stringer,
+	start time.Time, stats *executor.Stats, sampled bool,
+) {
+	q.slowQuery.observe(queryType, metadata, plan, start, stats)
+	if sampled {
+		q.audit.observe(queryType, request, metadata, start, stats)
+	}
+}
+
+// resourceExhausted returns the diagnostics of the query if err is caused by exceeding a limit.
+func resourceExhausted(err error, stats *executor.Stats, start time.Time) (*modelv1.ResourceExhausted, bool) {
+	var re *executor.ResourceExhaustedError
+	if !errors.As(err, &re) {
+		return nil, false
+	}
+	return &modelv1.ResourceExhausted{
+		Resource:      re.Resource,
+		Limit:         re.Limit,
+		Indexes:       stats.Indexes(),
+		ScannedSeries: int64(stats.ScannedSeries()),
+		ScannedBlocks: int64(stats.ScannedBlocks()),
+		Elapsed:       durationpb.New(time.Since(start)),
+	}, true
+}
+
+// truncated returns the truncation of the results if err is caused by exceeding a response limit of the query truncating its results.
+func truncated(err error, stats *executor.Stats) *modelv1.Truncation {
+	var re *executor.ResourceExhaustedError
+	if !stats.Limits().Truncate || !errors.As(err, &re) {
+		return nil
+	}
+	if re.Resource != executor.ResourceMaxResponseBytes && re.Resource != executor.ResourceMaxResponseItems {
+		return nil
+	}
+	return &modelv1.Truncation{Resource: re.Resource, Limit: re.Limit}
+}
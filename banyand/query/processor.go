@@ -19,13 +19,18 @@ package query
 
 import (
 	"context"
+	"runtime"
 	"time"
 
+	"github.com/pkg/errors"
 	"go.uber.org/multierr"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/apache/skywalking-banyandb/api/common"
 	"github.com/apache/skywalking-banyandb/api/data"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
 	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
 	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
 	"github.com/apache/skywalking-banyandb/banyand/discovery"
 	"github.com/apache/skywalking-banyandb/banyand/measure"
@@ -34,30 +39,79 @@ import (
 	"github.com/apache/skywalking-banyandb/banyand/stream"
 	"github.com/apache/skywalking-banyandb/pkg/bus"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/profiling"
+	"github.com/apache/skywalking-banyandb/pkg/query/aggregation"
 	"github.com/apache/skywalking-banyandb/pkg/query/executor"
+	"github.com/apache/skywalking-banyandb/pkg/query/logical"
 	logical_measure "github.com/apache/skywalking-banyandb/pkg/query/logical/measure"
 	logical_stream "github.com/apache/skywalking-banyandb/pkg/query/logical/stream"
+	"github.com/apache/skywalking-banyandb/pkg/run"
 )
 
 const (
 	moduleName = "query-processor"
+	// moduleProfilingLabel attributes the CPU time of the queries to the module
+	moduleProfilingLabel = "query"
+	// unflushedTimeout bounds the wait for the writes acknowledged before a query including the unflushed data
+	unflushedTimeout = 5 * time.Second
+
+	slowStreamQueryFlag  = "slow-stream-query-threshold"
+	slowMeasureQueryFlag = "slow-measure-query-threshold"
+	slowTopNQueryFlag    = "slow-topn-query-threshold"
 )
 
+// slowQueryFlags map the reloadable flags of the slow query thresholds to their query types.
+var slowQueryFlags = map[string]string{
+	slowStreamQueryFlag:  queryTypeStream,
+	slowMeasureQueryFlag: queryTypeMeasure,
+	slowTopNQueryFlag:    queryTypeTopN,
+}
+
 var (
+	errNegativeSlowQueryLogCapacity = errors.New("slow query log capacity is negative")
+	errNegativeMaxParallelism       = errors.New("the maximum parallelism of queries is negative")
+	errInvalidAuditSampleRate       = errors.New("the sample rate of the query audit is out of [0, 1]")
+	errShortAuditWindow             = errors.New("the window of the query audit is shorter than a second")
+
 	_ Executor            = (*queryService)(nil)
+	_ run.Reloader        = (*queryService)(nil)
 	_ bus.MessageListener = (*streamQueryProcessor)(nil)
 	_ bus.MessageListener = (*measureQueryProcessor)(nil)
 	_ bus.MessageListener = (*topNQueryProcessor)(nil)
+	_ bus.MessageListener = (*streamExplainProcessor)(nil)
+	_ bus.MessageListener = (*measureExplainProcessor)(nil)
 )
 
 type queryService struct {
-	log         *logger.Logger
-	metaService metadata.Service
-	serviceRepo discovery.ServiceRepo
-	pipeline    queue.Queue
-	sqp         *streamQueryProcessor
-	mqp         *measureQueryProcessor
-	tqp         *topNQueryProcessor
+	log                  *logger.Logger
+	metaService          metadata.Service
+	serviceRepo          discovery.ServiceRepo
+	pipeline             queue.Queue
+	sqp                  *streamQueryProcessor
+	mqp                  *measureQueryProcessor
+	tqp                  *topNQueryProcessor
+	slowQuery            *slowQueryLog
+	slowStreamQuery      time.Duration
+	slowMeasureQuery     time.Duration
+	slowTopNQuery        time.Duration
+	slowQueryLogCapacity int
+	audit                *queryAudit
+	auditSampleRate      float64
+	auditWindow          time.Duration
+	maxParallelism       int
+	scheduler            *executor.Scheduler
+	aggregationPlugins   []string
+}
+
+// profilingLabels returns the pprof labels attributing the CPU time of a query to it.
+// The fingerprint is computed only if a CPU profile is being captured.
+func profilingLabels(queryType string, metadata *commonv1.Metadata, request proto.Message) []string {
+	kv := []string{profiling.LabelModule, moduleProfilingLabel, "type", queryType, "group", metadata.GetGroup(), "name", metadata.GetName()}
+	if profiling.Capturing() {
+		fp, _ := fingerprint(request)
+		kv = append(kv, profiling.LabelFingerprint, fp)
+	}
+	return kv
 }
 
 type streamQueryProcessor struct {
@@ -66,49 +120,136 @@ type streamQueryProcessor struct {
 }
 
 func (p *streamQueryProcessor) Rev(message bus.Message) (resp bus.Message) {
-	now := time.Now().UnixNano()
-	queryCriteria, ok := message.Data().(*streamv1.QueryRequest)
+	start := time.Now()
+	now := start.UnixNano()
+	payload, send := data.UnwrapQuerySink(message.Data())
+	payload, shardIDs := data.UnwrapShardQuery(payload)
+	queryCriteria, ok := payload.(*streamv1.QueryRequest)
 	if !ok {
 		resp = bus.NewMessage(bus.MessageID(now), common.NewError("invalid event data type"))
 		return
 	}
 	p.log.Debug().Stringer("criteria", queryCriteria).Msg("received a query request")
+	defer profiling.Label(profilingLabels(queryTypeStream, queryCriteria.GetMetadata(), queryCriteria)...)()
 
 	meta := queryCriteria.GetMetadata()
-	ec, err := p.streamService.Stream(meta)
+	ec, plan, err := p.analyze(queryCriteria)
 	if err != nil {
-		resp = bus.NewMessage(bus.MessageID(now), common.NewError("fail to get execution context for stream %s: %v", meta.GetName(), err))
+		resp = bus.NewMessage(bus.MessageID(now), common.NewError("%v", err))
 		return
 	}
 
-	analyzer, err := logical_stream.CreateAnalyzerFromMetaService(p.metaService)
+	if queryCriteria.GetIncludeUnflushed() {
+		if err = p.flushWrites(data.TopicStreamWrite, ec.(writeFlusher)); err != nil {
+			resp = bus.NewMessage(bus.MessageID(now), common.NewError("%v", err))
+			return
+		}
+	}
+
+	sampled := p.audit.sample()
+	stats := p.newStats(queryTypeStream, queryCriteria.GetLimits(), sampled)
+	defer stats.Release()
+	ec = executor.WithStreamShards(ec, shardIDs)
+	entities := make([]*streamv1.Element, 0)
+	emit := func(e *streamv1.Element) error {
+		entities = append(entities, e)
+		return nil
+	}
+	if send != nil {
+		emit = func(e *streamv1.Element) error {
+			return send(e)
+		}
+	}
+	var truncation *modelv1.Truncation
+	it, err := executor.IterateStream(plan.(executor.StreamExecutable), executor.WithStreamStats(executor.WithStreamScheduler(ec, p.scheduler), stats))
+	if err == nil {
+		for err == nil && it.Next() {
+			current := it.Current()
+			if err = stats.AddResponseItem(proto.Size(current)); err == nil {
+				err = emit(current)
+			} else if truncation = truncated(err, stats); truncation != nil {
+				err = nil
+				break
+			}
+		}
+		if errClose := it.Close(); err == nil {
+			err = errClose
+		}
+	}
+	if detail, ok := resourceExhausted(err, stats, start); ok {
+		p.observe(queryTypeStream, queryCriteria, meta, plan, start, stats, sampled)
+		resp = bus.NewMessage(bus.MessageID(now), detail)
+		return
+	}
 	if err != nil {
-		resp = bus.NewMessage(bus.MessageID(now), common.NewError("fail to build analyzer for stream %s: %v", meta.GetName(), err))
+		resp = bus.NewMessage(bus.MessageID(now), common.NewError("execute the query plan for stream %s: %v", meta.GetName(), err))
+		return
+	}
+	p.observe(queryTypeStream, queryCriteria, meta, plan, start, stats, sampled)
+
+	if truncation != nil {
+		resp = bus.NewMessage(bus.MessageID(now), &streamv1.QueryResponse{Elements: entities, Truncation: truncation})
 		return
 	}
+	resp = bus.NewMessage(bus.MessageID(now), entities)
+
+	return
+}
+
+func (p *streamQueryProcessor) analyze(queryCriteria *streamv1.QueryRequest) (executor.StreamExecutionContext, logical.Plan, error) {
+	meta := queryCriteria.GetMetadata()
+	ec, err := p.streamService.Stream(meta)
+	if err != nil {
+		return nil, nil, errors.Errorf("fail to get execution context for stream %s: %v", meta.GetName(), err)
+	}
+
+	analyzer, err := logical_stream.CreateAnalyzerFromMetaService(p.metaService)
+	if err != nil {
+		return nil, nil, errors.Errorf("fail to build analyzer for stream %s: %v", meta.GetName(), err)
+	}
 
 	s, err := analyzer.BuildSchema(context.TODO(), meta)
 	if err != nil {
-		resp = bus.NewMessage(bus.MessageID(now), common.NewError("fail to build schema for stream %s: %v", meta.GetName(), err))
-		return
+		return nil, nil, errors.Errorf("fail to build schema for stream %s: %v", meta.GetName(), err)
 	}
 
 	plan, err := analyzer.Analyze(context.TODO(), queryCriteria, meta, s)
 	if err != nil {
-		resp = bus.NewMessage(bus.MessageID(now), common.NewError("fail to analyze the query request for stream %s: %v", meta.GetName(), err))
-		return
+		return nil, nil, errors.Errorf("fail to analyze the query request for stream %s: %v", meta.GetName(), err)
+	}
+
+	plan, err = analyzer.Optimize(plan, ec)
+	if err != nil {
+		return nil, nil, errors.Errorf("fail to optimize the query plan for stream %s: %v", meta.GetName(), err)
 	}
 
 	p.log.Debug().Str("plan", plan.String()).Msg("query plan")
+	return ec, plan, nil
+}
+
+type streamExplainProcessor struct {
+	*streamQueryProcessor
+}
 
-	entities, err := plan.(executor.StreamExecutable).Execute(ec)
+func (p *streamExplainProcessor) Rev(message bus.Message) (resp bus.Message) {
+	now := time.Now().UnixNano()
+	queryCriteria, ok := message.Data().(*streamv1.QueryRequest)
+	if !ok {
+		resp = bus.NewMessage(bus.MessageID(now), common.NewError("invalid event data type"))
+		return
+	}
+	ec, plan, err := p.analyze(queryCriteria)
 	if err != nil {
-		resp = bus.NewMessage(bus.MessageID(now), common.NewError("execute the query plan for stream %s: %v", meta.GetName(), err))
+		resp = bus.NewMessage(bus.MessageID(now), common.NewError("%v", err))
 		return
 	}
-
-	resp = bus.NewMessage(bus.MessageID(now), entities)
-
+	node, err := logical.Explain(plan, ec)
+	if err != nil {
+		resp = bus.NewMessage(bus.MessageID(now), common.NewError("fail to explain the query plan for stream %s: %v",
+			queryCriteria.GetMetadata().GetName(), err))
+		return
+	}
+	resp = bus.NewMessage(bus.MessageID(now), node)
 	return
 }
 
@@ -118,42 +259,42 @@ type measureQueryProcessor struct {
 }
 
 func (p *measureQueryProcessor) Rev(message bus.Message) (resp bus.Message) {
-	queryCriteria, ok := message.Data().(*measurev1.QueryRequest)
-	now := time.Now().UnixNano()
+	payload, send := data.UnwrapQuerySink(message.Data())
+	payload, shardIDs := data.UnwrapShardQuery(payload)
+	queryCriteria, ok := payload.(*measurev1.QueryRequest)
+	start := time.Now()
+	now := start.UnixNano()
 	if !ok {
 		resp = bus.NewMessage(bus.MessageID(now), common.NewError("invalid event data type"))
 		return
 	}
 	p.log.Debug().Msg("received a query event")
+	defer profiling.Label(profilingLabels(queryTypeMeasure, queryCriteria.GetMetadata(), queryCriteria)...)()
 
 	meta := queryCriteria.GetMetadata()
-	ec, err := p.measureService.Measure(meta)
-	if err != nil {
-		resp = bus.NewMessage(bus.MessageID(now), common.NewError("fail to get execution context for measure %s: %v", meta.GetName(), err))
-		return
-	}
-
-	analyzer, err := logical_measure.CreateAnalyzerFromMetaService(p.metaService)
+	ec, plan, err := p.analyze(queryCriteria)
 	if err != nil {
-		resp = bus.NewMessage(bus.MessageID(now), common.NewError("fail to build analyzer for measure %s: %v", meta.GetName(), err))
+		resp = bus.NewMessage(bus.MessageID(now), common.NewError("%v", err))
 		return
 	}
 
-	s, err := analyzer.BuildSchema(context.TODO(), meta)
-	if err != nil {
-		resp = bus.NewMessage(bus.MessageID(now), common.NewError("fail to build schema for measure %s: %v", meta.GetName(), err))
-		return
+	if queryCriteria.GetIncludeUnflushed() {
+		if err = p.flushWrites(data.TopicMeasureWrite, ec.(writeFlusher)); err != nil {
+			resp = bus.NewMessage(bus.MessageID(now), common.NewError("%v", err))
+			return
+		}
 	}
 
-	plan, err := analyzer.Analyze(context.TODO(), queryCriteria, meta, s)
-	if err != nil {
-		resp = bus.NewMessage(bus.MessageID(now), common.NewError("fail to analyze the query request for measure %s: %v", meta.GetName(), err))
+	sampled := p.audit.sample()
+	stats := p.newStats(queryTypeMeasure, queryCriteria.GetLimits(), sampled)
+	defer stats.Release()
+	ec = executor.WithMeasureShards(ec, shardIDs)
+	mIterator, err := plan.(executor.MeasureExecutable).Execute(executor.WithMeasureStats(executor.WithMeasureScheduler(ec, p.scheduler), stats))
+	if detail, ok := resourceExhausted(err, stats, start); ok {
+		p.observe(queryTypeMeasure, queryCriteria, meta, plan, start, stats, sampled)
+		resp = bus.NewMessage(bus.MessageID(now), detail)
 		return
 	}
-
-	p.queryService.log.Debug().Str("plan", plan.String()).Msg("query plan")
-
-	mIterator, err := plan.(executor.MeasureExecutable).Execute(ec)
 	if err != nil {
 		resp = bus.NewMessage(bus.MessageID(now), common.NewError("fail to execute the query plan for measure %s: %v", meta.GetName(), err))
 		return
@@ -164,25 +305,214 @@ func (p *measureQueryProcessor) Rev(message bus.Message) (resp bus.Message) {
 		}
 	}()
 	result := make([]*measurev1.DataPoint, 0)
-	for mIterator.Next() {
+	emit := func(dp *measurev1.DataPoint) error {
+		result = append(result, dp)
+		return nil
+	}
+	if send != nil {
+		emit = func(dp *measurev1.DataPoint) error {
+			return send(dp)
+		}
+	}
+	var truncation *modelv1.Truncation
+	for err == nil && mIterator.Next() {
 		current := mIterator.Current()
 		if len(current) > 0 {
-			result = append(result, current[0])
+			if err = stats.AddResponseItem(proto.Size(current[0])); err != nil {
+				if truncation = truncated(err, stats); truncation != nil {
+					err = nil
+				}
+				break
+			}
+			err = emit(current[0])
 		}
 	}
+	// the iterator stops early once the query times out
+	if err == nil && truncation == nil {
+		err = stats.Check()
+	}
+	p.observe(queryTypeMeasure, queryCriteria, meta, plan, start, stats, sampled)
+	if detail, ok := resourceExhausted(err, stats, start); ok {
+		resp = bus.NewMessage(bus.MessageID(now), detail)
+		return
+	}
+	if err != nil {
+		resp = bus.NewMessage(bus.MessageID(now), common.NewError("fail to execute the query plan for measure %s: %v", meta.GetName(), err))
+		return
+	}
+	if truncation != nil {
+		resp = bus.NewMessage(bus.MessageID(now), &measurev1.QueryResponse{DataPoints: result, Truncation: truncation})
+		return
+	}
 	resp = bus.NewMessage(bus.MessageID(now), result)
 	return
 }
 
+func (p *measureQueryProcessor) analyze(queryCriteria *measurev1.QueryRequest) (executor.MeasureExecutionContext, logical.Plan, error) {
+	meta := queryCriteria.GetMetadata()
+	ec, err := p.measureService.Measure(meta)
+	if err != nil {
+		return nil, nil, errors.Errorf("fail to get execution context for measure %s: %v", meta.GetName(), err)
+	}
+
+	analyzer, err := logical_measure.CreateAnalyzerFromMetaService(p.metaService)
+	if err != nil {
+		return nil, nil, errors.Errorf("fail to build analyzer for measure %s: %v", meta.GetName(), err)
+	}
+
+	s, err := analyzer.BuildSchema(context.TODO(), meta)
+	if err != nil {
+		return nil, nil, errors.Errorf("fail to build schema for measure %s: %v", meta.GetName(), err)
+	}
+
+	plan, err := analyzer.Analyze(context.TODO(), queryCriteria, meta, s)
+	if err != nil {
+		return nil, nil, errors.Errorf("fail to analyze the query request for measure %s: %v", meta.GetName(), err)
+	}
+
+	plan, err = analyzer.Optimize(plan, ec)
+	if err != nil {
+		return nil, nil, errors.Errorf("fail to optimize the query plan for measure %s: %v", meta.GetName(), err)
+	}
+
+	p.queryService.log.Debug().Str("plan", plan.String()).Msg("query plan")
+	return ec, plan, nil
+}
+
+type measureExplainProcessor struct {
+	*measureQueryProcessor
+}
+
+func (p *measureExplainProcessor) Rev(message bus.Message) (resp bus.Message) {
+	now := time.Now().UnixNano()
+	queryCriteria, ok := message.Data().(*measurev1.QueryRequest)
+	if !ok {
+		resp = bus.NewMessage(bus.MessageID(now), common.NewError("invalid event data type"))
+		return
+	}
+	ec, plan, err := p.analyze(queryCriteria)
+	if err != nil {
+		resp = bus.NewMessage(bus.MessageID(now), common.NewError("%v", err))
+		return
+	}
+	node, err := logical.Explain(plan, ec)
+	if err != nil {
+		resp = bus.NewMessage(bus.MessageID(now), common.NewError("fail to explain the query plan for measure %s: %v",
+			queryCriteria.GetMetadata().GetName(), err))
+		return
+	}
+	resp = bus.NewMessage(bus.MessageID(now), node)
+	return
+}
+
+// writeFlusher is a stream or a measure waiting for its pending indices.
+type writeFlusher interface {
+	Flush(ctx context.Context) error
+}
+
+// flushWrites waits for the writes published to the topic before to be stored and indexed by the resource,
+// so that a query including the unflushed data reads them.
+func (q *queryService) flushWrites(topic bus.Topic, resource writeFlusher) error {
+	ctx, cancel := context.WithTimeout(context.Background(), unflushedTimeout)
+	defer cancel()
+	if err := q.pipeline.Flush(ctx, topic); err != nil {
+		return errors.WithMessage(err, "failed to wait for the write pipeline")
+	}
+	if err := resource.Flush(ctx); err != nil {
+		return errors.WithMessage(err, "failed to wait for the pending indices")
+	}
+	return nil
+}
+
 func (q *queryService) Name() string {
 	return moduleName
 }
 
+func (q *queryService) FlagSet() *run.FlagSet {
+	flagS := run.NewFlagSet("query")
+	flagS.DurationVar(&q.slowStreamQuery, slowStreamQueryFlag, 0,
+		"the stream queries taking longer than this are logged as slow queries, 0 disables the slow query log")
+	flagS.DurationVar(&q.slowMeasureQuery, slowMeasureQueryFlag, 0,
+		"the measure queries taking longer than this are logged as slow queries, 0 disables the slow query log")
+	flagS.DurationVar(&q.slowTopNQuery, slowTopNQueryFlag, 0,
+		"the topN queries taking longer than this are logged as slow queries, 0 disables the slow query log")
+	flagS.IntVar(&q.slowQueryLogCapacity, "slow-query-log-capacity", 100, "the number of the recent slow queries kept in memory")
+	flagS.Float64Var(&q.auditSampleRate, "query-audit-sample-rate", 0,
+		"the fraction of the queries whose statistics are aggregated by their fingerprints for the top queries report, 0 disables the audit")
+	flagS.DurationVar(&q.auditWindow, "query-audit-window", 10*time.Minute, "the rolling window of the top queries report")
+	flagS.IntVar(&q.maxParallelism, "query-max-parallelism", 0,
+		"the maximum number of the goroutines scanning the series and shards in parallel for all the queries, 0 means the number of CPUs, 1 disables the parallel scan")
+	flagS.StringSliceVar(&q.aggregationPlugins, "aggregation-plugins", nil,
+		"the paths of the Go plugins registering the custom aggregate functions")
+	return flagS
+}
+
+func (q *queryService) Validate() error {
+	if q.slowQueryLogCapacity < 0 {
+		return errNegativeSlowQueryLogCapacity
+	}
+	if q.maxParallelism < 0 {
+		return errNegativeMaxParallelism
+	}
+	if q.auditSampleRate < 0 || q.auditSampleRate > 1 {
+		return errInvalidAuditSampleRate
+	}
+	if q.auditSampleRate > 0 && q.auditWindow < time.Second {
+		return errShortAuditWindow
+	}
+	return nil
+}
+
+// ReloadableFlags returns the thresholds of the slow queries, which are changed at runtime.
+func (q *queryService) ReloadableFlags() map[string]string {
+	flags := make(map[string]string, len(slowQueryFlags))
+	for name, queryType := range slowQueryFlags {
+		flags[name] = q.slowQuery.threshold(queryType).String()
+	}
+	return flags
+}
+
+func (q *queryService) PrepareReload(values map[string]string) (func(), error) {
+	thresholds := make(map[string]time.Duration, len(values))
+	for name, value := range values {
+		threshold, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid %s", name)
+		}
+		thresholds[slowQueryFlags[name]] = threshold
+	}
+	return func() {
+		q.slowQuery.setThresholds(thresholds)
+	}, nil
+}
+
 func (q *queryService) PreRun() error {
 	q.log = logger.GetLogger(moduleName)
+	q.slowQuery = newSlowQueryLog(q.slowQueryLogCapacity, map[string]time.Duration{
+		queryTypeStream:  q.slowStreamQuery,
+		queryTypeMeasure: q.slowMeasureQuery,
+		queryTypeTopN:    q.slowTopNQuery,
+	})
+	q.audit = newQueryAudit(q.auditSampleRate, q.auditWindow)
+	if q.maxParallelism == 0 {
+		q.maxParallelism = runtime.GOMAXPROCS(0)
+	}
+	q.scheduler = executor.NewScheduler(q.maxParallelism)
+	if err := aggregation.LoadPlugins(q.aggregationPlugins); err != nil {
+		return err
+	}
+	if functions := aggregation.CustomFunctions(); len(functions) > 0 {
+		q.log.Info().Strs("functions", functions).Msg("the custom aggregate functions are registered")
+	}
 	return multierr.Combine(
 		q.pipeline.Subscribe(data.TopicStreamQuery, q.sqp),
 		q.pipeline.Subscribe(data.TopicMeasureQuery, q.mqp),
 		q.pipeline.Subscribe(data.TopicTopNQuery, q.tqp),
+		q.pipeline.Subscribe(data.TopicStreamExplain, &streamExplainProcessor{streamQueryProcessor: q.sqp}),
+		q.pipeline.Subscribe(data.TopicMeasureExplain, &measureExplainProcessor{measureQueryProcessor: q.mqp}),
+		q.pipeline.Subscribe(data.TopicStreamInspect, &streamInspectProcessor{streamQueryProcessor: q.sqp}),
+		q.pipeline.Subscribe(data.TopicMeasureInspect, &measureInspectProcessor{measureQueryProcessor: q.mqp}),
+		q.pipeline.Subscribe(data.TopicSlowQueryList, q.slowQuery),
+		q.pipeline.Subscribe(data.TopicTopQueryList, q.audit),
 	)
 }
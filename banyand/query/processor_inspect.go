@@ -0,0 +1,210 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package query
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/multierr"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/banyand/tsdb"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/query/executor"
+	"github.com/apache/skywalking-banyandb/pkg/query/logical"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+var (
+	_ bus.MessageListener = (*streamInspectProcessor)(nil)
+	_ bus.MessageListener = (*measureInspectProcessor)(nil)
+
+	latestFirst = &modelv1.QueryOrder{Sort: modelv1.Sort_SORT_DESC}
+)
+
+type streamInspectProcessor struct {
+	*streamQueryProcessor
+}
+
+func (p *streamInspectProcessor) Rev(message bus.Message) (resp bus.Message) {
+	now := time.Now().UnixNano()
+	req, ok := message.Data().(*streamv1.InspectRequest)
+	if !ok {
+		return bus.NewMessage(bus.MessageID(now), common.NewError("invalid event data type"))
+	}
+	result, err := p.inspect(req)
+	if err != nil {
+		return bus.NewMessage(bus.MessageID(now), common.NewError("fail to inspect the stream %s: %v", req.GetMetadata().GetName(), err))
+	}
+	return bus.NewMessage(bus.MessageID(now), result)
+}
+
+func (p *streamInspectProcessor) inspect(req *streamv1.InspectRequest) (*streamv1.InspectResponse, error) {
+	schema, err := p.metaService.StreamRegistry().GetStream(context.TODO(), req.GetMetadata())
+	if err != nil {
+		return nil, err
+	}
+	ec, plan, err := p.analyze(&streamv1.QueryRequest{
+		Metadata:   req.GetMetadata(),
+		TimeRange:  inspectedTimeRange(req.GetTimeRange()),
+		Limit:      1,
+		OrderBy:    latestFirst,
+		Projection: projectStoredTags(schema.GetTagFamilies()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	ec = executor.WithStreamScheduler(ec, p.scheduler)
+	elements, err := plan.(executor.StreamExecutable).Execute(ec)
+	if err != nil {
+		return nil, err
+	}
+	result := &streamv1.InspectResponse{Stream: schema}
+	if len(elements) > 0 {
+		result.Sample = elements[0]
+	}
+	result.SeriesCount, result.ElementCount, err = countItems(ec, len(schema.GetEntity().GetTagNames()), req.GetTimeRange())
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type measureInspectProcessor struct {
+	*measureQueryProcessor
+}
+
+func (p *measureInspectProcessor) Rev(message bus.Message) (resp bus.Message) {
+	now := time.Now().UnixNano()
+	req, ok := message.Data().(*measurev1.InspectRequest)
+	if !ok {
+		return bus.NewMessage(bus.MessageID(now), common.NewError("invalid event data type"))
+	}
+	result, err := p.inspect(req)
+	if err != nil {
+		return bus.NewMessage(bus.MessageID(now), common.NewError("fail to inspect the measure %s: %v", req.GetMetadata().GetName(), err))
+	}
+	return bus.NewMessage(bus.MessageID(now), result)
+}
+
+func (p *measureInspectProcessor) inspect(req *measurev1.InspectRequest) (result *measurev1.InspectResponse, err error) {
+	schema, err := p.metaService.MeasureRegistry().GetMeasure(context.TODO(), req.GetMetadata())
+	if err != nil {
+		return nil, err
+	}
+	fieldProjection := &measurev1.QueryRequest_FieldProjection{}
+	for _, f := range schema.GetFields() {
+		fieldProjection.Names = append(fieldProjection.Names, f.GetName())
+	}
+	ec, plan, err := p.analyze(&measurev1.QueryRequest{
+		Metadata:        req.GetMetadata(),
+		TimeRange:       inspectedTimeRange(req.GetTimeRange()),
+		Limit:           1,
+		OrderBy:         latestFirst,
+		TagProjection:   projectStoredTags(schema.GetTagFamilies()),
+		FieldProjection: fieldProjection,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ec = executor.WithMeasureScheduler(ec, p.scheduler)
+	mIterator, err := plan.(executor.MeasureExecutable).Execute(ec)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = multierr.Append(err, mIterator.Close())
+	}()
+	result = &measurev1.InspectResponse{Measure: schema}
+	if mIterator.Next() {
+		if current := mIterator.Current(); len(current) > 0 {
+			result.Sample = current[0]
+		}
+	}
+	result.SeriesCount, result.DataPointCount, err = countItems(ec, len(schema.GetEntity().GetTagNames()), req.GetTimeRange())
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func inspectedTimeRange(timeRange *modelv1.TimeRange) *modelv1.TimeRange {
+	if timeRange == nil {
+		return timestamp.DefaultTimeRange
+	}
+	return timeRange
+}
+
+// projectStoredTags projects all the tags except the indexed-only ones, which can't be decoded from the items.
+func projectStoredTags(families []*databasev1.TagFamilySpec) *modelv1.TagProjection {
+	projection := &modelv1.TagProjection{}
+	for _, family := range families {
+		var tags []string
+		for _, tag := range family.GetTags() {
+			if !tag.GetIndexedOnly() {
+				tags = append(tags, tag.GetName())
+			}
+		}
+		if len(tags) > 0 {
+			projection.TagFamilies = append(projection.TagFamilies, &modelv1.TagProjection_TagFamily{
+				Name: family.GetName(),
+				Tags: tags,
+			})
+		}
+	}
+	return projection
+}
+
+// countItems counts the series of an entity with entityLen tags in all the shards, and the items of them in the time range.
+func countItems(ec executor.ExecutionContext, entityLen int, timeRange *modelv1.TimeRange) (seriesCount, itemCount int64, err error) {
+	entity := make(tsdb.Entity, entityLen)
+	for i := range entity {
+		entity[i] = tsdb.AnyEntry
+	}
+	seriesList, err := logical.ListSeries(ec, []tsdb.Entity{entity})
+	if err != nil {
+		return 0, 0, err
+	}
+	timeRange = inspectedTimeRange(timeRange)
+	tr := timestamp.NewInclusiveTimeRange(timeRange.GetBegin().AsTime(), timeRange.GetEnd().AsTime())
+	iters, closers, err := logical.ExecuteForShard(ec, seriesList, tr, func(builder tsdb.SeekerBuilder) {
+		builder.OrderByTime(modelv1.Sort_SORT_ASC)
+	})
+	defer func() {
+		for _, iter := range iters {
+			err = multierr.Append(err, iter.Close())
+		}
+		for _, c := range closers {
+			err = multierr.Append(err, c.Close())
+		}
+	}()
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, iter := range iters {
+		for iter.Next() {
+			itemCount++
+		}
+	}
+	return int64(len(seriesList)), itemCount, nil
+}
@@ -21,6 +21,7 @@ import (
 	"bytes"
 	"container/heap"
 	"context"
+	"math"
 	"time"
 
 	"github.com/pkg/errors"
@@ -28,6 +29,7 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/apache/skywalking-banyandb/api/common"
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
 	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
 	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
@@ -38,6 +40,7 @@ import (
 	"github.com/apache/skywalking-banyandb/pkg/flow"
 	"github.com/apache/skywalking-banyandb/pkg/flow/streaming"
 	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/profiling"
 	"github.com/apache/skywalking-banyandb/pkg/query/aggregation"
 	"github.com/apache/skywalking-banyandb/pkg/timestamp"
 )
@@ -48,6 +51,7 @@ type topNQueryProcessor struct {
 }
 
 func (t *topNQueryProcessor) Rev(message bus.Message) (resp bus.Message) {
+	start := time.Now()
 	request, ok := message.Data().(*measurev1.TopNRequest)
 	if !ok {
 		t.log.Warn().Msg("invalid event data type")
@@ -58,6 +62,7 @@ func (t *topNQueryProcessor) Rev(message bus.Message) (resp bus.Message) {
 		return
 	}
 	t.log.Info().Msg("received a topN query event")
+	defer profiling.Label(profilingLabels(queryTypeTopN, request.GetMetadata(), request)...)()
 	topNMetadata := request.GetMetadata()
 	topNSchema, err := t.metaService.TopNAggregationRegistry().GetTopNAggregation(context.TODO(), topNMetadata)
 	if err != nil {
@@ -66,11 +71,6 @@ func (t *topNQueryProcessor) Rev(message bus.Message) (resp bus.Message) {
 			Msg("fail to get execution context")
 		return
 	}
-	if topNSchema.GetFieldValueSort() != modelv1.Sort_SORT_UNSPECIFIED &&
-		topNSchema.GetFieldValueSort() != request.GetFieldValueSort() {
-		t.log.Warn().Msg("unmatched sort direction")
-		return
-	}
 	sourceMeasure, err := t.measureService.Measure(topNSchema.GetSourceMeasure())
 	if err != nil {
 		t.log.Error().Err(err).
@@ -78,22 +78,29 @@ func (t *topNQueryProcessor) Rev(message bus.Message) (resp bus.Message) {
 			Msg("fail to find source measure")
 		return
 	}
-	shards, err := sourceMeasure.CompanionShards(topNMetadata)
-	if err != nil {
-		t.log.Error().Err(err).
-			Str("topN", topNMetadata.GetName()).
-			Msg("fail to list shards")
-		return
-	}
 	aggregator := createTopNPostAggregator(request.GetTopN(),
 		request.GetAgg(), request.GetFieldValueSort())
-	entity, err := locateEntity(topNSchema, request.GetFieldValueSort(), request.GetConditions())
+	entity, covered := covers(topNSchema, request)
+	if !covered {
+		t.log.Debug().Str("topN", topNMetadata.GetName()).Msg("the pre-aggregation doesn't cover the request, rank the source measure on the fly")
+		if err = t.rankOnTheFly(topNSchema, sourceMeasure, request, aggregator); err != nil {
+			resp = bus.NewMessage(bus.MessageID(time.Now().UnixNano()),
+				common.NewError("fail to rank the measure %s on the fly: %v", topNSchema.GetSourceMeasure().GetName(), err))
+			return
+		}
+		resp = bus.NewMessage(bus.MessageID(time.Now().UnixNano()), aggregator.val())
+		return
+	}
+	shards, err := sourceMeasure.CompanionShards(topNMetadata)
 	if err != nil {
 		t.log.Error().Err(err).
 			Str("topN", topNMetadata.GetName()).
-			Msg("fail to parse entity")
+			Msg("fail to list shards")
 		return
 	}
+	sampled := t.audit.sample()
+	stats := t.newStats(queryTypeTopN, nil, sampled)
+	stopTrace := stats.Trace("TopN")
 	for _, shard := range shards {
 		// TODO: support condition
 		sl, innerErr := shard.Series().List(tsdb.NewPath(entity))
@@ -111,6 +118,8 @@ func (t *topNQueryProcessor) Rev(message bus.Message) (resp bus.Message) {
 					Msg("fail to scan series")
 				return
 			}
+			// topN queries have no limit
+			_ = stats.AddScanned(1, len(iters))
 			for _, iter := range iters {
 				for iter.Next() {
 					tuple, parseErr := parseTopNFamily(iter.Val(), sourceMeasure.GetInterval())
@@ -127,12 +136,120 @@ func (t *topNQueryProcessor) Rev(message bus.Message) (resp bus.Message) {
 		}
 	}
 
+	stopTrace()
+	// there is no logical plan for topN, the request is logged instead
+	t.observe(queryTypeTopN, request, topNMetadata, request, start, stats, sampled)
+
 	now := time.Now().UnixNano()
 	resp = bus.NewMessage(bus.MessageID(now), aggregator.val())
 
 	return
 }
 
+// covers tells whether the pre-aggregated results serve the request, which should be ranked in the requested direction
+// and only be filtered by the equalities of the group-by tags. It returns the entity of the pre-aggregated series if they do.
+func covers(topNSchema *databasev1.TopNAggregation, request *measurev1.TopNRequest) (tsdb.Entity, bool) {
+	if topNSchema.GetFieldValueSort() != modelv1.Sort_SORT_UNSPECIFIED &&
+		topNSchema.GetFieldValueSort() != request.GetFieldValueSort() {
+		return nil, false
+	}
+	entity, err := locateEntity(topNSchema, request.GetFieldValueSort(), request.GetConditions())
+	if err != nil {
+		return nil, false
+	}
+	return entity, true
+}
+
+// rankOnTheFly queries the data points of the source measure and puts them into the aggregator
+// the way the pre-aggregation does: the items are named by the group-by tag values and ranked in the time buckets of the measure's interval.
+func (t *topNQueryProcessor) rankOnTheFly(topNSchema *databasev1.TopNAggregation, sourceMeasure measure.Measure,
+	request *measurev1.TopNRequest, aggregator postProcessor,
+) error {
+	groupBy := topNSchema.GetGroupByTagNames()
+	projection := &modelv1.TagProjection{}
+	for _, name := range groupBy {
+		fIdx, _, spec := pbv1.FindTagByName(sourceMeasure.GetSchema().GetTagFamilies(), name)
+		if spec == nil {
+			return errors.Errorf("the group-by tag %s is not found", name)
+		}
+		family := sourceMeasure.GetSchema().GetTagFamilies()[fIdx].GetName()
+		i := slices.IndexFunc(projection.TagFamilies, func(f *modelv1.TagProjection_TagFamily) bool {
+			return f.GetName() == family
+		})
+		if i < 0 {
+			projection.TagFamilies = append(projection.TagFamilies, &modelv1.TagProjection_TagFamily{Name: family})
+			i = len(projection.TagFamilies) - 1
+		}
+		projection.TagFamilies[i].Tags = append(projection.TagFamilies[i].Tags, name)
+	}
+	criteria := topNSchema.GetCriteria()
+	for _, cond := range request.GetConditions() {
+		criteria = and(criteria, &modelv1.Criteria{Exp: &modelv1.Criteria_Condition{Condition: cond}})
+	}
+	msg := t.mqp.Rev(bus.NewMessage(bus.MessageID(time.Now().UnixNano()), &measurev1.QueryRequest{
+		Metadata:        topNSchema.GetSourceMeasure(),
+		TimeRange:       request.GetTimeRange(),
+		Criteria:        criteria,
+		TagProjection:   projection,
+		FieldProjection: &measurev1.QueryRequest_FieldProjection{Names: []string{topNSchema.GetFieldName()}},
+		Limit:           math.MaxUint32,
+	}))
+	var dataPoints []*measurev1.DataPoint
+	switch d := msg.Data().(type) {
+	case []*measurev1.DataPoint:
+		dataPoints = d
+	case *modelv1.ResourceExhausted:
+		return errors.Errorf("the query exceeds the limit %s %d", d.GetResource(), d.GetLimit())
+	case common.Error:
+		return errors.New(d.Msg())
+	default:
+		return errors.New("invalid query result")
+	}
+	interval := sourceMeasure.GetInterval().Milliseconds()
+	for _, dp := range dataPoints {
+		tagValues := make([]*modelv1.TagValue, 0, len(groupBy))
+		for _, name := range groupBy {
+			tagValues = append(tagValues, tagValueOf(dp, name))
+		}
+		var fieldValue int64
+		for _, f := range dp.GetFields() {
+			if f.GetName() == topNSchema.GetFieldName() {
+				fieldValue = f.GetValue().GetInt().GetValue()
+			}
+		}
+		millis := dp.GetTimestamp().AsTime().UnixMilli()
+		if interval > 0 {
+			millis -= millis % interval
+		}
+		if err := aggregator.put(measure.GroupValues(tagValues), fieldValue, uint64(time.UnixMilli(millis).UnixNano())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func and(left, right *modelv1.Criteria) *modelv1.Criteria {
+	if left == nil {
+		return right
+	}
+	return &modelv1.Criteria{Exp: &modelv1.Criteria_Le{Le: &modelv1.LogicalExpression{
+		Op:    modelv1.LogicalExpression_LOGICAL_OP_AND,
+		Left:  left,
+		Right: right,
+	}}}
+}
+
+func tagValueOf(dp *measurev1.DataPoint, name string) *modelv1.TagValue {
+	for _, family := range dp.GetTagFamilies() {
+		for _, tag := range family.GetTags() {
+			if tag.GetKey() == name {
+				return tag.GetValue()
+			}
+		}
+	}
+	return pbv1.NullTag
+}
+
 func locateEntity(topNSchema *databasev1.TopNAggregation, sortDirection modelv1.Sort, conditions []*modelv1.Condition) (tsdb.Entity, error) {
 	entityMap := make(map[string]int)
 	entity := make([]tsdb.Entry, 1+1+len(topNSchema.GetGroupByTagNames()))
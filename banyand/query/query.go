@@ -30,6 +30,7 @@ import (
 
 type Executor interface {
 	run.PreRunner
+	run.Config
 }
 
 func NewExecutor(_ context.Context, streamService stream.Service, measureService measure.Service,
@@ -0,0 +1,156 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package query
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/query/executor"
+)
+
+const (
+	queryTypeStream  = "stream"
+	queryTypeMeasure = "measure"
+	queryTypeTopN    = "topn"
+)
+
+var _ bus.MessageListener = (*slowQueryLog)(nil)
+
+// slowQueryLog logs the queries exceeding the thresholds and keeps the recent ones in a ring buffer.
+type slowQueryLog struct {
+	log        *logger.Logger
+	thresholds map[string]time.Duration
+	queries    []*databasev1.SlowQuery
+	next       int
+	mu         sync.RWMutex
+	// thresholdMu guards the thresholds, which are reloaded at runtime
+	thresholdMu sync.RWMutex
+}
+
+func newSlowQueryLog(capacity int, thresholds map[string]time.Duration) *slowQueryLog {
+	return &slowQueryLog{
+		log:        logger.GetLogger(moduleName, "slow-query"),
+		thresholds: thresholds,
+		queries:    make([]*databasev1.SlowQuery, 0, capacity),
+	}
+}
+
+func (l *slowQueryLog) threshold(queryType string) time.Duration {
+	l.thresholdMu.RLock()
+	defer l.thresholdMu.RUnlock()
+	return l.thresholds[queryType]
+}
+
+// setThresholds changes the thresholds of the query types.
+func (l *slowQueryLog) setThresholds(thresholds map[string]time.Duration) {
+	l.thresholdMu.Lock()
+	defer l.thresholdMu.Unlock()
+	for queryType, threshold := range thresholds {
+		l.thresholds[queryType] = threshold
+	}
+}
+
+// newStats returns a Stats to collect the details of a query, or nil if the query type is disabled.
+func (l *slowQueryLog) newStats(queryType string) *executor.Stats {
+	if l.threshold(queryType) <= 0 {
+		return nil
+	}
+	return executor.NewStats()
+}
+
+// observe records the query if it's slower than the threshold of its type, 0 threshold disables the type.
+// The plan is serialized only if the query is slow.
+func (l *slowQueryLog) observe(queryType string, metadata *commonv1.Metadata, plan fmt.Stringer, start time.Time, stats *executor.Stats) {
+	threshold := l.threshold(queryType)
+	if threshold <= 0 {
+		return
+	}
+	elapsed := time.Since(start)
+	if elapsed < threshold {
+		return
+	}
+	sq := &databasev1.SlowQuery{
+		Type:          queryType,
+		Metadata:      metadata,
+		Plan:          plan.String(),
+		Indexes:       stats.Indexes(),
+		ScannedSeries: int64(stats.ScannedSeries()),
+		ScannedBlocks: int64(stats.ScannedBlocks()),
+		Duration:      durationpb.New(elapsed),
+		StartedAt:     timestamppb.New(start),
+	}
+	e := l.log.Warn().
+		Str("type", queryType).
+		Str("group", metadata.GetGroup()).
+		Str("name", metadata.GetName()).
+		Str("plan", sq.Plan).
+		Strs("indexes", sq.Indexes).
+		Int64("scanned_series", sq.ScannedSeries).
+		Int64("scanned_blocks", sq.ScannedBlocks).
+		Dur("duration", elapsed)
+	for _, n := range stats.Nodes() {
+		sq.Nodes = append(sq.Nodes, &databasev1.PlanNodeStat{
+			Name:     n.Name,
+			Duration: durationpb.New(n.Duration),
+		})
+		e = e.Dur("node_"+n.Name, n.Duration)
+	}
+	e.Msg("slow query")
+	l.add(sq)
+}
+
+func (l *slowQueryLog) add(sq *databasev1.SlowQuery) {
+	capacity := cap(l.queries)
+	if capacity == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.queries) < capacity {
+		l.queries = append(l.queries, sq)
+		return
+	}
+	l.queries[l.next] = sq
+	l.next = (l.next + 1) % capacity
+}
+
+// list returns the recorded queries from the latest to the oldest.
+func (l *slowQueryLog) list() []*databasev1.SlowQuery {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	size := len(l.queries)
+	result := make([]*databasev1.SlowQuery, 0, size)
+	// the oldest one sits at next once the buffer is full, otherwise at 0
+	for i := 0; i < size; i++ {
+		result = append(result, l.queries[(l.next+size-1-i)%size])
+	}
+	return result
+}
+
+func (l *slowQueryLog) Rev(message bus.Message) (resp bus.Message) {
+	return bus.NewMessage(bus.MessageID(time.Now().UnixNano()), l.list())
+}
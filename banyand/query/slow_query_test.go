@@ -0,0 +1,83 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package query
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+)
+
+type plan string
+
+func (p plan) String() string {
+	return string(p)
+}
+
+var _ = Describe("SlowQueryLog", func() {
+	var l *slowQueryLog
+	BeforeEach(func() {
+		l = newSlowQueryLog(2, map[string]time.Duration{
+			queryTypeStream:  time.Second,
+			queryTypeMeasure: 0,
+		})
+	})
+	names := func() []string {
+		resp := l.Rev(bus.NewMessage(bus.MessageID(time.Now().UnixNano()), nil))
+		var result []string
+		for _, sq := range resp.Data().([]*databasev1.SlowQuery) {
+			result = append(result, sq.GetMetadata().GetName())
+		}
+		return result
+	}
+	observe := func(queryType, name string, elapsed time.Duration) {
+		stats := l.newStats(queryType)
+		stats.AddScanned(1, 2)
+		l.observe(queryType, &commonv1.Metadata{Group: "default", Name: name}, plan("IndexScan"), time.Now().Add(-elapsed), stats)
+	}
+	It("records the slow queries", func() {
+		observe(queryTypeStream, "fast", time.Millisecond)
+		observe(queryTypeStream, "slow", 2*time.Second)
+		Expect(names()).To(Equal([]string{"slow"}))
+		sq := l.list()[0]
+		Expect(sq.GetType()).To(Equal(queryTypeStream))
+		Expect(sq.GetPlan()).To(Equal("IndexScan"))
+		Expect(sq.GetScannedSeries()).To(BeNumerically("==", 1))
+		Expect(sq.GetScannedBlocks()).To(BeNumerically("==", 2))
+		Expect(sq.GetDuration().AsDuration()).To(BeNumerically(">=", 2*time.Second))
+	})
+	It("ignores the disabled types", func() {
+		Expect(l.newStats(queryTypeMeasure)).To(BeNil())
+		observe(queryTypeMeasure, "slow", time.Hour)
+		observe(queryTypeTopN, "slow", time.Hour)
+		Expect(names()).To(BeEmpty())
+	})
+	It("keeps the recent ones", func() {
+		observe(queryTypeStream, "q1", 2*time.Second)
+		observe(queryTypeStream, "q2", 2*time.Second)
+		observe(queryTypeStream, "q3", 2*time.Second)
+		Expect(names()).To(Equal([]string{"q3", "q2"}))
+		observe(queryTypeStream, "q4", 2*time.Second)
+		Expect(names()).To(Equal([]string{"q4", "q3"}))
+	})
+})
@@ -0,0 +1,270 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package query
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/convert"
+	"github.com/apache/skywalking-banyandb/pkg/query/executor"
+)
+
+const (
+	// the window is split into the buckets, the oldest one is discarded as the window rolls
+	auditBuckets = 10
+	// the fingerprints exceeding it in a bucket are not recorded to bound the memory
+	maxAuditFingerprints = 1000
+	defaultTopQueryLimit = 10
+)
+
+var (
+	_ bus.MessageListener = (*queryAudit)(nil)
+
+	// the fields holding the literals of a query, which are removed from its shape
+	literalFields = map[protoreflect.Name]struct{}{
+		"time_range":     {},
+		"offset":         {},
+		"limit":          {},
+		"read_timestamp": {},
+		"limits":         {},
+		"top_n":          {},
+		"number":         {},
+	}
+	tagValueName = (&modelv1.TagValue{}).ProtoReflect().Descriptor().FullName()
+)
+
+// fingerprint returns the shape of a query and its hash.
+// The shape is the request without the literals, so that the queries issued by a dashboard share a fingerprint
+// regardless of the time range and the tag values they carry.
+func fingerprint(request proto.Message) (string, string) {
+	normalized := proto.Clone(request)
+	removeLiterals(normalized.ProtoReflect())
+	data, _ := proto.MarshalOptions{Deterministic: true}.Marshal(normalized)
+	shape, _ := protojson.Marshal(normalized)
+	return strconv.FormatUint(convert.Hash(data), 16), string(shape)
+}
+
+func removeLiterals(m protoreflect.Message) {
+	var literals []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if _, ok := literalFields[fd.Name()]; ok {
+			literals = append(literals, fd)
+			return true
+		}
+		if fd.Kind() != protoreflect.MessageKind || fd.IsMap() {
+			return true
+		}
+		if fd.Message().FullName() == tagValueName {
+			literals = append(literals, fd)
+			return true
+		}
+		if fd.IsList() {
+			l := v.List()
+			for i := 0; i < l.Len(); i++ {
+				removeLiterals(l.Get(i).Message())
+			}
+			return true
+		}
+		removeLiterals(v.Message())
+		return true
+	})
+	for _, fd := range literals {
+		m.Clear(fd)
+	}
+}
+
+type auditBucket struct {
+	start   time.Time
+	queries map[string]*databasev1.TopQuery
+}
+
+// queryAudit samples the queries and aggregates their statistics by the fingerprints in a rolling window.
+type queryAudit struct {
+	now        func() time.Time
+	buckets    []auditBucket
+	sampleRate float64
+	window     time.Duration
+	width      time.Duration
+	mu         sync.Mutex
+}
+
+func newQueryAudit(sampleRate float64, window time.Duration) *queryAudit {
+	a := &queryAudit{
+		now:        time.Now,
+		sampleRate: sampleRate,
+		window:     window,
+	}
+	if sampleRate > 0 {
+		a.buckets = make([]auditBucket, auditBuckets)
+		a.width = window / auditBuckets
+	}
+	return a
+}
+
+// sample decides whether a query is audited, 0 sample rate disables the audit.
+func (a *queryAudit) sample() bool {
+	if a.sampleRate <= 0 {
+		return false
+	}
+	return a.sampleRate >= 1 || rand.Float64() < a.sampleRate
+}
+
+// observe records the statistics of a sampled query.
+func (a *queryAudit) observe(queryType string, request proto.Message, metadata *commonv1.Metadata, start time.Time, stats *executor.Stats) {
+	elapsed := time.Since(start)
+	fp, shape := fingerprint(request)
+	now := a.now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	b := a.bucket(now)
+	tq, ok := b.queries[fp]
+	if !ok {
+		if len(b.queries) >= maxAuditFingerprints {
+			return
+		}
+		tq = &databasev1.TopQuery{
+			Fingerprint:   fp,
+			Type:          queryType,
+			Metadata:      metadata,
+			Shape:         shape,
+			TotalDuration: durationpb.New(0),
+			MaxDuration:   durationpb.New(0),
+		}
+		b.queries[fp] = tq
+	}
+	mergeTopQuery(tq, &databasev1.TopQuery{
+		Count:         1,
+		TotalDuration: durationpb.New(elapsed),
+		MaxDuration:   durationpb.New(elapsed),
+		ScannedSeries: int64(stats.ScannedSeries()),
+		ScannedBlocks: int64(stats.ScannedBlocks()),
+		LastSeenAt:    timestamppb.New(start),
+	})
+}
+
+// bucket returns the bucket covering t, which is reset if it holds the statistics out of the window.
+func (a *queryAudit) bucket(t time.Time) *auditBucket {
+	start := t.Truncate(a.width)
+	b := &a.buckets[(start.UnixNano()/int64(a.width))%auditBuckets]
+	if !b.start.Equal(start) {
+		b.start = start
+		b.queries = make(map[string]*databasev1.TopQuery)
+	}
+	return b
+}
+
+func mergeTopQuery(dst, src *databasev1.TopQuery) {
+	dst.Count += src.GetCount()
+	dst.TotalDuration = durationpb.New(dst.GetTotalDuration().AsDuration() + src.GetTotalDuration().AsDuration())
+	if src.GetMaxDuration().AsDuration() > dst.GetMaxDuration().AsDuration() {
+		dst.MaxDuration = src.GetMaxDuration()
+	}
+	dst.ScannedSeries += src.GetScannedSeries()
+	dst.ScannedBlocks += src.GetScannedBlocks()
+	if dst.GetLastSeenAt().AsTime().Before(src.GetLastSeenAt().AsTime()) {
+		dst.LastSeenAt = src.GetLastSeenAt()
+	}
+}
+
+// top merges the buckets in the window and returns the most expensive queries.
+func (a *queryAudit) top(limit int, orderBy databasev1.TopQueryServiceListRequest_OrderBy) []*databasev1.TopQuery {
+	if limit <= 0 {
+		limit = defaultTopQueryLimit
+	}
+	now := a.now()
+	merged := make(map[string]*databasev1.TopQuery)
+	a.mu.Lock()
+	for i := range a.buckets {
+		b := a.buckets[i]
+		if b.queries == nil || !b.start.Add(a.width).After(now.Add(-a.window)) {
+			continue
+		}
+		for fp, tq := range b.queries {
+			m, ok := merged[fp]
+			if !ok {
+				m = &databasev1.TopQuery{
+					Fingerprint:   fp,
+					Type:          tq.GetType(),
+					Metadata:      tq.GetMetadata(),
+					Shape:         tq.GetShape(),
+					TotalDuration: durationpb.New(0),
+					MaxDuration:   durationpb.New(0),
+				}
+				merged[fp] = m
+			}
+			mergeTopQuery(m, tq)
+		}
+	}
+	a.mu.Unlock()
+	result := make([]*databasev1.TopQuery, 0, len(merged))
+	for _, tq := range merged {
+		result = append(result, tq)
+	}
+	cost := costOf(orderBy)
+	sort.Slice(result, func(i, j int) bool {
+		ci, cj := cost(result[i]), cost(result[j])
+		if ci != cj {
+			return ci > cj
+		}
+		return result[i].GetFingerprint() < result[j].GetFingerprint()
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+func costOf(orderBy databasev1.TopQueryServiceListRequest_OrderBy) func(*databasev1.TopQuery) int64 {
+	switch orderBy {
+	case databasev1.TopQueryServiceListRequest_ORDER_BY_COUNT:
+		return (*databasev1.TopQuery).GetCount
+	case databasev1.TopQueryServiceListRequest_ORDER_BY_MAX_DURATION:
+		return func(tq *databasev1.TopQuery) int64 {
+			return int64(tq.GetMaxDuration().AsDuration())
+		}
+	case databasev1.TopQueryServiceListRequest_ORDER_BY_SCANNED_BLOCKS:
+		return (*databasev1.TopQuery).GetScannedBlocks
+	default:
+		return func(tq *databasev1.TopQuery) int64 {
+			return int64(tq.GetTotalDuration().AsDuration())
+		}
+	}
+}
+
+func (a *queryAudit) Rev(message bus.Message) (resp bus.Message) {
+	req, _ := message.Data().(*databasev1.TopQueryServiceListRequest)
+	return bus.NewMessage(bus.MessageID(time.Now().UnixNano()), &databasev1.TopQueryServiceListResponse{
+		TopQueries: a.top(int(req.GetLimit()), req.GetOrderBy()),
+		Window:     durationpb.New(a.window),
+		SampleRate: a.sampleRate,
+	})
+}
@@ -0,0 +1,134 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package query
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/query/executor"
+)
+
+func streamQuery(name string, serviceID string, begin time.Time) *streamv1.QueryRequest {
+	return &streamv1.QueryRequest{
+		Metadata:  &commonv1.Metadata{Group: "default", Name: name},
+		TimeRange: &modelv1.TimeRange{Begin: timestamppb.New(begin), End: timestamppb.New(begin.Add(time.Hour))},
+		Limit:     20,
+		Criteria: &modelv1.Criteria{
+			Exp: &modelv1.Criteria_Condition{
+				Condition: &modelv1.Condition{
+					Name:  "service_id",
+					Op:    modelv1.Condition_BINARY_OP_EQ,
+					Value: &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: serviceID}}},
+				},
+			},
+		},
+		Projection: &modelv1.TagProjection{
+			TagFamilies: []*modelv1.TagProjection_TagFamily{{Name: "searchable", Tags: []string{"trace_id"}}},
+		},
+	}
+}
+
+var _ = Describe("Fingerprint", func() {
+	It("ignores the literals", func() {
+		fp1, shape := fingerprint(streamQuery("sw", "svc-1", time.Now()))
+		fp2, _ := fingerprint(streamQuery("sw", "svc-2", time.Now().Add(-time.Hour)))
+		Expect(fp1).To(Equal(fp2))
+		Expect(shape).To(ContainSubstring("service_id"))
+		Expect(shape).NotTo(ContainSubstring("svc-1"))
+	})
+	It("distinguishes the shapes", func() {
+		fp1, _ := fingerprint(streamQuery("sw", "svc-1", time.Now()))
+		fp2, _ := fingerprint(streamQuery("other", "svc-1", time.Now()))
+		q := streamQuery("sw", "svc-1", time.Now())
+		q.Projection.TagFamilies[0].Tags = append(q.Projection.TagFamilies[0].Tags, "duration")
+		fp3, _ := fingerprint(q)
+		Expect(fp1).NotTo(Equal(fp2))
+		Expect(fp1).NotTo(Equal(fp3))
+	})
+	It("leaves the request untouched", func() {
+		q := streamQuery("sw", "svc-1", time.Now())
+		origin := proto.Clone(q)
+		fingerprint(q)
+		Expect(proto.Equal(q, origin)).To(BeTrue())
+	})
+})
+
+var _ = Describe("QueryAudit", func() {
+	var a *queryAudit
+	var now time.Time
+	BeforeEach(func() {
+		now = time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+		a = newQueryAudit(1, 10*time.Minute)
+		a.now = func() time.Time {
+			return now
+		}
+	})
+	observe := func(name string, elapsed time.Duration, blocks int) {
+		stats := executor.NewStats()
+		_ = stats.AddScanned(1, blocks)
+		a.observe(queryTypeStream, streamQuery(name, "svc", now), &commonv1.Metadata{Group: "default", Name: name},
+			time.Now().Add(-elapsed), stats)
+	}
+	names := func(orderBy databasev1.TopQueryServiceListRequest_OrderBy) []string {
+		resp := a.Rev(bus.NewMessage(bus.MessageID(time.Now().UnixNano()), &databasev1.TopQueryServiceListRequest{OrderBy: orderBy}))
+		var result []string
+		for _, tq := range resp.Data().(*databasev1.TopQueryServiceListResponse).GetTopQueries() {
+			result = append(result, tq.GetMetadata().GetName())
+		}
+		return result
+	}
+	It("ranks the queries by the cost", func() {
+		observe("frequent", time.Second, 1)
+		observe("frequent", time.Second, 1)
+		observe("frequent", time.Second, 1)
+		observe("slow", 2*time.Second, 100)
+		Expect(names(databasev1.TopQueryServiceListRequest_ORDER_BY_UNSPECIFIED)).To(Equal([]string{"frequent", "slow"}))
+		Expect(names(databasev1.TopQueryServiceListRequest_ORDER_BY_MAX_DURATION)).To(Equal([]string{"slow", "frequent"}))
+		Expect(names(databasev1.TopQueryServiceListRequest_ORDER_BY_SCANNED_BLOCKS)).To(Equal([]string{"slow", "frequent"}))
+		tq := a.top(1, databasev1.TopQueryServiceListRequest_ORDER_BY_COUNT)
+		Expect(tq).To(HaveLen(1))
+		Expect(tq[0].GetCount()).To(BeNumerically("==", 3))
+		Expect(tq[0].GetTotalDuration().AsDuration()).To(BeNumerically(">=", 3*time.Second))
+		Expect(tq[0].GetScannedSeries()).To(BeNumerically("==", 3))
+	})
+	It("rolls the window", func() {
+		observe("old", time.Second, 1)
+		now = now.Add(5 * time.Minute)
+		observe("new", time.Second, 1)
+		observe("old", time.Second, 1)
+		Expect(a.top(0, databasev1.TopQueryServiceListRequest_ORDER_BY_COUNT)[0].GetCount()).To(BeNumerically("==", 2))
+		now = now.Add(6 * time.Minute)
+		Expect(a.top(0, databasev1.TopQueryServiceListRequest_ORDER_BY_COUNT)[0].GetCount()).To(BeNumerically("==", 1))
+		now = now.Add(time.Hour)
+		Expect(names(databasev1.TopQueryServiceListRequest_ORDER_BY_UNSPECIFIED)).To(BeEmpty())
+	})
+	It("samples nothing if it's disabled", func() {
+		Expect(newQueryAudit(0, time.Minute).sample()).To(BeFalse())
+		Expect(a.sample()).To(BeTrue())
+	})
+})
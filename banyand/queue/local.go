@@ -18,13 +18,17 @@
 package queue
 
 import (
+	"context"
+
 	"github.com/apache/skywalking-banyandb/banyand/discovery"
 	"github.com/apache/skywalking-banyandb/pkg/bus"
 )
 
 var (
-	_ bus.Publisher  = (*local)(nil)
-	_ bus.Subscriber = (*local)(nil)
+	_ bus.Publisher       = (*local)(nil)
+	_ bus.Subscriber      = (*local)(nil)
+	_ bus.CodecNegotiator = (*local)(nil)
+	_ bus.Flusher         = (*local)(nil)
 )
 
 type local struct {
@@ -40,6 +44,23 @@ func (l *local) Publish(topic bus.Topic, message ...bus.Message) (bus.Future, er
 	return l.local.Publish(topic, message...)
 }
 
+func (l *local) Flush(ctx context.Context, topics ...bus.Topic) error {
+	return l.local.Flush(ctx, topics...)
+}
+
+func (l *local) Pending(topics ...bus.Topic) int64 {
+	return l.local.Pending(topics...)
+}
+
+func (l *local) RegisterCodecs(topic bus.Topic, codecs ...bus.Codec) {
+	l.local.RegisterCodecs(topic, codecs...)
+}
+
+// Negotiate picks the codec of the topic accepted by a peer. The subscribers of the local pipeline accept the native codec.
+func (l *local) Negotiate(topic bus.Topic, accepted ...string) (bus.Codec, error) {
+	return l.local.Negotiate(topic, accepted...)
+}
+
 func (l local) Name() string {
 	return "local-pipeline"
 }
@@ -20,6 +20,7 @@ package queue
 import (
 	"context"
 
+	"github.com/apache/skywalking-banyandb/api/data"
 	"github.com/apache/skywalking-banyandb/banyand/discovery"
 	"github.com/apache/skywalking-banyandb/pkg/bus"
 	"github.com/apache/skywalking-banyandb/pkg/run"
@@ -30,11 +31,17 @@ type Queue interface {
 	run.Unit
 	bus.Subscriber
 	bus.Publisher
+	bus.CodecNegotiator
+	bus.Flusher
 }
 
 func NewQueue(_ context.Context, repo discovery.ServiceRepo) (Queue, error) {
+	b := bus.NewBus()
+	// the writes are serialized by protobuf once they are sent to the remote data nodes
+	b.RegisterCodecs(data.TopicStreamWrite, data.StreamWriteCodec)
+	b.RegisterCodecs(data.TopicMeasureWrite, data.MeasureWriteCodec)
 	return &local{
 		repo:  repo,
-		local: bus.NewBus(),
+		local: b,
 	}, nil
 }
@@ -31,7 +31,9 @@ import (
 	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
 	"github.com/apache/skywalking-banyandb/banyand/tsdb"
 	"github.com/apache/skywalking-banyandb/pkg/encoding"
+	"github.com/apache/skywalking-banyandb/pkg/intern"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/partition"
 	pb_v1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
 	resourceSchema "github.com/apache/skywalking-banyandb/pkg/schema"
 )
@@ -40,10 +42,12 @@ type schemaRepo struct {
 	resourceSchema.Repository
 	l        *logger.Logger
 	metadata metadata.Repo
+	// indexManager backfills the indices of the new bindings, it's absent until the service runs
+	indexManager *resourceSchema.IndexManager
 }
 
 func newSchemaRepo(path string, metadata metadata.Repo, repo discovery.ServiceRepo,
-	dbOpts tsdb.DatabaseOpts, l *logger.Logger,
+	dbOpts tsdb.DatabaseOpts, interning *intern.Table, l *logger.Logger,
 ) schemaRepo {
 	return schemaRepo{
 		l:        l,
@@ -52,7 +56,7 @@ func newSchemaRepo(path string, metadata metadata.Repo, repo discovery.ServiceRe
 			metadata,
 			repo,
 			l,
-			newSupplier(path, metadata, dbOpts, l),
+			newSupplier(path, metadata, dbOpts, interning, l),
 			event.StreamTopicShardEvent,
 			event.StreamTopicEntityEvent,
 		),
@@ -99,6 +103,9 @@ func (sr *schemaRepo) OnAddOrUpdate(m schema.Metadata) {
 				Kind:     resourceSchema.EventKindResource,
 				Metadata: stm.GetMetadata(),
 			})
+			if sr.indexManager != nil {
+				sr.indexManager.Backfill(irb)
+			}
 		}
 	case schema.KindIndexRule:
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -172,26 +179,33 @@ func (sr *schemaRepo) loadStream(metadata *commonv1.Metadata) (*stream, bool) {
 var _ resourceSchema.ResourceSupplier = (*supplier)(nil)
 
 type supplier struct {
-	path     string
-	dbOpts   tsdb.DatabaseOpts
-	metadata metadata.Repo
-	l        *logger.Logger
+	path         string
+	dbOpts       tsdb.DatabaseOpts
+	metadata     metadata.Repo
+	propertyTags pb_v1.PropertyTagsGetter
+	interning    *intern.Table
+	l            *logger.Logger
 }
 
-func newSupplier(path string, metadata metadata.Repo, dbOpts tsdb.DatabaseOpts, l *logger.Logger) *supplier {
+func newSupplier(path string, metadata metadata.Repo, dbOpts tsdb.DatabaseOpts, interning *intern.Table, l *logger.Logger) *supplier {
 	return &supplier{
-		path:     path,
-		dbOpts:   dbOpts,
-		metadata: metadata,
-		l:        l,
+		path:      path,
+		dbOpts:    dbOpts,
+		metadata:  metadata,
+		interning: interning,
+		l:         l,
+
+		propertyTags: resourceSchema.NewPropertyTagsGetter(metadata.PropertyRegistry()),
 	}
 }
 
-func (s *supplier) OpenResource(shardNum uint32, db tsdb.Supplier, spec resourceSchema.ResourceSpec) (resourceSchema.Resource, error) {
+func (s *supplier) OpenResource(sharding partition.Sharding, db tsdb.Supplier, spec resourceSchema.ResourceSpec) (resourceSchema.Resource, error) {
 	streamSchema := spec.Schema.(*databasev1.Stream)
-	return openStream(shardNum, db, streamSpec{
-		schema:     streamSchema,
-		indexRules: spec.IndexRules,
+	return openStream(sharding, db, streamSpec{
+		schema:       streamSchema,
+		indexRules:   spec.IndexRules,
+		propertyTags: s.propertyTags,
+		interning:    s.interning,
 	}, s.l)
 }
 
@@ -220,6 +234,10 @@ func (s *supplier) OpenDB(groupSchema *commonv1.Group) (tsdb.Database, error) {
 	if opts.TTL, err = pb_v1.ToIntervalRule(groupSchema.ResourceOpts.Ttl); err != nil {
 		return nil, err
 	}
+	opts = pb_v1.ApplyFlushOpts(opts, groupSchema.ResourceOpts.GetFlush())
+	if opts, err = pb_v1.ApplyTimeZone(opts, groupSchema.ResourceOpts.GetTimeZone()); err != nil {
+		return nil, err
+	}
 	return tsdb.OpenDatabase(
 		context.WithValue(context.Background(), common.PositionKey, common.Position{
 			Module:   "stream",
@@ -123,9 +123,12 @@ var _ = Describe("Metadata", func() {
 
 			It("should update a new stream", func() {
 				svcs.repo.EXPECT().Publish(event.StreamTopicEntityEvent, test.NewEntityEventMatcher(databasev1.Action_ACTION_PUT)).Times(1)
-				// Remove the first tag from the entity
-				streamSchema.Entity.TagNames = streamSchema.Entity.TagNames[1:]
-				entitySize := len(streamSchema.Entity.TagNames)
+				// Append a tag to the first tag family
+				streamSchema.TagFamilies[0].Tags = append(streamSchema.TagFamilies[0].Tags, &databasev1.TagSpec{
+					Name: "appended",
+					Type: databasev1.TagType_TAG_TYPE_STRING,
+				})
+				tagSize := len(streamSchema.TagFamilies[0].Tags)
 
 				Expect(svcs.metadataService.StreamRegistry().UpdateStream(context.TODO(), streamSchema)).Should(Succeed())
 
@@ -138,9 +141,17 @@ var _ = Describe("Metadata", func() {
 						return false
 					}
 
-					return len(val.schema.GetEntity().TagNames) == entitySize
+					return len(val.schema.GetTagFamilies()[0].GetTags()) == tagSize &&
+						val.schema.GetSchemaVersion() == streamSchema.GetSchemaVersion()
 				}).WithTimeout(10 * time.Second).Should(BeTrue())
 			})
+
+			It("should reject changing the entity", func() {
+				// Remove the first tag from the entity
+				streamSchema.Entity.TagNames = streamSchema.Entity.TagNames[1:]
+
+				Expect(svcs.metadataService.StreamRegistry().UpdateStream(context.TODO(), streamSchema)).ShouldNot(Succeed())
+			})
 		})
 	})
 })
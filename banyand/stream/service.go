@@ -20,6 +20,7 @@ package stream
 import (
 	"context"
 	"path"
+	"strconv"
 	"time"
 
 	"github.com/pkg/errors"
@@ -27,17 +28,23 @@ import (
 	"github.com/apache/skywalking-banyandb/api/data"
 	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
 	"github.com/apache/skywalking-banyandb/banyand/discovery"
+	"github.com/apache/skywalking-banyandb/banyand/kv"
 	"github.com/apache/skywalking-banyandb/banyand/metadata"
 	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
 	"github.com/apache/skywalking-banyandb/banyand/queue"
 	"github.com/apache/skywalking-banyandb/banyand/tsdb"
+	"github.com/apache/skywalking-banyandb/pkg/cache"
+	"github.com/apache/skywalking-banyandb/pkg/intern"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 	"github.com/apache/skywalking-banyandb/pkg/run"
+	resourceSchema "github.com/apache/skywalking-banyandb/pkg/schema"
+	"github.com/apache/skywalking-banyandb/pkg/throttle"
 )
 
 var (
-	ErrEmptyRootPath  = errors.New("root path is empty")
-	ErrStreamNotExist = errors.New("stream doesn't exist")
+	ErrEmptyRootPath       = errors.New("root path is empty")
+	ErrRecoveryConcurrency = errors.New("the recovery concurrency should be positive")
+	ErrStreamNotExist      = errors.New("stream doesn't exist")
 )
 
 type Service interface {
@@ -49,12 +56,24 @@ type Service interface {
 
 var _ Service = (*service)(nil)
 
+var _ run.Reloader = (*service)(nil)
+
+const backgroundIORateFlag = "stream-background-io-rate"
+
 type service struct {
 	root   string
 	dbOpts tsdb.DatabaseOpts
+	// backgroundIORate is the max bytes per second read by the background jobs
+	backgroundIORate int
+	blockCacheSize   int64
+	blockCachePolicy string
+	internSize       int
+	fsyncPolicy      string
+	timeZone         string
 
 	schemaRepo    schemaRepo
 	writeListener *writeCallback
+	indexManager  *resourceSchema.IndexManager
 	l             *logger.Logger
 	metadata      metadata.Repo
 	pipeline      queue.Queue
@@ -74,9 +93,31 @@ func (s *service) Stream(metadata *commonv1.Metadata) (Stream, error) {
 func (s *service) FlagSet() *run.FlagSet {
 	flagS := run.NewFlagSet("storage")
 	flagS.StringVar(&s.root, "stream-root-path", "/tmp", "the root path of database")
-	flagS.Int64Var(&s.dbOpts.BlockMemSize, "stream-block-mem-size", 8<<20, "block memory size")
+	flagS.Int64Var(&s.dbOpts.BlockMemSize, "stream-block-mem-size", 8<<20, "block memory size, which is overridden by the memtable size of a group")
 	flagS.Int64Var(&s.dbOpts.SeriesMemSize, "stream-seriesmeta-mem-size", 1<<20, "series metadata memory size")
 	flagS.Int64Var(&s.dbOpts.GlobalIndexMemSize, "stream-global-index-mem-size", 2<<20, "global index memory size")
+	flagS.IntVar(&s.backgroundIORate, backgroundIORateFlag, 0,
+		"the max bytes per second read by the background jobs, e.g. merging the blocks and backfilling the indices, 0 means unlimited")
+	flagS.Int64Var(&s.blockCacheSize, "stream-block-cache-size", 0, "the size in bytes of the cache of the values read from the blocks, 0 disables the cache")
+	flagS.IntVar(&s.internSize, "stream-intern-size", 1<<16,
+		"the number of the strings of the tags interned by the writes, e.g. the names of services and endpoints, 0 disables the interning")
+	flagS.StringVar(&s.blockCachePolicy, "stream-block-cache-policy", string(cache.PolicyLRU), "the eviction policy of the block cache, lru or tinylfu")
+	flagS.StringVar(&s.fsyncPolicy, "stream-fsync-policy", string(kv.SyncPolicyOS),
+		"when the write-ahead logs are synced to the disk: os leaves them to the OS, per-write syncs before acknowledging each write, interval syncs them periodically")
+	flagS.DurationVar(&s.dbOpts.Durability.Interval, "stream-fsync-interval", time.Second,
+		"the period of syncing the write-ahead logs under the interval fsync policy, which bounds the data lost by a crash")
+	flagS.DurationVar(&s.dbOpts.IdleTimeout, "stream-idle-timeout", 0,
+		"close the blocks not written for the period, which flushes the buffered writes of their series and frees the memory, 0 disables it")
+	flagS.Int64Var(&s.dbOpts.MaxBufferedElements, "stream-max-buffered-elements", 0,
+		"flush a block once the elements written since its last flush reach the number, 0 disables it, which is overridden by the flush options of a group")
+	flagS.DurationVar(&s.dbOpts.FlushInterval, "stream-flush-interval", 0,
+		"flush the elements buffered by the blocks at least once per the interval, 0 disables it, which is overridden by the flush options of a group")
+	flagS.Int64Var(&s.dbOpts.BlockMergeSize, "stream-block-merge-size", 0,
+		"the target size in bytes of the blocks merged from the small adjacent sealed blocks of a segment, 0 disables the merging")
+	flagS.StringVar(&s.timeZone, "stream-time-zone", "Local",
+		"the IANA name of the time zone the segments and blocks align to, e.g. Asia/Shanghai, which is overridden by the time zone of a group")
+	flagS.IntVar(&s.dbOpts.RecoveryConcurrency, "stream-recovery-concurrency", tsdb.DefaultRecoveryConcurrency,
+		"the number of the shards opened in parallel at startup, whose series databases replay their write-ahead logs")
 	return flagS
 }
 
@@ -84,7 +125,36 @@ func (s *service) Validate() error {
 	if s.root == "" {
 		return ErrEmptyRootPath
 	}
-	return nil
+	if s.dbOpts.RecoveryConcurrency < 1 {
+		return ErrRecoveryConcurrency
+	}
+	if err := (kv.Durability{Policy: kv.SyncPolicy(s.fsyncPolicy), Interval: s.dbOpts.Durability.Interval}).Validate(); err != nil {
+		return err
+	}
+	if _, err := time.LoadLocation(s.timeZone); err != nil {
+		return errors.Wrapf(err, "invalid time zone %q", s.timeZone)
+	}
+	return cache.CheckPolicy(cache.Policy(s.blockCachePolicy))
+}
+
+// RecoveryProgress returns the progress of opening the shards of the groups.
+func (s *service) RecoveryProgress() tsdb.RecoveryProgress {
+	return s.dbOpts.Recovery.Progress()
+}
+
+// ReloadableFlags returns the IO rate of the background jobs, which is changed at runtime.
+func (s *service) ReloadableFlags() map[string]string {
+	return map[string]string{backgroundIORateFlag: strconv.Itoa(s.dbOpts.BackgroundThrottle.Limit())}
+}
+
+func (s *service) PrepareReload(values map[string]string) (func(), error) {
+	rate, err := strconv.Atoi(values[backgroundIORateFlag])
+	if err != nil || rate < 0 {
+		return nil, errors.Errorf("invalid %s %q", backgroundIORateFlag, values[backgroundIORateFlag])
+	}
+	return func() {
+		s.dbOpts.BackgroundThrottle.SetLimit(rate)
+	}, nil
 }
 
 func (s *service) Name() string {
@@ -99,7 +169,15 @@ func (s *service) PreRun() error {
 	if err != nil {
 		return err
 	}
-	s.schemaRepo = newSchemaRepo(path.Join(s.root, s.Name()), s.metadata, s.repo, s.dbOpts, s.l)
+	s.dbOpts.BackgroundThrottle = throttle.NewAdjustable(s.backgroundIORate)
+	s.dbOpts.Durability.Policy = kv.SyncPolicy(s.fsyncPolicy)
+	// the time zone is checked by Validate
+	s.dbOpts.TimeZone, _ = time.LoadLocation(s.timeZone)
+	if s.dbOpts.BlockCache, err = cache.New(cache.Policy(s.blockCachePolicy), s.blockCacheSize); err != nil {
+		return err
+	}
+	s.dbOpts.Recovery = tsdb.NewRecovery(s.l)
+	s.schemaRepo = newSchemaRepo(path.Join(s.root, s.Name()), s.metadata, s.repo, s.dbOpts, intern.New(s.Name(), s.internSize), s.l)
 	for _, g := range groups {
 		if g.Catalog != commonv1.Catalog_CATALOG_STREAM {
 			continue
@@ -127,7 +205,27 @@ func (s *service) PreRun() error {
 	if errWrite != nil {
 		return errWrite
 	}
-	return nil
+	if err := s.pipeline.Subscribe(data.TopicStreamRollover, resourceSchema.NewRolloverListener(s.schemaRepo, s.l)); err != nil {
+		return err
+	}
+	if err := s.pipeline.Subscribe(data.TopicStreamTimeRange, resourceSchema.NewTimeRangeListener(s.schemaRepo, s.l)); err != nil {
+		return err
+	}
+	if err := s.pipeline.Subscribe(data.TopicStreamManifest, resourceSchema.NewManifestListener(s.schemaRepo, s.l)); err != nil {
+		return err
+	}
+	if err := s.pipeline.Subscribe(data.TopicStreamUsage, resourceSchema.NewUsageListener(s.schemaRepo, s.l)); err != nil {
+		return err
+	}
+	if err := s.pipeline.Subscribe(data.TopicStreamBlockExport, resourceSchema.NewBlockExportListener(s.schemaRepo, s.l)); err != nil {
+		return err
+	}
+	s.indexManager = resourceSchema.NewIndexManager(s.schemaRepo, s.l)
+	s.schemaRepo.indexManager = s.indexManager
+	if err := s.pipeline.Subscribe(data.TopicStreamIndexStats, s.indexManager); err != nil {
+		return err
+	}
+	return s.pipeline.Subscribe(data.TopicStreamIndexRebuild, s.indexManager)
 }
 
 func (s *service) Serve() run.StopNotify {
@@ -141,7 +239,11 @@ func (s *service) Serve() run.StopNotify {
 }
 
 func (s *service) GracefulStop() {
+	if s.indexManager != nil {
+		s.indexManager.Close()
+	}
 	s.schemaRepo.Close()
+	s.dbOpts.BlockCache.Close()
 	if s.stopCh != nil {
 		close(s.stopCh)
 	}
@@ -24,6 +24,7 @@ import (
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
 	"github.com/apache/skywalking-banyandb/banyand/tsdb"
 	"github.com/apache/skywalking-banyandb/banyand/tsdb/index"
+	"github.com/apache/skywalking-banyandb/pkg/intern"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 	"github.com/apache/skywalking-banyandb/pkg/partition"
 	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
@@ -33,12 +34,15 @@ import (
 // a chunk is 1MB
 const chunkSize = 1 << 20
 
-var _ schema.Resource = (*stream)(nil)
+var (
+	_ schema.Resource       = (*stream)(nil)
+	_ schema.IndexRebuilder = (*stream)(nil)
+)
 
 type stream struct {
 	name     string
 	group    string
-	shardNum uint32
+	sharding partition.Sharding
 	l        *logger.Logger
 	// schema is the reference to the spec of the stream
 	schema *databasev1.Stream
@@ -48,6 +52,8 @@ type stream struct {
 	entityLocator          partition.EntityLocator
 	indexRules             []*databasev1.IndexRule
 	indexWriter            *index.Writer
+	propertyTags           pbv1.PropertyTagsGetter
+	interning              *intern.Table
 }
 
 func (s *stream) GetMetadata() *commonv1.Metadata {
@@ -70,6 +76,28 @@ func (s *stream) Close() error {
 	return s.indexWriter.Close()
 }
 
+func (s *stream) Flush(ctx context.Context) error {
+	return s.indexWriter.Flush(ctx)
+}
+
+func (s *stream) RebuildIndex(ctx context.Context, rules []*databasev1.IndexRule, opts schema.IndexRebuildOptions) (int, error) {
+	return index.Rebuild(context.WithValue(ctx, logger.ContextKey, s.l), s, index.RebuildOptions{
+		WriterOptions: index.WriterOptions{
+			DB:                s.db,
+			ShardNum:          s.sharding.ShardNum,
+			Families:          s.schema.GetTagFamilies(),
+			IndexRules:        rules,
+			EnableGlobalIndex: true,
+		},
+		Scope:       tsdb.Entry(s.name),
+		TimeRange:   opts.TimeRange,
+		Throttle:    opts.Throttle,
+		EntityLen:   len(s.schema.GetEntity().GetTagNames()),
+		Progress:    opts.Progress,
+		Incremental: opts.Incremental,
+	})
+}
+
 func (s *stream) parseSpec() {
 	s.name, s.group = s.schema.GetMetadata().GetName(), s.schema.GetMetadata().GetGroup()
 	s.entityLocator = partition.NewEntityLocator(s.schema.GetTagFamilies(), s.schema.GetEntity())
@@ -77,16 +105,20 @@ func (s *stream) parseSpec() {
 }
 
 type streamSpec struct {
-	schema     *databasev1.Stream
-	indexRules []*databasev1.IndexRule
+	schema       *databasev1.Stream
+	indexRules   []*databasev1.IndexRule
+	propertyTags pbv1.PropertyTagsGetter
+	interning    *intern.Table
 }
 
-func openStream(shardNum uint32, db tsdb.Supplier, spec streamSpec, l *logger.Logger) (*stream, error) {
+func openStream(sharding partition.Sharding, db tsdb.Supplier, spec streamSpec, l *logger.Logger) (*stream, error) {
 	sm := &stream{
-		shardNum:   shardNum,
-		schema:     spec.schema,
-		indexRules: spec.indexRules,
-		l:          l,
+		sharding:     sharding,
+		schema:       spec.schema,
+		indexRules:   spec.indexRules,
+		propertyTags: spec.propertyTags,
+		interning:    spec.interning,
+		l:            l,
 	}
 	sm.parseSpec()
 	ctx := context.WithValue(context.Background(), logger.ContextKey, l)
@@ -94,7 +126,7 @@ func openStream(shardNum uint32, db tsdb.Supplier, spec streamSpec, l *logger.Lo
 	sm.db = db
 	sm.indexWriter = index.NewWriter(ctx, index.WriterOptions{
 		DB:                db,
-		ShardNum:          shardNum,
+		ShardNum:          sharding.ShardNum,
 		Families:          spec.schema.TagFamilies,
 		IndexRules:        spec.indexRules,
 		EnableGlobalIndex: true,
@@ -18,6 +18,7 @@
 package stream
 
 import (
+	"context"
 	"io"
 
 	"github.com/pkg/errors"
@@ -28,8 +29,8 @@ import (
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
 	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
 	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/banyand/kv"
 	"github.com/apache/skywalking-banyandb/banyand/tsdb"
-	"github.com/apache/skywalking-banyandb/pkg/partition"
 )
 
 var ErrTagFamilyNotExist = errors.New("tag family doesn't exist")
@@ -45,6 +46,8 @@ type Stream interface {
 	Shard(id common.ShardID) (tsdb.Shard, error)
 	ParseTagFamily(family string, item tsdb.Item) (*modelv1.TagFamily, error)
 	ParseElementID(item tsdb.Item) (string, error)
+	// Flush waits for the elements written before to be indexed.
+	Flush(ctx context.Context) error
 }
 
 var _ Stream = (*stream)(nil)
@@ -66,15 +69,19 @@ func (s *stream) Shards(entity tsdb.Entity) ([]tsdb.Shard, error) {
 			return wrap(db.Shards()), nil
 		}
 	}
-	shardID, err := partition.ShardID(entity.Prepend(tsdb.Entry(s.name)).Marshal(), s.shardNum)
+	shardIDs, err := s.sharding.ShardIDs(entity.Prepend(tsdb.Entry(s.name)).Marshal())
 	if err != nil {
 		return nil, err
 	}
-	shard, err := db.Shard(common.ShardID(shardID))
-	if err != nil {
-		return nil, err
+	shards := make([]tsdb.Shard, 0, len(shardIDs))
+	for _, id := range shardIDs {
+		shard, errShard := db.Shard(common.ShardID(id))
+		if errShard != nil {
+			return nil, errShard
+		}
+		shards = append(shards, tsdb.NewScopedShard(tsdb.Entry(s.name), shard))
 	}
-	return []tsdb.Shard{tsdb.NewScopedShard(tsdb.Entry(s.name), shard)}, nil
+	return shards, nil
 }
 
 func (s *stream) Shard(id common.ShardID) (tsdb.Shard, error) {
@@ -86,7 +93,20 @@ func (s *stream) Shard(id common.ShardID) (tsdb.Shard, error) {
 }
 
 func (s *stream) ParseTagFamily(family string, item tsdb.Item) (*modelv1.TagFamily, error) {
+	var tagSpec []*databasev1.TagSpec
+	for _, tf := range s.schema.GetTagFamilies() {
+		if tf.GetName() == family {
+			tagSpec = tf.GetTags()
+		}
+	}
+	if tagSpec == nil {
+		return nil, ErrTagFamilyNotExist
+	}
 	familyRawBytes, err := item.Family(tsdb.Hash([]byte(family)))
+	if errors.Is(err, kv.ErrKeyNotFound) {
+		// the tag family is appended to the schema after the item is written
+		return &modelv1.TagFamily{Name: family}, nil
+	}
 	if err != nil {
 		return nil, errors.Wrapf(err, "parse family %s", family)
 	}
@@ -96,15 +116,6 @@ func (s *stream) ParseTagFamily(family string, item tsdb.Item) (*modelv1.TagFami
 		return nil, err
 	}
 	tags := make([]*modelv1.Tag, len(tagFamily.GetTags()))
-	var tagSpec []*databasev1.TagSpec
-	for _, tf := range s.schema.GetTagFamilies() {
-		if tf.GetName() == family {
-			tagSpec = tf.GetTags()
-		}
-	}
-	if tagSpec == nil {
-		return nil, ErrTagFamilyNotExist
-	}
 	for i, tag := range tagFamily.GetTags() {
 		tags[i] = &modelv1.Tag{
 			Key: tagSpec[i].GetName(),
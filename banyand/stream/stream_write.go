@@ -26,12 +26,14 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 
 	"github.com/apache/skywalking-banyandb/api/common"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
 	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
 	"github.com/apache/skywalking-banyandb/banyand/tsdb"
 	"github.com/apache/skywalking-banyandb/banyand/tsdb/index"
 	"github.com/apache/skywalking-banyandb/pkg/bus"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+	"github.com/apache/skywalking-banyandb/pkg/profiling"
 	"github.com/apache/skywalking-banyandb/pkg/timestamp"
 )
 
@@ -52,7 +54,7 @@ func init() {
 }
 
 func (s *stream) Write(value *streamv1.ElementValue) error {
-	entity, shardID, err := s.entityLocator.Locate(s.name, value.GetTagFamilies(), s.shardNum)
+	entity, shardID, err := s.entityLocator.Locate(s.interning, s.name, value.GetTagFamilies(), s.sharding, value.GetTimestamp().AsTime())
 	if err != nil {
 		return err
 	}
@@ -81,7 +83,20 @@ func (s *stream) write(shardID common.ShardID, seriesHashKey []byte, value *stre
 	if fLen > len(sm.TagFamilies) {
 		return errors.Wrap(ErrMalformedElement, "tag family number is more than expected")
 	}
-	shard, err := s.db.SupplyTSDB().Shard(shardID)
+	pbv1.InternTagFamilies(s.interning, value.GetTagFamilies())
+	tagFamilies, err := pbv1.EnrichTagFamilies(s.group, sm.GetTagFamilies(), sm.GetEnrichments(), value.GetTagFamilies(), s.propertyTags)
+	if err != nil {
+		return err
+	}
+	value.TagFamilies = tagFamilies
+	// the liaison generates the UUID once for all the replicas, so it's kept as is
+	if sm.GetElementIdPolicy().GetSource() != databasev1.ElementIDPolicy_SOURCE_UUID || value.GetElementId() == "" {
+		if value.ElementId, err = pbv1.ElementID(sm.GetElementIdPolicy(), sm.GetTagFamilies(), tagFamilies, value.GetElementId()); err != nil {
+			return err
+		}
+	}
+	db := s.db.SupplyTSDB()
+	shard, err := db.Shard(shardID)
 	if err != nil {
 		return err
 	}
@@ -145,6 +160,7 @@ func (s *stream) write(shardID common.ShardID, seriesHashKey []byte, value *stre
 		Cb:          cb,
 	}
 	s.indexWriter.Write(m)
+	db.ObserveWrite(s.name, t)
 	return err
 }
 
@@ -161,12 +177,23 @@ func setUpWriteCallback(l *logger.Logger, schemaRepo *schemaRepo) *writeCallback
 	return wcb
 }
 
+// Rev writes the batch of the writes coalesced by the liaison, or a single write.
 func (w *writeCallback) Rev(message bus.Message) (resp bus.Message) {
-	writeEvent, ok := message.Data().(*streamv1.InternalWriteRequest)
-	if !ok {
+	defer profiling.Label(profiling.LabelModule, "stream")()
+	switch d := message.Data().(type) {
+	case *streamv1.InternalWriteBatch:
+		for _, writeEvent := range d.GetRequests() {
+			w.write(writeEvent)
+		}
+	case *streamv1.InternalWriteRequest:
+		w.write(d)
+	default:
 		w.l.Warn().Msg("invalid event data type")
-		return
 	}
+	return
+}
+
+func (w *writeCallback) write(writeEvent *streamv1.InternalWriteRequest) {
 	stm, ok := w.schemaRepo.loadStream(writeEvent.GetRequest().GetMetadata())
 	if !ok {
 		w.l.Warn().Msg("cannot find stream definition")
@@ -176,5 +203,4 @@ func (w *writeCallback) Rev(message bus.Message) (resp bus.Message) {
 	if err != nil {
 		w.l.Error().Err(err).Msg("fail to write entity")
 	}
-	return
 }
@@ -30,17 +30,21 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/multierr"
 
 	"github.com/apache/skywalking-banyandb/api/common"
 	"github.com/apache/skywalking-banyandb/banyand/kv"
 	"github.com/apache/skywalking-banyandb/banyand/observability"
 	"github.com/apache/skywalking-banyandb/banyand/tsdb/bucket"
+	"github.com/apache/skywalking-banyandb/pkg/cache"
+	"github.com/apache/skywalking-banyandb/pkg/convert"
 	"github.com/apache/skywalking-banyandb/pkg/encoding"
 	"github.com/apache/skywalking-banyandb/pkg/index"
 	"github.com/apache/skywalking-banyandb/pkg/index/inverted"
 	"github.com/apache/skywalking-banyandb/pkg/index/lsm"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/throttle"
 	"github.com/apache/skywalking-banyandb/pkg/timestamp"
 )
 
@@ -53,7 +57,11 @@ const (
 	defaultEnqueueTimeout = 500 * time.Millisecond
 )
 
-var ErrBlockClosingInterrupted = errors.New("interrupt to close the block")
+var (
+	ErrBlockClosingInterrupted = errors.New("interrupt to close the block")
+
+	lastCacheID atomic.Uint64
+)
 
 type block struct {
 	path       string
@@ -75,21 +83,42 @@ type block struct {
 	clock         timestamp.Clock
 	timestamp.TimeRange
 	bucket.Reporter
-	segID          uint16
-	blockID        uint16
+	segID   uint16
+	blockID uint16
+	// coveredIDs are the ids of the intervals spanned by the block, which are more than one if the block is merged
+	coveredIDs     []uint16
 	segSuffix      string
 	encodingMethod EncodingMethod
+	throttle       *throttle.Throttle
+	durability     kv.Durability
+	cardinality    *index.Cardinality
+	ingest         *ingestMeter
+	cache          *cache.Cache
+	// cacheID identifies the cached values of the block, which are invalidated by changing it
+	cacheID     *atomic.Uint64
+	cacheHits   prometheus.Counter
+	cacheMisses prometheus.Counter
+	// lastWrite is the time in nanoseconds of the last write, or of the opening if there is none
+	lastWrite *atomic.Int64
+	// buffered is the number of the elements written since the block is opened, which are flushed once it's closed
+	buffered        *atomic.Int64
+	maxBuffered     int64
+	flushing        *atomic.Bool
+	flushes         *prometheus.CounterVec
+	flushedElements *prometheus.CounterVec
+	memFlushes      prometheus.Counter
 }
 
 type blockOpts struct {
-	segID     uint16
-	segSuffix string
-	blockSize IntervalRule
-	timeRange timestamp.TimeRange
-	suffix    string
-	path      string
-	queue     bucket.Queue
-	scheduler *timestamp.Scheduler
+	segID      uint16
+	segSuffix  string
+	blockSize  IntervalRule
+	timeRange  timestamp.TimeRange
+	suffix     string
+	coveredIDs []uint16
+	path       string
+	queue      bucket.Queue
+	scheduler  *timestamp.Scheduler
 }
 
 func newBlock(ctx context.Context, opts blockOpts) (b *block, err error) {
@@ -100,17 +129,22 @@ func newBlock(ctx context.Context, opts blockOpts) (b *block, err error) {
 	id := GenerateInternalID(opts.blockSize.Unit, suffixInteger)
 	clock, _ := timestamp.GetClock(ctx)
 	b = &block{
-		segID:     opts.segID,
-		segSuffix: opts.segSuffix,
-		suffix:    opts.suffix,
-		blockID:   id,
-		path:      opts.path,
-		TimeRange: opts.timeRange,
-		clock:     clock,
-		ref:       &atomic.Int32{},
-		closed:    &atomic.Bool{},
-		deleted:   &atomic.Bool{},
-		queue:     opts.queue,
+		segID:      opts.segID,
+		segSuffix:  opts.segSuffix,
+		suffix:     opts.suffix,
+		blockID:    id,
+		coveredIDs: opts.coveredIDs,
+		path:       opts.path,
+		TimeRange:  opts.timeRange,
+		clock:      clock,
+		ref:        &atomic.Int32{},
+		closed:     &atomic.Bool{},
+		deleted:    &atomic.Bool{},
+		queue:      opts.queue,
+		cacheID:    &atomic.Uint64{},
+		lastWrite:  &atomic.Int64{},
+		buffered:   &atomic.Int64{},
+		flushing:   &atomic.Bool{},
 	}
 	b.l = logger.Fetch(ctx, b.String())
 	b.Reporter = bucket.NewTimeBasedReporter(b.String(), opts.timeRange, clock, opts.scheduler)
@@ -120,6 +154,14 @@ func newBlock(ctx context.Context, opts blockOpts) (b *block, err error) {
 	if position != nil {
 		b.position = position.(common.Position)
 	}
+	b.flushes = curryPosition(blockFlushes, b.position)
+	b.flushedElements = curryPosition(blockFlushedElements, b.position)
+	b.memFlushes = curryPosition(memtableFlushes, b.position).WithLabelValues()
+	if b.cache != nil {
+		b.invalidateCache()
+		b.cacheHits = curryPosition(blockCacheHits, b.position).WithLabelValues()
+		b.cacheMisses = curryPosition(blockCacheMisses, b.position).WithLabelValues()
+	}
 
 	return b, nil
 }
@@ -133,15 +175,25 @@ func (b *block) options(ctx context.Context) {
 	if options.EncodingMethod.EncoderPool == nil {
 		options.EncodingMethod.EncoderPool = encoding.NewPlainEncoderPool("tsdb", 0)
 	}
-	if options.EncodingMethod.EncoderPool == nil {
+	if options.EncodingMethod.DecoderPool == nil {
 		options.EncodingMethod.DecoderPool = encoding.NewPlainDecoderPool("tsdb", 0)
 	}
 	b.encodingMethod = options.EncodingMethod
+	b.throttle = options.BackgroundThrottle
+	b.durability = options.Durability
+	b.cache = options.BlockCache
+	if c := ctx.Value(cardinalityKey); c != nil {
+		b.cardinality = c.(*index.Cardinality)
+	}
+	if m, ok := ctx.Value(ingestMeterKey).(*ingestMeter); ok {
+		b.ingest = m
+	}
 	if options.BlockMemSize < 1 {
 		b.memSize = defaultMainMemorySize
 	} else {
 		b.memSize = options.BlockMemSize
 	}
+	b.maxBuffered = options.MaxBufferedElements
 	b.lsmMemSize = b.memSize / 8
 	if b.lsmMemSize < defaultKVMemorySize {
 		b.lsmMemSize = defaultKVMemorySize
@@ -161,33 +213,45 @@ func (b *block) openSafely() (err error) {
 }
 
 func (b *block) open() (err error) {
-	if b.store, err = kv.OpenTimeSeriesStore(
+	if b.store, b.invertedIndex, b.lsmIndex, err = b.openStores(b.path); err != nil {
+		return err
+	}
+	b.closableLst = append(b.closableLst, b.store, b.invertedIndex, b.lsmIndex)
+	b.ref.Store(0)
+	b.buffered.Store(0)
+	b.lastWrite.Store(b.clock.Now().UnixNano())
+	b.closed.Store(false)
+	return nil
+}
+
+// openStores opens the data store and the indices of a block located at root.
+func (b *block) openStores(root string) (store kv.TimeSeriesStore, invertedIndex index.Store, lsmIndex index.Store, err error) {
+	if store, err = kv.OpenTimeSeriesStore(
 		0,
-		path.Join(b.path, componentMain),
+		path.Join(root, componentMain),
 		kv.TSSWithEncoding(b.encodingMethod.EncoderPool, b.encodingMethod.DecoderPool),
 		kv.TSSWithLogger(b.l.Named(componentMain)),
 		kv.TSSWithMemTableSize(b.memSize),
+		kv.TSSWithBackgroundThrottle(b.throttle),
+		kv.TSSWithDurability(b.durability),
+		kv.TSSWithFlushCallback(b.memFlushes.Inc),
 	); err != nil {
-		return err
+		return nil, nil, nil, err
 	}
-	b.closableLst = append(b.closableLst, b.store)
-	if b.invertedIndex, err = inverted.NewStore(inverted.StoreOpts{
-		Path:   path.Join(b.path, componentSecondInvertedIdx),
+	if invertedIndex, err = inverted.NewStore(inverted.StoreOpts{
+		Path:   path.Join(root, componentSecondInvertedIdx),
 		Logger: b.l.Named(componentSecondInvertedIdx),
 	}); err != nil {
-		return err
+		return nil, nil, nil, multierr.Append(err, store.Close())
 	}
-	if b.lsmIndex, err = lsm.NewStore(lsm.StoreOpts{
-		Path:         path.Join(b.path, componentSecondLSMIdx),
+	if lsmIndex, err = lsm.NewStore(lsm.StoreOpts{
+		Path:         path.Join(root, componentSecondLSMIdx),
 		Logger:       b.l.Named(componentSecondLSMIdx),
 		MemTableSize: b.lsmMemSize,
 	}); err != nil {
-		return err
+		return nil, nil, nil, multierr.Combine(err, store.Close(), invertedIndex.Close())
 	}
-	b.closableLst = append(b.closableLst, b.invertedIndex, b.lsmIndex)
-	b.ref.Store(0)
-	b.closed.Store(false)
-	return nil
+	return store, invertedIndex, lsmIndex, nil
 }
 
 func (b *block) delegate(ctx context.Context) (BlockDelegate, error) {
@@ -269,6 +333,24 @@ func (b *block) waitDone(stopped *atomic.Bool) <-chan struct{} {
 func (b *block) close(ctx context.Context) (err error) {
 	b.lock.Lock()
 	defer b.lock.Unlock()
+	return b.closeLocked(ctx)
+}
+
+// rollover closes the block and removes it from the queue of the open blocks, so that it's reopened on the next access.
+func (b *block) rollover(ctx context.Context) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if err := b.closeLocked(ctx); err != nil {
+		return err
+	}
+	b.queue.Remove(BlockID{
+		SegID:   b.segID,
+		BlockID: b.blockID,
+	})
+	return nil
+}
+
+func (b *block) closeLocked(ctx context.Context) (err error) {
 	if b.closed.Load() {
 		return nil
 	}
@@ -281,9 +363,14 @@ func (b *block) close(ctx context.Context) (err error) {
 	case <-ch:
 	}
 	b.closed.Store(true)
+	if f, ok := injectedFault(FaultDelayedClose); ok {
+		time.Sleep(f.Delay)
+	}
 	for _, closer := range b.closableLst {
 		err = multierr.Append(err, closer.Close())
 	}
+	// the stores are recreated once the block is reopened
+	b.closableLst = b.closableLst[:0]
 	return err
 }
 
@@ -292,10 +379,42 @@ func (b *block) delete(ctx context.Context) error {
 		return nil
 	}
 	b.deleted.Store(true)
+	b.invalidateCache()
 	b.close(ctx)
 	return os.RemoveAll(b.path)
 }
 
+// invalidateCache drops the cached values of the block. They're evicted by the cache later.
+func (b *block) invalidateCache() {
+	if b.cache != nil {
+		b.cacheID.Store(lastCacheID.Add(1))
+	}
+}
+
+func (b *block) cacheKey(key []byte, ts uint64) []byte {
+	k := make([]byte, 0, 16+len(key))
+	k = append(k, convert.Uint64ToBytes(b.cacheID.Load())...)
+	k = append(k, convert.Uint64ToBytes(ts)...)
+	return append(k, key...)
+}
+
+// owns tells whether the block holds the data of the block id, which is one of the sources if the block is merged.
+func (b *block) owns(blockID uint16) bool {
+	if b.blockID == blockID {
+		return true
+	}
+	for _, id := range b.coveredIDs {
+		if id == blockID {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *block) lastWriteTime() time.Time {
+	return time.Unix(0, b.lastWrite.Load())
+}
+
 func (b *block) Closed() bool {
 	return b.closed.Load()
 }
@@ -322,6 +441,7 @@ type BlockDelegate interface {
 	writeLSMIndex(fields []index.Field, id common.ItemID) error
 	writeInvertedIndex(fields []index.Field, id common.ItemID) error
 	dataReader() kv.TimeSeriesReader
+	backgroundDataReader() kv.TimeSeriesReader
 	lsmIndexReader() index.Searcher
 	invertedIndexReader() index.Searcher
 	primaryIndexReader() index.FieldIterable
@@ -337,7 +457,17 @@ type bDelegate struct {
 }
 
 func (d *bDelegate) dataReader() kv.TimeSeriesReader {
-	return d.delegate.store
+	if d.delegate.cache == nil {
+		return d.delegate.store
+	}
+	return &cachedReader{TimeSeriesReader: d.delegate.store, b: d.delegate}
+}
+
+func (d *bDelegate) backgroundDataReader() kv.TimeSeriesReader {
+	if d.delegate.throttle == nil {
+		return d.dataReader()
+	}
+	return &throttledReader{TimeSeriesReader: d.dataReader(), t: d.delegate.throttle}
 }
 
 func (d *bDelegate) lsmIndexReader() index.Searcher {
@@ -361,18 +491,40 @@ func (d *bDelegate) identity() (segID uint16, blockID uint16) {
 }
 
 func (d *bDelegate) write(key []byte, val []byte, ts time.Time) error {
-	return d.delegate.store.Put(key, val, uint64(ts.UnixNano()))
+	if f, ok := injectedFault(FaultPartialWrite); ok {
+		_ = d.delegate.store.Put(key, val[:len(val)/2], uint64(ts.UnixNano()))
+		return f.err()
+	}
+	if err := d.delegate.store.Put(key, val, uint64(ts.UnixNano())); err != nil {
+		return err
+	}
+	if f, ok := injectedFault(FaultSync); ok {
+		return f.err()
+	}
+	d.delegate.lastWrite.Store(d.delegate.clock.Now().UnixNano())
+	if d.delegate.cache != nil {
+		d.delegate.cache.Del(d.delegate.cacheKey(key, uint64(ts.UnixNano())))
+	}
+	return nil
 }
 
+// writePrimaryIndex is called once per item, which counts the item to the ingest rate.
 func (d *bDelegate) writePrimaryIndex(field index.Field, id common.ItemID) error {
-	return d.delegate.lsmIndex.Write([]index.Field{field}, id)
+	if err := d.delegate.lsmIndex.Write([]index.Field{field}, id); err != nil {
+		return err
+	}
+	d.delegate.ingest.mark()
+	d.delegate.buffer()
+	return nil
 }
 
 func (d *bDelegate) writeLSMIndex(fields []index.Field, id common.ItemID) error {
+	d.delegate.cardinality.Observe(fields)
 	return d.delegate.lsmIndex.Write(fields, id)
 }
 
 func (d *bDelegate) writeInvertedIndex(fields []index.Field, id common.ItemID) error {
+	d.delegate.cardinality.Observe(fields)
 	return d.delegate.invertedIndex.Write(fields, id)
 }
 
@@ -388,3 +540,61 @@ func (d *bDelegate) Close() error {
 	d.delegate.Done()
 	return nil
 }
+
+// cachedReader looks up the values in the block cache before decoding them from the store.
+type cachedReader struct {
+	kv.TimeSeriesReader
+	b *block
+}
+
+func (r *cachedReader) Get(key []byte, ts uint64) ([]byte, error) {
+	k := r.b.cacheKey(key, ts)
+	if val, ok := r.b.cache.Get(k); ok {
+		r.b.cacheHits.Inc()
+		return val, nil
+	}
+	r.b.cacheMisses.Inc()
+	val, err := r.TimeSeriesReader.Get(key, ts)
+	if err != nil {
+		return nil, err
+	}
+	if val != nil {
+		r.b.cache.Set(k, val)
+	}
+	return val, nil
+}
+
+type backgroundIOKey struct{}
+
+// WithBackgroundIO marks the reads of a background job, e.g. a backfill of the indices,
+// whose values read through the series spans are limited by DatabaseOpts.BackgroundThrottle.
+func WithBackgroundIO(ctx context.Context) context.Context {
+	return context.WithValue(ctx, backgroundIOKey{}, true)
+}
+
+func isBackgroundIO(ctx context.Context) bool {
+	background, _ := ctx.Value(backgroundIOKey{}).(bool)
+	return background
+}
+
+// throttledReader limits the values read by a background job.
+type throttledReader struct {
+	kv.TimeSeriesReader
+	t *throttle.Throttle
+}
+
+func (r *throttledReader) Get(key []byte, ts uint64) ([]byte, error) {
+	val, err := r.TimeSeriesReader.Get(key, ts)
+	r.t.Wait(len(val))
+	return val, err
+}
+
+func (r *throttledReader) GetAll(key []byte) ([][]byte, error) {
+	vals, err := r.TimeSeriesReader.GetAll(key)
+	size := 0
+	for _, v := range vals {
+		size += len(v)
+	}
+	r.t.Wait(size)
+	return vals, err
+}
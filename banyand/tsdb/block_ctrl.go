@@ -20,6 +20,7 @@ package tsdb
 import (
 	"context"
 	"sort"
+	"strconv"
 	"sync"
 	"time"
 
@@ -109,6 +110,8 @@ func (bc *blockController) OnMove(prev bucket.Reporter, next bucket.Reporter) {
 	if prev != nil {
 		event.Stringer("prev", prev)
 		b := prev.(*block)
+		// the sealed block might have cached the values overwritten by the writes racing the reads
+		b.invalidateCache()
 		ctx, cancel := context.WithTimeout(context.Background(), defaultEnqueueTimeout)
 		defer cancel()
 		if err := bc.blockQueue.Push(ctx, BlockID{
@@ -127,7 +130,9 @@ func (bc *blockController) OnMove(prev bucket.Reporter, next bucket.Reporter) {
 	event.Msg("move to the next block")
 }
 
+// Format formats the time in the time zone of the segment, whose start time is parsed in it.
 func (bc *blockController) Format(tm time.Time) string {
+	tm = tm.In(bc.segTimeRange.Start.Location())
 	switch bc.blockSize.Unit {
 	case HOUR:
 		return tm.Format(blockHourFormat)
@@ -140,9 +145,9 @@ func (bc *blockController) Format(tm time.Time) string {
 func (bc *blockController) Parse(value string) (time.Time, error) {
 	switch bc.blockSize.Unit {
 	case HOUR:
-		return time.ParseInLocation(blockHourFormat, value, time.Local)
+		return time.ParseInLocation(blockHourFormat, value, bc.segTimeRange.Start.Location())
 	case DAY:
-		return time.ParseInLocation(blockDayFormat, value, time.Local)
+		return time.ParseInLocation(blockDayFormat, value, bc.segTimeRange.Start.Location())
 	}
 	panic("invalid interval unit")
 }
@@ -175,7 +180,7 @@ func (bc *blockController) get(ctx context.Context, blockID uint16) (BlockDelega
 
 func (bc *blockController) getBlock(blockID uint16) *block {
 	bb := bc.search(func(b *block) bool {
-		return b.blockID == blockID
+		return b.owns(blockID)
 	})
 	if len(bb) > 0 {
 		return bb[0]
@@ -224,14 +229,14 @@ func (bc *blockController) startTime(suffix string) (time.Time, error) {
 		return time.Date(startTime.Year(), startTime.Month(),
 			startTime.Day(), t.Hour(), 0, 0, 0, startTime.Location()), nil
 	case DAY:
-		return time.Date(startTime.Year(), startTime.Month(),
+		return time.Date(startTime.Year(), t.Month(),
 			t.Day(), t.Hour(), 0, 0, 0, startTime.Location()), nil
 	}
 	panic("invalid interval unit")
 }
 
 func (bc *blockController) open() error {
-	return WalkDir(
+	if err := WalkDir(
 		bc.location,
 		blockPathPrefix,
 		func(suffix, absolutePath string) error {
@@ -239,7 +244,10 @@ func (bc *blockController) open() error {
 			defer bc.Unlock()
 			_, err := bc.load(suffix, absolutePath)
 			return err
-		})
+		}); err != nil {
+		return err
+	}
+	return bc.recoverMerges()
 }
 
 func (bc *blockController) create(startTime time.Time) (*block, error) {
@@ -276,7 +284,21 @@ func (bc *blockController) load(suffix, path string) (b *block, err error) {
 	if err != nil {
 		return nil, err
 	}
-	endTime := bc.blockSize.NextTime(starTime)
+	span, err := blockSpan(path)
+	if err != nil {
+		return nil, err
+	}
+	// a merged block spans the intervals of its sources, whose ids are resolved to it
+	endTime := starTime
+	var coveredIDs []uint16
+	for i := 0; i < span && endTime.Before(bc.segTimeRange.End); i++ {
+		id, errID := strconv.Atoi(bc.Format(endTime))
+		if errID != nil {
+			return nil, errID
+		}
+		coveredIDs = append(coveredIDs, GenerateInternalID(bc.blockSize.Unit, id))
+		endTime = bc.blockSize.NextTime(endTime)
+	}
 	if endTime.After(bc.segTimeRange.End) {
 		endTime = bc.segTimeRange.End
 	}
@@ -286,14 +308,15 @@ func (bc *blockController) load(suffix, path string) (b *block, err error) {
 			return p
 		}),
 		blockOpts{
-			segID:     bc.segID,
-			segSuffix: bc.segSuffix,
-			path:      path,
-			timeRange: timestamp.NewSectionTimeRange(starTime, endTime),
-			suffix:    suffix,
-			blockSize: bc.blockSize,
-			queue:     bc.blockQueue,
-			scheduler: bc.scheduler,
+			segID:      bc.segID,
+			segSuffix:  bc.segSuffix,
+			path:       path,
+			timeRange:  timestamp.NewSectionTimeRange(starTime, endTime),
+			suffix:     suffix,
+			coveredIDs: coveredIDs,
+			blockSize:  bc.blockSize,
+			queue:      bc.blockQueue,
+			scheduler:  bc.scheduler,
 		}); err != nil {
 		return nil, err
 	}
@@ -346,3 +369,13 @@ func (bc *blockController) removeBlock(blockID uint16) {
 		}
 	}
 }
+
+// removeBlockOf removes the block, which might share the id with a merged one.
+func (bc *blockController) removeBlockOf(target *block) {
+	for i, b := range bc.lst {
+		if b == target {
+			bc.lst = append(bc.lst[:i], bc.lst[i+1:]...)
+			break
+		}
+	}
+}
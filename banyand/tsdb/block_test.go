@@ -0,0 +1,112 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tsdb
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/banyand/kv"
+	"github.com/apache/skywalking-banyandb/pkg/cache"
+	"github.com/apache/skywalking-banyandb/pkg/throttle"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+type countingReader struct {
+	kv.TimeSeriesReader
+	vals  map[uint64][]byte
+	reads int
+}
+
+func (r *countingReader) Get(_ []byte, ts uint64) ([]byte, error) {
+	r.reads++
+	return r.vals[ts], nil
+}
+
+func TestBlockCache(t *testing.T) {
+	c, err := cache.New(cache.PolicyLRU, 1<<10)
+	require.NoError(t, err)
+	b := &block{
+		cache:       c,
+		cacheID:     &atomic.Uint64{},
+		cacheHits:   prometheus.NewCounter(prometheus.CounterOpts{Name: "hits"}),
+		cacheMisses: prometheus.NewCounter(prometheus.CounterOpts{Name: "misses"}),
+	}
+	b.invalidateCache()
+	store := &countingReader{vals: map[uint64][]byte{1: []byte("v1")}}
+	r := &cachedReader{TimeSeriesReader: store, b: b}
+
+	for i := 0; i < 3; i++ {
+		val, err := r.Get([]byte("key"), 1)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("v1"), val)
+	}
+	assert.Equal(t, 1, store.reads)
+	assert.Equal(t, 2.0, testutil.ToFloat64(b.cacheHits))
+	assert.Equal(t, 1.0, testutil.ToFloat64(b.cacheMisses))
+
+	_, err = r.Get([]byte("key"), 2)
+	require.NoError(t, err)
+	_, err = r.Get([]byte("key"), 2)
+	require.NoError(t, err)
+	assert.Equal(t, 3, store.reads, "the absent values aren't cached")
+
+	b.invalidateCache()
+	_, err = r.Get([]byte("key"), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 4, store.reads, "the sealed or deleted block drops its cached values")
+}
+
+func TestBackgroundDataReader(t *testing.T) {
+	clock := timestamp.NewMockClock()
+	d := &bDelegate{delegate: &block{throttle: throttle.New(100).WithClock(clock)}}
+	span := &seriesSpan{background: isBackgroundIO(WithBackgroundIO(context.Background()))}
+	assert.IsType(t, &throttledReader{}, span.dataReader(d))
+	span = &seriesSpan{background: isBackgroundIO(context.Background())}
+	_, throttled := span.dataReader(d).(*throttledReader)
+	assert.False(t, throttled, "the foreground reads aren't throttled")
+
+	store := &countingReader{vals: map[uint64][]byte{1: make([]byte, 100)}}
+	r := &throttledReader{TimeSeriesReader: store, t: d.delegate.throttle}
+	start := clock.Now()
+	_, err := r.Get([]byte("key"), 1)
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), clock.Since(start), "the first read consumes the burst")
+	done := make(chan error)
+	go func() {
+		_, err := r.Get([]byte("key"), 1)
+		done <- err
+	}()
+	for waiting := true; waiting; {
+		select {
+		case err = <-done:
+			require.NoError(t, err)
+			waiting = false
+		default:
+			clock.Add(10 * time.Millisecond)
+		}
+	}
+	assert.GreaterOrEqual(t, clock.Since(start), time.Second, "the second read waits for the tokens")
+}
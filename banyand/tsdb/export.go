@@ -0,0 +1,171 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tsdb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+
+	"github.com/apache/skywalking-banyandb/pkg/throttle"
+)
+
+// exportDir keeps the snapshots of the blocks being exported, which are left behind by a crash and removed at the opening of the shard.
+const exportDir = "export"
+
+var (
+	ErrBlockNotFound  = errors.New("the block is not found")
+	ErrBlockNotSealed = errors.New("the block is not sealed")
+
+	exportSeq atomic.Uint64
+)
+
+// BlockExport is a snapshot of the files of a sealed block for copying them to another node or a backup.
+// The files are hard linked to the ones of the block, so the snapshot stays intact while the block is merged or removed by the retention.
+type BlockExport struct {
+	// Path is the directory of the snapshot
+	Path     string
+	Segment  string
+	Files    []ExportedFile
+	Manifest BlockManifest
+	// Throttle limits the reads of the files, which is the background throttle of the database
+	Throttle *throttle.Throttle
+	Shard    uint32
+}
+
+// ExportedFile is a file of the exported block, whose name is relative to the block directory.
+type ExportedFile struct {
+	Name string
+	// Checksum is the SHA-256 of the content in hex
+	Checksum string
+	Size     int64
+}
+
+// Release removes the snapshot, which should be called once the files are copied.
+func (e *BlockExport) Release() error {
+	return os.RemoveAll(e.Path)
+}
+
+func (s *shard) ExportBlock(segment, block string) (*BlockExport, error) {
+	now := s.clock.Now()
+	for _, seg := range s.segmentController.segments() {
+		if seg.suffix != segment {
+			continue
+		}
+		for _, b := range seg.blockController.blocks() {
+			if filepath.Base(b.path) != block {
+				continue
+			}
+			// the files of an open block are still being written
+			if !b.Closed() || b.End.After(now) {
+				return nil, errors.WithMessagef(ErrBlockNotSealed, "block %s of segment %s", block, segment)
+			}
+			e := &BlockExport{
+				Path:     filepath.Join(s.path, exportDir, fmt.Sprintf("%s-%s-%d", segment, block, exportSeq.Add(1))),
+				Segment:  segment,
+				Shard:    uint32(s.id),
+				Throttle: b.throttle,
+				Manifest: BlockManifest{
+					Name:   block,
+					Begin:  b.Start,
+					End:    b.End,
+					Sealed: true,
+				},
+			}
+			if err := e.snapshot(b.path); err != nil {
+				_ = e.Release()
+				return nil, errors.WithMessagef(err, "export block %s of segment %s", block, segment)
+			}
+			return e, nil
+		}
+	}
+	return nil, errors.WithMessagef(ErrBlockNotFound, "block %s of segment %s", block, segment)
+}
+
+// snapshot links the files of the block, and computes their checksums and the one of the block as the manifest does.
+func (e *BlockExport) snapshot(blockPath string) error {
+	digest := sha256.New()
+	err := filepath.WalkDir(blockPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(blockPath, p)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(e.Path, rel)
+		if d.IsDir() {
+			return os.MkdirAll(dst, 0o700)
+		}
+		if err = linkOrCopy(p, dst); err != nil {
+			return err
+		}
+		// the checksums are computed from the snapshot, which isn't changed by the block any more
+		f, err := os.Open(dst)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		fileDigest := sha256.New()
+		_, _ = io.WriteString(digest, rel)
+		size, err := io.Copy(io.MultiWriter(digest, fileDigest), throttle.NewReader(f, e.Throttle))
+		if err != nil {
+			return err
+		}
+		e.Files = append(e.Files, ExportedFile{
+			Name:     filepath.ToSlash(rel),
+			Size:     size,
+			Checksum: hex.EncodeToString(fileDigest.Sum(nil)),
+		})
+		e.Manifest.DiskBytes += size
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	e.Manifest.Checksum = hex.EncodeToString(digest.Sum(nil))
+	return nil
+}
+
+// linkOrCopy hard links src to dst, and copies it if the file system doesn't support the hard links.
+func linkOrCopy(src, dst string) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
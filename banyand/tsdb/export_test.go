@@ -0,0 +1,87 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tsdb
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/pkg/test"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+func TestExportBlock(t *testing.T) {
+	req := require.New(t)
+	tempDir, deferFunc := test.Space(req)
+	defer deferFunc()
+	clock := timestamp.NewMockClock()
+	clock.Set(time.Date(1970, 0o1, 0o1, 0, 0, 0, 0, time.Local))
+	ctx := timestamp.SetClock(context.Background(), clock)
+	db := openDatabase(ctx, req, tempDir)
+	defer db.Close()
+	s := db.Shards()[0]
+	req.Eventually(func() bool {
+		return len(s.State().Blocks) == 1
+	}, flags.EventuallyTimeout, time.Millisecond)
+	seg := s.(*shard).segmentController.segments()[0]
+	b := seg.blockController.blocks()[0]
+	d, err := b.delegate(ctx)
+	req.NoError(err)
+	req.NoError(d.write([]byte("key"), []byte("val"), b.Start.Add(time.Millisecond)))
+	req.NoError(d.Close())
+	name := filepath.Base(b.path)
+
+	_, err = s.ExportBlock(seg.suffix, name)
+	req.ErrorIs(err, ErrBlockNotSealed)
+	_, err = s.ExportBlock(seg.suffix, "block-unknown")
+	req.ErrorIs(err, ErrBlockNotFound)
+
+	clock.Add(3 * time.Hour)
+	ctxClose, cancel := context.WithTimeout(context.Background(), flags.EventuallyTimeout)
+	defer cancel()
+	req.NoError(b.rollover(ctxClose))
+	manifests, err := s.Manifests()
+	req.NoError(err)
+	e, err := s.ExportBlock(seg.suffix, name)
+	req.NoError(err)
+	assert.Equal(t, manifests[0].Blocks[0].Checksum, e.Manifest.Checksum, "the checksum of the export matches the manifest")
+	assert.Equal(t, manifests[0].Blocks[0].DiskBytes, e.Manifest.DiskBytes)
+	assert.Equal(t, seg.suffix, e.Segment)
+	req.NotEmpty(e.Files)
+	for _, f := range e.Files {
+		info, errStat := os.Stat(filepath.Join(e.Path, filepath.FromSlash(f.Name)))
+		req.NoError(errStat)
+		assert.Equal(t, f.Size, info.Size())
+		assert.Len(t, f.Checksum, 64)
+	}
+
+	// the snapshot outlives the block
+	req.NoError(b.delete(ctxClose))
+	_, err = os.Stat(filepath.Join(e.Path, e.Files[0].Name))
+	req.NoError(err)
+	req.NoError(e.Release())
+	_, err = os.Stat(e.Path)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,56 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tsdb
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInjectedFault is returned by the faults injected into the write path.
+var ErrInjectedFault = errors.New("injected fault")
+
+// FaultPoint is a point of the write/read path where the builds with the `fault` tag inject the faults.
+type FaultPoint string
+
+const (
+	// FaultSync fails the writes after putting them, as if syncing their write-ahead log failed.
+	FaultSync FaultPoint = "sync"
+	// FaultPartialWrite puts the first half of a value and fails the write.
+	FaultPartialWrite FaultPoint = "partial-write"
+	// FaultDelayedClose delays closing the stores of a block.
+	FaultDelayedClose FaultPoint = "delayed-close"
+)
+
+// Fault is the fault injected at a FaultPoint.
+type Fault struct {
+	// Err is returned by the failed operations. It's ErrInjectedFault if absent.
+	Err error
+	// Delay is how long the delayed operations wait.
+	Delay time.Duration
+	// Probability is the chance of injecting the fault into an operation, which is always injected if it's 0.
+	Probability float64
+}
+
+func (f Fault) err() error {
+	if f.Err != nil {
+		return f.Err
+	}
+	return ErrInjectedFault
+}
@@ -0,0 +1,48 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build fault
+
+package tsdb
+
+import (
+	"math/rand"
+	"sync"
+)
+
+var faults sync.Map
+
+// InjectFault injects the fault at the point until the returned function is called.
+func InjectFault(point FaultPoint, f Fault) (reset func()) {
+	faults.Store(point, f)
+	return func() {
+		faults.Delete(point)
+	}
+}
+
+// injectedFault returns the fault to inject at the point.
+func injectedFault(point FaultPoint) (Fault, bool) {
+	v, ok := faults.Load(point)
+	if !ok {
+		return Fault{}, false
+	}
+	f := v.(Fault)
+	if f.Probability > 0 && rand.Float64() >= f.Probability {
+		return Fault{}, false
+	}
+	return f, true
+}
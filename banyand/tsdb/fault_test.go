@@ -0,0 +1,80 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build fault
+
+package tsdb
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/banyand/kv"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+type recordingStore struct {
+	kv.TimeSeriesStore
+	vals [][]byte
+}
+
+func (s *recordingStore) Put(_, val []byte, _ uint64) error {
+	s.vals = append(s.vals, val)
+	return nil
+}
+
+func TestInjectWriteFaults(t *testing.T) {
+	store := &recordingStore{}
+	d := &bDelegate{delegate: &block{
+		store:     store,
+		clock:     timestamp.NewClock(),
+		lastWrite: &atomic.Int64{},
+	}}
+	val := []byte("value")
+
+	reset := InjectFault(FaultPartialWrite, Fault{})
+	assert.ErrorIs(t, d.write([]byte("key"), val, time.Now()), ErrInjectedFault)
+	assert.Equal(t, [][]byte{[]byte("va")}, store.vals)
+	reset()
+
+	store.vals = nil
+	reset = InjectFault(FaultSync, Fault{Err: context.DeadlineExceeded})
+	assert.ErrorIs(t, d.write([]byte("key"), val, time.Now()), context.DeadlineExceeded)
+	assert.Equal(t, [][]byte{val}, store.vals, "the value is put before syncing fails")
+	reset()
+
+	store.vals = nil
+	require.NoError(t, d.write([]byte("key"), val, time.Now()))
+	assert.Equal(t, [][]byte{val}, store.vals)
+}
+
+func TestInjectDelayedClose(t *testing.T) {
+	defer InjectFault(FaultDelayedClose, Fault{Delay: 100 * time.Millisecond})()
+	b := &block{
+		ref:    &atomic.Int32{},
+		closed: &atomic.Bool{},
+	}
+	start := time.Now()
+	require.NoError(t, b.close(context.Background()))
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	assert.True(t, b.Closed())
+}
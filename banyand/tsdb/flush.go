@@ -0,0 +1,99 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tsdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+const (
+	// how long the in-flight reads and writes of a block are waited for before flushing it
+	flushCloseTimeout = 5 * time.Second
+
+	flushReasonElements = "elements"
+	flushReasonInterval = "interval"
+)
+
+// flushTask flushes the open blocks buffering the writes once per the flush interval,
+// which bounds how long the written elements stay in the memory tables.
+type flushTask struct {
+	segment  *segmentController
+	interval time.Duration
+}
+
+func newFlushTask(segment *segmentController, interval time.Duration) *flushTask {
+	return &flushTask{
+		segment:  segment,
+		interval: interval,
+	}
+}
+
+func (ft *flushTask) expr() string {
+	return fmt.Sprintf("@every %s", ft.interval)
+}
+
+func (ft *flushTask) run(_ time.Time, l *logger.Logger) bool {
+	for _, seg := range ft.segment.segments() {
+		for _, b := range seg.blockController.blocks() {
+			if b.Closed() || b.buffered.Load() < 1 {
+				continue
+			}
+			if err := b.flush(flushReasonInterval); err != nil {
+				// the block is busy, it's tried again in the next round
+				l.Debug().Err(err).Stringer("block", b).Msg("failed to flush the block")
+			}
+		}
+	}
+	return true
+}
+
+// flush closes the block to flush the elements buffered by its memory tables, and it's reopened on the next access.
+// It does nothing if the block is being flushed.
+func (b *block) flush(reason string) error {
+	if !b.flushing.CompareAndSwap(false, true) {
+		return nil
+	}
+	defer b.flushing.Store(false)
+	buffered := b.buffered.Load()
+	ctx, cancel := context.WithTimeout(context.Background(), flushCloseTimeout)
+	defer cancel()
+	if err := b.rollover(ctx); err != nil {
+		return err
+	}
+	b.flushes.WithLabelValues(reason).Inc()
+	b.flushedElements.WithLabelValues(reason).Add(float64(buffered))
+	b.l.Debug().Str("reason", reason).Int64("elements", buffered).Msg("flushed the block")
+	return nil
+}
+
+// buffer counts an element written to the block, and flushes the block in the background
+// once the elements buffered reach the limit.
+func (b *block) buffer() {
+	if b.buffered.Add(1) < b.maxBuffered || b.maxBuffered < 1 || b.flushing.Load() {
+		return
+	}
+	go func() {
+		if err := b.flush(flushReasonElements); err != nil {
+			b.l.Debug().Err(err).Msg("failed to flush the block")
+		}
+	}()
+}
@@ -0,0 +1,67 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tsdb
+
+import (
+	"context"
+	"time"
+
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+const (
+	// how often the idle blocks are looked for
+	idleCheckInterval = "@every 1m"
+	// how long the in-flight reads and writes of an idle block are waited for
+	idleCloseTimeout = 5 * time.Second
+)
+
+// idleTask closes the open blocks which aren't written for the quiet period.
+// Closing a block flushes the buffered writes of its series and frees the memory tables,
+// and the block is reopened once it's accessed again.
+type idleTask struct {
+	segment *segmentController
+	timeout time.Duration
+}
+
+func newIdleTask(segment *segmentController, timeout time.Duration) *idleTask {
+	return &idleTask{
+		segment: segment,
+		timeout: timeout,
+	}
+}
+
+func (it *idleTask) run(now time.Time, l *logger.Logger) bool {
+	for _, seg := range it.segment.segments() {
+		for _, b := range seg.blockController.blocks() {
+			if b.Closed() || now.Sub(b.lastWriteTime()) < it.timeout {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), idleCloseTimeout)
+			err := b.rollover(ctx)
+			cancel()
+			if err != nil {
+				// the block is busy, it's tried again in the next round
+				l.Debug().Err(err).Stringer("block", b).Msg("failed to close the idle block")
+				continue
+			}
+			l.Info().Stringer("block", b).Dur("idle", now.Sub(b.lastWriteTime())).Msg("closed the idle block")
+		}
+	}
+	return true
+}
@@ -0,0 +1,172 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package index
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/banyand/tsdb"
+	"github.com/apache/skywalking-banyandb/pkg/index"
+	"github.com/apache/skywalking-banyandb/pkg/throttle"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+// Source supplies the stored items to rebuild the indices from, e.g. a stream or a measure.
+type Source interface {
+	Shards(entity tsdb.Entity) ([]tsdb.Shard, error)
+	ParseTagFamily(family string, item tsdb.Item) (*modelv1.TagFamily, error)
+}
+
+type RebuildOptions struct {
+	WriterOptions
+	// Scope is the one of the global index, which is absent if the global index is disabled
+	Scope     tsdb.Entry
+	TimeRange timestamp.TimeRange
+	// Throttle bounds the number of the items indexed per second
+	Throttle *throttle.Throttle
+	// EntityLen is the number of the tags composing the entity of the series
+	EntityLen int
+	// Progress counts the items indexed so far if it's present
+	Progress *atomic.Int64
+	// Incremental keeps the statistics of the rules collected by the writes, e.g. by a backfill of the rules just bound
+	Incremental bool
+}
+
+// Rebuild reconstructs the indices of the rules in options from the items of all the series stored in the time range.
+// The statistics of the rules are collected again unless the rebuild is incremental,
+// and the rules are marked built once all the items are indexed.
+// The indexed-only tags aren't stored, so their indices can't be rebuilt.
+// It returns the number of the items indexed, which are the ones indexed so far if it fails or ctx is done.
+func Rebuild(ctx context.Context, source Source, options RebuildOptions) (int, error) {
+	w := newWriter(ctx, options.WriterOptions)
+	shards, err := source.Shards(nil)
+	if err != nil {
+		return 0, err
+	}
+	if !options.Incremental {
+		forEachRule(shards, options.IndexRules, func(c *index.Cardinality, indexRuleID uint32) {
+			c.Reset(indexRuleID)
+		})
+	}
+	entity := make(tsdb.Entity, options.EntityLen)
+	for i := range entity {
+		entity[i] = tsdb.AnyEntry
+	}
+	var items int
+	for _, shard := range shards {
+		seriesList, err := shard.Series().List(tsdb.NewPath(entity))
+		if err != nil {
+			return items, err
+		}
+		for _, series := range seriesList {
+			n, err := w.rebuildSeries(ctx, source, series, options)
+			items += n
+			if err != nil {
+				return items, errors.WithMessagef(err, "rebuild the series %d in the shard %d", series.ID(), shard.ID())
+			}
+		}
+	}
+	now := time.Now()
+	forEachRule(shards, options.IndexRules, func(c *index.Cardinality, indexRuleID uint32) {
+		c.Built(indexRuleID, now)
+	})
+	return items, nil
+}
+
+func forEachRule(shards []tsdb.Shard, rules []*databasev1.IndexRule, fn func(c *index.Cardinality, indexRuleID uint32)) {
+	for _, shard := range shards {
+		for _, rule := range rules {
+			fn(shard.Cardinality(), rule.GetMetadata().GetId())
+		}
+	}
+}
+
+func (s *Writer) rebuildSeries(ctx context.Context, source Source, series tsdb.Series, options RebuildOptions) (items int, err error) {
+	// the values are read at the rate limited by the background throttle of the database
+	span, err := series.Span(tsdb.WithBackgroundIO(ctx), options.TimeRange)
+	if errors.Is(err, tsdb.ErrEmptySeriesSpan) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		err = multierr.Append(err, span.Close())
+	}()
+	seeker, err := span.SeekerBuilder().OrderByTime(modelv1.Sort_SORT_ASC).Build()
+	if err != nil {
+		return 0, err
+	}
+	iters, err := seeker.Seek()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		for _, iter := range iters {
+			err = multierr.Append(err, iter.Close())
+		}
+	}()
+	for _, iter := range iters {
+		for iter.Next() {
+			if err = options.Throttle.WaitContext(ctx, 1); err != nil {
+				return items, err
+			}
+			if err = s.rebuildItem(span, source, iter.Val(), options); err != nil {
+				return items, err
+			}
+			items++
+			if options.Progress != nil {
+				options.Progress.Add(1)
+			}
+		}
+	}
+	return items, nil
+}
+
+func (s *Writer) rebuildItem(span tsdb.SeriesSpan, source Source, item tsdb.Item, options RebuildOptions) error {
+	value := Value{
+		Timestamp: time.Unix(0, int64(item.Time())),
+	}
+	for _, spec := range options.Families {
+		family, err := source.ParseTagFamily(spec.GetName(), item)
+		if err != nil {
+			return err
+		}
+		tagFamily := &modelv1.TagFamilyForWrite{}
+		for _, tag := range family.GetTags() {
+			tagFamily.Tags = append(tagFamily.Tags, tag.GetValue())
+		}
+		value.TagFamilies = append(value.TagFamilies, tagFamily)
+	}
+	// the writer locates the block and the item, it only writes the indices so that the stored data are kept
+	writer, err := span.WriterBuilder().Time(value.Timestamp).Val(nil).Build()
+	if err != nil {
+		return err
+	}
+	return multierr.Combine(
+		s.writeLocalIndex(writer, value),
+		s.writeGlobalIndex(options.Scope, writer.ItemID(), value),
+	)
+}
@@ -20,6 +20,7 @@ package index
 import (
 	"context"
 	"io"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
@@ -35,6 +36,9 @@ import (
 	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
 )
 
+// flushCheckInterval is the interval of checking whether the written messages are indexed
+const flushCheckInterval = 10 * time.Millisecond
+
 type CallbackFn func()
 
 type Message struct {
@@ -72,9 +76,18 @@ type Writer struct {
 	enableGlobalIndex bool
 	ch                chan Message
 	invertRuleIndex   map[byte][]*partition.IndexRuleLocator
+	// pending counts the messages written but not indexed yet
+	pending atomic.Int64
 }
 
 func NewWriter(ctx context.Context, options WriterOptions) *Writer {
+	w := newWriter(ctx, options)
+	w.ch = make(chan Message)
+	w.bootIndexGenerator()
+	return w
+}
+
+func newWriter(ctx context.Context, options WriterOptions) *Writer {
 	w := new(Writer)
 	parentLogger := ctx.Value(logger.ContextKey)
 	if parentLogger != nil {
@@ -109,12 +122,11 @@ func NewWriter(ctx context.Context, options WriterOptions) *Writer {
 		rules = append(rules, ruleIndex)
 		w.invertRuleIndex[key] = rules
 	}
-	w.ch = make(chan Message)
-	w.bootIndexGenerator()
 	return w
 }
 
 func (s *Writer) Write(value Message) {
+	s.pending.Add(1)
 	go func(m Message) {
 		s.ch <- m
 	}(value)
@@ -138,10 +150,25 @@ func (s *Writer) bootIndexGenerator() {
 			if m.Cb != nil {
 				m.Cb()
 			}
+			s.pending.Add(-1)
 		}
 	}()
 }
 
+// Flush blocks until the messages written before are indexed, or the context is done.
+func (s *Writer) Flush(ctx context.Context) error {
+	ticker := time.NewTicker(flushCheckInterval)
+	defer ticker.Stop()
+	for s.pending.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
 // TODO: should listen to pipeline in a distributed cluster
 func (s *Writer) writeGlobalIndex(scope tsdb.Entry, ref tsdb.GlobalItemID, value Value) error {
 	collect := func(ruleIndexes []*partition.IndexRuleLocator, fn func(indexWriter tsdb.IndexWriter, fields []index.Field) error) error {
@@ -52,8 +52,9 @@ type IndexSeekBuilder interface{}
 var _ IndexDatabase = (*indexDB)(nil)
 
 type indexDB struct {
-	shardID common.ShardID
-	segCtrl *segmentController
+	shardID     common.ShardID
+	segCtrl     *segmentController
+	cardinality *index.Cardinality
 }
 
 func (i *indexDB) Seek(field index.Field) ([]GlobalItemID, error) {
@@ -80,14 +81,18 @@ func (i *indexDB) Seek(field index.Field) ([]GlobalItemID, error) {
 }
 
 func (i *indexDB) WriterBuilder() IndexWriterBuilder {
-	return newIndexWriterBuilder(i.segCtrl)
+	return newIndexWriterBuilder(i.segCtrl, i.cardinality)
 }
 
-func newIndexDatabase(_ context.Context, id common.ShardID, segCtrl *segmentController) (IndexDatabase, error) {
-	return &indexDB{
+func newIndexDatabase(ctx context.Context, id common.ShardID, segCtrl *segmentController) (IndexDatabase, error) {
+	idb := &indexDB{
 		shardID: id,
 		segCtrl: segCtrl,
-	}, nil
+	}
+	if c := ctx.Value(cardinalityKey); c != nil {
+		idb.cardinality = c.(*index.Cardinality)
+	}
+	return idb, nil
 }
 
 var _ IndexWriterBuilder = (*indexWriterBuilder)(nil)
@@ -95,6 +100,7 @@ var _ IndexWriterBuilder = (*indexWriterBuilder)(nil)
 type indexWriterBuilder struct {
 	scope        Entry
 	segCtrl      *segmentController
+	cardinality  *index.Cardinality
 	ts           time.Time
 	globalItemID *GlobalItemID
 }
@@ -123,29 +129,33 @@ func (i *indexWriterBuilder) Build() (IndexWriter, error) {
 		return nil, errors.WithStack(ErrNoVal)
 	}
 	return &indexWriter{
-		scope:  i.scope,
-		seg:    seg,
-		ts:     i.ts,
-		itemID: i.globalItemID,
+		scope:       i.scope,
+		seg:         seg,
+		cardinality: i.cardinality,
+		ts:          i.ts,
+		itemID:      i.globalItemID,
 	}, nil
 }
 
-func newIndexWriterBuilder(segCtrl *segmentController) IndexWriterBuilder {
+func newIndexWriterBuilder(segCtrl *segmentController, cardinality *index.Cardinality) IndexWriterBuilder {
 	return &indexWriterBuilder{
-		segCtrl: segCtrl,
+		segCtrl:     segCtrl,
+		cardinality: cardinality,
 	}
 }
 
 var _ IndexWriter = (*indexWriter)(nil)
 
 type indexWriter struct {
-	scope  Entry
-	seg    *segment
-	ts     time.Time
-	itemID *GlobalItemID
+	scope       Entry
+	seg         *segment
+	cardinality *index.Cardinality
+	ts          time.Time
+	itemID      *GlobalItemID
 }
 
 func (i *indexWriter) WriteLSMIndex(fields []index.Field) (err error) {
+	i.cardinality.Observe(fields)
 	for _, field := range fields {
 		if i.scope != nil {
 			field.Key.SeriesID = GlobalSeriesID(i.scope)
@@ -161,6 +171,7 @@ func (i *indexWriter) WriteLSMIndex(fields []index.Field) (err error) {
 }
 
 func (i *indexWriter) WriteInvertedIndex(fields []index.Field) (err error) {
+	i.cardinality.Observe(fields)
 	for _, field := range fields {
 		if i.scope != nil {
 			field.Key.SeriesID = GlobalSeriesID(i.scope)
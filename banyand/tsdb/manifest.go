@@ -0,0 +1,251 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tsdb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+// how often the manifests of the segments are refreshed
+const manifestInterval = "@every 1m"
+
+// SegmentManifest is the inventory of a segment for the external catalogs and the backup tools,
+// which is kept up to date in the manifest.json of the segment directory.
+type SegmentManifest struct {
+	Begin     time.Time       `json:"begin"`
+	End       time.Time       `json:"end"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Segment   string          `json:"segment"`
+	Checksum  string          `json:"checksum,omitempty"`
+	Resources []string        `json:"resources"`
+	Blocks    []BlockManifest `json:"blocks"`
+	DiskBytes int64           `json:"disk_bytes"`
+	Shard     uint32          `json:"shard"`
+}
+
+// BlockManifest is the inventory of a block.
+type BlockManifest struct {
+	Begin     time.Time `json:"begin"`
+	End       time.Time `json:"end"`
+	Name      string    `json:"name"`
+	Checksum  string    `json:"checksum,omitempty"`
+	DiskBytes int64     `json:"disk_bytes"`
+	Sealed    bool      `json:"sealed"`
+}
+
+// manifestKeeper refreshes the manifest of a segment, and caches the checksums of the sealed blocks
+// which are recomputed only if their files change, e.g. by the merging.
+type manifestKeeper struct {
+	checksums map[string]fileChecksum
+	last      SegmentManifest
+	mu        sync.Mutex
+}
+
+type fileChecksum struct {
+	modTime  time.Time
+	checksum string
+	size     int64
+}
+
+func newManifestKeeper() *manifestKeeper {
+	return &manifestKeeper{checksums: make(map[string]fileChecksum)}
+}
+
+// refresh collects the manifest of the segment, and writes it to the segment directory if it changes.
+func (s *segment) refreshManifest(shard uint32, now time.Time, ranges map[string]DataTimeRange) (SegmentManifest, error) {
+	mk := s.manifest
+	mk.mu.Lock()
+	defer mk.mu.Unlock()
+	m := SegmentManifest{
+		Shard:     shard,
+		Segment:   s.suffix,
+		Begin:     s.Start,
+		End:       s.End,
+		Resources: make([]string, 0),
+		Blocks:    make([]BlockManifest, 0),
+		UpdatedAt: now,
+	}
+	for name, r := range ranges {
+		if r.Latest.Before(s.Start) || !r.Earliest.Before(s.End) {
+			continue
+		}
+		m.Resources = append(m.Resources, name)
+	}
+	sort.Strings(m.Resources)
+	complete := true
+	digest := sha256.New()
+	for _, b := range s.blockController.blocks() {
+		bm := BlockManifest{
+			Name:   filepath.Base(b.path),
+			Begin:  b.Start,
+			End:    b.End,
+			Sealed: b.Closed() && !b.End.After(now),
+		}
+		size, modTime, err := statDir(b.path)
+		if err != nil {
+			return m, err
+		}
+		bm.DiskBytes = size
+		if bm.Sealed {
+			c, ok := mk.checksums[bm.Name]
+			if !ok || c.size != size || !c.modTime.Equal(modTime) {
+				if c.checksum, err = checksumDir(b.path); err != nil {
+					return m, err
+				}
+				c.size, c.modTime = size, modTime
+				mk.checksums[bm.Name] = c
+			}
+			bm.Checksum = c.checksum
+			_, _ = io.WriteString(digest, c.checksum)
+		} else {
+			complete = false
+		}
+		m.Blocks = append(m.Blocks, bm)
+	}
+	if complete && len(m.Blocks) > 0 {
+		m.Checksum = hex.EncodeToString(digest.Sum(nil))
+	}
+	var err error
+	if m.DiskBytes, err = diskSize(s.path); err != nil {
+		return m, err
+	}
+	last := mk.last
+	last.UpdatedAt = m.UpdatedAt
+	if reflect.DeepEqual(last, m) {
+		return mk.last, nil
+	}
+	if err = writeManifest(fmt.Sprintf(manifestTemplate, s.path), m); err != nil {
+		return m, err
+	}
+	mk.last = m
+	return m, nil
+}
+
+// statDir returns the total size and the latest modification time of the files in the directory.
+// The files removed while walking, e.g. by the compaction of an open block, are skipped.
+func statDir(root string) (size int64, modTime time.Time, err error) {
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+		return nil
+	})
+	return size, modTime, err
+}
+
+// checksumDir returns the SHA-256 of the relative paths and the contents of the files in the directory in hex.
+func checksumDir(root string) (string, error) {
+	digest := sha256.New()
+	// WalkDir visits the files in the lexical order, so the checksum is stable
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		_, _ = io.WriteString(digest, rel)
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(digest, f)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// writeManifest replaces the manifest atomically, so that the readers never see a partial one.
+func writeManifest(path string, m SegmentManifest) error {
+	bb, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, bb, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// manifestTask refreshes the manifests of the segments of a shard periodically.
+type manifestTask struct {
+	shard *shard
+}
+
+func (mt *manifestTask) run(_ time.Time, l *logger.Logger) bool {
+	if _, err := mt.shard.Manifests(); err != nil {
+		// the files being rewritten, e.g. by a merge, are visited again in the next round
+		l.Warn().Err(err).Msg("failed to refresh the manifests of the segments")
+	}
+	return true
+}
+
+func (s *shard) Manifests() ([]SegmentManifest, error) {
+	var ranges map[string]DataTimeRange
+	if s.timeRanges != nil {
+		ranges = s.timeRanges.get()
+	}
+	now := s.clock.Now()
+	var result []SegmentManifest
+	for _, seg := range s.segmentController.segments() {
+		m, err := seg.refreshManifest(uint32(s.id), now, ranges)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, m)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Begin.Before(result[j].Begin)
+	})
+	return result, nil
+}
@@ -0,0 +1,308 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tsdb
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/banyand/kv"
+	"github.com/apache/skywalking-banyandb/pkg/index"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+const (
+	// how often the small blocks are looked for
+	mergeCheckInterval = "@every 10m"
+	// how long the in-flight reads and writes of a source block are waited for before merging it
+	mergeCloseTimeout = 5 * time.Second
+	// how long the delegates of the merged source blocks are waited for before removing them
+	mergeDrainTimeout = 10 * time.Minute
+
+	// a merged block is located at "block-merged<the number of the intervals>-<the suffix of the first interval>"
+	mergedBlockPrefix = "merged"
+	// a merged block is written into "merging-<suffix>", which is renamed once it's complete
+	mergingPathPrefix = "merging"
+)
+
+var errMergeConflict = errors.New("the block is changed during the merge")
+
+// mergeTask merges the adjacent sealed blocks of a segment, which are left small by the frequent flushes,
+// into the blocks of the target size to reduce the seeks of the queries.
+type mergeTask struct {
+	segment    *segmentController
+	targetSize int64
+}
+
+func newMergeTask(segment *segmentController, targetSize int64) *mergeTask {
+	return &mergeTask{
+		segment:    segment,
+		targetSize: targetSize,
+	}
+}
+
+func (mt *mergeTask) run(now time.Time, l *logger.Logger) bool {
+	for _, seg := range mt.segment.segments() {
+		for _, sources := range seg.blockController.mergeable(now, mt.targetSize) {
+			merged, err := seg.blockController.merge(sources, l)
+			if err != nil {
+				// the blocks are tried again in the next round
+				l.Warn().Err(err).Stringer("block", sources[0]).Int("num", len(sources)).Msg("failed to merge the blocks")
+				continue
+			}
+			l.Info().Stringer("block", merged).Int("num", len(sources)).Msg("merged the small blocks")
+		}
+	}
+	return true
+}
+
+// mergeable returns the runs of the adjacent sealed blocks whose total size is up to targetSize.
+func (bc *blockController) mergeable(now time.Time, targetSize int64) (runs [][]*block) {
+	var run []*block
+	var runSize int64
+	flush := func() {
+		if len(run) > 1 {
+			runs = append(runs, run)
+		}
+		run, runSize = nil, 0
+	}
+	for _, b := range bc.blocks() {
+		if b.End.After(now) || b.deleted.Load() {
+			flush()
+			continue
+		}
+		size, err := flushedSize(b.path)
+		if err != nil || size >= targetSize {
+			flush()
+			continue
+		}
+		if len(run) > 0 && (!run[len(run)-1].End.Equal(b.Start) || runSize+size > targetSize) {
+			flush()
+		}
+		run = append(run, b)
+		runSize += size
+	}
+	flush()
+	return runs
+}
+
+// merge writes the data and the indices of the sources into a new block, and swaps it in for them.
+// The readers holding the delegates of the sources keep reading them until they're released,
+// and then the sources are removed in the background.
+func (bc *blockController) merge(sources []*block, l *logger.Logger) (merged *block, err error) {
+	first, last := sources[0], sources[len(sources)-1]
+	span := 0
+	for t := first.Start; t.Before(last.End); t = bc.blockSize.NextTime(t) {
+		span++
+	}
+	mergingPath := fmt.Sprintf(rootPrefix, bc.location) + mergingPathPrefix + "-" + first.suffix
+	if err = os.RemoveAll(mergingPath); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.RemoveAll(mergingPath)
+		}
+	}()
+
+	lastWrites := make([]int64, len(sources))
+	for i, b := range sources {
+		// only the flushed values are visited, which are flushed by closing the block
+		ctx, cancel := context.WithTimeout(context.Background(), mergeCloseTimeout)
+		err = b.rollover(ctx)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		var d BlockDelegate
+		d, err = b.delegate(ctx)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		defer d.Close()
+		lastWrites[i] = b.lastWrite.Load()
+	}
+	store, invertedIndex, lsmIndex, err := first.openStores(mergingPath)
+	if err != nil {
+		return nil, err
+	}
+	err = copyBlocks(sources, store, invertedIndex, lsmIndex)
+	if err = multierr.Combine(err, store.Close(), invertedIndex.Close(), lsmIndex.Close()); err != nil {
+		return nil, err
+	}
+
+	bc.Lock()
+	defer bc.Unlock()
+	for i, b := range sources {
+		if b.deleted.Load() || b.lastWrite.Load() != lastWrites[i] {
+			return nil, errors.WithMessagef(errMergeConflict, "block %s", b)
+		}
+	}
+	mergedPath := fmt.Sprintf(blockTemplate, bc.location, fmt.Sprintf("%s%d-%s", mergedBlockPrefix, span, first.suffix))
+	if err = os.Rename(mergingPath, mergedPath); err != nil {
+		return nil, err
+	}
+	// the sources covered by the merged block are removed on the next opening if the server crashes from now on
+	for _, b := range sources {
+		bc.removeBlockOf(b)
+		b.queue.Remove(BlockID{
+			SegID:   b.segID,
+			BlockID: b.blockID,
+		})
+	}
+	if merged, err = bc.load(first.suffix, mergedPath); err != nil {
+		return nil, err
+	}
+	go bc.retire(sources, lastWrites, merged, l)
+	return merged, nil
+}
+
+// retire removes the merged sources once their delegates are released.
+// The writes racing the swap are copied into the merged block before removing a source.
+func (bc *blockController) retire(sources []*block, lastWrites []int64, merged *block, l *logger.Logger) {
+	for i, b := range sources {
+		ctx, cancel := context.WithTimeout(context.Background(), mergeDrainTimeout)
+		err := b.close(ctx)
+		if err == nil && b.lastWrite.Load() != lastWrites[i] {
+			err = catchUp(ctx, b, merged)
+		}
+		if err == nil {
+			err = b.delete(ctx)
+		}
+		cancel()
+		if err != nil {
+			// the source is removed on the next opening
+			l.Warn().Err(err).Stringer("block", b).Msg("failed to remove the merged block")
+		}
+	}
+}
+
+func catchUp(ctx context.Context, source, merged *block) error {
+	if err := source.openSafely(); err != nil {
+		return err
+	}
+	defer source.close(ctx)
+	d, err := merged.delegate(ctx)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return copyBlocks([]*block{source}, merged.store, merged.invertedIndex, merged.lsmIndex)
+}
+
+// copyBlocks copies the data and the indices of the open blocks into the stores.
+// The reads of the sources are limited by their background throttle, the writes are not so that the flushes aren't stalled.
+func copyBlocks(sources []*block, store kv.TimeSeriesWriter, invertedIndex, lsmIndex index.Writer) error {
+	for _, b := range sources {
+		if err := b.store.Visit(func(key, val []byte, ts uint64) error {
+			return store.Put(key, val, ts)
+		}); err != nil {
+			return errors.WithMessagef(err, "failed to copy the data of %s", b)
+		}
+		for _, idx := range []struct {
+			from index.Visitor
+			to   index.Writer
+		}{{b.invertedIndex, invertedIndex}, {b.lsmIndex, lsmIndex}} {
+			if err := idx.from.Visit(func(itemID common.ItemID, fields []index.Field) error {
+				size := 0
+				for _, f := range fields {
+					size += len(f.Term)
+				}
+				b.throttle.Wait(size)
+				return idx.to.Write(fields, itemID)
+			}); err != nil {
+				return errors.WithMessagef(err, "failed to copy the index of %s", b)
+			}
+		}
+	}
+	return nil
+}
+
+// recoverMerges removes the incomplete merged blocks and the sources of the complete ones left by a crash.
+func (bc *blockController) recoverMerges() error {
+	entries, err := os.ReadDir(bc.location)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() && strings.HasPrefix(e.Name(), mergingPathPrefix) {
+			if err = os.RemoveAll(filepath.Join(bc.location, e.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	bc.Lock()
+	defer bc.Unlock()
+	for _, m := range append([]*block(nil), bc.lst...) {
+		if len(m.coveredIDs) < 2 {
+			continue
+		}
+		for _, b := range append([]*block(nil), bc.lst...) {
+			if b == m || b.Start.Before(m.Start) || b.End.After(m.End) {
+				continue
+			}
+			bc.l.Info().Stringer("block", b).Stringer("merged", m).Msg("remove the block merged before the crash")
+			if err = os.RemoveAll(b.path); err != nil {
+				return err
+			}
+			bc.removeBlockOf(b)
+		}
+	}
+	return nil
+}
+
+// blockSpan returns the number of the intervals spanned by the block located at blockPath.
+func blockSpan(blockPath string) (int, error) {
+	name := strings.TrimPrefix(filepath.Base(blockPath), blockPathPrefix+"-")
+	if !strings.HasPrefix(name, mergedBlockPrefix) {
+		return 1, nil
+	}
+	span, _, _ := strings.Cut(strings.TrimPrefix(name, mergedBlockPrefix), "-")
+	return strconv.Atoi(span)
+}
+
+// flushedSize returns the size of the tables and the segments flushed by the data store and the indices of a block.
+// The other files, e.g. the value logs and the memory tables, are preallocated by the open stores.
+func flushedSize(root string) (size int64, err error) {
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if ext := filepath.Ext(p); ext != ".sst" && ext != ".seg" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
@@ -22,13 +22,21 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 
+	"github.com/apache/skywalking-banyandb/api/common"
 	"github.com/apache/skywalking-banyandb/banyand/observability"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 )
 
 var (
-	mtBytes    *prometheus.GaugeVec
-	maxMtBytes *prometheus.GaugeVec
+	mtBytes          *prometheus.GaugeVec
+	maxMtBytes       *prometheus.GaugeVec
+	unsyncedBytes    *prometheus.GaugeVec
+	blockCacheHits   *prometheus.CounterVec
+	blockCacheMisses *prometheus.CounterVec
+
+	blockFlushes         *prometheus.CounterVec
+	blockFlushedElements *prometheus.CounterVec
+	memtableFlushes      *prometheus.CounterVec
 )
 
 func init() {
@@ -47,6 +55,50 @@ func init() {
 		},
 		labels,
 	)
+	unsyncedBytes = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "banyand_unsynced_bytes",
+			Help: "The acknowledged bytes which are not synced to the disk yet",
+		},
+		labels,
+	)
+	cacheLabels := []string{"module", "database", "shard"}
+	blockCacheHits = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "banyand_block_cache_hits_total",
+			Help: "The number of the values found in the block cache",
+		},
+		cacheLabels,
+	)
+	blockCacheMisses = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "banyand_block_cache_misses_total",
+			Help: "The number of the values absent from the block cache",
+		},
+		cacheLabels,
+	)
+	flushLabels := []string{"module", "database", "shard", "reason"}
+	blockFlushes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "banyand_block_flushes_total",
+			Help: "The number of the blocks flushed by the buffered elements or the flush interval",
+		},
+		flushLabels,
+	)
+	blockFlushedElements = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "banyand_block_flushed_elements_total",
+			Help: "The number of the elements buffered by the blocks when they're flushed",
+		},
+		flushLabels,
+	)
+	memtableFlushes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "banyand_memtable_flushes_total",
+			Help: "The number of the memory tables of the blocks flushed to the disk",
+		},
+		cacheLabels,
+	)
 }
 
 func (s *shard) stat(_ time.Time, _ *logger.Logger) bool {
@@ -58,6 +110,7 @@ func (s *shard) stat(_ time.Time, _ *logger.Logger) bool {
 	seriesStat := s.seriesDatabase.Stats()
 	s.curry(mtBytes).WithLabelValues("series").Set(float64(seriesStat.MemBytes))
 	s.curry(maxMtBytes).WithLabelValues("series").Set(float64(seriesStat.MaxMemBytes))
+	s.curry(unsyncedBytes).WithLabelValues("series").Set(float64(seriesStat.UnsyncedBytes))
 	segStats := observability.Statistics{}
 	blockStats := newBlockStat()
 	for _, seg := range s.segmentController.segments() {
@@ -74,6 +127,7 @@ func (s *shard) stat(_ time.Time, _ *logger.Logger) bool {
 				if ok {
 					bsc.MaxMemBytes += bs.MaxMemBytes
 					bsc.MemBytes += bs.MemBytes
+					bsc.UnsyncedBytes += bs.UnsyncedBytes
 				}
 			}
 		}
@@ -83,16 +137,27 @@ func (s *shard) stat(_ time.Time, _ *logger.Logger) bool {
 	for name, bs := range blockStats {
 		s.curry(mtBytes).WithLabelValues(name).Set(float64(bs.MemBytes))
 		s.curry(maxMtBytes).WithLabelValues(name).Set(float64(bs.MaxMemBytes))
+		if name == componentMain {
+			s.curry(unsyncedBytes).WithLabelValues(name).Set(float64(bs.UnsyncedBytes))
+		}
 	}
 	return true
 }
 
 func (s *shard) curry(gv *prometheus.GaugeVec) *prometheus.GaugeVec {
-	return gv.MustCurryWith(prometheus.Labels{
-		"module":   s.position.Module,
-		"database": s.position.Database,
-		"shard":    s.position.Shard,
-	})
+	return gv.MustCurryWith(positionLabels(s.position))
+}
+
+func curryPosition(cv *prometheus.CounterVec, position common.Position) *prometheus.CounterVec {
+	return cv.MustCurryWith(positionLabels(position))
+}
+
+func positionLabels(position common.Position) prometheus.Labels {
+	return prometheus.Labels{
+		"module":   position.Module,
+		"database": position.Database,
+		"shard":    position.Shard,
+	}
 }
 
 func newBlockStat() map[string]*observability.Statistics {
@@ -0,0 +1,108 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tsdb
+
+import (
+	"sync"
+	"time"
+
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+const (
+	// DefaultRecoveryConcurrency is the default number of the shards opened in parallel
+	DefaultRecoveryConcurrency = 4
+	// the min interval between the logs of the progress
+	recoveryReportInterval = 5 * time.Second
+)
+
+// RecoveryProgress is a snapshot of the progress of opening the shards.
+type RecoveryProgress struct {
+	StartedAt    time.Time
+	Shards       int
+	OpenedShards int
+	Segments     int
+	// ETA estimates the time to open the rest shards by the average time the opened ones took
+	ETA time.Duration
+}
+
+// Done tells whether all the shards are opened.
+func (p RecoveryProgress) Done() bool {
+	return p.OpenedShards >= p.Shards
+}
+
+// Recovery tracks the progress of opening the shards at startup, which is shared by the databases of a service.
+// Opening a shard replays the write-ahead log of its series database, and loads its segments.
+type Recovery struct {
+	l          *logger.Logger
+	startedAt  time.Time
+	reportedAt time.Time
+	progress   RecoveryProgress
+	mu         sync.Mutex
+}
+
+func NewRecovery(l *logger.Logger) *Recovery {
+	return &Recovery{l: l}
+}
+
+// Progress returns the progress, which is done before any shard starts to open.
+func (r *Recovery) Progress() RecoveryProgress {
+	if r == nil {
+		return RecoveryProgress{}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.progress
+}
+
+func (r *Recovery) add(shards int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.progress.StartedAt.IsZero() {
+		r.progress.StartedAt = time.Now()
+		r.reportedAt = r.progress.StartedAt
+	}
+	r.progress.Shards += shards
+}
+
+func (r *Recovery) opened(segments int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p := &r.progress
+	p.OpenedShards++
+	p.Segments += segments
+	now := time.Now()
+	elapsed := now.Sub(p.StartedAt)
+	p.ETA = elapsed / time.Duration(p.OpenedShards) * time.Duration(p.Shards-p.OpenedShards)
+	if p.Done() {
+		r.l.Info().Int("shards", p.Shards).Int("segments", p.Segments).Dur("elapsed", elapsed).Msg("opened all the shards")
+		return
+	}
+	if now.Sub(r.reportedAt) < recoveryReportInterval {
+		return
+	}
+	r.reportedAt = now
+	r.l.Info().Int("shards", p.Shards).Int("opened_shards", p.OpenedShards).Int("segments", p.Segments).
+		Dur("elapsed", elapsed).Dur("eta", p.ETA).Msg("opening the shards")
+}
@@ -28,35 +28,45 @@ import (
 
 type retentionTask struct {
 	segment *segmentController
+	// timeRanges are truncated to the deadline once the data before it are removed
+	timeRanges *timeRanges
+	zone       *time.Location
 
-	option   cron.ParseOption
-	expr     string
-	duration time.Duration
+	option cron.ParseOption
+	expr   string
+	ttl    IntervalRule
 }
 
-func newRetentionTask(segment *segmentController, ttl IntervalRule) *retentionTask {
+// newRetentionTask returns the task removing the data older than the ttl, which runs on the hours or the days of the zone.
+func newRetentionTask(segment *segmentController, ttl IntervalRule, zone *time.Location) *retentionTask {
 	var expr string
 	switch ttl.Unit {
 	case HOUR:
 		// Every hour on the 5th minute
 		expr = "5 *"
-	case DAY:
+	case DAY, MONTH:
 		// Every day on 00:05
 		expr = "5 0"
 	}
 	return &retentionTask{
-		segment:  segment,
-		option:   cron.Minute | cron.Hour,
-		expr:     expr,
-		duration: ttl.EstimatedDuration(),
+		segment: segment,
+		zone:    zone,
+		option:  cron.Minute | cron.Hour,
+		expr:    "CRON_TZ=" + zone.String() + " " + expr,
+		ttl:     ttl,
 	}
 }
 
 func (rc *retentionTask) run(now time.Time, l *logger.Logger) bool {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
 	defer cancel()
-	if err := rc.segment.remove(ctx, now.Add(-rc.duration)); err != nil {
+	// the days and months are the calendar ones of the zone, which are shorter or longer than 24 hours across the DST changes
+	deadline := rc.ttl.PreviousTime(now.In(rc.zone))
+	if err := rc.segment.remove(ctx, deadline); err != nil {
 		l.Error().Err(err)
 	}
+	if rc.timeRanges != nil {
+		rc.timeRanges.truncate(deadline)
+	}
 	return true
 }
@@ -18,8 +18,11 @@
 package tsdb
 
 import (
+	"context"
+
 	"github.com/apache/skywalking-banyandb/api/common"
 	"github.com/apache/skywalking-banyandb/banyand/observability"
+	"github.com/apache/skywalking-banyandb/pkg/index"
 )
 
 var _ Shard = (*ScopedShard)(nil)
@@ -56,6 +59,26 @@ func (sd *ScopedShard) Index() IndexDatabase {
 	return sd.delegated.Index()
 }
 
+func (sd *ScopedShard) Cardinality() *index.Cardinality {
+	return sd.delegated.Cardinality()
+}
+
+func (sd *ScopedShard) Usage() (ShardUsage, error) {
+	return sd.delegated.Usage()
+}
+
+func (sd *ScopedShard) Manifests() ([]SegmentManifest, error) {
+	return sd.delegated.Manifests()
+}
+
+func (sd *ScopedShard) ExportBlock(segment, block string) (*BlockExport, error) {
+	return sd.delegated.ExportBlock(segment, block)
+}
+
+func (sd *ScopedShard) Rollover(ctx context.Context) ([]string, error) {
+	return sd.delegated.Rollover(ctx)
+}
+
 func (sd *ScopedShard) TriggerSchedule(task string) bool {
 	return sd.delegated.TriggerSchedule(task)
 }
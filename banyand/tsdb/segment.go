@@ -45,6 +45,7 @@ type segment struct {
 	bucket.Reporter
 	blockController     *blockController
 	blockManageStrategy *bucket.Strategy
+	manifest            *manifestKeeper
 	closeOnce           sync.Once
 }
 
@@ -62,6 +63,7 @@ func openSegment(ctx context.Context, startTime time.Time, path, suffix string,
 		path:      path,
 		suffix:    suffix,
 		TimeRange: timeRange,
+		manifest:  newManifestKeeper(),
 	}
 	l := logger.Fetch(ctx, s.String())
 	s.l = l
@@ -38,15 +38,17 @@ type segmentController struct {
 	location    string
 	segmentSize IntervalRule
 	blockSize   IntervalRule
-	lst         []*segment
-	blockQueue  bucket.Queue
-	scheduler   *timestamp.Scheduler
-	clock       timestamp.Clock
+	// zone is the time zone the segments align to
+	zone       *time.Location
+	lst        []*segment
+	blockQueue bucket.Queue
+	scheduler  *timestamp.Scheduler
+	clock      timestamp.Clock
 
 	l *logger.Logger
 }
 
-func newSegmentController(shardCtx context.Context, location string, segmentSize, blockSize IntervalRule,
+func newSegmentController(shardCtx context.Context, location string, segmentSize, blockSize IntervalRule, zone *time.Location,
 	openedBlockSize, maxOpenedBlockSize int, l *logger.Logger, scheduler *timestamp.Scheduler,
 ) (*segmentController, error) {
 	clock, _ := timestamp.GetClock(shardCtx)
@@ -55,6 +57,7 @@ func newSegmentController(shardCtx context.Context, location string, segmentSize
 		location:    location,
 		segmentSize: segmentSize,
 		blockSize:   blockSize,
+		zone:        zone,
 		l:           l,
 		clock:       clock,
 		scheduler:   scheduler,
@@ -156,9 +159,9 @@ func (sc *segmentController) OnMove(prev bucket.Reporter, next bucket.Reporter)
 func (sc *segmentController) Format(tm time.Time) string {
 	switch sc.segmentSize.Unit {
 	case HOUR:
-		return tm.Format(segHourFormat)
+		return tm.In(sc.zone).Format(segHourFormat)
 	case DAY:
-		return tm.Format(segDayFormat)
+		return tm.In(sc.zone).Format(segDayFormat)
 	}
 	panic("invalid interval unit")
 }
@@ -166,9 +169,9 @@ func (sc *segmentController) Format(tm time.Time) string {
 func (sc *segmentController) Parse(value string) (time.Time, error) {
 	switch sc.segmentSize.Unit {
 	case HOUR:
-		return time.ParseInLocation(segHourFormat, value, time.Local)
+		return time.ParseInLocation(segHourFormat, value, sc.zone)
 	case DAY:
-		return time.ParseInLocation(segDayFormat, value, time.Local)
+		return time.ParseInLocation(segDayFormat, value, sc.zone)
 	}
 	panic("invalid interval unit")
 }
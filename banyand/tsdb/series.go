@@ -27,6 +27,7 @@ import (
 	"go.uber.org/multierr"
 
 	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/banyand/kv"
 	"github.com/apache/skywalking-banyandb/pkg/convert"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 	"github.com/apache/skywalking-banyandb/pkg/timestamp"
@@ -84,6 +85,7 @@ type SeriesSpan interface {
 	io.Closer
 	WriterBuilder() WriterBuilder
 	SeekerBuilder() SeekerBuilder
+	BlockNum() int
 }
 
 var _ Series = (*series)(nil)
@@ -125,7 +127,9 @@ func (s *series) Span(ctx context.Context, timeRange timestamp.TimeRange) (Serie
 	s.l.Debug().
 		Times("time_range", []time.Time{timeRange.Start, timeRange.End}).
 		Msg("select series span")
-	return newSeriesSpan(context.WithValue(context.Background(), logger.ContextKey, s.l), timeRange, blocks, s.id, s.shardID), nil
+	span := newSeriesSpan(context.WithValue(context.Background(), logger.ContextKey, s.l), timeRange, blocks, s.id, s.shardID)
+	span.background = isBackgroundIO(ctx)
+	return span, nil
 }
 
 func (s *series) Create(ctx context.Context, t time.Time) (SeriesSpan, error) {
@@ -174,6 +178,15 @@ type seriesSpan struct {
 	shardID   common.ShardID
 	timeRange timestamp.TimeRange
 	l         *logger.Logger
+	// background tells whether the span is read by a background job
+	background bool
+}
+
+func (s *seriesSpan) dataReader(b BlockDelegate) kv.TimeSeriesReader {
+	if s.background {
+		return b.backgroundDataReader()
+	}
+	return b.dataReader()
 }
 
 func (s *seriesSpan) Close() (err error) {
@@ -191,6 +204,10 @@ func (s *seriesSpan) SeekerBuilder() SeekerBuilder {
 	return newSeekerBuilder(s)
 }
 
+func (s *seriesSpan) BlockNum() int {
+	return len(s.blocks)
+}
+
 func newSeriesSpan(ctx context.Context, timeRange timestamp.TimeRange, blocks []BlockDelegate, id common.SeriesID, shardID common.ShardID) *seriesSpan {
 	s := &seriesSpan{
 		blocks:    blocks,
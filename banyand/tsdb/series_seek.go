@@ -18,6 +18,8 @@
 package tsdb
 
 import (
+	"context"
+
 	"github.com/apache/skywalking-banyandb/api/common"
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
 	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
@@ -43,6 +45,8 @@ type SeekerBuilder interface {
 	Filter(predicator index.Filter) SeekerBuilder
 	OrderByIndex(indexRule *databasev1.IndexRule, order modelv1.Sort) SeekerBuilder
 	OrderByTime(order modelv1.Sort) SeekerBuilder
+	// WithContext stops the iterators once ctx is done, e.g. the query times out
+	WithContext(ctx context.Context) SeekerBuilder
 	Build() (Seeker, error)
 }
 
@@ -54,6 +58,7 @@ var _ SeekerBuilder = (*seekerBuilder)(nil)
 
 type seekerBuilder struct {
 	seriesSpan *seriesSpan
+	ctx        context.Context
 
 	predicator          index.Filter
 	order               modelv1.Sort
@@ -61,6 +66,11 @@ type seekerBuilder struct {
 	rangeOptsForSorting index.RangeOpts
 }
 
+func (s *seekerBuilder) WithContext(ctx context.Context) SeekerBuilder {
+	s.ctx = ctx
+	return s
+}
+
 func (s *seekerBuilder) Build() (Seeker, error) {
 	if s.order == modelv1.Sort_SORT_UNSPECIFIED {
 		s.order = modelv1.Sort_SORT_ASC
@@ -75,6 +85,7 @@ func (s *seekerBuilder) Build() (Seeker, error) {
 func newSeekerBuilder(s *seriesSpan) SeekerBuilder {
 	return &seekerBuilder{
 		seriesSpan: s,
+		ctx:        context.Background(),
 	}
 }
 
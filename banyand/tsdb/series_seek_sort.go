@@ -18,6 +18,8 @@
 package tsdb
 
 import (
+	"container/heap"
+	"context"
 	"sort"
 	"time"
 
@@ -64,8 +66,10 @@ func (s *seekerBuilder) buildSeriesByIndex() (series []Iterator, err error) {
 		return valid
 	}
 	for _, b := range s.seriesSpan.blocks {
+		if err = s.ctx.Err(); err != nil {
+			return nil, err
+		}
 		var inner index.FieldIterator
-		var err error
 		fieldKey := index.FieldKey{
 			SeriesID:    s.seriesSpan.seriesID,
 			IndexRuleID: s.indexRuleForSorting.GetMetadata().GetId(),
@@ -88,7 +92,7 @@ func (s *seekerBuilder) buildSeriesByIndex() (series []Iterator, err error) {
 			return nil, err
 		}
 		if inner != nil {
-			series = append(series, newSearcherIterator(s.seriesSpan.l, inner, b.dataReader(), s.seriesSpan.seriesID, filters))
+			series = append(series, newSearcherIterator(s.ctx, s.seriesSpan.l, inner, s.seriesSpan.dataReader(b), s.seriesSpan.seriesID, filters))
 		}
 	}
 	return
@@ -116,6 +120,9 @@ func (s *seekerBuilder) buildSeriesByTime() ([]Iterator, error) {
 		IncludesLower: true,
 	}
 	for _, b := range bb {
+		if err := s.ctx.Err(); err != nil {
+			return nil, err
+		}
 		bTimes = append(bTimes, b.startTime())
 		inner, err := b.primaryIndexReader().
 			Iterator(
@@ -134,9 +141,9 @@ func (s *seekerBuilder) buildSeriesByTime() ([]Iterator, error) {
 				return nil, err
 			}
 			if filter == nil {
-				delegated = append(delegated, newSearcherIterator(s.seriesSpan.l, inner, b.dataReader(), s.seriesSpan.seriesID, emptyFilters))
+				delegated = append(delegated, newSearcherIterator(s.ctx, s.seriesSpan.l, inner, s.seriesSpan.dataReader(b), s.seriesSpan.seriesID, emptyFilters))
 			} else {
-				delegated = append(delegated, newSearcherIterator(s.seriesSpan.l, inner, b.dataReader(), s.seriesSpan.seriesID, []filterFn{filter}))
+				delegated = append(delegated, newSearcherIterator(s.ctx, s.seriesSpan.l, inner, s.seriesSpan.dataReader(b), s.seriesSpan.seriesID, []filterFn{filter}))
 			}
 		}
 	}
@@ -146,12 +153,13 @@ func (s *seekerBuilder) buildSeriesByTime() ([]Iterator, error) {
 		Uint64("series_id", uint64(s.seriesSpan.seriesID)).
 		Int("shard_id", int(s.seriesSpan.shardID)).
 		Msg("seek series by time")
-	return []Iterator{newMergedIterator(delegated)}, nil
+	return []Iterator{newMergedIterator(delegated, s.order)}, nil
 }
 
 var _ Iterator = (*searcherIterator)(nil)
 
 type searcherIterator struct {
+	ctx           context.Context
 	fieldIterator index.FieldIterator
 	curKey        []byte
 	cur           posting.Iterator
@@ -162,6 +170,9 @@ type searcherIterator struct {
 }
 
 func (s *searcherIterator) Next() bool {
+	if s.ctx.Err() != nil {
+		return false
+	}
 	if s.cur == nil {
 		if s.fieldIterator.Next() {
 			v := s.fieldIterator.Val()
@@ -200,10 +211,11 @@ func (s *searcherIterator) Close() error {
 	return s.fieldIterator.Close()
 }
 
-func newSearcherIterator(l *logger.Logger, fieldIterator index.FieldIterator, data kv.TimeSeriesReader,
+func newSearcherIterator(ctx context.Context, l *logger.Logger, fieldIterator index.FieldIterator, data kv.TimeSeriesReader,
 	seriesID common.SeriesID, filters []filterFn,
 ) Iterator {
 	return &searcherIterator{
+		ctx:           ctx,
 		fieldIterator: fieldIterator,
 		data:          data,
 		seriesID:      seriesID,
@@ -214,30 +226,42 @@ func newSearcherIterator(l *logger.Logger, fieldIterator index.FieldIterator, da
 
 var _ Iterator = (*mergedIterator)(nil)
 
+// mergedIterator merges the items of the blocks, including the ones in the memory tables, by their time in the order.
+// The items of a series are identified by their time, so the duplicated ones written to the overlapped blocks,
+// e.g. a merged block and its source catching up the racing writes, are read once.
 type mergedIterator struct {
-	curr      Iterator
-	index     int
 	delegated []Iterator
+	heads     *itemHeap
+	cur       Item
+	init      bool
 }
 
 func (m *mergedIterator) Next() bool {
-	if m.curr == nil {
-		m.index++
-		if m.index >= len(m.delegated) {
-			return false
+	if !m.init {
+		m.init = true
+		for i, d := range m.delegated {
+			if d.Next() {
+				m.heads.items = append(m.heads.items, headItem{Item: d.Val(), index: i})
+			}
 		}
-		m.curr = m.delegated[m.index]
+		heap.Init(m.heads)
 	}
-	hasNext := m.curr.Next()
-	if !hasNext {
-		m.curr = nil
-		return m.Next()
+	for m.heads.Len() > 0 {
+		head := heap.Pop(m.heads).(headItem)
+		if d := m.delegated[head.index]; d.Next() {
+			heap.Push(m.heads, headItem{Item: d.Val(), index: head.index})
+		}
+		if m.cur != nil && m.cur.Time() == head.Time() {
+			continue
+		}
+		m.cur = head.Item
+		return true
 	}
-	return true
+	return false
 }
 
 func (m *mergedIterator) Val() Item {
-	return m.curr.Val()
+	return m.cur
 }
 
 func (m *mergedIterator) Close() error {
@@ -248,9 +272,41 @@ func (m *mergedIterator) Close() error {
 	return err
 }
 
-func newMergedIterator(delegated []Iterator) Iterator {
+func newMergedIterator(delegated []Iterator, order modelv1.Sort) Iterator {
 	return &mergedIterator{
-		index:     -1,
 		delegated: delegated,
+		heads:     &itemHeap{desc: order == modelv1.Sort_SORT_DESC},
+	}
+}
+
+// headItem is the next item of a delegated iterator.
+type headItem struct {
+	Item
+	index int
+}
+
+// itemHeap orders the head items by their time, and the ones of the same time by their iterators.
+type itemHeap struct {
+	items []headItem
+	desc  bool
+}
+
+func (h *itemHeap) Len() int { return len(h.items) }
+
+func (h *itemHeap) Less(i, j int) bool {
+	ti, tj := h.items[i].Time(), h.items[j].Time()
+	if ti == tj {
+		return h.items[i].index < h.items[j].index
 	}
+	return (ti < tj) != h.desc
+}
+
+func (h *itemHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *itemHeap) Push(x interface{}) { h.items = append(h.items, x.(headItem)) }
+
+func (h *itemHeap) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
 }
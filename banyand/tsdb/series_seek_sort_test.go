@@ -0,0 +1,112 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tsdb
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/index"
+	"github.com/apache/skywalking-banyandb/pkg/index/posting/roaring"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+type sliceIterator struct {
+	items []Item
+	index int
+}
+
+func (s *sliceIterator) Next() bool {
+	s.index++
+	return s.index < len(s.items)
+}
+
+func (s *sliceIterator) Val() Item {
+	return s.items[s.index]
+}
+
+func (s *sliceIterator) Close() error {
+	return nil
+}
+
+// blockOf returns the iterator of the items at the times, whose sorted fields tell the block they're read from.
+func blockOf(name string, times ...uint64) Iterator {
+	items := make([]Item, 0, len(times))
+	for _, t := range times {
+		items = append(items, &item{itemID: common.ItemID(t), sortedField: []byte(name)})
+	}
+	return &sliceIterator{items: items, index: -1}
+}
+
+func readAll(t *testing.T, iter Iterator) (result []string) {
+	for iter.Next() {
+		result = append(result, fmt.Sprintf("%s%d", iter.Val().SortedField(), iter.Val().Time()))
+	}
+	require.NoError(t, iter.Close())
+	return result
+}
+
+func TestMergedIterator(t *testing.T) {
+	asc := newMergedIterator([]Iterator{blockOf("a", 1, 4, 6), blockOf("b", 2, 3, 7), blockOf("c")}, modelv1.Sort_SORT_ASC)
+	assert.Equal(t, []string{"a1", "b2", "b3", "a4", "a6", "b7"}, readAll(t, asc))
+	desc := newMergedIterator([]Iterator{blockOf("a", 6, 4, 1), blockOf("b", 7, 3, 2)}, modelv1.Sort_SORT_DESC)
+	assert.Equal(t, []string{"b7", "a6", "a4", "b3", "b2", "a1"}, readAll(t, desc))
+	// the items of the overlapped blocks are read once
+	overlapped := newMergedIterator([]Iterator{blockOf("a", 1, 2, 3), blockOf("b", 2, 3, 4)}, modelv1.Sort_SORT_ASC)
+	assert.Equal(t, []string{"a1", "a2", "a3", "b4"}, readAll(t, overlapped))
+}
+
+type sliceFieldIterator struct {
+	values []*index.PostingValue
+	index  int
+}
+
+func (s *sliceFieldIterator) Next() bool {
+	s.index++
+	return s.index < len(s.values)
+}
+
+func (s *sliceFieldIterator) Val() *index.PostingValue {
+	return s.values[s.index]
+}
+
+func (s *sliceFieldIterator) Close() error {
+	return nil
+}
+
+func TestSearcherIteratorStopsOnContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	fields := &sliceFieldIterator{index: -1, values: []*index.PostingValue{
+		{Term: []byte("a"), Value: roaring.NewPostingListWithInitialData(1, 2, 3)},
+		{Term: []byte("b"), Value: roaring.NewPostingListWithInitialData(4, 5)},
+	}}
+	iter := newSearcherIterator(ctx, logger.GetLogger("test"), fields, nil, 0, emptyFilters)
+	require.True(t, iter.Next())
+	assert.Equal(t, common.ItemID(1), iter.Val().ID())
+	require.True(t, iter.Next())
+	// the query times out in the middle of the scan
+	cancel()
+	assert.False(t, iter.Next())
+	require.NoError(t, iter.Close())
+}
@@ -165,6 +165,9 @@ type seriesDB struct {
 	segCtrl        *segmentController
 	seriesMetadata kv.Store
 	sID            common.ShardID
+	// series is the number of the series, which is counted by the first count and increased by the series created after it
+	series  int64
+	counted bool
 }
 
 func (s *seriesDB) GetByHashKey(key []byte) (Series, error) {
@@ -177,14 +180,40 @@ func (s *seriesDB) GetByHashKey(key []byte) (Series, error) {
 	}
 	s.Lock()
 	defer s.Unlock()
+	// the series might be created by another writer while waiting for the lock
+	if seriesID, err = s.seriesMetadata.Get(key); err == nil {
+		return newSeries(s.context(), bytesToSeriesID(seriesID), s), nil
+	}
 	seriesID = Hash(key)
 	err = s.seriesMetadata.Put(key, seriesID)
 	if err != nil {
 		return nil, err
 	}
+	if s.counted {
+		s.series++
+	}
 	return newSeries(s.context(), bytesToSeriesID(seriesID), s), nil
 }
 
+// count returns the number of the series. The series metadata are scanned once, which blocks the creation of new series.
+func (s *seriesDB) count() (int64, error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.counted {
+		return s.series, nil
+	}
+	iter := s.seriesMetadata.NewIterator(kv.ScanOpts{})
+	for iter.Rewind(); iter.Valid(); iter.Next() {
+		s.series++
+	}
+	if err := iter.Close(); err != nil {
+		s.series = 0
+		return 0, err
+	}
+	s.counted = true
+	return s.series, nil
+}
+
 func (s *seriesDB) GetByID(id common.SeriesID) (Series, error) {
 	return newSeries(s.context(), id, s), nil
 }
@@ -317,8 +346,10 @@ func newSeriesDataBase(ctx context.Context, shardID common.ShardID, path string,
 	}
 	o := ctx.Value(optionsKey)
 	var memSize int64
+	var durability kv.Durability
 	if o != nil {
 		options := o.(DatabaseOpts)
+		durability = options.Durability
 		if options.SeriesMemSize > 1 {
 			memSize = options.SeriesMemSize
 		} else {
@@ -331,6 +362,7 @@ func newSeriesDataBase(ctx context.Context, shardID common.ShardID, path string,
 	sdb.seriesMetadata, err = kv.OpenStore(0, path+"/md",
 		kv.StoreWithNamedLogger("metadata", sdb.l),
 		kv.StoreWithMemTableSize(memSize),
+		kv.StoreWithDurability(durability),
 	)
 	if err != nil {
 		return nil, err
@@ -19,6 +19,8 @@ package tsdb
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"sync"
@@ -30,6 +32,7 @@ import (
 
 	"github.com/apache/skywalking-banyandb/api/common"
 	"github.com/apache/skywalking-banyandb/banyand/tsdb/bucket"
+	"github.com/apache/skywalking-banyandb/pkg/index"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 	"github.com/apache/skywalking-banyandb/pkg/timestamp"
 )
@@ -46,12 +49,17 @@ type shard struct {
 	l        *logger.Logger
 	id       common.ShardID
 	position common.Position
+	path     string
 
 	seriesDatabase        SeriesDatabase
 	indexDatabase         IndexDatabase
 	segmentController     *segmentController
 	segmentManageStrategy *bucket.Strategy
 	scheduler             *timestamp.Scheduler
+	cardinality           *index.Cardinality
+	ingest                *ingestMeter
+	clock                 timestamp.Clock
+	timeRanges            *timeRanges
 
 	closeOnce sync.Once
 }
@@ -63,6 +71,9 @@ func OpenShard(ctx context.Context, id common.ShardID,
 	if err != nil {
 		return nil, errors.Wrapf(err, "make the directory of the shard %d ", int(id))
 	}
+	if err = os.RemoveAll(filepath.Join(path, exportDir)); err != nil {
+		return nil, errors.Wrapf(err, "remove the exported blocks of the shard %d", int(id))
+	}
 	l := logger.Fetch(ctx, "shard"+strconv.Itoa(int(id)))
 	l.Info().Int("shard_id", int(id)).Str("path", path).Msg("creating a shard")
 	if openedBlockSize < 1 {
@@ -73,17 +84,29 @@ func OpenShard(ctx context.Context, id common.ShardID,
 		p.Shard = strconv.Itoa(int(id))
 		return p
 	})
+	cardinality := index.NewCardinality()
+	shardCtx = context.WithValue(shardCtx, cardinalityKey, cardinality)
 	clock, _ := timestamp.GetClock(shardCtx)
+	ingest := newIngestMeter(clock)
+	shardCtx = context.WithValue(shardCtx, ingestMeterKey, ingest)
 	scheduler := timestamp.NewScheduler(l, clock)
-	sc, err := newSegmentController(shardCtx, path, segmentSize, blockSize, openedBlockSize, maxOpenedBlockSize, l, scheduler)
+	zone := time.Local
+	if o, ok := ctx.Value(optionsKey).(DatabaseOpts); ok && o.TimeZone != nil {
+		zone = o.TimeZone
+	}
+	sc, err := newSegmentController(shardCtx, path, segmentSize, blockSize, zone, openedBlockSize, maxOpenedBlockSize, l, scheduler)
 	if err != nil {
 		return nil, errors.Wrapf(err, "create the segment controller of the shard %d", int(id))
 	}
 	s := &shard{
 		id:                id,
+		path:              path,
 		segmentController: sc,
 		l:                 l,
 		scheduler:         scheduler,
+		cardinality:       cardinality,
+		ingest:            ingest,
+		clock:             clock,
 	}
 	err = s.segmentController.open()
 	if err != nil {
@@ -115,10 +138,35 @@ func OpenShard(ctx context.Context, id common.ShardID,
 	if err := scheduler.Register("stat", cron.Descriptor, "@every 5s", s.stat); err != nil {
 		return nil, err
 	}
-	retentionTask := newRetentionTask(s.segmentController, ttl)
+	retentionTask := newRetentionTask(s.segmentController, ttl, zone)
+	if tr, ok := ctx.Value(timeRangesKey).(*timeRanges); ok {
+		retentionTask.timeRanges = tr
+		s.timeRanges = tr
+	}
 	if err := scheduler.Register("retention", retentionTask.option, retentionTask.expr, retentionTask.run); err != nil {
 		return nil, err
 	}
+	if err := scheduler.Register("manifest", cron.Descriptor, manifestInterval, (&manifestTask{shard: s}).run); err != nil {
+		return nil, err
+	}
+	if o, ok := ctx.Value(optionsKey).(DatabaseOpts); ok && o.IdleTimeout > 0 {
+		idleTask := newIdleTask(s.segmentController, o.IdleTimeout)
+		if err := scheduler.Register("idle", cron.Descriptor, idleCheckInterval, idleTask.run); err != nil {
+			return nil, err
+		}
+	}
+	if o, ok := ctx.Value(optionsKey).(DatabaseOpts); ok && o.FlushInterval > 0 {
+		flushTask := newFlushTask(s.segmentController, o.FlushInterval)
+		if err := scheduler.Register("flush", cron.Descriptor, flushTask.expr(), flushTask.run); err != nil {
+			return nil, err
+		}
+	}
+	if o, ok := ctx.Value(optionsKey).(DatabaseOpts); ok && o.BlockMergeSize > 0 {
+		mergeTask := newMergeTask(s.segmentController, o.BlockMergeSize)
+		if err := scheduler.Register("merge", cron.Descriptor, mergeCheckInterval, mergeTask.run); err != nil {
+			return nil, err
+		}
+	}
 	return s, nil
 }
 
@@ -168,6 +216,25 @@ func (s *shard) State() (shardState ShardState) {
 	return shardState
 }
 
+func (s *shard) Cardinality() *index.Cardinality {
+	return s.cardinality
+}
+
+func (s *shard) Rollover(ctx context.Context) (closed []string, err error) {
+	for _, seg := range s.segmentController.segments() {
+		for _, b := range seg.blockController.blocks() {
+			if b.Closed() {
+				continue
+			}
+			if err = b.rollover(ctx); err != nil {
+				return closed, errors.WithMessagef(err, "the in-flight reads and writes of %s don't drain", b)
+			}
+			closed = append(closed, b.String())
+		}
+	}
+	return closed, nil
+}
+
 func (s *shard) TriggerSchedule(task string) bool {
 	return s.scheduler.Trigger(task)
 }
@@ -188,6 +255,8 @@ type IntervalUnit int
 const (
 	HOUR IntervalUnit = iota
 	DAY
+	// MONTH is a calendar month, which is only allowed by the TTL
+	MONTH
 )
 
 func (iu IntervalUnit) String() string {
@@ -196,6 +265,8 @@ func (iu IntervalUnit) String() string {
 		return "hour"
 	case DAY:
 		return "day"
+	case MONTH:
+		return "month"
 	}
 	panic("invalid interval unit")
 }
@@ -211,6 +282,8 @@ func (ir IntervalRule) NextTime(current time.Time) time.Time {
 		return current.Add(time.Hour * time.Duration(ir.Num))
 	case DAY:
 		return current.AddDate(0, 0, ir.Num)
+	case MONTH:
+		return current.AddDate(0, ir.Num, 0)
 	}
 	panic("invalid interval unit")
 }
@@ -221,6 +294,8 @@ func (ir IntervalRule) PreviousTime(current time.Time) time.Time {
 		return current.Add(-time.Hour * time.Duration(ir.Num))
 	case DAY:
 		return current.AddDate(0, 0, -ir.Num)
+	case MONTH:
+		return current.AddDate(0, -ir.Num, 0)
 	}
 	panic("invalid interval unit")
 }
@@ -231,6 +306,8 @@ func (ir IntervalRule) EstimatedDuration() time.Duration {
 		return time.Hour * time.Duration(ir.Num)
 	case DAY:
 		return 24 * time.Hour * time.Duration(ir.Num)
+	case MONTH:
+		return 30 * 24 * time.Hour * time.Duration(ir.Num)
 	}
 	panic("invalid interval unit")
 }
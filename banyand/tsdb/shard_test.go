@@ -588,5 +588,49 @@ var _ = Describe("Shard", func() {
 				return shard.State().OpenBlocks
 			}, flags.EventuallyTimeout).Should(Equal([]tsdb.BlockID{}))
 		})
+		It("rolls over the open blocks", func() {
+			var err error
+			shard, err = tsdb.OpenShard(timestamp.SetClock(context.Background(), clock), common.ShardID(0), tmp,
+				tsdb.IntervalRule{
+					Unit: tsdb.DAY,
+					Num:  1,
+				},
+				tsdb.IntervalRule{
+					Unit: tsdb.HOUR,
+					Num:  12,
+				},
+				tsdb.IntervalRule{
+					Unit: tsdb.DAY,
+					Num:  7,
+				},
+				2,
+				3,
+			)
+			Expect(err).NotTo(HaveOccurred())
+			t1 := clock.Now()
+			Eventually(func() []tsdb.BlockState {
+				return shard.State().Blocks
+			}, flags.EventuallyTimeout).Should(Equal([]tsdb.BlockState{
+				{
+					ID: tsdb.BlockID{
+						SegID:   tsdb.GenerateInternalID(tsdb.DAY, 19700101),
+						BlockID: tsdb.GenerateInternalID(tsdb.HOUR, 0),
+					},
+					TimeRange: timestamp.NewTimeRangeDuration(t1, 12*time.Hour, true, false),
+				},
+			}))
+			ctx, cancel := context.WithTimeout(context.Background(), flags.EventuallyTimeout)
+			defer cancel()
+			closed, err := shard.Rollover(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(closed).To(HaveLen(1))
+			Expect(shard.State().Blocks).To(HaveLen(1))
+			Expect(shard.State().Blocks[0].Closed).To(BeTrue())
+			Expect(shard.State().OpenBlocks).To(BeEmpty())
+			By("the closed blocks are skipped")
+			closed, err = shard.Rollover(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(closed).To(BeEmpty())
+		})
 	})
 })
@@ -0,0 +1,121 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tsdb
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var timeRangesKey = contextTimeRangesKey{}
+
+type contextTimeRangesKey struct{}
+
+// DataTimeRange is the earliest and the latest timestamps of the data written to a resource.
+type DataTimeRange struct {
+	Earliest time.Time
+	Latest   time.Time
+}
+
+// timeRanges tracks the data time ranges of the resources of a database.
+// They are extended by the writes, truncated by the retention, and persisted once the database is closed.
+type timeRanges struct {
+	sync.RWMutex
+	path   string
+	ranges map[string]*DataTimeRange
+}
+
+func openTimeRanges(path string) (*timeRanges, error) {
+	tr := &timeRanges{
+		path:   path,
+		ranges: make(map[string]*DataTimeRange),
+	}
+	bb, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return tr, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(bb, &tr.ranges); err != nil {
+		return nil, errors.WithMessagef(err, "failed to parse %s", path)
+	}
+	return tr, nil
+}
+
+func (tr *timeRanges) observe(resource string, ts time.Time) {
+	tr.RLock()
+	r, ok := tr.ranges[resource]
+	if ok && !ts.Before(r.Earliest) && !ts.After(r.Latest) {
+		tr.RUnlock()
+		return
+	}
+	tr.RUnlock()
+	tr.Lock()
+	defer tr.Unlock()
+	r, ok = tr.ranges[resource]
+	if !ok {
+		tr.ranges[resource] = &DataTimeRange{Earliest: ts, Latest: ts}
+		return
+	}
+	if ts.Before(r.Earliest) {
+		r.Earliest = ts
+	}
+	if ts.After(r.Latest) {
+		r.Latest = ts
+	}
+}
+
+// truncate drops the data before the deadline, which are removed by the retention.
+func (tr *timeRanges) truncate(deadline time.Time) {
+	tr.Lock()
+	defer tr.Unlock()
+	for resource, r := range tr.ranges {
+		if r.Latest.Before(deadline) {
+			delete(tr.ranges, resource)
+			continue
+		}
+		if r.Earliest.Before(deadline) {
+			r.Earliest = deadline
+		}
+	}
+}
+
+func (tr *timeRanges) get() map[string]DataTimeRange {
+	tr.RLock()
+	defer tr.RUnlock()
+	result := make(map[string]DataTimeRange, len(tr.ranges))
+	for resource, r := range tr.ranges {
+		result[resource] = *r
+	}
+	return result
+}
+
+func (tr *timeRanges) save() error {
+	tr.RLock()
+	bb, err := json.Marshal(tr.ranges)
+	tr.RUnlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(tr.path, bb, 0o600)
+}
@@ -31,8 +31,12 @@ import (
 	"go.uber.org/multierr"
 
 	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/banyand/kv"
+	"github.com/apache/skywalking-banyandb/pkg/cache"
 	"github.com/apache/skywalking-banyandb/pkg/encoding"
+	"github.com/apache/skywalking-banyandb/pkg/index"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/throttle"
 	"github.com/apache/skywalking-banyandb/pkg/timestamp"
 )
 
@@ -47,6 +51,8 @@ const (
 	blockPathPrefix     = "block"
 	blockTemplate       = rootPrefix + blockPathPrefix + "-%s"
 	globalIndexTemplate = rootPrefix + "index"
+	timeRangesTemplate  = rootPrefix + "time-ranges.json"
+	manifestTemplate    = rootPrefix + "manifest.json"
 
 	segHourFormat   = "2006010215"
 	segDayFormat    = "20060102"
@@ -60,10 +66,14 @@ var (
 	ErrInvalidShardID = errors.New("invalid shard id")
 	ErrOpenDatabase   = errors.New("fails to open the database")
 
-	optionsKey = contextOptionsKey{}
+	optionsKey     = contextOptionsKey{}
+	cardinalityKey = contextCardinalityKey{}
 )
 
-type contextOptionsKey struct{}
+type (
+	contextOptionsKey     struct{}
+	contextCardinalityKey struct{}
+)
 
 type Supplier interface {
 	SupplyTSDB() Database
@@ -72,6 +82,10 @@ type Database interface {
 	io.Closer
 	Shards() []Shard
 	Shard(id common.ShardID) (Shard, error)
+	// ObserveWrite extends the data time range of the resource by the timestamp of a write
+	ObserveWrite(resource string, ts time.Time)
+	// TimeRanges returns the data time ranges of the resources keyed by their names
+	TimeRanges() map[string]DataTimeRange
 }
 
 type Shard interface {
@@ -80,6 +94,17 @@ type Shard interface {
 	Series() SeriesDatabase
 	Index() IndexDatabase
 	State() ShardState
+	// Cardinality returns the statistics of the local index rules in the shard
+	Cardinality() *index.Cardinality
+	// Usage returns the disk bytes, the series, the blocks and the ingest rate of the shard and its segments
+	Usage() (ShardUsage, error)
+	// Manifests refreshes the manifests of the segments, which are sorted by their begin times
+	Manifests() ([]SegmentManifest, error)
+	// ExportBlock takes a snapshot of the files of the sealed block in the segment, which should be released once they're copied
+	ExportBlock(segment, block string) (*BlockExport, error)
+	// Rollover closes the open blocks once their in-flight reads and writes drain, so that their memory tables are flushed.
+	// The blocks are reopened by the next reads or writes. It returns the names of the closed blocks.
+	Rollover(ctx context.Context) ([]string, error)
 	// Only works with MockClock
 	TriggerSchedule(task string) bool
 }
@@ -97,6 +122,28 @@ type DatabaseOpts struct {
 	SeriesMemSize      int64
 	EnableGlobalIndex  bool
 	GlobalIndexMemSize int64
+	// BackgroundThrottle limits the reads of the background jobs: merging the blocks, backfilling the indices and exporting the blocks.
+	// The flushes of the memory tables are never throttled so that the writes are not stalled.
+	BackgroundThrottle *throttle.Throttle
+	// BlockCache holds the values read from the blocks, which is shared by all the databases of a service
+	BlockCache *cache.Cache
+	// Durability is the fsync policy of the data and the series metadata
+	Durability kv.Durability
+	// TimeZone is the time zone the segments and the blocks align to, e.g. the local midnight of a daily segment,
+	// which is the local one of the node if it's nil
+	TimeZone *time.Location
+	// IdleTimeout closes the blocks not written for the period to free their memory, 0 disables it
+	IdleTimeout time.Duration
+	// MaxBufferedElements flushes a block once the elements written since its last flush reach the number, 0 disables it
+	MaxBufferedElements int64
+	// FlushInterval flushes the elements buffered by the blocks at least once per the interval, 0 disables it
+	FlushInterval time.Duration
+	// BlockMergeSize is the target size in bytes of the blocks merged from the small adjacent ones, 0 disables the merging
+	BlockMergeSize int64
+	// RecoveryConcurrency is the number of the shards opened in parallel, 1 opens them one by one
+	RecoveryConcurrency int
+	// Recovery tracks the progress of opening the shards, which is shared by all the databases of a service
+	Recovery *Recovery
 }
 
 type EncodingMethod struct {
@@ -139,6 +186,9 @@ type database struct {
 	segmentSize IntervalRule
 	blockSize   IntervalRule
 	ttl         IntervalRule
+	concurrency int
+	recovery    *Recovery
+	timeRanges  *timeRanges
 
 	sLst []Shard
 	sync.Mutex
@@ -155,6 +205,14 @@ func (d *database) Shard(id common.ShardID) (Shard, error) {
 	return d.sLst[id], nil
 }
 
+func (d *database) ObserveWrite(resource string, ts time.Time) {
+	d.timeRanges.observe(resource, ts)
+}
+
+func (d *database) TimeRanges() map[string]DataTimeRange {
+	return d.timeRanges.get()
+}
+
 func (d *database) Close() error {
 	var err error
 	for _, s := range d.sLst {
@@ -163,7 +221,7 @@ func (d *database) Close() error {
 			err = multierr.Append(err, innerErr)
 		}
 	}
-	return err
+	return multierr.Append(err, d.timeRanges.save())
 }
 
 func OpenDatabase(ctx context.Context, opts DatabaseOpts) (Database, error) {
@@ -179,6 +237,9 @@ func OpenDatabase(ctx context.Context, opts DatabaseOpts) (Database, error) {
 	if opts.BlockInterval.Num == 0 {
 		return nil, errors.Wrap(ErrOpenDatabase, "block interval is absent")
 	}
+	if opts.SegmentInterval.Unit == MONTH || opts.BlockInterval.Unit == MONTH {
+		return nil, errors.Wrap(ErrOpenDatabase, "the segment and block intervals can't be in months, which are only allowed by the ttl")
+	}
 	if opts.BlockInterval.EstimatedDuration() > opts.SegmentInterval.EstimatedDuration() {
 		return nil, errors.Wrapf(ErrOpenDatabase, "the block size is bigger than the segment size")
 	}
@@ -195,6 +256,11 @@ func OpenDatabase(ctx context.Context, opts DatabaseOpts) (Database, error) {
 		segmentSize: opts.SegmentInterval,
 		blockSize:   opts.BlockInterval,
 		ttl:         opts.TTL,
+		concurrency: opts.RecoveryConcurrency,
+		recovery:    opts.Recovery,
+	}
+	if db.concurrency < 1 {
+		db.concurrency = 1
 	}
 	db.logger.Info().Str("path", opts.Location).Msg("initialized")
 	var entries []os.DirEntry
@@ -202,8 +268,12 @@ func OpenDatabase(ctx context.Context, opts DatabaseOpts) (Database, error) {
 	if entries, err = os.ReadDir(opts.Location); err != nil {
 		return nil, errors.Wrap(err, "failed to read directory contents failed")
 	}
+	if db.timeRanges, err = openTimeRanges(fmt.Sprintf(timeRangesTemplate, opts.Location)); err != nil {
+		return nil, errors.WithMessage(err, "load the time ranges of the data failed")
+	}
 	thisContext := context.WithValue(ctx, logger.ContextKey, db.logger)
 	thisContext = context.WithValue(thisContext, optionsKey, opts)
+	thisContext = context.WithValue(thisContext, timeRangesKey, db.timeRanges)
 	if len(entries) > 0 {
 		return loadDatabase(thisContext, db)
 	}
@@ -213,22 +283,10 @@ func OpenDatabase(ctx context.Context, opts DatabaseOpts) (Database, error) {
 func initDatabase(ctx context.Context, db *database) (Database, error) {
 	db.Lock()
 	defer db.Unlock()
-	return createDatabase(ctx, db, 0)
-}
-
-func createDatabase(ctx context.Context, db *database, startID int) (Database, error) {
-	var err error
-	for i := startID; i < int(db.shardNum); i++ {
-		db.logger.Info().Int("shard_id", i).Msg("creating a shard")
-		so, errNewShard := OpenShard(ctx, common.ShardID(i),
-			db.location, db.segmentSize, db.blockSize, db.ttl, defaultBlockQueueSize, defaultMaxBlockQueueSize)
-		if errNewShard != nil {
-			err = multierr.Append(err, errNewShard)
-			continue
-		}
-		db.sLst = append(db.sLst, so)
+	if err := openShards(ctx, db, nil); err != nil {
+		return nil, errors.WithMessage(err, "create the database failed")
 	}
-	return db, err
+	return db, nil
 }
 
 func loadDatabase(ctx context.Context, db *database) (Database, error) {
@@ -236,44 +294,67 @@ func loadDatabase(ctx context.Context, db *database) (Database, error) {
 	// TODO: open the manifest file
 	db.Lock()
 	defer db.Unlock()
+	existing := make(map[int]bool)
 	err := WalkDir(db.location, shardPathPrefix, func(suffix, _ string) error {
 		shardID, err := strconv.Atoi(suffix)
 		if err != nil {
 			return err
 		}
-		if shardID >= int(db.shardNum) {
-			return nil
-		}
-		db.logger.Info().Int("shard_id", shardID).Msg("opening a existing shard")
-		so, errOpenShard := OpenShard(
-			context.WithValue(ctx, logger.ContextKey, db.logger),
-			common.ShardID(shardID),
-			db.location,
-			db.segmentSize,
-			db.blockSize,
-			db.ttl,
-			defaultBlockQueueSize,
-			defaultMaxBlockQueueSize,
-		)
-		if errOpenShard != nil {
-			return errOpenShard
-		}
-		db.sLst = append(db.sLst, so)
+		existing[shardID] = true
 		return nil
 	})
 	if err != nil {
 		return nil, errors.WithMessage(err, "load the database failed")
 	}
+	if err = openShards(ctx, db, existing); err != nil {
+		return nil, errors.WithMessage(err, "load the database failed")
+	}
+	return db, nil
+}
 
-	loadedShardsNum := len(db.sLst)
-	if loadedShardsNum < int(db.shardNum) {
-		_, err := createDatabase(ctx, db, loadedShardsNum)
-		if err != nil {
-			return nil, errors.WithMessage(err, "load the database failed")
+// openShards opens the existing shards and creates the absent ones, whose number is bounded by the concurrency.
+// The opened shards are closed if any of them fails.
+func openShards(ctx context.Context, db *database, existing map[int]bool) error {
+	db.recovery.add(int(db.shardNum))
+	shards := make([]Shard, db.shardNum)
+	errs := make([]error, db.shardNum)
+	semaphore := make(chan struct{}, db.concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < int(db.shardNum); i++ {
+		if existing[i] {
+			db.logger.Info().Int("shard_id", i).Msg("opening a existing shard")
+		} else {
+			db.logger.Info().Int("shard_id", i).Msg("creating a shard")
 		}
-
+		semaphore <- struct{}{}
+		wg.Add(1)
+		go func(id int) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			so, err := OpenShard(ctx, common.ShardID(id),
+				db.location, db.segmentSize, db.blockSize, db.ttl, defaultBlockQueueSize, defaultMaxBlockQueueSize)
+			if err != nil {
+				errs[id] = err
+				db.recovery.opened(0)
+				return
+			}
+			shards[id] = so
+			db.recovery.opened(len(so.(*shard).segmentController.segments()))
+		}(i)
 	}
-	return db, nil
+	wg.Wait()
+	if err := multierr.Combine(errs...); err != nil {
+		for _, s := range shards {
+			if s != nil {
+				_ = s.Close()
+			}
+		}
+		return err
+	}
+	db.sLst = shards
+	return nil
 }
 
 type WalkFn func(suffix, absolutePath string) error
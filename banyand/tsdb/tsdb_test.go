@@ -19,15 +19,19 @@ package tsdb
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/apache/skywalking-banyandb/api/common"
 	"github.com/apache/skywalking-banyandb/pkg/encoding"
+	"github.com/apache/skywalking-banyandb/pkg/index"
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 	"github.com/apache/skywalking-banyandb/pkg/test"
 	"github.com/apache/skywalking-banyandb/pkg/test/flags"
@@ -46,6 +50,28 @@ func TestOpenDatabase(t *testing.T) {
 	verifyDatabaseStructure(tester, tempDir, time.Now())
 }
 
+func TestOpenDatabaseMonthlyInterval(t *testing.T) {
+	req := require.New(t)
+	tempDir, deferFunc := test.Space(req)
+	defer deferFunc()
+	opts := DatabaseOpts{
+		Location: tempDir,
+		ShardNum: 1,
+		EncodingMethod: EncodingMethod{
+			EncoderPool: encoding.NewPlainEncoderPool("tsdb", 0),
+			DecoderPool: encoding.NewPlainDecoderPool("tsdb", 0),
+		},
+		BlockInterval:   IntervalRule{Num: 1, Unit: MONTH},
+		SegmentInterval: IntervalRule{Num: 1, Unit: MONTH},
+		TTL:             IntervalRule{Num: 1, Unit: MONTH},
+	}
+	_, err := OpenDatabase(context.Background(), opts)
+	req.ErrorIs(err, ErrOpenDatabase)
+	opts.SegmentInterval = IntervalRule{Num: 1, Unit: DAY}
+	_, err = OpenDatabase(context.Background(), opts)
+	req.ErrorIs(err, ErrOpenDatabase)
+}
+
 func TestReOpenDatabase(t *testing.T) {
 	tester := assert.New(t)
 	req := require.New(t)
@@ -91,6 +117,342 @@ func TestReOpenDatabaseNextDay(t *testing.T) {
 	verifyDatabaseStructure(tester, tempDir, clock.Now())
 }
 
+func TestRecoverShardsInParallel(t *testing.T) {
+	req := require.New(t)
+	tempDir, deferFunc := test.Space(req)
+	defer deferFunc()
+	req.NoError(logger.Init(logger.Logging{
+		Env:   "dev",
+		Level: flags.LogLevel,
+	}))
+	opts := DatabaseOpts{
+		Location: tempDir,
+		ShardNum: 12,
+		EncodingMethod: EncodingMethod{
+			EncoderPool: encoding.NewPlainEncoderPool("tsdb", 0),
+			DecoderPool: encoding.NewPlainDecoderPool("tsdb", 0),
+		},
+		BlockInterval:       IntervalRule{Num: 2},
+		SegmentInterval:     IntervalRule{Num: 1, Unit: DAY},
+		TTL:                 IntervalRule{Num: 7, Unit: DAY},
+		RecoveryConcurrency: 4,
+	}
+	ctx := context.WithValue(context.Background(), logger.ContextKey, logger.GetLogger("test"))
+	db, err := OpenDatabase(ctx, opts)
+	req.NoError(err)
+	req.NoError(db.Close())
+
+	opts.Recovery = NewRecovery(logger.GetLogger("test"))
+	req.True(opts.Recovery.Progress().Done())
+	db, err = OpenDatabase(ctx, opts)
+	req.NoError(err)
+	defer func() {
+		req.NoError(db.Close())
+	}()
+	// the shards are listed by their ids though the directories of the ones above 10 are walked before shard-2
+	for i, s := range db.Shards() {
+		req.Equal(common.ShardID(i), s.ID())
+	}
+	progress := opts.Recovery.Progress()
+	req.True(progress.Done())
+	req.Equal(12, progress.Shards)
+	req.Equal(12, progress.OpenedShards)
+	req.Equal(12, progress.Segments)
+	req.Zero(progress.ETA)
+	req.False(progress.StartedAt.IsZero())
+}
+
+func TestDataTimeRanges(t *testing.T) {
+	tester := assert.New(t)
+	req := require.New(t)
+	tempDir, deferFunc := test.Space(req)
+	defer deferFunc()
+	clock := timestamp.NewMockClock()
+	clock.Set(time.Date(1970, 0o1, 8, 0, 0, 0, 0, time.Local))
+	ctx := timestamp.SetClock(context.Background(), clock)
+	// the retention runs at 00:05 every day, which removes the data written 7 days ago
+	deadline := clock.Now().Add(5*time.Minute - 7*24*time.Hour)
+	db := openDatabase(ctx, req, tempDir)
+	db.ObserveWrite("sw1", deadline.Add(time.Hour))
+	db.ObserveWrite("sw1", deadline.Add(-time.Hour))
+	db.ObserveWrite("sw2", deadline.Add(-30*time.Minute))
+	db.ObserveWrite("sw1", deadline)
+	verify := func(expected map[string]DataTimeRange) {
+		ranges := db.TimeRanges()
+		tester.Len(ranges, len(expected))
+		for name, r := range expected {
+			tester.True(r.Earliest.Equal(ranges[name].Earliest), "the earliest of %s is %s", name, ranges[name].Earliest)
+			tester.True(r.Latest.Equal(ranges[name].Latest), "the latest of %s is %s", name, ranges[name].Latest)
+		}
+	}
+	expected := map[string]DataTimeRange{
+		"sw1": {Earliest: deadline.Add(-time.Hour), Latest: deadline.Add(time.Hour)},
+		"sw2": {Earliest: deadline.Add(-30 * time.Minute), Latest: deadline.Add(-30 * time.Minute)},
+	}
+	verify(expected)
+	req.NoError(db.Close())
+
+	db = openDatabase(ctx, req, tempDir)
+	defer db.Close()
+	verify(expected)
+
+	clock.Add(10 * time.Minute)
+	req.Eventually(func() bool {
+		return db.Shards()[0].TriggerSchedule("retention") && len(db.TimeRanges()) == 1
+	}, flags.EventuallyTimeout, time.Millisecond)
+	verify(map[string]DataTimeRange{
+		"sw1": {Earliest: deadline, Latest: deadline.Add(time.Hour)},
+	})
+}
+
+func TestSegmentManifests(t *testing.T) {
+	req := require.New(t)
+	tempDir, deferFunc := test.Space(req)
+	defer deferFunc()
+	clock := timestamp.NewMockClock()
+	clock.Set(time.Date(1970, 0o1, 0o1, 0, 0, 0, 0, time.Local))
+	ctx := timestamp.SetClock(context.Background(), clock)
+	db := openDatabase(ctx, req, tempDir)
+	defer db.Close()
+	s := db.Shards()[0]
+	req.Eventually(func() bool {
+		return len(s.State().Blocks) == 1
+	}, flags.EventuallyTimeout, time.Millisecond)
+	seg := s.(*shard).segmentController.segments()[0]
+	b := seg.blockController.blocks()[0]
+	d, err := b.delegate(ctx)
+	req.NoError(err)
+	req.NoError(d.write([]byte("key"), []byte("val"), b.Start.Add(time.Millisecond)))
+	req.NoError(d.Close())
+	db.ObserveWrite("sw", b.Start.Add(time.Millisecond))
+	db.ObserveWrite("sw-before", b.Start.Add(-time.Hour))
+
+	manifests, err := s.Manifests()
+	req.NoError(err)
+	req.Len(manifests, 1)
+	m := manifests[0]
+	assert.Equal(t, seg.suffix, m.Segment)
+	assert.Equal(t, []string{"sw"}, m.Resources)
+	req.Len(m.Blocks, 1)
+	assert.False(t, m.Blocks[0].Sealed, "the block is open")
+	assert.Empty(t, m.Blocks[0].Checksum)
+	assert.Empty(t, m.Checksum)
+
+	clock.Add(3 * time.Hour)
+	ctxClose, cancel := context.WithTimeout(context.Background(), flags.EventuallyTimeout)
+	defer cancel()
+	req.NoError(b.rollover(ctxClose))
+	manifests, err = s.Manifests()
+	req.NoError(err)
+	sealed := manifests[0].Blocks[0]
+	assert.True(t, sealed.Sealed)
+	assert.Len(t, sealed.Checksum, 64)
+	assert.Positive(t, sealed.DiskBytes)
+
+	bb, err := os.ReadFile(fmt.Sprintf(manifestTemplate, seg.path))
+	req.NoError(err)
+	var written SegmentManifest
+	req.NoError(json.Unmarshal(bb, &written))
+	assert.Equal(t, sealed.Checksum, written.Blocks[0].Checksum, "the manifest is written to the segment directory")
+	manifests, err = s.Manifests()
+	req.NoError(err)
+	assert.Equal(t, sealed.Checksum, manifests[0].Blocks[0].Checksum, "the checksum is stable")
+}
+
+func TestCloseIdleBlocks(t *testing.T) {
+	req := require.New(t)
+	tempDir, deferFunc := test.Space(req)
+	defer deferFunc()
+	req.NoError(logger.Init(logger.Logging{
+		Env:   "dev",
+		Level: flags.LogLevel,
+	}))
+	clock := timestamp.NewMockClock()
+	clock.Set(time.Date(1970, 0o1, 0o1, 0, 0, 0, 0, time.Local))
+	ctx := timestamp.SetClock(context.WithValue(context.Background(), logger.ContextKey, logger.GetLogger("test")), clock)
+	ctx = context.WithValue(ctx, optionsKey, DatabaseOpts{IdleTimeout: time.Hour})
+	s, err := OpenShard(ctx, 0, tempDir, IntervalRule{Unit: DAY, Num: 1}, IntervalRule{Unit: HOUR, Num: 12},
+		IntervalRule{Unit: DAY, Num: 7}, 2, 3)
+	req.NoError(err)
+	defer s.Close()
+	req.Eventually(func() bool {
+		return len(s.State().Blocks) == 1
+	}, flags.EventuallyTimeout, time.Millisecond)
+	b := s.(*shard).segmentController.segments()[0].blockController.blocks()[0]
+	write := func() {
+		d, errDelegate := b.delegate(ctx)
+		req.NoError(errDelegate)
+		req.NoError(d.write([]byte("key"), []byte("val"), b.Start.Add(time.Millisecond)))
+		req.NoError(d.Close())
+	}
+	idle := func(d time.Duration) {
+		clock.Add(d)
+		req.Eventually(func() bool {
+			return s.TriggerSchedule("idle")
+		}, flags.EventuallyTimeout, time.Millisecond)
+	}
+	write()
+	idle(30 * time.Minute)
+	write()
+	idle(40 * time.Minute)
+	assert.False(t, b.Closed(), "the block is written during the quiet period")
+	idle(30 * time.Minute)
+	assert.True(t, b.Closed())
+	assert.Empty(t, s.State().OpenBlocks)
+
+	write()
+	assert.False(t, b.Closed(), "the idle block is reopened by the writes")
+}
+
+func TestFlushBlocks(t *testing.T) {
+	req := require.New(t)
+	tempDir, deferFunc := test.Space(req)
+	defer deferFunc()
+	req.NoError(logger.Init(logger.Logging{
+		Env:   "dev",
+		Level: flags.LogLevel,
+	}))
+	clock := timestamp.NewMockClock()
+	clock.Set(time.Date(1970, 0o1, 0o1, 0, 0, 0, 0, time.Local))
+	ctx := timestamp.SetClock(context.WithValue(context.Background(), logger.ContextKey, logger.GetLogger("test")), clock)
+	ctx = context.WithValue(ctx, optionsKey, DatabaseOpts{FlushInterval: time.Minute, MaxBufferedElements: 3})
+	s, err := OpenShard(ctx, 0, tempDir, IntervalRule{Unit: DAY, Num: 1}, IntervalRule{Unit: HOUR, Num: 12},
+		IntervalRule{Unit: DAY, Num: 7}, 2, 3)
+	req.NoError(err)
+	defer s.Close()
+	req.Eventually(func() bool {
+		return len(s.State().Blocks) == 1
+	}, flags.EventuallyTimeout, time.Millisecond)
+	b := s.(*shard).segmentController.segments()[0].blockController.blocks()[0]
+	write := func(id common.ItemID) {
+		d, errDelegate := b.delegate(ctx)
+		req.NoError(errDelegate)
+		req.NoError(d.write([]byte("key"), []byte("val"), b.Start.Add(time.Duration(id)*time.Millisecond)))
+		req.NoError(d.writePrimaryIndex(index.Field{Key: index.FieldKey{SeriesID: 1}, Term: []byte("term")}, id))
+		req.NoError(d.Close())
+	}
+	flush := func() {
+		clock.Add(time.Minute)
+		req.Eventually(func() bool {
+			return s.TriggerSchedule("flush")
+		}, flags.EventuallyTimeout, time.Millisecond)
+	}
+	flushes := b.flushes.WithLabelValues(flushReasonInterval)
+	flushed := testutil.ToFloat64(flushes)
+	flush()
+	assert.False(t, b.Closed(), "the block buffering nothing isn't flushed")
+
+	write(1)
+	flush()
+	req.Eventually(func() bool {
+		return testutil.ToFloat64(flushes) == flushed+1
+	}, flags.EventuallyTimeout, time.Millisecond, "the buffered elements are flushed by the interval")
+	assert.True(t, b.Closed())
+
+	write(2)
+	write(3)
+	assert.False(t, b.Closed())
+	write(4)
+	req.Eventually(b.Closed, flags.EventuallyTimeout, time.Millisecond,
+		"the block is flushed once the buffered elements reach the limit")
+	write(5)
+	assert.EqualValues(t, 1, b.buffered.Load(), "the elements are counted again since the flush")
+}
+
+func TestMergeSmallBlocks(t *testing.T) {
+	req := require.New(t)
+	tempDir, deferFunc := test.Space(req)
+	defer deferFunc()
+	req.NoError(logger.Init(logger.Logging{
+		Env:   "dev",
+		Level: flags.LogLevel,
+	}))
+	clock := timestamp.NewMockClock()
+	clock.Set(time.Date(1970, 0o1, 0o1, 0, 0, 0, 0, time.Local))
+	ctx := timestamp.SetClock(context.WithValue(context.Background(), logger.ContextKey, logger.GetLogger("test")), clock)
+	ctx = context.WithValue(ctx, optionsKey, DatabaseOpts{
+		EncodingMethod: EncodingMethod{
+			EncoderPool: encoding.NewPlainEncoderPool("tsdb", 0),
+			DecoderPool: encoding.NewPlainDecoderPool("tsdb", 0),
+		},
+	})
+	open := func() (Shard, *blockController) {
+		s, err := OpenShard(ctx, 0, tempDir, IntervalRule{Unit: DAY, Num: 1}, IntervalRule{Unit: HOUR, Num: 1},
+			IntervalRule{Unit: DAY, Num: 7}, 2, 3)
+		req.NoError(err)
+		req.Eventually(func() bool {
+			return len(s.State().Blocks) > 0
+		}, flags.EventuallyTimeout, time.Millisecond)
+		return s, s.(*shard).segmentController.segments()[0].blockController
+	}
+	s, bc := open()
+	field := func(i int) index.Field {
+		return index.Field{Key: index.FieldKey{SeriesID: 1, IndexRuleID: 1}, Term: []byte(fmt.Sprintf("term-%d", i))}
+	}
+	var ids []uint16
+	for i := 0; i < 3; i++ {
+		b, err := bc.create(clock.Now().Add(time.Duration(i) * time.Hour))
+		req.NoError(err)
+		ids = append(ids, b.blockID)
+		d, err := b.delegate(ctx)
+		req.NoError(err)
+		ts := b.Start.Add(time.Millisecond)
+		req.NoError(d.write([]byte("key"), []byte(fmt.Sprintf("val-%d", i)), ts))
+		req.NoError(d.writeLSMIndex([]index.Field{field(i)}, common.ItemID(ts.UnixNano())))
+		req.NoError(d.writeInvertedIndex([]index.Field{field(i)}, common.ItemID(ts.UnixNano())))
+		req.NoError(d.Close())
+	}
+	sources := bc.blocks()
+	req.Len(sources, 3)
+
+	clock.Add(3 * time.Hour)
+	newMergeTask(s.(*shard).segmentController, 1<<20).run(clock.Now(), logger.GetLogger("test"))
+	verify := func() {
+		// the head block might be created after the merged one
+		blocks := bc.blocks()
+		merged := blocks[0]
+		for _, b := range blocks[1:] {
+			req.False(b.Start.Before(merged.End))
+		}
+		req.Equal(ids, merged.coveredIDs)
+		req.Equal(sources[0].Start, merged.Start)
+		req.Equal(sources[2].End, merged.End)
+		for i, id := range ids {
+			d, err := bc.get(ctx, id)
+			req.NoError(err)
+			ts := sources[i].Start.Add(time.Millisecond)
+			val, err := d.dataReader().Get([]byte("key"), uint64(ts.UnixNano()))
+			req.NoError(err)
+			req.Equal(fmt.Sprintf("val-%d", i), string(val))
+			for _, searcher := range []index.Searcher{d.lsmIndexReader(), d.invertedIndexReader()} {
+				list, err := searcher.MatchTerms(field(i))
+				req.NoError(err)
+				req.True(list.Contains(common.ItemID(ts.UnixNano())))
+			}
+			req.NoError(d.Close())
+		}
+	}
+	verify()
+	req.Eventually(func() bool {
+		for _, b := range sources {
+			if _, err := os.Stat(b.path); !os.IsNotExist(err) {
+				return false
+			}
+		}
+		return true
+	}, flags.EventuallyTimeout, time.Millisecond, "the sources are removed once they're released")
+
+	req.NoError(s.Close())
+	// an incomplete merge left by a crash
+	mergingPath, err := mkdir("%s/"+mergingPathPrefix+"-%s", bc.location, "03")
+	req.NoError(err)
+	s, bc = open()
+	defer s.Close()
+	verify()
+	_, err = os.Stat(mergingPath)
+	req.True(os.IsNotExist(err))
+}
+
 func verifyDatabaseStructure(tester *assert.Assertions, tempDir string, now time.Time) {
 	shardPath := fmt.Sprintf(shardTemplate, tempDir, 0)
 	validateDirectory(tester, shardPath)
@@ -130,3 +492,38 @@ func validateDirectory(t *assert.Assertions, dir string) {
 	t.NoError(err, "Directory error: %v", dir)
 	t.True(info.IsDir(), "Directory is a file, not a directory: %#v\n", dir)
 }
+
+func TestAlignToTimeZone(t *testing.T) {
+	req := require.New(t)
+	tempDir, deferFunc := test.Space(req)
+	defer deferFunc()
+	req.NoError(logger.Init(logger.Logging{
+		Env:   "dev",
+		Level: flags.LogLevel,
+	}))
+	zone, err := time.LoadLocation("Asia/Shanghai")
+	req.NoError(err)
+	clock := timestamp.NewMockClock()
+	// it's 01:00 of Jan 2nd in the zone
+	clock.Set(time.Date(2022, 1, 1, 17, 0, 0, 0, time.UTC))
+	ctx := timestamp.SetClock(context.WithValue(context.Background(), logger.ContextKey, logger.GetLogger("test")), clock)
+	ctx = context.WithValue(ctx, optionsKey, DatabaseOpts{TimeZone: zone})
+	s, err := OpenShard(ctx, 0, tempDir, IntervalRule{Unit: DAY, Num: 1}, IntervalRule{Unit: DAY, Num: 1},
+		IntervalRule{Unit: MONTH, Num: 1}, 2, 3)
+	req.NoError(err)
+	defer s.Close()
+	req.Eventually(func() bool {
+		return len(s.State().Blocks) == 1
+	}, flags.EventuallyTimeout, time.Millisecond)
+	seg := s.(*shard).segmentController.segments()[0]
+	assert.Equal(t, "20220102", seg.suffix)
+	assert.True(t, seg.Start.Equal(time.Date(2022, 1, 2, 0, 0, 0, 0, zone)), "the segment starts at %s", seg.Start)
+	assert.True(t, seg.End.Equal(time.Date(2022, 1, 3, 0, 0, 0, 0, zone)), "the segment ends at %s", seg.End)
+	b := seg.blockController.blocks()[0]
+	assert.True(t, b.Start.Equal(seg.Start), "the block starts at %s", b.Start)
+
+	// the months of the ttl are the calendar ones
+	ttl := IntervalRule{Unit: MONTH, Num: 1}
+	assert.True(t, ttl.PreviousTime(time.Date(2022, 3, 31, 0, 0, 0, 0, zone)).Equal(time.Date(2022, 3, 3, 0, 0, 0, 0, zone)))
+	assert.True(t, ttl.NextTime(time.Date(2022, 1, 15, 0, 0, 0, 0, zone)).Equal(time.Date(2022, 2, 15, 0, 0, 0, 0, zone)))
+}
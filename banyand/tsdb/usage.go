@@ -0,0 +1,145 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tsdb
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+// ingestWindow is the seconds of the sliding window measuring the ingest rate.
+const ingestWindow = 60
+
+var ingestMeterKey = contextIngestMeterKey{}
+
+type contextIngestMeterKey struct{}
+
+// ShardUsage is the storage usage of a shard.
+type ShardUsage struct {
+	Segments []SegmentUsage
+	// DiskBytes is the size of all the files of the shard, including the series metadata and the segments.
+	DiskBytes int64
+	Series    int64
+	Blocks    int
+	// IngestRate is the number of the items written per second in the recent minute.
+	IngestRate float64
+}
+
+// SegmentUsage is the storage usage of a segment.
+type SegmentUsage struct {
+	Name      string
+	TimeRange timestamp.TimeRange
+	DiskBytes int64
+	Blocks    int
+}
+
+func (s *shard) Usage() (ShardUsage, error) {
+	var u ShardUsage
+	var err error
+	if u.DiskBytes, err = diskSize(s.path); err != nil {
+		return u, err
+	}
+	if sdb, ok := s.seriesDatabase.(*seriesDB); ok {
+		if u.Series, err = sdb.count(); err != nil {
+			return u, err
+		}
+	}
+	for _, seg := range s.segmentController.segments() {
+		su := SegmentUsage{
+			Name:      seg.suffix,
+			TimeRange: seg.TimeRange,
+			Blocks:    len(seg.blockController.blocks()),
+		}
+		if su.DiskBytes, err = diskSize(seg.path); err != nil {
+			return u, err
+		}
+		u.Blocks += su.Blocks
+		u.Segments = append(u.Segments, su)
+	}
+	u.IngestRate = s.ingest.rate()
+	return u, nil
+}
+
+// diskSize returns the size of the files in the directory. The files removed while walking are skipped.
+func diskSize(root string) (size int64, err error) {
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
+
+// ingestMeter counts the items written to a shard per second in a sliding window.
+type ingestMeter struct {
+	clock   timestamp.Clock
+	counts  [ingestWindow]int64
+	seconds [ingestWindow]int64
+	mu      sync.Mutex
+}
+
+func newIngestMeter(clock timestamp.Clock) *ingestMeter {
+	return &ingestMeter{clock: clock}
+}
+
+func (m *ingestMeter) mark() {
+	if m == nil {
+		return
+	}
+	now := m.clock.Now().Unix()
+	i := now % ingestWindow
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.seconds[i] != now {
+		m.seconds[i] = now
+		m.counts[i] = 0
+	}
+	m.counts[i]++
+}
+
+// rate returns the average number of the items written per second in the window.
+func (m *ingestMeter) rate() float64 {
+	now := m.clock.Now().Unix()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var sum int64
+	for i, s := range m.seconds {
+		if now-s < ingestWindow {
+			sum += m.counts[i]
+		}
+	}
+	return float64(sum) / ingestWindow
+}
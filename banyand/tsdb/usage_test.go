@@ -0,0 +1,79 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tsdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/pkg/test"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+func TestShardUsage(t *testing.T) {
+	req := require.New(t)
+	tempDir, deferFunc := test.Space(req)
+	defer deferFunc()
+	db := openDatabase(context.Background(), req, tempDir)
+	defer func() {
+		req.NoError(db.Close())
+	}()
+	shard := db.Shards()[0]
+	_, err := shard.Series().GetByHashKey(HashEntity(Entity{Entry("svc"), Entry("instance-1")}))
+	req.NoError(err)
+	u, err := shard.Usage()
+	req.NoError(err)
+	req.Equal(int64(1), u.Series)
+	req.Positive(u.DiskBytes)
+	req.Len(u.Segments, 1)
+	req.Equal(u.Blocks, u.Segments[0].Blocks)
+	req.LessOrEqual(u.Segments[0].DiskBytes, u.DiskBytes)
+
+	// the series created after the first count are counted as well
+	_, err = shard.Series().GetByHashKey(HashEntity(Entity{Entry("svc"), Entry("instance-2")}))
+	req.NoError(err)
+	_, err = shard.Series().GetByHashKey(HashEntity(Entity{Entry("svc"), Entry("instance-1")}))
+	req.NoError(err)
+	u, err = shard.Usage()
+	req.NoError(err)
+	req.Equal(int64(2), u.Series)
+}
+
+func TestIngestMeter(t *testing.T) {
+	req := require.New(t)
+	clock := timestamp.NewMockClock()
+	clock.Set(time.Date(2022, 10, 15, 0, 0, 0, 0, time.UTC))
+	m := newIngestMeter(clock)
+	req.Zero(m.rate())
+	for i := 0; i < 30; i++ {
+		m.mark()
+		m.mark()
+		clock.Add(time.Second)
+	}
+	req.Equal(float64(60)/ingestWindow, m.rate())
+	// the marks of the first 16 seconds are out of the window
+	clock.Add(45 * time.Second)
+	req.Equal(float64(28)/ingestWindow, m.rate())
+	clock.Add(time.Hour)
+	req.Zero(m.rate())
+	var nilMeter *ingestMeter
+	nilMeter.mark()
+}
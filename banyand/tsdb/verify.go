@@ -0,0 +1,216 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tsdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/apache/skywalking-banyandb/banyand/kv"
+	"github.com/apache/skywalking-banyandb/pkg/index/inverted"
+	"github.com/apache/skywalking-banyandb/pkg/index/lsm"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+// the directory of a shard where the damaged blocks are moved to by the repair
+const quarantineDir = "quarantine"
+
+// VerifyReport is the result of verifying the files of the databases.
+type VerifyReport struct {
+	Damages  []Damage
+	Shards   int
+	Segments int
+	Blocks   int
+}
+
+// Damage is a damaged file or directory found by Verify.
+type Damage struct {
+	Path   string
+	Reason string
+	// Repaired tells whether the damage is repaired, e.g. the damaged block is quarantined.
+	Repaired bool
+}
+
+// Verify scans the shards under root, which are the directories of the databases or their parents, and checks
+// the manifests of the segments, the checksums of the sealed blocks and the stores and the indices of all the blocks.
+// If repair is set, the damaged blocks are moved to the quarantine directories of their shards and dropped from
+// the manifests, and the corrupted manifests are removed, which are rewritten by the server.
+// The databases must not be opened by a server while they are verified.
+func Verify(root string, repair bool, l *logger.Logger) (VerifyReport, error) {
+	v := &verifier{repair: repair, l: l}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		if !strings.HasPrefix(d.Name(), shardPathPrefix+"-") {
+			return nil
+		}
+		v.report.Shards++
+		if err := WalkDir(p, segPathPrefix, func(_, segPath string) error {
+			return v.verifySegment(p, segPath)
+		}); err != nil {
+			return err
+		}
+		return filepath.SkipDir
+	})
+	return v.report, err
+}
+
+type verifier struct {
+	l      *logger.Logger
+	report VerifyReport
+	repair bool
+}
+
+func (v *verifier) damage(p, reason string, repair func() error) {
+	d := Damage{Path: p, Reason: reason}
+	if v.repair {
+		if err := repair(); err != nil {
+			v.l.Error().Err(err).Str("path", p).Msg("failed to repair")
+		} else {
+			d.Repaired = true
+		}
+	}
+	v.report.Damages = append(v.report.Damages, d)
+}
+
+func (v *verifier) verifySegment(shardPath, segPath string) error {
+	v.report.Segments++
+	manifestPath := fmt.Sprintf(manifestTemplate, segPath)
+	var m *SegmentManifest
+	switch bb, err := os.ReadFile(manifestPath); {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		m = &SegmentManifest{}
+		if err = json.Unmarshal(bb, m); err != nil {
+			m = nil
+			v.damage(manifestPath, fmt.Sprintf("the manifest is corrupted: %v", err), func() error {
+				return os.Remove(manifestPath)
+			})
+		}
+	}
+	sealed := make(map[string]BlockManifest)
+	if m != nil {
+		for _, b := range m.Blocks {
+			sealed[b.Name] = b
+		}
+	}
+	dropped := make(map[string]struct{})
+	onDisk := make(map[string]struct{})
+	if err := WalkDir(segPath, blockPathPrefix, func(_, blockPath string) error {
+		v.report.Blocks++
+		name := filepath.Base(blockPath)
+		onDisk[name] = struct{}{}
+		reason, err := verifyBlock(blockPath, sealed[name], v.l)
+		if err != nil {
+			return err
+		}
+		if reason == "" {
+			return nil
+		}
+		v.damage(blockPath, reason, func() error {
+			dir := filepath.Join(shardPath, quarantineDir)
+			if err := os.MkdirAll(dir, dirPerm); err != nil {
+				return err
+			}
+			if err := os.Rename(blockPath, filepath.Join(dir, filepath.Base(segPath)+"-"+name)); err != nil {
+				return err
+			}
+			dropped[name] = struct{}{}
+			return nil
+		})
+		return nil
+	}); err != nil {
+		return err
+	}
+	if m == nil {
+		return nil
+	}
+	for name := range sealed {
+		if _, ok := onDisk[name]; ok {
+			continue
+		}
+		name := name
+		v.damage(filepath.Join(segPath, name), "the block in the manifest is missing", func() error {
+			dropped[name] = struct{}{}
+			return nil
+		})
+	}
+	if len(dropped) < 1 {
+		return nil
+	}
+	blocks := m.Blocks[:0]
+	for _, b := range m.Blocks {
+		if _, ok := dropped[b.Name]; !ok {
+			blocks = append(blocks, b)
+		}
+	}
+	m.Blocks = blocks
+	// the checksum of the segment no longer covers all the blocks
+	m.Checksum = ""
+	return writeManifest(manifestPath, *m)
+}
+
+// verifyBlock returns the reason why the block is damaged, or an empty one if it's healthy.
+// The stores and the indices are opened to check their files, which replays the write-ahead logs of an open block.
+func verifyBlock(blockPath string, bm BlockManifest, l *logger.Logger) (string, error) {
+	if bm.Sealed && bm.Checksum != "" {
+		checksum, err := checksumDir(blockPath)
+		if err != nil {
+			return "", err
+		}
+		if checksum != bm.Checksum {
+			return fmt.Sprintf("the checksum %s doesn't match %s in the manifest", checksum, bm.Checksum), nil
+		}
+	}
+	for _, c := range []string{componentMain, componentSecondInvertedIdx, componentSecondLSMIdx} {
+		if _, err := os.Stat(path.Join(blockPath, c)); err != nil {
+			return fmt.Sprintf("the %s component is missing: %v", c, err), nil
+		}
+	}
+	store, err := kv.OpenTimeSeriesStore(0, path.Join(blockPath, componentMain), kv.TSSWithLogger(l.Named(componentMain)))
+	if err != nil {
+		return fmt.Sprintf("failed to open the %s store: %v", componentMain, err), nil
+	}
+	defer store.Close()
+	invertedIndex, err := inverted.NewStore(inverted.StoreOpts{
+		Path:   path.Join(blockPath, componentSecondInvertedIdx),
+		Logger: l.Named(componentSecondInvertedIdx),
+	})
+	if err != nil {
+		return fmt.Sprintf("failed to open the %s index: %v", componentSecondInvertedIdx, err), nil
+	}
+	defer invertedIndex.Close()
+	lsmIndex, err := lsm.NewStore(lsm.StoreOpts{
+		Path:   path.Join(blockPath, componentSecondLSMIdx),
+		Logger: l.Named(componentSecondLSMIdx),
+	})
+	if err != nil {
+		return fmt.Sprintf("failed to open the %s index: %v", componentSecondLSMIdx, err), nil
+	}
+	return "", lsmIndex.Close()
+}
@@ -0,0 +1,92 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tsdb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/test"
+)
+
+func TestVerify(t *testing.T) {
+	req := require.New(t)
+	dir, deferFunc := test.Space(req)
+	defer deferFunc()
+	shardPath := filepath.Join(dir, "default", "shard-0")
+	seg := filepath.Join(shardPath, "seg-20221011")
+	for _, b := range []string{"block-00", "block-01"} {
+		for _, c := range []string{componentMain, componentSecondInvertedIdx, componentSecondLSMIdx} {
+			req.NoError(os.MkdirAll(filepath.Join(seg, b, c), dirPerm))
+		}
+	}
+	req.NoError(writeManifest(filepath.Join(seg, "manifest.json"), SegmentManifest{
+		Segment:  "20221011",
+		Checksum: "segment",
+		Blocks: []BlockManifest{
+			{Name: "block-00"},
+			{Name: "block-01", Sealed: true, Checksum: "tampered"},
+			{Name: "block-02", Sealed: true, Checksum: "lost"},
+		},
+	}))
+	corrupted := filepath.Join(shardPath, "seg-20221012")
+	req.NoError(os.MkdirAll(corrupted, dirPerm))
+	req.NoError(os.WriteFile(filepath.Join(corrupted, "manifest.json"), []byte("{"), 0o600))
+	l := logger.GetLogger("test")
+
+	report, err := Verify(dir, false, l)
+	req.NoError(err)
+	assert.Equal(t, 1, report.Shards)
+	assert.Equal(t, 2, report.Segments)
+	assert.Equal(t, 2, report.Blocks)
+	damaged := func(report VerifyReport, repaired bool) []string {
+		var paths []string
+		for _, d := range report.Damages {
+			assert.Equal(t, repaired, d.Repaired, d.Path)
+			paths = append(paths, d.Path)
+		}
+		return paths
+	}
+	assert.ElementsMatch(t, []string{
+		filepath.Join(seg, "block-01"),
+		filepath.Join(seg, "block-02"),
+		filepath.Join(corrupted, "manifest.json"),
+	}, damaged(report, false))
+
+	report, err = Verify(dir, true, l)
+	req.NoError(err)
+	assert.Len(t, damaged(report, true), 3)
+	assert.DirExists(t, filepath.Join(shardPath, quarantineDir, "seg-20221011-block-01"))
+	assert.NoFileExists(t, filepath.Join(corrupted, "manifest.json"))
+	m, err := os.ReadFile(filepath.Join(seg, "manifest.json"))
+	req.NoError(err)
+	assert.JSONEq(t, `{"segment":"20221011","begin":"0001-01-01T00:00:00Z","end":"0001-01-01T00:00:00Z",
+		"updated_at":"0001-01-01T00:00:00Z","resources":null,"disk_bytes":0,"shard":0,
+		"blocks":[{"name":"block-00","begin":"0001-01-01T00:00:00Z","end":"0001-01-01T00:00:00Z","disk_bytes":0,"sealed":false}]}`,
+		string(m))
+
+	report, err = Verify(dir, true, l)
+	req.NoError(err)
+	assert.Empty(t, report.Damages)
+	assert.Equal(t, 1, report.Blocks)
+}
@@ -18,17 +18,24 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/spf13/cobra"
 	"google.golang.org/protobuf/encoding/protojson"
+	"sigs.k8s.io/yaml"
 
 	common_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
 	database_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
 	"github.com/apache/skywalking-banyandb/pkg/version"
 )
 
+var (
+	dryRun      bool
+	cloneTarget string
+)
+
 func newGroupCmd() *cobra.Command {
 	groupCmd := &cobra.Command{
 		Use:     "group",
@@ -133,6 +140,89 @@ func newGroupCmd() *cobra.Command {
 		},
 	}
 
-	groupCmd.AddCommand(createCmd, updateCmd, listCmd, getCmd, deleteCmd)
+	exportCmd := &cobra.Command{
+		Use:     "export [-g group]",
+		Version: version.Build(),
+		Short:   "Export all the schemas of a group",
+		RunE: func(_ *cobra.Command, _ []string) (err error) {
+			return rest(parseGroupFromFlags, func(request request) (*resty.Response, error) {
+				return request.req.SetPathParam("group", request.group).Get(getPath("/api/v1/group/schema/export/{group}"))
+			}, bundlePrinter)
+		},
+	}
+
+	importCmd := &cobra.Command{
+		Use:     "import -f [file|dir|-] [--dry-run]",
+		Version: version.Build(),
+		Short:   "Import the schemas of groups from files",
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			return rest(func() ([]reqBody, error) { return parseBundleFromYAML(cmd.InOrStdin(), dryRun) },
+				func(request request) (*resty.Response, error) {
+					return request.req.SetBody(request.data).Post(getPath("/api/v1/group/schema/import"))
+				},
+				func(_ int, reqBody reqBody, body []byte) error {
+					resp := new(database_v1.GroupRegistryServiceImportResponse)
+					if err := protojson.Unmarshal(body, resp); err != nil {
+						return err
+					}
+					action := "imported"
+					if dryRun {
+						action = "checked"
+					}
+					fmt.Printf("group %s is %s: %d created, %d updated, %d unchanged", reqBody.name, action,
+						resp.GetCreated(), resp.GetUpdated(), resp.GetUnchanged())
+					fmt.Println()
+					return nil
+				})
+		},
+	}
+	bindFileFlag(importCmd)
+	importCmd.Flags().BoolVar(&dryRun, "dry-run", false, "validate the schemas without applying them")
+
+	cloneCmd := &cobra.Command{
+		Use:     "clone [-g group] --target target [-s start_time] [-e end_time]",
+		Version: version.Build(),
+		Short:   "Clone the schemas of a group, and copy its data in a time range",
+		Long:    "Only the schemas are cloned if both \"start\" and \"end\" are absent.\n\t\t" + timeRangeUsage,
+		RunE: func(_ *cobra.Command, _ []string) (err error) {
+			return rest(func() ([]reqBody, error) { return parseCloneFromFlags(cloneTarget) },
+				func(request request) (*resty.Response, error) {
+					return request.req.SetBody(request.data).Post(getPath("/api/v1/group/schema/clone"))
+				},
+				func(_ int, reqBody reqBody, body []byte) error {
+					resp := new(database_v1.GroupRegistryServiceCloneResponse)
+					if err := protojson.Unmarshal(body, resp); err != nil {
+						return err
+					}
+					fmt.Printf("group %s is cloned to %s: %d created, %d elements and %d data points copied", reqBody.group, reqBody.name,
+						resp.GetCreated(), resp.GetElements(), resp.GetDataPoints())
+					fmt.Println()
+					return nil
+				})
+		},
+	}
+	cloneCmd.Flags().StringVar(&cloneTarget, "target", "", "the name of the group to create")
+	_ = cloneCmd.MarkFlagRequired("target")
+	bindTimeRangeFlag(cloneCmd)
+
+	groupCmd.AddCommand(createCmd, updateCmd, listCmd, getCmd, deleteCmd, exportCmd, importCmd, cloneCmd)
 	return groupCmd
 }
+
+// bundlePrinter prints the bundle of an export response, which can be imported as is.
+func bundlePrinter(index int, _ reqBody, body []byte) error {
+	var resp map[string]interface{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return err
+	}
+	b, err := yaml.Marshal(resp["bundle"])
+	if err != nil {
+		return err
+	}
+	if index > 0 {
+		fmt.Println("---")
+	}
+	fmt.Print(string(b))
+	fmt.Println()
+	return nil
+}
@@ -0,0 +1,71 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"github.com/go-resty/resty/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/apache/skywalking-banyandb/pkg/version"
+)
+
+var (
+	rebuiltIndexRules []string
+	rebuildRateLimit  uint32
+)
+
+func newIndexCmd() *cobra.Command {
+	indexCmd := &cobra.Command{
+		Use:     "index",
+		Version: version.Build(),
+		Short:   "Index operation",
+	}
+
+	statsCmd := &cobra.Command{
+		Use:     "stats [-g group] -n name",
+		Version: version.Build(),
+		Short:   "Show the cardinality, the size and the build time of the indices of a stream or a measure",
+		RunE: func(_ *cobra.Command, _ []string) (err error) {
+			return rest(parseFromFlags, func(request request) (*resty.Response, error) {
+				return request.req.SetPathParam("group", request.group).SetPathParam("name", request.name).
+					Get(getPath("/api/v1/index/stats/{group}/{name}"))
+			}, yamlPrinter)
+		},
+	}
+
+	rebuildCmd := &cobra.Command{
+		Use:     "rebuild [-g group] -n name [--index-rules rule1,rule2] [-s start_time] [-e end_time] [--rate-limit 1000]",
+		Version: version.Build(),
+		Short:   "Rebuild the indices of a stream or a measure from the stored data in the background",
+		Long:    "The indices of the whole data are rebuilt if both \"start\" and \"end\" are absent.\n\t\t" + timeRangeUsage,
+		RunE: func(_ *cobra.Command, _ []string) (err error) {
+			return rest(func() ([]reqBody, error) { return parseRebuildFromFlags(rebuiltIndexRules, rebuildRateLimit) },
+				func(request request) (*resty.Response, error) {
+					return request.req.SetBody(request.data).Post(getPath("/api/v1/index/rebuild"))
+				}, yamlPrinter)
+		},
+	}
+	rebuildCmd.Flags().StringSliceVar(&rebuiltIndexRules, "index-rules", nil, "the index rules to rebuild, all the rules bound to the resource if absent")
+	rebuildCmd.Flags().Uint32Var(&rebuildRateLimit, "rate-limit", 0, "the number of the elements or the data points indexed per second, 1000 if absent")
+
+	bindNameFlag(statsCmd, rebuildCmd)
+	bindTimeRangeFlag(rebuildCmd)
+
+	indexCmd.AddCommand(statsCmd, rebuildCmd)
+	return indexCmd
+}
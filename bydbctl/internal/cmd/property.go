@@ -18,6 +18,8 @@
 package cmd
 
 import (
+	"strconv"
+
 	"github.com/go-resty/resty/v2"
 	"github.com/spf13/cobra"
 	"google.golang.org/protobuf/encoding/protojson"
@@ -32,6 +34,7 @@ var (
 	id                                  string
 	tags                                []string
 	ids                                 []string
+	modRevision                         int64
 	propertySchemaPathWithoutTagParams  = propertySchemaPath + "/{group}/{name}/{id}"
 	propertySchemaPathWithTagParams     = propertySchemaPath + "/{group}/{name}/{id}/{tag}"
 	propertyListSchemaPathWithTagParams = propertySchemaPath + "/lists/{group}/{name}/{ids}/{tags}"
@@ -45,7 +48,7 @@ func newPropertyCmd() *cobra.Command {
 	}
 
 	applyCmd := &cobra.Command{
-		Use:     "apply -f [file|dir|-]",
+		Use:     "apply -f [file|dir|-] [--mod-revision revision]",
 		Version: version.Build(),
 		Short:   "Apply(Create or Update) properties from files",
 		RunE: func(cmd *cobra.Command, _ []string) error {
@@ -57,7 +60,8 @@ func newPropertyCmd() *cobra.Command {
 						return nil, err
 					}
 					cr := &property_v1.ApplyRequest{
-						Property: s,
+						Property:    s,
+						ModRevision: modRevision,
 					}
 					b, err := protojson.Marshal(cr)
 					if err != nil {
@@ -84,17 +88,23 @@ func newPropertyCmd() *cobra.Command {
 	}
 
 	deleteCmd := &cobra.Command{
-		Use:     "delete [-g group] -n name -i id -t [tags]",
+		Use:     "delete [-g group] -n name -i id -t [tags] [--mod-revision revision]",
 		Version: version.Build(),
 		Short:   "Delete a property",
 		RunE: func(_ *cobra.Command, _ []string) (err error) {
 			return rest(parseFromFlags, func(request request) (*resty.Response, error) {
+				if modRevision > 0 {
+					request.req.SetQueryParam("mod_revision", strconv.FormatInt(modRevision, 10))
+				}
 				return request.req.SetPathParam("name", request.name).SetPathParam("group", request.group).
 					SetPathParam("id", request.id).SetPathParam("tag", request.tags()).Delete(getPath(propertySchemaPathWithTagParams))
 			}, yamlPrinter)
 		},
 	}
 	bindNameAndIDAndTagsFlag(getCmd, deleteCmd)
+	for _, c := range []*cobra.Command{applyCmd, deleteCmd} {
+		c.Flags().Int64VarP(&modRevision, "mod-revision", "", 0, "apply or delete the property only if it's the revision of the existing one")
+	}
 
 	listCmd := &cobra.Command{
 		Use:     "list [-g group] -n name",
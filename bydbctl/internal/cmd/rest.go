@@ -150,36 +150,138 @@ func parseFromFlags() (requests []reqBody, err error) {
 	return requests, nil
 }
 
-func parseGroupFromFlags() ([]reqBody, error) {
-	group := viper.GetString("group")
-	if group == "" {
-		return nil, errors.New("please specify a group through the flag or the config file")
+// parseInspectFromFlags builds the body of an inspect request.
+// The time range is only set if "start" or "end" is present, otherwise all the data is inspected.
+func parseInspectFromFlags() (requests []reqBody, err error) {
+	if requests, err = parseFromFlags(); err != nil {
+		return nil, err
 	}
-	return []reqBody{{group: group}}, nil
+	body := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"group": requests[0].group,
+			"name":  requests[0].name,
+		},
+	}
+	if start != "" || end != "" {
+		startTS, endTS, err := parseTimeRangeFromFlags()
+		if err != nil {
+			return nil, err
+		}
+		body["timeRange"] = map[string]interface{}{
+			"begin": startTS.Format(time.RFC3339),
+			"end":   endTS.Format(time.RFC3339),
+		}
+	}
+	if requests[0].data, err = json.Marshal(body); err != nil {
+		return nil, err
+	}
+	return requests, nil
 }
 
-func parseTimeRangeFromFlagAndYAML(reader io.Reader) (requests []reqBody, err error) {
-	var startTS, endTS time.Time
-	if start == "" && end == "" {
-		startTS = time.Now().Add((-30) * time.Minute)
-		endTS = time.Now()
-	} else if start != "" && end != "" {
-		if startTS, err = parseTime(start); err != nil {
+// parseRebuildFromFlags builds the body of an index rebuild request on top of the inspect one.
+func parseRebuildFromFlags(indexRules []string, rateLimit uint32) (requests []reqBody, err error) {
+	if requests, err = parseInspectFromFlags(); err != nil {
+		return nil, err
+	}
+	var body map[string]interface{}
+	if err = json.Unmarshal(requests[0].data, &body); err != nil {
+		return nil, err
+	}
+	if len(indexRules) > 0 {
+		body["indexRules"] = indexRules
+	}
+	if rateLimit > 0 {
+		body["rateLimit"] = rateLimit
+	}
+	if requests[0].data, err = json.Marshal(body); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// parseCloneFromFlags builds the body of a clone request.
+// The data are only copied if "start" or "end" is present, otherwise only the schemas are cloned.
+func parseCloneFromFlags(target string) (requests []reqBody, err error) {
+	if requests, err = parseGroupFromFlags(); err != nil {
+		return nil, err
+	}
+	requests[0].name = target
+	body := map[string]interface{}{
+		"group":  requests[0].group,
+		"target": target,
+	}
+	if start != "" || end != "" {
+		startTS, endTS, err := parseTimeRangeFromFlags()
+		if err != nil {
 			return nil, err
 		}
-		if endTS, err = parseTime(end); err != nil {
+		body["timeRange"] = map[string]interface{}{
+			"begin": startTS.Format(time.RFC3339),
+			"end":   endTS.Format(time.RFC3339),
+		}
+	}
+	if requests[0].data, err = json.Marshal(body); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// parseBundleFromYAML builds the import requests of the schema bundles, one per document.
+func parseBundleFromYAML(reader io.Reader, dryRun bool) (requests []reqBody, err error) {
+	contents, err := file.Read(filePath, reader)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range contents {
+		j, err := yaml.YAMLToJSON(c)
+		if err != nil {
 			return nil, err
 		}
-	} else if start != "" {
-		if startTS, err = parseTime(start); err != nil {
+		var data map[string]interface{}
+		if err = json.Unmarshal(j, &data); err != nil {
 			return nil, err
 		}
-		endTS = startTS.Add(timeRange)
-	} else {
-		if endTS, err = parseTime(end); err != nil {
+		group, ok := data["group"].(map[string]interface{})
+		if !ok {
+			return nil, errors.WithMessage(errMalformedInput, "absent node: group")
+		}
+		metadata, ok := group["metadata"].(map[string]interface{})
+		if !ok {
+			return nil, errors.WithMessage(errMalformedInput, "absent node: metadata in group")
+		}
+		groupName, ok := metadata["name"].(string)
+		if !ok {
+			return nil, errors.WithMessage(errMalformedInput, "absent node: name in metadata")
+		}
+		j, err = json.Marshal(map[string]interface{}{
+			"bundle": data,
+			"dryRun": dryRun,
+		})
+		if err != nil {
 			return nil, err
 		}
-		startTS = endTS.Add(-timeRange)
+		requests = append(requests, reqBody{
+			name:       groupName,
+			group:      groupName,
+			data:       j,
+			parsedData: data,
+		})
+	}
+	return requests, nil
+}
+
+func parseGroupFromFlags() ([]reqBody, error) {
+	group := viper.GetString("group")
+	if group == "" {
+		return nil, errors.New("please specify a group through the flag or the config file")
+	}
+	return []reqBody{{group: group}}, nil
+}
+
+func parseTimeRangeFromFlagAndYAML(reader io.Reader) (requests []reqBody, err error) {
+	startTS, endTS, err := parseTimeRangeFromFlags()
+	if err != nil {
+		return nil, err
 	}
 	s := startTS.Format(time.RFC3339)
 	e := endTS.Format(time.RFC3339)
@@ -205,6 +307,31 @@ func parseTimeRangeFromFlagAndYAML(reader io.Reader) (requests []reqBody, err er
 	return requests, nil
 }
 
+func parseTimeRangeFromFlags() (startTS, endTS time.Time, err error) {
+	if start == "" && end == "" {
+		startTS = time.Now().Add((-30) * time.Minute)
+		endTS = time.Now()
+	} else if start != "" && end != "" {
+		if startTS, err = parseTime(start); err != nil {
+			return startTS, endTS, err
+		}
+		if endTS, err = parseTime(end); err != nil {
+			return startTS, endTS, err
+		}
+	} else if start != "" {
+		if startTS, err = parseTime(start); err != nil {
+			return startTS, endTS, err
+		}
+		endTS = startTS.Add(timeRange)
+	} else {
+		if endTS, err = parseTime(end); err != nil {
+			return startTS, endTS, err
+		}
+		startTS = endTS.Add(-timeRange)
+	}
+	return startTS, endTS, nil
+}
+
 func parseTime(timestamp string) (time.Time, error) {
 	if len(timestamp) < 1 {
 		return time.Time{}, errors.New("time is empty")
@@ -0,0 +1,72 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	database_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/pkg/version"
+)
+
+var (
+	shardIDs     []uint
+	drainTimeout time.Duration
+)
+
+func newShardCmd() *cobra.Command {
+	shardCmd := &cobra.Command{
+		Use:     "shard",
+		Version: version.Build(),
+		Short:   "Shard operation",
+	}
+
+	rolloverCmd := &cobra.Command{
+		Use:     "rollover [-g group] [--shard-ids 0,1] [--drain-timeout 10s]",
+		Version: version.Build(),
+		Short:   "Close the open blocks of the shards and roll to new ones",
+		RunE: func(_ *cobra.Command, _ []string) (err error) {
+			return rest(parseGroupFromFlags, func(request request) (*resty.Response, error) {
+				rr := &database_v1.ShardServiceRolloverRequest{
+					Group: request.group,
+				}
+				for _, id := range shardIDs {
+					rr.ShardIds = append(rr.ShardIds, uint32(id))
+				}
+				if drainTimeout > 0 {
+					rr.DrainTimeout = durationpb.New(drainTimeout)
+				}
+				b, err := protojson.Marshal(rr)
+				if err != nil {
+					return nil, err
+				}
+				return request.req.SetBody(b).SetPathParam("group", request.group).Post(getPath("/api/v1/shard/rollover/{group}"))
+			}, yamlPrinter)
+		},
+	}
+	rolloverCmd.Flags().UintSliceVar(&shardIDs, "shard-ids", nil, "the shards to roll over, all shards of the group if absent")
+	rolloverCmd.Flags().DurationVar(&drainTimeout, "drain-timeout", 0, "how long to wait for the in-flight reads and writes to drain, 10s if absent")
+
+	shardCmd.AddCommand(rolloverCmd)
+	return shardCmd
+}
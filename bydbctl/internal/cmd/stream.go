@@ -154,9 +154,22 @@ func newStreamCmd() *cobra.Command {
 				}, yamlPrinter)
 		},
 	}
+	inspectCmd := &cobra.Command{
+		Use:     "inspect [-g group] -n name [-s start_time] [-e end_time]",
+		Version: version.Build(),
+		Short:   "Inspect a stream with a sample and the counts of its data",
+		Long:    "The whole data is inspected if both \"start\" and \"end\" are absent.\n\t\t" + timeRangeUsage,
+		RunE: func(_ *cobra.Command, _ []string) (err error) {
+			return rest(parseInspectFromFlags, func(request request) (*resty.Response, error) {
+				return request.req.SetBody(request.data).Post(getPath("/api/v1/stream/inspect"))
+			}, yamlPrinter)
+		},
+	}
+
 	bindFileFlag(createCmd, updateCmd, queryCmd)
-	bindTimeRangeFlag(queryCmd)
+	bindNameFlag(inspectCmd)
+	bindTimeRangeFlag(queryCmd, inspectCmd)
 
-	streamCmd.AddCommand(getCmd, createCmd, deleteCmd, updateCmd, listCmd, queryCmd)
+	streamCmd.AddCommand(getCmd, createCmd, deleteCmd, updateCmd, listCmd, queryCmd, inspectCmd)
 	return streamCmd
 }
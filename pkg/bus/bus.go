@@ -18,13 +18,19 @@
 package bus
 
 import (
+	"context"
 	"errors"
 	"io"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"go.uber.org/multierr"
 )
 
+// flushCheckInterval is the interval of checking whether the published messages are received
+const flushCheckInterval = 10 * time.Millisecond
+
 // Payload represents a simple data
 type Payload interface{}
 type (
@@ -66,6 +72,13 @@ type Publisher interface {
 	Publish(topic Topic, message ...Message) (Future, error)
 }
 
+// Flusher waits for the listeners to receive the messages published to the topics.
+type Flusher interface {
+	Flush(ctx context.Context, topics ...Topic) error
+	// Pending returns the number of the messages published to the topics but not received by all the listeners yet.
+	Pending(topics ...Topic) int64
+}
+
 type Channel chan Event
 
 type ChType int
@@ -91,15 +104,94 @@ func BiTopic(ID string) Topic {
 // The Bus allows publish-subscribe-style communication between components
 type Bus struct {
 	topics map[Topic][]Channel
-	mutex  sync.RWMutex
+	codecs map[Topic][]Codec
+	// pending counts the messages of a topic which are published but not received by all the listeners
+	pending map[Topic]*int64
+	mutex   sync.RWMutex
 }
 
+var (
+	_ CodecNegotiator = (*Bus)(nil)
+	_ Flusher         = (*Bus)(nil)
+)
+
 func NewBus() *Bus {
 	b := new(Bus)
 	b.topics = make(map[Topic][]Channel)
+	b.codecs = make(map[Topic][]Codec)
+	b.pending = make(map[Topic]*int64)
 	return b
 }
 
+// Flush blocks until the listeners receive the messages published to the topics before, or the context is done.
+func (b *Bus) Flush(ctx context.Context, topics ...Topic) error {
+	b.mutex.RLock()
+	counters := make([]*int64, 0, len(topics))
+	for _, t := range topics {
+		if c, ok := b.pending[t]; ok {
+			counters = append(counters, c)
+		}
+	}
+	b.mutex.RUnlock()
+	ticker := time.NewTicker(flushCheckInterval)
+	defer ticker.Stop()
+	for {
+		flushed := true
+		for _, c := range counters {
+			if atomic.LoadInt64(c) > 0 {
+				flushed = false
+				break
+			}
+		}
+		if flushed {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Pending returns the number of the messages published to the topics but not received by all the listeners yet.
+func (b *Bus) Pending(topics ...Topic) (n int64) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	for _, t := range topics {
+		if c, ok := b.pending[t]; ok {
+			n += atomic.LoadInt64(c)
+		}
+	}
+	return n
+}
+
+// RegisterCodecs appends the codecs, in the order of preference, serializing the payloads of the topic for the remote peers.
+func (b *Bus) RegisterCodecs(topic Topic, codecs ...Codec) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.codecs[topic] = append(b.codecs[topic], codecs...)
+}
+
+// Codecs returns the registered codecs of the topic followed by the NativeCodec,
+// so that the in-process subscribers always receive the Go values as they are.
+func (b *Bus) Codecs(topic Topic) []Codec {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	codecs := make([]Codec, 0, len(b.codecs[topic])+1)
+	for _, c := range b.codecs[topic] {
+		if c.Name() != NativeCodecName {
+			codecs = append(codecs, c)
+		}
+	}
+	return append(codecs, NativeCodec)
+}
+
+// Negotiate picks the codec of the topic accepted by a peer.
+func (b *Bus) Negotiate(topic Topic, accepted ...string) (Codec, error) {
+	return Negotiate(b.Codecs(topic), accepted...)
+}
+
 var (
 	ErrTopicEmpty    = errors.New("the topic is empty")
 	ErrTopicNotExist = errors.New("the topic does not exist")
@@ -172,6 +264,7 @@ func (b *Bus) Publish(topic Topic, message ...Message) (Future, error) {
 	case ChTypeBidirectional:
 		f = &localFuture{retCount: len(message), retCh: make(chan Message)}
 	}
+	atomic.AddInt64(b.pending[topic], int64(len(cc)*len(message)))
 	for _, each := range cc {
 		for _, m := range message {
 			go func(ch Channel, message Message) {
@@ -200,7 +293,9 @@ func (b *Bus) Subscribe(topic Topic, listener MessageListener) error {
 	defer b.mutex.Unlock()
 	if _, exist := b.topics[topic]; !exist {
 		b.topics[topic] = make([]Channel, 0)
+		b.pending[topic] = new(int64)
 	}
+	pending := b.pending[topic]
 	ch := make(Channel)
 	list := b.topics[topic]
 	list = append(list, ch)
@@ -210,6 +305,7 @@ func (b *Bus) Subscribe(topic Topic, listener MessageListener) error {
 			c, ok := <-ch
 			if ok {
 				ret := listener.Rev(c.m)
+				atomic.AddInt64(pending, -1)
 				if c.f == nil {
 					continue
 				}
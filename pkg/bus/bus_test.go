@@ -18,6 +18,8 @@
 package bus
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"sort"
 	"sync"
@@ -231,6 +233,50 @@ func waitTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
 
 var _ MessageListener = new(mockListener)
 
+type blockingListener struct {
+	release  chan struct{}
+	received int
+}
+
+func (l *blockingListener) Rev(_ Message) Message {
+	<-l.release
+	l.received++
+	return Message{}
+}
+
+func TestBus_Flush(t *testing.T) {
+	b := NewBus()
+	topic := UniTopic("flush")
+	l := &blockingListener{release: make(chan struct{})}
+	if err := b.Subscribe(topic, l); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	if err := b.Flush(context.Background(), topic, UniTopic("absent")); err != nil {
+		t.Errorf("Flush() of no message error = %v", err)
+	}
+	if _, err := b.Publish(topic, NewMessage(1, nil), NewMessage(2, nil)); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if n := b.Pending(topic, UniTopic("absent")); n != 2 {
+		t.Errorf("Pending() = %d, want 2", n)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := b.Flush(ctx, topic); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Flush() of the blocked messages error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	close(l.release)
+	if err := b.Flush(context.Background(), topic); err != nil {
+		t.Errorf("Flush() error = %v", err)
+	}
+	if l.received != 2 {
+		t.Errorf("received %d messages, want 2", l.received)
+	}
+	if n := b.Pending(topic); n != 0 {
+		t.Errorf("Pending() of the flushed messages = %d, want 0", n)
+	}
+}
+
 type mockListener struct {
 	queue   []MessageID
 	wg      *sync.WaitGroup
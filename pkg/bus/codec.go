@@ -0,0 +1,142 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bus
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	NativeCodecName   = "native"
+	ProtobufCodecName = "protobuf"
+)
+
+var (
+	ErrNoCommonCodec   = errors.New("no codec is supported by both the publisher and the subscriber")
+	ErrUnknownCodec    = errors.New("the codec is unknown")
+	ErrNotSerializable = errors.New("the payload can't be serialized by the codec")
+)
+
+// Codec serializes the payloads of a topic into the bytes transferred by a remote queue.
+type Codec interface {
+	Name() string
+	Marshal(payload Payload) ([]byte, error)
+	Unmarshal(data []byte) (Payload, error)
+}
+
+// CodecNegotiator holds the codecs of the topics, which are negotiated with the peers of the topics.
+type CodecNegotiator interface {
+	RegisterCodecs(topic Topic, codecs ...Codec)
+	Negotiate(topic Topic, accepted ...string) (Codec, error)
+}
+
+// NativeCodec passes the Go values as they are. It's only accepted by the subscribers in the same process.
+var NativeCodec Codec = nativeCodec{}
+
+type nativeCodec struct{}
+
+func (nativeCodec) Name() string {
+	return NativeCodecName
+}
+
+func (nativeCodec) Marshal(Payload) ([]byte, error) {
+	return nil, ErrNotSerializable
+}
+
+func (nativeCodec) Unmarshal([]byte) (Payload, error) {
+	return nil, ErrNotSerializable
+}
+
+type protoCodec struct {
+	newMessage func() proto.Message
+}
+
+// NewProtoCodec returns a Codec serializing the payloads by protobuf. newMessage creates the message the bytes are decoded into.
+func NewProtoCodec(newMessage func() proto.Message) Codec {
+	return &protoCodec{newMessage: newMessage}
+}
+
+func (c *protoCodec) Name() string {
+	return ProtobufCodecName
+}
+
+func (c *protoCodec) Marshal(payload Payload) ([]byte, error) {
+	m, ok := payload.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T isn't a protobuf message", ErrNotSerializable, payload)
+	}
+	return proto.Marshal(m)
+}
+
+func (c *protoCodec) Unmarshal(data []byte) (Payload, error) {
+	m := c.newMessage()
+	if err := proto.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Negotiate picks the first one of the codecs, which are in the order of preference, accepted by the peer.
+// All the codecs are accepted if the peer doesn't tell.
+func Negotiate(codecs []Codec, accepted ...string) (Codec, error) {
+	for _, c := range codecs {
+		if len(accepted) < 1 {
+			return c, nil
+		}
+		for _, name := range accepted {
+			if c.Name() == name {
+				return c, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %v", ErrNoCommonCodec, accepted)
+}
+
+// Envelope is a Message serialized by a codec, which is transferred to the subscribers in other processes.
+type Envelope struct {
+	Codec string
+	Data  []byte
+	ID    MessageID
+}
+
+// Encode serializes the payload of the message by the codec.
+func Encode(codec Codec, m Message) (Envelope, error) {
+	data, err := codec.Marshal(m.Data())
+	if err != nil {
+		return Envelope{}, err
+	}
+	return Envelope{ID: m.ID(), Codec: codec.Name(), Data: data}, nil
+}
+
+// Decode restores the message by the codec which serialized it.
+func Decode(codecs []Codec, e Envelope) (Message, error) {
+	for _, c := range codecs {
+		if c.Name() != e.Codec {
+			continue
+		}
+		payload, err := c.Unmarshal(e.Data)
+		if err != nil {
+			return Message{}, err
+		}
+		return NewMessage(e.ID, payload), nil
+	}
+	return Message{}, fmt.Errorf("%w: %s", ErrUnknownCodec, e.Codec)
+}
@@ -0,0 +1,82 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bus
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestBus_Negotiate(t *testing.T) {
+	topic := UniTopic("write")
+	b := NewBus()
+	c, err := b.Negotiate(topic)
+	if err != nil || c.Name() != NativeCodecName {
+		t.Fatalf("Negotiate() = %v, %v, want the native codec", c, err)
+	}
+	b.RegisterCodecs(topic, NewProtoCodec(func() proto.Message { return &wrapperspb.StringValue{} }))
+	tests := []struct {
+		name     string
+		accepted []string
+		want     string
+		wantErr  error
+	}{
+		{name: "preferred", want: ProtobufCodecName},
+		{name: "in-process", accepted: []string{NativeCodecName}, want: NativeCodecName},
+		{name: "remote", accepted: []string{"json", ProtobufCodecName}, want: ProtobufCodecName},
+		{name: "unsupported", accepted: []string{"json"}, wantErr: ErrNoCommonCodec},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := b.Negotiate(topic, tt.accepted...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Negotiate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && got.Name() != tt.want {
+				t.Errorf("Negotiate() = %s, want %s", got.Name(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeAndDecode(t *testing.T) {
+	codec := NewProtoCodec(func() proto.Message { return &wrapperspb.StringValue{} })
+	e, err := Encode(codec, NewMessage(MessageID(7), wrapperspb.String("payload")))
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	m, err := Decode([]Codec{NativeCodec, codec}, e)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if m.ID() != 7 || m.Data().(*wrapperspb.StringValue).GetValue() != "payload" {
+		t.Errorf("Decode() = %v, want the message 7 carrying the payload", m)
+	}
+	if _, err = Decode([]Codec{NativeCodec}, e); !errors.Is(err, ErrUnknownCodec) {
+		t.Errorf("Decode() error = %v, want %v", err, ErrUnknownCodec)
+	}
+	if _, err = Encode(NativeCodec, m); !errors.Is(err, ErrNotSerializable) {
+		t.Errorf("Encode() error = %v, want %v", err, ErrNotSerializable)
+	}
+	if _, err = Encode(codec, NewMessage(MessageID(8), "not a protobuf message")); !errors.Is(err, ErrNotSerializable) {
+		t.Errorf("Encode() error = %v, want %v", err, ErrNotSerializable)
+	}
+}
@@ -0,0 +1,218 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package cache keeps the hot values in memory within a budget of bytes.
+package cache
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/dgraph-io/ristretto"
+	"github.com/pkg/errors"
+)
+
+// Policy decides which values are admitted and evicted.
+type Policy string
+
+const (
+	// PolicyLRU evicts the least recently used values.
+	PolicyLRU Policy = "lru"
+	// PolicyTinyLFU admits a value only if it's accessed more frequently than the one to evict.
+	PolicyTinyLFU Policy = "tinylfu"
+
+	// a value larger than 1/maxItemRatio of the cache isn't admitted, so that a single large read can't flush the cache
+	maxItemRatio = 8
+	// the TinyLFU tracks the frequency of about 10x of the values the cache holds, assuming they're about 1KB
+	tinyLFUCountersPerByte = 100
+	minTinyLFUCounters     = 1 << 10
+)
+
+var ErrUnknownPolicy = errors.New("unknown cache policy")
+
+// CheckPolicy validates the name of a policy.
+func CheckPolicy(policy Policy) error {
+	switch policy {
+	case PolicyLRU, PolicyTinyLFU:
+		return nil
+	}
+	return errors.WithMessagef(ErrUnknownPolicy, "policy %s", policy)
+}
+
+type store interface {
+	get(key string) ([]byte, bool)
+	set(key string, val []byte)
+	del(key string)
+	close()
+}
+
+// Cache holds the values within size bytes, counting both the keys and the values.
+// A nil Cache holds nothing so that callers don't have to check whether it's enabled.
+type Cache struct {
+	store
+	maxItemSize int64
+}
+
+// New returns a Cache of size bytes, or nil if size is not positive.
+func New(policy Policy, size int64) (*Cache, error) {
+	if size <= 0 {
+		return nil, nil
+	}
+	c := &Cache{maxItemSize: size / maxItemRatio}
+	switch policy {
+	case PolicyLRU:
+		c.store = newLRU(size)
+	case PolicyTinyLFU:
+		counters := size / tinyLFUCountersPerByte
+		if counters < minTinyLFUCounters {
+			counters = minTinyLFUCounters
+		}
+		r, err := ristretto.NewCache(&ristretto.Config{
+			NumCounters: counters,
+			MaxCost:     size,
+			BufferItems: 64,
+		})
+		if err != nil {
+			return nil, err
+		}
+		c.store = &tinyLFU{r: r}
+	default:
+		return nil, CheckPolicy(policy)
+	}
+	return c, nil
+}
+
+// Get returns the value of key. The returned value shouldn't be modified.
+func (c *Cache) Get(key []byte) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	return c.get(string(key))
+}
+
+// Set copies val into the cache. It might be rejected by the policy.
+func (c *Cache) Set(key, val []byte) {
+	if c == nil || int64(len(key)+len(val)) > c.maxItemSize {
+		return
+	}
+	c.set(string(key), append([]byte(nil), val...))
+}
+
+// Del removes the value of key.
+func (c *Cache) Del(key []byte) {
+	if c == nil {
+		return
+	}
+	c.del(string(key))
+}
+
+// Close releases the cache.
+func (c *Cache) Close() {
+	if c == nil {
+		return
+	}
+	c.close()
+}
+
+type lruEntry struct {
+	key string
+	val []byte
+}
+
+type lru struct {
+	entries map[string]*list.Element
+	order   *list.List
+	size    int64
+	maxSize int64
+	mu      sync.Mutex
+}
+
+func newLRU(maxSize int64) *lru {
+	return &lru{
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		maxSize: maxSize,
+	}
+}
+
+func (l *lru) get(key string) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e, ok := l.entries[key]
+	if !ok {
+		return nil, false
+	}
+	l.order.MoveToFront(e)
+	return e.Value.(*lruEntry).val, true
+}
+
+func (l *lru) set(key string, val []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.entries[key]; ok {
+		l.remove(e)
+	}
+	l.entries[key] = l.order.PushFront(&lruEntry{key: key, val: val})
+	l.size += cost(key, val)
+	for l.size > l.maxSize {
+		l.remove(l.order.Back())
+	}
+}
+
+func (l *lru) del(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.entries[key]; ok {
+		l.remove(e)
+	}
+}
+
+func (l *lru) remove(e *list.Element) {
+	entry := l.order.Remove(e).(*lruEntry)
+	delete(l.entries, entry.key)
+	l.size -= cost(entry.key, entry.val)
+}
+
+func (l *lru) close() {}
+
+func cost(key string, val []byte) int64 {
+	return int64(len(key) + len(val))
+}
+
+type tinyLFU struct {
+	r *ristretto.Cache
+}
+
+func (t *tinyLFU) get(key string) ([]byte, bool) {
+	v, ok := t.r.Get(key)
+	if !ok {
+		return nil, false
+	}
+	return v.([]byte), true
+}
+
+func (t *tinyLFU) set(key string, val []byte) {
+	t.r.Set(key, val, cost(key, val))
+}
+
+func (t *tinyLFU) del(key string) {
+	t.r.Del(key)
+}
+
+func (t *tinyLFU) close() {
+	t.r.Close()
+}
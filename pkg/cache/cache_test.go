@@ -0,0 +1,85 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cache_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/pkg/cache"
+)
+
+func TestLRU(t *testing.T) {
+	c, err := cache.New(cache.PolicyLRU, 64)
+	require.NoError(t, err)
+	defer c.Close()
+	// every entry costs 8 bytes
+	for i := 0; i < 8; i++ {
+		c.Set([]byte(fmt.Sprintf("k%d", i)), []byte("value0"))
+	}
+	_, ok := c.Get([]byte("k0"))
+	assert.True(t, ok)
+	c.Set([]byte("k8"), []byte("value0"))
+	_, ok = c.Get([]byte("k1"))
+	assert.False(t, ok, "k1 is the least recently used one")
+	_, ok = c.Get([]byte("k0"))
+	assert.True(t, ok)
+
+	c.Del([]byte("k0"))
+	_, ok = c.Get([]byte("k0"))
+	assert.False(t, ok)
+
+	c.Set([]byte("large"), make([]byte, 16))
+	_, ok = c.Get([]byte("large"))
+	assert.False(t, ok, "the value larger than 1/8 of the cache isn't admitted")
+}
+
+func TestTinyLFU(t *testing.T) {
+	c, err := cache.New(cache.PolicyTinyLFU, 1<<20)
+	require.NoError(t, err)
+	defer c.Close()
+	val := []byte("value")
+	c.Set([]byte("key"), val)
+	val[0] = 'V'
+	assert.Eventually(t, func() bool {
+		v, ok := c.Get([]byte("key"))
+		return ok && string(v) == "value"
+	}, time.Second, 10*time.Millisecond)
+	c.Del([]byte("key"))
+	assert.Eventually(t, func() bool {
+		_, ok := c.Get([]byte("key"))
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestDisabled(t *testing.T) {
+	c, err := cache.New(cache.PolicyLRU, 0)
+	require.NoError(t, err)
+	assert.Nil(t, c)
+	c.Set([]byte("key"), []byte("value"))
+	_, ok := c.Get([]byte("key"))
+	assert.False(t, ok)
+	c.Close()
+
+	_, err = cache.New("fifo", 1)
+	assert.ErrorIs(t, err, cache.ErrUnknownPolicy)
+}
@@ -0,0 +1,174 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+)
+
+// BatchWriter buffers the write requests, and sends them once the buffer is full or the interval elapses.
+type BatchWriter[Req any] struct {
+	w *Writer[Req]
+	// merge folds the buffered requests into fewer ones, e.g. the elements of a stream into a batched request
+	merge  func([]Req) []Req
+	stop   chan struct{}
+	done   chan struct{}
+	err    error
+	buf    []Req
+	size   int
+	mu     sync.Mutex
+	closed bool
+}
+
+func newBatchWriter[Req any](w *Writer[Req], size int, interval time.Duration, merge func([]Req) []Req) *BatchWriter[Req] {
+	if size < 1 {
+		size = 1
+	}
+	b := &BatchWriter[Req]{
+		w:     w,
+		merge: merge,
+		size:  size,
+		buf:   make([]Req, 0, size),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go func() {
+		defer close(b.done)
+		if interval <= 0 {
+			<-b.stop
+			return
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-b.stop:
+				return
+			case <-ticker.C:
+				b.mu.Lock()
+				if err := b.flush(); err != nil {
+					l.Warn().Err(err).Msg("fail to flush the batch")
+					b.err = err
+				}
+				b.mu.Unlock()
+			}
+		}
+	}()
+	return b
+}
+
+// StreamBatchWriter opens the batch writer of the elements, which merges the elements sharing the metadata
+// into the batched write requests.
+func (c *Client) StreamBatchWriter(ctx context.Context, size int, interval time.Duration,
+	opts ...WriterOption,
+) *BatchWriter[*streamv1.WriteRequest] {
+	return newBatchWriter(c.StreamWriter(ctx, opts...), size, interval, mergeStreamWrites)
+}
+
+// MeasureBatchWriter opens the batch writer of the data points.
+func (c *Client) MeasureBatchWriter(ctx context.Context, size int, interval time.Duration,
+	opts ...WriterOption,
+) *BatchWriter[*measurev1.WriteRequest] {
+	return newBatchWriter(c.MeasureWriter(ctx, opts...), size, interval, nil)
+}
+
+// Add buffers a request. It returns the error of the last flush in the background, if there is one.
+func (b *BatchWriter[Req]) Add(req Req) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+	if err := b.err; err != nil {
+		b.err = nil
+		return err
+	}
+	b.buf = append(b.buf, req)
+	if len(b.buf) < b.size {
+		return nil
+	}
+	return b.flush()
+}
+
+// Flush sends the buffered requests. They are dropped if the writer fails to send them.
+func (b *BatchWriter[Req]) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.flush()
+}
+
+func (b *BatchWriter[Req]) flush() error {
+	if len(b.buf) < 1 {
+		return nil
+	}
+	reqs := b.buf
+	if b.merge != nil {
+		reqs = b.merge(reqs)
+	}
+	b.buf = b.buf[:0]
+	for _, req := range reqs {
+		if err := b.w.Send(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close sends the buffered requests and closes the writer.
+func (b *BatchWriter[Req]) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.mu.Unlock()
+	close(b.stop)
+	<-b.done
+	b.mu.Lock()
+	err := b.flush()
+	b.mu.Unlock()
+	if errClose := b.w.Close(); err == nil {
+		err = errClose
+	}
+	return err
+}
+
+// mergeStreamWrites merges the adjacent requests of the same stream into one request carrying all their elements.
+func mergeStreamWrites(reqs []*streamv1.WriteRequest) []*streamv1.WriteRequest {
+	merged := make([]*streamv1.WriteRequest, 0, 1)
+	var last *streamv1.WriteRequest
+	for _, req := range reqs {
+		if last == nil || !proto.Equal(last.GetMetadata(), req.GetMetadata()) {
+			last = &streamv1.WriteRequest{Metadata: req.GetMetadata()}
+			merged = append(merged, last)
+		}
+		if req.GetElement() != nil {
+			last.Elements = append(last.Elements, req.GetElement())
+		}
+		last.Elements = append(last.Elements, req.GetElements()...)
+	}
+	return merged
+}
@@ -0,0 +1,196 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package client is a typed client of BanyanDB, which writes through the Write streams reopened with a backoff,
+// batches the writes, builds the queries and applies the schemas. The write requests are built by the builders
+// of the pkg/pb/v1 package, e.g. NewStreamWriteRequestBuilder.
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/grpchelper"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+var (
+	l = logger.GetLogger("client")
+
+	// ErrClosed is returned by the writers once they are closed.
+	ErrClosed = errors.New("the writer is closed")
+)
+
+type config struct {
+	dialOpts    []grpc.DialOption
+	connTimeout time.Duration
+	rpcTimeout  time.Duration
+	backoff     backoff
+}
+
+// Option configures a Client.
+type Option func(*config)
+
+// WithDialOptions replaces the default dial options, which connect to the server without TLS.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(c *config) {
+		c.dialOpts = opts
+	}
+}
+
+// WithConnTimeout sets the timeout of connecting to the server.
+func WithConnTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.connTimeout = timeout
+	}
+}
+
+// WithRPCTimeout sets the timeout of the unary calls, e.g. the queries and the registry helpers.
+func WithRPCTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.rpcTimeout = timeout
+	}
+}
+
+// WithBackoff sets the delays between reopening a broken write stream, which begin at initial and double up to max.
+// A write fails after maxRetries reopenings, and zero retries until the context of the writer is done.
+func WithBackoff(initial, max time.Duration, maxRetries int) Option {
+	return func(c *config) {
+		c.backoff = backoff{initial: initial, max: max, maxRetries: maxRetries}
+	}
+}
+
+// Client is a typed client of the stream, measure and registry services.
+type Client struct {
+	conn     grpc.ClientConnInterface
+	closer   func() error
+	stream   streamv1.StreamServiceClient
+	measure  measurev1.MeasureServiceClient
+	registry *Registry
+	cfg      config
+}
+
+// New connects to the server at addr.
+func New(addr string, opts ...Option) (*Client, error) {
+	cfg := newConfig(opts)
+	conn, err := grpchelper.Conn(addr, cfg.connTimeout, cfg.dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	c := newClient(conn, cfg)
+	c.closer = conn.Close
+	return c, nil
+}
+
+// NewWithConn wraps an established connection, which is not closed by the client.
+func NewWithConn(conn grpc.ClientConnInterface, opts ...Option) *Client {
+	return newClient(conn, newConfig(opts))
+}
+
+func newConfig(opts []Option) config {
+	cfg := config{
+		dialOpts:    []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+		connTimeout: 10 * time.Second,
+		rpcTimeout:  30 * time.Second,
+		backoff:     backoff{initial: 100 * time.Millisecond, max: 10 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+func newClient(conn grpc.ClientConnInterface, cfg config) *Client {
+	c := &Client{
+		conn:    conn,
+		stream:  streamv1.NewStreamServiceClient(conn),
+		measure: measurev1.NewMeasureServiceClient(conn),
+		cfg:     cfg,
+	}
+	c.registry = newRegistry(c)
+	return c
+}
+
+// Conn returns the connection, which serves the services not wrapped by the client.
+func (c *Client) Conn() grpc.ClientConnInterface {
+	return c.conn
+}
+
+// Registry returns the helpers of the schema registry.
+func (c *Client) Registry() *Registry {
+	return c.registry
+}
+
+// QueryStream queries the elements of a stream, e.g. by the request built by NewStreamQuery.
+func (c *Client) QueryStream(ctx context.Context, req *streamv1.QueryRequest) (resp *streamv1.QueryResponse, err error) {
+	err = c.request(ctx, func(ctx context.Context) error {
+		resp, err = c.stream.Query(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+// QueryMeasure queries the data points of a measure, e.g. by the request built by NewMeasureQuery.
+func (c *Client) QueryMeasure(ctx context.Context, req *measurev1.QueryRequest) (resp *measurev1.QueryResponse, err error) {
+	err = c.request(ctx, func(ctx context.Context) error {
+		resp, err = c.measure.Query(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+// StreamWriter opens the writer of the elements, which lives until ctx is done or it's closed.
+func (c *Client) StreamWriter(ctx context.Context, opts ...WriterOption) *Writer[*streamv1.WriteRequest] {
+	return newWriter(ctx, c.cfg.backoff, func(ctx context.Context) (writeStream[*streamv1.WriteRequest], error) {
+		s, err := c.stream.Write(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return streamWriteStream{s}, nil
+	}, opts)
+}
+
+// MeasureWriter opens the writer of the data points, which lives until ctx is done or it's closed.
+func (c *Client) MeasureWriter(ctx context.Context, opts ...WriterOption) *Writer[*measurev1.WriteRequest] {
+	return newWriter(ctx, c.cfg.backoff, func(ctx context.Context) (writeStream[*measurev1.WriteRequest], error) {
+		s, err := c.measure.Write(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return measureWriteStream{s}, nil
+	}, opts)
+}
+
+// Close closes the connection opened by New.
+func (c *Client) Close() error {
+	if c.closer == nil {
+		return nil
+	}
+	return c.closer()
+}
+
+func (c *Client) request(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.rpcTimeout)
+	defer cancel()
+	return fn(ctx)
+}
@@ -0,0 +1,203 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package client_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/client"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+)
+
+type fakeStreamService struct {
+	streamv1.UnimplementedStreamServiceServer
+	received []*streamv1.WriteRequest
+	streams  int
+	mu       sync.Mutex
+	// breakFirst breaks the first stream once it's opened
+	breakFirst bool
+}
+
+func (s *fakeStreamService) Write(stream streamv1.StreamService_WriteServer) error {
+	s.mu.Lock()
+	s.streams++
+	first := s.breakFirst && s.streams == 1
+	s.mu.Unlock()
+	if first {
+		return status.Error(codes.Unavailable, "the server is restarting")
+	}
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.mu.Lock()
+		s.received = append(s.received, req)
+		s.mu.Unlock()
+		resp := &streamv1.WriteResponse{}
+		if req.GetElement().GetElementId() == "bad" {
+			resp.Errors = append(resp.Errors, &modelv1.WriteError{Code: modelv1.WriteError_CODE_INVALID_TIMESTAMP, Message: "bad"})
+		}
+		if err = stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *fakeStreamService) requests() []*streamv1.WriteRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*streamv1.WriteRequest(nil), s.received...)
+}
+
+type fakeGroupRegistry struct {
+	databasev1.UnimplementedGroupRegistryServiceServer
+	updated []*commonv1.Group
+}
+
+func (r *fakeGroupRegistry) Create(context.Context, *databasev1.GroupRegistryServiceCreateRequest) (*databasev1.GroupRegistryServiceCreateResponse, error) {
+	return nil, status.Error(codes.AlreadyExists, "the group exists")
+}
+
+func (r *fakeGroupRegistry) Update(_ context.Context, req *databasev1.GroupRegistryServiceUpdateRequest,
+) (*databasev1.GroupRegistryServiceUpdateResponse, error) {
+	r.updated = append(r.updated, req.GetGroup())
+	return &databasev1.GroupRegistryServiceUpdateResponse{}, nil
+}
+
+func setup(t *testing.T, breakFirst bool) (*client.Client, *fakeStreamService, *fakeGroupRegistry) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	streams, groups := &fakeStreamService{breakFirst: breakFirst}, &fakeGroupRegistry{}
+	streamv1.RegisterStreamServiceServer(server, streams)
+	databasev1.RegisterGroupRegistryServiceServer(server, groups)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	c, err := client.New(lis.Addr().String(), client.WithBackoff(10*time.Millisecond, 100*time.Millisecond, 5))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = c.Close()
+		server.Stop()
+	})
+	return c, streams, groups
+}
+
+func element(id string) *streamv1.WriteRequest {
+	return pbv1.NewStreamWriteRequestBuilder().Metadata("default", "sw").ID(id).Timestamp(time.Now()).
+		TagFamily("svc").Build()
+}
+
+func TestWriterReopensBrokenStream(t *testing.T) {
+	c, streams, _ := setup(t, true)
+	rejected := make(chan *modelv1.WriteError, 1)
+	w := c.StreamWriter(context.Background(), client.OnWriteError(func(e *modelv1.WriteError) {
+		rejected <- e
+	}))
+	// the requests sent to the broken stream are lost until it's reopened
+	require.Eventually(t, func() bool {
+		return assert.NoError(t, w.Send(element("1"))) && len(streams.requests()) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, w.Send(element("bad")))
+	require.NoError(t, w.Close())
+	assert.Equal(t, modelv1.WriteError_CODE_INVALID_TIMESTAMP, (<-rejected).GetCode())
+	assert.ErrorIs(t, w.Send(element("2")), client.ErrClosed)
+	streams.mu.Lock()
+	defer streams.mu.Unlock()
+	assert.GreaterOrEqual(t, streams.streams, 2)
+}
+
+func TestBatchWriterMergesElements(t *testing.T) {
+	c, streams, _ := setup(t, false)
+	w := c.StreamBatchWriter(context.Background(), 3, time.Hour)
+	for _, id := range []string{"1", "2", "3", "4"} {
+		require.NoError(t, w.Add(element(id)))
+	}
+	require.NoError(t, w.Close())
+	reqs := streams.requests()
+	// the full batch is sent at once, and the rest is flushed by Close
+	require.Len(t, reqs, 2)
+	ids := func(req *streamv1.WriteRequest) (result []string) {
+		for _, e := range req.GetElements() {
+			result = append(result, e.GetElementId())
+		}
+		return result
+	}
+	assert.Equal(t, []string{"1", "2", "3"}, ids(reqs[0]))
+	assert.Equal(t, []string{"4"}, ids(reqs[1]))
+	assert.Nil(t, reqs[0].GetElement())
+	assert.Equal(t, "sw", reqs[0].GetMetadata().GetName())
+}
+
+func TestRegistryApply(t *testing.T) {
+	c, _, groups := setup(t, false)
+	g := &commonv1.Group{Metadata: &commonv1.Metadata{Name: "default"}, Catalog: commonv1.Catalog_CATALOG_STREAM}
+	require.NoError(t, c.Registry().ApplyGroup(context.Background(), g))
+	require.Len(t, groups.updated, 1)
+	assert.Equal(t, "default", groups.updated[0].GetMetadata().GetName())
+}
+
+func TestQueryBuilders(t *testing.T) {
+	begin := time.Now().Add(-time.Hour)
+	req := client.NewStreamQuery("default", "sw", begin, time.Now()).
+		Project("searchable", "trace_id").
+		Project("searchable", "state").
+		Where(client.And(client.Eq("state", client.Int(1)), client.Eq("service_id", client.Str("svc")))).
+		OrderBy("duration", modelv1.Sort_SORT_DESC).
+		Limit(0, 10).
+		Build()
+	require.Len(t, req.GetProjection().GetTagFamilies(), 1)
+	assert.Equal(t, []string{"trace_id", "state"}, req.GetProjection().GetTagFamilies()[0].GetTags())
+	le := req.GetCriteria().GetLe()
+	require.NotNil(t, le)
+	assert.Equal(t, modelv1.LogicalExpression_LOGICAL_OP_AND, le.GetOp())
+	assert.Equal(t, "state", le.GetLeft().GetCondition().GetName())
+	assert.Equal(t, "svc", le.GetRight().GetCondition().GetValue().GetStr().GetValue())
+	assert.Equal(t, uint32(10), req.GetLimit())
+
+	m := client.NewMeasureQuery("default", "service_cpm", begin, time.Now()).
+		Fields("total").
+		GroupBy(modelv1.AggregationFunction_AGGREGATION_FUNCTION_SUM, "total", "default", "entity_id").
+		TimeBucket(time.Hour, "Asia/Shanghai").
+		Top(5, "total", modelv1.Sort_SORT_DESC).
+		Build()
+	assert.Equal(t, []string{"total"}, m.GetFieldProjection().GetNames())
+	assert.Equal(t, "total", m.GetAgg().GetFieldName())
+	assert.Equal(t, time.Hour, m.GetGroupBy().GetTimeBucket().GetInterval().AsDuration())
+	assert.Equal(t, "Asia/Shanghai", m.GetGroupBy().GetTimeBucket().GetTimeZone())
+	assert.Equal(t, int32(5), m.GetTop().GetNumber())
+}
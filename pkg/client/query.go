@@ -0,0 +1,219 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package client
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+)
+
+// Str returns a string tag value.
+func Str(v string) *modelv1.TagValue {
+	return &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: v}}}
+}
+
+// Int returns an integer tag value.
+func Int(v int64) *modelv1.TagValue {
+	return &modelv1.TagValue{Value: &modelv1.TagValue_Int{Int: &modelv1.Int{Value: v}}}
+}
+
+// StrArray returns a string array tag value.
+func StrArray(v ...string) *modelv1.TagValue {
+	return &modelv1.TagValue{Value: &modelv1.TagValue_StrArray{StrArray: &modelv1.StrArray{Value: v}}}
+}
+
+// IntArray returns an integer array tag value.
+func IntArray(v ...int64) *modelv1.TagValue {
+	return &modelv1.TagValue{Value: &modelv1.TagValue_IntArray{IntArray: &modelv1.IntArray{Value: v}}}
+}
+
+// Cond returns the criteria of a condition on a tag.
+func Cond(name string, op modelv1.Condition_BinaryOp, value *modelv1.TagValue) *modelv1.Criteria {
+	return &modelv1.Criteria{Exp: &modelv1.Criteria_Condition{Condition: &modelv1.Condition{Name: name, Op: op, Value: value}}}
+}
+
+// Eq returns the criteria of a tag equal to value.
+func Eq(name string, value *modelv1.TagValue) *modelv1.Criteria {
+	return Cond(name, modelv1.Condition_BINARY_OP_EQ, value)
+}
+
+// And returns the criteria matching all the criteria.
+func And(criteria ...*modelv1.Criteria) *modelv1.Criteria {
+	return logical(modelv1.LogicalExpression_LOGICAL_OP_AND, criteria)
+}
+
+// Or returns the criteria matching any of the criteria.
+func Or(criteria ...*modelv1.Criteria) *modelv1.Criteria {
+	return logical(modelv1.LogicalExpression_LOGICAL_OP_OR, criteria)
+}
+
+func logical(op modelv1.LogicalExpression_LogicalOp, criteria []*modelv1.Criteria) *modelv1.Criteria {
+	if len(criteria) < 1 {
+		return nil
+	}
+	result := criteria[0]
+	for _, c := range criteria[1:] {
+		result = &modelv1.Criteria{Exp: &modelv1.Criteria_Le{Le: &modelv1.LogicalExpression{Op: op, Left: result, Right: c}}}
+	}
+	return result
+}
+
+func timeRange(begin, end time.Time) *modelv1.TimeRange {
+	return &modelv1.TimeRange{Begin: timestamppb.New(begin), End: timestamppb.New(end)}
+}
+
+// appendProjection adds the tags of a family to a projection, which merges the tags of the same family.
+func appendProjection(p *modelv1.TagProjection, family string, tags []string) *modelv1.TagProjection {
+	if p == nil {
+		p = &modelv1.TagProjection{}
+	}
+	for _, f := range p.TagFamilies {
+		if f.Name == family {
+			f.Tags = append(f.Tags, tags...)
+			return p
+		}
+	}
+	p.TagFamilies = append(p.TagFamilies, &modelv1.TagProjection_TagFamily{Name: family, Tags: tags})
+	return p
+}
+
+// StreamQueryBuilder builds the query requests of a stream.
+type StreamQueryBuilder struct {
+	req *streamv1.QueryRequest
+}
+
+// NewStreamQuery begins the query of the elements of a stream in the time range [begin, end).
+func NewStreamQuery(group, name string, begin, end time.Time) *StreamQueryBuilder {
+	return &StreamQueryBuilder{req: &streamv1.QueryRequest{
+		Metadata:  &commonv1.Metadata{Group: group, Name: name},
+		TimeRange: timeRange(begin, end),
+	}}
+}
+
+// Project adds the tags of a family to the projection.
+func (b *StreamQueryBuilder) Project(family string, tags ...string) *StreamQueryBuilder {
+	b.req.Projection = appendProjection(b.req.Projection, family, tags)
+	return b
+}
+
+// Where sets the criteria, e.g. built by And, Or and Eq.
+func (b *StreamQueryBuilder) Where(criteria *modelv1.Criteria) *StreamQueryBuilder {
+	b.req.Criteria = criteria
+	return b
+}
+
+// OrderBy sorts the elements by the index rule, or by time if it's empty.
+func (b *StreamQueryBuilder) OrderBy(indexRule string, sort modelv1.Sort) *StreamQueryBuilder {
+	b.req.OrderBy = &modelv1.QueryOrder{IndexRuleName: indexRule, Sort: sort}
+	return b
+}
+
+// Limit sets the offset and the limit of the elements.
+func (b *StreamQueryBuilder) Limit(offset, limit uint32) *StreamQueryBuilder {
+	b.req.Offset, b.req.Limit = offset, limit
+	return b
+}
+
+// Build returns the request.
+func (b *StreamQueryBuilder) Build() *streamv1.QueryRequest {
+	return b.req
+}
+
+// MeasureQueryBuilder builds the query requests of a measure.
+type MeasureQueryBuilder struct {
+	req *measurev1.QueryRequest
+}
+
+// NewMeasureQuery begins the query of the data points of a measure in the time range [begin, end).
+func NewMeasureQuery(group, name string, begin, end time.Time) *MeasureQueryBuilder {
+	return &MeasureQueryBuilder{req: &measurev1.QueryRequest{
+		Metadata:  &commonv1.Metadata{Group: group, Name: name},
+		TimeRange: timeRange(begin, end),
+	}}
+}
+
+// Project adds the tags of a family to the projection.
+func (b *MeasureQueryBuilder) Project(family string, tags ...string) *MeasureQueryBuilder {
+	b.req.TagProjection = appendProjection(b.req.TagProjection, family, tags)
+	return b
+}
+
+// Fields adds the fields to the projection.
+func (b *MeasureQueryBuilder) Fields(names ...string) *MeasureQueryBuilder {
+	if b.req.FieldProjection == nil {
+		b.req.FieldProjection = &measurev1.QueryRequest_FieldProjection{}
+	}
+	b.req.FieldProjection.Names = append(b.req.FieldProjection.Names, names...)
+	return b
+}
+
+// Where sets the criteria, e.g. built by And, Or and Eq.
+func (b *MeasureQueryBuilder) Where(criteria *modelv1.Criteria) *MeasureQueryBuilder {
+	b.req.Criteria = criteria
+	return b
+}
+
+// GroupBy groups the data points by the tags of a family, and aggregates the field of each group by fn.
+func (b *MeasureQueryBuilder) GroupBy(fn modelv1.AggregationFunction, field, family string, tags ...string) *MeasureQueryBuilder {
+	b.req.GroupBy = &measurev1.QueryRequest_GroupBy{
+		TagProjection: appendProjection(nil, family, tags),
+		FieldName:     field,
+	}
+	b.req.Agg = &measurev1.QueryRequest_Aggregation{Function: fn, FieldName: field}
+	return b
+}
+
+// TimeBucket groups the data points of GroupBy by the buckets of their timestamps as well, which are aligned to the time zone,
+// e.g. Asia/Shanghai or +08:00, so that the daily buckets begin at the local midnight.
+func (b *MeasureQueryBuilder) TimeBucket(interval time.Duration, timeZone string) *MeasureQueryBuilder {
+	if b.req.GroupBy == nil {
+		b.req.GroupBy = &measurev1.QueryRequest_GroupBy{}
+	}
+	b.req.GroupBy.TimeBucket = &measurev1.QueryRequest_GroupBy_TimeBucket{Interval: durationpb.New(interval), TimeZone: timeZone}
+	return b
+}
+
+// Top returns the top n data points by the field.
+func (b *MeasureQueryBuilder) Top(n int32, field string, sort modelv1.Sort) *MeasureQueryBuilder {
+	b.req.Top = &measurev1.QueryRequest_Top{Number: n, FieldName: field, FieldValueSort: sort}
+	return b
+}
+
+// OrderBy sorts the data points by the index rule, or by time if it's empty.
+func (b *MeasureQueryBuilder) OrderBy(indexRule string, sort modelv1.Sort) *MeasureQueryBuilder {
+	b.req.OrderBy = &modelv1.QueryOrder{IndexRuleName: indexRule, Sort: sort}
+	return b
+}
+
+// Limit sets the offset and the limit of the data points.
+func (b *MeasureQueryBuilder) Limit(offset, limit uint32) *MeasureQueryBuilder {
+	b.req.Offset, b.req.Limit = offset, limit
+	return b
+}
+
+// Build returns the request.
+func (b *MeasureQueryBuilder) Build() *measurev1.QueryRequest {
+	return b.req
+}
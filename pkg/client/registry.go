@@ -0,0 +1,156 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package client
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+)
+
+// Registry wraps the registry services. The Apply helpers create a schema, or update it if it exists,
+// so that an embedder declares its schemas idempotently at startup.
+type Registry struct {
+	c                 *Client
+	groups            databasev1.GroupRegistryServiceClient
+	streams           databasev1.StreamRegistryServiceClient
+	measures          databasev1.MeasureRegistryServiceClient
+	indexRules        databasev1.IndexRuleRegistryServiceClient
+	indexRuleBindings databasev1.IndexRuleBindingRegistryServiceClient
+	topNAggregations  databasev1.TopNAggregationRegistryServiceClient
+}
+
+func newRegistry(c *Client) *Registry {
+	return &Registry{
+		c:                 c,
+		groups:            databasev1.NewGroupRegistryServiceClient(c.conn),
+		streams:           databasev1.NewStreamRegistryServiceClient(c.conn),
+		measures:          databasev1.NewMeasureRegistryServiceClient(c.conn),
+		indexRules:        databasev1.NewIndexRuleRegistryServiceClient(c.conn),
+		indexRuleBindings: databasev1.NewIndexRuleBindingRegistryServiceClient(c.conn),
+		topNAggregations:  databasev1.NewTopNAggregationRegistryServiceClient(c.conn),
+	}
+}
+
+// apply creates a schema, and updates it if it already exists.
+func (r *Registry) apply(ctx context.Context, create, update func(ctx context.Context) error) error {
+	return r.c.request(ctx, func(ctx context.Context) error {
+		err := create(ctx)
+		if status.Code(err) != codes.AlreadyExists {
+			return err
+		}
+		return update(ctx)
+	})
+}
+
+// ApplyGroup creates or updates a group.
+func (r *Registry) ApplyGroup(ctx context.Context, group *commonv1.Group) error {
+	return r.apply(ctx, func(ctx context.Context) error {
+		_, err := r.groups.Create(ctx, &databasev1.GroupRegistryServiceCreateRequest{Group: group})
+		return err
+	}, func(ctx context.Context) error {
+		_, err := r.groups.Update(ctx, &databasev1.GroupRegistryServiceUpdateRequest{Group: group})
+		return err
+	})
+}
+
+// ApplyStream creates or updates a stream.
+func (r *Registry) ApplyStream(ctx context.Context, stream *databasev1.Stream) error {
+	return r.apply(ctx, func(ctx context.Context) error {
+		_, err := r.streams.Create(ctx, &databasev1.StreamRegistryServiceCreateRequest{Stream: stream})
+		return err
+	}, func(ctx context.Context) error {
+		_, err := r.streams.Update(ctx, &databasev1.StreamRegistryServiceUpdateRequest{Stream: stream})
+		return err
+	})
+}
+
+// ApplyMeasure creates or updates a measure.
+func (r *Registry) ApplyMeasure(ctx context.Context, measure *databasev1.Measure) error {
+	return r.apply(ctx, func(ctx context.Context) error {
+		_, err := r.measures.Create(ctx, &databasev1.MeasureRegistryServiceCreateRequest{Measure: measure})
+		return err
+	}, func(ctx context.Context) error {
+		_, err := r.measures.Update(ctx, &databasev1.MeasureRegistryServiceUpdateRequest{Measure: measure})
+		return err
+	})
+}
+
+// ApplyIndexRule creates or updates an index rule.
+func (r *Registry) ApplyIndexRule(ctx context.Context, indexRule *databasev1.IndexRule) error {
+	return r.apply(ctx, func(ctx context.Context) error {
+		_, err := r.indexRules.Create(ctx, &databasev1.IndexRuleRegistryServiceCreateRequest{IndexRule: indexRule})
+		return err
+	}, func(ctx context.Context) error {
+		_, err := r.indexRules.Update(ctx, &databasev1.IndexRuleRegistryServiceUpdateRequest{IndexRule: indexRule})
+		return err
+	})
+}
+
+// ApplyIndexRuleBinding creates or updates an index rule binding.
+func (r *Registry) ApplyIndexRuleBinding(ctx context.Context, binding *databasev1.IndexRuleBinding) error {
+	return r.apply(ctx, func(ctx context.Context) error {
+		_, err := r.indexRuleBindings.Create(ctx, &databasev1.IndexRuleBindingRegistryServiceCreateRequest{IndexRuleBinding: binding})
+		return err
+	}, func(ctx context.Context) error {
+		_, err := r.indexRuleBindings.Update(ctx, &databasev1.IndexRuleBindingRegistryServiceUpdateRequest{IndexRuleBinding: binding})
+		return err
+	})
+}
+
+// ApplyTopNAggregation creates or updates a TopN aggregation.
+func (r *Registry) ApplyTopNAggregation(ctx context.Context, topN *databasev1.TopNAggregation) error {
+	return r.apply(ctx, func(ctx context.Context) error {
+		_, err := r.topNAggregations.Create(ctx, &databasev1.TopNAggregationRegistryServiceCreateRequest{TopNAggregation: topN})
+		return err
+	}, func(ctx context.Context) error {
+		_, err := r.topNAggregations.Update(ctx, &databasev1.TopNAggregationRegistryServiceUpdateRequest{TopNAggregation: topN})
+		return err
+	})
+}
+
+// GetStream returns a stream, or false if it doesn't exist.
+func (r *Registry) GetStream(ctx context.Context, md *commonv1.Metadata) (stream *databasev1.Stream, ok bool, err error) {
+	err = r.c.request(ctx, func(ctx context.Context) error {
+		resp, errGet := r.streams.Get(ctx, &databasev1.StreamRegistryServiceGetRequest{Metadata: md})
+		stream = resp.GetStream()
+		return errGet
+	})
+	return notFound(stream, err)
+}
+
+// GetMeasure returns a measure, or false if it doesn't exist.
+func (r *Registry) GetMeasure(ctx context.Context, md *commonv1.Metadata) (measure *databasev1.Measure, ok bool, err error) {
+	err = r.c.request(ctx, func(ctx context.Context) error {
+		resp, errGet := r.measures.Get(ctx, &databasev1.MeasureRegistryServiceGetRequest{Metadata: md})
+		measure = resp.GetMeasure()
+		return errGet
+	})
+	return notFound(measure, err)
+}
+
+func notFound[T any](v T, err error) (T, bool, error) {
+	if status.Code(err) == codes.NotFound {
+		return v, false, nil
+	}
+	return v, err == nil, err
+}
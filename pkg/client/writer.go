@@ -0,0 +1,230 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+)
+
+type backoff struct {
+	initial    time.Duration
+	max        time.Duration
+	maxRetries int
+}
+
+// delay returns the delay before the attempt-th retry, which starts from 0.
+func (b backoff) delay(attempt int) time.Duration {
+	d := b.initial
+	for i := 0; i < attempt && d < b.max; i++ {
+		d *= 2
+	}
+	if d > b.max {
+		return b.max
+	}
+	return d
+}
+
+// writeStream is the Write stream of the stream or measure service.
+type writeStream[Req any] interface {
+	Send(Req) error
+	CloseSend() error
+	recvErrors() ([]*modelv1.WriteError, error)
+}
+
+type streamWriteStream struct {
+	streamv1.StreamService_WriteClient
+}
+
+func (s streamWriteStream) recvErrors() ([]*modelv1.WriteError, error) {
+	resp, err := s.Recv()
+	return resp.GetErrors(), err
+}
+
+type measureWriteStream struct {
+	measurev1.MeasureService_WriteClient
+}
+
+func (s measureWriteStream) recvErrors() ([]*modelv1.WriteError, error) {
+	resp, err := s.Recv()
+	return resp.GetErrors(), err
+}
+
+type writerConfig struct {
+	onError func(*modelv1.WriteError)
+}
+
+// WriterOption configures a Writer.
+type WriterOption func(*writerConfig)
+
+// OnWriteError sets the handler of the items rejected by the server, which is called from the goroutine receiving the responses.
+func OnWriteError(handler func(*modelv1.WriteError)) WriterOption {
+	return func(c *writerConfig) {
+		c.onError = handler
+	}
+}
+
+// Writer sends the write requests on a Write stream, and reopens the stream with an exponential backoff once it's broken.
+// The requests sent just before the stream is broken might be lost, i.e. they are written at most once.
+type Writer[Req any] struct {
+	ctx     context.Context
+	stream  writeStream[Req]
+	open    func(ctx context.Context) (writeStream[Req], error)
+	cancel  context.CancelFunc
+	onError func(*modelv1.WriteError)
+	// received is closed once the responses of the current stream are all received
+	received chan struct{}
+	// broken is the error ending the current stream, which is set before received is closed
+	broken       error
+	cancelStream context.CancelFunc
+	backoff      backoff
+	mu           sync.Mutex
+	closed       bool
+}
+
+func newWriter[Req any](ctx context.Context, b backoff, open func(ctx context.Context) (writeStream[Req], error),
+	opts []WriterOption,
+) *Writer[Req] {
+	cfg := writerConfig{onError: func(e *modelv1.WriteError) {
+		l.Warn().Str("code", e.GetCode().String()).Str("message", e.GetMessage()).Msg("the item is rejected")
+	}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	w := &Writer[Req]{
+		open:    open,
+		onError: cfg.onError,
+		backoff: b,
+	}
+	w.ctx, w.cancel = context.WithCancel(ctx)
+	return w
+}
+
+// Send sends a request, which opens the stream if it's not open or is broken.
+// It fails once the retries are exhausted, the context of the writer is done or the server refuses the stream for good.
+func (w *Writer[Req]) Send(req Req) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	for attempt := 0; ; attempt++ {
+		err := w.send(req)
+		if err == nil {
+			return nil
+		}
+		if w.ctx.Err() != nil {
+			return w.ctx.Err()
+		}
+		if !retryable(err) || (w.backoff.maxRetries > 0 && attempt >= w.backoff.maxRetries) {
+			return err
+		}
+		delay := w.backoff.delay(attempt)
+		l.Warn().Err(err).Dur("delay", delay).Int("attempt", attempt+1).Msg("the write stream is broken, reopening it")
+		select {
+		case <-w.ctx.Done():
+			return w.ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (w *Writer[Req]) send(req Req) error {
+	if w.stream == nil {
+		if err := w.connect(); err != nil {
+			return err
+		}
+	}
+	if err := w.stream.Send(req); err != nil {
+		// Send only reports io.EOF, the cause is returned by Recv
+		w.reset()
+		<-w.received
+		if w.broken != nil {
+			return w.broken
+		}
+		return err
+	}
+	return nil
+}
+
+func (w *Writer[Req]) connect() error {
+	ctx, cancel := context.WithCancel(w.ctx)
+	s, err := w.open(ctx)
+	if err != nil {
+		cancel()
+		return err
+	}
+	w.stream, w.cancelStream, w.received, w.broken = s, cancel, make(chan struct{}), nil
+	go func(received chan struct{}) {
+		defer close(received)
+		for {
+			errs, err := s.recvErrors()
+			if err != nil {
+				w.broken = err
+				return
+			}
+			for _, e := range errs {
+				w.onError(e)
+			}
+		}
+	}(w.received)
+	return nil
+}
+
+func (w *Writer[Req]) reset() {
+	w.cancelStream()
+	w.stream = nil
+}
+
+// Close half-closes the stream and waits for the responses of the requests sent.
+func (w *Writer[Req]) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer w.cancel()
+	if w.stream == nil {
+		return nil
+	}
+	err := w.stream.CloseSend()
+	select {
+	case <-w.received:
+	case <-w.ctx.Done():
+	}
+	w.reset()
+	return err
+}
+
+// retryable tells whether a broken stream is worth reopening.
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.NotFound, codes.PermissionDenied, codes.Unauthenticated, codes.Unimplemented:
+		return false
+	}
+	return true
+}
@@ -101,3 +101,18 @@ func bindFlags(fs *pflag.FlagSet, v *viper.Viper) error {
 	})
 	return err
 }
+
+// ReadFlags reads the values of the flags from the config file, whose format is told by its extension, e.g. yaml or json.
+// The nested keys are joined by dots, e.g. the level of logging is the flag logging.level.
+func ReadFlags(path string) (map[string]string, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	for _, key := range v.AllKeys() {
+		values[key] = v.GetString(key)
+	}
+	return values, nil
+}
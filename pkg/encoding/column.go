@@ -0,0 +1,391 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package encoding
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/apache/skywalking-banyandb/pkg/convert"
+)
+
+// MaxDictionarySize is the max number of the distinct values in a series encoded by the dictionary.
+const MaxDictionarySize = 256
+
+type columnKind uint8
+
+const (
+	// columnDelta encodes the int values by the zigzag varints of their deltas
+	columnDelta columnKind = iota
+	// columnDictionary encodes the values by the indices of a dictionary of the distinct ones
+	columnDictionary
+)
+
+func (k columnKind) String() string {
+	if k == columnDelta {
+		return "delta"
+	}
+	return "dictionary"
+}
+
+var (
+	columnEncoderPool = sync.Pool{
+		New: func() interface{} {
+			return &columnEncoder{}
+		},
+	}
+	columnDecoderPool = sync.Pool{
+		New: func() interface{} {
+			return &columnDecoder{}
+		},
+	}
+)
+
+type columnEncoderPoolDelegator struct {
+	name string
+	kind columnKind
+	size int
+}
+
+// NewDeltaEncoderPool returns the pool of the encoders encoding the timestamps by the delta-of-delta
+// and the int values by the zigzag varints of their deltas. An encoder is full once it holds size items.
+func NewDeltaEncoderPool(name string, size int) SeriesEncoderPool {
+	return &columnEncoderPoolDelegator{
+		name: name,
+		kind: columnDelta,
+		size: size,
+	}
+}
+
+// NewDictionaryEncoderPool returns the pool of the encoders encoding the timestamps by the delta-of-delta
+// and the values by the indices of a dictionary, which suits the values of a low cardinality.
+// An encoder is full once it holds size items or MaxDictionarySize distinct values.
+func NewDictionaryEncoderPool(name string, size int) SeriesEncoderPool {
+	return &columnEncoderPoolDelegator{
+		name: name,
+		kind: columnDictionary,
+		size: size,
+	}
+}
+
+func (b *columnEncoderPoolDelegator) Get(metadata []byte) SeriesEncoder {
+	encoder := columnEncoderPool.Get().(*columnEncoder)
+	encoder.name = b.name
+	encoder.kind = b.kind
+	encoder.size = b.size
+	encoder.Reset(metadata)
+	return encoder
+}
+
+func (b *columnEncoderPoolDelegator) Put(encoder SeriesEncoder) {
+	if e, ok := encoder.(*columnEncoder); ok && e.kind == b.kind {
+		columnEncoderPool.Put(encoder)
+	}
+}
+
+type columnDecoderPoolDelegator struct {
+	name string
+	kind columnKind
+	size int
+}
+
+// NewDeltaDecoderPool returns the pool of the decoders of the ones encoded by the encoders of NewDeltaEncoderPool.
+func NewDeltaDecoderPool(name string, size int) SeriesDecoderPool {
+	return &columnDecoderPoolDelegator{
+		name: name,
+		kind: columnDelta,
+		size: size,
+	}
+}
+
+// NewDictionaryDecoderPool returns the pool of the decoders of the ones encoded by the encoders of NewDictionaryEncoderPool.
+func NewDictionaryDecoderPool(name string, size int) SeriesDecoderPool {
+	return &columnDecoderPoolDelegator{
+		name: name,
+		kind: columnDictionary,
+		size: size,
+	}
+}
+
+func (b *columnDecoderPoolDelegator) Get(_ []byte) SeriesDecoder {
+	decoder := columnDecoderPool.Get().(*columnDecoder)
+	decoder.name = b.name
+	decoder.kind = b.kind
+	decoder.size = b.size
+	return decoder
+}
+
+func (b *columnDecoderPoolDelegator) Put(decoder SeriesDecoder) {
+	if d, ok := decoder.(*columnDecoder); ok && d.kind == b.kind {
+		columnDecoderPool.Put(decoder)
+	}
+}
+
+var (
+	_ SeriesEncoder = (*columnEncoder)(nil)
+	_ SeriesDecoder = (*columnDecoder)(nil)
+)
+
+// columnEncoder encodes the timestamps and the values of a series in two columns:
+//
+//	uvarint(num) | timestamps | values
+//
+// The timestamps are the first one, the zigzag varint of the first delta and the ones of the delta-of-deltas.
+// The values are the zigzag varints of the deltas of the int values,
+// or the dictionary of the distinct values followed by the uvarint indices of the values.
+type columnEncoder struct {
+	name      string
+	kind      columnKind
+	size      int
+	times     []uint64
+	values    [][]byte
+	dict      map[string]uint64
+	startTime uint64
+	rawSize   int
+}
+
+func (e *columnEncoder) Append(ts uint64, value []byte) {
+	if e.startTime == 0 || ts < e.startTime {
+		e.startTime = ts
+	}
+	e.times = append(e.times, ts)
+	e.values = append(e.values, append([]byte(nil), value...))
+	e.rawSize += len(value) + 8
+	if e.kind == columnDictionary {
+		if _, ok := e.dict[string(value)]; !ok {
+			e.dict[string(value)] = uint64(len(e.dict))
+		}
+	}
+}
+
+func (e *columnEncoder) IsFull() bool {
+	return len(e.times) >= e.size || len(e.dict) >= MaxDictionarySize
+}
+
+func (e *columnEncoder) Reset(_ []byte) {
+	e.times = e.times[:0]
+	e.values = e.values[:0]
+	e.dict = make(map[string]uint64)
+	e.startTime = 0
+	e.rawSize = 0
+}
+
+func (e *columnEncoder) Encode() ([]byte, error) {
+	if len(e.times) < 1 {
+		return nil, ErrEncodeEmpty
+	}
+	dst := binary.AppendUvarint(make([]byte, 0, e.rawSize/2), uint64(len(e.times)))
+	dst = appendTimestamps(dst, e.times)
+	switch e.kind {
+	case columnDelta:
+		var prev int64
+		for _, v := range e.values {
+			if len(v) != 8 {
+				return nil, errors.WithMessagef(ErrInvalidValue, "the int value is %d bytes", len(v))
+			}
+			val := convert.BytesToInt64(v)
+			dst = binary.AppendVarint(dst, val-prev)
+			prev = val
+		}
+	case columnDictionary:
+		entries := make([][]byte, len(e.dict))
+		for _, v := range e.values {
+			entries[e.dict[string(v)]] = v
+		}
+		dst = binary.AppendUvarint(dst, uint64(len(entries)))
+		for _, entry := range entries {
+			dst = binary.AppendUvarint(dst, uint64(len(entry)))
+			dst = append(dst, entry...)
+		}
+		for _, v := range e.values {
+			dst = binary.AppendUvarint(dst, e.dict[string(v)])
+		}
+	}
+	typ := e.kind.String()
+	itemsNum.WithLabelValues(e.name, typ).Inc()
+	rawSize.WithLabelValues(e.name, typ).Add(float64(e.rawSize))
+	encodedSize.WithLabelValues(e.name, typ).Add(float64(len(dst)))
+	return dst, nil
+}
+
+func (e *columnEncoder) StartTime() uint64 {
+	return e.startTime
+}
+
+func appendTimestamps(dst []byte, times []uint64) []byte {
+	dst = binary.AppendUvarint(dst, times[0])
+	var prevDelta int64
+	for i := 1; i < len(times); i++ {
+		delta := int64(times[i] - times[i-1])
+		dst = binary.AppendVarint(dst, delta-prevDelta)
+		prevDelta = delta
+	}
+	return dst
+}
+
+// columnDecoder decodes the series encoded by columnEncoder.
+type columnDecoder struct {
+	name     string
+	kind     columnKind
+	size     int
+	times    []uint64
+	values   [][]byte
+	dictSize int
+}
+
+func (d *columnDecoder) Decode(_, data []byte) error {
+	r := &varintReader{data: data}
+	num := int(r.uvarint())
+	if r.err != nil || num < 1 {
+		return errors.WithMessage(ErrInvalidValue, "malformed item number")
+	}
+	d.times = d.times[:0]
+	d.values = d.values[:0]
+	ts := r.uvarint()
+	d.times = append(d.times, ts)
+	var delta int64
+	for i := 1; i < num; i++ {
+		delta += r.varint()
+		ts += uint64(delta)
+		d.times = append(d.times, ts)
+	}
+	switch d.kind {
+	case columnDelta:
+		var val int64
+		for i := 0; i < num; i++ {
+			val += r.varint()
+			d.values = append(d.values, convert.Int64ToBytes(val))
+		}
+		d.dictSize = 0
+	case columnDictionary:
+		entries := make([][]byte, r.uvarint())
+		for i := range entries {
+			entries[i] = r.bytes(int(r.uvarint()))
+		}
+		for i := 0; i < num; i++ {
+			index := r.uvarint()
+			if index >= uint64(len(entries)) {
+				return errors.WithMessagef(ErrInvalidValue, "the dictionary index %d is out of %d entries", index, len(entries))
+			}
+			d.values = append(d.values, entries[index])
+		}
+		d.dictSize = len(entries)
+	}
+	if r.err != nil {
+		return errors.WithMessagef(ErrInvalidValue, "malformed %s series: %v", d.kind, r.err)
+	}
+	return nil
+}
+
+func (d *columnDecoder) Len() int {
+	return len(d.times)
+}
+
+func (d *columnDecoder) IsFull() bool {
+	return len(d.times) >= d.size || d.dictSize >= MaxDictionarySize
+}
+
+func (d *columnDecoder) Get(ts uint64) ([]byte, error) {
+	for i, t := range d.times {
+		if t == ts {
+			return d.values[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%d doesn't exist", ts)
+}
+
+func (d *columnDecoder) Iterator() SeriesIterator {
+	return &columnIterator{
+		times:  d.times,
+		values: d.values,
+		idx:    -1,
+	}
+}
+
+var _ SeriesIterator = (*columnIterator)(nil)
+
+type columnIterator struct {
+	times  []uint64
+	values [][]byte
+	idx    int
+}
+
+func (i *columnIterator) Next() bool {
+	i.idx++
+	return i.idx < len(i.times)
+}
+
+func (i *columnIterator) Val() []byte {
+	return i.values[i.idx]
+}
+
+func (i *columnIterator) Time() uint64 {
+	return i.times[i.idx]
+}
+
+func (i *columnIterator) Error() error {
+	return nil
+}
+
+// varintReader reads the varints in sequence, and keeps the first error.
+type varintReader struct {
+	err  error
+	data []byte
+}
+
+func (r *varintReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = errors.New("malformed uvarint")
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *varintReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.data)
+	if n <= 0 {
+		r.err = errors.New("malformed varint")
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *varintReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > len(r.data) {
+		r.err = errors.New("truncated bytes")
+		return nil
+	}
+	b := r.data[:n:n]
+	r.data = r.data[n:]
+	return b
+}
@@ -0,0 +1,142 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package encoding
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/apache/skywalking-banyandb/pkg/convert"
+)
+
+func TestNewDeltaEncoderAndDecoder(t *testing.T) {
+	tests := []struct {
+		name string
+		ts   []uint64
+		data []int64
+	}{
+		{
+			name: "golden path",
+			ts:   []uint64{uint64(4 * time.Minute), uint64(3 * time.Minute), uint64(2 * time.Minute), uint64(time.Minute)},
+			data: []int64{7, 8, 7, 9},
+		},
+		{
+			name: "irregular intervals and negative values",
+			ts:   []uint64{uint64(time.Minute), uint64(3 * time.Minute), uint64(4 * time.Minute), uint64(10 * time.Minute)},
+			data: []int64{-1, 1 << 40, 0, -(1 << 40)},
+		},
+		{
+			name: "single item",
+			ts:   []uint64{uint64(time.Minute)},
+			data: []int64{42},
+		},
+	}
+	key := []byte("foo")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := assert.New(t)
+			encoderPool := NewDeltaEncoderPool("minute", 4)
+			decoderPool := NewDeltaDecoderPool("minute", 4)
+			encoder := encoderPool.Get(key)
+			defer encoderPool.Put(encoder)
+			decoder := decoderPool.Get(key)
+			defer decoderPool.Put(decoder)
+			for i, v := range tt.data {
+				encoder.Append(tt.ts[i], convert.Int64ToBytes(v))
+			}
+			at.Equal(len(tt.data) == 4, encoder.IsFull())
+			bb, err := encoder.Encode()
+			at.NoError(err)
+			at.Less(len(bb), len(tt.data)*16)
+
+			at.NoError(decoder.Decode(key, bb))
+			at.Equal(len(tt.data), decoder.Len())
+			i := 0
+			for iter := decoder.Iterator(); iter.Next(); i++ {
+				at.NoError(iter.Error())
+				at.Equal(tt.ts[i], iter.Time())
+				at.Equal(tt.data[i], convert.BytesToInt64(iter.Val()))
+				v, err := decoder.Get(iter.Time())
+				at.NoError(err)
+				at.Equal(tt.data[i], convert.BytesToInt64(v))
+			}
+			at.Equal(len(tt.data), i)
+		})
+	}
+}
+
+func TestNewDictionaryEncoderAndDecoder(t *testing.T) {
+	at := assert.New(t)
+	key := []byte("foo")
+	encoderPool := NewDictionaryEncoderPool("minute", 1024)
+	decoderPool := NewDictionaryDecoderPool("minute", 1024)
+	encoder := encoderPool.Get(key)
+	defer encoderPool.Put(encoder)
+	decoder := decoderPool.Get(key)
+	defer decoderPool.Put(decoder)
+
+	var values []string
+	for i := 0; i < 10; i++ {
+		values = append(values, "GET", "POST", "GET", "", "GET", "PUT")
+	}
+	var raw int
+	for i, v := range values {
+		encoder.Append(uint64(time.Duration(i)*time.Minute), []byte(v))
+		raw += len(v) + 8
+	}
+	at.False(encoder.IsFull())
+	bb, err := encoder.Encode()
+	at.NoError(err)
+	at.Less(len(bb), raw/2)
+
+	at.NoError(decoder.Decode(key, bb))
+	at.Equal(len(values), decoder.Len())
+	i := 0
+	for iter := decoder.Iterator(); iter.Next(); i++ {
+		at.Equal(uint64(time.Duration(i)*time.Minute), iter.Time())
+		at.Equal(values[i], string(iter.Val()))
+	}
+	at.Equal(len(values), i)
+	_, err = decoder.Get(uint64(time.Hour))
+	at.Error(err)
+
+	encoder.Reset(key)
+	for i := 0; i < MaxDictionarySize; i++ {
+		at.False(encoder.IsFull())
+		encoder.Append(uint64(i), []byte(fmt.Sprintf("value-%d", i)))
+	}
+	at.True(encoder.IsFull())
+}
+
+func TestColumnEncoderErrors(t *testing.T) {
+	at := assert.New(t)
+	key := []byte("foo")
+	encoder := NewDeltaEncoderPool("minute", 4).Get(key)
+	_, err := encoder.Encode()
+	at.ErrorIs(err, ErrEncodeEmpty)
+	encoder.Append(1, []byte("foo"))
+	_, err = encoder.Encode()
+	at.ErrorIs(err, ErrInvalidValue)
+
+	decoder := NewDictionaryDecoderPool("minute", 4).Get(key)
+	at.ErrorIs(decoder.Decode(key, nil), ErrInvalidValue)
+	at.ErrorIs(decoder.Decode(key, []byte{2, 1}), ErrInvalidValue)
+}
@@ -0,0 +1,101 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package export
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// csvWriter writes a row per line. The arrays are encoded as JSON arrays, the binaries by base64
+// and the timestamps by RFC 3339. The absent values are empty.
+type csvWriter struct {
+	w       *csv.Writer
+	columns []Column
+	record  []string
+}
+
+func newCSVWriter(w io.Writer, columns []Column) (Writer, error) {
+	cw := &csvWriter{
+		w:       csv.NewWriter(w),
+		columns: columns,
+		record:  make([]string, len(columns)),
+	}
+	for i, c := range columns {
+		cw.record[i] = c.Name
+	}
+	if err := cw.w.Write(cw.record); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (cw *csvWriter) Write(row []interface{}) error {
+	for i, c := range cw.columns {
+		s, err := formatValue(c, row[i])
+		if err != nil {
+			return err
+		}
+		cw.record[i] = s
+	}
+	return cw.w.Write(cw.record)
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func formatValue(c Column, v interface{}) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	switch val := v.(type) {
+	case string:
+		if c.Type == TypeString {
+			return val, nil
+		}
+	case int64:
+		if c.Type == TypeInt {
+			return strconv.FormatInt(val, 10), nil
+		}
+	case []byte:
+		if c.Type == TypeBinary {
+			return base64.StdEncoding.EncodeToString(val), nil
+		}
+	case []string:
+		if c.Type == TypeStringArray {
+			bb, err := json.Marshal(val)
+			return string(bb), err
+		}
+	case []int64:
+		if c.Type == TypeIntArray {
+			bb, err := json.Marshal(val)
+			return string(bb), err
+		}
+	case time.Time:
+		if c.Type == TypeTimestamp {
+			return val.UTC().Format(time.RFC3339Nano), nil
+		}
+	}
+	return "", typeError(c, v)
+}
@@ -0,0 +1,94 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package export writes the rows of query results as CSV or Parquet files.
+package export
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Format is the file format of the exported rows.
+type Format string
+
+const (
+	// FormatCSV writes a header line and a line of comma-separated values per row.
+	FormatCSV Format = "csv"
+	// FormatParquet writes a Parquet file whose columns are typed.
+	FormatParquet Format = "parquet"
+)
+
+// ErrUnknownFormat is returned if a format is neither csv nor parquet.
+var ErrUnknownFormat = errors.New("unknown format")
+
+// ContentType returns the MIME type of the format.
+func (f Format) ContentType() string {
+	if f == FormatParquet {
+		return "application/vnd.apache.parquet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Type is the type of the values of a column.
+type Type int
+
+const (
+	// TypeString is the column of strings.
+	TypeString Type = iota
+	// TypeInt is the column of int64 values.
+	TypeInt
+	// TypeBinary is the column of []byte values.
+	TypeBinary
+	// TypeStringArray is the column of []string values.
+	TypeStringArray
+	// TypeIntArray is the column of []int64 values.
+	TypeIntArray
+	// TypeTimestamp is the column of time.Time values, which are stored as milliseconds.
+	TypeTimestamp
+)
+
+// Column is a named and typed column of the rows.
+type Column struct {
+	Name string
+	Type Type
+}
+
+// Writer writes rows, whose values are in the order of the columns.
+// A value is nil if it's absent, or of the Go type of its column:
+// string, int64, []byte, []string, []int64 or time.Time.
+type Writer interface {
+	Write(row []interface{}) error
+	// Close flushes the buffered rows. It doesn't close the underlying writer.
+	Close() error
+}
+
+// NewWriter returns the writer of the format writing the rows of the columns to w.
+func NewWriter(format Format, w io.Writer, columns []Column) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatParquet:
+		return newParquetWriter(w, columns)
+	}
+	return nil, errors.WithMessagef(ErrUnknownFormat, "%q is not one of [%s %s]", format, FormatCSV, FormatParquet)
+}
+
+func typeError(c Column, v interface{}) error {
+	return errors.Errorf("the value %v of the column %s has the unexpected type %T", v, c.Name, v)
+}
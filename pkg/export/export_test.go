@@ -0,0 +1,157 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v11/parquet"
+	"github.com/apache/arrow/go/v11/parquet/file"
+	"github.com/apache/arrow/go/v11/parquet/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testColumns = []Column{
+		{Name: "timestamp", Type: TypeTimestamp},
+		{Name: "endpoint", Type: TypeString},
+		{Name: "duration", Type: TypeInt},
+		{Name: "data", Type: TypeBinary},
+		{Name: "labels", Type: TypeStringArray},
+		{Name: "ids", Type: TypeIntArray},
+	}
+	testRows = [][]interface{}{
+		{time.UnixMilli(1000), "/home, index", int64(100), []byte("ab"), []string{"a", "b"}, []int64{1, 2}},
+		{time.UnixMilli(2000), nil, int64(-1), nil, nil, []int64{}},
+	}
+)
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatCSV, &buf, testColumns)
+	require.NoError(t, err)
+	for _, row := range testRows {
+		require.NoError(t, w.Write(row))
+	}
+	require.NoError(t, w.Close())
+	assert.Equal(t, "timestamp,endpoint,duration,data,labels,ids\n"+
+		`1970-01-01T00:00:01Z,"/home, index",100,YWI=,"[""a"",""b""]","[1,2]"`+"\n"+
+		"1970-01-01T00:00:02Z,,-1,,,[]\n", buf.String())
+}
+
+func TestWriterRejectsMismatchedTypes(t *testing.T) {
+	for _, format := range []Format{FormatCSV, FormatParquet} {
+		w, err := NewWriter(format, &bytes.Buffer{}, []Column{{Name: "duration", Type: TypeInt}})
+		require.NoError(t, err)
+		assert.Error(t, w.Write([]interface{}{"100"}), format)
+	}
+	_, err := NewWriter("json", &bytes.Buffer{}, testColumns)
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestParquetWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatParquet, &buf, testColumns)
+	require.NoError(t, err)
+	for _, row := range testRows {
+		require.NoError(t, w.Write(row))
+	}
+	require.NoError(t, w.Close())
+
+	r, err := file.NewParquetReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	defer r.Close()
+	assert.Equal(t, int64(len(testRows)), r.NumRows())
+	require.Equal(t, 1, r.NumRowGroups())
+	s := r.MetaData().Schema
+	require.Equal(t, len(testColumns), s.NumColumns())
+	for i, c := range testColumns {
+		assert.Equal(t, c.Name, s.Column(i).Name())
+	}
+	assert.Equal(t, schema.NewTimestampLogicalType(true, schema.TimeUnitMillis), s.Column(0).LogicalType())
+	assert.Equal(t, schema.StringLogicalType{}, s.Column(1).LogicalType())
+	assert.Equal(t, parquet.Repetitions.Repeated, s.Column(4).SchemaNode().RepetitionType())
+
+	// column reads the values and the levels of a column
+	column := func(i int) (values interface{}, defLevels, repLevels []int16) {
+		cr, err := r.RowGroup(0).Column(i)
+		require.NoError(t, err)
+		defLevels, repLevels = make([]int16, 8), make([]int16, 8)
+		switch cr := cr.(type) {
+		case *file.Int64ColumnChunkReader:
+			vv := make([]int64, 8)
+			total, n, err := cr.ReadBatch(8, vv, defLevels, repLevels)
+			require.NoError(t, err)
+			values, defLevels, repLevels = vv[:n], defLevels[:total], repLevels[:total]
+		case *file.ByteArrayColumnChunkReader:
+			vv := make([]parquet.ByteArray, 8)
+			total, n, err := cr.ReadBatch(8, vv, defLevels, repLevels)
+			require.NoError(t, err)
+			ss := make([]string, n)
+			for j := range ss {
+				ss[j] = string(vv[j])
+			}
+			values, defLevels, repLevels = ss, defLevels[:total], repLevels[:total]
+		}
+		if s.Column(i).MaxRepetitionLevel() == 0 {
+			repLevels = nil
+		}
+		return values, defLevels, repLevels
+	}
+
+	values, def, _ := column(0)
+	assert.Equal(t, []int64{1000, 2000}, values)
+	assert.Equal(t, []int16{1, 1}, def)
+	values, def, _ = column(1)
+	assert.Equal(t, []string{"/home, index"}, values)
+	assert.Equal(t, []int16{1, 0}, def)
+	values, def, _ = column(2)
+	assert.Equal(t, []int64{100, -1}, values)
+	assert.Equal(t, []int16{1, 1}, def)
+	values, def, _ = column(3)
+	assert.Equal(t, []string{"ab"}, values)
+	assert.Equal(t, []int16{1, 0}, def)
+	values, def, rep := column(4)
+	assert.Equal(t, []string{"a", "b"}, values)
+	assert.Equal(t, []int16{1, 1, 0}, def)
+	assert.Equal(t, []int16{0, 1, 0}, rep)
+	values, def, rep = column(5)
+	assert.Equal(t, []int64{1, 2}, values)
+	assert.Equal(t, []int16{1, 1, 0}, def)
+	assert.Equal(t, []int16{0, 1, 0}, rep)
+}
+
+func TestParquetWriterRowGroups(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatParquet, &buf, testColumns[:1])
+	require.NoError(t, err)
+	for i := 0; i < parquetRowGroupSize+1; i++ {
+		require.NoError(t, w.Write([]interface{}{time.UnixMilli(int64(i))}))
+	}
+	require.NoError(t, w.Close())
+	r, err := file.NewParquetReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	defer r.Close()
+	assert.Equal(t, int64(parquetRowGroupSize+1), r.NumRows())
+	require.Equal(t, 2, r.NumRowGroups())
+	assert.Equal(t, int64(parquetRowGroupSize), r.RowGroup(0).NumRows())
+	assert.Equal(t, int64(1), r.RowGroup(1).NumRows())
+}
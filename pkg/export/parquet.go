@@ -0,0 +1,221 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package export
+
+import (
+	"io"
+	"time"
+
+	"github.com/apache/arrow/go/v11/parquet"
+	"github.com/apache/arrow/go/v11/parquet/file"
+	"github.com/apache/arrow/go/v11/parquet/schema"
+)
+
+// parquetRowGroupSize is the number of the rows buffered by a row group before it's written.
+const parquetRowGroupSize = 8192
+
+// parquetWriter writes the rows by row groups, whose column chunks are buffered until they're full.
+// The arrays are repeated columns, in which an absent array is the same as an empty one.
+// The other columns are optional.
+type parquetWriter struct {
+	w       *file.Writer
+	columns []Column
+	chunks  []*parquetChunk
+	rows    int
+}
+
+// parquetChunk buffers the values and the levels of a column, the values are either int64s or byte arrays.
+type parquetChunk struct {
+	ints      []int64
+	bytes     []parquet.ByteArray
+	defLevels []int16
+	repLevels []int16
+}
+
+func newParquetWriter(w io.Writer, columns []Column) (Writer, error) {
+	fields := make(schema.FieldList, len(columns))
+	for i, c := range columns {
+		var err error
+		if fields[i], err = parquetNode(c); err != nil {
+			return nil, err
+		}
+	}
+	root, err := schema.NewGroupNode("schema", parquet.Repetitions.Required, fields, -1)
+	if err != nil {
+		return nil, err
+	}
+	pw := &parquetWriter{
+		// the file writer closes its sink, but the underlying writer is left to the caller
+		w: file.NewParquetWriter(struct{ io.Writer }{w}, root,
+			file.WithWriterProps(parquet.NewWriterProperties(parquet.WithCreatedBy("banyandb")))),
+		columns: columns,
+		chunks:  make([]*parquetChunk, len(columns)),
+	}
+	for i := range pw.chunks {
+		pw.chunks[i] = &parquetChunk{}
+	}
+	return pw, nil
+}
+
+func parquetNode(c Column) (schema.Node, error) {
+	switch c.Type {
+	case TypeString:
+		return schema.NewPrimitiveNodeLogical(c.Name, parquet.Repetitions.Optional, schema.StringLogicalType{}, parquet.Types.ByteArray, -1, -1)
+	case TypeInt:
+		return schema.NewPrimitiveNode(c.Name, parquet.Repetitions.Optional, parquet.Types.Int64, -1, -1)
+	case TypeTimestamp:
+		return schema.NewPrimitiveNodeLogical(c.Name, parquet.Repetitions.Optional,
+			schema.NewTimestampLogicalType(true, schema.TimeUnitMillis), parquet.Types.Int64, -1, -1)
+	case TypeStringArray:
+		return schema.NewPrimitiveNodeLogical(c.Name, parquet.Repetitions.Repeated, schema.StringLogicalType{}, parquet.Types.ByteArray, -1, -1)
+	case TypeIntArray:
+		return schema.NewPrimitiveNode(c.Name, parquet.Repetitions.Repeated, parquet.Types.Int64, -1, -1)
+	}
+	return schema.NewPrimitiveNode(c.Name, parquet.Repetitions.Optional, parquet.Types.ByteArray, -1, -1)
+}
+
+func (pw *parquetWriter) Write(row []interface{}) error {
+	for i, c := range pw.columns {
+		if err := pw.chunks[i].append(c, row[i]); err != nil {
+			return err
+		}
+	}
+	pw.rows++
+	if pw.rows >= parquetRowGroupSize {
+		return pw.flush()
+	}
+	return nil
+}
+
+func (pw *parquetWriter) Close() error {
+	if err := pw.flush(); err != nil {
+		return err
+	}
+	return pw.w.Close()
+}
+
+// flush writes the buffered rows as a row group.
+func (pw *parquetWriter) flush() error {
+	if pw.rows == 0 {
+		return nil
+	}
+	rg := pw.w.AppendRowGroup()
+	for _, chunk := range pw.chunks {
+		cw, err := rg.NextColumn()
+		if err != nil {
+			return err
+		}
+		switch cw := cw.(type) {
+		case *file.Int64ColumnChunkWriter:
+			_, err = cw.WriteBatch(chunk.ints, chunk.defLevels, chunk.repLevels)
+		case *file.ByteArrayColumnChunkWriter:
+			_, err = cw.WriteBatch(chunk.bytes, chunk.defLevels, chunk.repLevels)
+		}
+		if err != nil {
+			return err
+		}
+		if err = cw.Close(); err != nil {
+			return err
+		}
+		chunk.reset()
+	}
+	pw.rows = 0
+	return rg.Close()
+}
+
+func isArray(t Type) bool {
+	return t == TypeStringArray || t == TypeIntArray
+}
+
+func (c *parquetChunk) append(col Column, v interface{}) error {
+	if v == nil {
+		c.defLevels = append(c.defLevels, 0)
+		if isArray(col.Type) {
+			c.repLevels = append(c.repLevels, 0)
+		}
+		return nil
+	}
+	switch val := v.(type) {
+	case string:
+		if col.Type == TypeString {
+			c.bytes = append(c.bytes, parquet.ByteArray(val))
+			c.defLevels = append(c.defLevels, 1)
+			return nil
+		}
+	case int64:
+		if col.Type == TypeInt {
+			c.ints = append(c.ints, val)
+			c.defLevels = append(c.defLevels, 1)
+			return nil
+		}
+	case []byte:
+		if col.Type == TypeBinary {
+			// the value is buffered until the row group is written, so it's copied
+			c.bytes = append(c.bytes, append(parquet.ByteArray(nil), val...))
+			c.defLevels = append(c.defLevels, 1)
+			return nil
+		}
+	case time.Time:
+		if col.Type == TypeTimestamp {
+			c.ints = append(c.ints, val.UnixMilli())
+			c.defLevels = append(c.defLevels, 1)
+			return nil
+		}
+	case []string:
+		if col.Type == TypeStringArray {
+			for _, s := range val {
+				c.bytes = append(c.bytes, parquet.ByteArray(s))
+			}
+			c.appendRepeated(len(val))
+			return nil
+		}
+	case []int64:
+		if col.Type == TypeIntArray {
+			c.ints = append(c.ints, val...)
+			c.appendRepeated(len(val))
+			return nil
+		}
+	}
+	return typeError(col, v)
+}
+
+// appendRepeated appends the levels of an array of n values.
+func (c *parquetChunk) appendRepeated(n int) {
+	if n == 0 {
+		c.defLevels = append(c.defLevels, 0)
+		c.repLevels = append(c.repLevels, 0)
+		return
+	}
+	for i := 0; i < n; i++ {
+		c.defLevels = append(c.defLevels, 1)
+		if i == 0 {
+			c.repLevels = append(c.repLevels, 0)
+		} else {
+			c.repLevels = append(c.repLevels, 1)
+		}
+	}
+}
+
+func (c *parquetChunk) reset() {
+	c.ints = c.ints[:0]
+	c.bytes = c.bytes[:0]
+	c.defLevels = c.defLevels[:0]
+	if c.repLevels != nil {
+		c.repLevels = c.repLevels[:0]
+	}
+}
@@ -0,0 +1,135 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package expr evaluates the boolean conditions on the tags of an element, which are the expressions of
+// the Common Expression Language (CEL), e.g. `endpoint.startsWith("/health")`.
+//
+// The tags are declared by their names and types. The scalar tags are nullable, so an absent tag is `null`.
+package expr
+
+import (
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/interpreter"
+	"github.com/pkg/errors"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+)
+
+var (
+	ErrSyntax = errors.New("syntax error")
+	ErrType   = errors.New("type error")
+
+	// parser parses the expressions without their tags
+	parser *cel.Env
+)
+
+func init() {
+	var err error
+	if parser, err = cel.NewEnv(); err != nil {
+		panic(err)
+	}
+}
+
+// Tags declares the types of the tags an expression refers to.
+type Tags map[string]databasev1.TagType
+
+// Resolver returns the value of a tag, false if it's absent.
+// The value is one of nil, int64, string, []int64 and []string.
+type Resolver func(name string) (interface{}, bool)
+
+// Program is a compiled expression, which is safe to be evaluated concurrently.
+type Program struct {
+	program cel.Program
+	source  string
+}
+
+// Parse checks the syntax of the source, whose tags are checked once it's compiled.
+func Parse(source string) error {
+	if _, issues := parser.Parse(source); issues.Err() != nil {
+		return errors.WithMessage(ErrSyntax, issues.Err().Error())
+	}
+	return nil
+}
+
+// Compile checks the source against the tags, and returns the Program whose result should be a bool.
+func Compile(source string, tags Tags) (*Program, error) {
+	if err := Parse(source); err != nil {
+		return nil, err
+	}
+	opts := make([]cel.EnvOption, 0, len(tags))
+	for name, t := range tags {
+		opts = append(opts, cel.Variable(name, tagType(t)))
+	}
+	env, err := cel.NewEnv(opts...)
+	if err != nil {
+		return nil, errors.WithMessage(ErrType, err.Error())
+	}
+	ast, issues := env.Compile(source)
+	if issues.Err() != nil {
+		return nil, errors.WithMessage(ErrType, issues.Err().Error())
+	}
+	if !ast.OutputType().IsAssignableType(cel.BoolType) {
+		return nil, errors.WithMessagef(ErrType, "the result of %q is %s rather than a bool", source, ast.OutputType())
+	}
+	program, err := env.Program(ast, cel.EvalOptions(cel.OptOptimize))
+	if err != nil {
+		return nil, errors.WithMessage(ErrType, err.Error())
+	}
+	return &Program{program: program, source: source}, nil
+}
+
+// Eval returns the result of the expression on the tags resolved by resolve.
+func (p *Program) Eval(resolve Resolver) (bool, error) {
+	out, _, err := p.program.Eval(activation(resolve))
+	if err != nil {
+		return false, err
+	}
+	b, ok := out.Value().(bool)
+	if !ok {
+		return false, errors.WithMessagef(ErrType, "the result %v of %q is not a bool", out, p.source)
+	}
+	return b, nil
+}
+
+func (p *Program) String() string {
+	return p.source
+}
+
+// tagType maps a tag to its CEL type, whose binary data isn't supported.
+func tagType(t databasev1.TagType) *cel.Type {
+	switch t {
+	case databasev1.TagType_TAG_TYPE_STRING:
+		return cel.NullableType(cel.StringType)
+	case databasev1.TagType_TAG_TYPE_INT:
+		return cel.NullableType(cel.IntType)
+	case databasev1.TagType_TAG_TYPE_STRING_ARRAY:
+		return cel.ListType(cel.StringType)
+	case databasev1.TagType_TAG_TYPE_INT_ARRAY:
+		return cel.ListType(cel.IntType)
+	}
+	return cel.NullType
+}
+
+type activation Resolver
+
+func (a activation) ResolveName(name string) (interface{}, bool) {
+	return a(name)
+}
+
+func (a activation) Parent() interpreter.Activation {
+	return nil
+}
@@ -0,0 +1,121 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package expr_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/pkg/expr"
+)
+
+var tags = expr.Tags{
+	"endpoint": databasev1.TagType_TAG_TYPE_STRING,
+	"status":   databasev1.TagType_TAG_TYPE_INT,
+	"duration": databasev1.TagType_TAG_TYPE_INT,
+	"labels":   databasev1.TagType_TAG_TYPE_STRING_ARRAY,
+	"absent":   databasev1.TagType_TAG_TYPE_STRING,
+}
+
+func TestEval(t *testing.T) {
+	values := map[string]interface{}{
+		"endpoint": "/health/live",
+		"status":   int64(200),
+		"duration": int64(15),
+		"labels":   []string{"canary", "internal"},
+		"absent":   nil,
+	}
+	resolve := func(name string) (interface{}, bool) {
+		v, ok := values[name]
+		return v, ok
+	}
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{`endpoint.startsWith("/health")`, true},
+		{`endpoint.endsWith('live') && status == 200`, true},
+		{`!endpoint.contains("ready")`, true},
+		{`endpoint.matches("^/health/(live|ready)$")`, true},
+		{`status >= 500 || duration > 10`, true},
+		{`status in [200, 204] && "canary" in labels`, true},
+		{`labels[1] == "internal" && size(labels) == 2 && endpoint.size() == 12`, true},
+		{`absent == null && endpoint != null`, true},
+		{`-duration < -20`, false},
+		{`(status == 200 || status == 204) && duration < 10`, false},
+		// the error of the absent tag is absorbed by the logical operators
+		{`status == 404 && absent.startsWith("/")`, false},
+		{`status == 200 || absent.startsWith("/")`, true},
+	}
+	for _, tt := range tests {
+		p, err := expr.Compile(tt.source, tags)
+		require.NoError(t, err, tt.source)
+		got, err := p.Eval(resolve)
+		require.NoError(t, err, tt.source)
+		assert.Equal(t, tt.want, got, tt.source)
+	}
+}
+
+func TestEvalError(t *testing.T) {
+	resolve := func(name string) (interface{}, bool) {
+		if name == "status" {
+			return int64(200), true
+		}
+		return nil, name == "absent"
+	}
+	for _, source := range []string{
+		`absent.startsWith("/")`,
+		`endpoint == "/"`,
+		`status == 200 && [1][1] == 1`,
+	} {
+		p, err := expr.Compile(source, tags)
+		require.NoError(t, err, source)
+		_, err = p.Eval(resolve)
+		assert.Error(t, err, source)
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	for _, source := range []string{
+		``,
+		`status ==`,
+		`endpoint.startsWith("/health"`,
+		`"unterminated`,
+		`status = 200`,
+		`status == 200 status`,
+	} {
+		assert.ErrorIs(t, expr.Parse(source), expr.ErrSyntax, source)
+		_, err := expr.Compile(source, tags)
+		assert.ErrorIs(t, err, expr.ErrSyntax, source)
+	}
+	for _, source := range []string{
+		`undefined == 1`,
+		`status`,
+		`status == "200"`,
+		`status.startsWith("2")`,
+		`endpoint.unknown("a")`,
+		`size(endpoint, labels)`,
+	} {
+		assert.NoError(t, expr.Parse(source), source)
+		_, err := expr.Compile(source, tags)
+		assert.ErrorIs(t, err, expr.ErrType, source)
+	}
+}
@@ -0,0 +1,263 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package flow
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+)
+
+const checkpointFileExt = ".ckpt"
+
+var ErrCodecMissing = errors.New("codec is required to checkpoint the state")
+
+// Checkpointable is implemented by stateful components, e.g. windows and aggregations,
+// whose state could be persisted by the CheckpointCoordinator.
+type Checkpointable interface {
+	// SnapshotState serializes the current state of the component.
+	SnapshotState() ([]byte, error)
+	// RestoreState rebuilds the state of the component from the output of SnapshotState.
+	// It must be called before the component starts to process elements.
+	RestoreState([]byte) error
+}
+
+// DataCodec encodes and decodes the user data carried by a StreamRecord.
+type DataCodec interface {
+	Encode(data interface{}) ([]byte, error)
+	Decode(raw []byte) (interface{}, error)
+}
+
+// Checkpoint is a snapshot of all components registered to a CheckpointCoordinator.
+type Checkpoint struct {
+	States    map[string][]byte `json:"states"`
+	ID        uint64            `json:"id"`
+	Timestamp int64             `json:"timestamp"`
+}
+
+// CheckpointStore persists checkpoints.
+type CheckpointStore interface {
+	// Save replaces the checkpoint of the given name atomically.
+	Save(name string, checkpoint *Checkpoint) error
+	// Load returns the latest checkpoint of the given name, or nil if there is none.
+	Load(name string) (*Checkpoint, error)
+	// Delete removes the checkpoint of the given name.
+	Delete(name string) error
+}
+
+var _ CheckpointStore = (*localCheckpointStore)(nil)
+
+type localCheckpointStore struct {
+	root string
+}
+
+// NewLocalCheckpointStore returns a CheckpointStore which keeps checkpoints in the local directory.
+func NewLocalCheckpointStore(root string) (CheckpointStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, errors.Wrap(err, "failed to create the checkpoint directory")
+	}
+	return &localCheckpointStore{root: root}, nil
+}
+
+func (l *localCheckpointStore) Save(name string, checkpoint *Checkpoint) (err error) {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	target := l.file(name)
+	tmp := target + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp)
+		}
+	}()
+	if _, err = f.Write(data); err != nil {
+		return multierr.Append(err, f.Close())
+	}
+	// the checkpoint has to hit the disk before it replaces the previous one
+	if err = f.Sync(); err != nil {
+		return multierr.Append(err, f.Close())
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, target)
+}
+
+func (l *localCheckpointStore) Load(name string) (*Checkpoint, error) {
+	data, err := os.ReadFile(l.file(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := &Checkpoint{}
+	if err = json.Unmarshal(data, checkpoint); err != nil {
+		return nil, errors.Wrapf(err, "checkpoint %s is corrupted", name)
+	}
+	return checkpoint, nil
+}
+
+func (l *localCheckpointStore) Delete(name string) error {
+	err := os.Remove(l.file(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (l *localCheckpointStore) file(name string) string {
+	return filepath.Join(l.root, name+checkpointFileExt)
+}
+
+// CheckpointCoordinator periodically snapshots the state of the registered components
+// and restores them on startup.
+//
+// The sink of a checkpointed flow gets the elements which are pending in the restored state again,
+// thus it should write them idempotently, e.g. with deterministic IDs. That makes the writes to
+// the sink exactly-once from the storage's point of view.
+type CheckpointCoordinator struct {
+	store      CheckpointStore
+	components map[string]Checkpointable
+	stopCh     chan struct{}
+	errHandler func(error)
+	name       string
+	order      []string
+	interval   time.Duration
+	wg         sync.WaitGroup
+	mu         sync.Mutex
+	lastID     uint64
+}
+
+// NewCheckpointCoordinator creates a coordinator persisting the checkpoint of the given name with an interval.
+// A non-positive interval disables the periodical checkpoint, while Close still takes the final one.
+func NewCheckpointCoordinator(name string, store CheckpointStore, interval time.Duration, errHandler func(error)) *CheckpointCoordinator {
+	if errHandler == nil {
+		errHandler = func(error) {}
+	}
+	return &CheckpointCoordinator{
+		name:       name,
+		store:      store,
+		interval:   interval,
+		errHandler: errHandler,
+		components: make(map[string]Checkpointable),
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// Register adds a component to the coordinator. The id must be stable across restarts.
+func (c *CheckpointCoordinator) Register(id string, component Checkpointable) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.components[id]; !ok {
+		c.order = append(c.order, id)
+	}
+	c.components[id] = component
+}
+
+// Restore loads the latest checkpoint and restores all registered components.
+// It returns false if there is no checkpoint yet.
+func (c *CheckpointCoordinator) Restore() (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	checkpoint, err := c.store.Load(c.name)
+	if err != nil || checkpoint == nil {
+		return false, err
+	}
+	for _, id := range c.order {
+		state, ok := checkpoint.States[id]
+		if !ok {
+			continue
+		}
+		if err = c.components[id].RestoreState(state); err != nil {
+			return false, errors.Wrapf(err, "failed to restore %s", id)
+		}
+	}
+	c.lastID = checkpoint.ID
+	return true, nil
+}
+
+// Start begins the periodical checkpoint.
+func (c *CheckpointCoordinator) Start() {
+	if c.interval <= 0 {
+		return
+	}
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.TriggerCheckpoint(); err != nil {
+					c.errHandler(err)
+				}
+			case <-c.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// TriggerCheckpoint snapshots all registered components and persists them as a whole.
+func (c *CheckpointCoordinator) TriggerCheckpoint() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	checkpoint := &Checkpoint{
+		ID:        c.lastID + 1,
+		Timestamp: time.Now().UnixMilli(),
+		States:    make(map[string][]byte, len(c.order)),
+	}
+	for _, id := range c.order {
+		state, err := c.components[id].SnapshotState()
+		if err != nil {
+			return errors.Wrapf(err, "failed to snapshot %s", id)
+		}
+		checkpoint.States[id] = state
+	}
+	if err := c.store.Save(c.name, checkpoint); err != nil {
+		return err
+	}
+	c.lastID = checkpoint.ID
+	return nil
+}
+
+// LastCheckpointID returns the ID of the latest persistent checkpoint.
+func (c *CheckpointCoordinator) LastCheckpointID() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastID
+}
+
+// Close stops the periodical checkpoint and takes the final one.
+func (c *CheckpointCoordinator) Close() error {
+	close(c.stopCh)
+	c.wg.Wait()
+	return c.TriggerCheckpoint()
+}
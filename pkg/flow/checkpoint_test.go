@@ -0,0 +1,90 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package flow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type counter struct {
+	value string
+}
+
+func (c *counter) SnapshotState() ([]byte, error) {
+	return []byte(c.value), nil
+}
+
+func (c *counter) RestoreState(data []byte) error {
+	c.value = string(data)
+	return nil
+}
+
+func TestLocalCheckpointStore(t *testing.T) {
+	require := require.New(t)
+	store, err := NewLocalCheckpointStore(t.TempDir())
+	require.NoError(err)
+
+	checkpoint, err := store.Load("absent")
+	require.NoError(err)
+	require.Nil(checkpoint)
+
+	require.NoError(store.Save("topn", &Checkpoint{ID: 1, States: map[string][]byte{"c": []byte("1")}}))
+	require.NoError(store.Save("topn", &Checkpoint{ID: 2, States: map[string][]byte{"c": []byte("2")}}))
+	checkpoint, err = store.Load("topn")
+	require.NoError(err)
+	require.EqualValues(2, checkpoint.ID)
+	require.Equal([]byte("2"), checkpoint.States["c"])
+
+	require.NoError(store.Delete("topn"))
+	require.NoError(store.Delete("topn"))
+	checkpoint, err = store.Load("topn")
+	require.NoError(err)
+	require.Nil(checkpoint)
+}
+
+func TestCheckpointCoordinator(t *testing.T) {
+	require := require.New(t)
+	store, err := NewLocalCheckpointStore(t.TempDir())
+	require.NoError(err)
+
+	c1, c2 := &counter{value: "1"}, &counter{value: "2"}
+	coordinator := NewCheckpointCoordinator("flow", store, 0, nil)
+	coordinator.Register("c1", c1)
+	coordinator.Register("c2", c2)
+	restored, err := coordinator.Restore()
+	require.NoError(err)
+	require.False(restored)
+	coordinator.Start()
+	require.NoError(coordinator.TriggerCheckpoint())
+	c1.value, c2.value = "10", "20"
+	require.NoError(coordinator.Close())
+	require.EqualValues(2, coordinator.LastCheckpointID())
+
+	r1, r2 := &counter{}, &counter{}
+	coordinator = NewCheckpointCoordinator("flow", store, 0, nil)
+	coordinator.Register("c1", r1)
+	coordinator.Register("c2", r2)
+	restored, err = coordinator.Restore()
+	require.NoError(err)
+	require.True(restored)
+	require.Equal("10", r1.value)
+	require.Equal("20", r2.value)
+	require.EqualValues(2, coordinator.LastCheckpointID())
+}
@@ -0,0 +1,153 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package streaming
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/apache/skywalking-banyandb/pkg/flow"
+	"github.com/apache/skywalking-banyandb/pkg/query/aggregation"
+)
+
+var _ flow.Checkpointable = (*customAggregator)(nil)
+
+func (s *windowedFlow) Aggregate(name string, opts ...any) flow.Flow {
+	if _, err := aggregation.NewAggregator(name); err != nil {
+		s.f.drainErr(err)
+		return s.f
+	}
+	s.wa.(*TumblingTimeWindows).aggregationFactory = func() flow.AggregationOp {
+		customAggrFunc := &customAggregator{
+			name:        name,
+			aggregators: make(map[string]aggregation.Aggregator),
+		}
+		// apply user customized options
+		for _, opt := range opts {
+			if applier, ok := opt.(AggregateOption); ok {
+				applier(customAggrFunc)
+			}
+		}
+		if customAggrFunc.valueExtractor == nil {
+			s.f.drainErr(errors.New("valueExtractor must be specified"))
+		}
+		return customAggrFunc
+	}
+	return s.f
+}
+
+// customAggregator aggregates the values of each group by the custom aggregate function.
+type customAggregator struct {
+	aggregators map[string]aggregation.Aggregator
+	// valueExtractor is an extractor to fetch the value to be aggregated from the record
+	valueExtractor func(flow.StreamRecord) int64
+	// groupKeyExtractor is an extractor to fetch the group key from the record.
+	// All the records fall into a single group if it's absent.
+	groupKeyExtractor func(flow.StreamRecord) string
+	name              string
+	dirty             bool
+}
+
+type AggregateOption func(aggregator *customAggregator)
+
+func WithValueExtractor(valueExtractor func(flow.StreamRecord) int64) AggregateOption {
+	return func(aggregator *customAggregator) {
+		aggregator.valueExtractor = valueExtractor
+	}
+}
+
+func WithGroupKeyExtractor(groupKeyExtractor func(flow.StreamRecord) string) AggregateOption {
+	return func(aggregator *customAggregator) {
+		aggregator.groupKeyExtractor = groupKeyExtractor
+	}
+}
+
+func (c *customAggregator) Add(input []flow.StreamRecord) {
+	for _, item := range input {
+		aggr, err := c.aggregator(c.groupKey(item))
+		if err != nil {
+			continue
+		}
+		aggr.In(c.valueExtractor(item))
+		c.dirty = true
+	}
+}
+
+func (c *customAggregator) groupKey(item flow.StreamRecord) string {
+	if c.groupKeyExtractor == nil {
+		return ""
+	}
+	return c.groupKeyExtractor(item)
+}
+
+func (c *customAggregator) aggregator(key string) (aggregation.Aggregator, error) {
+	if aggr, ok := c.aggregators[key]; ok {
+		return aggr, nil
+	}
+	// the function is checked by Aggregate
+	aggr, err := aggregation.NewAggregator(c.name)
+	if err != nil {
+		return nil, err
+	}
+	c.aggregators[key] = aggr
+	return aggr, nil
+}
+
+// Snapshot returns the finalized results keyed by the groups.
+func (c *customAggregator) Snapshot() interface{} {
+	c.dirty = false
+	results := make(map[string]int64, len(c.aggregators))
+	for key, aggr := range c.aggregators {
+		results[key] = aggr.Val()
+	}
+	return results
+}
+
+func (c *customAggregator) Dirty() bool {
+	return c.dirty
+}
+
+// SnapshotState serializes the partial states of the groups without touching the dirty flag.
+func (c *customAggregator) SnapshotState() ([]byte, error) {
+	states := make(map[string]json.RawMessage, len(c.aggregators))
+	for key, aggr := range c.aggregators {
+		states[key] = aggr.State()
+	}
+	return json.Marshal(states)
+}
+
+// RestoreState merges the partial states back to the groups.
+// The restored aggregator is always dirty because the results might not reach the sink before the crash.
+func (c *customAggregator) RestoreState(raw []byte) error {
+	var states map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &states); err != nil {
+		return err
+	}
+	for key, state := range states {
+		aggr, err := c.aggregator(key)
+		if err != nil {
+			return err
+		}
+		if err := aggr.Merge(state); err != nil {
+			return err
+		}
+		c.dirty = true
+	}
+	return nil
+}
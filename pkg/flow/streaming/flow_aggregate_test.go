@@ -0,0 +1,93 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package streaming
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/pkg/flow"
+	"github.com/apache/skywalking-banyandb/pkg/query/aggregation"
+)
+
+type apdexState struct {
+	Satisfied int64 `json:"satisfied"`
+	Tolerated int64 `json:"tolerated"`
+	Total     int64 `json:"total"`
+}
+
+func init() {
+	aggregation.MustRegister(aggregation.Function[apdexState]{
+		Name: "flow-apdex",
+		Init: func() apdexState { return apdexState{} },
+		In: func(s apdexState, latency int64) apdexState {
+			switch {
+			case latency <= 100:
+				s.Satisfied++
+			case latency <= 400:
+				s.Tolerated++
+			}
+			s.Total++
+			return s
+		},
+		Merge: func(a, b apdexState) apdexState {
+			return apdexState{Satisfied: a.Satisfied + b.Satisfied, Tolerated: a.Tolerated + b.Tolerated, Total: a.Total + b.Total}
+		},
+		Finalize: func(s apdexState) int64 {
+			if s.Total == 0 {
+				return 0
+			}
+			// the apdex in percentage
+			return (s.Satisfied*100 + s.Tolerated*50) / s.Total
+		},
+	})
+}
+
+func newApdexAggregator() *customAggregator {
+	aggr := &customAggregator{name: "flow-apdex", aggregators: make(map[string]aggregation.Aggregator)}
+	WithValueExtractor(func(r flow.StreamRecord) int64 {
+		return int64(r.Data().(flow.Data)[1].(int))
+	})(aggr)
+	WithGroupKeyExtractor(func(r flow.StreamRecord) string {
+		return r.Data().(flow.Data)[0].(string)
+	})(aggr)
+	return aggr
+}
+
+func TestFlow_Custom_Aggregator(t *testing.T) {
+	require := require.New(t)
+	aggr := newApdexAggregator()
+	aggr.Add([]flow.StreamRecord{
+		flow.NewStreamRecordWithoutTS(flow.Data{"e2e-service-provider", 50}),
+		flow.NewStreamRecordWithoutTS(flow.Data{"e2e-service-provider", 300}),
+		flow.NewStreamRecordWithoutTS(flow.Data{"e2e-service-provider", 1000}),
+		flow.NewStreamRecordWithoutTS(flow.Data{"e2e-service-consumer", 80}),
+	})
+	require.True(aggr.Dirty())
+	require.Equal(map[string]int64{"e2e-service-provider": 50, "e2e-service-consumer": 100}, aggr.Snapshot())
+	require.False(aggr.Dirty())
+
+	state, err := aggr.SnapshotState()
+	require.NoError(err)
+	restored := newApdexAggregator()
+	restored.Add([]flow.StreamRecord{flow.NewStreamRecordWithoutTS(flow.Data{"e2e-service-consumer", 1000})})
+	require.NoError(restored.RestoreState(state))
+	require.True(restored.Dirty())
+	require.Equal(map[string]int64{"e2e-service-provider": 50, "e2e-service-consumer": 50}, restored.Snapshot())
+}
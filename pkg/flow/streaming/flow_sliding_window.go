@@ -20,6 +20,7 @@ package streaming
 import (
 	"container/heap"
 	"context"
+	"encoding/json"
 	"math"
 	"sync"
 	"time"
@@ -40,6 +41,7 @@ const (
 var (
 	_ flow.Operator       = (*TumblingTimeWindows)(nil)
 	_ flow.WindowAssigner = (*TumblingTimeWindows)(nil)
+	_ flow.Checkpointable = (*TumblingTimeWindows)(nil)
 	_ flow.Window         = (*timeWindow)(nil)
 
 	DefaultCacheSize = 2
@@ -92,6 +94,9 @@ type TumblingTimeWindows struct {
 	// aggregationFactory is the factory for creating aggregation operator
 	aggregationFactory flow.AggregationOpFactory
 
+	// stateMu guards the windows against the checkpoint while an element is processed
+	stateMu sync.Mutex
+
 	// For api.Operator
 	in  chan flow.StreamRecord
 	out chan flow.StreamRecord
@@ -106,16 +111,8 @@ func (s *TumblingTimeWindows) Out() <-chan flow.StreamRecord {
 }
 
 func (s *TumblingTimeWindows) Setup(ctx context.Context) (err error) {
-	if s.snapshots == nil {
-		if s.windowCount <= 0 {
-			s.windowCount = DefaultCacheSize
-		}
-		s.snapshots, err = lru.NewWithEvict(s.windowCount, func(key interface{}, value interface{}) {
-			s.flushSnapshot(key.(timeWindow), value.(flow.AggregationOp))
-		})
-		if err != nil {
-			return err
-		}
+	if err = s.initSnapshots(); err != nil {
+		return err
 	}
 	// start processing
 	s.Add(1)
@@ -124,6 +121,19 @@ func (s *TumblingTimeWindows) Setup(ctx context.Context) (err error) {
 	return
 }
 
+func (s *TumblingTimeWindows) initSnapshots() (err error) {
+	if s.snapshots != nil {
+		return nil
+	}
+	if s.windowCount <= 0 {
+		s.windowCount = DefaultCacheSize
+	}
+	s.snapshots, err = lru.NewWithEvict(s.windowCount, func(key interface{}, value interface{}) {
+		s.flushSnapshot(key.(timeWindow), value.(flow.AggregationOp))
+	})
+	return err
+}
+
 func (s *TumblingTimeWindows) flushSnapshot(w timeWindow, snapshot flow.AggregationOp) {
 	if snapshot.Dirty() {
 		s.out <- flow.NewStreamRecord(snapshot.Snapshot(), w.start)
@@ -161,62 +171,68 @@ func (s *TumblingTimeWindows) receive() {
 	defer s.Done()
 
 	for elem := range s.in {
-		assignedWindows, err := s.AssignWindows(elem.TimestampMillis())
-		if err != nil {
-			s.errorHandler(err)
+		s.process(elem)
+	}
+	close(s.out)
+}
+
+func (s *TumblingTimeWindows) process(elem flow.StreamRecord) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	assignedWindows, err := s.AssignWindows(elem.TimestampMillis())
+	if err != nil {
+		s.errorHandler(err)
+		return
+	}
+	ctx := triggerContext{
+		delegation: s,
+	}
+	for _, w := range assignedWindows {
+		// drop if the window is late
+		if s.isWindowLate(w) {
 			continue
 		}
-		ctx := triggerContext{
-			delegation: s,
+		tw := w.(timeWindow)
+		ctx.window = tw
+		// add elem to the bucket
+		if oldAggr, ok := s.snapshots.Get(tw); ok {
+			oldAggr.(flow.AggregationOp).Add([]flow.StreamRecord{elem})
+		} else {
+			newAggr := s.aggregationFactory()
+			newAggr.Add([]flow.StreamRecord{elem})
+			s.snapshots.Add(tw, newAggr)
 		}
-		for _, w := range assignedWindows {
-			// drop if the window is late
-			if s.isWindowLate(w) {
-				continue
-			}
-			tw := w.(timeWindow)
-			ctx.window = tw
-			// add elem to the bucket
-			if oldAggr, ok := s.snapshots.Get(tw); ok {
-				oldAggr.(flow.AggregationOp).Add([]flow.StreamRecord{elem})
-			} else {
-				newAggr := s.aggregationFactory()
-				newAggr.Add([]flow.StreamRecord{elem})
-				s.snapshots.Add(tw, newAggr)
-			}
 
-			result := ctx.OnElement(elem)
-			if result == FIRE {
-				s.flushWindow(tw)
-			}
+		result := ctx.OnElement(elem)
+		if result == FIRE {
+			s.flushWindow(tw)
 		}
+	}
 
-		// even if the incoming elements do not follow strict order,
-		// the watermark could increase monotonically.
-		if pastDur := elem.TimestampMillis() - s.currentWatermark; pastDur > 0 {
-			previousWaterMark := s.currentWatermark
-			s.currentWatermark = elem.TimestampMillis()
-
-			// Currently, assume the current watermark is t,
-			// then we allow lateness items by not purging the window
-			// of which the flush trigger time is less and equal than t,
-			// i.e. triggerTime <= t
-			s.flushDueWindows()
-
-			// flush dirty windows if the necessary
-			// use 40% of the data point interval as the flush interval,
-			// which means roughly the record located in the same time bucket will be persistent twice.
-			// |---------------------------------|
-			// |    40%     |    40%     |  20%  |
-			// |          flush        flush     |
-			// |---------------------------------|
-			// TODO: how to determine the threshold
-			if previousWaterMark > 0 && float64(pastDur) > float64(s.windowSize)*0.4 {
-				s.flushDirtyWindows()
-			}
+	// even if the incoming elements do not follow strict order,
+	// the watermark could increase monotonically.
+	if pastDur := elem.TimestampMillis() - s.currentWatermark; pastDur > 0 {
+		previousWaterMark := s.currentWatermark
+		s.currentWatermark = elem.TimestampMillis()
+
+		// Currently, assume the current watermark is t,
+		// then we allow lateness items by not purging the window
+		// of which the flush trigger time is less and equal than t,
+		// i.e. triggerTime <= t
+		s.flushDueWindows()
+
+		// flush dirty windows if the necessary
+		// use 40% of the data point interval as the flush interval,
+		// which means roughly the record located in the same time bucket will be persistent twice.
+		// |---------------------------------|
+		// |    40%     |    40%     |  20%  |
+		// |          flush        flush     |
+		// |---------------------------------|
+		// TODO: how to determine the threshold
+		if previousWaterMark > 0 && float64(pastDur) > float64(s.windowSize)*0.4 {
+			s.flushDirtyWindows()
 		}
 	}
-	close(s.out)
 }
 
 // isWindowLate checks whether this window is valid. The window is late if and only if
@@ -228,6 +244,94 @@ func (s *TumblingTimeWindows) isWindowLate(w flow.Window) bool {
 	return w.MaxTimestamp() <= s.currentWatermark && s.snapshots.Len() >= s.windowCount && !s.snapshots.Contains(w)
 }
 
+type windowState struct {
+	Aggregation []byte `json:"aggregation"`
+	Start       int64  `json:"start"`
+	End         int64  `json:"end"`
+}
+
+type windowsState struct {
+	Windows   []windowState `json:"windows"`
+	Watermark int64         `json:"watermark"`
+}
+
+// SnapshotState serializes the watermark and the aggregations of all windows kept in the memory.
+func (s *TumblingTimeWindows) SnapshotState() ([]byte, error) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	state := windowsState{
+		Watermark: s.currentWatermark,
+	}
+	if s.snapshots != nil {
+		// keys are ordered from the oldest to the newest
+		for _, key := range s.snapshots.Keys() {
+			value, ok := s.snapshots.Peek(key)
+			if !ok {
+				continue
+			}
+			aggr, ok := value.(flow.Checkpointable)
+			if !ok {
+				return nil, errors.New("the aggregation is not checkpointable")
+			}
+			data, err := aggr.SnapshotState()
+			if err != nil {
+				return nil, err
+			}
+			w := key.(timeWindow)
+			state.Windows = append(state.Windows, windowState{
+				Start:       w.start,
+				End:         w.end,
+				Aggregation: data,
+			})
+		}
+	}
+	return json.Marshal(state)
+}
+
+// RestoreState rebuilds the windows from the output of SnapshotState.
+// The unfinished windows register their timers again, so they will be fired by the watermark as usual.
+func (s *TumblingTimeWindows) RestoreState(data []byte) error {
+	if s.aggregationFactory == nil {
+		return errors.New("the aggregation should be applied before restoring")
+	}
+	var state windowsState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if err := s.initSnapshots(); err != nil {
+		return err
+	}
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	s.currentWatermark = state.Watermark
+	windows := state.Windows
+	// only the latest windows are kept if the capacity shrinks,
+	// since there is no downstream to flush the evicted ones yet.
+	if len(windows) > s.windowCount {
+		windows = windows[len(windows)-s.windowCount:]
+	}
+	for _, ws := range windows {
+		aggr := s.aggregationFactory()
+		restorer, ok := aggr.(flow.Checkpointable)
+		if !ok {
+			return errors.New("the aggregation is not checkpointable")
+		}
+		if err := restorer.RestoreState(ws.Aggregation); err != nil {
+			return err
+		}
+		tw := timeWindow{start: ws.Start, end: ws.End}
+		s.snapshots.Add(tw, aggr)
+		if tw.MaxTimestamp() > s.currentWatermark {
+			ctx := triggerContext{
+				window:     tw,
+				delegation: s,
+			}
+			ctx.RegisterEventTimeTimer(tw.MaxTimestamp())
+		}
+	}
+	return nil
+}
+
 func (s *TumblingTimeWindows) Teardown(ctx context.Context) error {
 	s.Wait()
 	return nil
@@ -19,6 +19,7 @@ package streaming
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -53,6 +54,16 @@ func (i *intSumAggregator) Dirty() bool {
 	return i.dirty
 }
 
+func (i *intSumAggregator) SnapshotState() ([]byte, error) {
+	return []byte(strconv.Itoa(i.sum)), nil
+}
+
+func (i *intSumAggregator) RestoreState(data []byte) (err error) {
+	i.sum, err = strconv.Atoi(string(data))
+	i.dirty = true
+	return err
+}
+
 var _ = Describe("Sliding Window", func() {
 	var (
 		baseTs         time.Time
@@ -135,3 +146,44 @@ var _ = Describe("Sliding Window", func() {
 		})
 	})
 })
+
+var _ = Describe("Sliding Window Checkpoint", func() {
+	It("Should restore the windows", func() {
+		baseTs := time.Unix(time.Now().Unix()-time.Now().Unix()%15, 0)
+		origin := NewTumblingTimeWindows(time.Second * 15)
+		origin.aggregationFactory = func() flow.AggregationOp {
+			return &intSumAggregator{}
+		}
+		origin.windowCount = 2
+		Expect(origin.Setup(context.TODO())).Should(Succeed())
+		originSnk := sink.NewSlice()
+		Expect(originSnk.Setup(context.TODO())).Should(Succeed())
+		origin.Exec(originSnk)
+		origin.In() <- flow.NewStreamRecord(1, baseTs.UnixMilli())
+		origin.In() <- flow.NewStreamRecord(2, baseTs.Add(time.Second*5).UnixMilli())
+		close(origin.in)
+		Expect(origin.Teardown(context.TODO())).Should(Succeed())
+		Expect(originSnk.Value()).Should(BeEmpty())
+		state, err := origin.SnapshotState()
+		Expect(err).ShouldNot(HaveOccurred())
+
+		restored := NewTumblingTimeWindows(time.Second * 15)
+		restored.aggregationFactory = func() flow.AggregationOp {
+			return &intSumAggregator{}
+		}
+		restored.windowCount = 2
+		Expect(restored.RestoreState(state)).Should(Succeed())
+		Expect(restored.currentWatermark).Should(Equal(baseTs.Add(time.Second * 5).UnixMilli()))
+		Expect(restored.Setup(context.TODO())).Should(Succeed())
+		snk := sink.NewSlice()
+		Expect(snk.Setup(context.TODO())).Should(Succeed())
+		restored.Exec(snk)
+		restored.In() <- flow.NewStreamRecord(4, baseTs.Add(time.Second*16).UnixMilli())
+		Eventually(func(g Gomega) {
+			// the restored window is fired by the watermark
+			g.Expect(snk.Value()).Should(ContainElement(flow.NewStreamRecord(3, baseTs.UnixMilli())))
+		}).WithTimeout(10 * time.Second).Should(Succeed())
+		close(restored.in)
+		Expect(restored.Teardown(context.TODO())).Should(Succeed())
+	})
+})
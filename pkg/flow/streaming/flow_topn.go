@@ -18,6 +18,8 @@
 package streaming
 
 import (
+	"encoding/json"
+
 	"github.com/emirpasic/gods/maps/treemap"
 	"github.com/emirpasic/gods/utils"
 	"github.com/google/go-cmp/cmp"
@@ -33,6 +35,8 @@ const (
 	ASC
 )
 
+var _ flow.Checkpointable = (*topNAggregator)(nil)
+
 type windowedFlow struct {
 	f  *streamingFlow
 	wa flow.WindowAssigner
@@ -81,6 +85,8 @@ type topNAggregator struct {
 	sort       TopNSort
 	comparator utils.Comparator
 	dirty      bool
+	// codec serializes the data of records while checkpointing
+	codec flow.DataCodec
 }
 
 type TopNOption func(aggregator *topNAggregator)
@@ -97,6 +103,13 @@ func OrderBy(sort TopNSort) TopNOption {
 	}
 }
 
+// WithDataCodec sets the codec of the records, which is mandatory for the checkpoint.
+func WithDataCodec(codec flow.DataCodec) TopNOption {
+	return func(aggregator *topNAggregator) {
+		aggregator.codec = codec
+	}
+}
+
 func (t *topNAggregator) Add(input []flow.StreamRecord) {
 	for _, item := range input {
 		sortKey := t.sortKeyExtractor(item)
@@ -175,3 +188,68 @@ func (t *topNAggregator) Snapshot() interface{} {
 func (t *topNAggregator) Dirty() bool {
 	return t.dirty
 }
+
+type topNRecordState struct {
+	Data      []byte `json:"data"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+type topNEntryState struct {
+	Records []topNRecordState `json:"records"`
+	SortKey int64             `json:"sort_key"`
+}
+
+// SnapshotState serializes the tracked records without touching the dirty flag.
+func (t *topNAggregator) SnapshotState() ([]byte, error) {
+	if t.codec == nil {
+		return nil, flow.ErrCodecMissing
+	}
+	entries := make([]topNEntryState, 0, t.treeMap.Size())
+	iter := t.treeMap.Iterator()
+	for iter.Next() {
+		list := iter.Value().([]interface{})
+		entry := topNEntryState{
+			SortKey: iter.Key().(int64),
+			Records: make([]topNRecordState, 0, len(list)),
+		}
+		for _, item := range list {
+			record := item.(flow.StreamRecord)
+			data, err := t.codec.Encode(record.Data())
+			if err != nil {
+				return nil, err
+			}
+			entry.Records = append(entry.Records, topNRecordState{
+				Data:      data,
+				Timestamp: record.TimestampMillis(),
+			})
+		}
+		entries = append(entries, entry)
+	}
+	return json.Marshal(entries)
+}
+
+// RestoreState puts the records back to the buffer.
+// The restored aggregator is always dirty because the records might not reach the sink before the crash,
+// and re-emitting them is harmless as long as the sink writes them idempotently.
+func (t *topNAggregator) RestoreState(raw []byte) error {
+	if t.codec == nil {
+		return flow.ErrCodecMissing
+	}
+	var entries []topNEntryState
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		for _, r := range entry.Records {
+			data, err := t.codec.Decode(r.Data)
+			if err != nil {
+				return err
+			}
+			t.put(entry.SortKey, flow.NewStreamRecord(data, r.Timestamp))
+		}
+	}
+	for t.currentTopNum > t.cacheSize {
+		t.doCleanUp()
+	}
+	return nil
+}
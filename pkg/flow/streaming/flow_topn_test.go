@@ -100,3 +100,50 @@ func TestFlow_TopN_Aggregator(t *testing.T) {
 		})
 	}
 }
+
+type stringCodec struct{}
+
+func (stringCodec) Encode(data interface{}) ([]byte, error) {
+	return []byte(data.(string)), nil
+}
+
+func (stringCodec) Decode(raw []byte) (interface{}, error) {
+	return string(raw), nil
+}
+
+func TestFlow_TopN_Aggregator_Checkpoint(t *testing.T) {
+	require := require.New(t)
+	newAggregator := func(codec flow.DataCodec) *topNAggregator {
+		comparator := func(a, b interface{}) int {
+			return utils.Int64Comparator(b, a)
+		}
+		return &topNAggregator{
+			cacheSize:  2,
+			sort:       DESC,
+			comparator: comparator,
+			treeMap:    treemap.NewWith(comparator),
+			codec:      codec,
+			sortKeyExtractor: func(record flow.StreamRecord) int64 {
+				return int64(len(record.Data().(string)))
+			},
+		}
+	}
+	_, err := newAggregator(nil).SnapshotState()
+	require.ErrorIs(err, flow.ErrCodecMissing)
+
+	origin := newAggregator(stringCodec{})
+	origin.Add([]flow.StreamRecord{
+		flow.NewStreamRecord("a", 1),
+		flow.NewStreamRecord("abc", 2),
+		flow.NewStreamRecord("ab", 3),
+	})
+	expected := origin.Snapshot()
+	require.False(origin.Dirty())
+	state, err := origin.SnapshotState()
+	require.NoError(err)
+
+	restored := newAggregator(stringCodec{})
+	require.NoError(restored.RestoreState(state))
+	require.True(restored.Dirty())
+	require.Equal(expected, restored.Snapshot())
+}
@@ -54,6 +54,8 @@ type WindowedFlow interface {
 	AllowedMaxWindows(windowCnt int) WindowedFlow
 	// TopN applies a TopNAggregation to each Window.
 	TopN(topNum int, opts ...any) Flow
+	// Aggregate applies the custom aggregate function registered by the name to each Window.
+	Aggregate(name string, opts ...any) Flow
 }
 
 // Window is a bucket of elements with a finite size.
@@ -0,0 +1,147 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpchelper
+
+import (
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// Zstd is the name of the zstd compressor.
+const Zstd = "zstd"
+
+// ErrUnknownCompressor is returned if a compressor isn't registered.
+var ErrUnknownCompressor = errors.New("unknown compressor")
+
+func init() {
+	encoding.RegisterCompressor(&zstdCompressor{})
+}
+
+// Compressors returns the names of the registered compressors, which are the ones a client or a server could use.
+func Compressors() []string {
+	return []string{gzip.Name, Zstd}
+}
+
+// CheckCompressor returns an error if the compressor isn't registered. The empty name means no compression.
+func CheckCompressor(name string) error {
+	if name == "" || encoding.GetCompressor(name) != nil {
+		return nil
+	}
+	return errors.WithMessagef(ErrUnknownCompressor, "%s is not one of %v", name, Compressors())
+}
+
+// SendCompressor adapts a registered compressor to the one compressing all the messages a server sends,
+// regardless of the compression of the received messages.
+func SendCompressor(name string) (grpc.Compressor, error) {
+	c := encoding.GetCompressor(name)
+	if c == nil {
+		return nil, errors.WithMessagef(ErrUnknownCompressor, "%s is not one of %v", name, Compressors())
+	}
+	return &sendCompressor{c: c}, nil
+}
+
+type sendCompressor struct {
+	c encoding.Compressor
+}
+
+func (s *sendCompressor) Do(w io.Writer, p []byte) error {
+	wc, err := s.c.Compress(w)
+	if err != nil {
+		return err
+	}
+	if _, err = wc.Write(p); err != nil {
+		_ = wc.Close()
+		return err
+	}
+	return wc.Close()
+}
+
+func (s *sendCompressor) Type() string {
+	return s.c.Name()
+}
+
+// zstdCompressor pools the encoders and the decoders since they are expensive to create.
+type zstdCompressor struct {
+	encoders sync.Pool
+	decoders sync.Pool
+}
+
+func (z *zstdCompressor) Name() string {
+	return Zstd
+}
+
+func (z *zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	if enc, ok := z.encoders.Get().(*zstd.Encoder); ok {
+		enc.Reset(w)
+		return &zstdWriter{Encoder: enc, pool: &z.encoders}, nil
+	}
+	enc, err := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return &zstdWriter{Encoder: enc, pool: &z.encoders}, nil
+}
+
+func (z *zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	if dec, ok := z.decoders.Get().(*zstd.Decoder); ok {
+		if err := dec.Reset(r); err != nil {
+			z.decoders.Put(dec)
+			return nil, err
+		}
+		return &zstdReader{dec: dec, pool: &z.decoders}, nil
+	}
+	dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return &zstdReader{dec: dec, pool: &z.decoders}, nil
+}
+
+type zstdWriter struct {
+	*zstd.Encoder
+	pool *sync.Pool
+}
+
+func (w *zstdWriter) Close() error {
+	defer w.pool.Put(w.Encoder)
+	return w.Encoder.Close()
+}
+
+// zstdReader returns the decoder to the pool once the message is read to the end.
+type zstdReader struct {
+	dec  *zstd.Decoder
+	pool *sync.Pool
+}
+
+func (r *zstdReader) Read(p []byte) (n int, err error) {
+	if r.dec == nil {
+		return 0, io.EOF
+	}
+	n, err = r.dec.Read(p)
+	if errors.Is(err, io.EOF) {
+		r.pool.Put(r.dec)
+		r.dec = nil
+	}
+	return n, err
+}
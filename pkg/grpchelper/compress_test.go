@@ -0,0 +1,70 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpchelper
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+func TestZstdCompressor(t *testing.T) {
+	c := encoding.GetCompressor(Zstd)
+	require.NotNil(t, c)
+	msg := bytes.Repeat([]byte("service_cpm_minute"), 1024)
+	// the second round reuses the pooled encoder and decoder
+	for i := 0; i < 2; i++ {
+		var buf bytes.Buffer
+		w, err := c.Compress(&buf)
+		require.NoError(t, err)
+		_, err = w.Write(msg)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		assert.Less(t, buf.Len(), len(msg))
+
+		r, err := c.Decompress(&buf)
+		require.NoError(t, err)
+		got, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, msg, got)
+	}
+}
+
+func TestSendCompressor(t *testing.T) {
+	cp, err := SendCompressor(gzip.Name)
+	require.NoError(t, err)
+	assert.Equal(t, gzip.Name, cp.Type())
+	msg := []byte("service_cpm_minute")
+	var buf bytes.Buffer
+	require.NoError(t, cp.Do(&buf, msg))
+	r, err := encoding.GetCompressor(gzip.Name).Decompress(&buf)
+	require.NoError(t, err)
+	got, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, msg, got)
+
+	_, err = SendCompressor("snappy")
+	assert.ErrorIs(t, err, ErrUnknownCompressor)
+	assert.ErrorIs(t, CheckCompressor("snappy"), ErrUnknownCompressor)
+	assert.NoError(t, CheckCompressor(""))
+}
@@ -0,0 +1,83 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpchelper
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Receiver is a server-streaming RPC, e.g. the Watch of the registry services.
+type Receiver[T any] interface {
+	Header() (metadata.MD, error)
+	Recv() (T, error)
+}
+
+// Watch opens a stream by open and passes the received messages to handle until ctx is done.
+// A broken stream is reopened after retryInterval. Since the events in between are missed,
+// onOpen, if it's not nil, is invoked once the server starts watching to resync, e.g. by listing the resources.
+func Watch[T any](ctx context.Context, retryInterval time.Duration, open func(ctx context.Context) (Receiver[T], error),
+	onOpen func(ctx context.Context) error, handle func(T),
+) error {
+	for {
+		err := watchOnce(ctx, open, onOpen, handle)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if stat, ok := status.FromError(err); ok && (stat.Code() == codes.Unimplemented || stat.Code() == codes.InvalidArgument) {
+			return err
+		}
+		l.Warn().Err(err).Dur("retryInterval", retryInterval).Msg("the watch is broken, reopening it")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+func watchOnce[T any](ctx context.Context, open func(ctx context.Context) (Receiver[T], error),
+	onOpen func(ctx context.Context) error, handle func(T),
+) error {
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	r, err := open(streamCtx)
+	if err != nil {
+		return err
+	}
+	// the server sends the header once it starts watching
+	if _, err = r.Header(); err != nil {
+		return err
+	}
+	if onOpen != nil {
+		if err = onOpen(ctx); err != nil {
+			return err
+		}
+	}
+	for {
+		m, err := r.Recv()
+		if err != nil {
+			return err
+		}
+		handle(m)
+	}
+}
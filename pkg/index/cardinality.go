@@ -0,0 +1,148 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package index
+
+import (
+	"sync"
+	"time"
+
+	"github.com/apache/skywalking-banyandb/pkg/convert"
+)
+
+// MaxTrackedTerms is the upper bound of the distinct terms tracked for an index rule.
+// The cardinality of an index rule saturates at it, which is selective enough for the query planner.
+const MaxTrackedTerms = 1 << 14
+
+// postingSize is the estimated bytes of an item id in a posting list.
+const postingSize = 8
+
+// RuleStats is the statistics of the index of a rule.
+type RuleStats struct {
+	// LastWrite is the last time a field of the rule is written
+	LastWrite time.Time
+	// LastBuild is the last time the index is rebuilt from the stored data, which is zero if it's never rebuilt
+	LastBuild time.Time
+	// Terms is the number of the distinct terms, which saturates at MaxTrackedTerms
+	Terms int
+	// Size estimates the bytes of the terms and the postings written
+	Size uint64
+}
+
+type ruleStats struct {
+	terms     map[uint64]struct{}
+	lastWrite time.Time
+	lastBuild time.Time
+	size      uint64
+}
+
+// Cardinality collects the number of the distinct terms of the index rules from the written fields.
+// The statistics live in memory, they are rebuilt as the data comes after a restart.
+// A nil Cardinality knows nothing so that callers don't have to check whether it's enabled.
+type Cardinality struct {
+	rules map[uint32]*ruleStats
+	mu    sync.RWMutex
+}
+
+// NewCardinality returns an empty Cardinality.
+func NewCardinality() *Cardinality {
+	return &Cardinality{
+		rules: make(map[uint32]*ruleStats),
+	}
+}
+
+// Observe records the terms of the fields.
+func (c *Cardinality) Observe(fields []Field) {
+	if c == nil || len(fields) == 0 {
+		return
+	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, f := range fields {
+		rs := c.rule(f.Key.IndexRuleID)
+		rs.lastWrite = now
+		rs.size += uint64(len(f.Term) + postingSize)
+		if len(rs.terms) >= MaxTrackedTerms {
+			continue
+		}
+		rs.terms[convert.Hash(f.Term)] = struct{}{}
+	}
+}
+
+func (c *Cardinality) rule(indexRuleID uint32) *ruleStats {
+	rs, ok := c.rules[indexRuleID]
+	if !ok {
+		rs = &ruleStats{terms: make(map[uint64]struct{})}
+		c.rules[indexRuleID] = rs
+	}
+	return rs
+}
+
+// Terms returns the number of the distinct terms of the index rule, 0 means it's unknown.
+func (c *Cardinality) Terms(indexRuleID uint32) int {
+	if c == nil {
+		return 0
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if rs, ok := c.rules[indexRuleID]; ok {
+		return len(rs.terms)
+	}
+	return 0
+}
+
+// Stats returns the statistics of the index rule, which are zero if nothing is observed.
+func (c *Cardinality) Stats(indexRuleID uint32) RuleStats {
+	if c == nil {
+		return RuleStats{}
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	rs, ok := c.rules[indexRuleID]
+	if !ok {
+		return RuleStats{}
+	}
+	return RuleStats{
+		LastWrite: rs.lastWrite,
+		LastBuild: rs.lastBuild,
+		Terms:     len(rs.terms),
+		Size:      rs.size,
+	}
+}
+
+// Reset clears the terms and the size of the index rule, which are collected again when its index is rebuilt.
+func (c *Cardinality) Reset(indexRuleID uint32) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rs := c.rule(indexRuleID)
+	rs.terms = make(map[uint64]struct{})
+	rs.size = 0
+}
+
+// Built marks the index of the rule rebuilt at t.
+func (c *Cardinality) Built(indexRuleID uint32, t time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rule(indexRuleID).lastBuild = t
+}
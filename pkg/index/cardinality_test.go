@@ -0,0 +1,84 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package index_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/apache/skywalking-banyandb/pkg/convert"
+	"github.com/apache/skywalking-banyandb/pkg/index"
+)
+
+func TestCardinality(t *testing.T) {
+	c := index.NewCardinality()
+	field := func(ruleID uint32, term string) index.Field {
+		return index.Field{
+			Key:  index.FieldKey{IndexRuleID: ruleID},
+			Term: []byte(term),
+		}
+	}
+	c.Observe([]index.Field{field(1, "a"), field(1, "b"), field(2, "a")})
+	c.Observe([]index.Field{field(1, "a")})
+	assert.Equal(t, 2, c.Terms(1))
+	assert.Equal(t, 1, c.Terms(2))
+	assert.Zero(t, c.Terms(3))
+
+	fields := make([]index.Field, 0, index.MaxTrackedTerms+10)
+	for i := 0; i < index.MaxTrackedTerms+10; i++ {
+		fields = append(fields, index.Field{
+			Key:  index.FieldKey{IndexRuleID: 4},
+			Term: convert.Int64ToBytes(int64(i)),
+		})
+	}
+	c.Observe(fields)
+	assert.Equal(t, index.MaxTrackedTerms, c.Terms(4))
+}
+
+func TestCardinalityStats(t *testing.T) {
+	c := index.NewCardinality()
+	c.Observe([]index.Field{
+		{Key: index.FieldKey{IndexRuleID: 1}, Term: []byte("ab")},
+		{Key: index.FieldKey{IndexRuleID: 1}, Term: []byte("ab")},
+	})
+	stats := c.Stats(1)
+	assert.Equal(t, 1, stats.Terms)
+	assert.Equal(t, uint64(2*(2+8)), stats.Size)
+	assert.False(t, stats.LastWrite.IsZero())
+	assert.True(t, stats.LastBuild.IsZero())
+
+	built := time.Now()
+	c.Reset(1)
+	c.Built(1, built)
+	stats = c.Stats(1)
+	assert.Zero(t, stats.Terms)
+	assert.Zero(t, stats.Size)
+	assert.Equal(t, built, stats.LastBuild)
+	assert.Equal(t, index.RuleStats{}, c.Stats(2))
+}
+
+func TestNilCardinality(t *testing.T) {
+	var c *index.Cardinality
+	c.Observe([]index.Field{{Key: index.FieldKey{IndexRuleID: 1}, Term: []byte("a")}})
+	assert.Zero(t, c.Terms(1))
+	c.Reset(1)
+	c.Built(1, time.Now())
+	assert.Equal(t, index.RuleStats{}, c.Stats(1))
+}
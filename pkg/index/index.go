@@ -155,17 +155,26 @@ type FieldIterable interface {
 
 type Searcher interface {
 	FieldIterable
-	Match(fieldKey FieldKey, match []string) (list posting.List, err error)
+	Match(fieldKey FieldKey, match []string, opts *modelv1.Condition_MatchOption) (list posting.List, err error)
 	MatchField(fieldKey FieldKey) (list posting.List, err error)
 	MatchTerms(field Field) (list posting.List, err error)
 	Range(fieldKey FieldKey, opts RangeOpts) (list posting.List, err error)
 }
 
+// VisitFunc is called with the fields written with an item
+type VisitFunc func(itemID common.ItemID, fields []Field) error
+
+type Visitor interface {
+	// Visit all the items and their fields, e.g. to copy them into another store
+	Visit(visitFn VisitFunc) error
+}
+
 type Store interface {
 	observability.Observable
 	io.Closer
 	Writer
 	Searcher
+	Visitor
 }
 
 type GetSearcher func(location databasev1.IndexRule_Type) (Searcher, error)
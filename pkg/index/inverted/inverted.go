@@ -23,6 +23,7 @@ import (
 	"errors"
 	"log"
 	"math"
+	"strings"
 
 	"github.com/blugelabs/bluge"
 	"github.com/blugelabs/bluge/analysis"
@@ -42,7 +43,11 @@ import (
 	"github.com/apache/skywalking-banyandb/pkg/logger"
 )
 
-const docID = "_id"
+const (
+	docID = "_id"
+	// analyzerSuffix names the stored field holding the analyzer of a field, which is required to rewrite the field
+	analyzerSuffix = "#analyzer"
+)
 
 var analyzers map[databasev1.IndexRule_Analyzer]*analysis.Analyzer
 
@@ -92,12 +97,67 @@ func (s *store) Write(fields []index.Field, itemID common.ItemID) error {
 		field := bluge.NewKeywordFieldBytes(f.Key.MarshalToStr(), f.Term).StoreValue().Sortable()
 		if f.Key.Analyzer != databasev1.IndexRule_ANALYZER_UNSPECIFIED {
 			field.WithAnalyzer(analyzers[f.Key.Analyzer])
+			doc.AddField(bluge.NewStoredOnlyField(f.Key.MarshalToStr()+analyzerSuffix, []byte{byte(f.Key.Analyzer)}))
 		}
 		doc.AddField(field)
 	}
 	return s.writer.Insert(doc)
 }
 
+// Visit the stored values of the documents. The analyzers of the fields written before they're stored are absent.
+func (s *store) Visit(visitFn index.VisitFunc) error {
+	reader, err := s.writer.Reader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+	documentMatchIterator, err := reader.Search(context.Background(), bluge.NewAllMatches(bluge.NewMatchAllQuery()))
+	if err != nil {
+		return err
+	}
+	for {
+		match, errNext := documentMatchIterator.Next()
+		if errNext != nil {
+			return errNext
+		}
+		if match == nil {
+			return nil
+		}
+		var itemID common.ItemID
+		var fields []index.Field
+		analyzerOf := make(map[string]databasev1.IndexRule_Analyzer)
+		errField := match.VisitStoredFields(func(field string, value []byte) bool {
+			switch {
+			case field == docID:
+				itemID = common.ItemID(convert.BytesToUint64(value))
+			case strings.HasSuffix(field, analyzerSuffix):
+				if len(value) > 0 {
+					analyzerOf[strings.TrimSuffix(field, analyzerSuffix)] = databasev1.IndexRule_Analyzer(value[0])
+				}
+			default:
+				f := index.Field{Term: y.Copy(value)}
+				err = f.Key.Unmarshal([]byte(field))
+				fields = append(fields, f)
+			}
+			return err == nil
+		})
+		if errField != nil {
+			return errField
+		}
+		if err != nil {
+			return err
+		}
+		for i := range fields {
+			fields[i].Key.Analyzer = analyzerOf[fields[i].Key.MarshalToStr()]
+		}
+		if err = visitFn(itemID, fields); err != nil {
+			return err
+		}
+	}
+}
+
 func (s *store) Iterator(fieldKey index.FieldKey, termRange index.RangeOpts, order modelv1.Sort) (iter index.FieldIterator, err error) {
 	if termRange.Lower != nil &&
 		termRange.Upper != nil &&
@@ -152,7 +212,8 @@ func (s *store) MatchTerms(field index.Field) (list posting.List, err error) {
 	return list, err
 }
 
-func (s *store) Match(fieldKey index.FieldKey, matches []string) (posting.List, error) {
+// Match searches the items whose tokens match all of matches in the mode of opts.
+func (s *store) Match(fieldKey index.FieldKey, matches []string, opts *modelv1.Condition_MatchOption) (posting.List, error) {
 	if len(matches) == 0 {
 		return roaring.EmptyPostingList, nil
 	}
@@ -163,6 +224,12 @@ func (s *store) Match(fieldKey index.FieldKey, matches []string) (posting.List,
 	fk := fieldKey.MarshalToStr()
 	var query bluge.Query
 	getMatchQuery := func(match string) bluge.Query {
+		switch opts.GetMode() {
+		case modelv1.Condition_MatchOption_MODE_PREFIX:
+			return bluge.NewPrefixQuery(normalize(fieldKey.Analyzer, match)).SetField(fk)
+		case modelv1.Condition_MatchOption_MODE_WILDCARD:
+			return bluge.NewWildcardQuery(normalize(fieldKey.Analyzer, match)).SetField(fk)
+		}
 		q := bluge.NewMatchQuery(match).SetField(fk)
 		if fieldKey.Analyzer != databasev1.IndexRule_ANALYZER_UNSPECIFIED {
 			q.SetAnalyzer(analyzers[fieldKey.Analyzer])
@@ -191,6 +258,16 @@ func (s *store) Match(fieldKey index.FieldKey, matches []string) (posting.List,
 	return list, err
 }
 
+// normalize lowercases the prefix or the wildcard if the analyzer lowercases the tokens,
+// since neither of them is analyzed.
+func normalize(a databasev1.IndexRule_Analyzer, pattern string) string {
+	switch a {
+	case databasev1.IndexRule_ANALYZER_SIMPLE, databasev1.IndexRule_ANALYZER_STANDARD:
+		return strings.ToLower(pattern)
+	}
+	return pattern
+}
+
 func (s *store) Range(fieldKey index.FieldKey, opts index.RangeOpts) (list posting.List, err error) {
 	iter, err := s.Iterator(fieldKey, opts, modelv1.Sort_SORT_ASC)
 	if err != nil {
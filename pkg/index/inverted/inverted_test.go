@@ -26,6 +26,7 @@ import (
 
 	"github.com/apache/skywalking-banyandb/api/common"
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
 	"github.com/apache/skywalking-banyandb/pkg/index"
 	"github.com/apache/skywalking-banyandb/pkg/index/posting"
 	"github.com/apache/skywalking-banyandb/pkg/index/posting/roaring"
@@ -66,8 +67,11 @@ func TestStore_Match(t *testing.T) {
 		Term: []byte("org.apache.skywalking.examples.OrderService.order"),
 	}}, common.ItemID(3)))
 
+	prefix := &modelv1.Condition_MatchOption{Mode: modelv1.Condition_MatchOption_MODE_PREFIX}
+	wildcard := &modelv1.Condition_MatchOption{Mode: modelv1.Condition_MatchOption_MODE_WILDCARD}
 	tests := []struct {
 		matches []string
+		opts    *modelv1.Condition_MatchOption
 		want    posting.List
 		wantErr bool
 	}{
@@ -123,11 +127,39 @@ func TestStore_Match(t *testing.T) {
 			matches: []string{"OrderService", "order"},
 			want:    roaring.NewPostingListWithInitialData(3),
 		},
+		{
+			matches: []string{"Order"},
+			opts:    prefix,
+			want:    roaring.NewPostingListWithInitialData(1, 3),
+		},
+		{
+			matches: []string{"ro", "prod"},
+			opts:    prefix,
+			want:    roaring.NewPostingListWithInitialData(2),
+		},
+		{
+			matches: []string{"/product"},
+			opts:    prefix,
+			want:    roaring.NewPostingList(),
+		},
+		{
+			matches: []string{"sky*ing"},
+			opts:    wildcard,
+			want:    roaring.NewPostingListWithInitialData(3),
+		},
+		{
+			matches: []string{"pro?uct"},
+			opts:    wildcard,
+			want:    roaring.NewPostingListWithInitialData(1, 2),
+		},
 	}
 	for _, tt := range tests {
 		name := strings.Join(tt.matches, " and ")
+		if tt.opts != nil {
+			name += " in " + tt.opts.GetMode().String()
+		}
 		t.Run(name, func(t *testing.T) {
-			list, err := s.Match(serviceName, tt.matches)
+			list, err := s.Match(serviceName, tt.matches, tt.opts)
 			if tt.wantErr {
 				tester.Error(err)
 				return
@@ -171,6 +203,37 @@ func TestStore_Iterator(t *testing.T) {
 	testcases.RunDuration(t, data, s)
 }
 
+func TestStore_Visit(t *testing.T) {
+	tester := assert.New(t)
+	path, fn := setUp(require.New(t))
+	s, err := NewStore(StoreOpts{
+		Path:   path + "/source",
+		Logger: logger.GetLogger("test"),
+	})
+	tester.NoError(err)
+	copied, err := NewStore(StoreOpts{
+		Path:   path + "/copied",
+		Logger: logger.GetLogger("test"),
+	})
+	tester.NoError(err)
+	defer func() {
+		tester.NoError(s.Close())
+		tester.NoError(copied.Close())
+		fn()
+	}()
+	testcases.SetUp(tester, s)
+	tester.NoError(s.Write([]index.Field{{
+		Key:  serviceName,
+		Term: []byte("GET::/product/order"),
+	}}, common.ItemID(100)))
+	testcases.Copy(tester, s, copied)
+	testcases.RunServiceName(t, copied)
+	// the analyzer of the field is kept
+	list, err := copied.Match(serviceName, []string{"product"}, nil)
+	tester.NoError(err)
+	tester.True(roaring.NewPostingListWithInitialData(100).Equal(list))
+}
+
 func setUp(t *require.Assertions) (tempDir string, deferFunc func()) {
 	t.NoError(logger.Init(logger.Logging{
 		Env:   "dev",
@@ -18,6 +18,7 @@
 package lsm
 
 import (
+	"github.com/pkg/errors"
 	"go.uber.org/multierr"
 
 	"github.com/apache/skywalking-banyandb/api/common"
@@ -56,6 +57,29 @@ func (s *store) Write(fields []index.Field, itemID common.ItemID) (err error) {
 	return err
 }
 
+func (s *store) Visit(visitFn index.VisitFunc) error {
+	iter := s.lsm.NewIterator(kv.DefaultScanOpts)
+	defer func() {
+		_ = iter.Close()
+	}()
+	for iter.Rewind(); iter.Valid(); iter.Next() {
+		// the term might be empty, which is rejected by index.Field.Unmarshal
+		raw := iter.Key()
+		if len(raw) < 12 {
+			return errors.WithMessagef(index.ErrMalformed, "malformed field: expected 12 at least, got %d", len(raw))
+		}
+		var field index.Field
+		if err := field.Key.Unmarshal(raw[:12]); err != nil {
+			return err
+		}
+		field.Term = append([]byte(nil), raw[12:]...)
+		if err := visitFn(common.ItemID(convert.BytesToUint64(iter.Val())), []index.Field{field}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type StoreOpts struct {
 	Path         string
 	Logger       *logger.Logger
@@ -61,6 +61,29 @@ func TestStore_Iterator(t *testing.T) {
 	testcases.RunDuration(t, data, s)
 }
 
+func TestStore_Visit(t *testing.T) {
+	tester := assert.New(t)
+	path, fn := setUp(require.New(t))
+	s, err := NewStore(StoreOpts{
+		Path:   path + "/source",
+		Logger: logger.GetLogger("test"),
+	})
+	tester.NoError(err)
+	copied, err := NewStore(StoreOpts{
+		Path:   path + "/copied",
+		Logger: logger.GetLogger("test"),
+	})
+	tester.NoError(err)
+	defer func() {
+		tester.NoError(s.Close())
+		tester.NoError(copied.Close())
+		fn()
+	}()
+	testcases.SetUp(tester, s)
+	testcases.Copy(tester, s, copied)
+	testcases.RunServiceName(t, copied)
+}
+
 func setUp(t *require.Assertions) (tempDir string, deferFunc func()) {
 	t.NoError(logger.Init(logger.Logging{
 		Env:   "dev",
@@ -92,6 +92,6 @@ func (s *store) Iterator(fieldKey index.FieldKey, termRange index.RangeOpts, ord
 		})
 }
 
-func (s *store) Match(_ index.FieldKey, _ []string) (posting.List, error) {
+func (s *store) Match(_ index.FieldKey, _ []string, _ *modelv1.Condition_MatchOption) (posting.List, error) {
 	return nil, errors.WithMessage(ErrUnsupportedOperation, "LSM-Tree index doesn't support full-text searching")
 }
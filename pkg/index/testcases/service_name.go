@@ -99,3 +99,10 @@ func SetUp(t *assert.Assertions, store SimpleStore) {
 		}
 	}
 }
+
+// Copy writes the items visited in a store into another one.
+func Copy(t *assert.Assertions, from index.Visitor, to index.Writer) {
+	t.NoError(from.Visit(func(itemID common.ItemID, fields []index.Field) error {
+		return to.Write(fields, itemID)
+	}))
+}
@@ -0,0 +1,153 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package intern shares the strings repeated by the writes, e.g. the names of services and endpoints,
+// so that the copies decoded from every write become garbage at once instead of being retained.
+package intern
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	// the stripes of a table, which are locked separately to reduce the contention of the concurrent writes
+	stripes = 16
+	// MaxLength is the length of the longest string being interned, the longer ones are rarely repeated
+	MaxLength = 256
+)
+
+var (
+	hits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "banyand_intern_hits",
+		Help: "The number of the strings found in the interning table",
+	}, []string{"table"})
+	misses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "banyand_intern_misses",
+		Help: "The number of the strings added to the interning table",
+	}, []string{"table"})
+	evictions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "banyand_intern_evictions",
+		Help: "The number of the strings evicted from the interning table",
+	}, []string{"table"})
+)
+
+// Table holds the interned strings within a bound of entries, and evicts the least recently used ones.
+// A nil Table interns nothing so that callers don't have to check whether it's enabled.
+type Table struct {
+	hits      prometheus.Counter
+	misses    prometheus.Counter
+	evictions prometheus.Counter
+	stripes   [stripes]stripe
+}
+
+type stripe struct {
+	entries    map[string]*list.Element
+	order      *list.List
+	maxEntries int
+	mu         sync.Mutex
+}
+
+type entry struct {
+	s string
+	b []byte
+}
+
+// New returns a Table named name holding size strings at most, or nil if size is not positive.
+func New(name string, size int) *Table {
+	if size <= 0 {
+		return nil
+	}
+	t := &Table{
+		hits:      hits.WithLabelValues(name),
+		misses:    misses.WithLabelValues(name),
+		evictions: evictions.WithLabelValues(name),
+	}
+	perStripe := (size + stripes - 1) / stripes
+	for i := range t.stripes {
+		t.stripes[i] = stripe{
+			entries:    make(map[string]*list.Element),
+			order:      list.New(),
+			maxEntries: perStripe,
+		}
+	}
+	return t
+}
+
+// String returns the interned string equal to s.
+func (t *Table) String(s string) string {
+	if t == nil || s == "" || len(s) > MaxLength {
+		return s
+	}
+	return t.lookup(s).s
+}
+
+// Bytes returns the interned bytes of s, which are shared and shouldn't be modified.
+func (t *Table) Bytes(s string) []byte {
+	if t == nil || len(s) > MaxLength {
+		return []byte(s)
+	}
+	return t.lookup(s).b
+}
+
+func (t *Table) lookup(s string) *entry {
+	st := &t.stripes[hash(s)%stripes]
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if e, ok := st.entries[s]; ok {
+		st.order.MoveToFront(e)
+		t.hits.Inc()
+		return e.Value.(*entry)
+	}
+	t.misses.Inc()
+	e := &entry{s: s, b: []byte(s)}
+	st.entries[s] = st.order.PushFront(e)
+	for st.order.Len() > st.maxEntries {
+		evicted := st.order.Remove(st.order.Back()).(*entry)
+		delete(st.entries, evicted.s)
+		t.evictions.Inc()
+	}
+	return e
+}
+
+// Len returns the number of the interned strings.
+func (t *Table) Len() int {
+	if t == nil {
+		return 0
+	}
+	n := 0
+	for i := range t.stripes {
+		st := &t.stripes[i]
+		st.mu.Lock()
+		n += st.order.Len()
+		st.mu.Unlock()
+	}
+	return n
+}
+
+// hash is the FNV-1a hash of s, which doesn't allocate.
+func hash(s string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(s); i++ {
+		h ^= uint32(s[i])
+		h *= 16777619
+	}
+	return h
+}
@@ -0,0 +1,66 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package intern_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/pkg/intern"
+)
+
+func TestIntern(t *testing.T) {
+	table := intern.New("test", 1024)
+	require.NotNil(t, table)
+	a := table.Bytes(strings.Repeat("svc", 2))
+	b := table.Bytes(strings.Repeat("svc", 2))
+	assert.Equal(t, []byte("svcsvc"), a)
+	assert.Same(t, &a[0], &b[0], "the bytes are shared")
+	assert.Equal(t, "svcsvc", table.String(strings.Repeat("svc", 2)))
+	assert.Equal(t, 1, table.Len())
+
+	assert.Zero(t, testing.AllocsPerRun(100, func() {
+		table.Bytes("svcsvc")
+	}), "the interned bytes are returned without allocations")
+
+	long := strings.Repeat("x", intern.MaxLength+1)
+	assert.Equal(t, long, table.String(long))
+	assert.Equal(t, "", table.String(""))
+	assert.Equal(t, 1, table.Len(), "the long and empty strings aren't interned")
+}
+
+func TestInternEviction(t *testing.T) {
+	table := intern.New("test", 64)
+	for i := 0; i < 1000; i++ {
+		table.String(fmt.Sprintf("endpoint-%d", i))
+	}
+	assert.LessOrEqual(t, table.Len(), 64)
+	assert.Positive(t, table.Len())
+}
+
+func TestNilTable(t *testing.T) {
+	var table *intern.Table
+	assert.Nil(t, intern.New("test", 0))
+	assert.Equal(t, "svc", table.String("svc"))
+	assert.Equal(t, []byte("svc"), table.Bytes("svc"))
+	assert.Zero(t, table.Len())
+}
@@ -90,6 +90,38 @@ func Init(cfg Logging) (err error) {
 	return nil
 }
 
+// SetLevel changes the level of all the loggers at runtime.
+func SetLevel(level string) error {
+	lvl, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+	zerolog.SetGlobalLevel(lvl)
+	return nil
+}
+
+// CheckLevel returns an error if the level is unknown.
+func CheckLevel(level string) error {
+	_, err := parseLevel(level)
+	return err
+}
+
+func parseLevel(level string) (zerolog.Level, error) {
+	lvl, err := zerolog.ParseLevel(level)
+	if err != nil {
+		return lvl, err
+	}
+	if lvl == zerolog.NoLevel {
+		return lvl, fmt.Errorf("unknown level %q", level)
+	}
+	return lvl, nil
+}
+
+// Level returns the current level of the loggers.
+func Level() string {
+	return zerolog.GlobalLevel().String()
+}
+
 // getLogger initializes a root logger
 func getLogger(cfg Logging) (*Logger, error) {
 	lvl, err := zerolog.ParseLevel(cfg.Level)
@@ -116,6 +148,8 @@ func getLogger(cfg Logging) (*Logger, error) {
 	default:
 		w = os.Stderr
 	}
-	l := zerolog.New(w).Level(lvl).With().Timestamp().Logger()
+	// the level is global, so that it's changed at runtime for all the loggers derived from the root one
+	zerolog.SetGlobalLevel(lvl)
+	l := zerolog.New(w).With().Timestamp().Logger()
 	return &Logger{module: "root", Logger: &l}, nil
 }
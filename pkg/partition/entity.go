@@ -18,12 +18,15 @@
 package partition
 
 import (
+	"time"
+
 	"github.com/pkg/errors"
 
 	"github.com/apache/skywalking-banyandb/api/common"
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
 	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
 	"github.com/apache/skywalking-banyandb/banyand/tsdb"
+	"github.com/apache/skywalking-banyandb/pkg/intern"
 	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
 )
 
@@ -47,14 +50,19 @@ func NewEntityLocator(families []*databasev1.TagFamilySpec, entity *databasev1.E
 	return locator
 }
 
-func (e EntityLocator) Find(subject string, value []*modelv1.TagFamilyForWrite) (tsdb.Entity, error) {
+func (e EntityLocator) Find(table *intern.Table, subject string, value []*modelv1.TagFamilyForWrite) (tsdb.Entity, error) {
 	entity := make(tsdb.Entity, len(e)+1)
-	entity[0] = []byte(subject)
+	entity[0] = table.Bytes(subject)
 	for i, index := range e {
 		tag, err := GetTagByOffset(value, index.FamilyOffset, index.TagOffset)
 		if err != nil {
 			return nil, err
 		}
+		// the string entries, e.g. the names of services, are shared by the table instead of being copied for every write
+		if str, ok := tag.GetValue().(*modelv1.TagValue_Str); ok {
+			entity[i+1] = table.Bytes(str.Str.GetValue())
+			continue
+		}
 		entry, errMarshal := pbv1.MarshalIndexFieldValue(tag)
 		if errors.Is(errMarshal, pbv1.ErrNullValue) {
 			continue
@@ -67,12 +75,16 @@ func (e EntityLocator) Find(subject string, value []*modelv1.TagFamilyForWrite)
 	return entity, nil
 }
 
-func (e EntityLocator) Locate(subject string, value []*modelv1.TagFamilyForWrite, shardNum uint32) (tsdb.Entity, common.ShardID, error) {
-	entity, err := e.Find(subject, value)
+// Locate finds the entity of the value generated at t and the shard to write it.
+// The entries of the entity are interned by the table, which are shared and shouldn't be modified.
+func (e EntityLocator) Locate(table *intern.Table, subject string, value []*modelv1.TagFamilyForWrite,
+	sharding Sharding, t time.Time,
+) (tsdb.Entity, common.ShardID, error) {
+	entity, err := e.Find(table, subject, value)
 	if err != nil {
 		return nil, 0, err
 	}
-	id, err := ShardID(entity.Marshal(), shardNum)
+	id, err := sharding.ShardID(entity.Marshal(), t)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -0,0 +1,130 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package partition
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/apache/skywalking-banyandb/pkg/convert"
+)
+
+// Ring is a consistent hashing ring placing the keys on the nodes.
+// Each node owns the replicas, i.e. the virtual nodes, spread over the ring,
+// so that only the keys owned by a node move when it joins or leaves.
+// It's not safe for the concurrent use.
+type Ring struct {
+	owners   map[uint64]string
+	nodes    map[string]struct{}
+	hashes   []uint64
+	replicas int
+}
+
+// NewRing returns an empty Ring whose nodes have the replicas.
+func NewRing(replicas int) *Ring {
+	if replicas < 1 {
+		replicas = 1
+	}
+	return &Ring{
+		owners:   make(map[uint64]string),
+		nodes:    make(map[string]struct{}),
+		replicas: replicas,
+	}
+}
+
+// Add places the node on the ring, which is ignored if the node exists.
+func (r *Ring) Add(node string) {
+	if _, ok := r.nodes[node]; ok {
+		return
+	}
+	r.nodes[node] = struct{}{}
+	for i := 0; i < r.replicas; i++ {
+		h := replicaHash(node, i)
+		if _, ok := r.owners[h]; !ok {
+			r.hashes = append(r.hashes, h)
+		}
+		r.owners[h] = node
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+}
+
+// Remove takes the node off the ring.
+func (r *Ring) Remove(node string) {
+	if _, ok := r.nodes[node]; !ok {
+		return
+	}
+	delete(r.nodes, node)
+	hashes := r.hashes[:0]
+	for _, h := range r.hashes {
+		if r.owners[h] == node {
+			delete(r.owners, h)
+			continue
+		}
+		hashes = append(hashes, h)
+	}
+	r.hashes = hashes
+}
+
+// Locate returns the node owning the key, which is the first replica clockwise from the hash of the key.
+// It returns false if the ring is empty.
+func (r *Ring) Locate(key []byte) (string, bool) {
+	if len(r.hashes) == 0 {
+		return "", false
+	}
+	return r.owners[r.hashes[r.search(key)]], true
+}
+
+// LocateN returns at most n distinct nodes met clockwise from the hash of the key, the owner of the key first.
+// They are the nodes holding the copies of the key.
+func (r *Ring) LocateN(key []byte, n int) []string {
+	if n > len(r.nodes) {
+		n = len(r.nodes)
+	}
+	if n < 1 {
+		return nil
+	}
+	nodes := make([]string, 0, n)
+	seen := make(map[string]struct{}, n)
+	for i, start := 0, r.search(key); len(nodes) < n; i++ {
+		node := r.owners[r.hashes[(start+i)%len(r.hashes)]]
+		if _, ok := seen[node]; ok {
+			continue
+		}
+		seen[node] = struct{}{}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+func (r *Ring) search(key []byte) int {
+	h := convert.Hash(key)
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return i
+}
+
+// Len returns the number of the nodes.
+func (r *Ring) Len() int {
+	return len(r.nodes)
+}
+
+func replicaHash(node string, replica int) uint64 {
+	return convert.Hash([]byte(node + "#" + strconv.Itoa(replica)))
+}
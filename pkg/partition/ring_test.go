@@ -0,0 +1,89 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package partition_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/apache/skywalking-banyandb/pkg/partition"
+)
+
+func TestRing(t *testing.T) {
+	r := partition.NewRing(64)
+	_, ok := r.Locate([]byte("any"))
+	assert.False(t, ok)
+
+	r.Add("node-1")
+	r.Add("node-2")
+	r.Add("node-3")
+	r.Add("node-3")
+	assert.Equal(t, 3, r.Len())
+	keys := make([][]byte, 1000)
+	before := make([]string, len(keys))
+	counts := make(map[string]int)
+	for i := range keys {
+		keys[i] = []byte("key-" + strconv.Itoa(i))
+		owner, located := r.Locate(keys[i])
+		assert.True(t, located)
+		before[i] = owner
+		counts[owner]++
+	}
+	// the virtual nodes spread the keys over all the nodes
+	assert.Len(t, counts, 3)
+
+	r.Remove("node-2")
+	assert.Equal(t, 2, r.Len())
+	for i, key := range keys {
+		owner, _ := r.Locate(key)
+		assert.NotEqual(t, "node-2", owner)
+		// only the keys of the leaving node move
+		if before[i] != "node-2" {
+			assert.Equal(t, before[i], owner)
+		}
+	}
+
+	r.Add("node-4")
+	var moved int
+	for i, key := range keys {
+		owner, _ := r.Locate(key)
+		if owner != before[i] && owner != "node-4" && before[i] != "node-2" {
+			moved++
+		}
+	}
+	assert.Zero(t, moved)
+}
+
+func TestRingLocateN(t *testing.T) {
+	r := partition.NewRing(64)
+	assert.Empty(t, r.LocateN([]byte("any"), 2))
+	r.Add("node-1")
+	r.Add("node-2")
+	r.Add("node-3")
+	for i := 0; i < 100; i++ {
+		key := []byte("key-" + strconv.Itoa(i))
+		owner, _ := r.Locate(key)
+		nodes := r.LocateN(key, 2)
+		assert.Len(t, nodes, 2)
+		assert.Equal(t, owner, nodes[0])
+		assert.NotEqual(t, nodes[0], nodes[1])
+		assert.ElementsMatch(t, []string{"node-1", "node-2", "node-3"}, r.LocateN(key, 5))
+	}
+}
@@ -18,11 +18,18 @@
 package partition
 
 import (
+	"time"
+
 	"github.com/pkg/errors"
 
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	"github.com/apache/skywalking-banyandb/banyand/tsdb"
 	"github.com/apache/skywalking-banyandb/pkg/convert"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
 )
 
+var ErrInvalidTimeBand = errors.New("the time band is invalid")
+
 func ShardID(key []byte, shardNum uint32) (uint, error) {
 	if shardNum < 1 {
 		return 0, errors.New("invalid shardNum")
@@ -30,3 +37,60 @@ func ShardID(key []byte, shardNum uint32) (uint, error) {
 	encodeKey := convert.Hash(key)
 	return uint(encodeKey % uint64(shardNum)), nil
 }
+
+// Sharding selects the shards of the series in two levels: the time band and the entity hash.
+// The data is recent if it's generated within the recent band when it's written,
+// which goes to one of all the shards by the entity hash to spread the writes.
+// The older data, for example the backfilled one, is consolidated into the first few shards of the band.
+// The time band is disabled if bandShardNum is 0.
+type Sharding struct {
+	recent       tsdb.IntervalRule
+	ShardNum     uint32
+	bandShardNum uint32
+}
+
+// NewSharding returns a Sharding of shardNum shards, whose time band is disabled if band is nil.
+func NewSharding(shardNum uint32, band *commonv1.ShardTimeBand) (Sharding, error) {
+	s := Sharding{ShardNum: shardNum}
+	if band == nil {
+		return s, nil
+	}
+	if band.GetShardNum() < 1 || band.GetShardNum() >= shardNum {
+		return s, errors.Wrapf(ErrInvalidTimeBand, "the shard num %d should be in [1, %d)", band.GetShardNum(), shardNum)
+	}
+	recent, err := pbv1.ToIntervalRule(band.GetRecent())
+	if err != nil {
+		return s, errors.Wrapf(ErrInvalidTimeBand, "recent: %v", err)
+	}
+	s.recent = recent
+	s.bandShardNum = band.GetShardNum()
+	return s, nil
+}
+
+// ShardID returns the shard where the data generated at t is written.
+func (s Sharding) ShardID(key []byte, t time.Time) (uint, error) {
+	if s.bandShardNum > 0 && t.Before(s.recent.PreviousTime(time.Now())) {
+		return ShardID(key, s.bandShardNum)
+	}
+	return ShardID(key, s.ShardNum)
+}
+
+// ShardIDs returns the shards which might hold the data of the key.
+// The recent data turns older as time goes by without moving, so both bands are searched.
+func (s Sharding) ShardIDs(key []byte) ([]uint, error) {
+	id, err := ShardID(key, s.ShardNum)
+	if err != nil {
+		return nil, err
+	}
+	if s.bandShardNum < 1 {
+		return []uint{id}, nil
+	}
+	bandID, err := ShardID(key, s.bandShardNum)
+	if err != nil {
+		return nil, err
+	}
+	if bandID == id {
+		return []uint{id}, nil
+	}
+	return []uint{id, bandID}, nil
+}
@@ -0,0 +1,80 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package partition_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	"github.com/apache/skywalking-banyandb/pkg/partition"
+)
+
+func TestShardingWithoutTimeBand(t *testing.T) {
+	s, err := partition.NewSharding(4, nil)
+	require.NoError(t, err)
+	key := []byte("service_1")
+	want, err := partition.ShardID(key, 4)
+	require.NoError(t, err)
+	for _, ts := range []time.Time{time.Now(), time.Now().Add(-30 * 24 * time.Hour)} {
+		got, errShard := s.ShardID(key, ts)
+		require.NoError(t, errShard)
+		assert.Equal(t, want, got)
+	}
+	ids, err := s.ShardIDs(key)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{want}, ids)
+}
+
+func TestShardingWithTimeBand(t *testing.T) {
+	s, err := partition.NewSharding(8, &commonv1.ShardTimeBand{
+		Recent:   &commonv1.IntervalRule{Unit: commonv1.IntervalRule_UNIT_HOUR, Num: 2},
+		ShardNum: 2,
+	})
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		key := []byte{byte(i)}
+		recent, errShard := s.ShardID(key, time.Now().Add(-time.Hour))
+		require.NoError(t, errShard)
+		assert.Less(t, recent, uint(8))
+		old, errShard := s.ShardID(key, time.Now().Add(-3*time.Hour))
+		require.NoError(t, errShard)
+		assert.Less(t, old, uint(2))
+		ids, errShard := s.ShardIDs(key)
+		require.NoError(t, errShard)
+		assert.Contains(t, ids, recent)
+		assert.Contains(t, ids, old)
+		assert.LessOrEqual(t, len(ids), 2)
+	}
+}
+
+func TestShardingInvalidTimeBand(t *testing.T) {
+	_, err := partition.NewSharding(2, &commonv1.ShardTimeBand{
+		Recent:   &commonv1.IntervalRule{Unit: commonv1.IntervalRule_UNIT_HOUR, Num: 2},
+		ShardNum: 2,
+	})
+	assert.ErrorIs(t, err, partition.ErrInvalidTimeBand)
+	_, err = partition.NewSharding(4, &commonv1.ShardTimeBand{
+		Recent:   &commonv1.IntervalRule{Num: 2},
+		ShardNum: 2,
+	})
+	assert.ErrorIs(t, err, partition.ErrInvalidTimeBand)
+}
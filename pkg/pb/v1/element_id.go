@@ -0,0 +1,93 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/convert"
+)
+
+var (
+	ErrEmptyElementID    = errors.New("the element id is empty")
+	ErrElementIDConflict = errors.New("the element id conflicts with the generated one")
+)
+
+// ElementID returns the id of an element following the policy. id is the one provided by the client.
+// The families should be enriched in advance since the tags to hash might be filled by the enrichment.
+func ElementID(policy *databasev1.ElementIDPolicy, specs []*databasev1.TagFamilySpec,
+	families []*modelv1.TagFamilyForWrite, id string,
+) (string, error) {
+	var generated string
+	switch policy.GetSource() {
+	case databasev1.ElementIDPolicy_SOURCE_UNSPECIFIED:
+		return id, nil
+	case databasev1.ElementIDPolicy_SOURCE_CLIENT:
+		if id == "" {
+			return "", ErrEmptyElementID
+		}
+		return id, nil
+	case databasev1.ElementIDPolicy_SOURCE_UUID:
+		if id != "" && policy.GetConflict() == databasev1.ElementIDPolicy_CONFLICT_KEEP {
+			return id, nil
+		}
+		generated = uuid.NewString()
+	case databasev1.ElementIDPolicy_SOURCE_TAGS:
+		if id != "" && policy.GetConflict() == databasev1.ElementIDPolicy_CONFLICT_KEEP {
+			return id, nil
+		}
+		var err error
+		if generated, err = hashTags(policy.GetTagNames(), specs, families); err != nil {
+			return "", err
+		}
+	default:
+		return "", errors.Errorf("unsupported element id source %s", policy.GetSource())
+	}
+	if id != "" && id != generated && policy.GetConflict() == databasev1.ElementIDPolicy_CONFLICT_REJECT {
+		return "", errors.WithMessagef(ErrElementIDConflict, "provided %s", id)
+	}
+	return generated, nil
+}
+
+func hashTags(names []string, specs []*databasev1.TagFamilySpec, families []*modelv1.TagFamilyForWrite) (string, error) {
+	if len(names) < 1 {
+		return "", errors.Wrap(ErrMalformedElement, "no tag to generate the element id")
+	}
+	var buf []byte
+	for _, name := range names {
+		fi, ti, ok := findTag(specs, name)
+		if !ok {
+			return "", errors.Errorf("the tag %s of the element id policy is not defined", name)
+		}
+		if isAbsent(families, fi, ti) {
+			return "", errors.Wrapf(ErrMalformedElement, "the tag %s to generate the element id is absent", name)
+		}
+		val, err := MarshalIndexFieldValue(families[fi].GetTags()[ti])
+		if err != nil {
+			return "", errors.WithMessagef(err, "marshal the tag %s to generate the element id", name)
+		}
+		buf = append(buf, val...)
+		buf = append(buf, strDelimiter...)
+	}
+	return strconv.FormatUint(convert.Hash(buf), 16), nil
+}
@@ -0,0 +1,115 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+)
+
+func TestElementID(t *testing.T) {
+	specs := []*databasev1.TagFamilySpec{
+		{
+			Name: "default",
+			Tags: []*databasev1.TagSpec{
+				{Name: "trace_id", Type: databasev1.TagType_TAG_TYPE_STRING},
+				{Name: "span_id", Type: databasev1.TagType_TAG_TYPE_STRING},
+			},
+		},
+	}
+	families := func(traceID, spanID string) []*modelv1.TagFamilyForWrite {
+		return []*modelv1.TagFamilyForWrite{{Tags: []*modelv1.TagValue{str(traceID), str(spanID)}}}
+	}
+	byTags := func(conflict databasev1.ElementIDPolicy_Conflict) *databasev1.ElementIDPolicy {
+		return &databasev1.ElementIDPolicy{
+			Source:   databasev1.ElementIDPolicy_SOURCE_TAGS,
+			TagNames: []string{"trace_id", "span_id"},
+			Conflict: conflict,
+		}
+	}
+	hashed, err := pbv1.ElementID(byTags(databasev1.ElementIDPolicy_CONFLICT_UNSPECIFIED), specs, families("t1", "s1"), "")
+	require.NoError(t, err)
+	assert.NotEmpty(t, hashed)
+
+	tests := []struct {
+		name     string
+		policy   *databasev1.ElementIDPolicy
+		families []*modelv1.TagFamilyForWrite
+		id       string
+		want     string
+		wantErr  error
+	}{
+		{name: "unspecified", id: "1", want: "1"},
+		{name: "unspecified empty", want: ""},
+		{name: "client", policy: &databasev1.ElementIDPolicy{Source: databasev1.ElementIDPolicy_SOURCE_CLIENT}, id: "1", want: "1"},
+		{
+			name:    "client empty",
+			policy:  &databasev1.ElementIDPolicy{Source: databasev1.ElementIDPolicy_SOURCE_CLIENT},
+			wantErr: pbv1.ErrEmptyElementID,
+		},
+		{name: "tags", policy: byTags(databasev1.ElementIDPolicy_CONFLICT_UNSPECIFIED), families: families("t1", "s1"), want: hashed},
+		{name: "tags overwrite", policy: byTags(databasev1.ElementIDPolicy_CONFLICT_OVERWRITE), families: families("t1", "s1"), id: "1", want: hashed},
+		{name: "tags keep", policy: byTags(databasev1.ElementIDPolicy_CONFLICT_KEEP), families: families("t1", "s1"), id: "1", want: "1"},
+		{name: "tags keep empty", policy: byTags(databasev1.ElementIDPolicy_CONFLICT_KEEP), families: families("t1", "s1"), want: hashed},
+		{name: "tags reject the same", policy: byTags(databasev1.ElementIDPolicy_CONFLICT_REJECT), families: families("t1", "s1"), id: hashed, want: hashed},
+		{
+			name:     "tags reject",
+			policy:   byTags(databasev1.ElementIDPolicy_CONFLICT_REJECT),
+			families: families("t1", "s1"),
+			id:       "1",
+			wantErr:  pbv1.ErrElementIDConflict,
+		},
+		{
+			name:     "tags absent",
+			policy:   byTags(databasev1.ElementIDPolicy_CONFLICT_UNSPECIFIED),
+			families: []*modelv1.TagFamilyForWrite{{Tags: []*modelv1.TagValue{str("t1")}}},
+			wantErr:  pbv1.ErrMalformedElement,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pbv1.ElementID(tt.policy, specs, tt.families, tt.id)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	other, err := pbv1.ElementID(byTags(databasev1.ElementIDPolicy_CONFLICT_UNSPECIFIED), specs, families("t1", "s2"), "")
+	require.NoError(t, err)
+	assert.NotEqual(t, hashed, other)
+
+	policy := &databasev1.ElementIDPolicy{Source: databasev1.ElementIDPolicy_SOURCE_UUID}
+	id, err := pbv1.ElementID(policy, specs, families("t1", "s1"), "1")
+	require.NoError(t, err)
+	_, err = uuid.Parse(id)
+	assert.NoError(t, err)
+	policy.Conflict = databasev1.ElementIDPolicy_CONFLICT_REJECT
+	_, err = pbv1.ElementID(policy, specs, families("t1", "s1"), "1")
+	assert.ErrorIs(t, err, pbv1.ErrElementIDConflict)
+}
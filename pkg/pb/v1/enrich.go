@@ -0,0 +1,177 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+)
+
+// PropertyTagsGetter returns the tags of the property identified by the container and the id.
+// It returns nil if the property is absent.
+type PropertyTagsGetter func(container *commonv1.Metadata, id string) ([]*modelv1.Tag, error)
+
+// EnrichTagFamilies fills absent tags with the tags pulled from properties by the enrichment rules,
+// then with the default values of tag specs. A tag is absent if it is null or out of the range of its family.
+// The families passed in are left untouched.
+func EnrichTagFamilies(group string, specs []*databasev1.TagFamilySpec, enrichments []*databasev1.TagEnrichment,
+	families []*modelv1.TagFamilyForWrite, getter PropertyTagsGetter,
+) ([]*modelv1.TagFamilyForWrite, error) {
+	enriched, err := pullTags(group, specs, enrichments, families, getter)
+	if err != nil {
+		return nil, err
+	}
+	var result []*modelv1.TagFamilyForWrite
+	for fi, familySpec := range specs {
+		for ti, tagSpec := range familySpec.GetTags() {
+			if !isAbsent(families, fi, ti) {
+				continue
+			}
+			v, ok := enriched[tagSpec.GetName()]
+			if !ok {
+				v = tagSpec.GetDefaultValue()
+			}
+			if v == nil {
+				continue
+			}
+			if err := CheckTagValue(tagSpec, v); err != nil {
+				return nil, errors.Wrapf(ErrMalformedElement, "failed to fill the tag %s: %v", tagSpec.GetName(), err)
+			}
+			if result == nil {
+				result = make([]*modelv1.TagFamilyForWrite, len(families))
+				copy(result, families)
+			}
+			result = setTag(result, families, fi, ti, v)
+		}
+	}
+	if result == nil {
+		return families, nil
+	}
+	return result, nil
+}
+
+// CheckTagValue returns an error if the type of a non-null value doesn't match the tag spec.
+func CheckTagValue(spec *databasev1.TagSpec, v *modelv1.TagValue) error {
+	t, isNull := TagValueTypeConv(v)
+	if isNull {
+		return nil
+	}
+	if t != spec.GetType() {
+		return errors.Errorf("the type %s of the value doesn't match the tag type %s", t, spec.GetType())
+	}
+	return nil
+}
+
+func pullTags(group string, specs []*databasev1.TagFamilySpec, enrichments []*databasev1.TagEnrichment,
+	families []*modelv1.TagFamilyForWrite, getter PropertyTagsGetter,
+) (map[string]*modelv1.TagValue, error) {
+	if len(enrichments) < 1 || getter == nil {
+		return nil, nil
+	}
+	enriched := make(map[string]*modelv1.TagValue)
+	for _, e := range enrichments {
+		fi, ti, ok := findTag(specs, e.GetKeyTagName())
+		if !ok {
+			return nil, errors.Errorf("the key tag %s of enrichment is not defined", e.GetKeyTagName())
+		}
+		if isAbsent(families, fi, ti) {
+			continue
+		}
+		id, ok := propertyID(families[fi].GetTags()[ti])
+		if !ok {
+			return nil, errors.Wrapf(ErrMalformedElement, "the key tag %s of enrichment should be a string or an int", e.GetKeyTagName())
+		}
+		tags, err := getter(&commonv1.Metadata{Group: group, Name: e.GetContainer()}, id)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to pull tags from the property %s/%s", e.GetContainer(), id)
+		}
+		names := e.GetTagNames()
+		for _, t := range tags {
+			if len(names) > 0 && !contains(names, t.GetKey()) {
+				continue
+			}
+			if _, exist := enriched[t.GetKey()]; exist {
+				continue
+			}
+			enriched[t.GetKey()] = t.GetValue()
+		}
+	}
+	return enriched, nil
+}
+
+func setTag(result, origin []*modelv1.TagFamilyForWrite, fi, ti int, v *modelv1.TagValue) []*modelv1.TagFamilyForWrite {
+	for len(result) <= fi {
+		result = append(result, &modelv1.TagFamilyForWrite{})
+	}
+	// copy on write, the origin family is shared with the caller
+	if fi < len(origin) && result[fi] == origin[fi] {
+		tags := make([]*modelv1.TagValue, len(origin[fi].GetTags()))
+		copy(tags, origin[fi].GetTags())
+		result[fi] = &modelv1.TagFamilyForWrite{Tags: tags}
+	}
+	for len(result[fi].Tags) <= ti {
+		result[fi].Tags = append(result[fi].Tags, NullTag)
+	}
+	result[fi].Tags[ti] = v
+	return result
+}
+
+func isAbsent(families []*modelv1.TagFamilyForWrite, fi, ti int) bool {
+	if fi >= len(families) || ti >= len(families[fi].GetTags()) {
+		return true
+	}
+	_, isNull := families[fi].GetTags()[ti].GetValue().(*modelv1.TagValue_Null)
+	return isNull || families[fi].GetTags()[ti].GetValue() == nil
+}
+
+func findTag(specs []*databasev1.TagFamilySpec, name string) (int, int, bool) {
+	for fi, familySpec := range specs {
+		for ti, tagSpec := range familySpec.GetTags() {
+			if tagSpec.GetName() == name {
+				return fi, ti, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+func propertyID(tag *modelv1.TagValue) (string, bool) {
+	switch v := tag.GetValue().(type) {
+	case *modelv1.TagValue_Str:
+		return v.Str.GetValue(), true
+	case *modelv1.TagValue_Int:
+		return strconv.FormatInt(v.Int.GetValue(), 10), true
+	case *modelv1.TagValue_Id:
+		return v.Id.GetValue(), true
+	}
+	return "", false
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+)
+
+func str(v string) *modelv1.TagValue {
+	return &modelv1.TagValue{Value: &modelv1.TagValue_Str{Str: &modelv1.Str{Value: v}}}
+}
+
+func TestEnrichTagFamilies(t *testing.T) {
+	specs := []*databasev1.TagFamilySpec{
+		{
+			Name: "default",
+			Tags: []*databasev1.TagSpec{
+				{Name: "service_id", Type: databasev1.TagType_TAG_TYPE_STRING},
+				{Name: "layer", Type: databasev1.TagType_TAG_TYPE_STRING, DefaultValue: str("GENERAL")},
+			},
+		},
+		{
+			Name: "searchable",
+			Tags: []*databasev1.TagSpec{
+				{Name: "region", Type: databasev1.TagType_TAG_TYPE_STRING},
+				{Name: "zone", Type: databasev1.TagType_TAG_TYPE_STRING, DefaultValue: str("unknown")},
+			},
+		},
+	}
+	enrichments := []*databasev1.TagEnrichment{
+		{Container: "service", KeyTagName: "service_id", TagNames: []string{"region", "layer"}},
+	}
+	var requested []string
+	getter := func(container *commonv1.Metadata, id string) ([]*modelv1.Tag, error) {
+		requested = append(requested, container.GetGroup()+"/"+container.GetName()+"/"+id)
+		if id != "svc-1" {
+			return nil, nil
+		}
+		return []*modelv1.Tag{
+			{Key: "region", Value: str("us-east")},
+			{Key: "layer", Value: str("MESH")},
+			{Key: "zone", Value: str("a")},
+		}, nil
+	}
+	tests := []struct {
+		name     string
+		families []*modelv1.TagFamilyForWrite
+		want     []*modelv1.TagFamilyForWrite
+	}{
+		{
+			name: "enrich and fill default values",
+			families: []*modelv1.TagFamilyForWrite{
+				{Tags: []*modelv1.TagValue{str("svc-1")}},
+			},
+			want: []*modelv1.TagFamilyForWrite{
+				{Tags: []*modelv1.TagValue{str("svc-1"), str("MESH")}},
+				{Tags: []*modelv1.TagValue{str("us-east"), str("unknown")}},
+			},
+		},
+		{
+			name: "keep the present tags",
+			families: []*modelv1.TagFamilyForWrite{
+				{Tags: []*modelv1.TagValue{str("svc-1"), str("VIRTUAL")}},
+				{Tags: []*modelv1.TagValue{pbv1.NullTag, str("b")}},
+			},
+			want: []*modelv1.TagFamilyForWrite{
+				{Tags: []*modelv1.TagValue{str("svc-1"), str("VIRTUAL")}},
+				{Tags: []*modelv1.TagValue{str("us-east"), str("b")}},
+			},
+		},
+		{
+			name: "absent property",
+			families: []*modelv1.TagFamilyForWrite{
+				{Tags: []*modelv1.TagValue{str("svc-2")}},
+			},
+			want: []*modelv1.TagFamilyForWrite{
+				{Tags: []*modelv1.TagValue{str("svc-2"), str("GENERAL")}},
+				{Tags: []*modelv1.TagValue{pbv1.NullTag, str("unknown")}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := make([]*modelv1.TagFamilyForWrite, 0, len(tt.families))
+			for _, f := range tt.families {
+				input = append(input, proto.Clone(f).(*modelv1.TagFamilyForWrite))
+			}
+			got, err := pbv1.EnrichTagFamilies("sw", specs, enrichments, tt.families, getter)
+			require.NoError(t, err)
+			assert.Empty(t, cmp.Diff(tt.want, got, protocmp.Transform()))
+			assert.Empty(t, cmp.Diff(input, tt.families, protocmp.Transform()), "the input should be left untouched")
+		})
+	}
+	assert.Equal(t, []string{"sw/service/svc-1", "sw/service/svc-1", "sw/service/svc-2"}, requested)
+}
+
+func TestEnrichTagFamiliesUndefinedKey(t *testing.T) {
+	specs := []*databasev1.TagFamilySpec{{Name: "default", Tags: []*databasev1.TagSpec{{Name: "id", Type: databasev1.TagType_TAG_TYPE_STRING}}}}
+	_, err := pbv1.EnrichTagFamilies("sw", specs, []*databasev1.TagEnrichment{{Container: "c", KeyTagName: "service_id"}},
+		[]*modelv1.TagFamilyForWrite{{Tags: []*modelv1.TagValue{str("1")}}},
+		func(*commonv1.Metadata, string) ([]*modelv1.Tag, error) { return nil, nil })
+	require.Error(t, err)
+}
+
+func TestEnrichTagFamiliesTypeMismatch(t *testing.T) {
+	specs := []*databasev1.TagFamilySpec{{Name: "default", Tags: []*databasev1.TagSpec{
+		{Name: "service_id", Type: databasev1.TagType_TAG_TYPE_STRING},
+		{Name: "instances", Type: databasev1.TagType_TAG_TYPE_INT},
+	}}}
+	_, err := pbv1.EnrichTagFamilies("sw", specs, []*databasev1.TagEnrichment{{Container: "service", KeyTagName: "service_id"}},
+		[]*modelv1.TagFamilyForWrite{{Tags: []*modelv1.TagValue{str("svc-1")}}},
+		func(*commonv1.Metadata, string) ([]*modelv1.Tag, error) {
+			return []*modelv1.Tag{{Key: "instances", Value: str("3")}}, nil
+		})
+	require.ErrorIs(t, err, pbv1.ErrMalformedElement)
+}
@@ -0,0 +1,44 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/intern"
+)
+
+// InternTagFamilies replaces the string values of the tags with the ones interned by the table,
+// so that the strings decoded from a write aren't retained by the buffers of the storage.
+// The families are modified in place, which should be owned by the caller.
+func InternTagFamilies(table *intern.Table, families []*modelv1.TagFamilyForWrite) {
+	if table == nil {
+		return
+	}
+	for _, family := range families {
+		for _, tag := range family.GetTags() {
+			switch v := tag.GetValue().(type) {
+			case *modelv1.TagValue_Str:
+				v.Str.Value = table.String(v.Str.GetValue())
+			case *modelv1.TagValue_StrArray:
+				for i, s := range v.StrArray.GetValue() {
+					v.StrArray.Value[i] = table.String(s)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/intern"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+)
+
+func TestInternTagFamilies(t *testing.T) {
+	table := intern.New("test", 1024)
+	families := func() []*modelv1.TagFamilyForWrite {
+		return []*modelv1.TagFamilyForWrite{{Tags: []*modelv1.TagValue{
+			str(strings.Repeat("svc", 1)),
+			{Value: &modelv1.TagValue_StrArray{StrArray: &modelv1.StrArray{Value: []string{"a", "svc"}}}},
+			{Value: &modelv1.TagValue_Int{Int: &modelv1.Int{Value: 1}}},
+			pbv1.NullTag,
+		}}}
+	}
+	written := families()
+	pbv1.InternTagFamilies(table, written)
+	pbv1.InternTagFamilies(table, families())
+	assert.Equal(t, families(), written)
+	assert.Equal(t, 2, table.Len(), "the repeated strings are interned once")
+
+	// a nil table leaves the families untouched
+	pbv1.InternTagFamilies(nil, written)
+	assert.Equal(t, families(), written)
+}
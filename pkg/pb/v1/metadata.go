@@ -19,6 +19,8 @@ package v1
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
 	common_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
 	database_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
@@ -89,9 +91,38 @@ func ToIntervalRule(ir *common_v1.IntervalRule) (result tsdb.IntervalRule, err e
 		result.Unit = tsdb.DAY
 	case common_v1.IntervalRule_UNIT_HOUR:
 		result.Unit = tsdb.HOUR
+	case common_v1.IntervalRule_UNIT_MONTH:
+		result.Unit = tsdb.MONTH
 	default:
 		return result, ErrInvalidUnit
 	}
 	result.Num = int(ir.Num)
 	return result, err
 }
+
+// ApplyFlushOpts overrides the flush options of opts, which are set by the flags of the node, by the ones of a group.
+func ApplyFlushOpts(opts tsdb.DatabaseOpts, flush *common_v1.FlushOpts) tsdb.DatabaseOpts {
+	if flush.GetMemtableSize() > 0 {
+		opts.BlockMemSize = flush.GetMemtableSize()
+	}
+	if flush.GetMaxBufferedElements() > 0 {
+		opts.MaxBufferedElements = flush.GetMaxBufferedElements()
+	}
+	if flush.GetInterval().AsDuration() > 0 {
+		opts.FlushInterval = flush.GetInterval().AsDuration()
+	}
+	return opts
+}
+
+// ApplyTimeZone overrides the time zone of opts, which is set by the flag of the node, by the one of a group.
+func ApplyTimeZone(opts tsdb.DatabaseOpts, zone string) (tsdb.DatabaseOpts, error) {
+	if zone == "" {
+		return opts, nil
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return opts, fmt.Errorf("invalid time zone %q: %w", zone, err)
+	}
+	opts.TimeZone = loc
+	return opts, nil
+}
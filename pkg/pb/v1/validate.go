@@ -0,0 +1,74 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"fmt"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+)
+
+// ValidateTagFamilies checks the arity and the types of the tag families against the schema, and all the tags of the entity are required.
+// It returns nil if the families are valid. The null tags match any type, and the absent tags at the end of a family are taken as null.
+func ValidateTagFamilies(specs []*databasev1.TagFamilySpec, entity *databasev1.Entity, families []*modelv1.TagFamilyForWrite) *modelv1.WriteError {
+	if len(families) > len(specs) {
+		return writeError(modelv1.WriteError_CODE_TAG_FAMILY_COUNT, "%d tag families are more than %d", len(families), len(specs))
+	}
+	for fi, family := range families {
+		spec := specs[fi]
+		if len(family.GetTags()) > len(spec.GetTags()) {
+			return writeError(modelv1.WriteError_CODE_TAG_COUNT, "%d tags of the family %s are more than %d",
+				len(family.GetTags()), spec.GetName(), len(spec.GetTags()))
+		}
+		for ti, tag := range family.GetTags() {
+			tagSpec := spec.GetTags()[ti]
+			if tType, isNull := TagValueTypeConv(tag); !isNull && tType != tagSpec.GetType() {
+				return writeError(modelv1.WriteError_CODE_TAG_TYPE, "the tag %s expects %s but gets %s", tagSpec.GetName(), tagSpec.GetType(), tType)
+			}
+		}
+	}
+	for _, name := range entity.GetTagNames() {
+		fi, ti, tag := FindTagByName(specs, name)
+		if tag == nil {
+			// the schema is validated by the registry, so it's not expected
+			continue
+		}
+		if fi >= len(families) || ti >= len(families[fi].GetTags()) {
+			return writeError(modelv1.WriteError_CODE_ENTITY_INCOMPLETE, "the tag %s of the entity is absent", name)
+		}
+	}
+	return nil
+}
+
+// ValidateFields checks the arity and the types of the fields against the schema. It returns nil if the fields are valid.
+func ValidateFields(specs []*databasev1.FieldSpec, fields []*modelv1.FieldValue) *modelv1.WriteError {
+	if len(fields) > len(specs) {
+		return writeError(modelv1.WriteError_CODE_FIELD_COUNT, "%d fields are more than %d", len(fields), len(specs))
+	}
+	for i, field := range fields {
+		if fType, isNull := FieldValueTypeConv(field); !isNull && fType != specs[i].GetFieldType() {
+			return writeError(modelv1.WriteError_CODE_FIELD_TYPE, "the field %s expects %s but gets %s", specs[i].GetName(), specs[i].GetFieldType(), fType)
+		}
+	}
+	return nil
+}
+
+func writeError(code modelv1.WriteError_Code, format string, args ...interface{}) *modelv1.WriteError {
+	return &modelv1.WriteError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
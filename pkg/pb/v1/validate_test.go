@@ -0,0 +1,88 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+)
+
+func TestValidateTagFamilies(t *testing.T) {
+	specs := []*databasev1.TagFamilySpec{
+		{
+			Name: "default",
+			Tags: []*databasev1.TagSpec{
+				{Name: "service_id", Type: databasev1.TagType_TAG_TYPE_STRING},
+				{Name: "duration", Type: databasev1.TagType_TAG_TYPE_INT},
+			},
+		},
+	}
+	entity := &databasev1.Entity{TagNames: []string{"service_id"}}
+	intTag := &modelv1.TagValue{Value: &modelv1.TagValue_Int{Int: &modelv1.Int{Value: 1}}}
+	tests := []struct {
+		name     string
+		families []*modelv1.TagFamilyForWrite
+		code     modelv1.WriteError_Code
+	}{
+		{name: "valid", families: []*modelv1.TagFamilyForWrite{{Tags: []*modelv1.TagValue{str("svc"), intTag}}}},
+		{name: "null and absent tags", families: []*modelv1.TagFamilyForWrite{{Tags: []*modelv1.TagValue{pbv1.NullTag}}}},
+		{
+			name:     "more families",
+			families: []*modelv1.TagFamilyForWrite{{Tags: []*modelv1.TagValue{str("svc")}}, {}},
+			code:     modelv1.WriteError_CODE_TAG_FAMILY_COUNT,
+		},
+		{
+			name:     "more tags",
+			families: []*modelv1.TagFamilyForWrite{{Tags: []*modelv1.TagValue{str("svc"), intTag, intTag}}},
+			code:     modelv1.WriteError_CODE_TAG_COUNT,
+		},
+		{
+			name:     "mismatched type",
+			families: []*modelv1.TagFamilyForWrite{{Tags: []*modelv1.TagValue{str("svc"), str("1")}}},
+			code:     modelv1.WriteError_CODE_TAG_TYPE,
+		},
+		{name: "absent entity", code: modelv1.WriteError_CODE_ENTITY_INCOMPLETE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := pbv1.ValidateTagFamilies(specs, entity, tt.families)
+			if tt.code == modelv1.WriteError_CODE_UNSPECIFIED {
+				assert.Nil(t, err)
+				return
+			}
+			assert.Equal(t, tt.code, err.GetCode())
+			assert.NotEmpty(t, err.GetMessage())
+		})
+	}
+}
+
+func TestValidateFields(t *testing.T) {
+	specs := []*databasev1.FieldSpec{{Name: "total", FieldType: databasev1.FieldType_FIELD_TYPE_INT}}
+	intField := &modelv1.FieldValue{Value: &modelv1.FieldValue_Int{Int: &modelv1.Int{Value: 1}}}
+	strField := &modelv1.FieldValue{Value: &modelv1.FieldValue_Str{Str: &modelv1.Str{Value: "1"}}}
+
+	assert.Nil(t, pbv1.ValidateFields(specs, []*modelv1.FieldValue{intField}))
+	assert.Nil(t, pbv1.ValidateFields(specs, []*modelv1.FieldValue{pbv1.NullFieldValue}))
+	assert.Equal(t, modelv1.WriteError_CODE_FIELD_COUNT, pbv1.ValidateFields(specs, []*modelv1.FieldValue{intField, intField}).GetCode())
+	assert.Equal(t, modelv1.WriteError_CODE_FIELD_TYPE, pbv1.ValidateFields(specs, []*modelv1.FieldValue{strField}).GetCode())
+}
@@ -38,9 +38,10 @@ type ID string
 const fieldFlagLength = 9
 
 var (
-	strDelimiter = []byte("\n")
-	NullTag      = &modelv1.TagValue{Value: &modelv1.TagValue_Null{}}
-	TagFlag      = make([]byte, fieldFlagLength)
+	strDelimiter   = []byte("\n")
+	NullTag        = &modelv1.TagValue{Value: &modelv1.TagValue_Null{}}
+	NullFieldValue = &modelv1.FieldValue{Value: &modelv1.FieldValue_Null{}}
+	TagFlag        = make([]byte, fieldFlagLength)
 
 	ErrUnsupportedTagForIndexField = errors.New("the tag type(for example, null) can not be as the index field value")
 	ErrNullValue                   = errors.New("the tag value is null")
@@ -376,9 +377,38 @@ func DecodeFieldValue(fieldValue []byte, fieldSpec *databasev1.FieldSpec) *model
 	return &modelv1.FieldValue{Value: &modelv1.FieldValue_Null{}}
 }
 
+// FieldEncodingMethod returns the encoding method of a field.
+// The unspecified one is chosen by the field type, and the binary data is not encoded.
+func FieldEncodingMethod(fieldSpec *databasev1.FieldSpec) databasev1.EncodingMethod {
+	if m := fieldSpec.GetEncodingMethod(); m != databasev1.EncodingMethod_ENCODING_METHOD_UNSPECIFIED {
+		return m
+	}
+	switch fieldSpec.GetFieldType() {
+	case databasev1.FieldType_FIELD_TYPE_INT:
+		return databasev1.EncodingMethod_ENCODING_METHOD_DELTA
+	case databasev1.FieldType_FIELD_TYPE_STRING:
+		return databasev1.EncodingMethod_ENCODING_METHOD_DICTIONARY
+	}
+	return databasev1.EncodingMethod_ENCODING_METHOD_UNSPECIFIED
+}
+
+// EncoderFieldFlag returns the flag of a field, which carries the encoding method, the compression method and the interval.
 func EncoderFieldFlag(fieldSpec *databasev1.FieldSpec, interval time.Duration) []byte {
-	encodingMethod := byte(fieldSpec.GetEncodingMethod().Number())
-	compressionMethod := byte(fieldSpec.GetCompressionMethod().Number())
+	return encodeFieldFlag(FieldEncodingMethod(fieldSpec), fieldSpec.GetCompressionMethod(), interval)
+}
+
+// LegacyFieldFlag returns the flag of a field written before its encoding method was chosen by the field type.
+// It's nil if the flag is the same as the one of EncoderFieldFlag.
+func LegacyFieldFlag(fieldSpec *databasev1.FieldSpec, interval time.Duration) []byte {
+	if FieldEncodingMethod(fieldSpec) == fieldSpec.GetEncodingMethod() {
+		return nil
+	}
+	return encodeFieldFlag(fieldSpec.GetEncodingMethod(), fieldSpec.GetCompressionMethod(), interval)
+}
+
+func encodeFieldFlag(encoding databasev1.EncodingMethod, compression databasev1.CompressionMethod, interval time.Duration) []byte {
+	encodingMethod := byte(encoding.Number())
+	compressionMethod := byte(compression.Number())
 	bb := make([]byte, fieldFlagLength)
 	bb[0] = encodingMethod<<4 | compressionMethod
 	copy(bb[1:], convert.Int64ToBytes(int64(interval)))
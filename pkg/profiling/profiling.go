@@ -0,0 +1,181 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package profiling attributes the CPU time to the modules and the queries by the pprof labels,
+// and captures the CPU profiles scoped to the labels, so that the hotspots can be attributed to specific workloads.
+package profiling
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"runtime/pprof"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	// LabelModule is the label of the module running the goroutine, e.g. query, stream or measure
+	LabelModule = "module"
+	// LabelFingerprint is the label of the fingerprint of the query, which shares it with the top query list
+	LabelFingerprint = "fingerprint"
+)
+
+// The field numbers of the messages in the profile.proto of pprof.
+const (
+	profileSampleField      = 2
+	profileStringTableField = 6
+	sampleLabelField        = 3
+	labelKeyField           = 1
+	labelStrField           = 2
+)
+
+// ErrCapturing is returned if another CPU profile is being captured, since the process has only one CPU profiler.
+var ErrCapturing = errors.New("a CPU profile is being captured")
+
+// capturing is the number of the CPU profiles being captured.
+var capturing atomic.Int32
+
+// Capturing tells whether a CPU profile is being captured, so that the costly labels are computed only if they're sampled.
+func Capturing() bool {
+	return capturing.Load() > 0
+}
+
+// Label sets the labels of the key-value pairs on the current goroutine, and returns the func removing them.
+// The goroutines started afterwards inherit the labels, e.g. the ones fanning out the scans of a query.
+func Label(kv ...string) (reset func()) {
+	pprof.SetGoroutineLabels(pprof.WithLabels(context.Background(), pprof.Labels(kv...)))
+	return func() {
+		pprof.SetGoroutineLabels(context.Background())
+	}
+}
+
+// CaptureCPU profiles the CPU for the duration or until ctx is done, and writes the profile to w in the gzipped
+// protobuf format of pprof. Only the samples carrying all the labels are kept, all the samples are kept if there's no label.
+func CaptureCPU(ctx context.Context, w io.Writer, duration time.Duration, labels map[string]string) error {
+	var buf bytes.Buffer
+	if err := pprof.StartCPUProfile(&buf); err != nil {
+		return errors.WithMessage(ErrCapturing, err.Error())
+	}
+	capturing.Add(1)
+	timer := time.NewTimer(duration)
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+	timer.Stop()
+	pprof.StopCPUProfile()
+	capturing.Add(-1)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(labels) == 0 {
+		_, err := w.Write(buf.Bytes())
+		return err
+	}
+	return filter(&buf, w, labels)
+}
+
+// filter copies the gzipped profile from r to w without the samples lacking any of the labels.
+func filter(r io.Reader, w io.Writer, labels map[string]string) error {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return errors.Wrap(err, "failed to decompress the profile")
+	}
+	profile, err := io.ReadAll(zr)
+	if err != nil {
+		return errors.Wrap(err, "failed to decompress the profile")
+	}
+	// the samples refer to the strings by their indexes, and the string table might follow them
+	var table []string
+	if err = rangeFields(profile, func(num protowire.Number, _ protowire.Type, value, _ []byte) {
+		if num == profileStringTableField {
+			table = append(table, string(value))
+		}
+	}); err != nil {
+		return err
+	}
+	var filtered []byte
+	if err = rangeFields(profile, func(num protowire.Number, _ protowire.Type, value, raw []byte) {
+		if num != profileSampleField || matches(value, table, labels) {
+			filtered = append(filtered, raw...)
+		}
+	}); err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(w)
+	if _, err = zw.Write(filtered); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// matches tells whether the sample carries all the labels.
+func matches(sample []byte, table []string, labels map[string]string) bool {
+	matched := 0
+	_ = rangeFields(sample, func(num protowire.Number, _ protowire.Type, value, _ []byte) {
+		if num != sampleLabelField {
+			return
+		}
+		var key, str uint64
+		_ = rangeFields(value, func(num protowire.Number, typ protowire.Type, value, _ []byte) {
+			if typ != protowire.VarintType {
+				return
+			}
+			v, _ := protowire.ConsumeVarint(value)
+			switch num {
+			case labelKeyField:
+				key = v
+			case labelStrField:
+				str = v
+			}
+		})
+		if key >= uint64(len(table)) || str >= uint64(len(table)) {
+			return
+		}
+		if expected, ok := labels[table[key]]; ok && expected == table[str] {
+			matched++
+		}
+	})
+	return matched == len(labels)
+}
+
+// rangeFields calls f with the number, the type, the value and the raw bytes of every field of the message in order.
+// The value of a length-delimited field is its content, and the one of a varint field is its encoding.
+func rangeFields(msg []byte, f func(num protowire.Number, typ protowire.Type, value, raw []byte)) error {
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		if n < 0 {
+			return errors.Wrap(protowire.ParseError(n), "failed to parse the profile")
+		}
+		m := protowire.ConsumeFieldValue(num, typ, msg[n:])
+		if m < 0 {
+			return errors.Wrap(protowire.ParseError(m), "failed to parse the profile")
+		}
+		value := msg[n : n+m]
+		if typ == protowire.BytesType {
+			value, _ = protowire.ConsumeBytes(value)
+		}
+		f(num, typ, value, msg[:n+m])
+		msg = msg[n+m:]
+	}
+	return nil
+}
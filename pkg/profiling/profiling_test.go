@@ -0,0 +1,159 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package profiling_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/apache/skywalking-banyandb/pkg/profiling"
+)
+
+// sampleLabels decodes the labels of the samples of a gzipped profile.
+func sampleLabels(t *testing.T, profile []byte) []map[string]string {
+	zr, err := gzip.NewReader(bytes.NewReader(profile))
+	require.NoError(t, err)
+	raw, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	type label struct{ key, str uint64 }
+	var table []string
+	var samples [][]label
+	fields := func(msg []byte, f func(num protowire.Number, value []byte, varint uint64)) {
+		for len(msg) > 0 {
+			num, typ, n := protowire.ConsumeTag(msg)
+			require.Positive(t, n)
+			m := protowire.ConsumeFieldValue(num, typ, msg[n:])
+			require.Positive(t, m)
+			switch typ {
+			case protowire.BytesType:
+				v, _ := protowire.ConsumeBytes(msg[n:])
+				f(num, v, 0)
+			case protowire.VarintType:
+				v, _ := protowire.ConsumeVarint(msg[n:])
+				f(num, nil, v)
+			}
+			msg = msg[n+m:]
+		}
+	}
+	fields(raw, func(num protowire.Number, value []byte, _ uint64) {
+		switch num {
+		case 2:
+			var labels []label
+			fields(value, func(num protowire.Number, value []byte, _ uint64) {
+				if num != 3 {
+					return
+				}
+				var l label
+				fields(value, func(num protowire.Number, _ []byte, varint uint64) {
+					switch num {
+					case 1:
+						l.key = varint
+					case 2:
+						l.str = varint
+					}
+				})
+				labels = append(labels, l)
+			})
+			samples = append(samples, labels)
+		case 6:
+			table = append(table, string(value))
+		}
+	})
+	result := make([]map[string]string, 0, len(samples))
+	for _, labels := range samples {
+		m := make(map[string]string)
+		for _, l := range labels {
+			m[table[l.key]] = table[l.str]
+		}
+		result = append(result, m)
+	}
+	return result
+}
+
+// spin burns the CPU with the labels until stop is closed.
+func spin(wg *sync.WaitGroup, stop chan struct{}, kv ...string) {
+	defer wg.Done()
+	if len(kv) > 0 {
+		defer profiling.Label(kv...)()
+	}
+	x := 0
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		for i := 0; i < 1000; i++ {
+			x ^= i * i
+		}
+		_ = x
+	}
+}
+
+func TestCaptureCPU(t *testing.T) {
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go spin(&wg, stop, profiling.LabelModule, "query", profiling.LabelFingerprint, "abc")
+	go spin(&wg, stop, profiling.LabelModule, "stream")
+	go spin(&wg, stop)
+	defer func() {
+		close(stop)
+		wg.Wait()
+	}()
+
+	var buf bytes.Buffer
+	assert.False(t, profiling.Capturing())
+	require.NoError(t, profiling.CaptureCPU(context.Background(), &buf, time.Second,
+		map[string]string{profiling.LabelModule: "query", profiling.LabelFingerprint: "abc"}))
+	assert.False(t, profiling.Capturing())
+	samples := sampleLabels(t, buf.Bytes())
+	require.NotEmpty(t, samples)
+	for _, labels := range samples {
+		assert.Equal(t, map[string]string{profiling.LabelModule: "query", profiling.LabelFingerprint: "abc"}, labels)
+	}
+
+	buf.Reset()
+	require.NoError(t, profiling.CaptureCPU(context.Background(), &buf, time.Second, nil))
+	modules := make(map[string]bool)
+	for _, labels := range sampleLabels(t, buf.Bytes()) {
+		modules[labels[profiling.LabelModule]] = true
+	}
+	assert.True(t, modules["query"] && modules["stream"] && modules[""], "all the samples are kept without the labels")
+}
+
+func TestCaptureCPUConcurrently(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- profiling.CaptureCPU(ctx, io.Discard, time.Minute, nil)
+	}()
+	require.Eventually(t, profiling.Capturing, time.Second, 10*time.Millisecond)
+	assert.ErrorIs(t, profiling.CaptureCPU(context.Background(), io.Discard, time.Second, nil), profiling.ErrCapturing)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
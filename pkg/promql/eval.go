@@ -0,0 +1,244 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package promql
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// MaxSteps is the maximum number of the steps of a range, which is the same as Prometheus.
+const MaxSteps = 11000
+
+// ErrRange is returned if the range of an evaluation is invalid.
+var ErrRange = errors.New("invalid range")
+
+// Sample is a value at a timestamp in milliseconds.
+type Sample struct {
+	T int64
+	V float64
+}
+
+// Series is the samples of a set of labels, which includes the metric name labeled by __name__.
+type Series struct {
+	Labels  map[string]string
+	Samples []Sample
+}
+
+// Fetcher returns the series selected by a selector whose samples are between start and end in milliseconds.
+// The series may not match all the matchers of the selector, which are filtered by the evaluation.
+type Fetcher func(s *VectorSelector, start, end int64) ([]Series, error)
+
+// Range is the steps of an evaluation in milliseconds. The instant evaluations have the same start and end.
+type Range struct {
+	Start    int64
+	End      int64
+	Step     int64
+	Lookback int64
+}
+
+func (r Range) steps() int64 {
+	return (r.End-r.Start)/r.Step + 1
+}
+
+// Eval evaluates the expression at the steps of the range, and returns the series sorted by their labels.
+// A series has a sample at a step if any of its selected samples is in the lookback delta before the step.
+func Eval(e Expr, r Range, fetch Fetcher) ([]Series, error) {
+	if r.End < r.Start {
+		return nil, errors.WithMessage(ErrRange, "the end is before the start")
+	}
+	if r.Step <= 0 {
+		return nil, errors.WithMessage(ErrRange, "the step should be positive")
+	}
+	if r.steps() > MaxSteps {
+		return nil, errors.WithMessagef(ErrRange, "the steps exceed the maximum %d", MaxSteps)
+	}
+	result, err := eval(e, r, fetch)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return labelsKey(result[i].Labels) < labelsKey(result[j].Labels)
+	})
+	return result, nil
+}
+
+func eval(e Expr, r Range, fetch Fetcher) ([]Series, error) {
+	switch e := e.(type) {
+	case *VectorSelector:
+		return evalSelector(e, r, fetch)
+	case *AggregateExpr:
+		series, err := eval(e.Expr, r, fetch)
+		if err != nil {
+			return nil, err
+		}
+		return aggregate(e, r, series), nil
+	}
+	return nil, errors.WithMessagef(ErrSyntax, "unsupported expression %s", e)
+}
+
+func evalSelector(s *VectorSelector, r Range, fetch Fetcher) ([]Series, error) {
+	fetched, err := fetch(s, r.Start-r.Lookback, r.End)
+	if err != nil {
+		return nil, err
+	}
+	// merges the samples of the same labels
+	merged := make(map[string]*Series)
+	for _, series := range fetched {
+		if !matches(s.Matchers, series.Labels) {
+			continue
+		}
+		k := labelsKey(series.Labels)
+		if m, ok := merged[k]; ok {
+			m.Samples = append(m.Samples, series.Samples...)
+			continue
+		}
+		merged[k] = &Series{Labels: series.Labels, Samples: append([]Sample(nil), series.Samples...)}
+	}
+	result := make([]Series, 0, len(merged))
+	for _, series := range merged {
+		samples := series.Samples
+		sort.SliceStable(samples, func(i, j int) bool {
+			return samples[i].T < samples[j].T
+		})
+		var stepped []Sample
+		j := -1
+		for t := r.Start; t <= r.End; t += r.Step {
+			for j+1 < len(samples) && samples[j+1].T <= t {
+				j++
+			}
+			if j >= 0 && samples[j].T > t-r.Lookback {
+				stepped = append(stepped, Sample{T: t, V: samples[j].V})
+			}
+		}
+		if len(stepped) > 0 {
+			result = append(result, Series{Labels: series.Labels, Samples: stepped})
+		}
+	}
+	return result, nil
+}
+
+func matches(matchers []*Matcher, labels map[string]string) bool {
+	for _, m := range matchers {
+		if !m.Matches(labels[m.Name]) {
+			return false
+		}
+	}
+	return true
+}
+
+type accumulator struct {
+	sum   float64
+	min   float64
+	max   float64
+	count float64
+}
+
+func (a *accumulator) add(v float64) {
+	if a.count == 0 {
+		a.min, a.max = v, v
+	}
+	a.sum += v
+	a.min = math.Min(a.min, v)
+	a.max = math.Max(a.max, v)
+	a.count++
+}
+
+func (a *accumulator) value(op string) float64 {
+	switch op {
+	case "sum":
+		return a.sum
+	case "avg":
+		return a.sum / a.count
+	case "min":
+		return a.min
+	case "max":
+		return a.max
+	}
+	return a.count
+}
+
+func aggregate(a *AggregateExpr, r Range, series []Series) []Series {
+	type group struct {
+		labels map[string]string
+		steps  []*accumulator
+	}
+	groups := make(map[string]*group)
+	for _, s := range series {
+		labels := groupLabels(a, s.Labels)
+		k := labelsKey(labels)
+		g, ok := groups[k]
+		if !ok {
+			g = &group{labels: labels, steps: make([]*accumulator, r.steps())}
+			groups[k] = g
+		}
+		for _, sample := range s.Samples {
+			i := (sample.T - r.Start) / r.Step
+			if g.steps[i] == nil {
+				g.steps[i] = &accumulator{}
+			}
+			g.steps[i].add(sample.V)
+		}
+	}
+	result := make([]Series, 0, len(groups))
+	for _, g := range groups {
+		s := Series{Labels: g.labels}
+		for i, acc := range g.steps {
+			if acc != nil {
+				s.Samples = append(s.Samples, Sample{T: r.Start + int64(i)*r.Step, V: acc.value(a.Op)})
+			}
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+// groupLabels returns the labels of the group of a series, which never includes the metric name.
+func groupLabels(a *AggregateExpr, labels map[string]string) map[string]string {
+	grouping := make(map[string]bool, len(a.Grouping))
+	for _, l := range a.Grouping {
+		grouping[l] = true
+	}
+	result := make(map[string]string)
+	for k, v := range labels {
+		if k == MetricNameLabel || grouping[k] == a.Without {
+			continue
+		}
+		result[k] = v
+	}
+	return result
+}
+
+func labelsKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(labels[k])
+		b.WriteByte(0xff)
+	}
+	return b.String()
+}
@@ -0,0 +1,422 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package promql implements a subset of PromQL, which selects the series by their metric names and label matchers,
+// and aggregates them by the operators sum, avg, min, max and count, e.g. `sum by (service) (service_cpm{layer="GENERAL"})`.
+//
+// The expressions are evaluated on the samples of the selected series at the steps of a range, and a sample is looked back
+// for a lookback delta if there's none at a step. Neither the range vectors nor the functions and the binary operators are supported.
+package promql
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// MetricNameLabel is the label of the metric name of a series.
+const MetricNameLabel = "__name__"
+
+// ErrSyntax is returned if an expression is invalid or unsupported.
+var ErrSyntax = errors.New("syntax error")
+
+// MatchType is the operator of a label matcher.
+type MatchType string
+
+// The operators of the label matchers.
+const (
+	MatchEqual     MatchType = "="
+	MatchNotEqual  MatchType = "!="
+	MatchRegexp    MatchType = "=~"
+	MatchNotRegexp MatchType = "!~"
+)
+
+// Matcher matches the value of a label.
+type Matcher struct {
+	re    *regexp.Regexp
+	Name  string
+	Type  MatchType
+	Value string
+}
+
+// NewMatcher returns the matcher, whose regular expression is anchored at both ends as PromQL does.
+func NewMatcher(t MatchType, name, value string) (*Matcher, error) {
+	m := &Matcher{Type: t, Name: name, Value: value}
+	if t == MatchRegexp || t == MatchNotRegexp {
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return nil, errors.WithMessagef(ErrSyntax, "invalid regular expression %q: %v", value, err)
+		}
+		m.re = re
+	}
+	return m, nil
+}
+
+// Matches reports whether the value of the label matches. An absent label has the empty value.
+func (m *Matcher) Matches(value string) bool {
+	switch m.Type {
+	case MatchEqual:
+		return value == m.Value
+	case MatchNotEqual:
+		return value != m.Value
+	case MatchRegexp:
+		return m.re.MatchString(value)
+	case MatchNotRegexp:
+		return !m.re.MatchString(value)
+	}
+	return false
+}
+
+func (m *Matcher) String() string {
+	return fmt.Sprintf("%s%s%q", m.Name, m.Type, m.Value)
+}
+
+// Expr is a parsed expression, which is either a *VectorSelector or an *AggregateExpr.
+type Expr interface {
+	fmt.Stringer
+	expr()
+}
+
+// VectorSelector selects the series of a metric whose labels match all the matchers.
+type VectorSelector struct {
+	Name string
+	// Matchers don't include the one of the metric name
+	Matchers []*Matcher
+}
+
+func (*VectorSelector) expr() {}
+
+func (s *VectorSelector) String() string {
+	matchers := make([]string, 0, len(s.Matchers))
+	for _, m := range s.Matchers {
+		matchers = append(matchers, m.String())
+	}
+	if len(matchers) == 0 {
+		return s.Name
+	}
+	return s.Name + "{" + strings.Join(matchers, ",") + "}"
+}
+
+// AggregateExpr aggregates the series of an expression into the groups of the labels, which are
+// the Grouping labels, or all the labels except the Grouping ones and the metric name if Without is set.
+type AggregateExpr struct {
+	Expr     Expr
+	Op       string
+	Grouping []string
+	Without  bool
+}
+
+func (*AggregateExpr) expr() {}
+
+func (a *AggregateExpr) String() string {
+	if len(a.Grouping) == 0 && !a.Without {
+		return fmt.Sprintf("%s(%s)", a.Op, a.Expr)
+	}
+	modifier := "by"
+	if a.Without {
+		modifier = "without"
+	}
+	return fmt.Sprintf("%s %s (%s) (%s)", a.Op, modifier, strings.Join(a.Grouping, ", "), a.Expr)
+}
+
+var aggregators = map[string]bool{"sum": true, "avg": true, "min": true, "max": true, "count": true}
+
+// Parse parses the expression.
+func Parse(source string) (Expr, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	e, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, errors.WithMessagef(ErrSyntax, "unexpected %q at %d", t.text, t.pos)
+	}
+	return e, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenOp
+)
+
+type token struct {
+	text  string
+	value string
+	kind  tokenKind
+	pos   int
+}
+
+// the longer operators go first to be matched greedily
+var operators = []string{"=~", "!~", "!=", "=", "(", ")", "{", "}", "[", "]", ","}
+
+func lex(source string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isLetter(c) || c == ':':
+			j := i + 1
+			for j < len(source) && (isLetter(source[j]) || isDigit(source[j]) || source[j] == ':') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: source[i:j], pos: i})
+			i = j
+		case c == '"' || c == '\'' || c == '`':
+			s, n, err := lexString(source[i:])
+			if err != nil {
+				return nil, errors.WithMessagef(ErrSyntax, "%v at %d", err, i)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: source[i : i+n], pos: i, value: s})
+			i += n
+		default:
+			var op string
+			for _, o := range operators {
+				if strings.HasPrefix(source[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, errors.WithMessagef(ErrSyntax, "unexpected %q at %d", c, i)
+			}
+			tokens = append(tokens, token{kind: tokenOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(source)}), nil
+}
+
+// lexString returns the unquoted string and the length of the quoted one. The backquoted strings aren't escaped.
+func lexString(s string) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == quote:
+			return b.String(), i + 1, nil
+		case c == '\\' && quote != '`':
+			if i+1 >= len(s) {
+				return "", 0, errors.New("unterminated string")
+			}
+			i++
+			switch e := s[i]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case '\\', '"', '\'':
+				b.WriteByte(e)
+			default:
+				// keeps the escapes of the regular expressions, e.g. \d
+				b.WriteByte('\\')
+				b.WriteByte(e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, errors.New("unterminated string")
+}
+
+func isLetter(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokenOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		t := p.peek()
+		return errors.WithMessagef(ErrSyntax, "expect %q but got %q at %d", op, t.text, t.pos)
+	}
+	return nil
+}
+
+func (p *parser) parseExpr() (Expr, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokenOp && t.text == "(":
+		p.next()
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		return e, p.expect(")")
+	case t.kind == tokenOp && t.text == "{":
+		return p.parseSelector("")
+	case t.kind == tokenIdent && aggregators[t.text]:
+		p.next()
+		return p.parseAggregate(t.text)
+	case t.kind == tokenIdent:
+		p.next()
+		if p.peek().kind == tokenOp && p.peek().text == "(" {
+			return nil, errors.WithMessagef(ErrSyntax, "unsupported function %s at %d", t.text, t.pos)
+		}
+		return p.parseSelector(t.text)
+	case t.kind == tokenEOF:
+		return nil, errors.WithMessage(ErrSyntax, "unexpected end of the expression")
+	}
+	return nil, errors.WithMessagef(ErrSyntax, "unexpected %q at %d", t.text, t.pos)
+}
+
+// parseAggregate parses the aggregation, whose modifier is either before or after the parenthesized expression.
+func (p *parser) parseAggregate(op string) (Expr, error) {
+	a := &AggregateExpr{Op: op}
+	modified, err := p.parseModifier(a)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect("("); err != nil {
+		return nil, err
+	}
+	if a.Expr, err = p.parseExpr(); err != nil {
+		return nil, err
+	}
+	if err = p.expect(")"); err != nil {
+		return nil, err
+	}
+	if !modified {
+		if _, err = p.parseModifier(a); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+func (p *parser) parseModifier(a *AggregateExpr) (bool, error) {
+	t := p.peek()
+	if t.kind != tokenIdent || (t.text != "by" && t.text != "without") {
+		return false, nil
+	}
+	p.next()
+	a.Without = t.text == "without"
+	if err := p.expect("("); err != nil {
+		return false, err
+	}
+	for !p.accept(")") {
+		if len(a.Grouping) > 0 {
+			if err := p.expect(","); err != nil {
+				return false, err
+			}
+		}
+		l := p.next()
+		if l.kind != tokenIdent {
+			return false, errors.WithMessagef(ErrSyntax, "expect a label but got %q at %d", l.text, l.pos)
+		}
+		a.Grouping = append(a.Grouping, l.text)
+	}
+	return true, nil
+}
+
+// parseSelector parses the matchers following the metric name. The metric name is matched by __name__ if it's absent.
+func (p *parser) parseSelector(name string) (Expr, error) {
+	s := &VectorSelector{Name: name}
+	if p.accept("{") {
+		if err := p.parseMatchers(s); err != nil {
+			return nil, err
+		}
+	}
+	if s.Name == "" {
+		return nil, errors.WithMessage(ErrSyntax, "the metric name is absent")
+	}
+	if p.accept("[") {
+		return nil, errors.WithMessage(ErrSyntax, "the range vectors are unsupported")
+	}
+	return s, nil
+}
+
+func (p *parser) parseMatchers(s *VectorSelector) error {
+	for first := true; !p.accept("}"); first = false {
+		if !first {
+			if err := p.expect(","); err != nil {
+				return err
+			}
+			// a trailing comma is allowed
+			if p.accept("}") {
+				break
+			}
+		}
+		l := p.next()
+		if l.kind != tokenIdent {
+			return errors.WithMessagef(ErrSyntax, "expect a label but got %q at %d", l.text, l.pos)
+		}
+		op := p.next()
+		if op.kind != tokenOp {
+			return errors.WithMessagef(ErrSyntax, "expect a match operator but got %q at %d", op.text, op.pos)
+		}
+		t := MatchType(op.text)
+		if t != MatchEqual && t != MatchNotEqual && t != MatchRegexp && t != MatchNotRegexp {
+			return errors.WithMessagef(ErrSyntax, "expect a match operator but got %q at %d", op.text, op.pos)
+		}
+		v := p.next()
+		if v.kind != tokenString {
+			return errors.WithMessagef(ErrSyntax, "expect a string but got %q at %d", v.text, v.pos)
+		}
+		if l.text == MetricNameLabel {
+			if t != MatchEqual || s.Name != "" {
+				return errors.WithMessagef(ErrSyntax, "the metric name should be matched once by = at %d", l.pos)
+			}
+			s.Name = v.value
+			continue
+		}
+		m, err := NewMatcher(t, l.text, v.value)
+		if err != nil {
+			return err
+		}
+		s.Matchers = append(s.Matchers, m)
+	}
+	return nil
+}
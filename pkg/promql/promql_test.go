@@ -0,0 +1,142 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package promql_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/pkg/promql"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`service_cpm`, `service_cpm`},
+		{`sw:service_cpm:value{service="gateway", layer!='MESH',}`, `sw:service_cpm:value{service="gateway",layer!="MESH"}`},
+		{`{__name__="service_cpm", service=~"gate.*"}`, `service_cpm{service=~"gate.*"}`},
+		{`sum by (service) (service_cpm)`, `sum by (service) (service_cpm)`},
+		{`avg(service_cpm{service!~"test"}) without (layer)`, `avg without (layer) (service_cpm{service!~"test"})`},
+		{`max(min by (a, b) ((service_cpm)))`, `max(min by (a, b) (service_cpm))`},
+	}
+	for _, tt := range tests {
+		e, err := promql.Parse(tt.source)
+		require.NoError(t, err, tt.source)
+		assert.Equal(t, tt.want, e.String(), tt.source)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, source := range []string{
+		``,
+		`{service="gateway"}`,
+		`service_cpm[5m]`,
+		`rate(service_cpm)`,
+		`service_cpm{service}`,
+		`service_cpm{service=gateway}`,
+		`service_cpm{service=~"("}`,
+		`service_cpm{__name__="other"}`,
+		`sum by (service) service_cpm`,
+		`service_cpm service_cpm`,
+		`service_cpm{service="gateway}`,
+	} {
+		_, err := promql.Parse(source)
+		assert.ErrorIs(t, err, promql.ErrSyntax, source)
+	}
+}
+
+func TestEval(t *testing.T) {
+	fetched := []promql.Series{
+		{
+			Labels:  map[string]string{promql.MetricNameLabel: "cpm", "service": "a", "instance": "1"},
+			Samples: []promql.Sample{{T: 20, V: 2}, {T: 0, V: 1}, {T: 40, V: 4}},
+		},
+		{
+			Labels:  map[string]string{promql.MetricNameLabel: "cpm", "service": "a", "instance": "2"},
+			Samples: []promql.Sample{{T: 0, V: 10}},
+		},
+		{
+			Labels:  map[string]string{promql.MetricNameLabel: "cpm", "service": "b", "instance": "1"},
+			Samples: []promql.Sample{{T: 30, V: 100}},
+		},
+	}
+	r := promql.Range{Start: 0, End: 40, Step: 10, Lookback: 15}
+	eval := func(source string) []promql.Series {
+		e, err := promql.Parse(source)
+		require.NoError(t, err)
+		result, err := promql.Eval(e, r, func(s *promql.VectorSelector, start, end int64) ([]promql.Series, error) {
+			assert.Equal(t, "cpm", s.Name)
+			assert.Equal(t, int64(-15), start)
+			assert.Equal(t, int64(40), end)
+			return fetched, nil
+		})
+		require.NoError(t, err)
+		return result
+	}
+
+	assert.Equal(t, []promql.Series{
+		{
+			Labels:  map[string]string{promql.MetricNameLabel: "cpm", "service": "a", "instance": "1"},
+			Samples: []promql.Sample{{T: 0, V: 1}, {T: 10, V: 1}, {T: 20, V: 2}, {T: 30, V: 2}, {T: 40, V: 4}},
+		},
+		{
+			Labels:  map[string]string{promql.MetricNameLabel: "cpm", "service": "a", "instance": "2"},
+			Samples: []promql.Sample{{T: 0, V: 10}, {T: 10, V: 10}},
+		},
+	}, eval(`cpm{service="a"}`))
+	assert.Equal(t, []promql.Series{
+		{
+			Labels:  map[string]string{"service": "a"},
+			Samples: []promql.Sample{{T: 0, V: 11}, {T: 10, V: 11}, {T: 20, V: 2}, {T: 30, V: 2}, {T: 40, V: 4}},
+		},
+		{
+			Labels:  map[string]string{"service": "b"},
+			Samples: []promql.Sample{{T: 30, V: 100}, {T: 40, V: 100}},
+		},
+	}, eval(`sum by (service) (cpm)`))
+	assert.Equal(t, []promql.Series{
+		{
+			Labels:  map[string]string{"instance": "1"},
+			Samples: []promql.Sample{{T: 0, V: 1}, {T: 10, V: 1}, {T: 20, V: 2}, {T: 30, V: 51}, {T: 40, V: 52}},
+		},
+	}, eval(`avg without (service) (cpm{instance=~"1|3"})`))
+	assert.Equal(t, []promql.Series{
+		{
+			Labels:  map[string]string{},
+			Samples: []promql.Sample{{T: 0, V: 2}, {T: 10, V: 2}, {T: 20, V: 1}, {T: 30, V: 2}, {T: 40, V: 2}},
+		},
+	}, eval(`count(cpm)`))
+	assert.Empty(t, eval(`max(cpm{service="c"})`))
+}
+
+func TestEvalRange(t *testing.T) {
+	e, err := promql.Parse(`cpm`)
+	require.NoError(t, err)
+	for _, r := range []promql.Range{
+		{Start: 10, End: 0, Step: 1},
+		{Start: 0, End: 10, Step: 0},
+		{Start: 0, End: promql.MaxSteps, Step: 1},
+	} {
+		_, err = promql.Eval(e, r, nil)
+		assert.ErrorIs(t, err, promql.ErrRange)
+	}
+}
@@ -0,0 +1,153 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package aggregation
+
+import (
+	"encoding/json"
+	"plugin"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrInvalidCustomFunc   = errors.New("the custom aggregation function should have a name, all the callbacks and a state encoded by JSON")
+	ErrDuplicateCustomFunc = errors.New("the custom aggregation function is registered")
+
+	customFuncs   = make(map[string]func() Aggregator)
+	customFuncsMu sync.RWMutex
+)
+
+// Function is a custom aggregate function, e.g. the apdex of the latencies, whose state S accumulates the values of a group.
+// The partial states of the shards, the data nodes or the windows of a flow are merged before the result is finalized.
+// The states are encoded by JSON while crossing the nodes, so their fields should be exported.
+type Function[S any] struct {
+	// Init returns the state of an empty group.
+	Init func() S
+	// In accumulates the value into the state.
+	In func(state S, value int64) S
+	// Merge merges the partial states.
+	Merge func(a, b S) S
+	// Finalize returns the result of the state.
+	Finalize func(state S) int64
+	// Name selects the function by the custom_function of the queries.
+	Name string
+}
+
+// Aggregator is an aggregation of a custom function, whose partial state can be merged into another one of the same function.
+type Aggregator interface {
+	Int64Func
+	// State encodes the partial state.
+	State() []byte
+	// Merge merges the encoded partial state.
+	Merge(state []byte) error
+}
+
+// Register registers the custom function, which is called by the init of the package implementing it.
+// The package is either linked into the server, or loaded as a Go plugin by LoadPlugins.
+func Register[S any](f Function[S]) error {
+	if f.Name == "" || f.Init == nil || f.In == nil || f.Merge == nil || f.Finalize == nil {
+		return ErrInvalidCustomFunc
+	}
+	if _, err := json.Marshal(f.Init()); err != nil {
+		return errors.WithMessage(ErrInvalidCustomFunc, err.Error())
+	}
+	customFuncsMu.Lock()
+	defer customFuncsMu.Unlock()
+	if _, ok := customFuncs[f.Name]; ok {
+		return errors.WithMessage(ErrDuplicateCustomFunc, f.Name)
+	}
+	customFuncs[f.Name] = func() Aggregator {
+		return &customAggregator[S]{fn: f, state: f.Init()}
+	}
+	return nil
+}
+
+// MustRegister registers the custom function, and panics if it's invalid or registered.
+func MustRegister[S any](f Function[S]) {
+	if err := Register(f); err != nil {
+		panic(err)
+	}
+}
+
+// NewAggregator returns an aggregator of the custom function registered by the name.
+func NewAggregator(name string) (Aggregator, error) {
+	customFuncsMu.RLock()
+	defer customFuncsMu.RUnlock()
+	newAggregator, ok := customFuncs[name]
+	if !ok {
+		return nil, errors.WithMessage(ErrUnknownFunc, name)
+	}
+	return newAggregator(), nil
+}
+
+// CustomFunctions returns the names of the registered custom functions in order.
+func CustomFunctions() []string {
+	customFuncsMu.RLock()
+	defer customFuncsMu.RUnlock()
+	names := make([]string, 0, len(customFuncs))
+	for name := range customFuncs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadPlugins opens the Go plugins, whose init functions register their custom functions.
+// A plugin should be built by the same toolchain and the same versions of the dependencies as the server.
+func LoadPlugins(paths []string) error {
+	for _, path := range paths {
+		if _, err := plugin.Open(path); err != nil {
+			return errors.Wrapf(err, "failed to load the aggregation plugin %s", path)
+		}
+	}
+	return nil
+}
+
+type customAggregator[S any] struct {
+	state S
+	fn    Function[S]
+}
+
+func (a *customAggregator[S]) In(val int64) {
+	a.state = a.fn.In(a.state, val)
+}
+
+func (a *customAggregator[S]) Val() int64 {
+	return a.fn.Finalize(a.state)
+}
+
+func (a *customAggregator[S]) Reset() {
+	a.state = a.fn.Init()
+}
+
+func (a *customAggregator[S]) State() []byte {
+	// the encoding of the state is checked by Register
+	data, _ := json.Marshal(a.state)
+	return data
+}
+
+func (a *customAggregator[S]) Merge(state []byte) error {
+	other := a.fn.Init()
+	if err := json.Unmarshal(state, &other); err != nil {
+		return errors.Wrapf(err, "failed to decode the state of the custom aggregation function %s", a.fn.Name)
+	}
+	a.state = a.fn.Merge(a.state, other)
+	return nil
+}
@@ -40,6 +40,70 @@ type StreamExecutable interface {
 	Execute(StreamExecutionContext) ([]*streamv1.Element, error)
 }
 
+// SIterator iterates the elements produced by a stream query plan.
+// Next returns false once the elements run out or the iteration fails,
+// and Close releases the resources held by the plan and returns the error stopping the iteration.
+type SIterator interface {
+	Next() bool
+
+	Current() *streamv1.Element
+
+	Close() error
+}
+
+// StreamIterable is a stream query plan producing its elements as they are read rather than all at once.
+type StreamIterable interface {
+	Iterate(StreamExecutionContext) (SIterator, error)
+}
+
+var EmptySIterator SIterator = &sliceSIterator{}
+
+// IterateStream iterates the elements of the plan, which are read on demand if the plan is StreamIterable,
+// or produced by executing the plan otherwise.
+func IterateStream(plan StreamExecutable, ec StreamExecutionContext) (SIterator, error) {
+	if iterable, ok := plan.(StreamIterable); ok {
+		return iterable.Iterate(ec)
+	}
+	elements, err := plan.Execute(ec)
+	if err != nil {
+		return nil, err
+	}
+	return &sliceSIterator{elements: elements, index: -1}, nil
+}
+
+// CollectStream reads all the elements of the iterator and closes it.
+func CollectStream(it SIterator) ([]*streamv1.Element, error) {
+	var elements []*streamv1.Element
+	for it.Next() {
+		elements = append(elements, it.Current())
+	}
+	if err := it.Close(); err != nil {
+		return nil, err
+	}
+	return elements, nil
+}
+
+type sliceSIterator struct {
+	elements []*streamv1.Element
+	index    int
+}
+
+func (si *sliceSIterator) Next() bool {
+	if si.index+1 >= len(si.elements) {
+		return false
+	}
+	si.index++
+	return true
+}
+
+func (si *sliceSIterator) Current() *streamv1.Element {
+	return si.elements[si.index]
+}
+
+func (si *sliceSIterator) Close() error {
+	return nil
+}
+
 type MeasureExecutionContext interface {
 	ExecutionContext
 	ParseField(name string, item tsdb.Item) (*measurev1.DataPoint_Field, error)
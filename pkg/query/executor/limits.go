@@ -0,0 +1,166 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+)
+
+// The names of the limits reported by ResourceExhaustedError.
+const (
+	ResourceTimeout          = "timeout"
+	ResourceMaxScannedSeries = "max_scanned_series"
+	ResourceMaxScannedBlocks = "max_scanned_blocks"
+	ResourceMaxResponseBytes = "max_response_bytes"
+	ResourceMaxResponseItems = "max_response_items"
+)
+
+var ErrResourceExhausted = errors.New("resource exhausted")
+
+// ResourceExhaustedError is returned when a query exceeds one of its limits.
+type ResourceExhaustedError struct {
+	Resource string
+	Limit    int64
+}
+
+func (e *ResourceExhaustedError) Error() string {
+	return fmt.Sprintf("%s: %s %d", ErrResourceExhausted, e.Resource, e.Limit)
+}
+
+func (e *ResourceExhaustedError) Unwrap() error {
+	return ErrResourceExhausted
+}
+
+// Limits bounds the resources consumed by a query, 0 means unlimited.
+type Limits struct {
+	Timeout          time.Duration
+	MaxScannedSeries int
+	MaxScannedBlocks int
+	MaxResponseBytes int
+	MaxResponseItems int
+	// Truncate returns the items within the response limits instead of failing the query
+	Truncate bool
+}
+
+// NewLimits converts the limits of a query request.
+func NewLimits(l *modelv1.QueryLimits) Limits {
+	return Limits{
+		Timeout:          l.GetTimeout().AsDuration(),
+		MaxScannedSeries: int(l.GetMaxScannedSeries()),
+		MaxScannedBlocks: int(l.GetMaxScannedBlocks()),
+		MaxResponseBytes: int(l.GetMaxResponseBytes()),
+		MaxResponseItems: int(l.GetMaxResponseItems()),
+		Truncate:         l.GetTruncate(),
+	}
+}
+
+// IsZero reports whether there is no limit.
+func (l Limits) IsZero() bool {
+	return l == Limits{}
+}
+
+// Limits returns the limits enforced on s.
+func (s *Stats) Limits() Limits {
+	if s == nil {
+		return Limits{}
+	}
+	return s.limits
+}
+
+// WithLimits starts enforcing the limits on s, the timeout counts from now.
+// The timeout is carried by the Context of s, which should be released once the query finishes.
+func (s *Stats) WithLimits(l Limits) *Stats {
+	if s == nil {
+		return nil
+	}
+	s.limits = l
+	if l.Timeout > 0 {
+		s.ctx, s.cancel = context.WithTimeout(context.Background(), l.Timeout)
+	}
+	return s
+}
+
+// Context returns the context that is done once the query times out.
+// The seekers and the iterators stop scanning when it's done.
+func (s *Stats) Context() context.Context {
+	if s == nil || s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+// Release releases the context of s.
+func (s *Stats) Release() {
+	if s == nil || s.cancel == nil {
+		return
+	}
+	s.cancel()
+}
+
+// Check returns a ResourceExhaustedError if the query times out.
+func (s *Stats) Check() error {
+	if s == nil || s.ctx == nil {
+		return nil
+	}
+	if errors.Is(s.ctx.Err(), context.DeadlineExceeded) {
+		return &ResourceExhaustedError{Resource: ResourceTimeout, Limit: s.limits.Timeout.Milliseconds()}
+	}
+	return nil
+}
+
+// AddResponseBytes records the size of the response.
+// It returns a ResourceExhaustedError if the response exceeds the limit or the query times out.
+func (s *Stats) AddResponseBytes(n int) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	s.responseBytes += n
+	responseBytes := s.responseBytes
+	s.mu.Unlock()
+	if s.limits.MaxResponseBytes > 0 && responseBytes > s.limits.MaxResponseBytes {
+		return &ResourceExhaustedError{Resource: ResourceMaxResponseBytes, Limit: int64(s.limits.MaxResponseBytes)}
+	}
+	return s.Check()
+}
+
+// AddResponseItem records an item of n bytes of the response.
+// It returns a ResourceExhaustedError if the response exceeds the limits or the query times out.
+// The first item of a truncated response is always admitted, so that the next page of the query makes progress.
+func (s *Stats) AddResponseItem(n int) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	s.responseItems++
+	responseItems := s.responseItems
+	s.mu.Unlock()
+	if s.limits.MaxResponseItems > 0 && responseItems > s.limits.MaxResponseItems {
+		return &ResourceExhaustedError{Resource: ResourceMaxResponseItems, Limit: int64(s.limits.MaxResponseItems)}
+	}
+	if err := s.AddResponseBytes(n); err != nil && (responseItems > 1 || !s.limits.Truncate) {
+		return err
+	}
+	return s.Check()
+}
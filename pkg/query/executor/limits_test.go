@@ -0,0 +1,122 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package executor_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/query/executor"
+)
+
+func assertExhausted(t *testing.T, err error, resource string, limit int64) {
+	var re *executor.ResourceExhaustedError
+	require.True(t, errors.As(err, &re), "unexpected error %v", err)
+	assert.ErrorIs(t, err, executor.ErrResourceExhausted)
+	assert.Equal(t, resource, re.Resource)
+	assert.Equal(t, limit, re.Limit)
+}
+
+func TestLimits(t *testing.T) {
+	assert.True(t, executor.NewLimits(nil).IsZero())
+	l := executor.NewLimits(&modelv1.QueryLimits{
+		Timeout:          durationpb.New(time.Second),
+		MaxScannedSeries: 2,
+		MaxScannedBlocks: 3,
+		MaxResponseBytes: 10,
+	})
+	assert.Equal(t, executor.Limits{
+		Timeout:          time.Second,
+		MaxScannedSeries: 2,
+		MaxScannedBlocks: 3,
+		MaxResponseBytes: 10,
+	}, l)
+
+	s := executor.NewStats().WithLimits(l)
+	assert.NoError(t, s.AddScanned(2, 1))
+	assertExhausted(t, s.AddScanned(1, 1), executor.ResourceMaxScannedSeries, 2)
+
+	s = executor.NewStats().WithLimits(l)
+	assert.NoError(t, s.AddScanned(1, 3))
+	assertExhausted(t, s.AddScanned(0, 1), executor.ResourceMaxScannedBlocks, 3)
+
+	s = executor.NewStats().WithLimits(l)
+	assert.NoError(t, s.AddResponseBytes(10))
+	assertExhausted(t, s.AddResponseBytes(1), executor.ResourceMaxResponseBytes, 10)
+}
+
+func TestResponseItems(t *testing.T) {
+	l := executor.NewLimits(&modelv1.QueryLimits{MaxResponseBytes: 10, MaxResponseItems: 2})
+	s := executor.NewStats().WithLimits(l)
+	assert.NoError(t, s.AddResponseItem(1))
+	assert.NoError(t, s.AddResponseItem(1))
+	assertExhausted(t, s.AddResponseItem(1), executor.ResourceMaxResponseItems, 2)
+
+	s = executor.NewStats().WithLimits(l)
+	assertExhausted(t, s.AddResponseItem(11), executor.ResourceMaxResponseBytes, 10)
+
+	l.Truncate = true
+	s = executor.NewStats().WithLimits(l)
+	assert.True(t, s.Limits().Truncate)
+	// the first item is admitted even if it's too large
+	assert.NoError(t, s.AddResponseItem(11))
+	assertExhausted(t, s.AddResponseItem(1), executor.ResourceMaxResponseBytes, 10)
+}
+
+func TestTimeout(t *testing.T) {
+	s := executor.NewStats().WithLimits(executor.Limits{Timeout: 10 * time.Millisecond})
+	assert.NoError(t, s.Check())
+	time.Sleep(20 * time.Millisecond)
+	assertExhausted(t, s.Check(), executor.ResourceTimeout, 10)
+	assertExhausted(t, s.AddScanned(1, 1), executor.ResourceTimeout, 10)
+	// the scans watching the context stop once the query times out
+	select {
+	case <-s.Context().Done():
+	default:
+		t.Fatal("the context is not done after the timeout")
+	}
+}
+
+func TestRelease(t *testing.T) {
+	s := executor.NewStats().WithLimits(executor.Limits{Timeout: time.Hour})
+	s.Release()
+	assert.Error(t, s.Context().Err())
+	// a released query doesn't time out
+	assert.NoError(t, s.Check())
+
+	var nilStats *executor.Stats
+	assert.NoError(t, nilStats.Context().Err())
+	nilStats.Release()
+}
+
+func TestUnlimited(t *testing.T) {
+	s := executor.NewStats().WithLimits(executor.Limits{})
+	assert.NoError(t, s.AddScanned(1<<20, 1<<20))
+	assert.NoError(t, s.AddResponseBytes(1<<30))
+	assert.NoError(t, s.Check())
+
+	var nilStats *executor.Stats
+	assert.Nil(t, nilStats.WithLimits(executor.Limits{MaxScannedSeries: 1}))
+	assert.NoError(t, nilStats.AddResponseBytes(1))
+}
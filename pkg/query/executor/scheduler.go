@@ -0,0 +1,124 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package executor
+
+import "sync"
+
+// Scheduler bounds the goroutines fanning out the scans of the queries on a node.
+// A nil Scheduler runs the tasks one by one in the calling goroutine.
+type Scheduler struct {
+	slots chan struct{}
+}
+
+// NewScheduler returns a Scheduler running at most maxParallelism tasks in parallel.
+// It returns nil if maxParallelism is less than 2.
+func NewScheduler(maxParallelism int) *Scheduler {
+	if maxParallelism < 2 {
+		return nil
+	}
+	return &Scheduler{slots: make(chan struct{}, maxParallelism)}
+}
+
+// Run runs task for every index in [0, n) and waits for all of them.
+// A task runs in a new goroutine if there's a free slot, otherwise in the calling goroutine,
+// so that the concurrent or nested queries never wait for each other.
+// It returns the error of the first failed task in the order of the indexes.
+func (s *Scheduler) Run(n int, task func(i int) error) error {
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		if s != nil && n > 1 {
+			select {
+			case s.slots <- struct{}{}:
+				wg.Add(1)
+				go func(i int) {
+					defer func() {
+						<-s.slots
+						wg.Done()
+					}()
+					errs[i] = task(i)
+				}(i)
+				continue
+			default:
+			}
+		}
+		errs[i] = task(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type schedulerCarrier interface {
+	scheduler() *Scheduler
+}
+
+// SchedulerOf returns the Scheduler attached to the ExecutionContext, or nil if there's none.
+func SchedulerOf(ec ExecutionContext) *Scheduler {
+	for ec != nil {
+		if sc, ok := ec.(schedulerCarrier); ok {
+			return sc.scheduler()
+		}
+		w, ok := ec.(wrapper)
+		if !ok {
+			return nil
+		}
+		ec = w.unwrap()
+	}
+	return nil
+}
+
+type streamSchedulerContext struct {
+	StreamExecutionContext
+	s *Scheduler
+}
+
+func (c *streamSchedulerContext) scheduler() *Scheduler {
+	return c.s
+}
+
+func (c *streamSchedulerContext) unwrap() ExecutionContext {
+	return c.StreamExecutionContext
+}
+
+// WithStreamScheduler attaches s to a StreamExecutionContext.
+func WithStreamScheduler(ec StreamExecutionContext, s *Scheduler) StreamExecutionContext {
+	return &streamSchedulerContext{StreamExecutionContext: ec, s: s}
+}
+
+type measureSchedulerContext struct {
+	MeasureExecutionContext
+	s *Scheduler
+}
+
+func (c *measureSchedulerContext) scheduler() *Scheduler {
+	return c.s
+}
+
+func (c *measureSchedulerContext) unwrap() ExecutionContext {
+	return c.MeasureExecutionContext
+}
+
+// WithMeasureScheduler attaches s to a MeasureExecutionContext.
+func WithMeasureScheduler(ec MeasureExecutionContext, s *Scheduler) MeasureExecutionContext {
+	return &measureSchedulerContext{MeasureExecutionContext: ec, s: s}
+}
@@ -0,0 +1,90 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package executor_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/pkg/query/executor"
+)
+
+func TestSchedulerBoundsParallelism(t *testing.T) {
+	s := executor.NewScheduler(2)
+	var running, peak atomic.Int32
+	visited := make([]bool, 10)
+	require.NoError(t, s.Run(len(visited), func(i int) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		visited[i] = true
+		return nil
+	}))
+	for i, v := range visited {
+		assert.True(t, v, "task %d isn't run", i)
+	}
+	// two slots plus the calling goroutine
+	assert.LessOrEqual(t, peak.Load(), int32(3))
+	assert.Greater(t, peak.Load(), int32(1))
+}
+
+func TestSchedulerError(t *testing.T) {
+	errFirst := errors.New("first")
+	for _, s := range []*executor.Scheduler{nil, executor.NewScheduler(4)} {
+		err := s.Run(4, func(i int) error {
+			switch i {
+			case 1:
+				return errFirst
+			case 3:
+				return errors.New("second")
+			}
+			return nil
+		})
+		assert.ErrorIs(t, err, errFirst)
+	}
+}
+
+func TestNilScheduler(t *testing.T) {
+	assert.Nil(t, executor.NewScheduler(1))
+	var order []int
+	require.NoError(t, executor.NewScheduler(0).Run(3, func(i int) error {
+		order = append(order, i)
+		return nil
+	}))
+	assert.Equal(t, []int{0, 1, 2}, order)
+}
+
+func TestSchedulerOf(t *testing.T) {
+	s := executor.NewScheduler(2)
+	stats := executor.NewStats()
+	ec := executor.WithStreamStats(executor.WithStreamScheduler(nil, s), stats)
+	assert.Same(t, s, executor.SchedulerOf(ec))
+	assert.Same(t, stats, executor.StatsOf(ec))
+	assert.Nil(t, executor.SchedulerOf(executor.WithMeasureStats(nil, stats)))
+}
@@ -0,0 +1,174 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// The names of the scratch quotas reported by ResourceExhaustedError.
+const (
+	ResourceMaxScratchBytes = "max_scratch_bytes"
+	ResourceScratchSpace    = "scratch_space"
+)
+
+var ErrScratchClosed = errors.New("the scratch directory of the query is closed")
+
+// ScratchSpace is the temp directory shared by the queries spilling the intermediate results to the disk.
+// Each query writes to its own sub directory, which is bounded by the per-query quota as well as the global one.
+type ScratchSpace struct {
+	root       string
+	quota      int64
+	queryQuota int64
+	used       int64
+	seq        uint64
+}
+
+// NewScratchSpace creates the root directory, and removes the files left by the queries of a crashed process.
+// A quota of 0 means unlimited.
+func NewScratchSpace(root string, quota, queryQuota int64) (*ScratchSpace, error) {
+	if err := os.RemoveAll(root); err != nil {
+		return nil, errors.Wrapf(err, "clean up the scratch directory %s", root)
+	}
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		return nil, errors.Wrapf(err, "create the scratch directory %s", root)
+	}
+	return &ScratchSpace{root: root, quota: quota, queryQuota: queryQuota}, nil
+}
+
+// Used returns the bytes written by the running queries.
+func (s *ScratchSpace) Used() int64 {
+	return atomic.LoadInt64(&s.used)
+}
+
+// NewScratch returns the scratch of a query, whose directory is created by the first file.
+// It's nil if s is nil, which rejects the spilling queries.
+func (s *ScratchSpace) NewScratch() *Scratch {
+	if s == nil {
+		return nil
+	}
+	return &Scratch{
+		space: s,
+		dir:   filepath.Join(s.root, "query-"+strconv.FormatUint(atomic.AddUint64(&s.seq, 1), 10)),
+	}
+}
+
+// Scratch is the temp directory of a query, which is removed once the query finishes or is canceled.
+type Scratch struct {
+	space   *ScratchSpace
+	dir     string
+	files   []*os.File
+	used    int64
+	mu      sync.Mutex
+	created bool
+	closed  bool
+}
+
+// Create creates a temp file, whose writes are charged to the quotas.
+func (s *Scratch) Create(pattern string) (*ScratchFile, error) {
+	if s == nil {
+		return nil, ErrScratchClosed
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrScratchClosed
+	}
+	if !s.created {
+		if err := os.Mkdir(s.dir, 0o700); err != nil {
+			return nil, errors.Wrapf(err, "create the scratch directory %s", s.dir)
+		}
+		s.created = true
+	}
+	f, err := os.CreateTemp(s.dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+	s.files = append(s.files, f)
+	return &ScratchFile{File: f, scratch: s}, nil
+}
+
+// Reserve charges n bytes to the quotas. It returns a ResourceExhaustedError if any quota is exceeded.
+func (s *Scratch) Reserve(n int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrScratchClosed
+	}
+	if s.space.queryQuota > 0 && s.used+n > s.space.queryQuota {
+		return &ResourceExhaustedError{Resource: ResourceMaxScratchBytes, Limit: s.space.queryQuota}
+	}
+	if used := atomic.AddInt64(&s.space.used, n); s.space.quota > 0 && used > s.space.quota {
+		atomic.AddInt64(&s.space.used, -n)
+		return &ResourceExhaustedError{Resource: ResourceScratchSpace, Limit: s.space.quota}
+	}
+	s.used += n
+	return nil
+}
+
+// Used returns the bytes written by the query.
+func (s *Scratch) Used() int64 {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.used
+}
+
+// Close removes the directory and releases the quota. It's safe to be called more than once.
+func (s *Scratch) Close() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	for _, f := range s.files {
+		_ = f.Close()
+	}
+	atomic.AddInt64(&s.space.used, -s.used)
+	s.used = 0
+	if !s.created {
+		return nil
+	}
+	return os.RemoveAll(s.dir)
+}
+
+// ScratchFile is a temp file of a query.
+type ScratchFile struct {
+	*os.File
+	scratch *Scratch
+}
+
+// Write reserves the quotas before writing p.
+func (f *ScratchFile) Write(p []byte) (int, error) {
+	if err := f.scratch.Reserve(int64(len(p))); err != nil {
+		return 0, err
+	}
+	return f.File.Write(p)
+}
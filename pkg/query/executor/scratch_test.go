@@ -0,0 +1,66 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package executor_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/pkg/query/executor"
+)
+
+func TestScratchSpace(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "scratch")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "query-1"), 0o700))
+	s, err := executor.NewScratchSpace(root, 10, 6)
+	require.NoError(t, err)
+	entries, err := os.ReadDir(root)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the files left by a crash are removed")
+
+	q1, q2 := s.NewScratch(), s.NewScratch()
+	f, err := q1.Create("sort-*")
+	require.NoError(t, err)
+	_, err = f.Write([]byte("12345"))
+	require.NoError(t, err)
+	_, err = f.Write([]byte("67"))
+	var re *executor.ResourceExhaustedError
+	require.True(t, errors.As(err, &re))
+	assert.Equal(t, executor.ResourceMaxScratchBytes, re.Resource)
+
+	require.NoError(t, q2.Reserve(5))
+	err = q2.Reserve(1)
+	require.True(t, errors.As(err, &re))
+	assert.Equal(t, executor.ResourceScratchSpace, re.Resource)
+	assert.Equal(t, int64(10), s.Used())
+
+	require.NoError(t, q1.Close())
+	require.NoError(t, q1.Close())
+	assert.Equal(t, int64(5), s.Used())
+	_, err = os.Stat(filepath.Dir(f.Name()))
+	assert.True(t, os.IsNotExist(err))
+	_, err = q1.Create("sort-*")
+	assert.ErrorIs(t, err, executor.ErrScratchClosed)
+	require.NoError(t, q2.Close())
+	assert.Equal(t, int64(0), s.Used())
+}
@@ -0,0 +1,88 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package executor
+
+import (
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/banyand/tsdb"
+)
+
+type shardSet map[common.ShardID]struct{}
+
+func newShardSet(ids []common.ShardID) shardSet {
+	set := make(shardSet, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return set
+}
+
+func (s shardSet) filter(shards []tsdb.Shard, err error) ([]tsdb.Shard, error) {
+	if err != nil {
+		return nil, err
+	}
+	result := make([]tsdb.Shard, 0, len(shards))
+	for _, shard := range shards {
+		if _, ok := s[shard.ID()]; ok {
+			result = append(result, shard)
+		}
+	}
+	return result, nil
+}
+
+type streamShardsContext struct {
+	StreamExecutionContext
+	shards shardSet
+}
+
+func (c *streamShardsContext) Shards(entity tsdb.Entity) ([]tsdb.Shard, error) {
+	return c.shards.filter(c.StreamExecutionContext.Shards(entity))
+}
+
+func (c *streamShardsContext) unwrap() ExecutionContext {
+	return c.StreamExecutionContext
+}
+
+// WithStreamShards restricts the shards scanned by a query to the ones in ids, which are all the shards if ids is empty.
+func WithStreamShards(ec StreamExecutionContext, ids []common.ShardID) StreamExecutionContext {
+	if len(ids) < 1 {
+		return ec
+	}
+	return &streamShardsContext{StreamExecutionContext: ec, shards: newShardSet(ids)}
+}
+
+type measureShardsContext struct {
+	MeasureExecutionContext
+	shards shardSet
+}
+
+func (c *measureShardsContext) Shards(entity tsdb.Entity) ([]tsdb.Shard, error) {
+	return c.shards.filter(c.MeasureExecutionContext.Shards(entity))
+}
+
+func (c *measureShardsContext) unwrap() ExecutionContext {
+	return c.MeasureExecutionContext
+}
+
+// WithMeasureShards restricts the shards scanned by a query to the ones in ids, which are all the shards if ids is empty.
+func WithMeasureShards(ec MeasureExecutionContext, ids []common.ShardID) MeasureExecutionContext {
+	if len(ids) < 1 {
+		return ec
+	}
+	return &measureShardsContext{MeasureExecutionContext: ec, shards: newShardSet(ids)}
+}
@@ -0,0 +1,63 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package executor_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	"github.com/apache/skywalking-banyandb/banyand/tsdb"
+	"github.com/apache/skywalking-banyandb/pkg/query/executor"
+)
+
+type fakeShard struct {
+	tsdb.Shard
+	id common.ShardID
+}
+
+func (s *fakeShard) ID() common.ShardID {
+	return s.id
+}
+
+type fakeStreamContext struct {
+	executor.StreamExecutionContext
+	shards []tsdb.Shard
+}
+
+func (c *fakeStreamContext) Shards(tsdb.Entity) ([]tsdb.Shard, error) {
+	return c.shards, nil
+}
+
+func TestWithStreamShards(t *testing.T) {
+	ec := &fakeStreamContext{shards: []tsdb.Shard{&fakeShard{id: 0}, &fakeShard{id: 1}, &fakeShard{id: 2}}}
+	assert.Same(t, executor.StreamExecutionContext(ec), executor.WithStreamShards(ec, nil))
+
+	stats := executor.NewStats()
+	restricted := executor.WithStreamStats(executor.WithStreamShards(ec, []common.ShardID{0, 2}), stats)
+	shards, err := restricted.Shards(nil)
+	require.NoError(t, err)
+	ids := make([]common.ShardID, 0, len(shards))
+	for _, s := range shards {
+		ids = append(ids, s.ID())
+	}
+	assert.Equal(t, []common.ShardID{0, 2}, ids)
+	assert.Same(t, stats, executor.StatsOf(restricted))
+}
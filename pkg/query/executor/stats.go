@@ -0,0 +1,205 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package executor
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// NodeStat is the time spent on executing a plan node, including its children.
+type NodeStat struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Stats collects the execution details of a query and enforces its limits.
+// A nil Stats discards everything so that plans don't have to check whether it's enabled.
+type Stats struct {
+	ctx           context.Context
+	cancel        context.CancelFunc
+	nodes         []NodeStat
+	indexes       []string
+	limits        Limits
+	scannedSeries int
+	scannedBlocks int
+	responseBytes int
+	responseItems int
+	mu            sync.Mutex
+}
+
+// NewStats returns an empty Stats.
+func NewStats() *Stats {
+	return &Stats{}
+}
+
+// Trace starts timing the plan node named name. The returned function stops it.
+func (s *Stats) Trace(name string) func() {
+	if s == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		d := time.Since(start)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.nodes = append(s.nodes, NodeStat{Name: name, Duration: d})
+	}
+}
+
+// AddIndexes records the index rules chosen by a plan.
+func (s *Stats) AddIndexes(names ...string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, n := range names {
+		found := false
+		for _, existing := range s.indexes {
+			if existing == n {
+				found = true
+				break
+			}
+		}
+		if !found {
+			s.indexes = append(s.indexes, n)
+		}
+	}
+}
+
+// AddScanned records the number of the scanned series and blocks.
+// It returns a ResourceExhaustedError if they exceed the limits or the query times out.
+func (s *Stats) AddScanned(series, blocks int) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	s.scannedSeries += series
+	s.scannedBlocks += blocks
+	scannedSeries, scannedBlocks := s.scannedSeries, s.scannedBlocks
+	s.mu.Unlock()
+	if s.limits.MaxScannedSeries > 0 && scannedSeries > s.limits.MaxScannedSeries {
+		return &ResourceExhaustedError{Resource: ResourceMaxScannedSeries, Limit: int64(s.limits.MaxScannedSeries)}
+	}
+	if s.limits.MaxScannedBlocks > 0 && scannedBlocks > s.limits.MaxScannedBlocks {
+		return &ResourceExhaustedError{Resource: ResourceMaxScannedBlocks, Limit: int64(s.limits.MaxScannedBlocks)}
+	}
+	return s.Check()
+}
+
+// Nodes returns the timing of the plan nodes in the order they finish.
+func (s *Stats) Nodes() []NodeStat {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]NodeStat(nil), s.nodes...)
+}
+
+// Indexes returns the chosen index rules.
+func (s *Stats) Indexes() []string {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.indexes...)
+}
+
+// ScannedSeries returns the number of the scanned series.
+func (s *Stats) ScannedSeries() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.scannedSeries
+}
+
+// ScannedBlocks returns the number of the scanned blocks.
+func (s *Stats) ScannedBlocks() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.scannedBlocks
+}
+
+type statsCarrier interface {
+	stats() *Stats
+}
+
+// wrapper is an ExecutionContext attaching something to another one.
+type wrapper interface {
+	unwrap() ExecutionContext
+}
+
+// StatsOf returns the Stats attached to the ExecutionContext, or nil if there's none.
+func StatsOf(ec ExecutionContext) *Stats {
+	for ec != nil {
+		if sc, ok := ec.(statsCarrier); ok {
+			return sc.stats()
+		}
+		w, ok := ec.(wrapper)
+		if !ok {
+			return nil
+		}
+		ec = w.unwrap()
+	}
+	return nil
+}
+
+type streamStatsContext struct {
+	StreamExecutionContext
+	s *Stats
+}
+
+func (c *streamStatsContext) stats() *Stats {
+	return c.s
+}
+
+func (c *streamStatsContext) unwrap() ExecutionContext {
+	return c.StreamExecutionContext
+}
+
+// WithStreamStats attaches s to a StreamExecutionContext.
+func WithStreamStats(ec StreamExecutionContext, s *Stats) StreamExecutionContext {
+	return &streamStatsContext{StreamExecutionContext: ec, s: s}
+}
+
+type measureStatsContext struct {
+	MeasureExecutionContext
+	s *Stats
+}
+
+func (c *measureStatsContext) stats() *Stats {
+	return c.s
+}
+
+func (c *measureStatsContext) unwrap() ExecutionContext {
+	return c.MeasureExecutionContext
+}
+
+// WithMeasureStats attaches s to a MeasureExecutionContext.
+func WithMeasureStats(ec MeasureExecutionContext, s *Stats) MeasureExecutionContext {
+	return &measureStatsContext{MeasureExecutionContext: ec, s: s}
+}
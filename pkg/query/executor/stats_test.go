@@ -0,0 +1,62 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package executor_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/apache/skywalking-banyandb/pkg/query/executor"
+)
+
+func TestStats(t *testing.T) {
+	s := executor.NewStats()
+	var ec executor.MeasureExecutionContext = executor.WithMeasureStats(nil, s)
+	assert.Same(t, s, executor.StatsOf(ec))
+
+	stop := executor.StatsOf(ec).Trace("IndexScan")
+	time.Sleep(10 * time.Millisecond)
+	stop()
+	s.AddIndexes("duration", "trace_id")
+	s.AddIndexes("duration")
+	assert.NoError(t, s.AddScanned(2, 3))
+	assert.NoError(t, s.AddScanned(1, 1))
+
+	nodes := s.Nodes()
+	assert.Len(t, nodes, 1)
+	assert.Equal(t, "IndexScan", nodes[0].Name)
+	assert.GreaterOrEqual(t, nodes[0].Duration, 10*time.Millisecond)
+	assert.Equal(t, []string{"duration", "trace_id"}, s.Indexes())
+	assert.Equal(t, 3, s.ScannedSeries())
+	assert.Equal(t, 4, s.ScannedBlocks())
+}
+
+func TestNilStats(t *testing.T) {
+	var ec executor.StreamExecutionContext
+	s := executor.StatsOf(ec)
+	assert.Nil(t, s)
+	s.Trace("TagFilter")()
+	s.AddIndexes("duration")
+	assert.NoError(t, s.AddScanned(1, 1))
+	assert.Empty(t, s.Nodes())
+	assert.Empty(t, s.Indexes())
+	assert.Zero(t, s.ScannedSeries())
+	assert.Zero(t, s.ScannedBlocks())
+}
@@ -76,11 +76,7 @@ func ProjectItem(ec executor.ExecutionContext, item tsdb.Item, projectionFieldRe
 		if err != nil {
 			return nil, err
 		}
-		if len(refs) > len(parsedTagFamily.Tags) {
-			return nil, errors.Wrapf(ErrInvalidData,
-				"the number of tags %d in %s is less then expected %d",
-				len(parsedTagFamily.Tags), familyName, len(refs))
-		}
+		// the tags appended to the schema after the item is written are absent
 		for j, ref := range refs {
 			if len(parsedTagFamily.GetTags()) > ref.Spec.TagIdx {
 				tags[j] = parsedTagFamily.GetTags()[ref.Spec.TagIdx]
@@ -101,43 +97,104 @@ func ProjectItem(ec executor.ExecutionContext, item tsdb.Item, projectionFieldRe
 // ExecuteForShard fetches elements from series within a single shard. A list of series must be prepared in advanced
 // with the help of Entity. The result is a list of element set, where the order of inner list is kept
 // as what the users specify in the seekerBuilder.
+// The series are seeked in parallel by the Scheduler of ec, and the iterators are returned in the order of the series.
+// The seeked series and iterators are counted by the Stats of ec one by one, so the seeking stops once the query exceeds its limits.
 // This method is used by the underlying tableScan and localIndexScan plans.
-func ExecuteForShard(series tsdb.SeriesList, timeRange timestamp.TimeRange,
+func ExecuteForShard(ec executor.ExecutionContext, series tsdb.SeriesList, timeRange timestamp.TimeRange,
 	builders ...SeekerBuilder,
 ) ([]tsdb.Iterator, []io.Closer, error) {
-	var itersInShard []tsdb.Iterator
+	stats := executor.StatsOf(ec)
+	itersInSeries := make([][]tsdb.Iterator, len(series))
+	spans := make([]tsdb.SeriesSpan, len(series))
+	err := executor.SchedulerOf(ec).Run(len(series), func(i int) error {
+		// the series left are not scanned once the query exceeds its limits
+		if errInner := stats.Check(); errInner != nil {
+			return errInner
+		}
+		ctx, cancel := context.WithTimeout(stats.Context(), 5*time.Second)
+		defer cancel()
+		sp, errInner := series[i].Span(ctx, timeRange)
+		if errInner != nil {
+			return errInner
+		}
+		spans[i] = sp
+		b := sp.SeekerBuilder().WithContext(stats.Context())
+		for _, builder := range builders {
+			builder(b)
+		}
+		seeker, errInner := b.Build()
+		if errInner != nil {
+			return errInner
+		}
+		itersInSeries[i], errInner = seeker.Seek()
+		if errInner != nil {
+			return errInner
+		}
+		return stats.AddScanned(1, len(itersInSeries[i]))
+	})
 	var closers []io.Closer
+	for _, sp := range spans {
+		if sp != nil {
+			closers = append(closers, sp)
+		}
+	}
+	if errTimeout := stats.Check(); errTimeout != nil {
+		err = errTimeout
+	}
+	if err != nil {
+		return nil, closers, err
+	}
+	var itersInShard []tsdb.Iterator
+	for _, iters := range itersInSeries {
+		itersInShard = append(itersInShard, iters...)
+	}
+	return itersInShard, closers, nil
+}
+
+// ListSeries lists the series matching the entities in all shards.
+func ListSeries(ec executor.ExecutionContext, entities []tsdb.Entity) (tsdb.SeriesList, error) {
+	var seriesList tsdb.SeriesList
+	for _, e := range entities {
+		shards, err := ec.Shards(e)
+		if err != nil {
+			return nil, err
+		}
+		for _, shard := range shards {
+			sl, err := shard.Series().List(tsdb.NewPath(e))
+			if err != nil {
+				return nil, err
+			}
+			seriesList = seriesList.Merge(sl)
+		}
+	}
+	return seriesList, nil
+}
+
+// EstimateForShard counts the blocks of the series overlapping with the time range without seeking them.
+func EstimateForShard(series tsdb.SeriesList, timeRange timestamp.TimeRange) (int, error) {
+	var blocks int
 	for _, seriesFound := range series {
-		itersInSeries, err := func() ([]tsdb.Iterator, error) {
+		n, err := func() (int, error) {
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
-			sp, errInner := seriesFound.Span(ctx, timeRange)
-			closers = append(closers, sp)
-			if errInner != nil {
-				return nil, errInner
-			}
-			b := sp.SeekerBuilder()
-			for _, builder := range builders {
-				builder(b)
+			sp, err := seriesFound.Span(ctx, timeRange)
+			if errors.Is(err, tsdb.ErrEmptySeriesSpan) {
+				return 0, nil
 			}
-			seeker, errInner := b.Build()
-			if errInner != nil {
-				return nil, errInner
+			if err != nil {
+				return 0, err
 			}
-			iters, errInner := seeker.Seek()
-			if errInner != nil {
-				return nil, errInner
-			}
-			return iters, nil
+			defer func() {
+				_ = sp.Close()
+			}()
+			return sp.BlockNum(), nil
 		}()
 		if err != nil {
-			return nil, nil, err
-		}
-		if len(itersInSeries) > 0 {
-			itersInShard = append(itersInShard, itersInSeries...)
+			return 0, err
 		}
+		blocks += n
 	}
-	return itersInShard, closers, nil
+	return blocks, nil
 }
 
 var DefaultLimit uint32 = 20
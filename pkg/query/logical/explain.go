@@ -0,0 +1,59 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package logical
+
+import (
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/query/executor"
+)
+
+// Estimation is the cost of a plan node reading the data.
+type Estimation struct {
+	Indexes []string
+	Series  int
+	Blocks  int
+}
+
+// Estimator is implemented by the plan nodes reading the data to estimate the cost without execution.
+type Estimator interface {
+	Estimate(ec executor.ExecutionContext) (Estimation, error)
+}
+
+// Explain resolves the plan tree without executing it.
+func Explain(plan Plan, ec executor.ExecutionContext) (*modelv1.PlanNode, error) {
+	node := &modelv1.PlanNode{
+		Description: plan.String(),
+	}
+	if e, ok := plan.(Estimator); ok {
+		estimation, err := e.Estimate(ec)
+		if err != nil {
+			return nil, err
+		}
+		node.Indexes = estimation.Indexes
+		node.EstimatedSeries = int64(estimation.Series)
+		node.EstimatedBlocks = int64(estimation.Blocks)
+	}
+	for _, child := range plan.Children() {
+		c, err := Explain(child, ec)
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, c)
+	}
+	return node, nil
+}
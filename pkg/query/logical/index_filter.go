@@ -129,7 +129,7 @@ func parseCondition(cond *model_v1.Condition, indexRule *database_v1.IndexRule,
 	case model_v1.Condition_BINARY_OP_EQ:
 		return newEq(indexRule, expr), []tsdb.Entity{entity}, nil
 	case model_v1.Condition_BINARY_OP_MATCH:
-		return newMatch(indexRule, expr), []tsdb.Entity{entity}, nil
+		return newMatch(indexRule, expr, cond.GetMatchOption()), []tsdb.Entity{entity}, nil
 	case model_v1.Condition_BINARY_OP_NE:
 		return newNot(indexRule, newEq(indexRule, expr)), []tsdb.Entity{entity}, nil
 	case model_v1.Condition_BINARY_OP_HAVING:
@@ -278,12 +278,13 @@ func execute(searcher index.GetSearcher, seriesID common.SeriesID, n *node, lp l
 		}
 		if result == nil {
 			result = r
-			continue
-		}
-		result, err = lp.merge(result, r)
-		if err != nil {
+		} else if result, err = lp.merge(result, r); err != nil {
 			return nil, err
 		}
+		// the rest conditions can't match anything once the intersection is empty
+		if _, ok := lp.(*andNode); ok && result.IsEmpty() {
+			return result, nil
+		}
 	}
 	return result, nil
 }
@@ -465,14 +466,16 @@ func (eq *eq) String() string {
 
 type match struct {
 	*leaf
+	opts *model_v1.Condition_MatchOption
 }
 
-func newMatch(indexRule *database_v1.IndexRule, values LiteralExpr) *match {
+func newMatch(indexRule *database_v1.IndexRule, values LiteralExpr, opts *model_v1.Condition_MatchOption) *match {
 	return &match{
 		leaf: &leaf{
 			Key:  newFieldKey(indexRule),
 			Expr: values,
 		},
+		opts: opts,
 	}
 }
 
@@ -489,12 +492,16 @@ func (match *match) Execute(searcher index.GetSearcher, seriesID common.SeriesID
 	return s.Match(
 		match.Key.ToIndex(seriesID),
 		matches,
+		match.opts,
 	)
 }
 
 func (match *match) MarshalJSON() ([]byte, error) {
 	data := make(map[string]interface{}, 1)
 	data["match"] = match.leaf
+	if mode := match.opts.GetMode(); mode != model_v1.Condition_MatchOption_MODE_UNSPECIFIED {
+		data["mode"] = mode.String()
+	}
 	return json.Marshal(data)
 }
 
@@ -553,6 +560,34 @@ func (r *rangeOp) String() string {
 	return jsonToString(r)
 }
 
+// IndexRuleNames returns the names of the index rules involved in the filter.
+func IndexRuleNames(filter index.Filter) []string {
+	var names []string
+	var walk func(f index.Filter)
+	walk = func(f index.Filter) {
+		switch n := f.(type) {
+		case *andNode:
+			for _, sub := range n.SubNodes {
+				walk(sub)
+			}
+		case *orNode:
+			for _, sub := range n.SubNodes {
+				walk(sub)
+			}
+		case *not:
+			walk(n.Inner)
+		case *eq:
+			names = append(names, n.Key.Metadata.GetName())
+		case *match:
+			names = append(names, n.Key.Metadata.GetName())
+		case *rangeOp:
+			names = append(names, n.Key.Metadata.GetName())
+		}
+	}
+	walk(filter)
+	return names
+}
+
 func jsonToString(marshaler json.Marshaler) string {
 	bb, err := marshaler.MarshalJSON()
 	if err != nil {
@@ -24,6 +24,7 @@ import (
 	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
 	"github.com/apache/skywalking-banyandb/banyand/metadata"
 	"github.com/apache/skywalking-banyandb/banyand/tsdb"
+	"github.com/apache/skywalking-banyandb/pkg/query/executor"
 	"github.com/apache/skywalking-banyandb/pkg/query/logical"
 )
 
@@ -99,15 +100,11 @@ func (a *Analyzer) Analyze(_ context.Context, criteria *measurev1.QueryRequest,
 	}
 
 	if criteria.GetGroupBy() != nil {
-		plan = GroupBy(plan, groupByTags, groupByEntity)
+		plan = GroupBy(plan, groupByTags, groupByEntity, criteria.GetGroupBy().GetTimeBucket())
 	}
 
 	if criteria.GetAgg() != nil {
-		plan = Aggregation(plan,
-			logical.NewField(criteria.GetAgg().GetFieldName()),
-			criteria.GetAgg().GetFunction(),
-			criteria.GetGroupBy() != nil,
-		)
+		plan = Aggregation(plan, criteria.GetAgg(), criteria.GetGroupBy() != nil)
 	}
 
 	if criteria.GetTop() != nil {
@@ -124,6 +121,24 @@ func (a *Analyzer) Analyze(_ context.Context, criteria *measurev1.QueryRequest,
 	return plan.Analyze(s)
 }
 
+// Optimize rewrites the analyzed plan with the statistics of the shards.
+func (a *Analyzer) Optimize(plan logical.Plan, ec executor.MeasureExecutionContext) (logical.Plan, error) {
+	return logical.Optimize(plan, ec, selectIndex)
+}
+
+// selectIndex runs the most selective index rule of the scan first.
+func selectIndex(plan logical.Plan, ec executor.ExecutionContext) (logical.Plan, error) {
+	scan, ok := plan.(*localIndexScan)
+	if !ok {
+		return plan, nil
+	}
+	var err error
+	if scan.filter, err = logical.SelectIndex(ec, scan.entities, scan.filter); err != nil {
+		return nil, err
+	}
+	return scan, nil
+}
+
 // parseFields parses the query request to decide which kind of plan should be generated
 // Basically,
 // 1 - If no criteria is given, we can only scan all shards
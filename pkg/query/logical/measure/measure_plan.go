@@ -37,6 +37,7 @@ type limitPlan struct {
 }
 
 func (l *limitPlan) Execute(ec executor.MeasureExecutionContext) (executor.MIterator, error) {
+	defer executor.StatsOf(ec).Trace("Limit")()
 	dps, err := l.Parent.Input.(executor.MeasureExecutable).Execute(ec)
 	if err != nil {
 		return nil, err
@@ -34,20 +34,28 @@ var (
 	_ logical.Plan           = (*aggregationPlan)(nil)
 )
 
+var errPartialBuiltinFunc = errors.New("the partial state is returned by the custom aggregation functions only")
+
 type unresolvedAggregation struct {
 	unresolvedInput logical.UnresolvedPlan
 	// aggrFunc is the type of aggregation
 	aggrFunc modelv1.AggregationFunction
 	// groupBy should be a subset of tag projection
 	aggregationField *logical.Field
-	isGroup          bool
+	// customFunc is the name of the custom function used instead of aggrFunc if it's set
+	customFunc string
+	// partial returns the encoded states of the custom function instead of their results
+	partial bool
+	isGroup bool
 }
 
-func Aggregation(input logical.UnresolvedPlan, aggrField *logical.Field, aggrFunc modelv1.AggregationFunction, isGroup bool) logical.UnresolvedPlan {
+func Aggregation(input logical.UnresolvedPlan, agg *measurev1.QueryRequest_Aggregation, isGroup bool) logical.UnresolvedPlan {
 	return &unresolvedAggregation{
 		unresolvedInput:  input,
-		aggrFunc:         aggrFunc,
-		aggregationField: aggrField,
+		aggrFunc:         agg.GetFunction(),
+		aggregationField: logical.NewField(agg.GetFieldName()),
+		customFunc:       agg.GetCustomFunction(),
+		partial:          agg.GetPartialState(),
 		isGroup:          isGroup,
 	}
 }
@@ -65,7 +73,14 @@ func (gba *unresolvedAggregation) Analyze(measureSchema logical.Schema) (logical
 	if len(aggregationFieldRefs) == 0 {
 		return nil, errors.Wrap(logical.ErrFieldNotDefined, "aggregation schema")
 	}
-	aggrFunc, err := aggregation.NewInt64Func(gba.aggrFunc)
+	var aggrFunc aggregation.Int64Func
+	if gba.customFunc != "" {
+		aggrFunc, err = aggregation.NewAggregator(gba.customFunc)
+	} else if gba.partial {
+		err = errPartialBuiltinFunc
+	} else {
+		aggrFunc, err = aggregation.NewInt64Func(gba.aggrFunc)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -76,6 +91,9 @@ func (gba *unresolvedAggregation) Analyze(measureSchema logical.Schema) (logical
 		},
 		schema:              measureSchema,
 		aggrFunc:            aggrFunc,
+		aggrType:            gba.aggrFunc,
+		customFunc:          gba.customFunc,
+		partial:             gba.partial,
 		aggregationFieldRef: aggregationFieldRefs[0],
 		isGroup:             gba.isGroup,
 	}, nil
@@ -87,10 +105,17 @@ type aggregationPlan struct {
 	aggregationFieldRef *logical.FieldRef
 	aggrFunc            aggregation.Int64Func
 	aggrType            modelv1.AggregationFunction
+	customFunc          string
+	partial             bool
 	isGroup             bool
 }
 
 func (g *aggregationPlan) String() string {
+	if g.customFunc != "" {
+		return fmt.Sprintf("aggregation: aggregation{custom=%s,partial=%t,field=%s}",
+			g.customFunc, g.partial,
+			g.aggregationFieldRef.Field.Name)
+	}
 	return fmt.Sprintf("aggregation: aggregation{type=%d,field=%s}",
 		g.aggrType,
 		g.aggregationFieldRef.Field.Name)
@@ -105,31 +130,49 @@ func (g *aggregationPlan) Schema() logical.Schema {
 }
 
 func (g *aggregationPlan) Execute(ec executor.MeasureExecutionContext) (executor.MIterator, error) {
+	defer executor.StatsOf(ec).Trace("Aggregation")()
 	iter, err := g.Parent.Input.(executor.MeasureExecutable).Execute(ec)
 	if err != nil {
 		return nil, err
 	}
 	if g.isGroup {
-		return newAggGroupMIterator(iter, g.aggregationFieldRef, g.aggrFunc), nil
+		return newAggGroupMIterator(iter, g.aggregationFieldRef, g.aggrFunc, g.partial), nil
+	}
+	return newAggAllIterator(iter, g.aggregationFieldRef, g.aggrFunc, g.partial), nil
+}
+
+// aggregatedValue returns the result of the aggregation, or its encoded state if it's partial.
+func aggregatedValue(aggrFunc aggregation.Int64Func, partial bool) *modelv1.FieldValue {
+	if partial {
+		return &modelv1.FieldValue{Value: &modelv1.FieldValue_BinaryData{BinaryData: aggrFunc.(aggregation.Aggregator).State()}}
+	}
+	return &modelv1.FieldValue{
+		Value: &modelv1.FieldValue_Int{
+			Int: &modelv1.Int{
+				Value: aggrFunc.Val(),
+			},
+		},
 	}
-	return newAggAllIterator(iter, g.aggregationFieldRef, g.aggrFunc), nil
 }
 
 type aggGroupIterator struct {
 	prev                executor.MIterator
 	aggregationFieldRef *logical.FieldRef
 	aggrFunc            aggregation.Int64Func
+	partial             bool
 }
 
 func newAggGroupMIterator(
 	prev executor.MIterator,
 	aggregationFieldRef *logical.FieldRef,
 	aggrFunc aggregation.Int64Func,
+	partial bool,
 ) executor.MIterator {
 	return &aggGroupIterator{
 		prev:                prev,
 		aggregationFieldRef: aggregationFieldRef,
 		aggrFunc:            aggrFunc,
+		partial:             partial,
 	}
 }
 
@@ -151,6 +194,7 @@ func (ami *aggGroupIterator) Current() []*measurev1.DataPoint {
 			continue
 		}
 		resultDp = &measurev1.DataPoint{
+			Timestamp:   dp.Timestamp,
 			TagFamilies: dp.TagFamilies,
 		}
 	}
@@ -159,14 +203,8 @@ func (ami *aggGroupIterator) Current() []*measurev1.DataPoint {
 	}
 	resultDp.Fields = []*measurev1.DataPoint_Field{
 		{
-			Name: ami.aggregationFieldRef.Field.Name,
-			Value: &modelv1.FieldValue{
-				Value: &modelv1.FieldValue_Int{
-					Int: &modelv1.Int{
-						Value: ami.aggrFunc.Val(),
-					},
-				},
-			},
+			Name:  ami.aggregationFieldRef.Field.Name,
+			Value: aggregatedValue(ami.aggrFunc, ami.partial),
 		},
 	}
 	return []*measurev1.DataPoint{resultDp}
@@ -180,6 +218,7 @@ type aggAllIterator struct {
 	prev                executor.MIterator
 	aggregationFieldRef *logical.FieldRef
 	aggrFunc            aggregation.Int64Func
+	partial             bool
 
 	result *measurev1.DataPoint
 }
@@ -188,11 +227,13 @@ func newAggAllIterator(
 	prev executor.MIterator,
 	aggregationFieldRef *logical.FieldRef,
 	aggrFunc aggregation.Int64Func,
+	partial bool,
 ) executor.MIterator {
 	return &aggAllIterator{
 		prev:                prev,
 		aggregationFieldRef: aggregationFieldRef,
 		aggrFunc:            aggrFunc,
+		partial:             partial,
 	}
 }
 
@@ -223,14 +264,8 @@ func (ami *aggAllIterator) Next() bool {
 	}
 	resultDp.Fields = []*measurev1.DataPoint_Field{
 		{
-			Name: ami.aggregationFieldRef.Field.Name,
-			Value: &modelv1.FieldValue{
-				Value: &modelv1.FieldValue_Int{
-					Int: &modelv1.Int{
-						Value: ami.aggrFunc.Val(),
-					},
-				},
-			},
+			Name:  ami.aggregationFieldRef.Field.Name,
+			Value: aggregatedValue(ami.aggrFunc, ami.partial),
 		},
 	}
 	ami.result = resultDp
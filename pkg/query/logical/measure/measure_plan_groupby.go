@@ -22,6 +22,7 @@ import (
 
 	"github.com/cespare/xxhash"
 	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
 	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
@@ -39,14 +40,19 @@ type unresolvedGroup struct {
 	unresolvedInput logical.UnresolvedPlan
 	// groupBy should be a subset of tag projection
 	groupBy       [][]*logical.Tag
+	timeBucket    *measurev1.QueryRequest_GroupBy_TimeBucket
 	groupByEntity bool
 }
 
-func GroupBy(input logical.UnresolvedPlan, groupBy [][]*logical.Tag, groupByEntity bool) logical.UnresolvedPlan {
+// GroupBy groups the data points by the tags, and by the buckets of their timestamps if timeBucket isn't nil.
+func GroupBy(input logical.UnresolvedPlan, groupBy [][]*logical.Tag, groupByEntity bool,
+	timeBucket *measurev1.QueryRequest_GroupBy_TimeBucket,
+) logical.UnresolvedPlan {
 	return &unresolvedGroup{
 		unresolvedInput: input,
 		groupBy:         groupBy,
 		groupByEntity:   groupByEntity,
+		timeBucket:      timeBucket,
 	}
 }
 
@@ -60,6 +66,10 @@ func (gba *unresolvedGroup) Analyze(measureSchema logical.Schema) (logical.Plan,
 	if err != nil {
 		return nil, err
 	}
+	bucket, err := newTimeBucket(gba.timeBucket)
+	if err != nil {
+		return nil, err
+	}
 	return &groupBy{
 		Parent: &logical.Parent{
 			UnresolvedInput: gba.unresolvedInput,
@@ -68,6 +78,7 @@ func (gba *unresolvedGroup) Analyze(measureSchema logical.Schema) (logical.Plan,
 		schema:          measureSchema,
 		groupByTagsRefs: groupByTagRefs,
 		groupByEntity:   gba.groupByEntity,
+		bucket:          bucket,
 	}, nil
 }
 
@@ -75,16 +86,21 @@ type groupBy struct {
 	*logical.Parent
 	schema          logical.Schema
 	groupByTagsRefs [][]*logical.TagRef
+	bucket          *timeBucket
 	groupByEntity   bool
 }
 
 func (g *groupBy) String() string {
 	var method string
-	if g.groupByEntity {
+	if g.sorted() {
 		method = "sort"
 	} else {
 		method = "hash"
 	}
+	if g.bucket != nil {
+		return fmt.Sprintf("GroupBy: groupBy=%s, timeBucket=%s, method=%s",
+			logical.FormatTagRefs(", ", g.groupByTagsRefs...), g.bucket, method)
+	}
 	return fmt.Sprintf("GroupBy: groupBy=%s, method=%s",
 		logical.FormatTagRefs(", ", g.groupByTagsRefs...), method)
 }
@@ -98,12 +114,19 @@ func (g *groupBy) Schema() logical.Schema {
 }
 
 func (g *groupBy) Execute(ec executor.MeasureExecutionContext) (executor.MIterator, error) {
-	if g.groupByEntity {
+	defer executor.StatsOf(ec).Trace("GroupBy")()
+	if g.sorted() {
 		return g.sort(ec)
 	}
 	return g.hash(ec)
 }
 
+// sorted returns true if the data points are grouped by the entity, which are sorted by their series.
+// The buckets of a series might interleave with the other series', so the time buckets are always hashed.
+func (g *groupBy) sorted() bool {
+	return g.groupByEntity && g.bucket == nil
+}
+
 func (g *groupBy) sort(ec executor.MeasureExecutionContext) (executor.MIterator, error) {
 	iter, err := g.Parent.Input.(executor.MeasureExecutable).Execute(ec)
 	if err != nil {
@@ -122,7 +145,11 @@ func (g *groupBy) hash(ec executor.MeasureExecutionContext) (executor.MIterator,
 	for iter.Next() {
 		dataPoints := iter.Current()
 		for _, dp := range dataPoints {
-			key, innerErr := formatGroupByKey(dp, g.groupByTagsRefs)
+			if g.bucket != nil {
+				// the data points are stamped with the beginning of their buckets
+				dp.Timestamp = timestamppb.New(g.bucket.start(dp.GetTimestamp().AsTime()))
+			}
+			key, innerErr := formatGroupByKey(dp, g.groupByTagsRefs, g.bucket != nil)
 			if innerErr != nil {
 				return nil, innerErr
 			}
@@ -144,8 +171,13 @@ func (g *groupBy) hash(ec executor.MeasureExecutionContext) (executor.MIterator,
 	return newGroupIterator(groupMap, groupLst), nil
 }
 
-func formatGroupByKey(point *measurev1.DataPoint, groupByTagsRefs [][]*logical.TagRef) (uint64, error) {
+func formatGroupByKey(point *measurev1.DataPoint, groupByTagsRefs [][]*logical.TagRef, bucketed bool) (uint64, error) {
 	hash := xxhash.New()
+	if bucketed {
+		if _, err := hash.Write(convert.Int64ToBytes(point.GetTimestamp().AsTime().UnixNano())); err != nil {
+			return 0, err
+		}
+	}
 	for _, tagFamilyRef := range groupByTagsRefs {
 		for _, tagRef := range tagFamilyRef {
 			tag := point.GetTagFamilies()[tagRef.Spec.TagFamilyIdx].GetTags()[tagRef.Spec.TagIdx]
@@ -236,7 +268,7 @@ func (gmi *groupSortIterator) Next() bool {
 			gmi.closed = true
 			return len(gmi.current) > 0
 		}
-		k, err := formatGroupByKey(dp, gmi.groupByTagsRefs)
+		k, err := formatGroupByKey(dp, gmi.groupByTagsRefs, false)
 		if err != nil {
 			gmi.closed = true
 			gmi.err = err
@@ -101,19 +101,10 @@ type localIndexScan struct {
 }
 
 func (i *localIndexScan) Execute(ec executor.MeasureExecutionContext) (executor.MIterator, error) {
-	var seriesList tsdb.SeriesList
-	for _, e := range i.entities {
-		shards, err := ec.Shards(e)
-		if err != nil {
-			return nil, err
-		}
-		for _, shard := range shards {
-			sl, err := shard.Series().List(tsdb.NewPath(e))
-			if err != nil {
-				return nil, err
-			}
-			seriesList = seriesList.Merge(sl)
-		}
+	defer executor.StatsOf(ec).Trace("IndexScan")()
+	seriesList, err := logical.ListSeries(ec, i.entities)
+	if err != nil {
+		return nil, err
 	}
 	if len(seriesList) == 0 {
 		return executor.EmptyMIterator, nil
@@ -133,7 +124,7 @@ func (i *localIndexScan) Execute(ec executor.MeasureExecutionContext) (executor.
 			b.Filter(i.filter)
 		})
 	}
-	iters, closers, innerErr := logical.ExecuteForShard(seriesList, i.timeRange, builders...)
+	iters, closers, innerErr := logical.ExecuteForShard(ec, seriesList, i.timeRange, builders...)
 	if len(closers) > 0 {
 		defer func(closers []io.Closer) {
 			for _, c := range closers {
@@ -144,6 +135,8 @@ func (i *localIndexScan) Execute(ec executor.MeasureExecutionContext) (executor.
 	if innerErr != nil {
 		return nil, innerErr
 	}
+	stats := executor.StatsOf(ec)
+	stats.AddIndexes(i.indexes()...)
 
 	if len(iters) == 0 {
 		return executor.EmptyMIterator, nil
@@ -167,6 +160,30 @@ func (i *localIndexScan) String() string {
 		i.filter, logical.FormatTagRefs(", ", i.projectionTagsRefs...))
 }
 
+func (i *localIndexScan) Estimate(ec executor.ExecutionContext) (logical.Estimation, error) {
+	seriesList, err := logical.ListSeries(ec, i.entities)
+	if err != nil {
+		return logical.Estimation{}, err
+	}
+	blocks, err := logical.EstimateForShard(seriesList, i.timeRange)
+	if err != nil {
+		return logical.Estimation{}, err
+	}
+	return logical.Estimation{
+		Indexes: i.indexes(),
+		Series:  len(seriesList),
+		Blocks:  blocks,
+	}, nil
+}
+
+func (i *localIndexScan) indexes() []string {
+	var names []string
+	if i.Index != nil {
+		names = append(names, i.Index.GetMetadata().GetName())
+	}
+	return append(names, logical.IndexRuleNames(i.filter)...)
+}
+
 func (i *localIndexScan) Children() []logical.Plan {
 	return []logical.Plan{}
 }
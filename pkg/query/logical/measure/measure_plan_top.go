@@ -88,6 +88,7 @@ func (g *top) Schema() logical.Schema {
 }
 
 func (g *top) Execute(ec executor.MeasureExecutionContext) (executor.MIterator, error) {
+	defer executor.StatsOf(ec).Trace("Top")()
 	iter, err := g.Parent.Input.(executor.MeasureExecutable).Execute(ec)
 	if err != nil {
 		return nil, err
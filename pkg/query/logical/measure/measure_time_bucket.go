@@ -0,0 +1,88 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+)
+
+var errInvalidTimeBucket = errors.New("the interval of the time bucket should be positive")
+
+// offsetLayouts are the layouts of the fixed offsets of the time zones, e.g. +08:00, +0800 and +08.
+var offsetLayouts = []string{"-07:00", "-0700", "-07"}
+
+// timeBucket assigns the timestamps to the buckets aligned to the local time of a time zone,
+// so that the daily buckets begin at the local midnight.
+type timeBucket struct {
+	loc      *time.Location
+	interval time.Duration
+}
+
+func newTimeBucket(bucket *measurev1.QueryRequest_GroupBy_TimeBucket) (*timeBucket, error) {
+	if bucket == nil {
+		return nil, nil
+	}
+	interval := bucket.GetInterval().AsDuration()
+	if interval <= 0 {
+		return nil, errInvalidTimeBucket
+	}
+	loc, err := parseTimeZone(bucket.GetTimeZone())
+	if err != nil {
+		return nil, err
+	}
+	return &timeBucket{loc: loc, interval: interval}, nil
+}
+
+// parseTimeZone parses an IANA name or a fixed offset, which is UTC if it's empty.
+func parseTimeZone(zone string) (*time.Location, error) {
+	if zone == "" {
+		return time.UTC, nil
+	}
+	if loc, err := time.LoadLocation(zone); err == nil {
+		return loc, nil
+	}
+	for _, layout := range offsetLayouts {
+		if t, err := time.Parse(layout, zone); err == nil {
+			_, offset := t.Zone()
+			return time.FixedZone(zone, offset), nil
+		}
+	}
+	return nil, fmt.Errorf("invalid time zone %q", zone)
+}
+
+// start returns the beginning of the bucket of t. The buckets are aligned to the local wall clock,
+// whose beginnings are converted back by the offset in effect at that time, e.g. across a daylight saving change.
+func (b *timeBucket) start(t time.Time) time.Time {
+	_, offset := t.In(b.loc).Zone()
+	local := t.UnixNano() + int64(offset)*int64(time.Second)
+	rem := local % int64(b.interval)
+	if rem < 0 {
+		rem += int64(b.interval)
+	}
+	wall := time.Unix(0, local-rem).UTC()
+	return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), wall.Nanosecond(), b.loc)
+}
+
+func (b *timeBucket) String() string {
+	return fmt.Sprintf("%s in %s", b.interval, b.loc)
+}
@@ -0,0 +1,99 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package measure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+)
+
+func TestTimeBucket(t *testing.T) {
+	tests := []struct {
+		name     string
+		zone     string
+		at       string
+		want     string
+		interval time.Duration
+	}{
+		{
+			name:     "daily buckets in UTC",
+			interval: 24 * time.Hour,
+			at:       "2022-10-15T02:30:00Z",
+			want:     "2022-10-15T00:00:00Z",
+		},
+		{
+			name:     "daily buckets begin at the local midnight",
+			zone:     "Asia/Shanghai",
+			interval: 24 * time.Hour,
+			at:       "2022-10-15T02:30:00Z",
+			want:     "2022-10-15T00:00:00+08:00",
+		},
+		{
+			name:     "daily buckets of a fixed offset",
+			zone:     "-05:00",
+			interval: 24 * time.Hour,
+			at:       "2022-10-15T02:30:00Z",
+			want:     "2022-10-14T00:00:00-05:00",
+		},
+		{
+			name:     "hourly buckets of a half-hour offset",
+			zone:     "+0530",
+			interval: time.Hour,
+			at:       "2022-10-15T02:10:00Z",
+			want:     "2022-10-15T07:00:00+05:30",
+		},
+		{
+			name:     "daily buckets across the daylight saving change",
+			zone:     "America/New_York",
+			interval: 24 * time.Hour,
+			at:       "2022-11-06T12:00:00Z",
+			want:     "2022-11-06T00:00:00-04:00",
+		},
+		{
+			name:     "buckets before the epoch",
+			interval: time.Hour,
+			at:       "1969-12-31T23:30:00Z",
+			want:     "1969-12-31T23:00:00Z",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := newTimeBucket(&measurev1.QueryRequest_GroupBy_TimeBucket{Interval: durationpb.New(tt.interval), TimeZone: tt.zone})
+			require.NoError(t, err)
+			at, err := time.Parse(time.RFC3339, tt.at)
+			require.NoError(t, err)
+			want, err := time.Parse(time.RFC3339, tt.want)
+			require.NoError(t, err)
+			got := b.start(at)
+			assert.True(t, want.Equal(got), "want %s, got %s", want, got)
+		})
+	}
+}
+
+func TestInvalidTimeBucket(t *testing.T) {
+	_, err := newTimeBucket(&measurev1.QueryRequest_GroupBy_TimeBucket{})
+	assert.ErrorIs(t, err, errInvalidTimeBucket)
+	_, err = newTimeBucket(&measurev1.QueryRequest_GroupBy_TimeBucket{Interval: durationpb.New(time.Hour), TimeZone: "Mars/Olympus"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,166 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package logical
+
+import (
+	"sort"
+
+	"github.com/apache/skywalking-banyandb/banyand/tsdb"
+	"github.com/apache/skywalking-banyandb/pkg/index"
+	"github.com/apache/skywalking-banyandb/pkg/query/executor"
+)
+
+const (
+	// the selectivity of a condition whose index rule has no statistics yet
+	defaultEqSelectivity    = 0.1
+	defaultMatchSelectivity = 0.1
+	defaultRangeSelectivity = 1.0 / 3
+)
+
+// OptimizeRule rewrites a plan node. It returns the node itself if the rule doesn't apply.
+type OptimizeRule func(plan Plan, ec executor.ExecutionContext) (Plan, error)
+
+type parentPlan interface {
+	parent() *Parent
+}
+
+func (p *Parent) parent() *Parent {
+	return p
+}
+
+// Optimize applies the rules to the plan tree from the bottom up.
+// The plans embedding Parent are traversed, and their inputs are replaced by the rewritten ones.
+func Optimize(plan Plan, ec executor.ExecutionContext, rules ...OptimizeRule) (Plan, error) {
+	if p, ok := plan.(parentPlan); ok && p.parent().Input != nil {
+		input, err := Optimize(p.parent().Input, ec, rules...)
+		if err != nil {
+			return nil, err
+		}
+		p.parent().Input = input
+	}
+	var err error
+	for _, rule := range rules {
+		if plan, err = rule(plan, ec); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+// EliminateRedundantOffset removes the Offset skipping nothing.
+func EliminateRedundantOffset(plan Plan, _ executor.ExecutionContext) (Plan, error) {
+	if o, ok := plan.(*Offset); ok && o.offsetNum == 0 {
+		return o.Input, nil
+	}
+	return plan, nil
+}
+
+// TermsCounter returns the number of the distinct terms of an index rule, 0 means it's unknown.
+type TermsCounter func(indexRuleID uint32) int
+
+// CardinalityOf returns the statistics of the index rules in the shards holding the entities.
+// The largest one among the shards is picked since an index rule has at least such many terms.
+func CardinalityOf(ec executor.ExecutionContext, entities []tsdb.Entity) (TermsCounter, error) {
+	var cardinalities []*index.Cardinality
+	for _, e := range entities {
+		shards, err := ec.Shards(e)
+		if err != nil {
+			return nil, err
+		}
+		for _, shard := range shards {
+			cardinalities = append(cardinalities, shard.Cardinality())
+		}
+	}
+	return func(indexRuleID uint32) int {
+		var terms int
+		for _, c := range cardinalities {
+			if n := c.Terms(indexRuleID); n > terms {
+				terms = n
+			}
+		}
+		return terms
+	}, nil
+}
+
+// Selectivity estimates the fraction of the items matching the filter.
+// An equality matches 1/terms of the items, assuming the terms are evenly distributed.
+func Selectivity(filter index.Filter, terms TermsCounter) float64 {
+	switch n := filter.(type) {
+	case *andNode:
+		s := 1.0
+		for _, sub := range n.SubNodes {
+			s *= Selectivity(sub, terms)
+		}
+		return s
+	case *orNode:
+		s := 0.0
+		for _, sub := range n.SubNodes {
+			s += Selectivity(sub, terms)
+		}
+		if s > 1 {
+			return 1
+		}
+		return s
+	case *not:
+		return 1 - Selectivity(n.Inner, terms)
+	case *eq:
+		if t := terms(n.Key.Metadata.GetId()); t > 0 {
+			return 1 / float64(t)
+		}
+		return defaultEqSelectivity
+	case *match:
+		return defaultMatchSelectivity
+	case *rangeOp:
+		return defaultRangeSelectivity
+	}
+	// the conditions without indexes match everything
+	return 1
+}
+
+// OrderBySelectivity sorts the conditions of the AND nodes by their selectivity,
+// so that the most selective index rule is searched first and the rest are skipped once nothing matches.
+func OrderBySelectivity(filter index.Filter, terms TermsCounter) index.Filter {
+	switch n := filter.(type) {
+	case *andNode:
+		for i, sub := range n.SubNodes {
+			n.SubNodes[i] = OrderBySelectivity(sub, terms)
+		}
+		sort.SliceStable(n.SubNodes, func(i, j int) bool {
+			return Selectivity(n.SubNodes[i], terms) < Selectivity(n.SubNodes[j], terms)
+		})
+	case *orNode:
+		for i, sub := range n.SubNodes {
+			n.SubNodes[i] = OrderBySelectivity(sub, terms)
+		}
+	case *not:
+		n.Inner = OrderBySelectivity(n.Inner, terms)
+	}
+	return filter
+}
+
+// SelectIndex orders the index rules of the filter by the cardinality of the shards holding the entities.
+func SelectIndex(ec executor.ExecutionContext, entities []tsdb.Entity, filter index.Filter) (index.Filter, error) {
+	if filter == nil {
+		return nil, nil
+	}
+	terms, err := CardinalityOf(ec, entities)
+	if err != nil {
+		return nil, err
+	}
+	return OrderBySelectivity(filter, terms), nil
+}
@@ -0,0 +1,106 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package logical
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/pkg/index"
+	"github.com/apache/skywalking-banyandb/pkg/index/posting"
+	"github.com/apache/skywalking-banyandb/pkg/index/posting/roaring"
+)
+
+func indexRule(id uint32, name string) *databasev1.IndexRule {
+	return &databasev1.IndexRule{
+		Metadata: &commonv1.Metadata{Id: id, Name: name},
+		Type:     databasev1.IndexRule_TYPE_INVERTED,
+	}
+}
+
+func TestOrderBySelectivity(t *testing.T) {
+	terms := func(indexRuleID uint32) int {
+		switch indexRuleID {
+		case 1:
+			return 2
+		case 2:
+			return 100
+		}
+		return 0
+	}
+	and := newAnd(3)
+	and.append(newEq(indexRule(1, "status"), Str("ok"))).
+		append(newRange(indexRule(3, "duration"), index.RangeOpts{})).
+		append(newEq(indexRule(2, "endpoint"), Str("/home")))
+
+	assert.InDelta(t, 1.0/2*1.0/3*1.0/100, Selectivity(and, terms), 1e-9)
+	OrderBySelectivity(and, terms)
+	assert.Equal(t, []string{"endpoint", "duration", "status"}, IndexRuleNames(and))
+}
+
+type mockSearcher struct {
+	index.Searcher
+	searched []string
+}
+
+func (m *mockSearcher) MatchTerms(field index.Field) (posting.List, error) {
+	m.searched = append(m.searched, string(field.Term))
+	if string(field.Term) == "broken" {
+		return nil, errors.New("should be skipped")
+	}
+	return roaring.NewPostingList(), nil
+}
+
+func TestAndShortCircuit(t *testing.T) {
+	s := &mockSearcher{}
+	and := newAnd(2)
+	and.append(newEq(indexRule(1, "endpoint"), Str("/home"))).
+		append(newEq(indexRule(2, "status"), Str("broken")))
+	list, err := and.Execute(func(databasev1.IndexRule_Type) (index.Searcher, error) {
+		return s, nil
+	}, common.SeriesID(1))
+	require.NoError(t, err)
+	assert.True(t, list.IsEmpty())
+	assert.Equal(t, []string{"/home"}, s.searched)
+}
+
+type mockPlan struct {
+	Plan
+}
+
+func TestEliminateRedundantOffset(t *testing.T) {
+	scan := &mockPlan{}
+	limit := &Limit{
+		Parent: &Parent{Input: &Offset{Parent: &Parent{Input: scan}}},
+	}
+	plan, err := Optimize(limit, nil, EliminateRedundantOffset)
+	require.NoError(t, err)
+	assert.Same(t, limit, plan)
+	assert.Same(t, scan, limit.Input)
+
+	offset := &Offset{Parent: &Parent{Input: scan}, offsetNum: 1}
+	plan, err = Optimize(offset, nil, EliminateRedundantOffset)
+	require.NoError(t, err)
+	assert.Same(t, offset, plan)
+}
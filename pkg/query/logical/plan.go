@@ -40,6 +40,7 @@ type Limit struct {
 }
 
 func (l *Limit) Execute(ec executor.StreamExecutionContext) ([]*streamv1.Element, error) {
+	defer executor.StatsOf(ec).Trace("Limit")()
 	entities, err := l.Parent.Input.(executor.StreamExecutable).Execute(ec)
 	if err != nil {
 		return nil, err
@@ -52,6 +53,17 @@ func (l *Limit) Execute(ec executor.StreamExecutionContext) ([]*streamv1.Element
 	return entities, nil
 }
 
+// Iterate stops reading the elements of its input once the limit is reached.
+func (l *Limit) Iterate(ec executor.StreamExecutionContext) (executor.SIterator, error) {
+	stop := executor.StatsOf(ec).Trace("Limit")
+	it, err := executor.IterateStream(l.Parent.Input.(executor.StreamExecutable), ec)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	return &limitIterator{SIterator: it, stop: stop, remaining: l.LimitNum}, nil
+}
+
 func (l *Limit) Analyze(s Schema) (Plan, error) {
 	var err error
 	l.Input, err = l.UnresolvedInput.Analyze(s)
@@ -93,6 +105,7 @@ type Offset struct {
 }
 
 func (l *Offset) Execute(ec executor.StreamExecutionContext) ([]*streamv1.Element, error) {
+	defer executor.StatsOf(ec).Trace("Offset")()
 	elements, err := l.Parent.Input.(executor.StreamExecutable).Execute(ec)
 	if err != nil {
 		return nil, err
@@ -105,6 +118,17 @@ func (l *Offset) Execute(ec executor.StreamExecutionContext) ([]*streamv1.Elemen
 	return []*streamv1.Element{}, nil
 }
 
+// Iterate skips the elements of its input before the offset.
+func (l *Offset) Iterate(ec executor.StreamExecutionContext) (executor.SIterator, error) {
+	stop := executor.StatsOf(ec).Trace("Offset")
+	it, err := executor.IterateStream(l.Parent.Input.(executor.StreamExecutable), ec)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	return &offsetIterator{SIterator: it, stop: stop, skip: l.offsetNum}, nil
+}
+
 func (l *Offset) Analyze(s Schema) (Plan, error) {
 	var err error
 	l.Input, err = l.UnresolvedInput.Analyze(s)
@@ -134,3 +158,42 @@ func NewOffset(input UnresolvedPlan, num uint32) UnresolvedPlan {
 		offsetNum: num,
 	}
 }
+
+type limitIterator struct {
+	executor.SIterator
+	stop      func()
+	remaining uint32
+}
+
+func (li *limitIterator) Next() bool {
+	if li.remaining == 0 {
+		return false
+	}
+	li.remaining--
+	return li.SIterator.Next()
+}
+
+func (li *limitIterator) Close() error {
+	defer li.stop()
+	return li.SIterator.Close()
+}
+
+type offsetIterator struct {
+	executor.SIterator
+	stop func()
+	skip uint32
+}
+
+func (oi *offsetIterator) Next() bool {
+	for ; oi.skip > 0; oi.skip-- {
+		if !oi.SIterator.Next() {
+			return false
+		}
+	}
+	return oi.SIterator.Next()
+}
+
+func (oi *offsetIterator) Close() error {
+	defer oi.stop()
+	return oi.SIterator.Close()
+}
@@ -0,0 +1,86 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package logical
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
+	"github.com/apache/skywalking-banyandb/pkg/query/executor"
+)
+
+type countingIterator struct {
+	elements []*streamv1.Element
+	read     int
+	closed   bool
+}
+
+func (c *countingIterator) Next() bool {
+	if c.read >= len(c.elements) {
+		return false
+	}
+	c.read++
+	return true
+}
+
+func (c *countingIterator) Current() *streamv1.Element {
+	return c.elements[c.read-1]
+}
+
+func (c *countingIterator) Close() error {
+	c.closed = true
+	return nil
+}
+
+type iterablePlan struct {
+	Plan
+	it *countingIterator
+}
+
+func (p *iterablePlan) Execute(executor.StreamExecutionContext) ([]*streamv1.Element, error) {
+	return p.it.elements, nil
+}
+
+func (p *iterablePlan) Iterate(executor.StreamExecutionContext) (executor.SIterator, error) {
+	return p.it, nil
+}
+
+func TestLimitStopsReadingItsInput(t *testing.T) {
+	it := &countingIterator{}
+	for i := 0; i < 10; i++ {
+		it.elements = append(it.elements, &streamv1.Element{ElementId: strconv.Itoa(i)})
+	}
+	offset := &Offset{Parent: &Parent{Input: &iterablePlan{it: it}}, offsetNum: 2}
+	limit := &Limit{Parent: &Parent{Input: offset}, LimitNum: 3}
+
+	iter, err := executor.IterateStream(limit, nil)
+	require.NoError(t, err)
+	elements, err := executor.CollectStream(iter)
+	require.NoError(t, err)
+	ids := make([]string, 0, len(elements))
+	for _, e := range elements {
+		ids = append(ids, e.GetElementId())
+	}
+	assert.Equal(t, []string{"2", "3", "4"}, ids)
+	assert.Equal(t, 5, it.read)
+	assert.True(t, it.closed)
+}
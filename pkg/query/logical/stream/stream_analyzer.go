@@ -23,6 +23,7 @@ import (
 	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
 	streamv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
 	"github.com/apache/skywalking-banyandb/banyand/metadata"
+	"github.com/apache/skywalking-banyandb/pkg/query/executor"
 	"github.com/apache/skywalking-banyandb/pkg/query/logical"
 )
 
@@ -99,6 +100,39 @@ func (a *Analyzer) Analyze(_ context.Context, criteria *streamv1.QueryRequest, m
 	return plan.Analyze(s)
 }
 
+// Optimize rewrites the analyzed plan with the statistics of the shards.
+func (a *Analyzer) Optimize(plan logical.Plan, ec executor.StreamExecutionContext) (logical.Plan, error) {
+	return logical.Optimize(plan, ec, selectIndex, pushDownTagFilter, logical.EliminateRedundantOffset)
+}
+
+// selectIndex runs the most selective index rule of the scan first.
+func selectIndex(plan logical.Plan, ec executor.ExecutionContext) (logical.Plan, error) {
+	scan, ok := plan.(*localIndexScan)
+	if !ok {
+		return plan, nil
+	}
+	var err error
+	if scan.filter, err = logical.SelectIndex(ec, scan.entities, scan.filter); err != nil {
+		return nil, err
+	}
+	return scan, nil
+}
+
+// pushDownTagFilter merges the tag filter into the local index scan below it,
+// so that the mismatched elements are dropped while scanning instead of being collected.
+func pushDownTagFilter(plan logical.Plan, _ executor.ExecutionContext) (logical.Plan, error) {
+	tf, ok := plan.(*tagFilterPlan)
+	if !ok {
+		return plan, nil
+	}
+	scan, ok := tf.Input.(*localIndexScan)
+	if !ok || scan.tagFilter != nil {
+		return plan, nil
+	}
+	scan.tagFilter = tf.tagFilter
+	return scan, nil
+}
+
 // parseTags parses the query request to decide which kind of plan should be generated
 // Basically,
 // 1 - If no criteria is given, we can only scan all shards
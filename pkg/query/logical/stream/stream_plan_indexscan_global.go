@@ -51,6 +51,13 @@ func (t *globalIndexScan) String() string {
 		t.expr.String(), logical.FormatTagRefs(", ", t.projectionTagRefs...))
 }
 
+// Estimate doesn't count the series and blocks, which are known only after looking up the global index.
+func (t *globalIndexScan) Estimate(_ executor.ExecutionContext) (logical.Estimation, error) {
+	return logical.Estimation{
+		Indexes: []string{t.globalIndexRule.GetMetadata().GetName()},
+	}, nil
+}
+
 func (t *globalIndexScan) Children() []logical.Plan {
 	return []logical.Plan{}
 }
@@ -60,16 +67,21 @@ func (t *globalIndexScan) Schema() logical.Schema {
 }
 
 func (t *globalIndexScan) Execute(ec executor.StreamExecutionContext) ([]*streamv1.Element, error) {
+	defer executor.StatsOf(ec).Trace("GlobalIndexScan")()
 	shards, err := ec.Shards(nil)
 	if err != nil {
 		return nil, err
 	}
+	executor.StatsOf(ec).AddIndexes(t.globalIndexRule.GetMetadata().GetName())
+	elementsInShards := make([][]*streamv1.Element, len(shards))
+	if err = executor.SchedulerOf(ec).Run(len(shards), func(i int) (shardErr error) {
+		elementsInShards[i], shardErr = t.executeForShard(ec, shards[i])
+		return shardErr
+	}); err != nil {
+		return nil, err
+	}
 	var elements []*streamv1.Element
-	for _, shard := range shards {
-		elementsInShard, shardErr := t.executeForShard(ec, shard)
-		if shardErr != nil {
-			return elements, shardErr
-		}
+	for _, elementsInShard := range elementsInShards {
 		elements = append(elements, elementsInShard...)
 	}
 	return elements, nil
@@ -87,7 +99,15 @@ func (t *globalIndexScan) executeForShard(ec executor.StreamExecutionContext, sh
 	if err != nil || len(itemIDs) < 1 {
 		return elementsInShard, nil
 	}
+	// every item is fetched from its own series
+	stats := executor.StatsOf(ec)
+	if err = stats.AddScanned(len(itemIDs), len(itemIDs)); err != nil {
+		return nil, err
+	}
 	for _, itemID := range itemIDs {
+		if err = stats.Check(); err != nil {
+			return nil, err
+		}
 		segShard, err := ec.Shard(itemID.ShardID)
 		if err != nil {
 			return elementsInShard, errors.WithStack(err)
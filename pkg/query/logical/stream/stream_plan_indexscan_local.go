@@ -43,25 +43,29 @@ type localIndexScan struct {
 	projectionTagRefs [][]*logical.TagRef
 	entities          []tsdb.Entity
 	filter            index.Filter
+	// tagFilter is pushed down from the tagFilterPlan to filter the elements while scanning
+	tagFilter logical.TagFilter
 }
 
 func (i *localIndexScan) Execute(ec executor.StreamExecutionContext) ([]*streamv1.Element, error) {
-	var seriesList tsdb.SeriesList
-	for _, e := range i.entities {
-		shards, err := ec.Shards(e)
-		if err != nil {
-			return nil, err
-		}
-		for _, shard := range shards {
-			sl, err := shard.Series().List(tsdb.NewPath(e))
-			if err != nil {
-				return nil, err
-			}
-			seriesList = seriesList.Merge(sl)
-		}
+	it, err := i.Iterate(ec)
+	if err != nil {
+		return nil, err
+	}
+	return executor.CollectStream(it)
+}
+
+// Iterate reads the elements of the series as they are iterated, and the series are held until the iterator is closed.
+func (i *localIndexScan) Iterate(ec executor.StreamExecutionContext) (executor.SIterator, error) {
+	stop := executor.StatsOf(ec).Trace("IndexScan")
+	seriesList, err := logical.ListSeries(ec, i.entities)
+	if err != nil {
+		stop()
+		return nil, err
 	}
 	if len(seriesList) == 0 {
-		return nil, nil
+		stop()
+		return executor.EmptySIterator, nil
 	}
 	var builders []logical.SeekerBuilder
 	if i.Index != nil {
@@ -78,49 +82,58 @@ func (i *localIndexScan) Execute(ec executor.StreamExecutionContext) ([]*streamv
 			b.Filter(i.filter)
 		})
 	}
-	iters, closers, innerErr := logical.ExecuteForShard(seriesList, i.timeRange, builders...)
-	if len(closers) > 0 {
-		defer func(closers []io.Closer) {
-			for _, c := range closers {
-				_ = c.Close()
-			}
-		}(closers)
+	iters, closers, innerErr := logical.ExecuteForShard(ec, seriesList, i.timeRange, builders...)
+	it := &indexScanIterator{
+		ec:      ec,
+		scan:    i,
+		closers: closers,
+		stop:    stop,
 	}
 	if innerErr != nil {
+		_ = it.Close()
 		return nil, innerErr
 	}
-
-	var elems []*streamv1.Element
-
+	executor.StatsOf(ec).AddIndexes(i.indexes()...)
 	if len(iters) == 0 {
-		return elems, nil
+		_ = it.Close()
+		return executor.EmptySIterator, nil
 	}
-
-	c := logical.CreateComparator(i.Sort)
-	it := logical.NewItemIter(iters, c)
-	for it.HasNext() {
-		nextItem := it.Next()
-		tagFamilies, innerErr := logical.ProjectItem(ec, nextItem, i.projectionTagRefs)
-		if innerErr != nil {
-			return nil, innerErr
-		}
-		elementID, innerErr := ec.ParseElementID(nextItem)
-		if innerErr != nil {
-			return nil, innerErr
-		}
-		elems = append(elems, &streamv1.Element{
-			ElementId:   elementID,
-			Timestamp:   timestamppb.New(time.Unix(0, int64(nextItem.Time()))),
-			TagFamilies: tagFamilies,
-		})
-	}
-	return elems, nil
+	it.inner = logical.NewItemIter(iters, logical.CreateComparator(i.Sort))
+	return it, nil
 }
 
 func (i *localIndexScan) String() string {
-	return fmt.Sprintf("IndexScan: startTime=%d,endTime=%d,Metadata{group=%s,name=%s},conditions=%s; projection=%s; orderBy;%s",
+	str := fmt.Sprintf("IndexScan: startTime=%d,endTime=%d,Metadata{group=%s,name=%s},conditions=%s; projection=%s; orderBy;%s",
 		i.timeRange.Start.Unix(), i.timeRange.End.Unix(), i.metadata.GetGroup(), i.metadata.GetName(),
 		i.filter, logical.FormatTagRefs(", ", i.projectionTagRefs...), i.OrderBy)
+	if i.tagFilter != nil {
+		str += "; tag-filter:" + i.tagFilter.String()
+	}
+	return str
+}
+
+func (i *localIndexScan) Estimate(ec executor.ExecutionContext) (logical.Estimation, error) {
+	seriesList, err := logical.ListSeries(ec, i.entities)
+	if err != nil {
+		return logical.Estimation{}, err
+	}
+	blocks, err := logical.EstimateForShard(seriesList, i.timeRange)
+	if err != nil {
+		return logical.Estimation{}, err
+	}
+	return logical.Estimation{
+		Indexes: i.indexes(),
+		Series:  len(seriesList),
+		Blocks:  blocks,
+	}, nil
+}
+
+func (i *localIndexScan) indexes() []string {
+	var names []string
+	if i.Index != nil {
+		names = append(names, i.Index.GetMetadata().GetName())
+	}
+	return append(names, logical.IndexRuleNames(i.filter)...)
 }
 
 func (i *localIndexScan) Children() []logical.Plan {
@@ -133,3 +146,72 @@ func (i *localIndexScan) Schema() logical.Schema {
 	}
 	return i.schema.ProjTags(i.projectionTagRefs...)
 }
+
+var _ executor.SIterator = (*indexScanIterator)(nil)
+
+type indexScanIterator struct {
+	ec      executor.StreamExecutionContext
+	scan    *localIndexScan
+	inner   logical.ItemIterator
+	closers []io.Closer
+	stop    func()
+
+	current *streamv1.Element
+	err     error
+}
+
+func (it *indexScanIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	stats := executor.StatsOf(it.ec)
+	for it.inner.HasNext() {
+		if it.err = stats.Check(); it.err != nil {
+			return false
+		}
+		nextItem := it.inner.Next()
+		tagFamilies, err := logical.ProjectItem(it.ec, nextItem, it.scan.projectionTagRefs)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		if it.scan.tagFilter != nil {
+			ok, err := it.scan.tagFilter.Match(tagFamilies)
+			if err != nil {
+				it.err = err
+				return false
+			}
+			if !ok {
+				continue
+			}
+		}
+		elementID, err := it.ec.ParseElementID(nextItem)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.current = &streamv1.Element{
+			ElementId:   elementID,
+			Timestamp:   timestamppb.New(time.Unix(0, int64(nextItem.Time()))),
+			TagFamilies: tagFamilies,
+		}
+		return true
+	}
+	// the iterators stop early once the query times out
+	it.err = stats.Check()
+	return false
+}
+
+func (it *indexScanIterator) Current() *streamv1.Element {
+	return it.current
+}
+
+func (it *indexScanIterator) Close() error {
+	for _, c := range it.closers {
+		_ = c.Close()
+	}
+	it.closers = nil
+	it.stop()
+	it.stop = func() {}
+	return it.err
+}
@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"time"
 
+	"go.uber.org/multierr"
+
 	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
 	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
 	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
@@ -151,21 +153,24 @@ var (
 )
 
 type tagFilterPlan struct {
+	*logical.Parent
 	s         logical.Schema
-	parent    logical.Plan
 	tagFilter logical.TagFilter
 }
 
 func NewTagFilter(s logical.Schema, parent logical.Plan, tagFilter logical.TagFilter) logical.Plan {
 	return &tagFilterPlan{
+		Parent: &logical.Parent{
+			Input: parent,
+		},
 		s:         s,
-		parent:    parent,
 		tagFilter: tagFilter,
 	}
 }
 
 func (t *tagFilterPlan) Execute(ec executor.StreamExecutionContext) ([]*streamv1.Element, error) {
-	entities, err := t.parent.(executor.StreamExecutable).Execute(ec)
+	defer executor.StatsOf(ec).Trace("TagFilter")()
+	entities, err := t.Input.(executor.StreamExecutable).Execute(ec)
 	if err != nil {
 		return nil, err
 	}
@@ -182,14 +187,51 @@ func (t *tagFilterPlan) Execute(ec executor.StreamExecutionContext) ([]*streamv1
 	return filteredElements, nil
 }
 
+// Iterate filters the elements of its input as they are read.
+func (t *tagFilterPlan) Iterate(ec executor.StreamExecutionContext) (executor.SIterator, error) {
+	stop := executor.StatsOf(ec).Trace("TagFilter")
+	it, err := executor.IterateStream(t.Input.(executor.StreamExecutable), ec)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	return &tagFilterIterator{SIterator: it, stop: stop, tagFilter: t.tagFilter}, nil
+}
+
 func (t *tagFilterPlan) String() string {
-	return fmt.Sprintf("%s tag-filter:%s", t.parent, t.tagFilter.String())
+	return fmt.Sprintf("%s tag-filter:%s", t.Input, t.tagFilter.String())
 }
 
 func (t *tagFilterPlan) Children() []logical.Plan {
-	return []logical.Plan{t.parent}
+	return []logical.Plan{t.Input}
 }
 
 func (t *tagFilterPlan) Schema() logical.Schema {
 	return t.s
 }
+
+type tagFilterIterator struct {
+	executor.SIterator
+	stop      func()
+	tagFilter logical.TagFilter
+	err       error
+}
+
+func (ti *tagFilterIterator) Next() bool {
+	for ti.err == nil && ti.SIterator.Next() {
+		ok, err := ti.tagFilter.Match(ti.Current().TagFamilies)
+		if err != nil {
+			ti.err = err
+			return false
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (ti *tagFilterIterator) Close() error {
+	defer ti.stop()
+	return multierr.Append(ti.err, ti.SIterator.Close())
+}
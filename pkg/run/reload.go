@@ -0,0 +1,134 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package run
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotReloadable is returned if a flag can't be changed at runtime.
+var ErrNotReloadable = errors.New("the flag is not reloadable")
+
+// reloadMu serializes the reloads, which are triggered by the admin RPC and the config file.
+var reloadMu sync.Mutex
+
+// Reloader interface could be implemented by Group Unit objects that are able
+// to change some of their flags at runtime without restarting.
+type Reloader interface {
+	// Unit for Group registration and identification
+	Unit
+	// ReloadableFlags returns the current values of the flags which can be reloaded, keyed by their names.
+	ReloadableFlags() map[string]string
+	// PrepareReload validates the new values of some of its reloadable flags, and returns the function
+	// applying them, which is only called once all the Reloaders accept their new values.
+	PrepareReload(values map[string]string) (apply func(), err error)
+}
+
+// NewReloader takes a name, a function returning the current values of the reloadable flags
+// and a function preparing their new values, and turns them into a Group compatible Reloader.
+func NewReloader(name string, flags func() map[string]string, prepare func(values map[string]string) (func(), error)) Reloader {
+	return &reloader{name: name, flags: flags, prepare: prepare}
+}
+
+type reloader struct {
+	flags   func() map[string]string
+	prepare func(values map[string]string) (func(), error)
+	name    string
+}
+
+func (r *reloader) Name() string {
+	return r.name
+}
+
+func (r *reloader) ReloadableFlags() map[string]string {
+	return r.flags()
+}
+
+func (r *reloader) PrepareReload(values map[string]string) (func(), error) {
+	return r.prepare(values)
+}
+
+// ReloadableFlags returns the current values of the reloadable flags of the Reloaders among the units.
+func ReloadableFlags(units ...Unit) map[string]string {
+	flags := make(map[string]string)
+	for _, u := range units {
+		if r, ok := u.(Reloader); ok {
+			for name, value := range r.ReloadableFlags() {
+				flags[name] = value
+			}
+		}
+	}
+	return flags
+}
+
+// Reload applies the new values of the flags to the Reloaders among the units atomically, that is, none is applied
+// if any flag isn't reloadable or any Reloader rejects its new values. It returns the sorted names of the flags
+// whose values change.
+func Reload(values map[string]string, units ...Unit) ([]string, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	owners := make(map[string]Reloader)
+	current := make(map[string]string)
+	for _, u := range units {
+		r, ok := u.(Reloader)
+		if !ok {
+			continue
+		}
+		for name, value := range r.ReloadableFlags() {
+			owners[name] = r
+			current[name] = value
+		}
+	}
+	changes := make(map[Reloader]map[string]string)
+	var changed []string
+	for name, value := range values {
+		r, ok := owners[name]
+		if !ok {
+			return nil, errors.WithMessagef(ErrNotReloadable, "flag %s", name)
+		}
+		if current[name] == value {
+			continue
+		}
+		if changes[r] == nil {
+			changes[r] = make(map[string]string)
+		}
+		changes[r][name] = value
+		changed = append(changed, name)
+	}
+	applies := make([]func(), 0, len(changes))
+	for r, c := range changes {
+		apply, err := r.PrepareReload(c)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to reload %s", r.Name())
+		}
+		applies = append(applies, apply)
+	}
+	for _, apply := range applies {
+		apply()
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// Reload applies the new values of the flags to the registered Reloaders, see Reload.
+func (g *Group) Reload(values map[string]string) ([]string, error) {
+	return Reload(values, g.rl...)
+}
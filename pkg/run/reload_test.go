@@ -0,0 +1,80 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package run_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/pkg/run"
+)
+
+// intReloader reloads the non-negative integer flags.
+func intReloader(name string, values map[string]int) run.Reloader {
+	return run.NewReloader(name, func() map[string]string {
+		flags := make(map[string]string, len(values))
+		for k, v := range values {
+			flags[k] = strconv.Itoa(v)
+		}
+		return flags
+	}, func(changes map[string]string) (func(), error) {
+		parsed := make(map[string]int, len(changes))
+		for k, v := range changes {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return nil, errors.Errorf("invalid %s %q", k, v)
+			}
+			parsed[k] = n
+		}
+		return func() {
+			for k, n := range parsed {
+				values[k] = n
+			}
+		}, nil
+	})
+}
+
+func TestReload(t *testing.T) {
+	a := map[string]int{"a-size": 1}
+	b := map[string]int{"b-size": 2, "b-rate": 3}
+	units := []run.Unit{intReloader("a", a), intReloader("b", b)}
+	assert.Equal(t, map[string]string{"a-size": "1", "b-size": "2", "b-rate": "3"}, run.ReloadableFlags(units...))
+
+	applied, err := run.Reload(map[string]string{"a-size": "10", "b-size": "2", "b-rate": "30"}, units...)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a-size", "b-rate"}, applied)
+	assert.Equal(t, map[string]int{"a-size": 10}, a)
+	assert.Equal(t, map[string]int{"b-size": 2, "b-rate": 30}, b)
+}
+
+func TestReloadAtomically(t *testing.T) {
+	a := map[string]int{"a-size": 1}
+	b := map[string]int{"b-size": 2}
+	units := []run.Unit{intReloader("a", a), intReloader("b", b)}
+
+	_, err := run.Reload(map[string]string{"a-size": "10", "b-size": "-1"}, units...)
+	assert.Error(t, err)
+	_, err = run.Reload(map[string]string{"a-size": "10", "c-size": "1"}, units...)
+	assert.ErrorIs(t, err, run.ErrNotReloadable)
+	assert.Equal(t, map[string]int{"a-size": 1}, a)
+	assert.Equal(t, map[string]int{"b-size": 2}, b)
+}
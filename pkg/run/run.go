@@ -96,6 +96,25 @@ func (p preRunner) PreRun() error {
 
 type StopNotify <-chan struct{}
 
+// Drainer interface could be implemented by Group Service objects that need
+// to stop taking new work and flush the pending one before any Service stops.
+// The Drainers are called in the order of the registration once the Group is
+// going to stop.
+type Drainer interface {
+	// Unit for Group registration and identification
+	Unit
+	Drain()
+}
+
+// HealthChecker interface could be implemented by Group Unit objects that are
+// able to report their health. A Unit without it is considered healthy.
+type HealthChecker interface {
+	// Unit for Group registration and identification
+	Unit
+	// CheckHealth returns an error if the Unit doesn't work well
+	CheckHealth() error
+}
+
 // Service interface should be implemented by Group Unit objects that need
 // to run a blocking service until an error occurs or a shutdown request is
 // made.
@@ -125,6 +144,7 @@ type Group struct {
 	c       []Config
 	p       []PreRunner
 	s       []Service
+	rl      []Unit
 	readyCh chan struct{}
 	log     *logger.Logger
 
@@ -172,6 +192,10 @@ func (g *Group) Register(units ...Unit) []bool {
 			g.s = append(g.s, s)
 			hasRegistered[idx] = true
 		}
+		if _, ok := units[idx].(Reloader); ok {
+			g.rl = append(g.rl, units[idx])
+			hasRegistered[idx] = true
+		}
 	}
 	return hasRegistered
 }
@@ -322,6 +346,16 @@ func (g *Group) Run() (err error) {
 		}
 	}
 
+	// the services are stopped one by one, so the drainers are called by the first stopped one
+	var drainOnce sync.Once
+	drain := func() {
+		for _, s := range g.s {
+			if d, ok := s.(Drainer); ok {
+				g.log.Debug().Str("name", d.Name()).Msg("drain")
+				d.Drain()
+			}
+		}
+	}
 	swg := &sync.WaitGroup{}
 	swg.Add(len(g.s))
 	go func() {
@@ -343,6 +377,7 @@ func (g *Group) Run() (err error) {
 			<-notify
 			return nil
 		}, func(_ error) {
+			drainOnce.Do(drain)
 			g.log.Debug().Uint32("total", uint32(len(g.s))).Uint32("ran", uint32(idx+1)).Str("name", s.Name()).Msg("stop")
 			s.GracefulStop()
 		})
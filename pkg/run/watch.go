@@ -0,0 +1,117 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package run
+
+import (
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/apache/skywalking-banyandb/pkg/config"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+var errInvalidReloadInterval = errors.New("the interval of checking the reload config file should be positive")
+
+// configWatcher reloads the flags set by a config file once it changes.
+type configWatcher struct {
+	modTime  time.Time
+	reload   func(values map[string]string) ([]string, error)
+	log      *logger.Logger
+	stopCh   chan struct{}
+	path     string
+	size     int64
+	interval time.Duration
+}
+
+// NewConfigWatcher returns the Unit watching the config file set by the reload-config-file flag,
+// which passes the values of the flags in the file to reload at startup and once the file changes.
+func NewConfigWatcher(reload func(values map[string]string) ([]string, error)) Unit {
+	return &configWatcher{reload: reload}
+}
+
+func (w *configWatcher) Name() string {
+	return "config-watcher"
+}
+
+func (w *configWatcher) FlagSet() *FlagSet {
+	flagS := NewFlagSet("config-watcher")
+	flagS.StringVar(&w.path, "reload-config-file", "",
+		"the config file of the reloadable flags, e.g. logging.level, which are applied at runtime once it changes")
+	flagS.DurationVar(&w.interval, "reload-config-interval", 10*time.Second, "the interval of checking whether the reload config file changes")
+	return flagS
+}
+
+func (w *configWatcher) Validate() error {
+	if w.path != "" && w.interval <= 0 {
+		return errInvalidReloadInterval
+	}
+	return nil
+}
+
+func (w *configWatcher) Serve() StopNotify {
+	w.log = logger.GetLogger("config-watcher")
+	w.stopCh = make(chan struct{})
+	if w.path == "" {
+		return w.stopCh
+	}
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			w.check()
+			select {
+			case <-w.stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return w.stopCh
+}
+
+// check reloads the flags if the file changes since the last check. A file failing to be reloaded isn't retried until it changes again.
+func (w *configWatcher) check() {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		w.log.Warn().Err(err).Str("path", w.path).Msg("failed to check the reload config file")
+		return
+	}
+	if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return
+	}
+	w.modTime, w.size = info.ModTime(), info.Size()
+	values, err := config.ReadFlags(w.path)
+	if err != nil {
+		w.log.Error().Err(err).Str("path", w.path).Msg("failed to read the reload config file")
+		return
+	}
+	applied, err := w.reload(values)
+	if err != nil {
+		w.log.Error().Err(err).Str("path", w.path).Msg("failed to reload the flags")
+		return
+	}
+	if len(applied) > 0 {
+		w.log.Info().Strs("flags", applied).Msg("reloaded the flags")
+	}
+}
+
+func (w *configWatcher) GracefulStop() {
+	close(w.stopCh)
+}
@@ -0,0 +1,66 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package schema
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	clusterv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/cluster/v1"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+var ErrShardNotExist = errors.New("shard doesn't exist")
+
+type blockExportListener struct {
+	repo Repository
+	l    *logger.Logger
+}
+
+// NewBlockExportListener returns the listener taking the snapshots of the sealed blocks of the groups in repo,
+// which replies a *tsdb.BlockExport to be released by the receiver.
+func NewBlockExportListener(repo Repository, l *logger.Logger) bus.MessageListener {
+	return &blockExportListener{
+		repo: repo,
+		l:    l,
+	}
+}
+
+func (b *blockExportListener) Rev(message bus.Message) (resp bus.Message) {
+	req, ok := message.Data().(*clusterv1.ExportBlockRequest)
+	if !ok {
+		return bus.NewMessage(message.ID(), common.NewError("invalid event data type"))
+	}
+	g, ok := b.repo.LoadGroup(req.GetGroup())
+	if !ok {
+		return bus.NewMessage(message.ID(), common.NewError("%v", errors.WithMessagef(ErrGroupNotExist, "group %s", req.GetGroup())))
+	}
+	for _, shard := range g.SupplyTSDB().Shards() {
+		if uint32(shard.ID()) != req.GetShardId() {
+			continue
+		}
+		e, err := shard.ExportBlock(req.GetSegment(), req.GetBlock())
+		if err != nil {
+			b.l.Error().Err(err).Str("group", req.GetGroup()).Uint32("shard", req.GetShardId()).Msg("fail to export the block")
+			return bus.NewMessage(message.ID(), common.NewError("%v", err))
+		}
+		return bus.NewMessage(message.ID(), e)
+	}
+	return bus.NewMessage(message.ID(), common.NewError("%v", errors.WithMessagef(ErrShardNotExist, "shard %d of group %s", req.GetShardId(), req.GetGroup())))
+}
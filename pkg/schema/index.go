@@ -0,0 +1,359 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package schema
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+	"github.com/apache/skywalking-banyandb/pkg/throttle"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+const (
+	// defaultRebuildRate is the number of the items indexed per second by a rebuild if the rate isn't limited by the request.
+	defaultRebuildRate = 1000
+	// backfillRetryInterval is the interval to check whether a backfill can start.
+	backfillRetryInterval = time.Second
+	// backfillStartTimeout is how long a backfill waits for the rules to be bound and the running rebuild to finish.
+	backfillStartTimeout = 5 * time.Minute
+)
+
+var (
+	ErrResourceNotExist   = errors.New("resource doesn't exist")
+	ErrIndexRuleNotBound  = errors.New("index rule isn't bound")
+	ErrRebuildInProgress  = errors.New("the indices are being rebuilt")
+	ErrRebuildUnsupported = errors.New("the indices of the resource can't be rebuilt")
+)
+
+// IndexRebuildOptions controls how an IndexRebuilder rebuilds the indices.
+type IndexRebuildOptions struct {
+	TimeRange timestamp.TimeRange
+	// Throttle bounds the number of the items indexed per second
+	Throttle *throttle.Throttle
+	// Progress counts the items indexed so far
+	Progress *atomic.Int64
+	// Incremental keeps the statistics of the rules collected by the writes
+	Incremental bool
+}
+
+// IndexRebuilder is a Resource rebuilding its indices from the stored data.
+type IndexRebuilder interface {
+	// RebuildIndex reconstructs the indices of the rules from the items stored in the time range of opts.
+	// It returns the number of the items indexed.
+	RebuildIndex(ctx context.Context, rules []*databasev1.IndexRule, opts IndexRebuildOptions) (int, error)
+}
+
+// indexJob is a rebuild or a backfill running on a resource.
+type indexJob struct {
+	rules    []string
+	progress atomic.Int64
+}
+
+var _ bus.MessageListener = (*IndexManager)(nil)
+
+// IndexManager reports the statistics of the indices, rebuilds them and backfills the ones of the new bindings
+// in the background. It listens to both the stats and the rebuild requests.
+type IndexManager struct {
+	repo   Repository
+	l      *logger.Logger
+	ctx    context.Context
+	cancel context.CancelFunc
+	// rebuilding holds the jobs running on the resources
+	rebuilding map[string]*indexJob
+	wg         sync.WaitGroup
+	mu         sync.Mutex
+}
+
+// NewIndexManager returns the IndexManager of the indices of the resources in repo.
+func NewIndexManager(repo Repository, l *logger.Logger) *IndexManager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &IndexManager{
+		repo:       repo,
+		l:          l,
+		ctx:        ctx,
+		cancel:     cancel,
+		rebuilding: make(map[string]*indexJob),
+	}
+}
+
+func (m *IndexManager) Rev(message bus.Message) (resp bus.Message) {
+	var result interface{}
+	var err error
+	switch req := message.Data().(type) {
+	case *databasev1.IndexServiceStatsRequest:
+		result, err = m.Stats(req)
+	case *databasev1.IndexServiceRebuildRequest:
+		result, err = m.Rebuild(req)
+	default:
+		return bus.NewMessage(message.ID(), common.NewError("invalid event data type"))
+	}
+	if err != nil {
+		return bus.NewMessage(message.ID(), common.NewError("%v", err))
+	}
+	return bus.NewMessage(message.ID(), result)
+}
+
+// Stats returns the statistics of the indices of the resource in each shard.
+func (m *IndexManager) Stats(req *databasev1.IndexServiceStatsRequest) (*databasev1.IndexServiceStatsResponse, error) {
+	g, r, err := m.load(req.GetMetadata())
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	job := m.rebuilding[resourceKey(req.GetMetadata())]
+	m.mu.Unlock()
+	shards := g.SupplyTSDB().Shards()
+	result := &databasev1.IndexServiceStatsResponse{}
+	for _, rule := range r.GetIndexRules() {
+		rs := &databasev1.IndexServiceStatsResponse_IndexRule{
+			Name: rule.GetMetadata().GetName(),
+		}
+		if job != nil && contains(job.rules, rs.Name) {
+			rs.Rebuilding = true
+			rs.IndexedItems = uint64(job.progress.Load())
+		}
+		for _, shard := range shards {
+			stats := shard.Cardinality().Stats(rule.GetMetadata().GetId())
+			s := &databasev1.IndexServiceStatsResponse_Shard{
+				ShardId:     uint32(shard.ID()),
+				Cardinality: uint64(stats.Terms),
+				DiskSize:    stats.Size,
+			}
+			if !stats.LastWrite.IsZero() {
+				s.LastWriteTime = timestamppb.New(stats.LastWrite)
+			}
+			if !stats.LastBuild.IsZero() {
+				s.LastBuildTime = timestamppb.New(stats.LastBuild)
+			}
+			rs.Shards = append(rs.Shards, s)
+		}
+		result.IndexRules = append(result.IndexRules, rs)
+	}
+	return result, nil
+}
+
+// Rebuild starts to rebuild the indices of the resource in the background, and returns the rules to rebuild.
+func (m *IndexManager) Rebuild(req *databasev1.IndexServiceRebuildRequest) (*databasev1.IndexServiceRebuildResponse, error) {
+	_, r, err := m.load(req.GetMetadata())
+	if err != nil {
+		return nil, err
+	}
+	rebuilder, ok := r.(IndexRebuilder)
+	if !ok {
+		return nil, errors.WithMessagef(ErrRebuildUnsupported, "resource %s", req.GetMetadata().GetName())
+	}
+	rules := r.GetIndexRules()
+	if len(req.GetIndexRules()) > 0 {
+		rules = make([]*databasev1.IndexRule, 0, len(req.GetIndexRules()))
+		for _, name := range req.GetIndexRules() {
+			rule := findIndexRule(r.GetIndexRules(), name)
+			if rule == nil {
+				return nil, errors.WithMessagef(ErrIndexRuleNotBound, "index rule %s", name)
+			}
+			rules = append(rules, rule)
+		}
+	}
+	result := &databasev1.IndexServiceRebuildResponse{}
+	for _, rule := range rules {
+		result.IndexRules = append(result.IndexRules, rule.GetMetadata().GetName())
+	}
+	tr := timestamp.NewInclusiveTimeRange(timestamp.DefaultBeginPbTime.AsTime(), timestamp.MaxMilliTime)
+	if req.GetTimeRange() != nil {
+		tr = timestamp.NewInclusiveTimeRange(req.GetTimeRange().GetBegin().AsTime(), req.GetTimeRange().GetEnd().AsTime())
+	}
+	rate := defaultRebuildRate
+	if req.GetRateLimit() > 0 {
+		rate = int(req.GetRateLimit())
+	}
+	if err := m.start(resourceKey(req.GetMetadata()), rebuilder, rules, IndexRebuildOptions{
+		TimeRange: tr,
+		Throttle:  throttle.New(rate),
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Backfill indexes the items stored before the binding is created by the rules it binds in the background,
+// if the binding is just created and begins in the past. The items from the begin time of the binding until
+// the backfill starts are indexed, those written later are indexed by the writes.
+// It waits for the rules to be bound to the subject and the running rebuild of the subject to finish.
+// The progress is reported by Stats.
+func (m *IndexManager) Backfill(binding *databasev1.IndexRuleBinding) {
+	md := binding.GetMetadata()
+	if md.GetCreateRevision() != md.GetModRevision() {
+		return
+	}
+	if binding.GetBeginAt() == nil || !binding.GetBeginAt().AsTime().Before(time.Now()) {
+		return
+	}
+	subject := &commonv1.Metadata{Group: md.GetGroup(), Name: binding.GetSubject().GetName()}
+	key := resourceKey(subject)
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(backfillRetryInterval)
+		defer ticker.Stop()
+		deadline := time.Now().Add(backfillStartTimeout)
+		for {
+			started, err := m.startBackfill(key, subject, binding)
+			if err != nil {
+				m.l.Error().Err(err).Str("resource", key).Str("binding", md.GetName()).Msg("fail to backfill the indices")
+				return
+			}
+			if started {
+				return
+			}
+			if time.Now().After(deadline) {
+				m.l.Warn().Str("resource", key).Str("binding", md.GetName()).
+					Msg("give up backfilling the indices since the rules aren't bound or the indices are being rebuilt")
+				return
+			}
+			select {
+			case <-m.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// startBackfill starts the backfill of binding, and returns false if the rules aren't bound or a job is running yet.
+func (m *IndexManager) startBackfill(key string, subject *commonv1.Metadata, binding *databasev1.IndexRuleBinding) (bool, error) {
+	_, r, err := m.load(subject)
+	if errors.Is(err, ErrGroupNotExist) || errors.Is(err, ErrResourceNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	rebuilder, ok := r.(IndexRebuilder)
+	if !ok {
+		return false, errors.WithMessagef(ErrRebuildUnsupported, "resource %s", subject.GetName())
+	}
+	rules := make([]*databasev1.IndexRule, 0, len(binding.GetRules()))
+	for _, name := range binding.GetRules() {
+		rule := findIndexRule(r.GetIndexRules(), name)
+		if rule == nil {
+			return false, nil
+		}
+		rules = append(rules, rule)
+	}
+	end := time.Now()
+	if binding.GetExpireAt() != nil && binding.GetExpireAt().AsTime().Before(end) {
+		end = binding.GetExpireAt().AsTime()
+	}
+	err = m.start(key, rebuilder, rules, IndexRebuildOptions{
+		TimeRange:   timestamp.NewInclusiveTimeRange(binding.GetBeginAt().AsTime(), end),
+		Throttle:    throttle.New(defaultRebuildRate),
+		Incremental: true,
+	})
+	if errors.Is(err, ErrRebuildInProgress) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// start runs a job rebuilding the indices of the rules on the resource of key in the background.
+func (m *IndexManager) start(key string, rebuilder IndexRebuilder, rules []*databasev1.IndexRule, opts IndexRebuildOptions) error {
+	job := &indexJob{}
+	for _, rule := range rules {
+		job.rules = append(job.rules, rule.GetMetadata().GetName())
+	}
+	opts.Progress = &job.progress
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.rebuilding[key]; ok {
+		return errors.WithMessagef(ErrRebuildInProgress, "resource %s", key)
+	}
+	m.rebuilding[key] = job
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer func() {
+			m.mu.Lock()
+			delete(m.rebuilding, key)
+			m.mu.Unlock()
+		}()
+		action, done := "rebuild", "rebuilt"
+		if opts.Incremental {
+			action, done = "backfill", "backfilled"
+		}
+		start := time.Now()
+		items, err := rebuilder.RebuildIndex(m.ctx, rules, opts)
+		if err != nil {
+			m.l.Error().Err(err).Str("resource", key).Strs("index_rules", job.rules).
+				Int("items", items).Msgf("fail to %s the indices", action)
+			return
+		}
+		m.l.Info().Str("resource", key).Strs("index_rules", job.rules).Time("begin", opts.TimeRange.Start).
+			Time("end", opts.TimeRange.End).Int("items", items).Dur("elapsed", time.Since(start)).Msgf("%s the indices", done)
+	}()
+	return nil
+}
+
+// Close stops the running rebuilds and waits for them to exit.
+func (m *IndexManager) Close() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+func (m *IndexManager) load(metadata *commonv1.Metadata) (Group, Resource, error) {
+	g, ok := m.repo.LoadGroup(metadata.GetGroup())
+	if !ok {
+		return nil, nil, errors.WithMessagef(ErrGroupNotExist, "group %s", metadata.GetGroup())
+	}
+	r, ok := g.LoadResource(metadata.GetName())
+	if !ok {
+		return nil, nil, errors.WithMessagef(ErrResourceNotExist, "resource %s", metadata.GetName())
+	}
+	return g, r, nil
+}
+
+func resourceKey(metadata *commonv1.Metadata) string {
+	return metadata.GetGroup() + "/" + metadata.GetName()
+}
+
+func findIndexRule(rules []*databasev1.IndexRule, name string) *databasev1.IndexRule {
+	for _, rule := range rules {
+		if rule.GetMetadata().GetName() == name {
+			return rule
+		}
+	}
+	return nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package schema
+
+import (
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+type manifestListener struct {
+	repo Repository
+	l    *logger.Logger
+}
+
+// NewManifestListener returns the listener reporting the manifests of the segments of the groups in repo.
+func NewManifestListener(repo Repository, l *logger.Logger) bus.MessageListener {
+	return &manifestListener{
+		repo: repo,
+		l:    l,
+	}
+}
+
+func (m *manifestListener) Rev(message bus.Message) (resp bus.Message) {
+	req, ok := message.Data().(*databasev1.ManifestServiceGetRequest)
+	if !ok {
+		return bus.NewMessage(message.ID(), common.NewError("invalid event data type"))
+	}
+	result, err := Manifest(m.repo, req)
+	if err != nil {
+		m.l.Error().Err(err).Str("group", req.GetGroup()).Msg("fail to get the manifests")
+		return bus.NewMessage(message.ID(), common.NewError("%v", err))
+	}
+	return bus.NewMessage(message.ID(), result)
+}
+
+// Manifest returns the manifests of the segments of the local shards of the group, which are sorted by their shards.
+func Manifest(repo Repository, req *databasev1.ManifestServiceGetRequest) (*databasev1.ManifestServiceGetResponse, error) {
+	g, ok := repo.LoadGroup(req.GetGroup())
+	if !ok {
+		return nil, errors.WithMessagef(ErrGroupNotExist, "group %s", req.GetGroup())
+	}
+	result := &databasev1.ManifestServiceGetResponse{}
+	for _, shard := range g.SupplyTSDB().Shards() {
+		manifests, err := shard.Manifests()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "shard %d", shard.ID())
+		}
+		for _, m := range manifests {
+			sm := &databasev1.SegmentManifest{
+				Shard:     m.Shard,
+				Segment:   m.Segment,
+				Begin:     timestamppb.New(m.Begin),
+				End:       timestamppb.New(m.End),
+				Resources: m.Resources,
+				DiskBytes: m.DiskBytes,
+				Checksum:  m.Checksum,
+				UpdatedAt: timestamppb.New(m.UpdatedAt),
+			}
+			for _, b := range m.Blocks {
+				sm.Blocks = append(sm.Blocks, &databasev1.SegmentManifest_Block{
+					Name:      b.Name,
+					Begin:     timestamppb.New(b.Begin),
+					End:       timestamppb.New(b.End),
+					DiskBytes: b.DiskBytes,
+					Sealed:    b.Sealed,
+					Checksum:  b.Checksum,
+				})
+			}
+			result.Segments = append(result.Segments, sm)
+		}
+	}
+	return result, nil
+}
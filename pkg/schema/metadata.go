@@ -55,6 +55,7 @@ const (
 )
 
 type Group interface {
+	tsdb.Supplier
 	GetSchema() *commonv1.Group
 	StoreResource(resourceSchema ResourceSchema) (Resource, error)
 	LoadResource(name string) (Resource, bool)
@@ -87,7 +88,7 @@ type Resource interface {
 }
 
 type ResourceSupplier interface {
-	OpenResource(shardNum uint32, db tsdb.Supplier, spec ResourceSpec) (Resource, error)
+	OpenResource(sharding partition.Sharding, db tsdb.Supplier, spec ResourceSpec) (Resource, error)
 	ResourceSchema(repo metadata.Repo, metdata *commonv1.Metadata) (ResourceSchema, error)
 	OpenDB(groupSchema *commonv1.Group) (tsdb.Database, error)
 }
@@ -309,8 +310,11 @@ func (sr *schemaRepo) notify(groupSchema *commonv1.Group, action databasev1.Acti
 	for i := 0; i < int(shardNum); i++ {
 		_, errInternal := sr.repo.Publish(sr.shardTopic, bus.NewMessage(bus.MessageID(now.UnixNano()), &databasev1.ShardEvent{
 			Shard: &databasev1.Shard{
-				Id:    uint64(i),
-				Total: shardNum,
+				Id:              uint64(i),
+				Total:           shardNum,
+				TimeBand:        groupSchema.GetResourceOpts().GetTimeBand(),
+				Replicas:        groupSchema.GetResourceOpts().GetReplicas(),
+				ReplicationMode: groupSchema.GetResourceOpts().GetReplicationMode(),
 				Metadata: &commonv1.Metadata{
 					Name: groupSchema.GetMetadata().GetName(),
 				},
@@ -458,7 +462,12 @@ func (g *group) StoreResource(resourceSchema ResourceSchema) (Resource, error) {
 		}
 	}
 
-	sm, errTS := g.resourceSupplier.OpenResource(g.GetSchema().GetResourceOpts().ShardNum, g, ResourceSpec{
+	opts := g.GetSchema().GetResourceOpts()
+	sharding, err := partition.NewSharding(opts.GetShardNum(), opts.GetTimeBand())
+	if err != nil {
+		return nil, err
+	}
+	sm, errTS := g.resourceSupplier.OpenResource(sharding, g, ResourceSpec{
 		Schema:       resourceSchema,
 		IndexRules:   idxRules,
 		Aggregations: topNAggrs,
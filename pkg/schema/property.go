@@ -0,0 +1,69 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package schema
+
+import (
+	"context"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	propertyv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/property/v1"
+	"github.com/apache/skywalking-banyandb/banyand/metadata/schema"
+	pbv1 "github.com/apache/skywalking-banyandb/pkg/pb/v1"
+)
+
+const (
+	propertyCacheSize = 1024
+	propertyCacheTTL  = 30 * time.Second
+)
+
+type propertyEntry struct {
+	expireAt time.Time
+	tags     []*modelv1.Tag
+}
+
+// NewPropertyTagsGetter returns a getter pulling tags from the property registry.
+// Properties are cached for a while to keep the registry away from the write path.
+func NewPropertyTagsGetter(registry schema.Property) pbv1.PropertyTagsGetter {
+	// lru.New fails only if the size is not positive
+	cache, _ := lru.New(propertyCacheSize)
+	return func(container *commonv1.Metadata, id string) ([]*modelv1.Tag, error) {
+		key := container.GetGroup() + "/" + container.GetName() + "/" + id
+		if v, ok := cache.Get(key); ok {
+			entry := v.(propertyEntry)
+			if time.Now().Before(entry.expireAt) {
+				return entry.tags, nil
+			}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		property, err := registry.GetProperty(ctx, &propertyv1.Metadata{Container: container, Id: id}, nil)
+		if err != nil && !schema.IsNotFound(err) {
+			return nil, err
+		}
+		entry := propertyEntry{
+			tags:     property.GetTags(),
+			expireAt: time.Now().Add(propertyCacheTTL),
+		}
+		cache.Add(key, entry)
+		return entry.tags, nil
+	}
+}
@@ -0,0 +1,100 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package schema
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/banyand/tsdb"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+const defaultDrainTimeout = 10 * time.Second
+
+var ErrGroupNotExist = errors.New("group doesn't exist")
+
+type rolloverListener struct {
+	repo Repository
+	l    *logger.Logger
+}
+
+// NewRolloverListener returns the listener rolling over the shards of the groups in repo.
+func NewRolloverListener(repo Repository, l *logger.Logger) bus.MessageListener {
+	return &rolloverListener{
+		repo: repo,
+		l:    l,
+	}
+}
+
+func (r *rolloverListener) Rev(message bus.Message) (resp bus.Message) {
+	req, ok := message.Data().(*databasev1.ShardServiceRolloverRequest)
+	if !ok {
+		return bus.NewMessage(message.ID(), common.NewError("invalid event data type"))
+	}
+	result, err := Rollover(r.repo, req)
+	if err != nil {
+		r.l.Error().Err(err).Str("group", req.GetGroup()).Msg("fail to roll over the shards")
+		return bus.NewMessage(message.ID(), common.NewError("%v", err))
+	}
+	return bus.NewMessage(message.ID(), result)
+}
+
+// Rollover closes the open blocks of the shards requested by req.
+// It stops at the first shard failing to drain, and the blocks closed so far are kept closed.
+func Rollover(repo Repository, req *databasev1.ShardServiceRolloverRequest) (*databasev1.ShardServiceRolloverResponse, error) {
+	g, ok := repo.LoadGroup(req.GetGroup())
+	if !ok {
+		return nil, errors.WithMessagef(ErrGroupNotExist, "group %s", req.GetGroup())
+	}
+	db := g.SupplyTSDB()
+	var shards []tsdb.Shard
+	if len(req.GetShardIds()) < 1 {
+		shards = db.Shards()
+	}
+	for _, id := range req.GetShardIds() {
+		shard, err := db.Shard(common.ShardID(id))
+		if err != nil {
+			return nil, err
+		}
+		shards = append(shards, shard)
+	}
+	timeout := defaultDrainTimeout
+	if req.GetDrainTimeout() != nil {
+		timeout = req.GetDrainTimeout().AsDuration()
+	}
+	result := &databasev1.ShardServiceRolloverResponse{}
+	for _, shard := range shards {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		closed, err := shard.Rollover(ctx)
+		cancel()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "shard %d", shard.ID())
+		}
+		result.Shards = append(result.Shards, &databasev1.ShardServiceRolloverResponse_Shard{
+			ShardId:      uint32(shard.ID()),
+			ClosedBlocks: closed,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,82 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package schema
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+type timeRangeListener struct {
+	repo Repository
+	l    *logger.Logger
+}
+
+// NewTimeRangeListener returns the listener reporting the time ranges of the data of the groups in repo.
+func NewTimeRangeListener(repo Repository, l *logger.Logger) bus.MessageListener {
+	return &timeRangeListener{
+		repo: repo,
+		l:    l,
+	}
+}
+
+func (t *timeRangeListener) Rev(message bus.Message) (resp bus.Message) {
+	req, ok := message.Data().(*databasev1.TimeRangeServiceGetRequest)
+	if !ok {
+		return bus.NewMessage(message.ID(), common.NewError("invalid event data type"))
+	}
+	result, err := TimeRange(t.repo, req)
+	if err != nil {
+		t.l.Error().Err(err).Str("group", req.GetGroup()).Msg("fail to get the time ranges")
+		return bus.NewMessage(message.ID(), common.NewError("%v", err))
+	}
+	return bus.NewMessage(message.ID(), result)
+}
+
+// TimeRange returns the time ranges of the data written to the group and its resources, which are sorted by their names.
+func TimeRange(repo Repository, req *databasev1.TimeRangeServiceGetRequest) (*databasev1.TimeRangeServiceGetResponse, error) {
+	g, ok := repo.LoadGroup(req.GetGroup())
+	if !ok {
+		return nil, errors.WithMessagef(ErrGroupNotExist, "group %s", req.GetGroup())
+	}
+	result := &databasev1.TimeRangeServiceGetResponse{}
+	for name, r := range g.SupplyTSDB().TimeRanges() {
+		result.Resources = append(result.Resources, &databasev1.TimeRangeServiceGetResponse_Resource{
+			Name:     name,
+			Earliest: timestamppb.New(r.Earliest),
+			Latest:   timestamppb.New(r.Latest),
+		})
+		if result.Earliest == nil || r.Earliest.Before(result.Earliest.AsTime()) {
+			result.Earliest = timestamppb.New(r.Earliest)
+		}
+		if result.Latest == nil || r.Latest.After(result.Latest.AsTime()) {
+			result.Latest = timestamppb.New(r.Latest)
+		}
+	}
+	sort.Slice(result.Resources, func(i, j int) bool {
+		return result.Resources[i].GetName() < result.Resources[j].GetName()
+	})
+	return result, nil
+}
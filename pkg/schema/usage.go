@@ -0,0 +1,103 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package schema
+
+import (
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/pkg/bus"
+	"github.com/apache/skywalking-banyandb/pkg/logger"
+)
+
+type usageListener struct {
+	repo Repository
+	l    *logger.Logger
+}
+
+// NewUsageListener returns the listener reporting the storage usage of the shards of the groups in repo.
+func NewUsageListener(repo Repository, l *logger.Logger) bus.MessageListener {
+	return &usageListener{
+		repo: repo,
+		l:    l,
+	}
+}
+
+func (u *usageListener) Rev(message bus.Message) (resp bus.Message) {
+	req, ok := message.Data().(*databasev1.UsageServiceGetRequest)
+	if !ok {
+		return bus.NewMessage(message.ID(), common.NewError("invalid event data type"))
+	}
+	result, err := Usage(u.repo, req.GetGroup())
+	if err != nil {
+		u.l.Error().Err(err).Str("group", req.GetGroup()).Msg("fail to get the usage")
+		return bus.NewMessage(message.ID(), common.NewError("%v", err))
+	}
+	return bus.NewMessage(message.ID(), result)
+}
+
+// Usage returns the storage usage of the local shards of the group, which are sorted by their ids.
+// The group usage sums up the ones of the shards.
+func Usage(repo Repository, group string) (*databasev1.UsageServiceGetResponse_Group, error) {
+	g, ok := repo.LoadGroup(group)
+	if !ok {
+		return nil, errors.WithMessagef(ErrGroupNotExist, "group %s", group)
+	}
+	result := &databasev1.UsageServiceGetResponse_Group{
+		Name:    group,
+		Catalog: g.GetSchema().GetCatalog(),
+		Usage:   &databasev1.Usage{},
+	}
+	for _, shard := range g.SupplyTSDB().Shards() {
+		su, err := shard.Usage()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "shard %d", shard.ID())
+		}
+		s := &databasev1.UsageServiceGetResponse_Shard{
+			Id: uint32(shard.ID()),
+			Usage: &databasev1.Usage{
+				DiskBytes:  su.DiskBytes,
+				Series:     su.Series,
+				Blocks:     int64(su.Blocks),
+				IngestRate: su.IngestRate,
+			},
+		}
+		for _, seg := range su.Segments {
+			s.Segments = append(s.Segments, &databasev1.UsageServiceGetResponse_Segment{
+				Name:      seg.Name,
+				Begin:     timestamppb.New(seg.TimeRange.Start),
+				End:       timestamppb.New(seg.TimeRange.End),
+				DiskBytes: seg.DiskBytes,
+				Blocks:    int64(seg.Blocks),
+			})
+		}
+		result.Shards = append(result.Shards, s)
+		addUsage(result.Usage, s.Usage)
+	}
+	return result, nil
+}
+
+// addUsage adds the usage delta to u.
+func addUsage(u, delta *databasev1.Usage) {
+	u.DiskBytes += delta.GetDiskBytes()
+	u.Series += delta.GetSeries()
+	u.Blocks += delta.GetBlocks()
+	u.IngestRate += delta.GetIngestRate()
+}
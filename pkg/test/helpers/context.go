@@ -44,6 +44,18 @@ type Args struct {
 	Want      string
 	WantEmpty bool
 	WantErr   bool
+	// ReadAt is the read timestamp relative to the base time, 0 disables the snapshot
+	ReadAt time.Duration
+	// Batched queries by QueryBatches, whose batches are concatenated into a single response
+	Batched bool
+}
+
+// ReadTimestamp returns the read timestamp of a snapshot, or nil if it's disabled.
+func ReadTimestamp(args Args, shardContext SharedContext) *timestamppb.Timestamp {
+	if args.ReadAt == 0 {
+		return nil
+	}
+	return timestamppb.New(shardContext.BaseTime.Add(args.ReadAt))
 }
 
 func UnmarshalYAML(ii []byte, m proto.Message) {
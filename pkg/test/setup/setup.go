@@ -42,12 +42,13 @@ func SetUp(flags ...string) (string, string, func()) {
 	path, deferFn, err := test.NewSpace()
 	gomega.Expect(err).NotTo(gomega.HaveOccurred())
 	var ports []int
-	ports, err = test.AllocateFreePorts(4)
+	ports, err = test.AllocateFreePorts(5)
 	gomega.Expect(err).NotTo(gomega.HaveOccurred())
 	addr := fmt.Sprintf("%s:%d", host, ports[0])
 	httpAddr := fmt.Sprintf("%s:%d", host, ports[1])
 	ff := []string{
 		"--addr=" + addr,
+		fmt.Sprintf("--internal-addr=%s:%d", host, ports[4]),
 		"--http-addr=" + httpAddr,
 		"--grpc-addr=" + addr,
 		"--stream-root-path=" + path,
@@ -0,0 +1,152 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package throttle limits the IO throughput of background jobs,
+// so that they don't compete with the foreground queries for the disk.
+package throttle
+
+import (
+	"context"
+	"io"
+
+	"golang.org/x/time/rate"
+
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+// Throttle limits the IO rate in bytes per second.
+// A nil Throttle is unlimited so that callers don't have to check whether it's enabled.
+type Throttle struct {
+	limiter *rate.Limiter
+	clock   timestamp.Clock
+}
+
+// New returns a Throttle allowing bytesPerSecond, or nil if bytesPerSecond is not positive.
+func New(bytesPerSecond int) *Throttle {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &Throttle{
+		// the burst is one second's worth
+		limiter: rate.NewLimiter(rate.Limit(bytesPerSecond), bytesPerSecond),
+		clock:   timestamp.NewClock(),
+	}
+}
+
+// NewAdjustable returns a Throttle allowing bytesPerSecond, whose limit can be changed by SetLimit at runtime.
+// It's unlimited but not nil if bytesPerSecond is not positive.
+func NewAdjustable(bytesPerSecond int) *Throttle {
+	t := &Throttle{limiter: rate.NewLimiter(rate.Inf, 0), clock: timestamp.NewClock()}
+	t.SetLimit(bytesPerSecond)
+	return t
+}
+
+// WithClock makes t measure the rate by clock, e.g. a mock clock of the tests.
+func (t *Throttle) WithClock(clock timestamp.Clock) *Throttle {
+	if t != nil {
+		t.clock = clock
+	}
+	return t
+}
+
+// SetLimit changes the allowed bytes per second, 0 means unlimited.
+func (t *Throttle) SetLimit(bytesPerSecond int) {
+	if bytesPerSecond <= 0 {
+		t.limiter.SetLimitAt(t.clock.Now(), rate.Inf)
+		return
+	}
+	now := t.clock.Now()
+	t.limiter.SetBurstAt(now, bytesPerSecond)
+	t.limiter.SetLimitAt(now, rate.Limit(bytesPerSecond))
+}
+
+// Wait blocks until n bytes are allowed to be read.
+func (t *Throttle) Wait(n int) {
+	_ = t.WaitContext(context.Background(), n)
+}
+
+// WaitContext blocks until n bytes are allowed to be read or the context is done.
+func (t *Throttle) WaitContext(ctx context.Context, n int) error {
+	if t == nil || t.limiter.Limit() == rate.Inf {
+		return nil
+	}
+	// split n into pieces because the limiter rejects the request exceeding the burst
+	burst := t.limiter.Burst()
+	for n > 0 {
+		size := n
+		if size > burst {
+			size = burst
+		}
+		if err := t.wait(ctx, size); err != nil {
+			return err
+		}
+		n -= size
+	}
+	return nil
+}
+
+// wait reserves n bytes, which never exceeds the burst, and sleeps on t.clock until they are allowed.
+func (t *Throttle) wait(ctx context.Context, n int) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+	now := t.clock.Now()
+	r := t.limiter.ReserveN(now, n)
+	delay := r.DelayFrom(now)
+	if delay == 0 {
+		return nil
+	}
+	timer := t.clock.Timer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// give back the tokens to the others waiting
+		r.CancelAt(t.clock.Now())
+		return ctx.Err()
+	}
+}
+
+// Limit returns the allowed bytes per second, 0 means unlimited.
+func (t *Throttle) Limit() int {
+	if t == nil || t.limiter.Limit() == rate.Inf {
+		return 0
+	}
+	return int(t.limiter.Limit())
+}
+
+// NewReader returns a reader of r, whose reads are limited by t.
+func NewReader(r io.Reader, t *Throttle) io.Reader {
+	if t == nil {
+		return r
+	}
+	return &reader{r: r, t: t}
+}
+
+type reader struct {
+	r io.Reader
+	t *Throttle
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.t.Wait(n)
+	return n, err
+}
@@ -0,0 +1,118 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package throttle_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/apache/skywalking-banyandb/pkg/throttle"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+)
+
+// waitFor calls wait, and moves the clock forward until wait returns.
+// It returns the time passed on the clock.
+func waitFor(clock timestamp.MockClock, wait func()) time.Duration {
+	start := clock.Now()
+	done := make(chan struct{})
+	go func() {
+		wait()
+		close(done)
+	}()
+	for {
+		select {
+		case <-done:
+			return clock.Since(start)
+		default:
+			clock.Add(10 * time.Millisecond)
+		}
+	}
+}
+
+func canceledContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
+
+func TestUnlimited(t *testing.T) {
+	th := throttle.New(0)
+	assert.Nil(t, th)
+	assert.Equal(t, 0, th.Limit())
+	// an unlimited throttle never waits, so even the canceled context passes
+	require.NoError(t, th.WaitContext(canceledContext(), 1<<30))
+}
+
+func TestThrottle(t *testing.T) {
+	clock := timestamp.NewMockClock()
+	th := throttle.New(1000).WithClock(clock)
+	assert.Equal(t, 1000, th.Limit())
+	start := clock.Now()
+	// the burst is consumed at once
+	th.Wait(1000)
+	assert.Equal(t, time.Duration(0), clock.Since(start))
+	// the rest has to wait for the tokens, the request larger than the burst is split
+	assert.GreaterOrEqual(t, waitFor(clock, func() { th.Wait(1500) }), 1500*time.Millisecond)
+}
+
+func TestThrottleCanceled(t *testing.T) {
+	th := throttle.New(10).WithClock(timestamp.NewMockClock())
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, th.WaitContext(ctx, 10))
+	cancel()
+	require.Error(t, th.WaitContext(ctx, 10))
+}
+
+func TestAdjustable(t *testing.T) {
+	clock := timestamp.NewMockClock()
+	th := throttle.NewAdjustable(0).WithClock(clock)
+	require.NotNil(t, th)
+	assert.Equal(t, 0, th.Limit())
+	require.NoError(t, th.WaitContext(canceledContext(), 1<<30))
+
+	th.SetLimit(1000)
+	assert.Equal(t, 1000, th.Limit())
+	elapsed := waitFor(clock, func() {
+		th.Wait(1000)
+		th.Wait(500)
+	})
+	assert.GreaterOrEqual(t, elapsed, 500*time.Millisecond)
+
+	th.SetLimit(0)
+	assert.Equal(t, 0, th.Limit())
+	require.NoError(t, th.WaitContext(canceledContext(), 1<<30))
+}
+
+func TestReader(t *testing.T) {
+	clock := timestamp.NewMockClock()
+	r := throttle.NewReader(bytes.NewReader(make([]byte, 1500)), throttle.New(1000).WithClock(clock))
+	var n int64
+	var err error
+	elapsed := waitFor(clock, func() {
+		n, err = io.Copy(io.Discard, r)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1500), n)
+	assert.GreaterOrEqual(t, elapsed, 500*time.Millisecond)
+}
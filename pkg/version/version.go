@@ -32,6 +32,15 @@ func Build() string {
 	return build
 }
 
+// Commit returns the git commit hash the service is built from. (from raw git label)
+func Commit() string {
+	v := strings.SplitN(build, "-", 4)
+	if len(v) != 4 || len(v[2]) < 2 {
+		return "unknown"
+	}
+	return v[2][1:]
+}
+
 // Show the service's version information
 func Show(serviceName string) {
 	fmt.Println(serviceName + " " + Parse())
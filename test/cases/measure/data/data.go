@@ -22,6 +22,7 @@ import (
 	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"io"
 	"time"
 
@@ -52,9 +53,15 @@ var VerifyFn = func(innerGm gm.Gomega, sharedContext helpers.SharedContext, args
 	query := &measurev1.QueryRequest{}
 	helpers.UnmarshalYAML(i, query)
 	query.TimeRange = helpers.TimeRange(args, sharedContext)
+	query.ReadTimestamp = helpers.ReadTimestamp(args, sharedContext)
 	c := measurev1.NewMeasureServiceClient(sharedContext.Connection)
 	ctx := context.Background()
-	resp, err := c.Query(ctx, query)
+	var resp *measurev1.QueryResponse
+	if args.Batched {
+		resp, err = queryBatches(ctx, c, query)
+	} else {
+		resp, err = c.Query(ctx, query)
+	}
 	if args.WantErr {
 		if err == nil {
 			g.Fail("expect error")
@@ -76,6 +83,7 @@ var VerifyFn = func(innerGm gm.Gomega, sharedContext helpers.SharedContext, args
 	innerGm.Expect(cmp.Equal(resp, want,
 		protocmp.IgnoreUnknown(),
 		protocmp.IgnoreFields(&measurev1.DataPoint{}, "timestamp"),
+		protocmp.IgnoreFields(&measurev1.QueryResponse{}, "read_timestamp", "snapshot_token"),
 		protocmp.Transform())).
 		To(gm.BeTrue(), func() string {
 			j, err := protojson.Marshal(resp)
@@ -90,6 +98,28 @@ var VerifyFn = func(innerGm gm.Gomega, sharedContext helpers.SharedContext, args
 		})
 }
 
+// queryBatches concatenates the batches of the query into a single response.
+func queryBatches(ctx context.Context, c measurev1.MeasureServiceClient, query *measurev1.QueryRequest) (*measurev1.QueryResponse, error) {
+	batches, err := c.QueryBatches(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	resp := &measurev1.QueryResponse{}
+	for {
+		batch, errRecv := batches.Recv()
+		if errors.Is(errRecv, io.EOF) {
+			return resp, nil
+		}
+		if errRecv != nil {
+			return nil, errRecv
+		}
+		resp.DataPoints = append(resp.DataPoints, batch.GetDataPoints()...)
+		if batch.GetTruncation() != nil {
+			resp.Truncation = batch.GetTruncation()
+		}
+	}
+}
+
 //go:embed testdata/*.json
 var dataFS embed.FS
 
@@ -19,11 +19,14 @@
 package measure_test
 
 import (
+	"context"
 	"time"
 
 	g "github.com/onsi/ginkgo/v2"
 	gm "github.com/onsi/gomega"
 
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
 	"github.com/apache/skywalking-banyandb/pkg/test/helpers"
 	measureTestData "github.com/apache/skywalking-banyandb/test/cases/measure/data"
 )
@@ -49,10 +52,32 @@ var _ = g.DescribeTable("Scanning Measures", verify,
 	g.Entry("order by time asc", helpers.Args{Input: "order_asc", Duration: 25 * time.Minute, Offset: -20 * time.Minute}),
 	g.Entry("order by time desc", helpers.Args{Input: "order_desc", Duration: 25 * time.Minute, Offset: -20 * time.Minute}),
 	g.Entry("limit 3,2", helpers.Args{Input: "limit", Duration: 25 * time.Minute, Offset: -20 * time.Minute}),
+	g.Entry("all in batches", helpers.Args{Input: "all", Duration: 25 * time.Minute, Offset: -20 * time.Minute, Batched: true}),
+	g.Entry("group and max in batches", helpers.Args{Input: "group_max", Duration: 25 * time.Minute, Offset: -20 * time.Minute, Batched: true}),
+	g.Entry("top 2 in batches", helpers.Args{Input: "top", Duration: 25 * time.Minute, Offset: -20 * time.Minute, Batched: true}),
+	g.Entry("limit 3,2 in batches", helpers.Args{Input: "limit", Duration: 25 * time.Minute, Offset: -20 * time.Minute, Batched: true}),
 	g.Entry("match a node", helpers.Args{Input: "match_node", Duration: 25 * time.Minute, Offset: -20 * time.Minute}),
 	g.Entry("match nodes", helpers.Args{Input: "match_nodes", Duration: 25 * time.Minute, Offset: -20 * time.Minute}),
 	g.Entry("filter by entity id", helpers.Args{Input: "entity", Duration: 25 * time.Minute, Offset: -20 * time.Minute}),
 	g.Entry("filter by entity id and service id", helpers.Args{Input: "entity_service", Want: "entity", Duration: 25 * time.Minute, Offset: -20 * time.Minute}),
 	g.Entry("without field", helpers.Args{Input: "no_field", Duration: 25 * time.Minute, Offset: -20 * time.Minute}),
+	g.Entry("nothing in a snapshot", helpers.Args{Input: "all", Duration: 25 * time.Minute, Offset: -20 * time.Minute, ReadAt: -30 * time.Minute, WantEmpty: true}),
 	g.Entry("invalid logical expression", helpers.Args{Input: "err_invalid_le", Duration: 25 * time.Minute, Offset: -20 * time.Minute, WantErr: true}),
 )
+
+var _ = g.Describe("Inspecting Measures", func() {
+	g.It("shows the schema with the data", func() {
+		gm.Eventually(func(innerGm gm.Gomega) {
+			c := measurev1.NewMeasureServiceClient(SharedContext.Connection)
+			resp, err := c.Inspect(context.Background(), &measurev1.InspectRequest{
+				Metadata:  &commonv1.Metadata{Group: "sw_metric", Name: "service_traffic"},
+				TimeRange: helpers.TimeRange(helpers.Args{Duration: 25 * time.Minute, Offset: -20 * time.Minute}, SharedContext),
+			})
+			innerGm.Expect(err).NotTo(gm.HaveOccurred())
+			innerGm.Expect(resp.GetMeasure().GetMetadata().GetName()).To(gm.Equal("service_traffic"))
+			innerGm.Expect(resp.GetSample().GetTagFamilies()).NotTo(gm.BeEmpty())
+			innerGm.Expect(resp.GetSeriesCount()).To(gm.BeNumerically(">=", 3))
+			innerGm.Expect(resp.GetDataPointCount()).To(gm.BeNumerically(">=", 3))
+		}).Should(gm.Succeed())
+	})
+})
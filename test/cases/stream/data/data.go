@@ -23,6 +23,7 @@ import (
 	"embed"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"io"
 	"strconv"
 	"time"
@@ -58,9 +59,15 @@ var VerifyFn = func(innerGm gm.Gomega, sharedContext helpers.SharedContext, args
 	query := &stream_v1.QueryRequest{}
 	helpers.UnmarshalYAML(i, query)
 	query.TimeRange = helpers.TimeRange(args, sharedContext)
+	query.ReadTimestamp = helpers.ReadTimestamp(args, sharedContext)
 	c := stream_v1.NewStreamServiceClient(sharedContext.Connection)
 	ctx := context.Background()
-	resp, err := c.Query(ctx, query)
+	var resp *stream_v1.QueryResponse
+	if args.Batched {
+		resp, err = queryBatches(ctx, c, query)
+	} else {
+		resp, err = c.Query(ctx, query)
+	}
 	if args.WantErr {
 		if err == nil {
 			g.Fail("expect error")
@@ -82,6 +89,7 @@ var VerifyFn = func(innerGm gm.Gomega, sharedContext helpers.SharedContext, args
 	innerGm.Expect(cmp.Equal(resp, want,
 		protocmp.IgnoreUnknown(),
 		protocmp.IgnoreFields(&stream_v1.Element{}, "timestamp"),
+		protocmp.IgnoreFields(&stream_v1.QueryResponse{}, "read_timestamp", "snapshot_token"),
 		protocmp.Transform())).
 		To(gm.BeTrue(), func() string {
 			j, err := protojson.Marshal(resp)
@@ -96,6 +104,41 @@ var VerifyFn = func(innerGm gm.Gomega, sharedContext helpers.SharedContext, args
 		})
 }
 
+// queryBatches concatenates the batches of the query into a single response.
+func queryBatches(ctx context.Context, c stream_v1.StreamServiceClient, query *stream_v1.QueryRequest) (*stream_v1.QueryResponse, error) {
+	batches, err := c.QueryBatches(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	resp := &stream_v1.QueryResponse{}
+	for {
+		batch, errRecv := batches.Recv()
+		if errors.Is(errRecv, io.EOF) {
+			return resp, nil
+		}
+		if errRecv != nil {
+			return nil, errRecv
+		}
+		resp.Elements = append(resp.Elements, batch.GetElements()...)
+		if batch.GetTruncation() != nil {
+			resp.Truncation = batch.GetTruncation()
+		}
+	}
+}
+
+// ExplainFn explains the query and returns the resolved plan
+var ExplainFn = func(innerGm gm.Gomega, sharedContext helpers.SharedContext, args helpers.Args) *model_v1.PlanNode {
+	i, err := inputFS.ReadFile("input/" + args.Input + ".yaml")
+	innerGm.Expect(err).NotTo(gm.HaveOccurred())
+	query := &stream_v1.QueryRequest{}
+	helpers.UnmarshalYAML(i, query)
+	query.TimeRange = helpers.TimeRange(args, sharedContext)
+	c := stream_v1.NewStreamServiceClient(sharedContext.Connection)
+	resp, err := c.Explain(context.Background(), query)
+	innerGm.Expect(err).NotTo(gm.HaveOccurred(), query.String())
+	return resp.GetPlan()
+}
+
 func loadData(stream stream_v1.StreamService_WriteClient, dataFile string, baseTime time.Time, interval time.Duration) {
 	var templates []interface{}
 	content, err := dataFS.ReadFile("testdata/" + dataFile)
@@ -19,6 +19,7 @@
 package stream_test
 
 import (
+	"context"
 	"math"
 	"time"
 
@@ -26,6 +27,9 @@ import (
 	gm "github.com/onsi/gomega"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	common_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	model_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	stream_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/stream/v1"
 	"github.com/apache/skywalking-banyandb/pkg/test/helpers"
 	stream_test_data "github.com/apache/skywalking-banyandb/test/cases/stream/data"
 )
@@ -65,5 +69,62 @@ var _ = g.DescribeTable("Scanning Streams", func(args helpers.Args) {
 	g.Entry("having non indexed", helpers.Args{Input: "having_non_indexed", Duration: 1 * time.Hour}),
 	g.Entry("having non indexed array", helpers.Args{Input: "having_non_indexed_arr", Duration: 1 * time.Hour}),
 	g.Entry("full text searching", helpers.Args{Input: "search", Duration: 1 * time.Hour}),
+	g.Entry("full text searching by the prefix", helpers.Args{Input: "search_prefix", Duration: 1 * time.Hour}),
+	g.Entry("full text searching by the wildcard", helpers.Args{Input: "search_wildcard", Duration: 1 * time.Hour}),
 	g.Entry("indexed only tags", helpers.Args{Input: "indexed_only", Duration: 1 * time.Hour}),
+	g.Entry("read from a snapshot", helpers.Args{Input: "all", Duration: 1 * time.Hour, ReadAt: 1500 * time.Millisecond, Want: "limit"}),
+	g.Entry("all elements in batches", helpers.Args{Input: "all", Duration: 1 * time.Hour, Batched: true}),
+	g.Entry("limit in batches", helpers.Args{Input: "limit", Duration: 1 * time.Hour, Batched: true}),
+	g.Entry("offset in batches", helpers.Args{Input: "offset", Duration: 1 * time.Hour, Batched: true}),
+	g.Entry("sort desc in batches", helpers.Args{Input: "sort_desc", Duration: 1 * time.Hour, Batched: true}),
+	g.Entry("filter by non-indexed tag in batches", helpers.Args{Input: "filter_tag", Duration: 1 * time.Hour, Batched: true}),
+	g.Entry("nothing in batches", helpers.Args{Input: "all", WantEmpty: true, Batched: true}),
+	g.Entry("read from a snapshot in batches", helpers.Args{Input: "all", Duration: 1 * time.Hour, ReadAt: 1500 * time.Millisecond, Want: "limit", Batched: true}),
+	g.Entry("nothing in a snapshot", helpers.Args{Input: "all", Offset: time.Second, Duration: 1 * time.Hour, ReadAt: 500 * time.Millisecond, WantEmpty: true}),
 )
+
+var _ = g.Describe("Explaining Streams", func() {
+	// collect indexes and the estimated series of the whole plan tree
+	collect := func(root *model_v1.PlanNode) (indexes []string, series int64) {
+		nodes := []*model_v1.PlanNode{root}
+		for len(nodes) > 0 {
+			n := nodes[0]
+			nodes = append(nodes[1:], n.GetChildren()...)
+			indexes = append(indexes, n.GetIndexes()...)
+			series += n.GetEstimatedSeries()
+		}
+		return indexes, series
+	}
+	g.It("estimates the local index scan", func() {
+		gm.Eventually(func(innerGm gm.Gomega) {
+			plan := stream_test_data.ExplainFn(innerGm, SharedContext, helpers.Args{Input: "less", Duration: 1 * time.Hour})
+			indexes, series := collect(plan)
+			innerGm.Expect(indexes).To(gm.ContainElement("duration"))
+			innerGm.Expect(series).To(gm.BeNumerically(">", 0))
+		}).Should(gm.Succeed())
+	})
+	g.It("chooses the global index", func() {
+		gm.Eventually(func(innerGm gm.Gomega) {
+			plan := stream_test_data.ExplainFn(innerGm, SharedContext, helpers.Args{Input: "global_index", Duration: 1 * time.Hour})
+			indexes, _ := collect(plan)
+			innerGm.Expect(indexes).To(gm.ContainElement("trace_id"))
+		}).Should(gm.Succeed())
+	})
+})
+
+var _ = g.Describe("Inspecting Streams", func() {
+	g.It("shows the schema with the data", func() {
+		gm.Eventually(func(innerGm gm.Gomega) {
+			c := stream_v1.NewStreamServiceClient(SharedContext.Connection)
+			resp, err := c.Inspect(context.Background(), &stream_v1.InspectRequest{
+				Metadata:  &common_v1.Metadata{Group: "default", Name: "sw"},
+				TimeRange: helpers.TimeRange(helpers.Args{Duration: 1 * time.Hour}, SharedContext),
+			})
+			innerGm.Expect(err).NotTo(gm.HaveOccurred())
+			innerGm.Expect(resp.GetStream().GetMetadata().GetName()).To(gm.Equal("sw"))
+			innerGm.Expect(resp.GetSample().GetTagFamilies()).NotTo(gm.BeEmpty())
+			innerGm.Expect(resp.GetSeriesCount()).To(gm.BeNumerically(">", 0))
+			innerGm.Expect(resp.GetElementCount()).To(gm.BeNumerically(">=", 5))
+		}).Should(gm.Succeed())
+	})
+})
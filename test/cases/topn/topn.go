@@ -43,4 +43,6 @@ var _ = g.DescribeTable("TopN Tests", verify,
 	g.Entry("asc", helpers.Args{Input: "asc", Duration: 25 * time.Minute, Offset: -20 * time.Minute}),
 	g.Entry("max top3 order by desc", helpers.Args{Input: "aggr_desc", Duration: 25 * time.Minute, Offset: -20 * time.Minute}),
 	g.Entry("max top3 with condition order by desc", helpers.Args{Input: "condition_aggr_desc", Duration: 25 * time.Minute, Offset: -20 * time.Minute}),
+	g.Entry("bottom1 uncovered by a top aggregation", helpers.Args{Input: "uncovered_asc", Duration: 25 * time.Minute, Offset: -20 * time.Minute}),
+	g.Entry("max top1 with a condition uncovered by the group-by tags", helpers.Args{Input: "uncovered_condition", Duration: 25 * time.Minute, Offset: -20 * time.Minute}),
 )
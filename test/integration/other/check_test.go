@@ -0,0 +1,85 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package integration_other_test
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	g "github.com/onsi/ginkgo/v2"
+	gm "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/banyand/check"
+	"github.com/apache/skywalking-banyandb/pkg/grpchelper"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/test/helpers"
+	"github.com/apache/skywalking-banyandb/pkg/test/setup"
+)
+
+var _ = g.Describe("Check the node", func() {
+	var deferFn func()
+	var conn *grpc.ClientConn
+
+	g.BeforeEach(func() {
+		var addr string
+		addr, _, deferFn = setup.SetUp()
+		gm.Eventually(helpers.HealthCheck(addr, 10*time.Second, 10*time.Second, grpc.WithTransportCredentials(insecure.NewCredentials())),
+			flags.EventuallyTimeout).Should(gm.Succeed())
+		var err error
+		conn, err = grpchelper.Conn(addr, 10*time.Second, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+	})
+	g.AfterEach(func() {
+		gm.Expect(conn.Close()).To(gm.Succeed())
+		deferFn()
+	})
+	g.It("passes the checks and removes the temporary group", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), flags.EventuallyTimeout)
+		defer cancel()
+		report := check.Run(ctx, conn)
+		var names []string
+		for _, r := range report.Results {
+			gm.Expect(r.Err).NotTo(gm.HaveOccurred(), r.Name)
+			names = append(names, r.Name)
+		}
+		gm.Expect(names).To(gm.Equal([]string{"schema", "write", "query", "index", "cleanup", "retention", "index-rules"}))
+		gm.Expect(report.Failed()).To(gm.BeZero())
+		resp, err := databasev1.NewGroupRegistryServiceClient(conn).List(context.Background(), &databasev1.GroupRegistryServiceListRequest{})
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		for _, group := range resp.GetGroup() {
+			gm.Expect(strings.HasPrefix(group.GetMetadata().GetName(), check.GroupPrefix)).To(gm.BeFalse())
+		}
+	})
+	g.It("reports the index rule bindings referring to the absent rules", func() {
+		_, err := databasev1.NewIndexRuleRegistryServiceClient(conn).Delete(context.Background(),
+			&databasev1.IndexRuleRegistryServiceDeleteRequest{Metadata: &commonv1.Metadata{Group: "default", Name: "trace_id"}})
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		ctx, cancel := context.WithTimeout(context.Background(), flags.EventuallyTimeout)
+		defer cancel()
+		report := check.Run(ctx, conn)
+		gm.Expect(report.Failed()).To(gm.Equal(1))
+		last := report.Results[len(report.Results)-1]
+		gm.Expect(last.Name).To(gm.Equal("index-rules"))
+		gm.Expect(last.Err).To(gm.MatchError(gm.ContainSubstring("the index rule trace_id is not found")))
+	})
+})
@@ -0,0 +1,101 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package integration_other_test
+
+import (
+	"context"
+	"time"
+
+	g "github.com/onsi/ginkgo/v2"
+	gm "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/grpchelper"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/test/helpers"
+	"github.com/apache/skywalking-banyandb/pkg/test/setup"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+	casesMeasureData "github.com/apache/skywalking-banyandb/test/cases/measure/data"
+)
+
+var _ = g.Describe("Clone the group", func() {
+	var deferFn func()
+	var baseTime time.Time
+	var conn *grpc.ClientConn
+
+	g.BeforeEach(func() {
+		var addr string
+		addr, _, deferFn = setup.SetUp()
+		gm.Eventually(helpers.HealthCheck(addr, 10*time.Second, 10*time.Second, grpc.WithTransportCredentials(insecure.NewCredentials())),
+			flags.EventuallyTimeout).Should(gm.Succeed())
+		var err error
+		conn, err = grpchelper.Conn(addr, 10*time.Second, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		baseTime = timestamp.NowMilli()
+		casesMeasureData.Write(conn, "service_cpm_minute", "sw_metric", "service_cpm_minute_data.json", baseTime, 500*time.Millisecond)
+	})
+	g.AfterEach(func() {
+		gm.Expect(conn.Close()).To(gm.Succeed())
+		deferFn()
+	})
+	g.It("copies the data points in the time range", func() {
+		timeRange := &modelv1.TimeRange{
+			Begin: timestamppb.New(baseTime.Add(-time.Minute)),
+			End:   timestamppb.New(baseTime.Add(time.Hour)),
+		}
+		query := func(group string) ([]*measurev1.DataPoint, error) {
+			resp, err := measurev1.NewMeasureServiceClient(conn).Query(context.Background(), &measurev1.QueryRequest{
+				Metadata:  &commonv1.Metadata{Group: group, Name: "service_cpm_minute"},
+				TimeRange: timeRange,
+				Limit:     100,
+				TagProjection: &modelv1.TagProjection{TagFamilies: []*modelv1.TagProjection_TagFamily{
+					{Name: "default", Tags: []string{"id", "entity_id"}},
+				}},
+				FieldProjection: &measurev1.QueryRequest_FieldProjection{Names: []string{"total", "value"}},
+			})
+			return resp.GetDataPoints(), err
+		}
+		var source []*measurev1.DataPoint
+		gm.Eventually(func(innerGm gm.Gomega) {
+			var err error
+			source, err = query("sw_metric")
+			innerGm.Expect(err).NotTo(gm.HaveOccurred())
+			innerGm.Expect(source).NotTo(gm.BeEmpty())
+		}, flags.EventuallyTimeout).Should(gm.Succeed())
+
+		resp, err := databasev1.NewGroupRegistryServiceClient(conn).Clone(context.Background(), &databasev1.GroupRegistryServiceCloneRequest{
+			Group:     "sw_metric",
+			Target:    "sw_metric_clone",
+			TimeRange: timeRange,
+		})
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		gm.Expect(resp.GetCreated()).To(gm.BeNumerically(">", 1))
+		gm.Expect(resp.GetDataPoints()).To(gm.BeNumerically(">=", len(source)))
+		gm.Eventually(func(innerGm gm.Gomega) {
+			cloned, errQuery := query("sw_metric_clone")
+			innerGm.Expect(errQuery).NotTo(gm.HaveOccurred())
+			innerGm.Expect(cloned).To(gm.HaveLen(len(source)))
+		}, flags.EventuallyTimeout).Should(gm.Succeed())
+	})
+})
@@ -0,0 +1,81 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package integration_other_test
+
+import (
+	"context"
+	"time"
+
+	g "github.com/onsi/ginkgo/v2"
+	gm "github.com/onsi/gomega"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	measurev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/measure/v1"
+	modelv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
+	"github.com/apache/skywalking-banyandb/pkg/grpchelper"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/test/helpers"
+	"github.com/apache/skywalking-banyandb/pkg/test/setup"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+	casesMeasureData "github.com/apache/skywalking-banyandb/test/cases/measure/data"
+)
+
+var _ = g.Describe("Compression", func() {
+	var deferFn func()
+	var baseTime time.Time
+	var conn *grpclib.ClientConn
+
+	g.BeforeEach(func() {
+		var addr string
+		addr, _, deferFn = setup.SetUp("--send-compression=" + grpchelper.Zstd)
+		gm.Eventually(helpers.HealthCheck(addr, 10*time.Second, 10*time.Second, grpclib.WithTransportCredentials(insecure.NewCredentials())),
+			flags.EventuallyTimeout).Should(gm.Succeed())
+		var err error
+		conn, err = grpchelper.Conn(addr, 10*time.Second, grpclib.WithTransportCredentials(insecure.NewCredentials()),
+			grpclib.WithDefaultCallOptions(grpclib.UseCompressor(gzip.Name)))
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		baseTime = timestamp.NowMilli()
+		casesMeasureData.Write(conn, "service_cpm_minute", "sw_metric", "service_cpm_minute_data.json", baseTime, 500*time.Millisecond)
+	})
+	g.AfterEach(func() {
+		gm.Expect(conn.Close()).To(gm.Succeed())
+		deferFn()
+	})
+	g.It("sends the gzip requests and receives the zstd responses", func() {
+		gm.Eventually(func(innerGm gm.Gomega) {
+			resp, err := measurev1.NewMeasureServiceClient(conn).Query(context.Background(), &measurev1.QueryRequest{
+				Metadata: &commonv1.Metadata{Group: "sw_metric", Name: "service_cpm_minute"},
+				TimeRange: &modelv1.TimeRange{
+					Begin: timestamppb.New(baseTime.Add(-time.Minute)),
+					End:   timestamppb.New(baseTime.Add(time.Hour)),
+				},
+				Limit: 100,
+				TagProjection: &modelv1.TagProjection{TagFamilies: []*modelv1.TagProjection_TagFamily{
+					{Name: "default", Tags: []string{"id", "entity_id"}},
+				}},
+				FieldProjection: &measurev1.QueryRequest_FieldProjection{Names: []string{"total", "value"}},
+			})
+			innerGm.Expect(err).NotTo(gm.HaveOccurred())
+			innerGm.Expect(resp.GetDataPoints()).To(gm.HaveLen(6))
+		}, flags.EventuallyTimeout).Should(gm.Succeed())
+	})
+})
@@ -0,0 +1,143 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package integration_other_test
+
+import (
+	"context"
+	"time"
+
+	g "github.com/onsi/ginkgo/v2"
+	gm "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	commonv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
+	databasev1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/database/v1"
+	"github.com/apache/skywalking-banyandb/pkg/grpchelper"
+	"github.com/apache/skywalking-banyandb/pkg/test/flags"
+	"github.com/apache/skywalking-banyandb/pkg/test/helpers"
+	"github.com/apache/skywalking-banyandb/pkg/test/setup"
+	"github.com/apache/skywalking-banyandb/pkg/timestamp"
+	casesStreamData "github.com/apache/skywalking-banyandb/test/cases/stream/data"
+)
+
+var _ = g.Describe("Index management", func() {
+	var deferFn func()
+	var conn *grpc.ClientConn
+	var client databasev1.IndexServiceClient
+	var ruleClient databasev1.IndexRuleRegistryServiceClient
+	var bindingClient databasev1.IndexRuleBindingRegistryServiceClient
+	md := &commonv1.Metadata{Group: "default", Name: "sw"}
+
+	g.BeforeEach(func() {
+		var addr string
+		addr, _, deferFn = setup.SetUp()
+		gm.Eventually(helpers.HealthCheck(addr, 10*time.Second, 10*time.Second, grpc.WithTransportCredentials(insecure.NewCredentials())),
+			flags.EventuallyTimeout).Should(gm.Succeed())
+		var err error
+		conn, err = grpchelper.Conn(addr, 10*time.Second, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		client = databasev1.NewIndexServiceClient(conn)
+		ruleClient = databasev1.NewIndexRuleRegistryServiceClient(conn)
+		bindingClient = databasev1.NewIndexRuleBindingRegistryServiceClient(conn)
+		casesStreamData.Write(conn, "data.json", timestamp.NowMilli(), 500*time.Millisecond)
+	})
+	g.AfterEach(func() {
+		gm.Expect(conn.Close()).To(gm.Succeed())
+		deferFn()
+	})
+	stats := func(innerGm gm.Gomega, name string) *databasev1.IndexServiceStatsResponse_IndexRule {
+		resp, err := client.Stats(context.Background(), &databasev1.IndexServiceStatsRequest{Metadata: md})
+		innerGm.Expect(err).NotTo(gm.HaveOccurred())
+		for _, r := range resp.GetIndexRules() {
+			if r.GetName() == name {
+				return r
+			}
+		}
+		innerGm.Expect(resp.GetIndexRules()).To(gm.ContainElement(gm.HaveField("Name", name)))
+		return nil
+	}
+	cardinality := func(r *databasev1.IndexServiceStatsResponse_IndexRule) (n uint64) {
+		for _, s := range r.GetShards() {
+			n += s.GetCardinality()
+		}
+		return n
+	}
+	g.It("reports the statistics and rebuilds the indices", func() {
+		gm.Eventually(func(innerGm gm.Gomega) {
+			r := stats(innerGm, "duration")
+			innerGm.Expect(cardinality(r)).To(gm.BeNumerically(">", 0))
+			innerGm.Expect(r.GetShards()).To(gm.ContainElement(gm.HaveField("LastWriteTime", gm.Not(gm.BeNil()))))
+		}, flags.EventuallyTimeout).Should(gm.Succeed())
+
+		resp, err := client.Rebuild(context.Background(), &databasev1.IndexServiceRebuildRequest{
+			Metadata:   md,
+			IndexRules: []string{"duration"},
+		})
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		gm.Expect(resp.GetIndexRules()).To(gm.Equal([]string{"duration"}))
+		gm.Eventually(func(innerGm gm.Gomega) {
+			r := stats(innerGm, "duration")
+			innerGm.Expect(r.GetRebuilding()).To(gm.BeFalse())
+			innerGm.Expect(cardinality(r)).To(gm.BeNumerically(">", 0))
+			innerGm.Expect(r.GetShards()).To(gm.ContainElement(gm.HaveField("LastBuildTime", gm.Not(gm.BeNil()))))
+		}, flags.EventuallyTimeout).Should(gm.Succeed())
+	})
+	g.It("backfills the indices of a binding beginning in the past", func() {
+		gm.Eventually(func(innerGm gm.Gomega) {
+			innerGm.Expect(cardinality(stats(innerGm, "duration"))).To(gm.BeNumerically(">", 0))
+		}, flags.EventuallyTimeout).Should(gm.Succeed())
+		now := time.Now()
+		_, err := ruleClient.Create(context.Background(), &databasev1.IndexRuleRegistryServiceCreateRequest{
+			IndexRule: &databasev1.IndexRule{
+				Metadata: &commonv1.Metadata{Group: "default", Name: "span_id"},
+				Tags:     []string{"span_id"},
+				Type:     databasev1.IndexRule_TYPE_INVERTED,
+				Location: databasev1.IndexRule_LOCATION_SERIES,
+			},
+		})
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		_, err = bindingClient.Create(context.Background(), &databasev1.IndexRuleBindingRegistryServiceCreateRequest{
+			IndexRuleBinding: &databasev1.IndexRuleBinding{
+				Metadata: &commonv1.Metadata{Group: "default", Name: "sw-span-id-binding"},
+				Rules:    []string{"span_id"},
+				Subject: &databasev1.Subject{
+					Catalog: commonv1.Catalog_CATALOG_STREAM,
+					Name:    "sw",
+				},
+				BeginAt:  timestamppb.New(now.Add(-time.Hour)),
+				ExpireAt: timestamppb.New(now.Add(time.Hour)),
+			},
+		})
+		gm.Expect(err).NotTo(gm.HaveOccurred())
+		gm.Eventually(func(innerGm gm.Gomega) {
+			r := stats(innerGm, "span_id")
+			innerGm.Expect(r.GetRebuilding()).To(gm.BeFalse())
+			innerGm.Expect(cardinality(r)).To(gm.BeNumerically(">", 0))
+			innerGm.Expect(r.GetShards()).To(gm.ContainElement(gm.HaveField("LastBuildTime", gm.Not(gm.BeNil()))))
+		}, flags.EventuallyTimeout).Should(gm.Succeed())
+	})
+	g.It("rejects the index rules not bound", func() {
+		_, err := client.Rebuild(context.Background(), &databasev1.IndexServiceRebuildRequest{
+			Metadata:   md,
+			IndexRules: []string{"absent"},
+		})
+		gm.Expect(err).To(gm.HaveOccurred())
+	})
+})
@@ -24,7 +24,10 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 
 	common_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/common/v1"
 	model_v1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v1"
@@ -143,4 +146,50 @@ var _ = Describe("Property application", func() {
 		_, err = client.Get(context.Background(), &property_v1.GetRequest{Metadata: md})
 		Expect(err).To(MatchError("rpc error: code = NotFound desc = banyandb: resource not found"))
 	})
+	It("applies properties conditionally", func() {
+		got, err := client.Get(context.Background(), &property_v1.GetRequest{Metadata: md})
+		Expect(err).NotTo(HaveOccurred())
+		revision := got.Property.ModRevision
+		Expect(revision).To(BeNumerically(">", 0))
+		resp, err := client.Apply(context.Background(), &property_v1.ApplyRequest{
+			Property: &property_v1.Property{
+				Metadata: md,
+				Tags: []*model_v1.Tag{
+					{Key: "t3", Value: &model_v1.TagValue{Value: &model_v1.TagValue_Str{Str: &model_v1.Str{Value: "v3"}}}},
+				},
+			},
+			ModRevision: revision,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.ModRevision).To(BeNumerically(">", revision))
+		got, err = client.Get(context.Background(), &property_v1.GetRequest{Metadata: md})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got.Property.Tags).To(HaveLen(3))
+		By("Rejecting the stale revision")
+		_, err = client.Apply(context.Background(), &property_v1.ApplyRequest{
+			Property:    &property_v1.Property{Metadata: md},
+			ModRevision: revision,
+		})
+		Expect(status.Code(err)).To(Equal(codes.Aborted))
+		_, err = client.Delete(context.Background(), &property_v1.DeleteRequest{Metadata: md, ModRevision: revision})
+		Expect(status.Code(err)).To(Equal(codes.Aborted))
+		deleted, err := client.Delete(context.Background(), &property_v1.DeleteRequest{Metadata: md, ModRevision: resp.ModRevision})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted.Deleted).To(BeTrue())
+	})
+	It("expires properties", func() {
+		_, err := client.Apply(context.Background(), &property_v1.ApplyRequest{Property: &property_v1.Property{
+			Metadata: md,
+			Ttl:      durationpb.New(2 * time.Second),
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		got, err := client.Get(context.Background(), &property_v1.GetRequest{Metadata: md})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got.Property.Ttl.AsDuration()).To(Equal(2 * time.Second))
+		Expect(got.Property.Tags).To(HaveLen(2))
+		Eventually(func() codes.Code {
+			_, err := client.Get(context.Background(), &property_v1.GetRequest{Metadata: md})
+			return status.Code(err)
+		}, 10*time.Second).Should(Equal(codes.NotFound))
+	})
 })